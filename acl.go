@@ -0,0 +1,356 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// ACL operations.
+const (
+	ACLPublish   = wire.OpPublish   // Send messages to a strand
+	ACLSubscribe = wire.OpSubscribe // Receive a strand's messages
+	ACLAdmin     = "admin"          // Delete a strand
+)
+
+// ACL roles, each granting a set of operations.
+const (
+	RolePublisher  = "publisher"  // publish
+	RoleSubscriber = "subscriber" // subscribe
+	RoleAdmin      = "admin"      // publish, subscribe, and admin
+)
+
+// aclRoles maps each role to the operations it grants.
+var aclRoles = map[string][]string{
+	RolePublisher:  {ACLPublish},
+	RoleSubscriber: {ACLSubscribe},
+	RoleAdmin:      {ACLPublish, ACLSubscribe, ACLAdmin},
+}
+
+// aclPollInterval is how often an ACL file is checked for changes.
+const aclPollInterval = 2 * time.Second
+
+// ErrDenied is returned when an identity's ACL does not allow an operation on a strand.
+var ErrDenied = errors.New("operation denied by ACL")
+
+// ACLRule allows identities matching Identity the Ops, and those of Role, on strands matching
+// Strands. Patterns may use '*' to match any run of characters, including the namespace separator.
+type ACLRule struct {
+	Identity string   `yaml:"identity" json:"identity"`
+	Strands  string   `yaml:"strands" json:"strands"`
+	Ops      []string `yaml:"ops,omitempty" json:"ops,omitempty"`   // publish, subscribe, or admin
+	Role     string   `yaml:"role,omitempty" json:"role,omitempty"` // publisher, subscriber, or admin
+}
+
+// allows reports whether the rule grants op.
+func (rule ACLRule) allows(op string) bool {
+	return slices.Contains(rule.Ops, op) || slices.Contains(aclRoles[rule.Role], op)
+}
+
+// ACL maps identities to the operations they may perform per strand pattern.
+// An empty ACL allows everything; otherwise whatever no rule allows is denied.
+type ACL []ACLRule
+
+// Allowed reports whether identity may perform op on strandID.
+func (acl ACL) Allowed(identity, op, strandID string) bool {
+	if len(acl) == 0 {
+		return true
+	}
+	for _, rule := range acl {
+		if rule.allows(op) && aclMatch(rule.Identity, identity) && aclMatch(rule.Strands, strandID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reports rules with missing patterns, or unknown operations or roles.
+func (acl ACL) Validate() error {
+	var errs []error
+	for i, rule := range acl {
+		if rule.Identity == "" || rule.Strands == "" {
+			errs = append(errs, fmt.Errorf("acl[%d]: identity and strands are required", i))
+		}
+		if len(rule.Ops) == 0 && rule.Role == "" {
+			errs = append(errs, fmt.Errorf("acl[%d]: ops or role is required", i))
+		}
+		if _, ok := aclRoles[rule.Role]; rule.Role != "" && !ok {
+			errs = append(errs, fmt.Errorf("acl[%d].role: unknown role %q (want publisher, subscriber, or admin)", i, rule.Role))
+		}
+		for _, op := range rule.Ops {
+			if op != ACLPublish && op != ACLSubscribe && op != ACLAdmin {
+				errs = append(errs, fmt.Errorf("acl[%d].ops: unknown operation %q (want publish, subscribe, or admin)", i, op))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// aclMatch matches s against pattern, where '*' matches any run of characters.
+func aclMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// SetACL replaces the Conduktor's ACL.
+func (c *Conduktor) SetACL(acl ACL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acl = acl
+}
+
+// ACL returns the Conduktor's ACL.
+func (c *Conduktor) ACL() ACL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.acl)
+}
+
+// ACLSet validates and replaces the Conduktor's ACL, writing it to the ACL file if one is served.
+func (c *Conduktor) ACLSet(acl ACL) error {
+	if err := acl.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.aclPath != "" {
+		if err := aclSave(c.aclPath, acl); err != nil {
+			return err
+		}
+	}
+	c.acl = acl
+	c.log.Info("ACL set", zap.Int("rules", len(acl)))
+	return nil
+}
+
+// ACLServe takes the ACL from the YAML file at path, seeding it with acl if the file does not
+// exist, and reloads it whenever the file changes until stop is called. A change that does not
+// parse or validate is reported and the ACL in force kept. Without a path it just sets acl.
+func (c *Conduktor) ACLServe(path string, acl ACL) (stop func(), err error) {
+	if path == "" {
+		c.SetACL(acl)
+		return func() {}, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := aclSave(path, acl); err != nil {
+			return nil, err
+		}
+	}
+	loaded, modified, err := aclLoad(path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.acl, c.aclPath = loaded, path
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(aclPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(modified) {
+				continue
+			}
+			acl, mtime, err := aclLoad(path)
+			modified = mtime
+			if err != nil {
+				c.log.Error("Failed to reload ACL", zap.String("path", path), zap.Error(err))
+				c.errs.report(ErrorSourceACL, "", "", err)
+				continue
+			}
+			c.SetACL(acl)
+			c.log.Info("ACL reloaded", zap.String("path", path), zap.Int("rules", len(acl)))
+		}
+	}()
+	c.log.Info("ACL loaded", zap.String("path", path), zap.Int("rules", len(loaded)))
+
+	return func() {
+		cancel()
+		<-stopped
+		c.mu.Lock()
+		c.aclPath = ""
+		c.mu.Unlock()
+	}, nil
+}
+
+// aclLoad reads and validates the ACL file at path, returning it with the file's modification time.
+func aclLoad(path string) (ACL, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var acl ACL
+	if err := yaml.Unmarshal(data, &acl); err != nil {
+		return nil, info.ModTime(), fmt.Errorf("%s: %w", path, err)
+	}
+	if err := acl.Validate(); err != nil {
+		return nil, info.ModTime(), fmt.Errorf("%s: %w", path, err)
+	}
+	return acl, info.ModTime(), nil
+}
+
+// aclSave writes acl to path as YAML, atomically.
+func aclSave(path string, acl ACL) error {
+	if acl == nil {
+		acl = ACL{}
+	}
+	data, err := yaml.Marshal(acl)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Authorize checks that identity may perform op on strandID, auditing denials. If identity
+// authenticated with a token, ctx carries its claims, which must allow the operation too.
+// An empty identity is a trusted in-process caller and is always allowed.
+func (c *Conduktor) Authorize(ctx context.Context, identity, op, strandID string) error {
+	if identity == "" {
+		return nil
+	}
+
+	claims, hasClaims := ClaimsFrom(ctx)
+	c.mu.RLock()
+	err := c.authorize(identity, op, strandID, claims, hasClaims)
+	c.mu.RUnlock()
+
+	if err != nil {
+		c.Audit(identity, AuditAuthFailure, strandID, map[string]string{"op": op}, err)
+	}
+	return err
+}
+
+// AuthorizeGlobal checks that identity may change broker-wide settings, like the ACL, namespace
+// quotas, and maintenance, which affect every namespace: a token limited to one namespace may not.
+func (c *Conduktor) AuthorizeGlobal(ctx context.Context, identity string) error {
+	claims, hasClaims := ClaimsFrom(ctx)
+	if identity == "" || !hasClaims || claims.Namespace == "" {
+		return nil
+	}
+
+	aclDenials.WithLabelValues(ACLAdmin).Inc()
+	err := fmt.Errorf("%w: %s is limited to namespace %s", ErrDenied, identity, claims.Namespace)
+	c.Audit(identity, AuditAuthFailure, "", map[string]string{"op": ACLAdmin, "namespace": claims.Namespace}, err)
+	return err
+}
+
+// authorize checks that identity may perform op on strandID, by the ACL and the claims of the
+// identity's token, if it authenticated with one. Callers must hold c.mu.
+func (c *Conduktor) authorize(identity, op, strandID string, claims IdentityClaims, hasClaims bool) error {
+	if c.acl.Allowed(identity, op, strandID) && (!hasClaims || claims.allows(op, strandID, c.now())) {
+		return nil
+	}
+	aclDenials.WithLabelValues(op).Inc()
+	c.log.Warn("Operation denied by ACL", zap.String("identity", identity), zap.String("op", op), zap.String("strand", strandID))
+	return fmt.Errorf("%w: %s may not %s %s", ErrDenied, identity, op, strandID)
+}
+
+// SendAs makes Send act as identity, subject to the ACL.
+func SendAs(identity string) SendOption {
+	return func(o *sendOpts) {
+		o.identity = identity
+	}
+}
+
+// ReceiveOption tunes a single Receive.
+type ReceiveOption func(*receiveOpts)
+
+type receiveOpts struct {
+	identity string
+	ctx      context.Context
+}
+
+// ReceiveAs makes Receive act as identity, subject to the ACL.
+func ReceiveAs(identity string) ReceiveOption {
+	return func(o *receiveOpts) {
+		o.identity = identity
+	}
+}
+
+// ReceiveContext makes Receive give up with ctx.Err() once ctx is done, instead of waiting for a
+// message indefinitely. Receive and Subscribe authorize with the token claims ctx carries.
+func ReceiveContext(ctx context.Context) ReceiveOption {
+	return func(o *receiveOpts) {
+		o.ctx = ctx
+	}
+}
+
+// RemoveOption tunes a single StrandRemove.
+type RemoveOption func(*removeOpts)
+
+type removeOpts struct {
+	identity string
+	ctx      context.Context // Carries the token claims of identity; nil for none
+}
+
+// RemoveAs makes StrandRemove act as identity, subject to the ACL.
+func RemoveAs(identity string) RemoveOption {
+	return func(o *removeOpts) {
+		o.identity = identity
+	}
+}
+
+// RemoveContext makes StrandRemove authorize with the token claims ctx carries.
+func RemoveContext(ctx context.Context) RemoveOption {
+	return func(o *removeOpts) {
+		o.ctx = ctx
+	}
+}
+
+// authContext returns ctx, or the background context if an option left it unset.
+func authContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,536 @@
+package condukt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AdminHandler serves the JSON admin API for a Conduktor:
+//
+//	GET    /admin/strands                  List strands with their configs and depths (?namespace=NS for one namespace)
+//	POST   /admin/strands                  Create a strand: {"id": "...", "config": {...}}
+//	GET    /admin/strands/{id}             Describe a strand
+//	DELETE /admin/strands/{id}             Delete a strand and its messages
+//	GET    /admin/strands/{id}/messages    Peek at unacked messages (?limit=N, default 100)
+//	POST   /admin/strands/{id}/purge       Delete a strand's messages
+//	POST   /admin/strands/{id}/pause       Hold back deliveries on a strand
+//	POST   /admin/strands/{id}/resume      Restart deliveries on a paused strand
+//	PUT    /admin/strands/{id}/maintenance Reject sends to a strand while it drains: {"enabled": true}
+//	GET    /admin/strands/{id}/dlq         Peek at a strand's dead-lettered messages and why (?limit=N)
+//	POST   /admin/strands/{id}/dlq/redrive Move dead-lettered messages back: {"ids": [...], "headers": {...}}, all without ids
+//	POST   /admin/strands/{id}/dlq/purge   Delete dead-lettered messages: {"ids": [...]}, all without ids
+//	GET    /admin/messages/{id}/trace      Where a message is: state, store, delivery attempts, and timestamps
+//	GET    /admin/events                   Message lifecycle events, oldest first (?msg=ID&strand=S&since=RFC3339&limit=N)
+//	GET    /admin/stats                    Totals across strands
+//	GET    /admin/lag                      Consumer lag of each strand, in unacked messages and seconds
+//	GET    /admin/namespaces               List namespaces with their quotas and usage
+//	GET    /admin/namespaces/{ns}          Describe a namespace
+//	PUT    /admin/namespaces/{ns}          Set a namespace's quota: {"max_strands": N, "max_bytes": N, "max_rate": N}
+//	DELETE /admin/namespaces/{ns}          Remove a namespace's quota, keeping its strands
+//	GET    /admin/schedules                List schedules with their next and last runs
+//	GET    /admin/schedules/{name}         Describe a schedule
+//	PUT    /admin/schedules/{name}         Set a schedule: {"cron": "@hourly", "strand": "...", "payload": "...", "headers": {...}}
+//	DELETE /admin/schedules/{name}         Delete a schedule
+//	GET    /admin/acl                      The ACL's rules
+//	PUT    /admin/acl                      Replace the ACL, and its file if served: [{"identity": "...", "strands": "...", "role": "publisher"}, ...]
+//	GET    /admin/maintenance              Broker-wide maintenance mode and the strands in maintenance
+//	PUT    /admin/maintenance              Reject all sends while consumers drain: {"enabled": true}
+//	POST   /admin/recover                  Resend unacked durable messages
+//	GET    /admin/cluster                  Cluster topology
+//	GET    /admin/loglevel                 Current log level
+//	PUT    /admin/loglevel                 Change the log level: {"level": "debug"}
+//	GET    /admin/dashboard                Web dashboard (see DashboardHandler)
+//
+// Requests changing a strand need the admin operation on it in the ACL.
+func AdminHandler(c *Conduktor) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/strands", func(w http.ResponseWriter, r *http.Request) {
+		strands, err := c.Strands()
+		if ns := r.URL.Query().Get("namespace"); ns != "" && err == nil {
+			filtered := []StrandInfo{}
+			for _, info := range strands {
+				if Namespace(info.ID) == ns {
+					filtered = append(filtered, info)
+				}
+			}
+			strands = filtered
+		}
+		adminReply(w, strands, err)
+	})
+
+	mux.HandleFunc("POST /admin/strands", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     string     `json:"id"`
+			Config StrandConf `json:"config"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+			adminError(w, http.StatusBadRequest, "request body must be {\"id\": ..., \"config\": {...}}")
+			return
+		}
+		if !adminAuthorize(c, w, r, req.ID) {
+			return
+		}
+		err := c.StrandAdd(req.ID, req.Config)
+		c.Audit(adminActor(r), AuditStrandCreate, req.ID, auditConf(req.Config), err)
+		if errors.Is(err, ErrQuotaExceeded) {
+			adminError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err != nil {
+			adminError(w, http.StatusConflict, err.Error())
+			return
+		}
+		info, err := c.Strand(req.ID)
+		if err != nil {
+			adminReply(w, nil, err)
+			return
+		}
+		adminWrite(w, http.StatusCreated, info)
+	})
+
+	mux.HandleFunc("GET /admin/strands/{id}", func(w http.ResponseWriter, r *http.Request) {
+		info, err := c.Strand(r.PathValue("id"))
+		adminReply(w, info, err)
+	})
+
+	mux.HandleFunc("DELETE /admin/strands/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err := c.StrandRemove(r.PathValue("id"), RemoveAs(ActorFrom(r.Context(), "")), RemoveContext(r.Context()))
+		c.Audit(adminActor(r), AuditStrandDelete, r.PathValue("id"), nil, err)
+		adminReply(w, map[string]string{"deleted": r.PathValue("id")}, err)
+	})
+
+	mux.HandleFunc("GET /admin/strands/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		msgs, err := c.Peek(r.PathValue("id"), adminLimit(r))
+		adminReply(w, msgs, err)
+	})
+
+	mux.HandleFunc("POST /admin/strands/{id}/purge", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		purged, err := c.Purge(r.PathValue("id"))
+		c.Audit(adminActor(r), AuditStrandPurge, r.PathValue("id"), map[string]string{"purged": strconv.Itoa(purged)}, err)
+		adminReply(w, map[string]int{"purged": purged}, err)
+	})
+
+	mux.HandleFunc("POST /admin/strands/{id}/pause", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		err := c.Pause(r.PathValue("id"))
+		c.Audit(adminActor(r), AuditStrandPause, r.PathValue("id"), nil, err)
+		adminReply(w, map[string]string{"paused": r.PathValue("id")}, err)
+	})
+
+	mux.HandleFunc("POST /admin/strands/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		err := c.Resume(r.PathValue("id"))
+		c.Audit(adminActor(r), AuditStrandResume, r.PathValue("id"), nil, err)
+		adminReply(w, map[string]string{"resumed": r.PathValue("id")}, err)
+	})
+
+	mux.HandleFunc("PUT /admin/strands/{id}/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		var req struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			adminError(w, http.StatusBadRequest, "request body must be {\"enabled\": true|false}")
+			return
+		}
+		err := c.SetStrandMaintenance(r.PathValue("id"), req.Enabled)
+		c.Audit(adminActor(r), AuditMaintenance, r.PathValue("id"), map[string]string{"enabled": strconv.FormatBool(req.Enabled)}, err)
+		adminReply(w, map[string]bool{"enabled": req.Enabled}, err)
+	})
+
+	mux.HandleFunc("GET /admin/strands/{id}/dlq", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		msgs, err := c.DeadLetters(r.PathValue("id"), adminLimit(r))
+		adminReply(w, msgs, err)
+	})
+
+	mux.HandleFunc("POST /admin/strands/{id}/dlq/redrive", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		var req struct {
+			IDs     []string          `json:"ids"`
+			Headers map[string]string `json:"headers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			adminError(w, http.StatusBadRequest, "request body must be {\"ids\": [...], \"headers\": {...}} or empty")
+			return
+		}
+		redriven, err := c.Redrive(r.PathValue("id"), req.IDs, req.Headers)
+		c.Audit(adminActor(r), AuditDLQRedrive, r.PathValue("id"), map[string]string{"redriven": strconv.Itoa(redriven)}, err)
+		adminReply(w, map[string]int{"redriven": redriven}, err)
+	})
+
+	mux.HandleFunc("POST /admin/strands/{id}/dlq/purge", func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorize(c, w, r, r.PathValue("id")) {
+			return
+		}
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			adminError(w, http.StatusBadRequest, "request body must be {\"ids\": [...]} or empty")
+			return
+		}
+		purged, err := c.DeadLetterPurge(r.PathValue("id"), req.IDs)
+		c.Audit(adminActor(r), AuditDLQPurge, r.PathValue("id"), map[string]string{"purged": strconv.Itoa(purged)}, err)
+		adminReply(w, map[string]int{"purged": purged}, err)
+	})
+
+	mux.HandleFunc("GET /admin/messages/{id}/trace", func(w http.ResponseWriter, r *http.Request) {
+		trace, err := c.Trace(r.PathValue("id"))
+		if err == nil && !adminAuthorize(c, w, r, trace.Strand) {
+			return
+		}
+		adminReply(w, trace, err)
+	})
+
+	mux.HandleFunc("GET /admin/events", func(w http.ResponseWriter, r *http.Request) {
+		q := EventQuery{MsgID: r.URL.Query().Get("msg"), Strand: r.URL.Query().Get("strand"), Limit: adminLimit(r)}
+		if since := r.URL.Query().Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				adminError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+				return
+			}
+			q.Since = t.UnixNano()
+		}
+		events, err := c.Events(q)
+		if errors.Is(err, ErrNoEventLog) {
+			adminError(w, http.StatusNotImplemented, err.Error())
+			return
+		}
+		adminReply(w, events, err)
+	})
+
+	mux.HandleFunc("GET /admin/stats", func(w http.ResponseWriter, r *http.Request) {
+		strands, err := c.Strands()
+		stats := struct {
+			Strands     int   `json:"strands"`
+			Depth       int   `json:"depth"`
+			DeadLetters int   `json:"dead_letters"`
+			Bytes       int64 `json:"bytes"`
+			Full        int   `json:"full"` // Strands at or above their MaxBytes
+		}{}
+		for _, info := range strands {
+			stats.Strands++
+			stats.Bytes += info.Bytes
+			if info.Config.MaxBytes > 0 && info.Bytes >= info.Config.MaxBytes {
+				stats.Full++
+			}
+			if strings.HasSuffix(info.ID, dlqSuffix) {
+				stats.DeadLetters += info.Depth
+			} else {
+				stats.Depth += info.Depth
+			}
+		}
+		adminReply(w, stats, err)
+	})
+
+	mux.HandleFunc("GET /admin/lag", func(w http.ResponseWriter, r *http.Request) {
+		lags, err := c.Lag()
+		adminReply(w, lags, err)
+	})
+
+	mux.HandleFunc("GET /admin/namespaces", func(w http.ResponseWriter, r *http.Request) {
+		namespaces, err := c.Namespaces()
+		adminReply(w, namespaces, err)
+	})
+
+	mux.HandleFunc("GET /admin/namespaces/{ns}", func(w http.ResponseWriter, r *http.Request) {
+		info, err := c.NamespaceGet(r.PathValue("ns"))
+		adminReply(w, info, err)
+	})
+
+	mux.HandleFunc("PUT /admin/namespaces/{ns}", func(w http.ResponseWriter, r *http.Request) {
+		if !adminGlobal(c, w, r) {
+			return
+		}
+		var quota NamespaceQuota
+		if err := json.NewDecoder(r.Body).Decode(&quota); err != nil || quota.MaxStrands < 0 || quota.MaxBytes < 0 || quota.MaxRate < 0 {
+			adminError(w, http.StatusBadRequest, "request body must be {\"max_strands\": N, \"max_bytes\": N, \"max_rate\": N} with no negative quotas")
+			return
+		}
+		err := c.SetNamespace(r.PathValue("ns"), quota)
+		c.Audit(adminActor(r), AuditNamespaceSet, "", auditQuota(r.PathValue("ns"), quota), err)
+		if err != nil {
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		info, err := c.NamespaceGet(r.PathValue("ns"))
+		adminReply(w, info, err)
+	})
+
+	mux.HandleFunc("DELETE /admin/namespaces/{ns}", func(w http.ResponseWriter, r *http.Request) {
+		if !adminGlobal(c, w, r) {
+			return
+		}
+		err := c.NamespaceRemove(r.PathValue("ns"))
+		c.Audit(adminActor(r), AuditNamespaceDel, "", map[string]string{"namespace": r.PathValue("ns")}, err)
+		adminReply(w, map[string]string{"deleted": r.PathValue("ns")}, err)
+	})
+
+	mux.HandleFunc("GET /admin/schedules", func(w http.ResponseWriter, r *http.Request) {
+		schedules, err := c.Schedules()
+		adminReply(w, schedules, err)
+	})
+
+	mux.HandleFunc("GET /admin/schedules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		info, err := c.ScheduleGet(r.PathValue("name"))
+		adminReply(w, info, err)
+	})
+
+	mux.HandleFunc("PUT /admin/schedules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var conf ScheduleConf
+		if err := json.NewDecoder(r.Body).Decode(&conf); err != nil {
+			adminError(w, http.StatusBadRequest, "request body must be {\"cron\": ..., \"strand\": ..., \"payload\": ..., \"headers\": {...}}")
+			return
+		}
+		conf.Name = r.PathValue("name")
+		conf.Identity = ActorFrom(r.Context(), "")
+		if err := conf.Validate(); err != nil {
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.Authorize(r.Context(), conf.Identity, ACLPublish, conf.Strand); err != nil {
+			adminError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if !adminSchedule(c, w, r, conf.Name) {
+			return
+		}
+		err := c.ScheduleSet(conf)
+		c.Audit(adminActor(r), AuditScheduleSet, conf.Strand, map[string]string{"schedule": conf.Name, "cron": conf.Cron}, err)
+		if err != nil {
+			adminReply(w, nil, err)
+			return
+		}
+		info, err := c.ScheduleGet(conf.Name)
+		adminReply(w, info, err)
+	})
+
+	mux.HandleFunc("DELETE /admin/schedules/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if !adminSchedule(c, w, r, r.PathValue("name")) {
+			return
+		}
+		err := c.ScheduleRemove(r.PathValue("name"))
+		c.Audit(adminActor(r), AuditScheduleDel, "", map[string]string{"schedule": r.PathValue("name")}, err)
+		adminReply(w, map[string]string{"deleted": r.PathValue("name")}, err)
+	})
+
+	mux.HandleFunc("GET /admin/acl", func(w http.ResponseWriter, r *http.Request) {
+		adminWrite(w, http.StatusOK, c.ACL())
+	})
+
+	mux.HandleFunc("PUT /admin/acl", func(w http.ResponseWriter, r *http.Request) {
+		if !adminGlobal(c, w, r) {
+			return
+		}
+		var acl ACL
+		if err := json.NewDecoder(r.Body).Decode(&acl); err != nil {
+			adminError(w, http.StatusBadRequest, "request body must be [{\"identity\": ..., \"strands\": ..., \"role\": ... or \"ops\": [...]}, ...]")
+			return
+		}
+		if err := acl.Validate(); err != nil {
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		err := c.ACLSet(acl)
+		c.Audit(adminActor(r), AuditACLSet, "", map[string]string{"rules": strconv.Itoa(len(acl))}, err)
+		adminReply(w, acl, err)
+	})
+
+	mux.HandleFunc("GET /admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		adminWrite(w, http.StatusOK, c.Maintenance())
+	})
+
+	mux.HandleFunc("PUT /admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		if !adminGlobal(c, w, r) {
+			return
+		}
+		var req struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			adminError(w, http.StatusBadRequest, "request body must be {\"enabled\": true|false}")
+			return
+		}
+		c.SetMaintenance(req.Enabled)
+		c.Audit(adminActor(r), AuditMaintenance, "", map[string]string{"enabled": strconv.FormatBool(req.Enabled)}, nil)
+		adminWrite(w, http.StatusOK, c.Maintenance())
+	})
+
+	mux.HandleFunc("POST /admin/recover", func(w http.ResponseWriter, r *http.Request) {
+		if !adminGlobal(c, w, r) {
+			return
+		}
+		err := c.RecoverUnackedMessages()
+		c.Audit(adminActor(r), AuditRecover, "", nil, err)
+		adminReply(w, map[string]bool{"recovered": err == nil}, err)
+	})
+
+	mux.Handle("GET /admin/cluster", TopologyHandler(c))
+
+	mux.HandleFunc("GET /admin/loglevel", func(w http.ResponseWriter, r *http.Request) {
+		adminWrite(w, http.StatusOK, map[string]string{"level": logLevel.Level().String()})
+	})
+
+	mux.HandleFunc("PUT /admin/loglevel", func(w http.ResponseWriter, r *http.Request) {
+		if !adminGlobal(c, w, r) {
+			return
+		}
+		var req struct {
+			Level zapcore.Level `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			adminError(w, http.StatusBadRequest, "request body must be {\"level\": \"debug|info|warn|error\"}")
+			return
+		}
+		previous := logLevel.Level()
+		logLevel.SetLevel(req.Level)
+		c.Audit(adminActor(r), AuditConfigChange, "", map[string]string{"log_level": req.Level.String(), "previous": previous.String()}, nil)
+		c.log.Info("Log level changed", zap.Stringer("level", req.Level), zap.Stringer("previous", previous))
+		adminWrite(w, http.StatusOK, map[string]string{"level": req.Level.String()})
+	})
+
+	dashboard := DashboardHandler(c)
+	mux.Handle("GET /admin/dashboard", dashboard)
+	mux.Handle("GET /admin/dashboard/", dashboard)
+
+	return mux
+}
+
+// adminAuthorize checks that the request's identity may administer strandID, replying 403 if not.
+func adminAuthorize(c *Conduktor, w http.ResponseWriter, r *http.Request, strandID string) bool {
+	if err := c.Authorize(r.Context(), ActorFrom(r.Context(), ""), ACLAdmin, strandID); err != nil {
+		adminError(w, http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// adminSchedule checks that the request's identity may publish to the strand of the schedule it
+// replaces or removes, if there is one, replying 403 if not.
+func adminSchedule(c *Conduktor, w http.ResponseWriter, r *http.Request, name string) bool {
+	existing, err := c.ScheduleGet(name)
+	if err != nil {
+		return true // Nothing to replace, or no scheduler to report
+	}
+	if err := c.Authorize(r.Context(), ActorFrom(r.Context(), ""), ACLPublish, existing.Strand); err != nil {
+		adminError(w, http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// adminGlobal checks that the request's identity may change broker-wide settings, replying 403 if not.
+func adminGlobal(c *Conduktor, w http.ResponseWriter, r *http.Request) bool {
+	if err := c.AuthorizeGlobal(r.Context(), ActorFrom(r.Context(), "")); err != nil {
+		adminError(w, http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// adminActor identifies who made an admin request: the authenticated identity if there is one,
+// or the client address.
+func adminActor(r *http.Request) string {
+	return ActorFrom(r.Context(), r.RemoteAddr)
+}
+
+// auditConf records a strand config as audit parameters.
+func auditConf(config StrandConf) map[string]string {
+	return map[string]string{
+		"durable":            strconv.FormatBool(config.Durable),
+		"ordered":            strconv.FormatBool(config.Ordered),
+		"replication_factor": strconv.Itoa(config.ReplicationFactor),
+		"max_bytes":          strconv.FormatInt(config.MaxBytes, 10),
+		"overflow":           config.Overflow,
+	}
+}
+
+// auditQuota records a namespace quota as audit parameters.
+func auditQuota(name string, quota NamespaceQuota) map[string]string {
+	return map[string]string{
+		"namespace":   name,
+		"max_strands": strconv.Itoa(quota.MaxStrands),
+		"max_bytes":   strconv.FormatInt(quota.MaxBytes, 10),
+		"max_rate":    strconv.FormatFloat(quota.MaxRate, 'g', -1, 64),
+	}
+}
+
+// adminLimit parses the limit query parameter.
+func adminLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return 100
+	}
+	return limit
+}
+
+// adminReply writes v as JSON, or err as a JSON error.
+func adminReply(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		adminError(w, adminStatusCode(err), err.Error())
+		return
+	}
+
+	adminWrite(w, http.StatusOK, v)
+}
+
+// adminStatusCode maps Conduktor errors to HTTP status codes.
+func adminStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrAuditAppendOnly) || errors.Is(err, ErrDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrStrandFull):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrMaintenance) || errors.Is(err, ErrStorePressure) || errors.Is(err, ErrShuttingDown) ||
+		errors.Is(err, ErrSchedulerStopped):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrPayloadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrSchemaMismatch):
+		return http.StatusUnprocessableEntity
+	case strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "does not exist"):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// adminWrite writes v as JSON with the given status.
+func adminWrite(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("Failed to encode admin response", zap.Error(err))
+	}
+}
+
+// adminError writes a JSON error with the given status.
+func adminError(w http.ResponseWriter, status int, message string) {
+	adminWrite(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,240 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jkassis/condukt/adminpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// adminGRPC implements the gRPC Admin service defined in proto/admin.proto.
+type adminGRPC struct {
+	adminpb.UnimplementedAdminServer
+	c *Conduktor
+}
+
+// AdminGRPCServerMake creates a gRPC server exposing the admin API for a Conduktor.
+func AdminGRPCServerMake(c *Conduktor, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	adminpb.RegisterAdminServer(server, &adminGRPC{c: c})
+	return server
+}
+
+// ListStrands lists strands with their configs and depths, optionally only those in one namespace.
+func (s *adminGRPC) ListStrands(ctx context.Context, req *adminpb.ListStrandsRequest) (*adminpb.ListStrandsResponse, error) {
+	strands, err := s.c.Strands()
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+
+	resp := &adminpb.ListStrandsResponse{}
+	for _, info := range strands {
+		if req.GetNamespace() != "" && Namespace(info.ID) != req.GetNamespace() {
+			continue
+		}
+		resp.Strands = append(resp.Strands, strandToPB(info))
+	}
+	return resp, nil
+}
+
+// CreateStrand creates a strand.
+func (s *adminGRPC) CreateStrand(ctx context.Context, req *adminpb.CreateStrandRequest) (*adminpb.Strand, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "strand id is required")
+	}
+	if err := s.c.Authorize(ctx, ActorFrom(ctx, ""), ACLAdmin, req.GetId()); err != nil {
+		return nil, adminStatus(err)
+	}
+
+	config := req.GetConfig()
+	conf := StrandConf{
+		Durable:           config.GetDurable(),
+		Ordered:           config.GetOrdered(),
+		ReplicationFactor: int(config.GetReplicationFactor()),
+		MaxBytes:          config.GetMaxBytes(),
+		Overflow:          config.GetOverflow(),
+	}
+	err := s.c.StrandAdd(req.GetId(), conf)
+	s.c.Audit(grpcActor(ctx), AuditStrandCreate, req.GetId(), auditConf(conf), err)
+	if errors.Is(err, ErrQuotaExceeded) {
+		return nil, adminStatus(err)
+	}
+	if err != nil {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	info, err := s.c.Strand(req.GetId())
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return strandToPB(info), nil
+}
+
+// GetStrand describes a strand.
+func (s *adminGRPC) GetStrand(ctx context.Context, req *adminpb.GetStrandRequest) (*adminpb.Strand, error) {
+	info, err := s.c.Strand(req.GetId())
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return strandToPB(info), nil
+}
+
+// DeleteStrand deletes a strand and its messages.
+func (s *adminGRPC) DeleteStrand(ctx context.Context, req *adminpb.DeleteStrandRequest) (*adminpb.DeleteStrandResponse, error) {
+	err := s.c.StrandRemove(req.GetId(), RemoveAs(ActorFrom(ctx, "")), RemoveContext(ctx))
+	s.c.Audit(grpcActor(ctx), AuditStrandDelete, req.GetId(), nil, err)
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return &adminpb.DeleteStrandResponse{}, nil
+}
+
+// PeekMessages returns unacked messages without consuming them.
+func (s *adminGRPC) PeekMessages(ctx context.Context, req *adminpb.PeekMessagesRequest) (*adminpb.PeekMessagesResponse, error) {
+	if err := s.c.Authorize(ctx, ActorFrom(ctx, ""), ACLAdmin, req.GetId()); err != nil {
+		return nil, adminStatus(err)
+	}
+	msgs, err := s.c.Peek(req.GetId(), grpcLimit(req.GetLimit()))
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return &adminpb.PeekMessagesResponse{Messages: msgsToPB(msgs)}, nil
+}
+
+// PurgeStrand deletes a strand's messages but keeps the strand.
+func (s *adminGRPC) PurgeStrand(ctx context.Context, req *adminpb.PurgeStrandRequest) (*adminpb.PurgeStrandResponse, error) {
+	if err := s.c.Authorize(ctx, ActorFrom(ctx, ""), ACLAdmin, req.GetId()); err != nil {
+		return nil, adminStatus(err)
+	}
+	purged, err := s.c.Purge(req.GetId())
+	s.c.Audit(grpcActor(ctx), AuditStrandPurge, req.GetId(), map[string]string{"purged": strconv.Itoa(purged)}, err)
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return &adminpb.PurgeStrandResponse{Purged: int64(purged)}, nil
+}
+
+// ListDeadLetters returns a strand's dead-lettered messages.
+func (s *adminGRPC) ListDeadLetters(ctx context.Context, req *adminpb.ListDeadLettersRequest) (*adminpb.ListDeadLettersResponse, error) {
+	if err := s.c.Authorize(ctx, ActorFrom(ctx, ""), ACLAdmin, req.GetId()); err != nil {
+		return nil, adminStatus(err)
+	}
+	msgs, err := s.c.DeadLetters(req.GetId(), grpcLimit(req.GetLimit()))
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	dead := make([]Msg, len(msgs))
+	for i, msg := range msgs {
+		dead[i] = msg.Msg
+	}
+	return &adminpb.ListDeadLettersResponse{Messages: msgsToPB(dead)}, nil
+}
+
+// Recover resends unacked durable messages.
+func (s *adminGRPC) Recover(ctx context.Context, req *adminpb.RecoverRequest) (*adminpb.RecoverResponse, error) {
+	if err := s.c.AuthorizeGlobal(ctx, ActorFrom(ctx, "")); err != nil {
+		return nil, adminStatus(err)
+	}
+	err := s.c.RecoverUnackedMessages()
+	s.c.Audit(grpcActor(ctx), AuditRecover, "", nil, err)
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return &adminpb.RecoverResponse{}, nil
+}
+
+// GetTopology describes the cluster topology.
+func (s *adminGRPC) GetTopology(ctx context.Context, req *adminpb.GetTopologyRequest) (*adminpb.Topology, error) {
+	topo := s.c.Topology()
+
+	resp := &adminpb.Topology{Self: topo.Self}
+	for _, node := range topo.Nodes {
+		resp.Nodes = append(resp.Nodes, &adminpb.NodeState{
+			Id:      node.ID,
+			Addr:    node.Addr,
+			Role:    string(node.Role),
+			Strands: node.Strands,
+		})
+	}
+	for _, m := range topo.Mirrors {
+		resp.Mirrors = append(resp.Mirrors, &adminpb.MirrorState{
+			Strand:      m.Strand,
+			Target:      m.Target,
+			LagMessages: int64(m.LagMsgs),
+			LagSeconds:  m.LagSeconds,
+		})
+	}
+	return resp, nil
+}
+
+// grpcActor identifies who made an admin call: the authenticated identity if there is one,
+// or the client address.
+func grpcActor(ctx context.Context) string {
+	fallback := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		fallback = p.Addr.String()
+	}
+	return ActorFrom(ctx, fallback)
+}
+
+// adminStatus maps Conduktor errors to gRPC status codes.
+func adminStatus(err error) error {
+	if errors.Is(err, ErrAuditAppendOnly) || errors.Is(err, ErrDenied) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, ErrMaintenance) || errors.Is(err, ErrStorePressure) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "does not exist") {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// grpcLimit applies the default peek limit.
+func grpcLimit(limit int32) int {
+	if limit <= 0 {
+		return 100
+	}
+	return int(limit)
+}
+
+// strandToPB converts a StrandInfo to its protobuf form.
+func strandToPB(info StrandInfo) *adminpb.Strand {
+	return &adminpb.Strand{
+		Id: info.ID,
+		Config: &adminpb.StrandConfig{
+			Durable:           info.Config.Durable,
+			Ordered:           info.Config.Ordered,
+			ReplicationFactor: int32(info.Config.ReplicationFactor),
+			MaxBytes:          info.Config.MaxBytes,
+			Overflow:          info.Config.Overflow,
+		},
+		Depth: int64(info.Depth),
+		Bytes: info.Bytes,
+	}
+}
+
+// msgsToPB converts messages to their protobuf form.
+func msgsToPB(msgs []Msg) []*adminpb.Message {
+	out := make([]*adminpb.Message, len(msgs))
+	for i, msg := range msgs {
+		out[i] = &adminpb.Message{
+			Id:        msg.ID,
+			Strand:    msg.Strand,
+			Payload:   msg.Payload,
+			Timestamp: msg.Timestamp,
+			Headers:   msg.Headers,
+		}
+	}
+	return out
+}
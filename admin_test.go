@@ -0,0 +1,1463 @@
+package condukt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/jkassis/condukt/adminpb"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// adminDo performs an admin request and decodes the JSON response into out.
+func adminDo(t *testing.T, h http.Handler, method, path, body string, out any) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+// Test Admin Strand Lifecycle
+func TestAdminStrands(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+
+	var info StrandInfo
+	assert.Equal(t, http.StatusCreated, adminDo(t, h, "POST", "/admin/strands", `{"id": "admin_channel", "config": {"Durable": true}}`, &info))
+	assert.True(t, info.Config.Durable)
+	assert.Equal(t, http.StatusConflict, adminDo(t, h, "POST", "/admin/strands", `{"id": "admin_channel", "config": {"Durable": true}}`, nil))
+
+	mq.Send("admin_channel", "Admin 1")
+	mq.Send("admin_channel", "Admin 2")
+
+	var strands []StrandInfo
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/strands", "", &strands))
+	if assert.Len(t, strands, 1) {
+		assert.Equal(t, 2, strands[0].Depth)
+	}
+
+	var msgs []Msg
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/strands/admin_channel/messages?limit=1", "", &msgs))
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, "Admin 1", msgs[0].Payload)
+		assert.NoError(t, mq.DeadLetter("admin_channel", msgs[0].ID, "poison"))
+	}
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/strands/admin_channel/dlq", "", &msgs))
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, "poison", msgs[0].Headers[HeaderDLQReason])
+	}
+
+	var purged map[string]int
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/admin_channel/purge", "", &purged))
+	assert.Equal(t, 1, purged["purged"])
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "DELETE", "/admin/strands/admin_channel", "", nil))
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "GET", "/admin/strands/admin_channel", "", nil))
+}
+
+// Test gRPC Admin Strand Lifecycle
+func TestAdminGRPC(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+
+	lis := bufconn.Listen(1 << 20)
+	server := AdminGRPCServerMake(mq)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := adminpb.NewAdminClient(conn)
+	ctx := context.Background()
+
+	strand, err := client.CreateStrand(ctx, &adminpb.CreateStrandRequest{Id: "grpc_channel", Config: &adminpb.StrandConfig{Durable: true}})
+	if assert.NoError(t, err) {
+		assert.True(t, strand.GetConfig().GetDurable())
+	}
+	_, err = client.CreateStrand(ctx, &adminpb.CreateStrandRequest{Id: "grpc_channel", Config: &adminpb.StrandConfig{Durable: true}})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	mq.Send("grpc_channel", "gRPC 1")
+	mq.Send("grpc_channel", "gRPC 2")
+
+	list, err := client.ListStrands(ctx, &adminpb.ListStrandsRequest{})
+	if assert.NoError(t, err) && assert.Len(t, list.GetStrands(), 1) {
+		assert.Equal(t, int64(2), list.GetStrands()[0].GetDepth())
+	}
+
+	peek, err := client.PeekMessages(ctx, &adminpb.PeekMessagesRequest{Id: "grpc_channel", Limit: 1})
+	if assert.NoError(t, err) && assert.Len(t, peek.GetMessages(), 1) {
+		assert.Equal(t, "gRPC 1", peek.GetMessages()[0].GetPayload())
+	}
+
+	purged, err := client.PurgeStrand(ctx, &adminpb.PurgeStrandRequest{Id: "grpc_channel"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2), purged.GetPurged())
+	}
+
+	_, err = client.DeleteStrand(ctx, &adminpb.DeleteStrandRequest{Id: "grpc_channel"})
+	assert.NoError(t, err)
+	_, err = client.GetStrand(ctx, &adminpb.GetStrandRequest{Id: "grpc_channel"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// Test Dashboard And Pause/Resume
+func TestAdminDashboard(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	mq.StrandAdd("dash_channel", StrandConf{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/dashboard", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/admin/dashboard/data")
+
+	// Paused strands store sends without delivering them
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/dash_channel/pause", "", nil))
+	mq.Send("dash_channel", "Held")
+	_, err := mq.Receive("dash_channel")
+	assert.Error(t, err)
+
+	var data DashboardData
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/dashboard/data", "", &data))
+	if assert.Len(t, data.Strands, 1) {
+		assert.True(t, data.Strands[0].Paused)
+		assert.Equal(t, 1, data.Strands[0].Depth)
+	}
+	if assert.Len(t, data.Wires, 1) {
+		assert.Equal(t, "primary", data.Wires[0].Role)
+		assert.Equal(t, "GoChanWire", data.Wires[0].Type)
+	}
+
+	// Resuming delivers the held messages
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/dash_channel/resume", "", nil))
+	msg, err := mq.Receive("dash_channel")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Held", msg.Payload)
+		assert.NoError(t, mq.Acknowledge("dash_channel", msg.ID))
+	}
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/dashboard/data", "", &data))
+	assert.Equal(t, float64(1), data.Metrics["dash_channel"].Acked)
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "POST", "/admin/strands/missing/pause", "", nil))
+}
+
+// Test The Browser Client Script And Its Query-Parameter Auth
+func TestClientScript(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	rec := httptest.NewRecorder()
+	ClientScriptHandler(mq).ServeHTTP(rec, httptest.NewRequest("GET", "/client.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/javascript; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "export class Condukt")
+
+	// Browsers pass their API key in the handshake's query, which only WebSocket upgrades accept
+	auth := AuthMake(mq, AuthConf{Keys: []AuthKey{{Name: "browser", Key: "browser-key", Scope: ScopeRead}}})
+	server := httptest.NewServer(ClientHandler(mq, auth))
+	defer server.Close()
+	url := "ws://" + server.Listener.Addr().String() + "/client"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if assert.Error(t, err) && assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?api_key=browser-key", nil)
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+	_, _, err = auth.Authenticate(httptest.NewRequest("GET", "/client?api_key=browser-key", nil))
+	assert.Error(t, err)
+}
+
+// Test Health Probes
+func TestHealth(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	health := HealthMake(mq)
+	h := health.Handler()
+
+	var reply struct {
+		OK     bool          `json:"ok"`
+		Checks []HealthCheck `json:"checks"`
+	}
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/livez", "", &reply))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/healthz", "", &reply))
+	assert.Len(t, reply.Checks, 2)
+
+	// Not ready until recovery completes and listeners serve
+	health.Listening("admin", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, adminDo(t, h, "GET", "/readyz", "", &reply))
+	assert.False(t, reply.OK)
+
+	assert.NoError(t, mq.RecoverUnackedMessages())
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/readyz", "", &reply))
+	assert.True(t, reply.OK)
+
+	health.Listening("admin", errors.New("listener closed"))
+	assert.Equal(t, http.StatusServiceUnavailable, adminDo(t, h, "GET", "/readyz", "", &reply))
+}
+
+// Test Metrics Server Scrape And Graceful Shutdown
+func TestMetricsServer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	metrics := MetricsServerMake(MetricsConfig{Path: "/scrape"})
+	stopped := make(chan error, 1)
+	go func() { stopped <- metrics.Serve(lis) }()
+
+	resp, err := http.Get("http://" + lis.Addr().String() + "/scrape")
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	assert.NoError(t, metrics.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-stopped, http.ErrServerClosed)
+}
+
+// Test Registering Metrics With An Embedding App's Registry
+func TestMetricsRegister(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	assert.NoError(t, MetricsRegister(reg, MetricsPrefix("condukt_"), MetricsLabels(map[string]string{"cluster": "east"})))
+	assert.Error(t, MetricsRegister(reg, MetricsPrefix("condukt_"), MetricsLabels(map[string]string{"cluster": "east"})), "metrics register twice")
+
+	// The app's own metric of the same name does not collide with condukt's
+	app := prometheus.NewCounter(prometheus.CounterOpts{Name: "messages_sent_total"})
+	assert.NoError(t, reg.Register(app))
+
+	messagesSent.WithLabelValues("metrics-register").Inc()
+	families, err := reg.Gather()
+	if !assert.NoError(t, err) {
+		return
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() != "condukt_messages_sent_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["channel"] == "metrics-register" {
+				found = true
+				assert.Equal(t, "east", labels["cluster"])
+			}
+		}
+	}
+	assert.True(t, found, "prefixed metric with const label")
+
+	// The dashboard still reads the unprefixed counters
+	strands, err := strandMetrics()
+	if assert.NoError(t, err) {
+		assert.Equal(t, float64(1), strands["metrics-register"].Sent)
+	}
+}
+
+// otlpCapture records the metrics MetricsOTLP exports.
+type otlpCapture struct {
+	exported *metricdata.ResourceMetrics
+}
+
+func (o *otlpCapture) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	o.exported = rm
+	return nil
+}
+
+func (o *otlpCapture) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Test Pushing Metrics To Pushgateway, OTLP, And StatsD
+func TestMetricsExport(t *testing.T) {
+	sent := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "sent_total"}, []string{"channel"})
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Buckets: []float64{0.1, 1}})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(sent, latency)
+	sent.WithLabelValues("export").Add(3)
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(5)
+	families, err := reg.Gather()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx := context.Background()
+
+	// StatsD sends counter increases since the previous export
+	lis, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer lis.Close()
+	statsd, err := MetricsStatsDMake(lis.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	buf := make([]byte, statsdPacket)
+	assert.NoError(t, statsd.Export(ctx, families))
+	n, _, err := lis.ReadFrom(buf)
+	if assert.NoError(t, err) {
+		lines := strings.Split(string(buf[:n]), "\n")
+		assert.Contains(t, lines, "sent_total:3|c|#channel:export")
+		assert.Contains(t, lines, "latency_seconds_count:3|c")
+	}
+	sent.WithLabelValues("export").Add(2)
+	families, _ = reg.Gather()
+	assert.NoError(t, statsd.Export(ctx, families))
+	n, _, err = lis.ReadFrom(buf)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "sent_total:2|c|#channel:export", string(buf[:n]))
+	}
+	assert.NoError(t, statsd.Shutdown(ctx))
+
+	// OTLP gets cumulative sums and per-bucket histogram counts
+	capture := &otlpCapture{}
+	assert.NoError(t, MetricsOTLPMake(capture, "condukt").Export(ctx, families))
+	if assert.NotNil(t, capture.exported) && assert.Len(t, capture.exported.ScopeMetrics, 1) {
+		for _, m := range capture.exported.ScopeMetrics[0].Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[float64]:
+				assert.Equal(t, "sent_total", m.Name)
+				assert.True(t, data.IsMonotonic)
+				assert.Equal(t, float64(5), data.DataPoints[0].Value)
+			case metricdata.Histogram[float64]:
+				assert.Equal(t, []float64{0.1, 1}, data.DataPoints[0].Bounds)
+				assert.Equal(t, []uint64{1, 1, 1}, data.DataPoints[0].BucketCounts)
+			}
+		}
+	}
+
+	// Pushgateway gets the Prometheus text format for the job
+	pushed := make(chan string, 1)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushed <- r.Method + " " + r.URL.Path
+	}))
+	defer gateway.Close()
+	exporter, err := MetricsExporterMake(ctx, MetricsExportConf{Exporter: MetricsExportPushgateway, Endpoint: gateway.URL})
+	if assert.NoError(t, err) {
+		assert.NoError(t, exporter.Export(ctx, families))
+		assert.Equal(t, "PUT /metrics/job/condukt", <-pushed)
+	}
+}
+
+// Test Audit Log Of Admin Operations
+func TestAudit(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	file, err := AuditFileMake(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+	strand, err := AuditStrandMake(mq)
+	if !assert.NoError(t, err) {
+		return
+	}
+	mq.SetAuditor(Auditors{file, strand})
+	h := AdminHandler(mq)
+
+	assert.Equal(t, http.StatusCreated, adminDo(t, h, "POST", "/admin/strands", `{"id": "audited_channel", "config": {"Durable": true}}`, nil))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/audited_channel/purge", "", nil))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "DELETE", "/admin/strands/audited_channel", "", nil))
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "DELETE", "/admin/strands/audited_channel", "", nil))
+
+	// The audit strand cannot be tampered with
+	assert.Equal(t, http.StatusForbidden, adminDo(t, h, "POST", "/admin/strands/_audit/purge", "", nil))
+	assert.Equal(t, http.StatusForbidden, adminDo(t, h, "DELETE", "/admin/strands/_audit", "", nil))
+
+	var msgs []Msg
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/strands/_audit/messages", "", &msgs))
+	var events []AuditEvent
+	for _, msg := range msgs {
+		var event AuditEvent
+		assert.NoError(t, json.Unmarshal([]byte(msg.Payload), &event))
+		events = append(events, event)
+	}
+	if assert.Len(t, events, 6) {
+		assert.Equal(t, AuditStrandCreate, events[0].Action)
+		assert.Equal(t, "true", events[0].Params["durable"])
+		assert.Equal(t, "192.0.2.1:1234", events[0].Actor)
+		assert.Equal(t, AuditStrandPurge, events[1].Action)
+		assert.Equal(t, AuditStrandDelete, events[2].Action)
+		assert.Empty(t, events[2].Error)
+		assert.NotEmpty(t, events[3].Error)
+	}
+
+	data, err := os.ReadFile(file.file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, 6, strings.Count(string(data), "\n"))
+}
+
+// Test Alert Webhooks Fire Once And Resolve
+func TestAlerts(t *testing.T) {
+	var events []AlertEvent
+	fail := false
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var event AlertEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+	}))
+	defer hook.Close()
+
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("alert_channel", StrandConf{})
+	a := &alerter{c: mq, conf: AlertConf{Webhook: hook.URL, DepthAbove: 1, UnackedAgeAbove: time.Minute}, client: hook.Client(), active: make(map[string]*alertState)}
+
+	mq.Send("alert_channel", "One")
+	mq.Send("alert_channel", "Two")
+	now := time.Now()
+	a.evaluate(now)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, AlertFiring, events[0].Status)
+		assert.Equal(t, AlertDepth, events[0].Alert)
+		assert.Equal(t, float64(2), events[0].Value)
+	}
+
+	// Still firing: deduplicated. Unacked age crosses its threshold but the webhook is down, so it is retried.
+	fail = true
+	a.evaluate(now.Add(2 * time.Minute))
+	assert.Len(t, events, 1)
+	fail = false
+	a.evaluate(now.Add(3 * time.Minute))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, AlertUnackedAge, events[1].Alert)
+	}
+
+	_, err := mq.Purge("alert_channel")
+	assert.NoError(t, err)
+	a.evaluate(now.Add(4 * time.Minute))
+	if assert.Len(t, events, 4) {
+		for _, event := range events[2:] {
+			assert.Equal(t, AlertResolved, event.Status)
+		}
+	}
+	assert.Empty(t, a.active)
+}
+
+// Test Runtime Log Level Control
+func TestAdminLogLevel(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	defer logLevel.SetLevel(logLevel.Level())
+
+	var reply map[string]string
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/loglevel", `{"level": "debug"}`, &reply))
+	assert.Equal(t, "debug", reply["level"])
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/loglevel", `{"level": "warn"}`, nil))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/loglevel", "", &reply))
+	assert.Equal(t, "warn", reply["level"])
+	assert.False(t, logger.Core().Enabled(zapcore.InfoLevel))
+
+	assert.Equal(t, http.StatusBadRequest, adminDo(t, h, "PUT", "/admin/loglevel", `{"level": "loud"}`, nil))
+}
+
+func TestNamespaces(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/namespaces/team-a", `{"max_strands": 2, "max_rate": 3}`, nil))
+	assert.Equal(t, http.StatusBadRequest, adminDo(t, h, "PUT", "/admin/namespaces/team-b", `{"max_strands": -1}`, nil))
+
+	// Strand quota
+	assert.NoError(t, mq.StrandAdd("team-a/orders", StrandConf{}))
+	assert.NoError(t, mq.StrandAdd("team-a/events", StrandConf{}))
+	assert.ErrorIs(t, mq.StrandAdd("team-a/third", StrandConf{}), ErrQuotaExceeded)
+	assert.Equal(t, http.StatusTooManyRequests, adminDo(t, h, "POST", "/admin/strands", `{"id": "team-a/third"}`, nil))
+	assert.NoError(t, mq.StrandAdd("team-b/orders", StrandConf{}), "other namespaces are unaffected")
+
+	// Rate quota: a burst of one second's worth, then rejections
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, mq.Send("team-a/events", "x"))
+	}
+	assert.ErrorIs(t, mq.Send("team-a/events", "x"), ErrQuotaExceeded)
+	assert.NoError(t, mq.Send("team-b/orders", "x"))
+
+	// Bytes quota
+	assert.NoError(t, mq.SetNamespace("team-a", NamespaceQuota{MaxBytes: 100}))
+	assert.ErrorIs(t, mq.Send("team-a/orders", strings.Repeat("x", 100)), ErrQuotaExceeded)
+	assert.Equal(t, float64(1), testutil.ToFloat64(namespaceRejections.WithLabelValues("team-a", QuotaBytes)))
+
+	var info NamespaceInfo
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/namespaces/team-a", "", &info))
+	assert.Equal(t, 2, info.Strands)
+	assert.Equal(t, 3, info.Depth)
+	assert.Greater(t, info.Bytes, int64(0))
+	assert.Equal(t, int64(100), info.Quota.MaxBytes)
+
+	var strands []StrandInfo
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/strands?namespace=team-b", "", &strands))
+	if assert.Len(t, strands, 1) {
+		assert.Equal(t, "team-b/orders", strands[0].ID)
+	}
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "DELETE", "/admin/namespaces/team-a", "", nil))
+	assert.NoError(t, mq.StrandAdd("team-a/third", StrandConf{}), "removing the quota lifts it")
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "DELETE", "/admin/namespaces/team-a", "", nil))
+}
+
+// auditMemory records audit events in memory.
+type auditMemory struct {
+	events []AuditEvent
+}
+
+func (a *auditMemory) Audit(event AuditEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+// Test Admin Authentication
+func TestAdminAuth(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	events := &auditMemory{}
+	mq.SetAuditor(events)
+	auth := AuthMake(mq, AuthConf{
+		Keys:  []AuthKey{{Name: "ops", Key: "admin-key", Scope: ScopeAdmin}, {Name: "viewer", Key: "read-key", Scope: ScopeRead}},
+		Users: []AuthUser{{Name: "alice", Password: "secret", Scope: ScopeAdmin}},
+	})
+	h := auth.Handler(AdminHandler(mq))
+
+	do := func(method, path, body string, header http.Header) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header = header
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	create := `{"id": "auth_channel"}`
+
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/admin/strands", "", http.Header{}))
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/admin/strands", "", http.Header{"X-Api-Key": {"wrong"}}))
+	assert.Equal(t, http.StatusOK, do("GET", "/admin/strands", "", http.Header{"X-Api-Key": {"read-key"}}))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/admin/strands", create, http.Header{"Authorization": {"Bearer read-key"}}))
+	assert.Equal(t, http.StatusCreated, do("POST", "/admin/strands", create, http.Header{"Authorization": {"Bearer admin-key"}}))
+
+	req := httptest.NewRequest("DELETE", "/admin/strands/auth_channel", nil)
+	req.SetBasicAuth("alice", "secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Failures are audited, and operations are attributed to the authenticated identity
+	actions := map[string]string{}
+	for _, event := range events.events {
+		if event.Action != AuditAuthSuccess {
+			actions[event.Action] = event.Actor
+		}
+	}
+	assert.Contains(t, actions, AuditAuthFailure)
+	assert.Equal(t, "ops", actions[AuditStrandCreate])
+	assert.Equal(t, "alice", actions[AuditStrandDelete])
+
+	// gRPC
+	lis := bufconn.Listen(1 << 20)
+	server := AdminGRPCServerMake(mq, grpc.UnaryInterceptor(auth.UnaryInterceptor()))
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := adminpb.NewAdminClient(conn)
+
+	_, err = client.ListStrands(context.Background(), &adminpb.ListStrandsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	readCtx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "read-key")
+	_, err = client.ListStrands(readCtx, &adminpb.ListStrandsRequest{})
+	assert.NoError(t, err)
+	_, err = client.CreateStrand(readCtx, &adminpb.CreateStrandRequest{Id: "auth_channel"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	adminCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer admin-key")
+	_, err = client.CreateStrand(adminCtx, &adminpb.CreateStrandRequest{Id: "auth_channel"})
+	assert.NoError(t, err)
+}
+
+// Test Per-Strand ACLs
+func TestACL(t *testing.T) {
+	acl := ACL{
+		{Identity: "producer", Strands: "team-a/*", Ops: []string{ACLPublish}},
+		{Identity: "*", Strands: "public", Ops: []string{ACLPublish, ACLSubscribe}},
+		{Identity: "ops", Strands: "*", Ops: []string{ACLAdmin}},
+	}
+	assert.NoError(t, acl.Validate())
+	assert.Error(t, ACL{{Identity: "x", Strands: "*", Ops: []string{"write"}}}.Validate())
+
+	wire := wire.WSWireMake()
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire)
+	mq.SetACL(acl)
+	mq.StrandAdd("team-a/orders", StrandConf{})
+	mq.StrandAdd("public", StrandConf{})
+
+	assert.NotErrorIs(t, mq.Send("team-a/orders", "x", SendAs("producer")), ErrDenied, "allowed, though there is no subscriber")
+	assert.ErrorIs(t, mq.Send("team-a/orders", "x", SendAs("stranger")), ErrDenied)
+	_, err := mq.Receive("team-a/orders", ReceiveAs("producer"))
+	assert.ErrorIs(t, err, ErrDenied)
+	assert.ErrorIs(t, mq.StrandRemove("public", RemoveAs("producer")), ErrDenied)
+	assert.NoError(t, mq.StrandRemove("public", RemoveAs("ops")))
+
+	// Roles grant their operations, admin changes to a strand included
+	acl = append(acl, ACLRule{Identity: "reader", Strands: "team-a/*", Role: RoleSubscriber}, ACLRule{Identity: "lead", Strands: "team-a/*", Role: RoleAdmin})
+	assert.NoError(t, mq.ACLSet(acl))
+	assert.Error(t, mq.ACLSet(ACL{{Identity: "x", Strands: "*", Role: "owner"}}))
+	assert.Len(t, mq.ACL(), 5)
+	assert.ErrorIs(t, mq.Send("team-a/orders", "x", SendAs("reader")), ErrDenied)
+	assert.NotErrorIs(t, mq.Send("team-a/orders", "x", SendAs("lead")), ErrDenied)
+	admin := AdminHandler(mq)
+	purge := func(actor string) int {
+		req := httptest.NewRequest("POST", "/admin/strands/team-a%2Forders/purge", nil)
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req.WithContext(WithActor(req.Context(), actor)))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusForbidden, purge("reader"))
+	assert.Equal(t, http.StatusOK, purge("lead"))
+
+	// Wire clients authenticate at the handshake and need subscribe on the strand
+	auth := AuthMake(mq, AuthConf{Keys: []AuthKey{{Name: "producer", Key: "producer-key", Scope: ScopeRead}}})
+	wire.SetAuthorizer(auth)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wire.HandleWebSocketConnection(w, r, strings.TrimPrefix(r.URL.Path, "/ws/"))
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/team-a/orders"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if assert.Error(t, err) && assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	_, resp, err = websocket.DefaultDialer.Dial(url, http.Header{"X-Api-Key": {"producer-key"}})
+	if assert.Error(t, err) && assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "producer may not subscribe")
+	}
+}
+
+// Test The ACL File Is Seeded, Reloaded When Edited, And Written By The Admin API
+func TestACLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acl.yaml")
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	stop, err := mq.ACLServe(path, ACL{{Identity: "app", Strands: "orders", Role: RolePublisher}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stop()
+	assert.True(t, mq.ACL().Allowed("app", ACLPublish, "orders"))
+	data, _ := os.ReadFile(path)
+	assert.Contains(t, string(data), "role: publisher")
+
+	// An edit is reloaded; an invalid one is ignored
+	assert.NoError(t, os.WriteFile(path, []byte("- {identity: app, strands: orders, role: subscriber}\n"), 0o600))
+	os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+	assert.Eventually(t, func() bool { return mq.ACL().Allowed("app", ACLSubscribe, "orders") }, 5*time.Second, 50*time.Millisecond)
+	assert.NoError(t, os.WriteFile(path, []byte("- {identity: app, role: subscriber}\n"), 0o600))
+	os.Chtimes(path, time.Now().Add(2*time.Minute), time.Now().Add(2*time.Minute))
+	time.Sleep(2 * aclPollInterval)
+	assert.True(t, mq.ACL().Allowed("app", ACLSubscribe, "orders"))
+
+	h := AdminHandler(mq)
+	var acl ACL
+	assert.Equal(t, http.StatusBadRequest, adminDo(t, h, "PUT", "/admin/acl", `[{"identity": "app", "strands": "orders"}]`, nil))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/acl", `[{"identity": "ops", "strands": "*", "role": "admin"}]`, &acl))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/acl", "", &acl))
+	assert.Equal(t, ACL{{Identity: "ops", Strands: "*", Role: RoleAdmin}}, acl)
+	data, _ = os.ReadFile(path)
+	assert.Contains(t, string(data), "identity: ops")
+}
+
+// kmsStub is an AWS KMS client whose ciphertexts are their plaintexts reversed, for tests.
+type kmsStub struct{}
+
+func (kmsStub) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	plain := slices.Clone(params.CiphertextBlob)
+	slices.Reverse(plain)
+	return &kms.DecryptOutput{Plaintext: plain}, nil
+}
+
+// Test Secrets Referenced By The Config Are Resolved From Env, Files, Vault, And AWS KMS
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	_, _, certPath := certIssue(t, dir, "server", &x509.Certificate{DNSNames: []string{"localhost"}}, nil, nil)
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/kv/data/condukt/api" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data": {"data": {"ops": "vault-key"}}}`))
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("CONDUKT_TEST_API_KEY", "env-key")
+	encryptionKey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	os.WriteFile(filepath.Join(dir, "encryption"), []byte(encryptionKey+"\n"), 0o600)
+	blob := []byte("kms-key")
+	slices.Reverse(blob)
+
+	cfg := ConfigDefault()
+	cfg.Secrets = SecretsConf{Dir: dir, Vault: VaultConf{Addr: vault.URL, Mount: "kv"}, AWSKMS: AWSKMSConf{Enabled: true}}
+	cfg.Listen.TLS = TLSConf{Cert: certPath, KeySecret: "file:server.key"}
+	cfg.Auth.Keys = []AuthKey{
+		{Name: "env", KeySecret: "env:CONDUKT_TEST_API_KEY", Scope: ScopeRead},
+		{Name: "vault", KeySecret: "vault:condukt/api#ops", Scope: ScopeAdmin},
+		{Name: "kms", KeySecret: "kms:" + base64.StdEncoding.EncodeToString(blob), Scope: ScopeRead},
+	}
+	cfg.Encryption.Keys = []StrandKey{{Strands: "*", ID: "1", Secret: "file:encryption"}}
+	assert.NoError(t, cfg.Validate())
+
+	secrets, err := SecretsMake(context.Background(), SecretsConf{Dir: dir, Vault: cfg.Secrets.Vault})
+	if !assert.NoError(t, err) {
+		return
+	}
+	secrets.SetProvider(SecretsAWSKMS, AWSKMSSecretsMake(kmsStub{}))
+	if !assert.NoError(t, cfg.SecretsResolve(context.Background(), secrets)) {
+		return
+	}
+	assert.Equal(t, []string{"env-key", "vault-key", "kms-key"}, []string{cfg.Auth.Keys[0].Key, cfg.Auth.Keys[1].Key, cfg.Auth.Keys[2].Key})
+	assert.Equal(t, encryptionKey, cfg.Encryption.Keys[0].Key)
+	_, err = cfg.Encryption.KeyRing(context.Background())
+	assert.NoError(t, err)
+	config, err := cfg.Listen.TLS.Config()
+	if assert.NoError(t, err) {
+		assert.Len(t, config.Certificates, 1)
+	}
+
+	// Unresolvable and unconfigured references are reported with their setting
+	cfg.Auth.Keys = []AuthKey{{Name: "missing", KeySecret: "vault:condukt/missing", Scope: ScopeRead}}
+	assert.ErrorContains(t, cfg.SecretsResolve(context.Background(), secrets), "auth.keys[0].key_secret")
+	cfg.Secrets = SecretsConf{}
+	assert.ErrorContains(t, cfg.Validate(), "requires secrets.vault.addr")
+}
+
+// Test IP Filters Close Rejected Connections Before Serving And Count Them
+func TestIPFilter(t *testing.T) {
+	_, err := IPFilterMake("admin", IPFilterConf{Allow: []string{"10.0.0.0/8", "bogus"}})
+	assert.ErrorContains(t, err, "allow[1]")
+	filter, err := IPFilterMake("admin", IPFilterConf{Allow: []string{"10.0.0.0/8", "::1"}, Deny: []string{"10.6.0.0/16"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for addr, allowed := range map[string]bool{"10.1.2.3": true, "::ffff:10.1.2.3": true, "10.6.0.1": false, "::1": true, "192.168.1.1": false} {
+		assert.Equal(t, allowed, filter.Allowed(netip.MustParseAddr(addr)), addr)
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(listenerRejections.WithLabelValues("admin")))
+
+	// A denied client's connection is closed unanswered
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	filter, _ = IPFilterMake("clients", IPFilterConf{Deny: []string{"127.0.0.0/8"}})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Listener.Close()
+	server.Listener = filter.Listener(lis)
+	server.Start()
+	defer server.Close()
+	_, err = http.Get(server.URL)
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(listenerRejections.WithLabelValues("clients")))
+}
+
+// Test JWTs Signed With A Secret Or A Key Set Key Authenticate, Their Claims Limiting The Identity
+func TestAuthJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b64 := base64.RawURLEncoding
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": b64.EncodeToString(rsaKey.N.Bytes()), "e": b64.EncodeToString([]byte{1, 0, 1}),
+		}}})
+	}))
+	defer jwksServer.Close()
+
+	secret := strings.Repeat("s", jwtSecretMin)
+	sign := func(claims jwt.MapClaims) string {
+		if _, ok := claims["exp"]; !ok {
+			claims["exp"] = time.Now().Add(time.Hour).Unix()
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		assert.NoError(t, err)
+		return token
+	}
+
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("team-a/orders", StrandConf{})
+	mq.StrandAdd("team-b/orders", StrandConf{})
+	auth := AuthMake(mq, AuthConf{JWT: JWTConf{Secret: secret, JWKSURL: jwksServer.URL, Issuer: "idp"}})
+	admin := auth.Handler(AdminHandler(mq))
+	do := func(h http.Handler, method, path, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"id": "jwt_channel"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Admin API scopes come from the scope claim
+	assert.Equal(t, http.StatusOK, do(admin, "GET", "/admin/strands", sign(jwt.MapClaims{"sub": "viewer", "iss": "idp", "scope": "read"})))
+	assert.Equal(t, http.StatusForbidden, do(admin, "POST", "/admin/strands", sign(jwt.MapClaims{"sub": "viewer", "iss": "idp", "scope": "read"})))
+	assert.Equal(t, http.StatusForbidden, do(admin, "GET", "/admin/strands", sign(jwt.MapClaims{"sub": "app", "iss": "idp", "scope": "publish"})))
+	assert.Equal(t, http.StatusUnauthorized, do(admin, "GET", "/admin/strands", sign(jwt.MapClaims{"sub": "viewer", "iss": "other", "scope": "read"})))
+	assert.Equal(t, http.StatusUnauthorized, do(admin, "GET", "/admin/strands", sign(jwt.MapClaims{"sub": "viewer", "iss": "idp", "scope": "read", "exp": time.Now().Add(-time.Minute).Unix()})))
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "ops", "iss": "idp", "scope": "admin", "exp": time.Now().Add(time.Hour).Unix()}).SignedString([]byte(strings.Repeat("x", jwtSecretMin)))
+	assert.Equal(t, http.StatusUnauthorized, do(admin, "POST", "/admin/strands", forged))
+
+	// Key set tokens are verified with the key of their kid
+	rsaToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "ops", "iss": "idp", "scope": []string{"admin"}, "exp": time.Now().Add(time.Hour).Unix()})
+	rsaToken.Header["kid"] = "k1"
+	signed, err := rsaToken.SignedString(rsaKey)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, do(admin, "POST", "/admin/strands", signed))
+
+	// Ingest takes the namespace and operation scopes of the token's identity
+	ingest := IngestHandler(mq, auth)
+	app := sign(jwt.MapClaims{"sub": "app", "iss": "idp", "scope": "publish", "namespace": "team-a"})
+	assert.Equal(t, http.StatusCreated, do(ingest, "POST", "/strands/team-a%2Forders/messages", app))
+	assert.Equal(t, http.StatusForbidden, do(ingest, "POST", "/strands/team-b%2Forders/messages", app))
+
+	// Schedules publish as whoever set them, so they may only target strands that identity may publish to
+	stop, err := mq.SchedulerServe(SchedulerConf{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stop()
+	scheduler := sign(jwt.MapClaims{"sub": "scheduler", "iss": "idp", "scope": "admin publish", "namespace": "team-a"})
+	schedule := func(strandID string) int {
+		req := httptest.NewRequest("PUT", "/admin/schedules/tick", strings.NewReader(`{"cron": "@hourly", "strand": "`+strandID+`", "identity": "ops"}`))
+		req.Header.Set("Authorization", "Bearer "+scheduler)
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusForbidden, schedule("team-b/orders"))
+	assert.Equal(t, http.StatusOK, schedule("team-a/orders"))
+	info, err := mq.ScheduleGet("tick")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "scheduler", info.Identity, "the caller, not the identity in the body")
+	}
+
+	// Browsers pass their token in the handshake's query
+	r := httptest.NewRequest("GET", "/client?access_token="+app, nil)
+	r.Header.Set("Connection", "upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	identity, appCtx, err := auth.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "app", identity)
+	claims, ok := ClaimsFrom(appCtx)
+	if assert.True(t, ok) {
+		assert.Equal(t, "team-a", claims.Namespace)
+		assert.Equal(t, []string{"publish"}, claims.Scopes)
+	}
+	_, err = mq.Receive("team-a/orders", ReceiveAs("app"), ReceiveContext(appCtx))
+	assert.ErrorIs(t, err, ErrDenied, "the token does not grant subscribe")
+
+	// Claims stay with the request or connection that presented them: another token for the same
+	// subject, in use at the same time, neither widens nor narrows them
+	r = httptest.NewRequest("GET", "/client", nil)
+	r.Header.Set("Authorization", "Bearer "+sign(jwt.MapClaims{"sub": "app", "iss": "idp", "scope": "publish subscribe"}))
+	_, wideCtx, err := auth.Authenticate(r)
+	assert.NoError(t, err)
+	assert.NoError(t, mq.Authorize(wideCtx, "app", ACLSubscribe, "team-b/orders"))
+	assert.ErrorIs(t, mq.Authorize(appCtx, "app", ACLSubscribe, "team-a/orders"), ErrDenied)
+	assert.ErrorIs(t, mq.Authorize(appCtx, "app", ACLPublish, "team-b/orders"), ErrDenied)
+	assert.NoError(t, mq.Authorize(appCtx, "app", ACLPublish, "team-a/orders"))
+	assert.Equal(t, http.StatusForbidden, do(ingest, "POST", "/strands/team-b%2Forders/messages", app))
+
+	// Nor do they apply to an identity of the same name that authenticated otherwise
+	assert.NoError(t, mq.Authorize(context.Background(), "app", ACLSubscribe, "team-b/orders"))
+}
+
+// Test A Token Limited To A Namespace Can Neither Change Broker-Wide Settings Nor Read Other Namespaces
+func TestAuthJWTNamespace(t *testing.T) {
+	secret := strings.Repeat("s", jwtSecretMin)
+	sign := func(claims jwt.MapClaims) string {
+		claims["iss"], claims["exp"] = "idp", time.Now().Add(time.Hour).Unix()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		assert.NoError(t, err)
+		return token
+	}
+
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("team-a/orders", StrandConf{})
+	mq.StrandAdd("team-b/orders", StrandConf{})
+	mq.Send("team-a/orders", "A 1")
+	mq.Send("team-b/orders", "B 1")
+	stop, err := mq.SchedulerServe(SchedulerConf{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stop()
+	assert.NoError(t, mq.ScheduleSet(ScheduleConf{Name: "b-tick", Cron: "@hourly", Strand: "team-b/orders"}))
+	msgID := func(strandID string) string {
+		msgs, err := mq.Peek(strandID, 1)
+		if assert.NoError(t, err) && assert.Len(t, msgs, 1) {
+			return msgs[0].ID
+		}
+		return ""
+	}
+	aID, bID := msgID("team-a/orders"), msgID("team-b/orders")
+
+	auth := AuthMake(mq, AuthConf{JWT: JWTConf{Secret: secret, Issuer: "idp"}})
+	admin := auth.Handler(AdminHandler(mq))
+	teamA := sign(jwt.MapClaims{"sub": "lead-a", "scope": "admin", "namespace": "team-a"})
+	ops := sign(jwt.MapClaims{"sub": "ops", "scope": "admin publish"})
+	do := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Broker-wide changes affect every namespace
+	global := []struct{ method, path, body string }{
+		{"PUT", "/admin/acl", `[]`},
+		{"PUT", "/admin/namespaces/team-b", `{"max_strands": 1}`},
+		{"DELETE", "/admin/namespaces/team-b", ``},
+		{"PUT", "/admin/maintenance", `{"enabled": false}`},
+		{"POST", "/admin/recover", ``},
+		{"PUT", "/admin/loglevel", `{"level": "info"}`},
+	}
+	for _, route := range global {
+		assert.Equal(t, http.StatusForbidden, do(route.method, route.path, route.body, teamA), route.method+" "+route.path)
+		assert.Equal(t, http.StatusOK, do(route.method, route.path, route.body, ops), route.method+" "+route.path)
+	}
+
+	// Payloads and schedules are only visible and changeable within the token's namespace
+	for _, route := range []string{"messages", "dlq"} {
+		assert.Equal(t, http.StatusOK, do("GET", "/admin/strands/team-a%2Forders/"+route, "", teamA), route)
+		assert.Equal(t, http.StatusForbidden, do("GET", "/admin/strands/team-b%2Forders/"+route, "", teamA), route)
+	}
+	assert.Equal(t, http.StatusOK, do("GET", "/admin/messages/"+aID+"/trace", "", teamA))
+	assert.Equal(t, http.StatusForbidden, do("GET", "/admin/messages/"+bID+"/trace", "", teamA))
+	assert.Equal(t, http.StatusForbidden, do("DELETE", "/admin/schedules/b-tick", "", teamA))
+	assert.Equal(t, http.StatusForbidden, do("PUT", "/admin/schedules/b-tick", `{"cron": "@hourly", "strand": "team-a/orders"}`, teamA))
+	assert.Equal(t, http.StatusOK, do("DELETE", "/admin/schedules/b-tick", "", ops))
+
+	// The gRPC admin API applies the same limits
+	server := &adminGRPC{c: mq}
+	ctx := WithClaims(WithActor(context.Background(), "lead-a"), IdentityClaims{Subject: "lead-a", Scopes: []string{ScopeAdmin}, Namespace: "team-a", Expires: time.Now().Add(time.Hour)})
+	_, err = server.PeekMessages(ctx, &adminpb.PeekMessagesRequest{Id: "team-a/orders"})
+	assert.NoError(t, err)
+	_, err = server.PeekMessages(ctx, &adminpb.PeekMessagesRequest{Id: "team-b/orders"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.ListDeadLetters(ctx, &adminpb.ListDeadLettersRequest{Id: "team-b/orders"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.Recover(ctx, &adminpb.RecoverRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// certIssue writes a PEM certificate for template, signed by parent's key or else self-signed, and
+// its key to dir, returning the certificate, its key, and the certificate's path.
+func certIssue(t *testing.T, dir, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore, template.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	path := filepath.Join(dir, name+".crt")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return cert, key, path
+}
+
+// Test Repeated Failures Are Audited With Their Source And Lock It Out For A While
+func TestAuthLockout(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake(), MakeClock(func() time.Time { return now }))
+	events := &auditMemory{}
+	mq.SetAuditor(events)
+	auth := AuthMake(mq, AuthConf{
+		Users:   []AuthUser{{Name: "alice", Password: "secret", Scope: ScopeAdmin}},
+		Lockout: AuthLockoutConf{Threshold: 3, Window: time.Minute, Duration: 10 * time.Minute},
+	})
+	h := auth.Handler(AdminHandler(mq))
+	do := func(remote, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/strands", nil)
+		req.RemoteAddr = remote
+		req.SetBasicAuth("alice", password)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Failures spread past the window do not add up, and a success forgets them
+	assert.Equal(t, http.StatusUnauthorized, do("198.51.100.7:4000", "guess1").Code)
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, http.StatusUnauthorized, do("198.51.100.7:4001", "guess2").Code)
+	assert.Equal(t, http.StatusOK, do("198.51.100.7:4002", "secret").Code)
+	before := testutil.ToFloat64(authLockouts)
+	for i := range 3 {
+		assert.Equal(t, http.StatusUnauthorized, do("198.51.100.7:4003", "guess"+strconv.Itoa(i)).Code)
+	}
+	assert.Equal(t, before+1, testutil.ToFloat64(authLockouts))
+
+	// The source is refused even with valid credentials, until its lockout ends; others are not
+	rec := do("198.51.100.7:4004", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "601", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, do("203.0.113.9:4000", "secret").Code)
+	_, _, err := auth.AuthenticateGRPC(peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.7"), Port: 5000}}))
+	assert.ErrorIs(t, err, ErrLockedOut)
+	now = now.Add(10 * time.Minute)
+	assert.Equal(t, http.StatusOK, do("198.51.100.7:4005", "secret").Code)
+
+	var failure, lockout *AuditEvent
+	for i, event := range events.events {
+		switch event.Action {
+		case AuditAuthFailure:
+			failure = &events.events[i]
+		case AuditAuthLockout:
+			lockout = &events.events[i]
+		}
+	}
+	if assert.NotNil(t, failure) && assert.NotNil(t, lockout) {
+		assert.Equal(t, map[string]string{"operation": "GET /admin/strands", "scope": ScopeRead, "ip": "198.51.100.7", "identity": "alice"}, failure.Params)
+		assert.Equal(t, "198.51.100.7", lockout.Params["ip"])
+	}
+}
+
+// Test Verified Client Certificates Authenticate As The Identity Of Their Subject Or SAN
+func TestAuthCert(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caPath := certIssue(t, dir, "ca", &x509.Certificate{Subject: pkix.Name{CommonName: "condukt-ca"}, IsCA: true, KeyUsage: x509.KeyUsageCertSign, BasicConstraintsValid: true}, nil, nil)
+	_, _, serverPath := certIssue(t, dir, "server", &x509.Certificate{DNSNames: []string{"localhost"}, IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca, caKey)
+	spiffe, _ := url.Parse("spiffe://shop/billing")
+	client, clientKey, _ := certIssue(t, dir, "client", &x509.Certificate{Subject: pkix.Name{CommonName: "billing-worker"}, URIs: []*url.URL{spiffe}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, ca, caKey)
+	assert.Equal(t, "billing-worker", certIdentity(client, CertIdentityCN))
+	assert.Equal(t, "spiffe://shop/billing", certIdentity(client, CertIdentityURI))
+	assert.Empty(t, certIdentity(client, CertIdentityEmail))
+
+	conf := TLSConf{Cert: serverPath, Key: filepath.Join(dir, "server.key"), ClientCA: caPath, ClientAuth: TLSClientVerify}
+	assert.NoError(t, conf.Validate())
+	serverTLS, err := conf.Config()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, tls.VerifyClientCertIfGiven, serverTLS.ClientAuth)
+
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("billing", StrandConf{})
+	mq.SetACL(ACL{{Identity: "spiffe://shop/*", Strands: "billing", Ops: []string{ACLPublish}}})
+	auth := AuthMake(mq, AuthConf{CertIdentity: CertIdentityURI, Certs: []AuthCert{{Name: "spiffe://shop/*", Scope: ScopeRead}}})
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", auth.Handler(AdminHandler(mq)))
+	mux.Handle("/strands/", IngestHandler(mq, auth))
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	get := func(method, path string, certs ...tls.Certificate) int {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader("x"))
+		resp, err := httpClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	clientCert := tls.Certificate{Certificate: [][]byte{client.Raw}, PrivateKey: clientKey}
+	assert.Equal(t, http.StatusUnauthorized, get("GET", "/admin/strands"))
+	assert.Equal(t, http.StatusOK, get("GET", "/admin/strands", clientCert))
+	assert.Equal(t, http.StatusForbidden, get("POST", "/admin/strands", clientCert))
+	assert.Equal(t, http.StatusCreated, get("POST", "/strands/billing/messages", clientCert))
+}
+
+// Test Changed Certificate Files Serve New Connections Without Dropping Open Ones
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, _ := certIssue(t, dir, "ca", &x509.Certificate{Subject: pkix.Name{CommonName: "condukt-ca"}, IsCA: true, KeyUsage: x509.KeyUsageCertSign, BasicConstraintsValid: true}, nil, nil)
+	issue := func(cn string) string {
+		_, _, path := certIssue(t, dir, "server", &x509.Certificate{Subject: pkix.Name{CommonName: cn}, IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca, caKey)
+		return path
+	}
+	conf := TLSConf{Cert: issue("first"), Key: filepath.Join(dir, "server.key"), Reload: 10 * time.Millisecond}
+	assert.NoError(t, conf.Validate())
+	serverTLS, stop, err := conf.ConfigServe()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stop()
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn)
+		}
+	}()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	dial := func() (*tls.Conn, string) {
+		conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{RootCAs: roots})
+		if err != nil {
+			return nil, ""
+		}
+		return conn, conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	open, cn := dial()
+	if !assert.NotNil(t, open) {
+		return
+	}
+	defer open.Close()
+	assert.Equal(t, "first", cn)
+
+	// Replace the certificate; new handshakes present it, the open connection keeps working
+	issue("second")
+	later := time.Now().Add(time.Second)
+	os.Chtimes(conf.Cert, later, later)
+	os.Chtimes(conf.Key, later, later)
+	assert.Eventually(t, func() bool {
+		conn, cn := dial()
+		if conn != nil {
+			conn.Close()
+		}
+		return cn == "second"
+	}, 2*time.Second, 10*time.Millisecond)
+	_, err = open.Write([]byte("ping"))
+	assert.NoError(t, err)
+	echo := make([]byte, 4)
+	_, err = io.ReadFull(open, echo)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(echo))
+
+	// A broken certificate is not loaded
+	os.WriteFile(conf.Cert, []byte("garbage"), 0o600)
+	later = later.Add(time.Second)
+	os.Chtimes(conf.Cert, later, later)
+	time.Sleep(50 * time.Millisecond)
+	conn, cn := dial()
+	if assert.NotNil(t, conn) {
+		conn.Close()
+	}
+	assert.Equal(t, "second", cn)
+
+	acmeConf := TLSConf{ACME: ACMEConf{Domains: []string{"mq.example.com"}}, Cert: "server.crt", Key: "server.key"}
+	if err := acmeConf.Validate(); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "acme: cert and acme are exclusive")
+		assert.Contains(t, err.Error(), "acme.cache_dir: required")
+	}
+	acmeConf = TLSConf{ACME: ACMEConf{Domains: []string{"mq.example.com"}, CacheDir: dir}}
+	assert.NoError(t, acmeConf.Validate())
+	acmeTLS, err := acmeConf.Config()
+	if assert.NoError(t, err) {
+		assert.NotNil(t, acmeTLS.GetCertificate)
+		assert.Contains(t, acmeTLS.NextProtos, "acme-tls/1")
+	}
+}
+
+// Test Publishing Over HTTP, One Message And An NDJSON Batch
+func TestIngest(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("team-a/orders", StrandConf{})
+	mq.SetACL(ACL{{Identity: "script", Strands: "team-a/*", Ops: []string{ACLPublish}}})
+	auth := AuthMake(mq, AuthConf{Keys: []AuthKey{{Name: "script", Key: "script-key", Scope: ScopeRead}}})
+	h := IngestHandler(mq, auth)
+	ingest := func(body, contentType string, out any) int {
+		req := httptest.NewRequest("POST", "/strands/team-a%2Forders/messages", strings.NewReader(body))
+		req.Header.Set("X-Api-Key", "script-key")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Msg-Order-Id", "42")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+		return rec.Code
+	}
+
+	var reply struct {
+		Sent  int    `json:"sent"`
+		Error string `json:"error"`
+	}
+	assert.Equal(t, http.StatusCreated, ingest(`{"webhook": true}`, "application/json", &reply))
+	assert.Equal(t, 1, reply.Sent)
+	msgs, _ := mq.Peek("team-a/orders", 0)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, `{"webhook": true}`, msgs[0].Payload, "the body is the payload")
+		assert.Equal(t, "42", msgs[0].Headers["order-id"])
+	}
+
+	batch := `{"payload": "one", "headers": {"k": "v"}}` + "\n" + `{"payload": "two"}` + "\n"
+	assert.Equal(t, http.StatusCreated, ingest(batch, "application/x-ndjson", &reply))
+	assert.Equal(t, 2, reply.Sent)
+	msgs, _ = mq.Peek("team-a/orders", 0)
+	if assert.Len(t, msgs, 3) {
+		assert.Equal(t, "one", msgs[1].Payload)
+		assert.Equal(t, "v", msgs[1].Headers["k"])
+		assert.Empty(t, msgs[2].Headers["order-id"], "batch records carry their own headers")
+	}
+
+	// A batch stops at its first bad record, keeping those before it
+	reply.Sent = 0
+	assert.Equal(t, http.StatusBadRequest, ingest(`{"payload": "three"}`+"\n"+`{"payload": `, "application/x-ndjson", &reply))
+	assert.Equal(t, 1, reply.Sent)
+	assert.NotEmpty(t, reply.Error)
+
+	mq.SetLimits(Limits{MaxPayloadBytes: 4})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, ingest("too large", "text/plain", &reply))
+	assert.Equal(t, 0, reply.Sent)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/strands/team-a%2Forders/messages", strings.NewReader("x")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mq.SetACL(ACL{{Identity: "script", Strands: "other", Ops: []string{ACLPublish}}})
+	assert.Equal(t, http.StatusForbidden, ingest("x", "text/plain", &reply))
+}
+
+// Test Message Tracing
+func TestMessageTrace(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	mq.StrandAdd("trace_channel", StrandConf{})
+	mq.Send("trace_channel", "Trace 1")
+	mq.Send("trace_channel", "Trace 2")
+
+	msg, err := mq.Receive("trace_channel")
+	assert.NoError(t, err)
+	var trace MsgTrace
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/messages/"+msg.ID+"/trace", "", &trace))
+	assert.Equal(t, DeliveryInFlight, trace.State)
+	assert.Equal(t, "volatile", trace.Store)
+	assert.Equal(t, 1, trace.Attempts)
+	assert.NotZero(t, trace.Received)
+	if assert.NotNil(t, trace.Message) {
+		assert.Equal(t, "Trace 1", trace.Message.Payload)
+	}
+
+	assert.NoError(t, mq.Acknowledge("trace_channel", msg.ID))
+	trace, err = mq.Trace(msg.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryAcked, trace.State)
+	assert.Empty(t, trace.Store)
+	assert.Nil(t, trace.Message)
+
+	msg, _ = mq.Receive("trace_channel")
+	assert.NoError(t, mq.DeadLetter("trace_channel", msg.ID, "poison"))
+	trace, err = mq.Trace(msg.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, DeliveryDeadLettered, trace.State)
+	assert.Equal(t, "trace_channel.dlq", trace.DLQStrand)
+	assert.Equal(t, "poison", trace.DLQReason)
+	assert.Equal(t, "volatile", trace.Store)
+
+	// Messages stored before the Conduktor started are found in the stores
+	fresh := ConduktorMake(mq.volatile, store.RamStoreMake(), wire.GoChanWireMake())
+	trace, err = fresh.Trace(msg.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "trace_channel.dlq", trace.Strand)
+
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "GET", "/admin/messages/missing/trace", "", nil))
+}
+
+// Test Dead-Letter Redrive and Purge
+func TestDeadLetterRedrive(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	mq.StrandAdd("redrive_channel", StrandConf{})
+	for _, payload := range []string{"Redrive 1", "Redrive 2", "Redrive 3"} {
+		mq.Send("redrive_channel", payload)
+	}
+	msgs, _ := mq.Peek("redrive_channel", 0)
+	for _, msg := range msgs {
+		assert.NoError(t, mq.DeadLetter("redrive_channel", msg.ID, "timeout"))
+	}
+
+	var dead []DeadLetterMsg
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/strands/redrive_channel/dlq", "", &dead))
+	if assert.Len(t, dead, 3) {
+		assert.Equal(t, "timeout", dead[0].Reason)
+		assert.Equal(t, "redrive_channel", dead[0].Source)
+	}
+
+	// Redrive one message with an edited header
+	var redriven map[string]int
+	body := `{"ids": ["` + msgs[0].ID + `"], "headers": {"x-fixed": "yes"}}`
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/redrive_channel/dlq/redrive", body, &redriven))
+	assert.Equal(t, 1, redriven["redriven"])
+	back, _ := mq.Peek("redrive_channel", 0)
+	if assert.Len(t, back, 1) {
+		assert.Equal(t, msgs[0].ID, back[0].ID)
+		assert.Equal(t, "yes", back[0].Headers["x-fixed"])
+		assert.NotContains(t, back[0].Headers, HeaderDLQReason)
+	}
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "POST", "/admin/strands/redrive_channel/dlq/redrive", body, nil))
+
+	// Purge one, then redrive the rest
+	var purged map[string]int
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/redrive_channel/dlq/purge", `{"ids": ["`+msgs[1].ID+`"]}`, &purged))
+	assert.Equal(t, 1, purged["purged"])
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "POST", "/admin/strands/redrive_channel/dlq/redrive", "", &redriven))
+	assert.Equal(t, 1, redriven["redriven"])
+	back, _ = mq.Peek("redrive_channel", 0)
+	assert.Len(t, back, 2)
+	dead, _ = mq.DeadLetters("redrive_channel", 0)
+	assert.Empty(t, dead)
+}
+
+// Test Maintenance Mode
+func TestMaintenance(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	mq.StrandAdd("maint_channel", StrandConf{})
+	mq.StrandAdd("other_channel", StrandConf{})
+	mq.Send("maint_channel", "Before")
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/strands/maint_channel/maintenance", `{"enabled": true}`, nil))
+	assert.ErrorIs(t, mq.Send("maint_channel", "During"), ErrMaintenance)
+	assert.NoError(t, mq.Send("other_channel", "Elsewhere"))
+
+	// Consumers still drain
+	msg, err := mq.Receive("maint_channel")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Before", msg.Payload)
+		assert.NoError(t, mq.Acknowledge("maint_channel", msg.ID))
+	}
+
+	var info MaintenanceInfo
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/maintenance", `{"enabled": true}`, &info))
+	assert.Equal(t, MaintenanceInfo{Enabled: true, Strands: []string{"maint_channel"}}, info)
+	assert.ErrorIs(t, mq.Send("other_channel", "During"), ErrMaintenance)
+	strand, _ := mq.Strand("other_channel")
+	assert.True(t, strand.Maintenance)
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/maintenance", `{"enabled": false}`, nil))
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "PUT", "/admin/strands/maint_channel/maintenance", `{"enabled": false}`, nil))
+	assert.NoError(t, mq.Send("maint_channel", "After"))
+	assert.Equal(t, http.StatusNotFound, adminDo(t, h, "PUT", "/admin/strands/missing/maintenance", `{"enabled": true}`, nil))
+}
+
+// Test Message Event Log
+func TestEventLog(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	assert.Equal(t, http.StatusNotImplemented, adminDo(t, h, "GET", "/admin/events", "", nil))
+
+	events, err := EventFileMake(filepath.Join(t.TempDir(), "events.jsonl"))
+	assert.NoError(t, err)
+	defer events.Close()
+	mq.SetEventLog(events)
+
+	mq.StrandAdd("events_channel", StrandConf{})
+	mq.Send("events_channel", "Events 1")
+	mq.Send("events_channel", "Events 2")
+	assert.NoError(t, mq.RecoverUnackedMessages())
+	first, _ := mq.Receive("events_channel")
+	assert.NoError(t, mq.Acknowledge("events_channel", first.ID))
+	msgs, _ := mq.Peek("events_channel", 1)
+	assert.NoError(t, mq.DeadLetter("events_channel", msgs[0].ID, "poison"))
+
+	var got []MsgEvent
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/events?msg="+first.ID, "", &got))
+	kinds := []string{}
+	for _, event := range got {
+		kinds = append(kinds, event.Event)
+	}
+	assert.Equal(t, []string{EventSaved, EventSent, EventRedelivered, EventReceived, EventAcked}, kinds)
+	assert.Equal(t, 2, got[2].Attempt)
+
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/events?msg="+msgs[0].ID+"&limit=10", "", &got))
+	if assert.NotEmpty(t, got) {
+		assert.Equal(t, EventDeadLettered, got[len(got)-1].Event)
+		assert.Equal(t, "poison", got[len(got)-1].Reason)
+	}
+	assert.Equal(t, http.StatusBadRequest, adminDo(t, h, "GET", "/admin/events?since=yesterday", "", nil))
+}
+
+// Test Consumer Lag
+func TestConsumerLag(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	h := AdminHandler(mq)
+	mq.StrandAdd("lag_channel", StrandConf{})
+	mq.StrandAdd("idle_channel", StrandConf{})
+	mq.Send("lag_channel", "Lag 1")
+	mq.Send("lag_channel", "Lag 2")
+	msgs, _ := mq.Peek("lag_channel", 1)
+	mq.DeadLetter("lag_channel", msgs[0].ID, "poison")
+	time.Sleep(10 * time.Millisecond)
+
+	var lags []StrandLag
+	assert.Equal(t, http.StatusOK, adminDo(t, h, "GET", "/admin/lag", "", &lags))
+	if assert.Len(t, lags, 2, "dead-letter strands have no consumers") {
+		assert.Equal(t, StrandLag{Strand: "idle_channel"}, lags[0])
+		assert.Equal(t, "lag_channel", lags[1].Strand)
+		assert.Equal(t, 1, lags[1].Messages)
+		assert.Greater(t, lags[1].Seconds, 0.0)
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(consumerLagMessages.WithLabelValues("lag_channel")))
+}
@@ -0,0 +1,445 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: admin.proto
+
+// Admin API for managing the strands of a Conduktor.
+
+package adminpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Admin_ListStrands_FullMethodName     = "/condukt.admin.v1.Admin/ListStrands"
+	Admin_CreateStrand_FullMethodName    = "/condukt.admin.v1.Admin/CreateStrand"
+	Admin_GetStrand_FullMethodName       = "/condukt.admin.v1.Admin/GetStrand"
+	Admin_DeleteStrand_FullMethodName    = "/condukt.admin.v1.Admin/DeleteStrand"
+	Admin_PeekMessages_FullMethodName    = "/condukt.admin.v1.Admin/PeekMessages"
+	Admin_PurgeStrand_FullMethodName     = "/condukt.admin.v1.Admin/PurgeStrand"
+	Admin_ListDeadLetters_FullMethodName = "/condukt.admin.v1.Admin/ListDeadLetters"
+	Admin_Recover_FullMethodName         = "/condukt.admin.v1.Admin/Recover"
+	Admin_GetTopology_FullMethodName     = "/condukt.admin.v1.Admin/GetTopology"
+)
+
+// AdminClient is the client API for Admin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminClient interface {
+	// List strands with their configs and depths.
+	ListStrands(ctx context.Context, in *ListStrandsRequest, opts ...grpc.CallOption) (*ListStrandsResponse, error)
+	// Create a strand.
+	CreateStrand(ctx context.Context, in *CreateStrandRequest, opts ...grpc.CallOption) (*Strand, error)
+	// Describe a strand.
+	GetStrand(ctx context.Context, in *GetStrandRequest, opts ...grpc.CallOption) (*Strand, error)
+	// Delete a strand and its messages.
+	DeleteStrand(ctx context.Context, in *DeleteStrandRequest, opts ...grpc.CallOption) (*DeleteStrandResponse, error)
+	// Peek at unacked messages without consuming them.
+	PeekMessages(ctx context.Context, in *PeekMessagesRequest, opts ...grpc.CallOption) (*PeekMessagesResponse, error)
+	// Delete a strand's messages but keep the strand.
+	PurgeStrand(ctx context.Context, in *PurgeStrandRequest, opts ...grpc.CallOption) (*PurgeStrandResponse, error)
+	// Peek at a strand's dead-lettered messages.
+	ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error)
+	// Resend unacked durable messages.
+	Recover(ctx context.Context, in *RecoverRequest, opts ...grpc.CallOption) (*RecoverResponse, error)
+	// Describe the cluster topology.
+	GetTopology(ctx context.Context, in *GetTopologyRequest, opts ...grpc.CallOption) (*Topology, error)
+}
+
+type adminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) ListStrands(ctx context.Context, in *ListStrandsRequest, opts ...grpc.CallOption) (*ListStrandsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListStrandsResponse)
+	err := c.cc.Invoke(ctx, Admin_ListStrands_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) CreateStrand(ctx context.Context, in *CreateStrandRequest, opts ...grpc.CallOption) (*Strand, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Strand)
+	err := c.cc.Invoke(ctx, Admin_CreateStrand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetStrand(ctx context.Context, in *GetStrandRequest, opts ...grpc.CallOption) (*Strand, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Strand)
+	err := c.cc.Invoke(ctx, Admin_GetStrand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) DeleteStrand(ctx context.Context, in *DeleteStrandRequest, opts ...grpc.CallOption) (*DeleteStrandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteStrandResponse)
+	err := c.cc.Invoke(ctx, Admin_DeleteStrand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PeekMessages(ctx context.Context, in *PeekMessagesRequest, opts ...grpc.CallOption) (*PeekMessagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PeekMessagesResponse)
+	err := c.cc.Invoke(ctx, Admin_PeekMessages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PurgeStrand(ctx context.Context, in *PurgeStrandRequest, opts ...grpc.CallOption) (*PurgeStrandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PurgeStrandResponse)
+	err := c.cc.Invoke(ctx, Admin_PurgeStrand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) ListDeadLetters(ctx context.Context, in *ListDeadLettersRequest, opts ...grpc.CallOption) (*ListDeadLettersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDeadLettersResponse)
+	err := c.cc.Invoke(ctx, Admin_ListDeadLetters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) Recover(ctx context.Context, in *RecoverRequest, opts ...grpc.CallOption) (*RecoverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecoverResponse)
+	err := c.cc.Invoke(ctx, Admin_Recover_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetTopology(ctx context.Context, in *GetTopologyRequest, opts ...grpc.CallOption) (*Topology, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Topology)
+	err := c.cc.Invoke(ctx, Admin_GetTopology_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServer is the server API for Admin service.
+// All implementations must embed UnimplementedAdminServer
+// for forward compatibility.
+type AdminServer interface {
+	// List strands with their configs and depths.
+	ListStrands(context.Context, *ListStrandsRequest) (*ListStrandsResponse, error)
+	// Create a strand.
+	CreateStrand(context.Context, *CreateStrandRequest) (*Strand, error)
+	// Describe a strand.
+	GetStrand(context.Context, *GetStrandRequest) (*Strand, error)
+	// Delete a strand and its messages.
+	DeleteStrand(context.Context, *DeleteStrandRequest) (*DeleteStrandResponse, error)
+	// Peek at unacked messages without consuming them.
+	PeekMessages(context.Context, *PeekMessagesRequest) (*PeekMessagesResponse, error)
+	// Delete a strand's messages but keep the strand.
+	PurgeStrand(context.Context, *PurgeStrandRequest) (*PurgeStrandResponse, error)
+	// Peek at a strand's dead-lettered messages.
+	ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error)
+	// Resend unacked durable messages.
+	Recover(context.Context, *RecoverRequest) (*RecoverResponse, error)
+	// Describe the cluster topology.
+	GetTopology(context.Context, *GetTopologyRequest) (*Topology, error)
+	mustEmbedUnimplementedAdminServer()
+}
+
+// UnimplementedAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServer struct{}
+
+func (UnimplementedAdminServer) ListStrands(context.Context, *ListStrandsRequest) (*ListStrandsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListStrands not implemented")
+}
+func (UnimplementedAdminServer) CreateStrand(context.Context, *CreateStrandRequest) (*Strand, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateStrand not implemented")
+}
+func (UnimplementedAdminServer) GetStrand(context.Context, *GetStrandRequest) (*Strand, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStrand not implemented")
+}
+func (UnimplementedAdminServer) DeleteStrand(context.Context, *DeleteStrandRequest) (*DeleteStrandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteStrand not implemented")
+}
+func (UnimplementedAdminServer) PeekMessages(context.Context, *PeekMessagesRequest) (*PeekMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PeekMessages not implemented")
+}
+func (UnimplementedAdminServer) PurgeStrand(context.Context, *PurgeStrandRequest) (*PurgeStrandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeStrand not implemented")
+}
+func (UnimplementedAdminServer) ListDeadLetters(context.Context, *ListDeadLettersRequest) (*ListDeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedAdminServer) Recover(context.Context, *RecoverRequest) (*RecoverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Recover not implemented")
+}
+func (UnimplementedAdminServer) GetTopology(context.Context, *GetTopologyRequest) (*Topology, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTopology not implemented")
+}
+func (UnimplementedAdminServer) mustEmbedUnimplementedAdminServer() {}
+func (UnimplementedAdminServer) testEmbeddedByValue()               {}
+
+// UnsafeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServer will
+// result in compilation errors.
+type UnsafeAdminServer interface {
+	mustEmbedUnimplementedAdminServer()
+}
+
+func RegisterAdminServer(s grpc.ServiceRegistrar, srv AdminServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Admin_ServiceDesc, srv)
+}
+
+func _Admin_ListStrands_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStrandsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListStrands(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListStrands_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListStrands(ctx, req.(*ListStrandsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_CreateStrand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateStrandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).CreateStrand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_CreateStrand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).CreateStrand(ctx, req.(*CreateStrandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetStrand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStrandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetStrand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetStrand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetStrand(ctx, req.(*GetStrandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_DeleteStrand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteStrandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).DeleteStrand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_DeleteStrand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).DeleteStrand(ctx, req.(*DeleteStrandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PeekMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeekMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PeekMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_PeekMessages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PeekMessages(ctx, req.(*PeekMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PurgeStrand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeStrandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PurgeStrand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_PurgeStrand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PurgeStrand(ctx, req.(*PurgeStrandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDeadLettersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_ListDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ListDeadLetters(ctx, req.(*ListDeadLettersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_Recover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).Recover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_Recover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).Recover(ctx, req.(*RecoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetTopology_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTopologyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetTopology(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Admin_GetTopology_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetTopology(ctx, req.(*GetTopologyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Admin_ServiceDesc is the grpc.ServiceDesc for Admin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Admin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "condukt.admin.v1.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListStrands",
+			Handler:    _Admin_ListStrands_Handler,
+		},
+		{
+			MethodName: "CreateStrand",
+			Handler:    _Admin_CreateStrand_Handler,
+		},
+		{
+			MethodName: "GetStrand",
+			Handler:    _Admin_GetStrand_Handler,
+		},
+		{
+			MethodName: "DeleteStrand",
+			Handler:    _Admin_DeleteStrand_Handler,
+		},
+		{
+			MethodName: "PeekMessages",
+			Handler:    _Admin_PeekMessages_Handler,
+		},
+		{
+			MethodName: "PurgeStrand",
+			Handler:    _Admin_PurgeStrand_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _Admin_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "Recover",
+			Handler:    _Admin_Recover_Handler,
+		},
+		{
+			MethodName: "GetTopology",
+			Handler:    _Admin_GetTopology_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,163 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrStorePressure is returned by sends shed because their strand's store is under pressure.
+var ErrStorePressure = errors.New("store is under pressure")
+
+// Admission actions, taken on sends to a store under pressure.
+const (
+	AdmissionShed  = "shed"  // Refuse the send with ErrStorePressure (the default)
+	AdmissionDelay = "delay" // Hold the send until the pressure eases, shedding it after MaxDelay
+)
+
+// pressureCheckInterval is how often a PressureReporter store is asked whether it is under pressure.
+const pressureCheckInterval = 100 * time.Millisecond
+
+// AdmissionConf configures admission control. A store is under pressure while its saves take longer
+// than SlowSave on average, or while it reports falling behind, as Badger does when compaction lags.
+// Sends to its strands are then shed or delayed, so their latency stays bounded and strands of the
+// other store are unaffected.
+type AdmissionConf struct {
+	SlowSave time.Duration `yaml:"slow_save"` // Average save time that puts a store under pressure; 0 disables admission control
+	Action   string        `yaml:"action"`    // shed (default) or delay
+	MaxDelay time.Duration `yaml:"max_delay"` // Longest a delayed send waits before it is shed; 0 is 1s
+}
+
+// Validate reports an unknown action or negative settings.
+func (conf AdmissionConf) Validate() error {
+	var errs []error
+	switch conf.Action {
+	case "", AdmissionShed, AdmissionDelay:
+	default:
+		errs = append(errs, fmt.Errorf("admission.action: unknown action %q (want %s or %s)", conf.Action, AdmissionShed, AdmissionDelay))
+	}
+	if conf.SlowSave < 0 || conf.MaxDelay < 0 {
+		errs = append(errs, errors.New("admission: slow_save and max_delay must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// SetAdmission replaces the admission control config.
+func (c *Conduktor) SetAdmission(conf AdmissionConf) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.admission.conf = conf
+}
+
+// admission holds the admission control config and how each store's saves are going.
+type admission struct {
+	conf     AdmissionConf // Guarded by c.mu
+	volatile *storePressure
+	durable  *storePressure
+}
+
+// storePressure tracks how a store's saves are going.
+type storePressure struct {
+	name     string
+	average  prometheus.Gauge
+	avg      atomic.Int64 // Moving average of save times, in nanoseconds
+	reported atomic.Bool  // Last answer of a PressureReporter store
+	checked  atomic.Int64 // When the store last answered, in Unix nanoseconds
+	probed   atomic.Int64 // When a save last finished, or a send was let through under pressure, in Unix nanoseconds
+}
+
+// storePressureMake returns the tracker of the store called name.
+func storePressureMake(name string) *storePressure {
+	return &storePressure{name: name, average: storeSaveSeconds.WithLabelValues(name)}
+}
+
+// observe adds a save that took d to the moving average, weighted 1/8.
+func (p *storePressure) observe(d time.Duration) {
+	p.probed.Store(time.Now().UnixNano())
+	for {
+		avg := p.avg.Load()
+		next := avg + (int64(d)-avg)/8
+		if p.avg.CompareAndSwap(avg, next) {
+			p.average.Set(time.Duration(next).Seconds())
+			return
+		}
+	}
+}
+
+// pressured reports whether store is under pressure.
+func (p *storePressure) pressured(store Store, slowSave time.Duration, now time.Time) bool {
+	if time.Duration(p.avg.Load()) > slowSave {
+		return true
+	}
+	reporter, ok := store.(PressureReporter)
+	if !ok {
+		return false
+	}
+	if checked := p.checked.Load(); now.UnixNano()-checked >= int64(pressureCheckInterval) && p.checked.CompareAndSwap(checked, now.UnixNano()) {
+		p.reported.Store(reporter.UnderPressure())
+	}
+	return p.reported.Load()
+}
+
+// admit reports whether a send to store may go now: any send while it is not under pressure, and
+// while it is, one once slowSave has passed without a save finishing, so the average follows the
+// store as it recovers.
+func (p *storePressure) admit(store Store, slowSave time.Duration, now time.Time) bool {
+	if !p.pressured(store, slowSave, now) {
+		return true
+	}
+	probed := p.probed.Load()
+	return now.UnixNano()-probed >= int64(slowSave) && p.probed.CompareAndSwap(probed, now.UnixNano())
+}
+
+// pressure returns the tracker of store.
+func (c *Conduktor) pressure(store Store) *storePressure {
+	if store == c.durable {
+		return c.admission.durable
+	}
+	return c.admission.volatile
+}
+
+// admit sheds or delays a send to strandID, as the admission config says, while its store is under
+// pressure. Sends to strands whose store is not known yet, such as those forwarded to their owner,
+// are let through.
+func (c *Conduktor) admit(ctx context.Context, strandID string) error {
+	c.mu.RLock()
+	conf := c.admission.conf
+	c.mu.RUnlock()
+	if conf.SlowSave == 0 {
+		return nil
+	}
+	store, cached := c.strandStores.lookup(strandID)
+	if !cached {
+		return nil
+	}
+	if conf.MaxDelay == 0 {
+		conf.MaxDelay = time.Second
+	}
+
+	p := c.pressure(store)
+	deadline := time.Now().Add(conf.MaxDelay)
+	for {
+		now := time.Now()
+		if p.admit(store, conf.SlowSave, now) {
+			return nil
+		}
+		if conf.Action != AdmissionDelay || !now.Before(deadline) {
+			sendsShed.WithLabelValues(p.name).Inc()
+			return fmt.Errorf("%w: %s store averages %s a save", ErrStorePressure, p.name, time.Duration(p.avg.Load()))
+		}
+
+		timer := time.NewTimer(min(conf.SlowSave, deadline.Sub(now)))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
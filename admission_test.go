@@ -0,0 +1,56 @@
+package condukt
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStore takes delay to save each message.
+type slowStore struct {
+	Store
+	delay atomic.Int64
+}
+
+func (s *slowStore) Save(ctx context.Context, msg Msg) error {
+	time.Sleep(time.Duration(s.delay.Load()))
+	return s.Store.Save(ctx, msg)
+}
+
+// Test Sends To A Store Under Pressure Are Shed Or Delayed, Leaving The Other Store's Alone
+func TestAdmission(t *testing.T) {
+	durable := &slowStore{Store: store.RamStoreMake()}
+	mq := ConduktorMake(store.RamStoreMake(), durable, wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("slow_channel", StrandConf{Durable: true}))
+	assert.NoError(t, mq.StrandAdd("fast_channel", StrandConf{}))
+	mq.SetAdmission(AdmissionConf{SlowSave: 10 * time.Millisecond})
+	shed := testutil.ToFloat64(sendsShed.WithLabelValues("durable"))
+
+	// A save far over slow_save puts the store under pressure
+	durable.delay.Store(int64(100 * time.Millisecond))
+	assert.NoError(t, mq.Send("slow_channel", "Slow"))
+	assert.ErrorIs(t, mq.Send("slow_channel", "Shed"), ErrStorePressure)
+	assert.Equal(t, shed+1, testutil.ToFloat64(sendsShed.WithLabelValues("durable")))
+	assert.NoError(t, mq.Send("fast_channel", "Unaffected"))
+
+	// A delayed send waits for slow_save without a save, then probes the recovered store
+	durable.delay.Store(0)
+	mq.SetAdmission(AdmissionConf{SlowSave: 10 * time.Millisecond, Action: AdmissionDelay})
+	start := time.Now()
+	assert.NoError(t, mq.Send("slow_channel", "Delayed"))
+	assert.GreaterOrEqual(t, time.Since(start), 9*time.Millisecond)
+
+	// Sends flow again once the probes bring the average down
+	assert.Eventually(t, func() bool {
+		return mq.Send("slow_channel", "Recovering") == nil && time.Duration(mq.admission.durable.avg.Load()) <= 10*time.Millisecond
+	}, time.Second, time.Millisecond)
+	start = time.Now()
+	assert.NoError(t, mq.Send("slow_channel", "Undelayed"))
+	assert.Less(t, time.Since(start), 9*time.Millisecond)
+}
@@ -0,0 +1,179 @@
+package condukt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Alert kinds.
+const (
+	AlertDepth      = "depth"       // Strand depth above DepthAbove
+	AlertDLQDepth   = "dlq_depth"   // Dead-letter strand depth above DLQDepthAbove
+	AlertUnackedAge = "unacked_age" // Oldest unacked message older than UnackedAgeAbove
+)
+
+// Alert statuses.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertConf configures webhook alerts on queue conditions. Zero thresholds disable their alerts.
+type AlertConf struct {
+	Webhook         string        `yaml:"webhook"`           // URL that receives alert events as JSON POSTs; empty disables alerting
+	Interval        time.Duration `yaml:"interval"`          // How often conditions are evaluated (default 30s)
+	Repeat          time.Duration `yaml:"repeat"`            // Re-send firing alerts this often; 0 sends each alert once until it resolves
+	DepthAbove      int           `yaml:"depth_above"`       // Strand depth threshold
+	DLQDepthAbove   int           `yaml:"dlq_depth_above"`   // Dead-letter strand depth threshold
+	UnackedAgeAbove time.Duration `yaml:"unacked_age_above"` // Oldest unacked message age threshold
+}
+
+// AlertEvent is the body POSTed to the webhook when an alert fires or resolves.
+type AlertEvent struct {
+	Status    string  `json:"status"` // firing or resolved
+	Alert     string  `json:"alert"`
+	Strand    string  `json:"strand"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Since     int64   `json:"since"` // Unix seconds the condition started
+	Time      int64   `json:"time"`  // Unix seconds the event was sent
+}
+
+// alertState tracks one active alert.
+type alertState struct {
+	event    AlertEvent
+	notified time.Time // When the webhook last accepted the event; zero if it has not
+}
+
+// alerter evaluates alert conditions and notifies the webhook on changes.
+type alerter struct {
+	c      *Conduktor
+	conf   AlertConf
+	client *http.Client
+	active map[string]*alertState // strand/alert -> state
+}
+
+// AlertServe evaluates conf's thresholds every interval and POSTs an event to the webhook when an alert
+// starts firing and when it resolves, until stop is called. Webhook failures are retried on the next evaluation.
+func (c *Conduktor) AlertServe(conf AlertConf) (stop func()) {
+	if conf.Interval <= 0 {
+		conf.Interval = 30 * time.Second
+	}
+	a := &alerter{c: c, conf: conf, client: &http.Client{Timeout: 10 * time.Second}, active: make(map[string]*alertState)}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				a.evaluate(time.Now())
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// evaluate checks every strand against the thresholds and notifies changes.
+func (a *alerter) evaluate(now time.Time) {
+	strands, err := a.c.Strands()
+	if err != nil {
+		a.c.log.Warn("Failed to evaluate alerts", zap.Error(err))
+		a.c.errs.report(ErrorSourceAlert, "", "", err)
+		return
+	}
+
+	depths := make(map[string]int, len(strands))
+	for _, info := range strands {
+		depths[info.ID] = info.Depth
+	}
+
+	firing := make(map[string]AlertEvent)
+	for _, info := range strands {
+		if strings.HasSuffix(info.ID, dlqSuffix) || info.ID == AuditStrand {
+			continue
+		}
+
+		if a.conf.DepthAbove > 0 && info.Depth > a.conf.DepthAbove {
+			firing[info.ID+"/"+AlertDepth] = AlertEvent{Alert: AlertDepth, Strand: info.ID, Value: float64(info.Depth), Threshold: float64(a.conf.DepthAbove)}
+		}
+		if dlqDepth := depths[DeadLetterStrand(info.ID)]; a.conf.DLQDepthAbove > 0 && dlqDepth > a.conf.DLQDepthAbove {
+			firing[info.ID+"/"+AlertDLQDepth] = AlertEvent{Alert: AlertDLQDepth, Strand: info.ID, Value: float64(dlqDepth), Threshold: float64(a.conf.DLQDepthAbove)}
+		}
+		if a.conf.UnackedAgeAbove > 0 && info.Depth > 0 {
+			oldest, err := a.c.Peek(info.ID, 1)
+			if err == nil && len(oldest) > 0 {
+				age := now.Sub(time.Unix(oldest[0].Timestamp, 0))
+				if age > a.conf.UnackedAgeAbove {
+					firing[info.ID+"/"+AlertUnackedAge] = AlertEvent{Alert: AlertUnackedAge, Strand: info.ID, Value: age.Seconds(), Threshold: a.conf.UnackedAgeAbove.Seconds()}
+				}
+			}
+		}
+	}
+
+	// Fire new alerts, refresh values of active ones, and repeat or retry notifications as due
+	for key, event := range firing {
+		state, exists := a.active[key]
+		if !exists {
+			event.Since = now.Unix()
+			state = &alertState{event: event}
+			a.active[key] = state
+			a.c.log.Warn("Alert firing", zap.String("alert", event.Alert), zap.String("strand", event.Strand), zap.Float64("value", event.Value))
+		}
+		state.event.Value = event.Value
+
+		due := state.notified.IsZero() || (a.conf.Repeat > 0 && now.Sub(state.notified) >= a.conf.Repeat)
+		if due && a.notify(state.event, AlertFiring, now) {
+			state.notified = now
+		}
+	}
+
+	// Resolve alerts whose condition cleared. Unnotified alerts resolve silently.
+	for key, state := range a.active {
+		if _, still := firing[key]; still {
+			continue
+		}
+		if !state.notified.IsZero() && !a.notify(state.event, AlertResolved, now) {
+			continue // Retry the resolution next time
+		}
+		delete(a.active, key)
+		a.c.log.Info("Alert resolved", zap.String("alert", state.event.Alert), zap.String("strand", state.event.Strand))
+	}
+}
+
+// notify POSTs event to the webhook, reporting whether it was accepted.
+func (a *alerter) notify(event AlertEvent, status string, now time.Time) bool {
+	event.Status = status
+	event.Time = now.Unix()
+
+	body, err := json.Marshal(event)
+	if err == nil {
+		var resp *http.Response
+		resp, err = a.client.Post(a.conf.Webhook, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("webhook returned %s", resp.Status)
+			}
+		}
+	}
+
+	if err != nil {
+		alertWebhookFailures.Inc()
+		a.c.log.Warn("Alert webhook failed", zap.String("alert", event.Alert), zap.String("strand", event.Strand), zap.Error(err))
+		a.c.errs.report(ErrorSourceAlert, event.Strand, "", err)
+		return false
+	}
+	alertsNotified.WithLabelValues(event.Alert, status).Inc()
+	return true
+}
@@ -0,0 +1,186 @@
+package condukt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AuditStrand is the durable strand audit records are appended to by AuditStrandMake.
+// It is append-only: its messages are never delivered and cannot be acked, purged, or deleted.
+const AuditStrand = "_audit"
+
+// Audited actions.
+const (
+	AuditStrandCreate = "strand.create"
+	AuditStrandDelete = "strand.delete"
+	AuditStrandPurge  = "strand.purge"
+	AuditStrandPause  = "strand.pause"
+	AuditStrandResume = "strand.resume"
+	AuditDLQRedrive   = "dlq.redrive"
+	AuditDLQPurge     = "dlq.purge"
+	AuditMaintenance  = "maintenance"
+	AuditConfigChange = "config.change"
+	AuditNamespaceSet = "namespace.set"
+	AuditNamespaceDel = "namespace.delete"
+	AuditScheduleSet  = "schedule.set"
+	AuditScheduleDel  = "schedule.delete"
+	AuditACLSet       = "acl.set"
+	AuditRecover      = "recover"
+	AuditAuthSuccess  = "auth.success"
+	AuditAuthFailure  = "auth.failure"
+	AuditAuthLockout  = "auth.lockout"
+)
+
+// ActorSystem is the actor of operations the server performs on its own, like startup recovery.
+const ActorSystem = "system"
+
+// ErrAuditAppendOnly is returned when removing records from the audit strand.
+var ErrAuditAppendOnly = errors.New("audit strand is append-only")
+
+// AuditEvent records one administrative operation.
+type AuditEvent struct {
+	Time   int64             `json:"time"` // Unix nanoseconds
+	Actor  string            `json:"actor"`
+	Action string            `json:"action"`
+	Strand string            `json:"strand,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+	Error  string            `json:"error,omitempty"` // Why the operation failed, if it did
+}
+
+// Auditor persists audit events.
+type Auditor interface {
+	Audit(event AuditEvent) error
+}
+
+// SetAuditor sets where the Conduktor records administrative operations. nil disables auditing.
+func (c *Conduktor) SetAuditor(auditor Auditor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auditor = auditor
+}
+
+// Audit records an administrative operation, stamping its time. Failures to record are logged, not returned,
+// so auditing never blocks the operation itself.
+func (c *Conduktor) Audit(actor, action, strandID string, params map[string]string, opErr error) {
+	c.mu.Lock()
+	auditor := c.auditor
+	c.mu.Unlock()
+	if auditor == nil {
+		return
+	}
+
+	event := AuditEvent{Time: time.Now().UnixNano(), Actor: actor, Action: action, Strand: strandID, Params: params}
+	if opErr != nil {
+		event.Error = opErr.Error()
+	}
+	if err := auditor.Audit(event); err != nil {
+		c.log.Error("Failed to record audit event", zap.String("action", action), zap.String("actor", actor), zap.Error(err))
+	}
+}
+
+// auditActorKey carries the actor of a request in its context.
+type auditActorKey struct{}
+
+// WithActor returns a context carrying the identity performing an operation.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, or fallback if there is none.
+func ActorFrom(ctx context.Context, fallback string) string {
+	if actor, ok := ctx.Value(auditActorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return fallback
+}
+
+// AuditFile appends audit events to a file as JSON lines, syncing after each one.
+type AuditFile struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// AuditFileMake opens path for appending, creating it if needed.
+func AuditFileMake(path string) (*AuditFile, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditFile{file: file}, nil
+}
+
+// Audit appends event to the file.
+func (a *AuditFile) Audit(event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+// Close closes the file.
+func (a *AuditFile) Close() error {
+	return a.file.Close()
+}
+
+// auditStrand appends audit events to AuditStrand in the durable store.
+type auditStrand struct {
+	c *Conduktor
+}
+
+// AuditStrandMake returns an Auditor that appends to AuditStrand, creating it if needed.
+func AuditStrandMake(c *Conduktor) (Auditor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.durable.HasStrand(context.Background(), AuditStrand) {
+		if err := c.durable.CreateStrand(context.Background(), AuditStrand, StrandConf{Durable: true, Ordered: true}); err != nil {
+			return nil, err
+		}
+	}
+	return &auditStrand{c: c}, nil
+}
+
+// Audit saves event without delivering it, so records stay in the strand.
+func (a *auditStrand) Audit(event AuditEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	a.c.mu.Lock()
+	defer a.c.mu.Unlock()
+	return a.c.durable.Save(context.Background(), Msg{
+		ID:        fmt.Sprintf("%d", event.Time),
+		Strand:    AuditStrand,
+		Payload:   string(payload),
+		Timestamp: event.Time / int64(time.Second),
+	})
+}
+
+// Auditors fans events out to several auditors, returning every failure.
+type Auditors []Auditor
+
+// Audit records event with each auditor.
+func (as Auditors) Audit(event AuditEvent) error {
+	var errs []error
+	for _, a := range as {
+		if err := a.Audit(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,398 @@
+package condukt
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Auth scopes. ScopeAdmin includes ScopeRead.
+const (
+	ScopeRead  = "read"  // Inspect strands, messages, and topology
+	ScopeAdmin = "admin" // Also create, delete, purge, pause, and reconfigure
+)
+
+// Authentication errors.
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrForbidden       = errors.New("credentials lack the required scope")
+	// ErrLockedOut refuses a source locked out after repeated failures. It is an ErrUnauthenticated.
+	ErrLockedOut = fmt.Errorf("%w: too many failed attempts, locked out", ErrUnauthenticated)
+)
+
+// AuthConf lists the credentials accepted by the admin APIs. With none, the admin APIs are open.
+type AuthConf struct {
+	Keys  []AuthKey  `yaml:"keys"`  // API keys, sent as "Authorization: Bearer <key>" or "X-API-Key: <key>"
+	Users []AuthUser `yaml:"users"` // HTTP basic-auth users, also accepted by gRPC
+	JWT   JWTConf    `yaml:"jwt"`   // JWTs, sent as API keys are
+	// Certs lists the client certificate identities accepted from TLS listeners verifying client
+	// certificates, and their scopes. A certificate's identity is taken from CertIdentity.
+	Certs        []AuthCert `yaml:"certs"`
+	CertIdentity string     `yaml:"cert_identity"` // cn, dns, uri, or email; empty is cn
+
+	Lockout AuthLockoutConf `yaml:"lockout"` // Locking out sources after repeated failures
+}
+
+// AuthKey is an API key.
+type AuthKey struct {
+	Name  string `yaml:"name"` // Identity recorded in the audit log
+	Key   string `yaml:"key"`
+	Scope string `yaml:"scope"` // read or admin
+	// KeySecret references the key as a secret instead, as "file:/run/secrets/ops-bot".
+	KeySecret string `yaml:"key_secret"`
+}
+
+// AuthCert accepts verified client certificates whose identity matches Name, where '*' matches any
+// run of characters, as in "*.workers.internal".
+type AuthCert struct {
+	Name  string `yaml:"name"`
+	Scope string `yaml:"scope"` // read, admin, or empty for no admin API access
+}
+
+// AuthUser is a basic-auth user.
+type AuthUser struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+	Scope    string `yaml:"scope"` // read or admin
+}
+
+// Auth checks admin API credentials and scopes.
+type Auth struct {
+	c    *Conduktor
+	conf AuthConf
+	jwt  *jwtVerifier // nil without JWTs configured
+
+	lockout *authLockout // nil without lockout configured
+}
+
+// AuthMake returns an Auth accepting conf's credentials and auditing to c. A JWT key set is not
+// fetched until the first token needs it.
+func AuthMake(c *Conduktor, conf AuthConf) *Auth {
+	return &Auth{c: c, conf: conf, jwt: jwtVerifierMake(conf.JWT), lockout: authLockoutMake(conf.Lockout)}
+}
+
+// Enabled reports whether any credentials are configured.
+func (a *Auth) Enabled() bool {
+	return len(a.conf.Keys) > 0 || len(a.conf.Users) > 0 || a.jwt != nil || len(a.conf.Certs) > 0
+}
+
+// Validate reports problems with the configured credentials.
+func (conf AuthConf) Validate() error {
+	var errs []error
+	keys := make(map[string]bool)
+	for i, key := range conf.Keys {
+		if key.Name == "" || (key.Key == "") == (key.KeySecret == "") {
+			errs = append(errs, fmt.Errorf("auth.keys[%d]: name and one of key or key_secret are required", i))
+		}
+		if keys[key.Key] {
+			errs = append(errs, fmt.Errorf("auth.keys[%d]: duplicate key", i))
+		}
+		if key.Key != "" {
+			keys[key.Key] = true
+		}
+		if key.Scope != ScopeRead && key.Scope != ScopeAdmin {
+			errs = append(errs, fmt.Errorf("auth.keys[%d].scope: unknown scope %q (want read or admin)", i, key.Scope))
+		}
+	}
+	for i, user := range conf.Users {
+		if user.Name == "" || user.Password == "" {
+			errs = append(errs, fmt.Errorf("auth.users[%d]: name and password are required", i))
+		}
+		if user.Scope != ScopeRead && user.Scope != ScopeAdmin {
+			errs = append(errs, fmt.Errorf("auth.users[%d].scope: unknown scope %q (want read or admin)", i, user.Scope))
+		}
+	}
+	for i, cert := range conf.Certs {
+		if cert.Name == "" {
+			errs = append(errs, fmt.Errorf("auth.certs[%d].name: required", i))
+		}
+		if cert.Scope != "" && cert.Scope != ScopeRead && cert.Scope != ScopeAdmin {
+			errs = append(errs, fmt.Errorf("auth.certs[%d].scope: unknown scope %q (want read, admin, or none)", i, cert.Scope))
+		}
+	}
+	switch conf.CertIdentity {
+	case "", CertIdentityCN, CertIdentityDNS, CertIdentityURI, CertIdentityEmail:
+	default:
+		errs = append(errs, fmt.Errorf("auth.cert_identity: unknown field %q (want cn, dns, uri, or email)", conf.CertIdentity))
+	}
+	if err := conf.JWT.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth.jwt: %w", err))
+	}
+	if err := conf.Lockout.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth.lockout: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// authenticate returns the identity and scope of an API key, basic-auth user, JWT, or else verified
+// client certificate, and a JWT's claims. Every key and user is compared in constant time.
+func (a *Auth) authenticate(key, user, password string, cert *x509.Certificate) (name, scope string, claims *IdentityClaims, err error) {
+	found := false
+	for _, k := range a.conf.Keys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(k.Key)) == 1 {
+			name, scope, found = k.Name, k.Scope, true
+		}
+	}
+	for _, u := range a.conf.Users {
+		nameMatch := subtle.ConstantTimeCompare([]byte(user), []byte(u.Name))
+		passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(u.Password))
+		if user != "" && nameMatch&passwordMatch == 1 {
+			name, scope, found = u.Name, u.Scope, true
+		}
+	}
+	if !found && key != "" && a.jwt != nil && strings.Count(key, ".") == 2 {
+		verified, err := a.jwt.verify(key)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+		}
+		return verified.Subject, verified.scope(), &verified, nil
+	}
+	if !found && key == "" && user == "" && cert != nil {
+		identity := certIdentity(cert, a.conf.CertIdentity)
+		for _, c := range a.conf.Certs {
+			if identity != "" && aclMatch(c.Name, identity) {
+				return identity, c.Scope, nil, nil
+			}
+		}
+	}
+	if !found {
+		return "", "", nil, ErrUnauthenticated
+	}
+	return name, scope, nil, nil
+}
+
+// authenticateFrom authenticates a request from remote, refusing locked out sources. Failures are
+// audited with the source's IP address and the identity it claimed, if any, and counted toward its
+// lockout.
+func (a *Auth) authenticateFrom(remote, operation, need, key, user, password string, cert *x509.Certificate) (name, scope string, claims *IdentityClaims, err error) {
+	ip := authRemoteIP(remote)
+	if a.lockout != nil {
+		if _, locked := a.lockout.lockedUntil(ip, a.c.now()); locked {
+			authFailures.WithLabelValues(authFailureLockedOut).Inc()
+			return "", "", nil, ErrLockedOut
+		}
+	}
+
+	name, scope, claims, err = a.authenticate(key, user, password, cert)
+	if err == nil {
+		if a.lockout != nil {
+			a.lockout.succeeded(ip)
+		}
+		return name, scope, claims, nil
+	}
+
+	authFailures.WithLabelValues(authFailureInvalid).Inc()
+	params := map[string]string{"operation": operation, "ip": ip}
+	if need != "" {
+		params["scope"] = need
+	}
+	if claimed := authClaimed(user, cert, a.conf.CertIdentity); claimed != "" {
+		params["identity"] = claimed
+	}
+	a.c.Audit(remote, AuditAuthFailure, "", params, err)
+	if a.lockout == nil {
+		return "", "", nil, err
+	}
+	if until, locked := a.lockout.failed(ip, a.c.now()); locked {
+		authLockouts.Inc()
+		a.c.log.Warn("Source locked out after repeated authentication failures", zap.String("ip", ip), zap.Time("until", until))
+		a.c.Audit(remote, AuditAuthLockout, "", map[string]string{"ip": ip, "until": until.UTC().Format(time.RFC3339)}, nil)
+	}
+	return "", "", nil, err
+}
+
+// claimsContext returns ctx carrying the claims of the token its caller authenticated with, if any.
+func (a *Auth) claimsContext(ctx context.Context, claims *IdentityClaims) context.Context {
+	if claims == nil {
+		return ctx
+	}
+	return WithClaims(ctx, *claims)
+}
+
+// authClaimed returns the identity a failed request claimed: its basic-auth user, or its
+// certificate's identity.
+func authClaimed(user string, cert *x509.Certificate, field string) string {
+	if user != "" {
+		return user
+	}
+	if cert != nil {
+		return certIdentity(cert, field)
+	}
+	return ""
+}
+
+// authorize checks credentials against the scope an operation requires, auditing failures and
+// successful admin operations, and returns ctx carrying the identity to act as and its token's claims.
+func (a *Auth) authorize(ctx context.Context, key, user, password string, cert *x509.Certificate, need, operation, remote string) (context.Context, error) {
+	name, scope, claims, err := a.authenticateFrom(remote, operation, need, key, user, password, cert)
+	if err != nil {
+		return nil, err
+	}
+	if scope != ScopeAdmin && (need == ScopeAdmin || scope != ScopeRead) {
+		authFailures.WithLabelValues(authFailureForbidden).Inc()
+		a.c.Audit(name, AuditAuthFailure, "", map[string]string{"operation": operation, "scope": need, "ip": authRemoteIP(remote)}, ErrForbidden)
+		return nil, ErrForbidden
+	}
+	if need == ScopeAdmin {
+		a.c.Audit(name, AuditAuthSuccess, "", map[string]string{"operation": operation, "scope": need}, nil)
+	}
+	return a.claimsContext(WithActor(ctx, name), claims), nil
+}
+
+// Handler requires credentials for requests to next: ScopeRead for GET and HEAD, ScopeAdmin otherwise.
+// Authenticated requests carry their identity for auditing.
+func (a *Auth) Handler(next http.Handler) http.Handler {
+	if !a.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		need := ScopeAdmin
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			need = ScopeRead
+		}
+
+		key, user, password := authHTTP(r)
+		ctx, err := a.authorize(r.Context(), key, user, password, certHTTP(r), need, r.Method+" "+r.URL.Path, r.RemoteAddr)
+		if errors.Is(err, ErrForbidden) {
+			adminError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, ErrLockedOut) {
+			if until, locked := a.lockout.lockedUntil(authRemoteIP(r.RemoteAddr), a.c.now()); locked {
+				w.Header().Set("Retry-After", strconv.Itoa(int(until.Sub(a.c.now())/time.Second)+1))
+			}
+			adminError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="condukt"`)
+			adminError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authHTTP extracts an API key or JWT and basic-auth credentials from a request. WebSocket
+// handshakes may pass the key as the api_key query parameter, or a JWT as access_token, since
+// browsers cannot set their headers.
+func authHTTP(r *http.Request) (key, user, password string) {
+	key = r.Header.Get("X-API-Key")
+	if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		key = bearer
+	}
+	if key == "" && websocket.IsWebSocketUpgrade(r) {
+		key = r.URL.Query().Get("api_key")
+		if key == "" {
+			key = r.URL.Query().Get("access_token")
+		}
+	}
+	user, password, _ = r.BasicAuth()
+	return key, user, password
+}
+
+// Authenticate identifies a wire client from its handshake, auditing failures, and returns the
+// request's context carrying the claims of its token, if it presented one. Without configured
+// credentials every client is anonymous, with an empty identity the ACL does not apply to.
+func (a *Auth) Authenticate(r *http.Request) (string, context.Context, error) {
+	if !a.Enabled() {
+		return "", r.Context(), nil
+	}
+
+	key, user, password := authHTTP(r)
+	name, _, claims, err := a.authenticateFrom(r.RemoteAddr, "wire "+r.URL.Path, "", key, user, password, certHTTP(r))
+	if err != nil {
+		return "", nil, err
+	}
+	return name, a.claimsContext(r.Context(), claims), nil
+}
+
+// Authorize checks the ACL, and the token claims ctx carries, for a wire client's operation.
+func (a *Auth) Authorize(ctx context.Context, identity, op, strandID string) error {
+	return a.c.Authorize(ctx, identity, op, strandID)
+}
+
+// grpcReadMethods are the Admin RPCs that need only ScopeRead.
+var grpcReadMethods = map[string]bool{
+	"ListStrands":     true,
+	"GetStrand":       true,
+	"PeekMessages":    true,
+	"ListDeadLetters": true,
+	"GetTopology":     true,
+}
+
+// UnaryInterceptor requires credentials for admin RPCs, from "authorization" (Bearer or Basic)
+// or "x-api-key" metadata. Authenticated calls carry their identity for auditing.
+func (a *Auth) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !a.Enabled() {
+			return handler(ctx, req)
+		}
+
+		method := info.FullMethod[strings.LastIndexByte(info.FullMethod, '/')+1:]
+		need := ScopeAdmin
+		if grpcReadMethods[method] {
+			need = ScopeRead
+		}
+
+		key, user, password := authGRPC(ctx)
+		authCtx, err := a.authorize(ctx, key, user, password, certGRPC(ctx), need, info.FullMethod, grpcActor(ctx))
+		if errors.Is(err, ErrForbidden) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if errors.Is(err, ErrLockedOut) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(authCtx, req)
+	}
+}
+
+// authGRPC extracts an API key and basic-auth credentials from a call's "authorization" (Bearer or
+// Basic) or "x-api-key" metadata.
+func authGRPC(ctx context.Context) (key, user, password string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		key = values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		if bearer, found := strings.CutPrefix(values[0], "Bearer "); found {
+			key = bearer
+		} else {
+			r := http.Request{Header: http.Header{"Authorization": values[:1]}}
+			user, password, _ = r.BasicAuth()
+		}
+	}
+	return key, user, password
+}
+
+// AuthenticateGRPC identifies a data-plane gRPC caller from its call's metadata, and returns ctx
+// carrying its token's claims, as Authenticate does wire clients.
+func (a *Auth) AuthenticateGRPC(ctx context.Context) (string, context.Context, error) {
+	if !a.Enabled() {
+		return "", ctx, nil
+	}
+
+	key, user, password := authGRPC(ctx)
+	name, _, claims, err := a.authenticateFrom(grpcActor(ctx), "grpc data", "", key, user, password, certGRPC(ctx))
+	if err != nil {
+		return "", nil, err
+	}
+	return name, a.claimsContext(ctx, claims), nil
+}
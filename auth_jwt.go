@@ -0,0 +1,360 @@
+package condukt
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtSecretMin is the shortest HMAC secret accepted, the size of an HS256 hash.
+const jwtSecretMin = 32
+
+// JWTConf configures the JWTs accepted as bearer tokens wherever API keys are. A token's sub claim
+// is the identity it authenticates; its scopes and namespace claims further limit what it may do.
+type JWTConf struct {
+	Secret      string        `yaml:"secret"`       // Key of HS256, HS384, and HS512 tokens, of at least 32 bytes
+	JWKSURL     string        `yaml:"jwks_url"`     // JSON Web Key Set of RS*, PS*, ES*, and EdDSA tokens, as https://idp.example.com/.well-known/jwks.json
+	JWKSRefresh time.Duration `yaml:"jwks_refresh"` // How often the key set is fetched again; 0 is 1h. A token with an unknown kid fetches it at once
+	Issuer      string        `yaml:"issuer"`       // Required iss claim; empty accepts any
+	Audience    string        `yaml:"audience"`     // Required aud claim; empty accepts any
+	Leeway      time.Duration `yaml:"leeway"`       // Clock skew tolerated checking exp and nbf
+	// ScopeClaim names the claim listing a token's scopes, as a space-separated string or an array;
+	// empty is "scope". read and admin grant those admin API scopes; publish, subscribe, and admin,
+	// if any is listed, are the only operations the token allows on strands.
+	ScopeClaim string `yaml:"scope_claim"`
+	// NamespaceClaim names the claim holding the namespace a token is confined to; empty is
+	// "namespace". A token with one may only operate on strands in it.
+	NamespaceClaim string `yaml:"namespace_claim"`
+}
+
+// Enabled reports whether JWTs are accepted.
+func (conf JWTConf) Enabled() bool {
+	return conf.Secret != "" || conf.JWKSURL != ""
+}
+
+// Validate reports a short secret, a malformed key set URL, and negative durations.
+func (conf JWTConf) Validate() error {
+	var errs []error
+	if conf.Secret != "" && len(conf.Secret) < jwtSecretMin {
+		errs = append(errs, fmt.Errorf("secret: must be at least %d bytes", jwtSecretMin))
+	}
+	if conf.JWKSURL != "" {
+		if u, err := url.Parse(conf.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("jwks_url: %q is not an http(s) URL", conf.JWKSURL))
+		}
+	}
+	if !conf.Enabled() && (conf.Issuer != "" || conf.Audience != "") {
+		errs = append(errs, errors.New("secret or jwks_url: required to accept tokens"))
+	}
+	if conf.JWKSRefresh < 0 || conf.Leeway < 0 {
+		errs = append(errs, errors.New("jwks_refresh and leeway must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// IdentityClaims are the claims of the token a request or connection authenticated with.
+type IdentityClaims struct {
+	Subject   string    `json:"subject"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Expires   time.Time `json:"expires"`
+}
+
+// allows reports whether the claims allow op on strandID at now.
+func (claims IdentityClaims) allows(op, strandID string, now time.Time) bool {
+	if now.After(claims.Expires) {
+		return false
+	}
+	if claims.Namespace != "" && Namespace(strandID) != claims.Namespace {
+		return false
+	}
+	for _, scoped := range []string{ACLPublish, ACLSubscribe, ACLAdmin} {
+		if slices.Contains(claims.Scopes, scoped) {
+			return slices.Contains(claims.Scopes, op)
+		}
+	}
+	return true
+}
+
+// scope returns the admin API scope the claims grant, or "" for none.
+func (claims IdentityClaims) scope() string {
+	switch {
+	case slices.Contains(claims.Scopes, ScopeAdmin):
+		return ScopeAdmin
+	case slices.Contains(claims.Scopes, ScopeRead):
+		return ScopeRead
+	}
+	return ""
+}
+
+// authClaimsKey carries the claims of the token a request or connection authenticated with.
+type authClaimsKey struct{}
+
+// WithClaims returns a context carrying the claims of the token its caller authenticated with, which
+// Authorize checks along with the ACL.
+func WithClaims(ctx context.Context, claims IdentityClaims) context.Context {
+	return context.WithValue(ctx, authClaimsKey{}, claims)
+}
+
+// ClaimsFrom returns the claims carried by ctx, if its caller authenticated with a token.
+func ClaimsFrom(ctx context.Context) (IdentityClaims, bool) {
+	claims, ok := ctx.Value(authClaimsKey{}).(IdentityClaims)
+	return claims, ok
+}
+
+// jwtVerifier checks tokens against the configured secret and key set.
+type jwtVerifier struct {
+	conf   JWTConf
+	parser *jwt.Parser
+	jwks   *jwks // nil without a key set
+}
+
+// jwtVerifierMake returns a verifier for conf, or nil if conf accepts no tokens.
+func jwtVerifierMake(conf JWTConf) *jwtVerifier {
+	if !conf.Enabled() {
+		return nil
+	}
+	if conf.ScopeClaim == "" {
+		conf.ScopeClaim = "scope"
+	}
+	if conf.NamespaceClaim == "" {
+		conf.NamespaceClaim = "namespace"
+	}
+
+	var methods []string
+	v := &jwtVerifier{conf: conf}
+	if conf.Secret != "" {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if conf.JWKSURL != "" {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA")
+		v.jwks = jwksMake(conf.JWKSURL, conf.JWKSRefresh)
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(conf.Leeway)}
+	if conf.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		opts = append(opts, jwt.WithAudience(conf.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v
+}
+
+// verify checks token's signature and registered claims, returning its identity claims.
+func (v *jwtVerifier) verify(token string) (IdentityClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.key); err != nil {
+		return IdentityClaims{}, err
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return IdentityClaims{}, errors.New("token has no sub claim")
+	}
+	expires, _ := claims.GetExpirationTime()
+
+	identity := IdentityClaims{Subject: subject, Expires: expires.Add(v.conf.Leeway)}
+	switch scopes := claims[v.conf.ScopeClaim].(type) {
+	case string:
+		identity.Scopes = strings.Fields(scopes)
+	case []any:
+		for _, scope := range scopes {
+			if scope, ok := scope.(string); ok {
+				identity.Scopes = append(identity.Scopes, scope)
+			}
+		}
+	}
+	identity.Namespace, _ = claims[v.conf.NamespaceClaim].(string)
+	return identity, nil
+}
+
+// key returns the key verifying token: the secret for HMAC tokens, else the key set's key of its kid.
+func (v *jwtVerifier) key(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return []byte(v.conf.Secret), nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	return v.jwks.key(kid)
+}
+
+// jwksRetry is the least time between fetches of a key set for unknown key IDs.
+const jwksRetry = time.Minute
+
+// jwks caches the public keys of a JSON Web Key Set by key ID.
+type jwks struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]any
+	fetched time.Time // When keys were last fetched, successfully or not
+}
+
+// jwksMake returns a cache of the key set at url, fetched again every refresh.
+func jwksMake(url string, refresh time.Duration) *jwks {
+	if refresh == 0 {
+		refresh = time.Hour
+	}
+	return &jwks{url: url, refresh: refresh, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// key returns the key with kid, fetching the set if it is stale or, at most every jwksRetry, if kid
+// is unknown, as when the issuer has rotated its keys. An empty kid matches a set's only key.
+func (k *jwks) key(kid string) (any, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.lookup(kid)
+	age := time.Since(k.fetched)
+	if age > k.refresh || !ok && age > jwksRetry {
+		k.fetched = time.Now()
+		keys, err := k.fetch()
+		if err != nil && !ok {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+		if err == nil {
+			k.keys = keys
+			key, ok = k.lookup(kid)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("jwks: no key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup returns the cached key with kid.
+func (k *jwks) lookup(kid string) (any, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// fetch downloads the key set, skipping keys of unsupported types and keys not for signatures.
+func (k *jwks) fetch() (map[string]any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), k.client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", k.url, resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("GET %s: %w", k.url, err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// jwk is a JSON Web Key's public parameters.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"` // RSA modulus
+	E   string `json:"e"` // RSA exponent
+	X   string `json:"x"` // EC or OKP point
+	Y   string `json:"y"` // EC point
+}
+
+// publicKey decodes the key as an *rsa.PublicKey, *ecdsa.PublicKey, or ed25519.PublicKey.
+func (k jwk) publicKey() (any, error) {
+	b64 := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA key too short or with a bad exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		curves := map[string]struct {
+			ecdsa elliptic.Curve
+			ecdh  ecdh.Curve
+		}{
+			"P-256": {elliptic.P256(), ecdh.P256()},
+			"P-384": {elliptic.P384(), ecdh.P384()},
+			"P-521": {elliptic.P521(), ecdh.P521()},
+		}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.ecdsa.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("EC point of the wrong size")
+		}
+		// ecdh checks that the point is on the curve
+		if _, err := curve.ecdh.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve.ecdsa, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
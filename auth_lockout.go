@@ -0,0 +1,129 @@
+package condukt
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Reasons of the auth_failures_total metric.
+const (
+	authFailureInvalid   = "invalid"    // Missing or invalid credentials
+	authFailureForbidden = "forbidden"  // Valid credentials lacking the scope
+	authFailureLockedOut = "locked_out" // Refused from a locked out source
+)
+
+// AuthLockoutConf configures locking out sources, by IP address, after repeated authentication
+// failures, to slow credential stuffing against exposed listeners. Locked out sources are refused
+// without their credentials being checked, valid or not.
+type AuthLockoutConf struct {
+	Threshold int           `yaml:"threshold"` // Failures within window that lock a source out; 0 disables lockout
+	Window    time.Duration `yaml:"window"`    // 0 is 5m
+	Duration  time.Duration `yaml:"duration"`  // How long a source stays locked out; 0 is 15m
+}
+
+// Validate reports negative settings.
+func (conf AuthLockoutConf) Validate() error {
+	if conf.Threshold < 0 || conf.Window < 0 || conf.Duration < 0 {
+		return errors.New("threshold, window, and duration must not be negative")
+	}
+	return nil
+}
+
+// authSource is the recent authentication failures of a source.
+type authSource struct {
+	failures []time.Time // Within the window, oldest first
+	until    time.Time   // End of the source's lockout; zero if not locked out
+}
+
+// authLockout counts authentication failures by source, locking out those that fail too often.
+type authLockout struct {
+	conf      AuthLockoutConf
+	mu        sync.Mutex
+	sources   map[string]*authSource // IP address -> failures
+	lastSweep time.Time
+}
+
+// authLockoutMake returns a lockout of conf, or nil if conf disables lockout.
+func authLockoutMake(conf AuthLockoutConf) *authLockout {
+	if conf.Threshold == 0 {
+		return nil
+	}
+	if conf.Window == 0 {
+		conf.Window = 5 * time.Minute
+	}
+	if conf.Duration == 0 {
+		conf.Duration = 15 * time.Minute
+	}
+	return &authLockout{conf: conf, sources: make(map[string]*authSource)}
+}
+
+// lockedUntil returns the end of ip's lockout, if it is locked out at now.
+func (l *authLockout) lockedUntil(ip string, now time.Time) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	source, exists := l.sources[ip]
+	if !exists || !now.Before(source.until) {
+		return time.Time{}, false
+	}
+	return source.until, true
+}
+
+// failed records a failure of ip at now, returning the end of the lockout it starts, if it starts
+// one.
+func (l *authLockout) failed(ip string, now time.Time) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	source, exists := l.sources[ip]
+	if !exists {
+		source = &authSource{}
+		l.sources[ip] = source
+	}
+	source.failures = append(authRecent(source.failures, now.Add(-l.conf.Window)), now)
+	if len(source.failures) < l.conf.Threshold {
+		return time.Time{}, false
+	}
+	source.failures = nil
+	source.until = now.Add(l.conf.Duration)
+	return source.until, true
+}
+
+// succeeded forgets the failures of ip.
+func (l *authLockout) succeeded(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.sources, ip)
+}
+
+// sweep forgets sources with neither recent failures nor a lockout, at most once a window.
+// Callers must hold l.mu.
+func (l *authLockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.conf.Window {
+		return
+	}
+	l.lastSweep = now
+	for ip, source := range l.sources {
+		source.failures = authRecent(source.failures, now.Add(-l.conf.Window))
+		if len(source.failures) == 0 && !now.Before(source.until) {
+			delete(l.sources, ip)
+		}
+	}
+}
+
+// authRecent drops the failures before since.
+func authRecent(failures []time.Time, since time.Time) []time.Time {
+	for len(failures) > 0 && failures[0].Before(since) {
+		failures = failures[1:]
+	}
+	return failures
+}
+
+// authRemoteIP returns the IP address of a remote address, as from http.Request.RemoteAddr.
+func authRemoteIP(remote string) string {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
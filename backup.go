@@ -0,0 +1,304 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.uber.org/zap"
+)
+
+// Backup file names are backupPrefix, a UTC timestamp in backupTimeFormat, and backupSuffix,
+// so they sort oldest first.
+const (
+	backupPrefix     = "condukt-"
+	backupSuffix     = ".bak"
+	backupTimeFormat = "20060102T150405.000Z"
+)
+
+// ErrBackupUnsupported is returned when backing up a durable store that cannot be backed up.
+var ErrBackupUnsupported = errors.New("durable store does not support backups")
+
+// BackupConf configures scheduled backups of the durable store.
+type BackupConf struct {
+	Interval time.Duration `yaml:"interval"` // How often to back up; 0 disables backups
+	Path     string        `yaml:"path"`     // Local directory backups are written to
+	S3       string        `yaml:"s3"`       // s3://bucket/prefix backups are uploaded to instead of Path
+	Keep     int           `yaml:"keep"`     // Newest backups retained; older ones are deleted (default 7)
+}
+
+// Backuper is implemented by stores that can write a full snapshot of themselves.
+type Backuper interface {
+	Backup(w io.Writer) error
+}
+
+// BackupTarget stores backup files.
+type BackupTarget interface {
+	Put(ctx context.Context, name string, r io.ReadSeeker) error
+	List(ctx context.Context) ([]string, error) // Names of the stored files
+	Delete(ctx context.Context, name string) error
+}
+
+// BackupInfo describes a completed backup.
+type BackupInfo struct {
+	Name     string        `json:"name"`
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+	Deleted  []string      `json:"deleted,omitempty"` // Older backups removed by retention
+}
+
+// BackupTargetMake opens the target configured by conf: S3 if set, otherwise the local Path.
+func BackupTargetMake(ctx context.Context, conf BackupConf) (BackupTarget, error) {
+	if conf.S3 == "" {
+		return BackupDirMake(conf.Path)
+	}
+
+	bucket, prefix, err := backupS3URL(conf.S3)
+	if err != nil {
+		return nil, err
+	}
+	awsConf, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BackupS3Make(s3.NewFromConfig(awsConf), bucket, prefix), nil
+}
+
+// Backup snapshots the durable store to target, then deletes all but the newest keep backups.
+func (c *Conduktor) Backup(ctx context.Context, target BackupTarget, keep int) (BackupInfo, error) {
+	start := time.Now()
+	backuper, ok := c.durable.(Backuper)
+	if !ok {
+		return BackupInfo{}, ErrBackupUnsupported
+	}
+
+	// Stage the snapshot in a temp file so targets get a sized, seekable body
+	tmp, err := os.CreateTemp("", backupPrefix+"*"+backupSuffix)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := backuper.Backup(tmp); err != nil {
+		return BackupInfo{}, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return BackupInfo{}, err
+	}
+
+	info := BackupInfo{Name: backupPrefix + start.UTC().Format(backupTimeFormat) + backupSuffix, Bytes: size}
+	if err := target.Put(ctx, info.Name, tmp); err != nil {
+		return BackupInfo{}, err
+	}
+
+	info.Deleted, err = backupRetain(ctx, target, keep)
+	info.Duration = time.Since(start)
+	return info, err
+}
+
+// backupRetain deletes all but the newest keep backups in target.
+func backupRetain(ctx context.Context, target BackupTarget, keep int) ([]string, error) {
+	names, err := target.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+
+	deleted := []string{}
+	for len(backups) > keep {
+		if err := target.Delete(ctx, backups[0]); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, backups[0])
+		backups = backups[1:]
+	}
+	return deleted, nil
+}
+
+// BackupServe backs up the durable store to target every conf.Interval until stop is called.
+// stop cancels a backup in progress and waits for it to end.
+func (c *Conduktor) BackupServe(conf BackupConf, target BackupTarget) (stop func()) {
+	if conf.Keep <= 0 {
+		conf.Keep = 7
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := c.Backup(ctx, target, conf.Keep)
+				if err != nil {
+					backupsTotal.WithLabelValues("failure").Inc()
+					c.log.Error("Backup failed", zap.Error(err))
+					c.errs.report(ErrorSourceBackup, "", "", err)
+					continue
+				}
+				backupsTotal.WithLabelValues("success").Inc()
+				backupDuration.Set(info.Duration.Seconds())
+				backupBytes.Set(float64(info.Bytes))
+				backupLastSuccess.SetToCurrentTime()
+				c.log.Info("Backup complete", zap.String("name", info.Name), zap.Int64("bytes", info.Bytes),
+					zap.Duration("duration", info.Duration), zap.Strings("deleted", info.Deleted))
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// BackupDir stores backups in a local directory.
+type BackupDir struct {
+	dir string
+}
+
+// BackupDirMake creates dir if needed and returns a target writing into it.
+func BackupDirMake(dir string) (*BackupDir, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &BackupDir{dir: dir}, nil
+}
+
+// Put writes r to name, atomically replacing any existing file.
+func (d *BackupDir) Put(ctx context.Context, name string, r io.ReadSeeker) error {
+	p := filepath.Join(d.dir, name)
+	file, err := os.OpenFile(p+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(p+".tmp", p)
+}
+
+// List returns the names of the files in the directory.
+func (d *BackupDir) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes a file from the directory.
+func (d *BackupDir) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(d.dir, name))
+}
+
+// S3API is the subset of the S3 client used for backups and the S3 sink.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// BackupS3 stores backups under a prefix of an S3 bucket.
+type BackupS3 struct {
+	client S3API
+	bucket string
+	prefix string
+}
+
+// BackupS3Make returns a target storing backups in bucket under prefix.
+func BackupS3Make(client S3API, bucket, prefix string) *BackupS3 {
+	return &BackupS3{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Put uploads r as name.
+func (b *BackupS3) Put(ctx context.Context, name string, r io.ReadSeeker) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, name)),
+		Body:   r,
+	})
+	return err
+}
+
+// List returns the names of the objects under the prefix.
+func (b *BackupS3) List(ctx context.Context) ([]string, error) {
+	prefix := b.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	names := []string{}
+	pages := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{Bucket: aws.String(b.bucket), Prefix: aws.String(prefix)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			names = append(names, strings.TrimPrefix(aws.ToString(object.Key), prefix))
+		}
+	}
+	return names, nil
+}
+
+// Delete removes the object name.
+func (b *BackupS3) Delete(ctx context.Context, name string) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, name)),
+	})
+	return err
+}
+
+// backupS3URL splits s3://bucket/prefix into its bucket and prefix.
+func backupS3URL(raw string) (bucket, prefix string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("%q is not an s3://bucket/prefix URL", raw)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
@@ -0,0 +1,224 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// benchSentHeader carries the time a benchmark message was sent, in Unix nanoseconds.
+const benchSentHeader = "x-bench-sent"
+
+// BenchConf configures a load test of a Conduktor.
+type BenchConf struct {
+	Producers   int           // Goroutines sending, each to one strand
+	Consumers   int           // Goroutines receiving and acking, each from one strand; at least Strands
+	Messages    int           // Messages each producer sends; 0 sends until Duration
+	Duration    time.Duration // Longest the producers send; 0 is unlimited
+	MessageSize int           // Payload bytes
+	Strands     int           // Strands the producers and consumers spread over
+	Strand      StrandConf    // Config of the strands
+	Drain       time.Duration // Longest the consumers wait for outstanding messages once sending ends; 0 is 5s
+}
+
+// Validate reports settings that would make a benchmark fail to start or never end.
+func (conf BenchConf) Validate() error {
+	var errs []error
+	if conf.Producers < 1 || conf.Consumers < 1 || conf.Strands < 1 {
+		errs = append(errs, errors.New("bench: producers, consumers, and strands must be at least 1"))
+	}
+	if conf.Consumers < conf.Strands {
+		errs = append(errs, errors.New("bench: consumers must be at least strands, so every strand is consumed"))
+	}
+	if conf.Messages < 0 || conf.Duration < 0 || conf.MessageSize < 0 || conf.Drain < 0 {
+		errs = append(errs, errors.New("bench: messages, duration, message size, and drain must not be negative"))
+	}
+	if conf.Messages == 0 && conf.Duration == 0 {
+		errs = append(errs, errors.New("bench: messages or duration is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// BenchLatency summarizes a latency distribution.
+type BenchLatency struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// benchLatency summarizes samples, sorting them.
+func benchLatency(samples []time.Duration) BenchLatency {
+	if len(samples) == 0 {
+		return BenchLatency{}
+	}
+	slices.Sort(samples)
+	at := func(p float64) time.Duration {
+		return samples[min(len(samples)-1, int(p*float64(len(samples))))]
+	}
+	return BenchLatency{P50: at(0.50), P95: at(0.95), P99: at(0.99), Max: samples[len(samples)-1]}
+}
+
+// String formats l as p50/p95/p99/max.
+func (l BenchLatency) String() string {
+	return fmt.Sprintf("p50=%v p95=%v p99=%v max=%v", l.P50, l.P95, l.P99, l.Max)
+}
+
+// BenchResult is the outcome of a load test.
+type BenchResult struct {
+	Sent       int           // Messages sent successfully
+	Received   int           // Messages received, including redeliveries
+	SendErrors int           // Sends that failed
+	AckErrors  int           // Acks that failed
+	Elapsed    time.Duration // From the first send to the last receipt
+	Send       BenchLatency  // How long Send took
+	EndToEnd   BenchLatency  // From Send to Receive
+}
+
+// Throughput returns the messages received per second.
+func (r BenchResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Received) / r.Elapsed.Seconds()
+}
+
+// Bench load-tests c: conf.Producers send to conf.Strands new strands while conf.Consumers
+// receive and ack, until each producer has sent conf.Messages, conf.Duration has passed, or ctx is
+// done. The strands are removed afterwards.
+func (c *Conduktor) Bench(ctx context.Context, conf BenchConf) (BenchResult, error) {
+	if err := conf.Validate(); err != nil {
+		return BenchResult{}, err
+	}
+	if conf.Drain == 0 {
+		conf.Drain = 5 * time.Second
+	}
+
+	strands := make([]string, conf.Strands)
+	for i := range strands {
+		strands[i] = "_bench_" + strconv.Itoa(i)
+		if err := c.StrandAdd(strands[i], conf.Strand); err != nil {
+			for _, strandID := range strands[:i] {
+				c.StrandRemove(strandID)
+			}
+			return BenchResult{}, err
+		}
+	}
+	defer func() {
+		for _, strandID := range strands {
+			c.StrandRemove(strandID)
+		}
+	}()
+
+	sendCtx := ctx
+	if conf.Duration > 0 {
+		var cancel context.CancelFunc
+		sendCtx, cancel = context.WithTimeout(ctx, conf.Duration)
+		defer cancel()
+	}
+	receiveCtx, stopReceiving := context.WithCancel(ctx)
+	defer stopReceiving()
+
+	var sent, received, sendErrors, ackErrors atomic.Int64
+	var mu sync.Mutex // Guards the latency samples
+	var sendSamples, endToEndSamples []time.Duration
+	payload := strings.Repeat("x", conf.MessageSize)
+	start := time.Now()
+	var last atomic.Int64 // Unix nanoseconds of the latest receipt
+
+	var consumers sync.WaitGroup
+	for i := range conf.Consumers {
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			var samples []time.Duration
+			defer func() {
+				mu.Lock()
+				endToEndSamples = append(endToEndSamples, samples...)
+				mu.Unlock()
+			}()
+			for {
+				msg, err := c.Receive(strands[i%conf.Strands], ReceiveContext(receiveCtx))
+				if err != nil {
+					select { // Such as when the wire has not seen the strand yet
+					case <-receiveCtx.Done():
+						return
+					case <-time.After(10 * time.Millisecond):
+						continue
+					}
+				}
+				now := time.Now()
+				received.Add(1)
+				last.Store(now.UnixNano())
+				if sentAt, err := strconv.ParseInt(msg.Headers[benchSentHeader], 10, 64); err == nil {
+					samples = append(samples, now.Sub(time.Unix(0, sentAt)))
+				}
+				if err := c.Acknowledge(msg.Strand, msg.ID); err != nil {
+					ackErrors.Add(1)
+				}
+			}
+		}()
+	}
+
+	var producers sync.WaitGroup
+	for i := range conf.Producers {
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			var samples []time.Duration
+			for n := 0; (conf.Messages == 0 || n < conf.Messages) && sendCtx.Err() == nil; n++ {
+				begin := time.Now()
+				err := c.Send(strands[i%conf.Strands], payload, SendContext(sendCtx),
+					SendHeaders(map[string]string{benchSentHeader: strconv.FormatInt(begin.UnixNano(), 10)}))
+				if err != nil {
+					if sendCtx.Err() == nil { // Not cut off by the end of the run
+						sendErrors.Add(1)
+					}
+					continue
+				}
+				samples = append(samples, time.Since(begin))
+				sent.Add(1)
+			}
+			mu.Lock()
+			sendSamples = append(sendSamples, samples...)
+			mu.Unlock()
+		}()
+	}
+	producers.Wait()
+
+	// Let the consumers catch up, then stop them
+	drained := time.After(conf.Drain)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+drain:
+	for received.Load() < sent.Load() {
+		select {
+		case <-ticker.C:
+		case <-drained:
+			break drain
+		case <-ctx.Done():
+			break drain
+		}
+	}
+	stopReceiving()
+	consumers.Wait()
+
+	result := BenchResult{
+		Sent:       int(sent.Load()),
+		Received:   int(received.Load()),
+		SendErrors: int(sendErrors.Load()),
+		AckErrors:  int(ackErrors.Load()),
+		Send:       benchLatency(sendSamples),
+		EndToEnd:   benchLatency(endToEndSamples),
+	}
+	if last := last.Load(); last > 0 {
+		result.Elapsed = time.Unix(0, last).Sub(start)
+	}
+	return result, ctx.Err()
+}
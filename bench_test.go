@@ -0,0 +1,33 @@
+package condukt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test A Load Test Delivers Every Message And Reports Latencies
+func TestBench(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	result, err := mq.Bench(context.Background(), BenchConf{Producers: 4, Consumers: 2, Strands: 2, Messages: 50, MessageSize: 64})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 200, result.Sent)
+	assert.Equal(t, 200, result.Received)
+	assert.Zero(t, result.SendErrors+result.AckErrors)
+	assert.Positive(t, result.Throughput())
+	assert.Positive(t, result.Send.P50)
+	assert.LessOrEqual(t, result.EndToEnd.P50, result.EndToEnd.P99)
+	assert.LessOrEqual(t, result.EndToEnd.P99, result.EndToEnd.Max)
+
+	strands, _ := mq.Strands()
+	assert.Empty(t, strands, "the bench strands are removed")
+
+	_, err = mq.Bench(context.Background(), BenchConf{Producers: 1, Consumers: 1, Strands: 2})
+	assert.ErrorContains(t, err, "consumers must be at least strands")
+	assert.ErrorContains(t, err, "messages or duration is required")
+}
@@ -0,0 +1,293 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// HeaderPubSubMessageID is the header carrying the Pub/Sub ID of a message imported from Pub/Sub.
+const HeaderPubSubMessageID = "x-pubsub-message-id"
+
+// PubSubSourceConf configures a source importing a Google Cloud Pub/Sub subscription. A
+// subscription with message ordering must be imported into an ordered strand.
+type PubSubSourceConf struct {
+	SourceConf `yaml:",inline"`
+
+	Project      string `yaml:"project"`      // Google Cloud project of the subscription
+	Subscription string `yaml:"subscription"` // Subscription ID
+	// AckDeadline is the ack deadline of the import: how long a received message is leased, its
+	// deadline extended as needed, while it is sent to the strand. One not acknowledged by then is
+	// redelivered. 0 is 1h.
+	AckDeadline time.Duration `yaml:"ack_deadline"`
+	// KeyHeader names the header a message's ordering key is imported as; empty is "key".
+	KeyHeader string        `yaml:"key_header"`
+	Batch     int           `yaml:"batch"`    // Messages sent to the strand at a time, and leased at once, at most; 0 is 100
+	MaxWait   time.Duration `yaml:"max_wait"` // Longest a read waits for messages; 0 is 1s
+}
+
+// Validate reports a missing name, strand, project, or subscription, and negative settings.
+func (conf PubSubSourceConf) Validate() error {
+	var errs []error
+	if err := conf.SourceConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.Project == "" {
+		errs = append(errs, errors.New("project: required"))
+	}
+	if conf.Subscription == "" {
+		errs = append(errs, errors.New("subscription: required"))
+	}
+	if conf.AckDeadline < 0 || conf.Batch < 0 || conf.MaxWait < 0 {
+		errs = append(errs, errors.New("ack_deadline, batch, and max_wait must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// PubSubSource imports a Pub/Sub subscription, acknowledging each message once it is sent to the
+// strand. Attributes are imported as headers, plus HeaderPubSubMessageID and, for messages with an
+// ordering key, the key header. Messages leased but not committed when the source closes are
+// nacked, so Pub/Sub redelivers them at once.
+type PubSubSource struct {
+	client    *pubsub.Client
+	ordered   bool
+	keyHeader string
+	batch     int
+	maxWait   time.Duration
+	msgs      chan *pubsub.Message
+	unacked   []*pubsub.Message // Messages of the last read, until committed
+	cancel    context.CancelFunc
+	received  chan error // Receive's result, once it returns
+}
+
+// PubSubSourceOpen connects to conf.Project with credentials from the environment and starts
+// receiving from conf.Subscription.
+func PubSubSourceOpen(ctx context.Context, conf PubSubSourceConf) (*PubSubSource, error) {
+	client, err := pubsub.NewClient(ctx, conf.Project)
+	if err != nil {
+		return nil, err
+	}
+	source, err := PubSubSourceMake(ctx, client, conf)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+// PubSubSourceMake starts receiving from conf.Subscription with client, which the source closes.
+func PubSubSourceMake(ctx context.Context, client *pubsub.Client, conf PubSubSourceConf) (*PubSubSource, error) {
+	if conf.AckDeadline == 0 {
+		conf.AckDeadline = time.Hour
+	}
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	if conf.Batch == 0 {
+		conf.Batch = 100
+	}
+	if conf.MaxWait == 0 {
+		conf.MaxWait = time.Second
+	}
+
+	sub := client.Subscription(conf.Subscription)
+	subConf, err := sub.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub.ReceiveSettings.MaxExtension = conf.AckDeadline
+	sub.ReceiveSettings.MaxOutstandingMessages = conf.Batch
+
+	receiveCtx, cancel := context.WithCancel(context.Background())
+	s := &PubSubSource{
+		client:    client,
+		ordered:   subConf.EnableMessageOrdering,
+		keyHeader: conf.KeyHeader,
+		batch:     conf.Batch,
+		maxWait:   conf.MaxWait,
+		msgs:      make(chan *pubsub.Message),
+		cancel:    cancel,
+		received:  make(chan error, 1),
+	}
+	go func() {
+		s.received <- sub.Receive(receiveCtx, func(ctx context.Context, msg *pubsub.Message) {
+			select {
+			case s.msgs <- msg:
+			case <-ctx.Done():
+				msg.Nack()
+			}
+		})
+	}()
+	return s, nil
+}
+
+// Ordered reports whether the subscription delivers messages of an ordering key in order.
+func (s *PubSubSource) Ordered() bool {
+	return s.ordered
+}
+
+// Read waits up to the source's max wait for a message, then returns it with those already
+// received, up to the batch size. Messages of a read whose commit failed are nacked first, so
+// Pub/Sub redelivers them rather than wait for their lease to expire.
+func (s *PubSubSource) Read(ctx context.Context) ([]SourceMsg, error) {
+	s.nack()
+	timer := time.NewTimer(s.maxWait)
+	defer timer.Stop()
+
+	var msgs []SourceMsg
+	for len(msgs) < s.batch {
+		var received *pubsub.Message
+		if len(msgs) == 0 {
+			select {
+			case received = <-s.msgs:
+			case err := <-s.received:
+				s.received <- err
+				if err == nil {
+					err = errors.New("pubsub: subscription stopped receiving")
+				}
+				return nil, err
+			case <-timer.C:
+				return nil, nil
+			case <-ctx.Done():
+				return nil, nil
+			}
+		} else {
+			select {
+			case received = <-s.msgs:
+			default:
+				return msgs, nil
+			}
+		}
+
+		headers := make(map[string]string, len(received.Attributes)+2)
+		for name, value := range received.Attributes {
+			headers[name] = value
+		}
+		headers[HeaderPubSubMessageID] = received.ID
+		if received.OrderingKey != "" {
+			headers[s.keyHeader] = received.OrderingKey
+		}
+		msgs = append(msgs, SourceMsg{Payload: string(received.Data), Headers: headers, Cursor: received})
+		s.unacked = append(s.unacked, received)
+	}
+	return msgs, nil
+}
+
+// Commit acknowledges msgs, waiting for Pub/Sub to confirm each.
+func (s *PubSubSource) Commit(ctx context.Context, msgs []SourceMsg) error {
+	results := make([]*pubsub.AckResult, len(msgs))
+	for i, msg := range msgs {
+		results[i] = msg.Cursor.(*pubsub.Message).AckWithResult()
+	}
+	s.unacked = s.unacked[:0]
+	for _, result := range results {
+		if _, err := result.Get(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nack releases the messages read but not committed.
+func (s *PubSubSource) nack() {
+	for _, msg := range s.unacked {
+		msg.Nack()
+	}
+	s.unacked = s.unacked[:0]
+}
+
+// Close stops receiving, nacking the messages not yet committed, and closes the client.
+func (s *PubSubSource) Close() error {
+	s.nack()
+	s.cancel()
+	err := <-s.received
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	return errors.Join(err, s.client.Close())
+}
+
+// PubSubSinkConf configures a sink publishing strands to a Google Cloud Pub/Sub topic.
+type PubSubSinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	Project string `yaml:"project"` // Google Cloud project of the topic
+	Topic   string `yaml:"topic"`   // Topic ID
+	// KeyHeader names the header whose value is a message's ordering key; empty is "key". Messages
+	// of one key are published in order, and delivered in order by subscriptions with message
+	// ordering. Messages without it are not ordered.
+	KeyHeader string `yaml:"key_header"`
+}
+
+// Validate reports a missing name, strands, project, or topic, and negative settings.
+func (conf PubSubSinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.Project == "" {
+		errs = append(errs, errors.New("project: required"))
+	}
+	if conf.Topic == "" {
+		errs = append(errs, errors.New("topic: required"))
+	}
+	return errors.Join(errs...)
+}
+
+// PubSubSink publishes messages to a Pub/Sub topic with their headers as attributes and the key
+// header as ordering key, returning once the topic has accepted each.
+type PubSubSink struct {
+	client    *pubsub.Client
+	topic     *pubsub.Topic
+	keyHeader string
+}
+
+// PubSubSinkOpen connects to conf.Project with credentials from the environment.
+func PubSubSinkOpen(ctx context.Context, conf PubSubSinkConf) (*PubSubSink, error) {
+	client, err := pubsub.NewClient(ctx, conf.Project)
+	if err != nil {
+		return nil, err
+	}
+	return PubSubSinkMake(client, conf), nil
+}
+
+// PubSubSinkMake returns a sink publishing to conf.Topic with client, which the sink closes.
+func PubSubSinkMake(client *pubsub.Client, conf PubSubSinkConf) *PubSubSink {
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	topic := client.Topic(conf.Topic)
+	topic.EnableMessageOrdering = true
+	return &PubSubSink{client: client, topic: topic, keyHeader: conf.KeyHeader}
+}
+
+// Write publishes msgs in order. A key whose publish failed is resumed, so the retry can publish it.
+func (p *PubSubSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	results := make([]*pubsub.PublishResult, len(msgs))
+	for i, msg := range msgs {
+		results[i] = p.topic.Publish(ctx, &pubsub.Message{
+			Data:        []byte(msg.Payload),
+			Attributes:  msg.Headers,
+			OrderingKey: msg.Headers[p.keyHeader],
+		})
+	}
+
+	var errs []error
+	for i, result := range results {
+		if _, err := result.Get(ctx); err != nil {
+			if key := msgs[i].Headers[p.keyHeader]; key != "" {
+				p.topic.ResumePublish(key)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close publishes anything buffered and closes the client.
+func (p *PubSubSink) Close() error {
+	p.topic.Stop()
+	return p.client.Close()
+}
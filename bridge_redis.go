@@ -0,0 +1,205 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// HeaderRedisChannel is the header carrying the Redis channel a message imported from Redis was
+// published to.
+const HeaderRedisChannel = "x-redis-channel"
+
+// RedisSourceConf configures a source importing Redis pub/sub channels.
+type RedisSourceConf struct {
+	SourceConf `yaml:",inline"`
+
+	URL      string        `yaml:"url"`      // redis://[user:password@]host:6379/db
+	Channels []string      `yaml:"channels"` // Channels subscribed to
+	Patterns []string      `yaml:"patterns"` // Channel patterns subscribed to, as news.*
+	Batch    int           `yaml:"batch"`    // Messages sent to the strand at a time at most; 0 is 100
+	MaxWait  time.Duration `yaml:"max_wait"` // Longest a read waits for messages; 0 is 1s
+}
+
+// Validate reports a missing name, strand, URL, or channel, and negative settings.
+func (conf RedisSourceConf) Validate() error {
+	var errs []error
+	if err := conf.SourceConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.URL == "" {
+		errs = append(errs, errors.New("url: required"))
+	}
+	if len(conf.Channels) == 0 && len(conf.Patterns) == 0 {
+		errs = append(errs, errors.New("channels or patterns: at least one is required"))
+	}
+	if conf.Batch < 0 || conf.MaxWait < 0 {
+		errs = append(errs, errors.New("batch and max_wait must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// RedisSource imports the messages published to Redis channels, with HeaderRedisChannel. Redis
+// pub/sub keeps nothing, so messages published while the source is stopped, or that overflow its
+// buffer while the strand is slow to accept sends, are lost; once read, they are sent at least once.
+type RedisSource struct {
+	client  *redis.Client
+	pubsub  *redis.PubSub
+	msgs    <-chan *redis.Message
+	batch   int
+	maxWait time.Duration
+}
+
+// RedisSourceOpen connects to conf.URL and subscribes to conf.Channels and conf.Patterns.
+func RedisSourceOpen(ctx context.Context, conf RedisSourceConf) (*RedisSource, error) {
+	if conf.Batch == 0 {
+		conf.Batch = 100
+	}
+	if conf.MaxWait == 0 {
+		conf.MaxWait = time.Second
+	}
+
+	opts, err := redis.ParseURL(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	pubsub := client.Subscribe(ctx, conf.Channels...)
+	if len(conf.Patterns) > 0 {
+		err = pubsub.PSubscribe(ctx, conf.Patterns...)
+	}
+	if err == nil {
+		_, err = pubsub.Receive(ctx) // Wait for the subscription to be confirmed
+	}
+	if err != nil {
+		pubsub.Close()
+		client.Close()
+		return nil, err
+	}
+	return &RedisSource{
+		client:  client,
+		pubsub:  pubsub,
+		msgs:    pubsub.Channel(redis.WithChannelSize(conf.Batch * 10)),
+		batch:   conf.Batch,
+		maxWait: conf.MaxWait,
+	}, nil
+}
+
+// Read waits up to the source's max wait for a message, then returns it with those already
+// received, up to the batch size.
+func (s *RedisSource) Read(ctx context.Context) ([]SourceMsg, error) {
+	timer := time.NewTimer(s.maxWait)
+	defer timer.Stop()
+
+	var msgs []SourceMsg
+	for len(msgs) < s.batch {
+		var received *redis.Message
+		if len(msgs) == 0 {
+			select {
+			case received = <-s.msgs:
+			case <-timer.C:
+				return nil, nil
+			case <-ctx.Done():
+				return nil, nil
+			}
+		} else {
+			select {
+			case received = <-s.msgs:
+			default:
+				return msgs, nil
+			}
+		}
+		msgs = append(msgs, SourceMsg{Payload: received.Payload, Headers: map[string]string{HeaderRedisChannel: received.Channel}})
+	}
+	return msgs, nil
+}
+
+// Commit does nothing: Redis pub/sub has no cursor.
+func (s *RedisSource) Commit(ctx context.Context, msgs []SourceMsg) error {
+	return nil
+}
+
+// Close unsubscribes and closes the connection.
+func (s *RedisSource) Close() error {
+	return errors.Join(s.pubsub.Close(), s.client.Close())
+}
+
+// RedisSinkConf configures a sink publishing strands to Redis pub/sub channels.
+type RedisSinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	URL string `yaml:"url"` // redis://[user:password@]host:6379/db
+	// Channel every strand is published to; empty publishes each strand to the channel named after
+	// it, with a namespace's slash as a dot.
+	Channel string `yaml:"channel"`
+}
+
+// Validate reports a missing name, strands, or URL, and negative settings.
+func (conf RedisSinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.URL == "" {
+		errs = append(errs, errors.New("url: required"))
+	}
+	return errors.Join(errs...)
+}
+
+// RedisPublisher is the subset of the Redis client used by the Redis sink.
+type RedisPublisher interface {
+	Publish(ctx context.Context, channel string, message any) *redis.IntCmd
+}
+
+// RedisSink publishes message payloads to Redis channels; pub/sub carries no headers, so they are
+// dropped. Messages imported from the channel they would be published to, by a RedisSource, are
+// skipped, so a channel bridged both ways to one strand does not loop. A message is delivered to
+// the channel's subscribers at the time, and lost to those subscribing later.
+type RedisSink struct {
+	client  RedisPublisher
+	channel string
+}
+
+// RedisSinkOpen returns a sink publishing to the server at conf.URL. The server is not dialed until
+// the first write.
+func RedisSinkOpen(conf RedisSinkConf) (*RedisSink, error) {
+	opts, err := redis.ParseURL(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	return RedisSinkMake(redis.NewClient(opts), conf.Channel), nil
+}
+
+// RedisSinkMake returns a sink publishing with client to channel, or to channels named after the
+// strands if it is empty.
+func RedisSinkMake(client RedisPublisher, channel string) *RedisSink {
+	return &RedisSink{client: client, channel: channel}
+}
+
+// Write publishes msgs to strandID's channel in order.
+func (r *RedisSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	channel := r.channel
+	if channel == "" {
+		channel = strings.ReplaceAll(strandID, "/", ".")
+	}
+	for _, msg := range msgs {
+		if msg.Headers[HeaderRedisChannel] == channel {
+			continue
+		}
+		if err := r.client.Publish(ctx, channel, msg.Payload).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the client, if it can be closed.
+func (r *RedisSink) Close() error {
+	if closer, ok := r.client.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
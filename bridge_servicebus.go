@@ -0,0 +1,356 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+)
+
+// HeaderServiceBusMessageID is the header carrying the Service Bus ID of a message imported from
+// Service Bus.
+const HeaderServiceBusMessageID = "x-servicebus-message-id"
+
+// ServiceBusSourceConf configures a source importing an Azure Service Bus queue, or a subscription
+// of a topic. A session-enabled entity must be imported into an ordered strand, so the messages of
+// a session keep their order.
+type ServiceBusSourceConf struct {
+	SourceConf `yaml:",inline"`
+
+	ConnectionString string `yaml:"connection_string"` // Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=...;SharedAccessKey=...
+	Queue            string `yaml:"queue"`             // Queue imported, or empty to import Subscription of Topic
+	Topic            string `yaml:"topic"`
+	Subscription     string `yaml:"subscription"`
+	// Sessions imports a session-enabled entity one session at a time, moving on to the next once it
+	// has no messages for MaxWait. A message's session ID is imported as the key header.
+	Sessions bool `yaml:"sessions"`
+	// DeadLetters also imports the entity's dead-letter queue into the strand's dead-letter strand,
+	// with Service Bus's dead-letter reason as HeaderDLQReason, so the messages can be inspected and
+	// redriven there.
+	DeadLetters bool `yaml:"dead_letters"`
+	// KeyHeader names the header a message's session ID is imported as; empty is "key".
+	KeyHeader string        `yaml:"key_header"`
+	Batch     int           `yaml:"batch"`    // Messages received at a time at most; 0 is 100
+	MaxWait   time.Duration `yaml:"max_wait"` // Longest a read waits for messages; 0 is 5s
+}
+
+// Validate reports a missing name, strand, connection string, or entity, and negative settings.
+func (conf ServiceBusSourceConf) Validate() error {
+	var errs []error
+	if err := conf.SourceConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.ConnectionString == "" {
+		errs = append(errs, errors.New("connection_string: required"))
+	}
+	if (conf.Queue == "") == (conf.Topic == "") {
+		errs = append(errs, errors.New("queue or topic: exactly one is required"))
+	}
+	if conf.Topic != "" && conf.Subscription == "" {
+		errs = append(errs, errors.New("subscription: required with topic"))
+	}
+	if conf.Batch < 0 || conf.MaxWait < 0 {
+		errs = append(errs, errors.New("batch and max_wait must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// deadLetterConf returns the configuration serving the entity's dead-letter queue.
+func (conf ServiceBusSourceConf) deadLetterConf() SourceConf {
+	return SourceConf{Name: conf.Name + dlqSuffix, Strand: DeadLetterStrand(conf.Strand), RetryDelay: conf.RetryDelay}
+}
+
+// serviceBusReceiver is the subset of Service Bus's receivers, with or without a session, used by
+// the Service Bus source.
+type serviceBusReceiver interface {
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	Close(ctx context.Context) error
+}
+
+// ServiceBusSource imports a Service Bus queue or subscription in peek-lock mode, completing each
+// message once it is sent to the strand. Application properties are imported as headers, plus
+// HeaderServiceBusMessageID and, for messages with a session, the key header. The entity's lock
+// duration must cover sending a batch to the strand, or its messages are received again. Messages
+// received but not completed when the source closes are abandoned, so Service Bus redelivers them
+// at once.
+type ServiceBusSource struct {
+	client     *azservicebus.Client
+	conf       ServiceBusSourceConf
+	deadLetter bool               // Importing the dead-letter queue
+	receiver   serviceBusReceiver // Receiver of the current session with sessions, which may be nil
+	unacked    []*azservicebus.ReceivedMessage
+}
+
+// ServiceBusSourceOpen connects to conf.ConnectionString and opens a receiver for the entity, or,
+// with sessions, prepares to accept its sessions.
+func ServiceBusSourceOpen(conf ServiceBusSourceConf) (*ServiceBusSource, error) {
+	return serviceBusSourceOpen(conf, false)
+}
+
+// ServiceBusDeadLetterSourceOpen connects to conf.ConnectionString and opens a receiver for the
+// entity's dead-letter queue, whose messages are imported with HeaderDLQReason and HeaderDLQSource,
+// as if dead-lettered from conf.Strand. It is served with the strand's dead-letter strand.
+func ServiceBusDeadLetterSourceOpen(conf ServiceBusSourceConf) (*ServiceBusSource, error) {
+	return serviceBusSourceOpen(conf, true)
+}
+
+// serviceBusSourceOpen opens a source for the entity of conf, or for its dead-letter queue.
+func serviceBusSourceOpen(conf ServiceBusSourceConf, deadLetter bool) (*ServiceBusSource, error) {
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	if conf.Batch == 0 {
+		conf.Batch = 100
+	}
+	if conf.MaxWait == 0 {
+		conf.MaxWait = 5 * time.Second
+	}
+
+	client, err := azservicebus.NewClientFromConnectionString(conf.ConnectionString, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &ServiceBusSource{client: client, conf: conf, deadLetter: deadLetter}
+	if conf.Sessions && !deadLetter {
+		return s, nil
+	}
+
+	// The dead-letter queue of a session-enabled entity is not session-enabled itself
+	opts := &azservicebus.ReceiverOptions{}
+	if deadLetter {
+		opts.SubQueue = azservicebus.SubQueueDeadLetter
+	}
+	if conf.Queue != "" {
+		s.receiver, err = client.NewReceiverForQueue(conf.Queue, opts)
+	} else {
+		s.receiver, err = client.NewReceiverForSubscription(conf.Topic, conf.Subscription, opts)
+	}
+	if err != nil {
+		client.Close(context.Background())
+		return nil, err
+	}
+	return s, nil
+}
+
+// Read waits up to the source's max wait for messages, returning up to the batch size. With
+// sessions, it first accepts the next session with messages, and a session with none left is
+// released so the next read moves on. Messages of a read whose commit failed are abandoned first.
+func (s *ServiceBusSource) Read(ctx context.Context) ([]SourceMsg, error) {
+	s.abandon(ctx)
+	ctx, cancel := context.WithTimeout(ctx, s.conf.MaxWait)
+	defer cancel()
+
+	if s.receiver == nil {
+		var err error
+		if s.conf.Queue != "" {
+			s.receiver, err = s.client.AcceptNextSessionForQueue(ctx, s.conf.Queue, nil)
+		} else {
+			s.receiver, err = s.client.AcceptNextSessionForSubscription(ctx, s.conf.Topic, s.conf.Subscription, nil)
+		}
+		if err != nil {
+			s.receiver = nil
+			return nil, serviceBusIdle(ctx, err)
+		}
+	}
+
+	received, err := s.receiver.ReceiveMessages(ctx, s.conf.Batch, nil)
+	if len(received) == 0 {
+		if s.conf.Sessions && !s.deadLetter {
+			err = errors.Join(serviceBusIdle(ctx, err), s.receiver.Close(context.Background()))
+			s.receiver = nil
+			return nil, err
+		}
+		return nil, serviceBusIdle(ctx, err)
+	}
+
+	msgs := make([]SourceMsg, len(received))
+	for i, msg := range received {
+		msgs[i] = serviceBusMsgDecode(msg, s.conf.KeyHeader, s.deadLetter, s.conf.Strand)
+	}
+	s.unacked = append(s.unacked, received...)
+	return msgs, nil
+}
+
+// Commit completes msgs, removing them from the entity.
+func (s *ServiceBusSource) Commit(ctx context.Context, msgs []SourceMsg) error {
+	for _, msg := range msgs {
+		if err := s.receiver.CompleteMessage(ctx, msg.Cursor.(*azservicebus.ReceivedMessage), nil); err != nil {
+			return err
+		}
+	}
+	s.unacked = s.unacked[:0]
+	return nil
+}
+
+// abandon releases the messages read but not committed.
+func (s *ServiceBusSource) abandon(ctx context.Context) {
+	for _, msg := range s.unacked {
+		s.receiver.AbandonMessage(ctx, msg, nil)
+	}
+	s.unacked = s.unacked[:0]
+}
+
+// Close abandons the messages not yet committed and closes the receiver and client.
+func (s *ServiceBusSource) Close() error {
+	ctx := context.Background()
+	var err error
+	if s.receiver != nil {
+		s.abandon(ctx)
+		err = s.receiver.Close(ctx)
+	}
+	return errors.Join(err, s.client.Close(ctx))
+}
+
+// serviceBusIdle returns err, unless it only reports that ctx expired before a message or session
+// was available.
+func serviceBusIdle(ctx context.Context, err error) error {
+	var sbErr *azservicebus.Error
+	if ctx.Err() != nil || errors.As(err, &sbErr) && sbErr.Code == azservicebus.CodeTimeout {
+		return nil
+	}
+	return err
+}
+
+// serviceBusMsgDecode returns received as a message, with its session ID as keyHeader. A message
+// of a dead-letter queue also gets the dead-letter headers, as if dead-lettered from strandID.
+func serviceBusMsgDecode(received *azservicebus.ReceivedMessage, keyHeader string, deadLetter bool, strandID string) SourceMsg {
+	headers := make(map[string]string, len(received.ApplicationProperties)+2)
+	for name, value := range received.ApplicationProperties {
+		headers[name] = fmt.Sprint(value)
+	}
+	headers[HeaderServiceBusMessageID] = received.MessageID
+	if received.SessionID != nil && *received.SessionID != "" {
+		headers[keyHeader] = *received.SessionID
+	}
+	if deadLetter {
+		reason := "dead-lettered by Service Bus"
+		if received.DeadLetterReason != nil {
+			reason = *received.DeadLetterReason
+		}
+		if received.DeadLetterErrorDescription != nil && *received.DeadLetterErrorDescription != "" {
+			reason += ": " + *received.DeadLetterErrorDescription
+		}
+		headers[HeaderDLQReason] = reason
+		headers[HeaderDLQSource] = strandID
+	}
+	return SourceMsg{Payload: string(received.Body), Headers: headers, Cursor: received}
+}
+
+// ServiceBusSinkConf configures a sink exporting strands to an Azure Service Bus queue or topic.
+type ServiceBusSinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	ConnectionString string `yaml:"connection_string"` // Endpoint=sb://namespace.servicebus.windows.net/;SharedAccessKeyName=...;SharedAccessKey=...
+	Queue            string `yaml:"queue"`             // Queue sent to, or empty to send to Topic
+	Topic            string `yaml:"topic"`
+	// Sessions sets each message's session ID, as a session-enabled entity requires, to the value of
+	// the key header, or to the strand for messages without it, so messages of one key are received
+	// in order.
+	Sessions bool `yaml:"sessions"`
+	// KeyHeader names the header whose value is a message's session ID; empty is "key".
+	KeyHeader string `yaml:"key_header"`
+}
+
+// Validate reports a missing name, strands, connection string, or entity, and negative settings.
+func (conf ServiceBusSinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.ConnectionString == "" {
+		errs = append(errs, errors.New("connection_string: required"))
+	}
+	if (conf.Queue == "") == (conf.Topic == "") {
+		errs = append(errs, errors.New("queue or topic: exactly one is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// ServiceBusSink sends messages to a Service Bus queue or topic in batches, with their headers as
+// application properties. Each message's Service Bus ID is its condukt ID, so an entity with
+// duplicate detection does not deliver a retried batch twice.
+type ServiceBusSink struct {
+	client    *azservicebus.Client
+	sender    *azservicebus.Sender
+	sessions  bool
+	keyHeader string
+}
+
+// ServiceBusSinkOpen connects to conf.ConnectionString and opens a sender for the entity.
+func ServiceBusSinkOpen(conf ServiceBusSinkConf) (*ServiceBusSink, error) {
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	client, err := azservicebus.NewClientFromConnectionString(conf.ConnectionString, nil)
+	if err != nil {
+		return nil, err
+	}
+	entity := conf.Queue
+	if entity == "" {
+		entity = conf.Topic
+	}
+	sender, err := client.NewSender(entity, nil)
+	if err != nil {
+		client.Close(context.Background())
+		return nil, err
+	}
+	return &ServiceBusSink{client: client, sender: sender, sessions: conf.Sessions, keyHeader: conf.KeyHeader}, nil
+}
+
+// Write sends msgs of strandID in order, in as few batches as Service Bus's size limit allows.
+func (b *ServiceBusSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	batch, err := b.sender.NewMessageBatch(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		message := serviceBusMsgEncode(msg, strandID, b.sessions, b.keyHeader)
+		err := batch.AddMessage(message, nil)
+		if errors.Is(err, azservicebus.ErrMessageTooLarge) && batch.NumMessages() > 0 {
+			if err := b.sender.SendMessageBatch(ctx, batch, nil); err != nil {
+				return err
+			}
+			if batch, err = b.sender.NewMessageBatch(ctx, nil); err != nil {
+				return err
+			}
+			err = batch.AddMessage(message, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("message %s: %w", msg.ID, err)
+		}
+	}
+	if batch.NumMessages() == 0 {
+		return nil
+	}
+	return b.sender.SendMessageBatch(ctx, batch, nil)
+}
+
+// Close closes the sender and client.
+func (b *ServiceBusSink) Close() error {
+	ctx := context.Background()
+	return errors.Join(b.sender.Close(ctx), b.client.Close(ctx))
+}
+
+// serviceBusMsgEncode returns msg of strandID as a Service Bus message, with a session ID if
+// sessions is set.
+func serviceBusMsgEncode(msg *Msg, strandID string, sessions bool, keyHeader string) *azservicebus.Message {
+	message := &azservicebus.Message{Body: []byte(msg.Payload), MessageID: &msg.ID}
+	if len(msg.Headers) > 0 {
+		message.ApplicationProperties = make(map[string]any, len(msg.Headers))
+		for name, value := range msg.Headers {
+			message.ApplicationProperties[name] = value
+		}
+	}
+	if sessions {
+		session := msg.Headers[keyHeader]
+		if session == "" {
+			session = strandID
+		}
+		message.SessionID = &session
+	}
+	return message
+}
@@ -0,0 +1,291 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// HeaderSQSMessageID is the header carrying the SQS ID of a message imported from SQS.
+const HeaderSQSMessageID = "x-sqs-message-id"
+
+// SQS limits: messages per receive or batch call, and attributes per message.
+const (
+	sqsBatchMax      = 10
+	sqsAttributesMax = 10
+)
+
+// SQSAPI is the subset of the SQS client used by the SQS source and sink.
+type SQSAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+// sqsFIFO reports whether queueURL names a FIFO queue.
+func sqsFIFO(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
+}
+
+// sqsClientMake returns an SQS client configured from the environment, like the S3 sink's.
+func sqsClientMake(ctx context.Context) (*sqs.Client, error) {
+	awsConf, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sqs.NewFromConfig(awsConf), nil
+}
+
+// SQSSourceConf configures a source importing an SQS queue. A FIFO queue, whose URL ends in .fifo,
+// must be imported into an ordered strand, so its messages keep their order.
+type SQSSourceConf struct {
+	SourceConf `yaml:",inline"`
+
+	Queue string `yaml:"queue"` // URL of the queue, as https://sqs.us-east-1.amazonaws.com/123456789012/orders
+	// VisibilityTimeout is the ack deadline of the import: how long a received message stays hidden
+	// from other consumers while it is sent to the strand. One not deleted by then is received
+	// again. 0 keeps the queue's.
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout"`
+	// KeyHeader names the header a FIFO queue's message group ID is imported as; empty is "key".
+	KeyHeader string        `yaml:"key_header"`
+	Batch     int           `yaml:"batch"`    // Messages received at a time, 1 to 10; 0 is 10
+	MaxWait   time.Duration `yaml:"max_wait"` // Longest a receive long-polls for messages, up to 20s; 0 is 20s
+}
+
+// Validate reports a missing name, strand, or queue, and settings out of SQS's range.
+func (conf SQSSourceConf) Validate() error {
+	var errs []error
+	if err := conf.SourceConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.Queue == "" {
+		errs = append(errs, errors.New("queue: required"))
+	}
+	if conf.VisibilityTimeout < 0 || conf.VisibilityTimeout > 12*time.Hour {
+		errs = append(errs, errors.New("visibility_timeout: must be between 0 and 12h"))
+	}
+	if conf.Batch < 0 || conf.Batch > sqsBatchMax {
+		errs = append(errs, fmt.Errorf("batch: must be between 0 and %d", sqsBatchMax))
+	}
+	if conf.MaxWait < 0 || conf.MaxWait > 20*time.Second {
+		errs = append(errs, errors.New("max_wait: must be between 0 and 20s"))
+	}
+	return errors.Join(errs...)
+}
+
+// SQSSource imports an SQS queue, deleting each message from it once sent to the strand. Message
+// attributes of string and number types are imported as headers, plus HeaderSQSMessageID and, from
+// a FIFO queue, the key header.
+type SQSSource struct {
+	client    SQSAPI
+	queue     string
+	keyHeader string
+	batch     int32
+	maxWait   int32 // Seconds
+	hide      int32 // Visibility timeout in seconds; 0 keeps the queue's
+}
+
+// SQSSourceOpen returns a source importing conf.Queue, with credentials and region from the
+// environment.
+func SQSSourceOpen(ctx context.Context, conf SQSSourceConf) (*SQSSource, error) {
+	client, err := sqsClientMake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return SQSSourceMake(client, conf), nil
+}
+
+// SQSSourceMake returns a source importing conf.Queue with client.
+func SQSSourceMake(client SQSAPI, conf SQSSourceConf) *SQSSource {
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	if conf.Batch == 0 {
+		conf.Batch = sqsBatchMax
+	}
+	if conf.MaxWait == 0 {
+		conf.MaxWait = 20 * time.Second
+	}
+	return &SQSSource{
+		client:    client,
+		queue:     conf.Queue,
+		keyHeader: conf.KeyHeader,
+		batch:     int32(conf.Batch),
+		maxWait:   int32(conf.MaxWait / time.Second),
+		hide:      int32((conf.VisibilityTimeout + time.Second - 1) / time.Second),
+	}
+}
+
+// Read long-polls the queue for its next messages.
+func (s *SQSSource) Read(ctx context.Context) ([]SourceMsg, error) {
+	out, err := s.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:                    aws.String(s.queue),
+		MaxNumberOfMessages:         s.batch,
+		WaitTimeSeconds:             s.maxWait,
+		VisibilityTimeout:           s.hide,
+		MessageAttributeNames:       []string{"All"},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameMessageGroupId},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]SourceMsg, 0, len(out.Messages))
+	for _, received := range out.Messages {
+		headers := map[string]string{HeaderSQSMessageID: aws.ToString(received.MessageId)}
+		for name, attribute := range received.MessageAttributes {
+			if attribute.StringValue != nil {
+				headers[name] = *attribute.StringValue
+			}
+		}
+		if group, ok := received.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]; ok {
+			headers[s.keyHeader] = group
+		}
+		msgs = append(msgs, SourceMsg{Payload: aws.ToString(received.Body), Headers: headers, Cursor: aws.ToString(received.ReceiptHandle)})
+	}
+	return msgs, nil
+}
+
+// Commit deletes msgs from the queue.
+func (s *SQSSource) Commit(ctx context.Context, msgs []SourceMsg) error {
+	for start := 0; start < len(msgs); start += sqsBatchMax {
+		chunk := msgs[start:min(start+sqsBatchMax, len(msgs))]
+		entries := make([]types.DeleteMessageBatchRequestEntry, len(chunk))
+		for i, msg := range chunk {
+			entries[i] = types.DeleteMessageBatchRequestEntry{Id: aws.String(strconv.Itoa(i)), ReceiptHandle: aws.String(msg.Cursor.(string))}
+		}
+		out, err := s.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{QueueUrl: aws.String(s.queue), Entries: entries})
+		if err != nil {
+			return err
+		}
+		if err := sqsFailed(out.Failed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing: the SQS client holds no connections of its own.
+func (s *SQSSource) Close() error {
+	return nil
+}
+
+// SQSSinkConf configures a sink exporting strands to an SQS queue.
+type SQSSinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	Queue string `yaml:"queue"` // URL of the queue; a FIFO queue's ends in .fifo
+	// KeyHeader names the header whose value is a FIFO queue's message group ID, so messages of one
+	// key stay in order; empty is "key". Messages without it are grouped by strand.
+	KeyHeader string `yaml:"key_header"`
+}
+
+// Validate reports a missing name, strands, or queue, and negative settings.
+func (conf SQSSinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.Queue == "" {
+		errs = append(errs, errors.New("queue: required"))
+	}
+	return errors.Join(errs...)
+}
+
+// SQSSink sends messages to an SQS queue, ten at a time, with their headers as string message
+// attributes; SQS allows ten, so headers past the first ten by name are dropped. Messages sent to a
+// FIFO queue are deduplicated by their condukt ID, so a batch retried within SQS's five-minute
+// deduplication window is not delivered twice.
+type SQSSink struct {
+	client    SQSAPI
+	queue     string
+	fifo      bool
+	keyHeader string
+}
+
+// SQSSinkOpen returns a sink sending to conf.Queue, with credentials and region from the
+// environment.
+func SQSSinkOpen(ctx context.Context, conf SQSSinkConf) (*SQSSink, error) {
+	client, err := sqsClientMake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return SQSSinkMake(client, conf), nil
+}
+
+// SQSSinkMake returns a sink sending to conf.Queue with client.
+func SQSSinkMake(client SQSAPI, conf SQSSinkConf) *SQSSink {
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	return &SQSSink{client: client, queue: conf.Queue, fifo: sqsFIFO(conf.Queue), keyHeader: conf.KeyHeader}
+}
+
+// Write sends msgs of strandID to the queue in order.
+func (q *SQSSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	for start := 0; start < len(msgs); start += sqsBatchMax {
+		chunk := msgs[start:min(start+sqsBatchMax, len(msgs))]
+		entries := make([]types.SendMessageBatchRequestEntry, len(chunk))
+		for i, msg := range chunk {
+			entries[i] = q.entry(strconv.Itoa(i), strandID, msg)
+		}
+		out, err := q.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{QueueUrl: aws.String(q.queue), Entries: entries})
+		if err != nil {
+			return err
+		}
+		if err := sqsFailed(out.Failed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entry returns msg of strandID as the batch entry id.
+func (q *SQSSink) entry(id, strandID string, msg *Msg) types.SendMessageBatchRequestEntry {
+	entry := types.SendMessageBatchRequestEntry{Id: aws.String(id), MessageBody: aws.String(msg.Payload)}
+
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names[:min(len(names), sqsAttributesMax)] {
+		if entry.MessageAttributes == nil {
+			entry.MessageAttributes = make(map[string]types.MessageAttributeValue)
+		}
+		entry.MessageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(msg.Headers[name])}
+	}
+
+	if q.fifo {
+		group := msg.Headers[q.keyHeader]
+		if group == "" {
+			group = strandID
+		}
+		entry.MessageGroupId = aws.String(group)
+		entry.MessageDeduplicationId = aws.String(msg.ID)
+	}
+	return entry
+}
+
+// Close does nothing: the SQS client holds no connections of its own.
+func (q *SQSSink) Close() error {
+	return nil
+}
+
+// sqsFailed returns the failures of a batch call as an error, or nil if there are none.
+func sqsFailed(failed []types.BatchResultErrorEntry) error {
+	var errs []error
+	for _, entry := range failed {
+		errs = append(errs, fmt.Errorf("entry %s: %s: %s", aws.ToString(entry.Id), aws.ToString(entry.Code), aws.ToString(entry.Message)))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+// Condukt browser client. Speaks the client protocol of the broker's /client endpoint over
+// WebSocket: sends, subscriptions, and acks, with reconnects and buffering while disconnected.
+//
+//   import { Condukt } from "http://broker:8081/client.js";
+//   const condukt = new Condukt("ws://broker:8081/client", { apiKey: "..." });
+//   await condukt.subscribe("orders", async (msg) => {
+//     console.log(msg.Payload);
+//     await condukt.ack("orders", msg.ID);
+//   });
+//   await condukt.send("orders", JSON.stringify({ id: 1 }));
+
+export class Condukt {
+  // url is the broker's client endpoint. Options:
+  //   apiKey      API key, sent as the api_key query parameter since browsers cannot set handshake headers
+  //   bufferSize  Most requests awaiting a reply, buffered while disconnected (default 1000)
+  //   backoff     First reconnect delay in ms, doubling with each failure (default 100)
+  //   maxBackoff  Longest reconnect delay in ms (default 10000)
+  constructor(url, options = {}) {
+    const u = new URL(url, location.href);
+    if (options.apiKey) u.searchParams.set("api_key", options.apiKey);
+    this.url = u.toString();
+    this.bufferSize = options.bufferSize ?? 1000;
+    this.backoff = options.backoff ?? 100;
+    this.maxBackoff = options.maxBackoff ?? 10000;
+
+    this.seq = 0;
+    this.pending = new Map(); // seq -> {frame, resolve, reject}, resent on reconnect
+    this.subscriptions = new Map(); // strand -> handler
+    this.queues = new Map(); // strand -> promise chain keeping each strand's handler calls in order
+    this.closed = false;
+    this.delay = this.backoff;
+    this.connect();
+  }
+
+  // send sends payload to strand, resolving once the broker accepts it. A send whose reply is
+  // lost to a reconnect is sent again, so the broker may see it twice.
+  send(strand, payload, headers) {
+    return this.request({ op: "send", strand, payload, headers });
+  }
+
+  // ack acknowledges a message delivered from strand, removing it from the broker.
+  ack(strand, msgID) {
+    return this.request({ op: "ack", strand, msg_id: msgID });
+  }
+
+  // ackBatch acknowledges messages delivered from strand in one request and one broker
+  // transaction. If the broker refuses any of them, none are acknowledged.
+  ackBatch(strand, msgIDs) {
+    return this.request({ op: "ack_batch", strand, msg_ids: msgIDs });
+  }
+
+  // subscribe calls handler with each message delivered from strand, one at a time, until the
+  // returned stop function is called. Messages stay on the broker until acked.
+  async subscribe(strand, handler) {
+    if (this.subscriptions.has(strand)) throw new Error(`already subscribed to ${strand}`);
+    this.subscriptions.set(strand, handler);
+    const stop = () => {
+      if (this.subscriptions.get(strand) !== handler) return;
+      this.subscriptions.delete(strand);
+      this.write({ op: "unsubscribe", strand });
+    };
+    try {
+      await this.request({ op: "subscribe", strand });
+    } catch (err) {
+      stop();
+      throw err;
+    }
+    return stop;
+  }
+
+  // close disconnects from the broker, rejecting requests still awaiting a reply.
+  close() {
+    this.closed = true;
+    if (this.ws) this.ws.close();
+    for (const { reject } of this.pending.values()) reject(new Error("client closed"));
+    this.pending.clear();
+    this.subscriptions.clear();
+  }
+
+  request(frame) {
+    if (this.closed) return Promise.reject(new Error("client closed"));
+    if (this.pending.size >= this.bufferSize) return Promise.reject(new Error("client request buffer full"));
+    frame.seq = ++this.seq;
+    return new Promise((resolve, reject) => {
+      this.pending.set(frame.seq, { frame, resolve, reject });
+      this.write(frame);
+    });
+  }
+
+  // write sends frame if connected; pending requests are resent on reconnect.
+  write(frame) {
+    if (this.ws && this.ws.readyState === WebSocket.OPEN) this.ws.send(JSON.stringify(frame));
+  }
+
+  connect() {
+    if (this.closed) return;
+    const ws = new WebSocket(this.url);
+    this.ws = ws;
+    ws.onopen = () => {
+      this.delay = this.backoff;
+      for (const strand of this.subscriptions.keys()) this.write({ op: "subscribe", strand });
+      const seqs = [...this.pending.keys()].sort((a, b) => a - b);
+      for (const seq of seqs) this.write(this.pending.get(seq).frame);
+    };
+    ws.onmessage = (event) => this.receive(JSON.parse(event.data));
+    ws.onclose = () => {
+      if (this.ws !== ws || this.closed) return;
+      this.ws = null;
+      setTimeout(() => this.connect(), this.delay);
+      this.delay = Math.min(this.delay * 2, this.maxBackoff);
+    };
+  }
+
+  receive(frame) {
+    switch (frame.op) {
+      case "ok":
+      case "error": {
+        const req = this.pending.get(frame.seq);
+        if (!req) return;
+        this.pending.delete(frame.seq);
+        if (frame.op === "ok") req.resolve();
+        else req.reject(new Error(frame.error));
+        break;
+      }
+      case "msg": {
+        const handler = this.subscriptions.get(frame.strand);
+        if (!handler) return;
+        const queue = this.queues.get(frame.strand) ?? Promise.resolve();
+        this.queues.set(
+          frame.strand,
+          queue.then(() => handler(frame.msg)).catch((err) => console.error("condukt handler failed", err)),
+        );
+        break;
+      }
+    }
+  }
+}
@@ -0,0 +1,493 @@
+// Package client talks to a remote condukt broker over the client protocol, so applications can
+// send, subscribe, and acknowledge without embedding a Conduktor. Clients reconnect on their own and
+// buffer requests while the broker is unreachable.
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+// Msg is a message delivered to a subscription.
+type Msg = wire.Msg
+
+// Client errors.
+var (
+	ErrClosed     = errors.New("client closed")
+	ErrBufferFull = errors.New("client request buffer full")
+)
+
+// Option tunes a Client.
+type Option func(*opts)
+
+type opts struct {
+	header     http.Header
+	bufferSize int
+	sendWindow int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	log        *zap.Logger
+	onError    func(error)
+}
+
+// APIKey authenticates the client with an API key.
+func APIKey(key string) Option {
+	return func(o *opts) {
+		o.header.Set("X-API-Key", key)
+	}
+}
+
+// BasicAuth authenticates the client as user.
+func BasicAuth(user, password string) Option {
+	return func(o *opts) {
+		o.header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+	}
+}
+
+// BufferSize caps the requests awaiting the broker's reply, buffered while disconnected. The default is 1000.
+func BufferSize(n int) Option {
+	return func(o *opts) {
+		o.bufferSize = n
+	}
+}
+
+// SendWindow caps the sends awaiting the broker's reply, so a producer using SendAsync keeps up to n
+// sends in flight instead of waiting out a round trip per message. Sends past it wait for a reply
+// to make room. The default is 64.
+func SendWindow(n int) Option {
+	return func(o *opts) {
+		o.sendWindow = n
+	}
+}
+
+// Backoff sets the delay before the first reconnect attempt, which doubles with each failure up to
+// max. The defaults are 100ms and 10s.
+func Backoff(initial, max time.Duration) Option {
+	return func(o *opts) {
+		o.backoff = initial
+		o.maxBackoff = max
+	}
+}
+
+// Logger sets the client's logger.
+func Logger(log *zap.Logger) Option {
+	return func(o *opts) {
+		o.log = log
+	}
+}
+
+// OnError sets a handler for connection failures, which the client otherwise only logs as it
+// retries: failed connection attempts and lost connections. It runs on the client's connect
+// loop, so it should return quickly.
+func OnError(handle func(error)) Option {
+	return func(o *opts) {
+		o.onError = handle
+	}
+}
+
+// SendOption tunes a single Send.
+type SendOption func(*wire.ClientFrame)
+
+// SendHeaders adds headers to the sent message.
+func SendHeaders(headers map[string]string) SendOption {
+	return func(f *wire.ClientFrame) {
+		f.Headers = headers
+	}
+}
+
+// Client is a connection to a remote broker's client endpoint.
+type Client struct {
+	url    string
+	o      opts
+	ctx    context.Context // Canceled by Close
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the connect loop exits
+	window chan struct{} // Holds a token for each send awaiting its reply
+
+	mu            sync.Mutex // Serializes writes to conn and guards the fields below
+	conn          *websocket.Conn
+	seq           uint64
+	pending       map[uint64]*request // Requests awaiting a reply, resent on reconnect
+	subscriptions map[string]*subscription
+}
+
+// request is a request awaiting the broker's reply.
+type request struct {
+	frame    wire.ClientFrame
+	reply    chan error
+	windowed bool // Holds a token of the send window
+}
+
+// ClientMake connects to the client endpoint at rawURL, like ws://broker:8081/client, in the
+// background. Requests made before the connection is up are buffered.
+func ClientMake(rawURL string, options ...Option) *Client {
+	o := opts{header: http.Header{}, bufferSize: 1000, sendWindow: 64, backoff: 100 * time.Millisecond, maxBackoff: 10 * time.Second, log: telemetry.Logger}
+	for _, option := range options {
+		option(&o)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		url:           rawURL,
+		o:             o,
+		ctx:           ctx,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+		window:        make(chan struct{}, max(o.sendWindow, 1)),
+		pending:       make(map[uint64]*request),
+		subscriptions: make(map[string]*subscription),
+	}
+	telemetry.ClientSendWindow.WithLabelValues(rawURL).Set(float64(cap(c.window)))
+	go c.run()
+	return c
+}
+
+// Send sends payload to strandID, waiting until the broker has accepted it or ctx is done.
+// A send whose reply is lost to a reconnect is sent again, so the broker may see it twice.
+func (c *Client) Send(ctx context.Context, strandID, payload string, opts ...SendOption) error {
+	wait, err := c.SendAsync(ctx, strandID, payload, opts...)
+	if err != nil {
+		return err
+	}
+	return wait(ctx)
+}
+
+// SendAsync sends payload to strandID as Send does, once the send window has room, but returns
+// without waiting for the broker's reply. wait, called once, waits for the reply or for its ctx to
+// be done. Sends reach the broker in the order SendAsync returns.
+func (c *Client) SendAsync(ctx context.Context, strandID, payload string, opts ...SendOption) (wait func(context.Context) error, err error) {
+	frame := wire.ClientFrame{Op: wire.ClientSend, Strand: strandID, Payload: payload}
+	for _, opt := range opts {
+		opt(&frame)
+	}
+
+	select {
+	case c.window <- struct{}{}:
+		telemetry.ClientSendsInFlight.WithLabelValues(c.url).Set(float64(len(c.window)))
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, ErrClosed
+	}
+	req, err := c.begin(frame, true)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error { return c.await(ctx, req) }, nil
+}
+
+// Ack acknowledges a message delivered from strandID, removing it from the broker.
+func (c *Client) Ack(ctx context.Context, strandID, msgID string) error {
+	return c.request(ctx, wire.ClientFrame{Op: wire.ClientAck, Strand: strandID, MsgID: msgID})
+}
+
+// AckBatch acknowledges messages delivered from strandID in one request and one broker
+// transaction. If the broker refuses any of them, none are acknowledged.
+func (c *Client) AckBatch(ctx context.Context, strandID string, msgIDs []string) error {
+	return c.request(ctx, wire.ClientFrame{Op: wire.ClientAckBatch, Strand: strandID, MsgIDs: msgIDs})
+}
+
+// Subscribe calls handle with each message delivered from strandID, one at a time, until stop is
+// called. Messages stay on the broker until acknowledged with Ack. The subscription is renewed
+// whenever the client reconnects.
+func (c *Client) Subscribe(ctx context.Context, strandID string, handle func(Msg)) (stop func(), err error) {
+	c.mu.Lock()
+	if _, exists := c.subscriptions[strandID]; exists {
+		c.mu.Unlock()
+		return nil, errors.New("already subscribed to " + strandID)
+	}
+	sub := subscriptionMake(handle)
+	c.subscriptions[strandID] = sub
+	c.mu.Unlock()
+
+	stop = func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.subscriptions[strandID] == sub {
+			delete(c.subscriptions, strandID)
+		}
+		sub.close()
+		if c.conn != nil {
+			c.write(c.conn, wire.ClientFrame{Op: wire.ClientUnsubscribe, Strand: strandID}) // Later connections are not subscribed
+		}
+	}
+	if err := c.request(ctx, wire.ClientFrame{Op: wire.ClientSubscribe, Strand: strandID}); err != nil {
+		stop()
+		return nil, err
+	}
+	return stop, nil
+}
+
+// Close disconnects from the broker. Requests still awaiting a reply fail with ErrClosed.
+func (c *Client) Close() error {
+	c.cancel()
+	c.mu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.mu.Unlock()
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, req := range c.pending {
+		c.settle(req, ErrClosed)
+	}
+	for _, sub := range c.subscriptions {
+		sub.close()
+	}
+	return nil
+}
+
+// request sends frame, or buffers it while disconnected, and waits for the broker's reply.
+func (c *Client) request(ctx context.Context, frame wire.ClientFrame) error {
+	req, err := c.begin(frame, false)
+	if err != nil {
+		return err
+	}
+	return c.await(ctx, req)
+}
+
+// begin sends frame, or buffers it while disconnected, returning the request awaiting its reply.
+// A windowed request already holds a token of the send window, which is released with its reply.
+func (c *Client) begin(frame wire.ClientFrame, windowed bool) (*request, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	switch {
+	case c.ctx.Err() != nil:
+		err = ErrClosed
+	case len(c.pending) >= c.o.bufferSize:
+		err = ErrBufferFull
+	}
+	if err != nil {
+		if windowed {
+			c.windowRelease()
+		}
+		return nil, err
+	}
+
+	c.seq++
+	frame.Seq = c.seq
+	req := &request{frame: frame, reply: make(chan error, 1), windowed: windowed}
+	c.pending[frame.Seq] = req
+	if c.conn != nil {
+		c.write(c.conn, frame) // On failure, the connect loop resends it after reconnecting
+	}
+	return req, nil
+}
+
+// await waits for the reply to req, abandoning it once ctx is done.
+func (c *Client) await(ctx context.Context, req *request) error {
+	select {
+	case err := <-req.reply:
+		return err
+	case <-ctx.Done():
+		c.mu.Lock()
+		if _, pending := c.pending[req.frame.Seq]; pending {
+			c.settle(req, ctx.Err())
+		}
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// settle ends req with err, making room in the send window. Callers must hold c.mu.
+func (c *Client) settle(req *request, err error) {
+	delete(c.pending, req.frame.Seq)
+	req.reply <- err
+	if req.windowed {
+		c.windowRelease()
+	}
+}
+
+// windowRelease returns a token to the send window.
+func (c *Client) windowRelease() {
+	<-c.window
+	telemetry.ClientSendsInFlight.WithLabelValues(c.url).Set(float64(len(c.window)))
+}
+
+// write sends frame on conn. Callers must hold c.mu.
+func (c *Client) write(conn *websocket.Conn, frame wire.ClientFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// run keeps the client connected until Close.
+func (c *Client) run() {
+	defer close(c.done)
+	backoff := c.o.backoff
+	for c.ctx.Err() == nil {
+		conn, _, err := websocket.DefaultDialer.DialContext(c.ctx, c.url, c.o.header)
+		if err != nil {
+			c.o.log.Debug("Failed to connect to broker", zap.String("url", c.url), zap.Duration("retry", backoff), zap.Error(err))
+			c.reportError(err)
+			select {
+			case <-c.ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, c.o.maxBackoff)
+			continue
+		}
+		backoff = c.o.backoff
+
+		if !c.attach(conn) {
+			conn.Close()
+			return
+		}
+		c.o.log.Debug("Connected to broker", zap.String("url", c.url))
+		c.read(conn)
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+		conn.Close()
+	}
+}
+
+// reportError passes a connection failure to the OnError handler, unless the client was closed.
+func (c *Client) reportError(err error) {
+	if c.o.onError != nil && c.ctx.Err() == nil {
+		c.o.onError(err)
+	}
+}
+
+// attach makes conn the client's connection, renewing subscriptions and resending pending requests
+// in order. Subscribing is idempotent, so pending subscribe requests may repeat a renewal. It
+// reports false if the client was closed meanwhile.
+func (c *Client) attach(conn *websocket.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctx.Err() != nil {
+		return false
+	}
+	c.conn = conn
+
+	for strandID := range c.subscriptions {
+		c.write(conn, wire.ClientFrame{Op: wire.ClientSubscribe, Strand: strandID})
+	}
+	seqs := make([]uint64, 0, len(c.pending))
+	for seq := range c.pending {
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	for _, seq := range seqs {
+		c.write(conn, c.pending[seq].frame)
+	}
+	return true
+}
+
+// read handles replies and pushed messages from conn until it fails.
+func (c *Client) read(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if c.ctx.Err() == nil {
+				c.o.log.Warn("Disconnected from broker", zap.String("url", c.url), zap.Error(err))
+				c.reportError(err)
+			}
+			return
+		}
+
+		var frame wire.ClientFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			c.o.log.Warn("Failed to unmarshal broker frame", zap.Error(err))
+			continue
+		}
+
+		switch frame.Op {
+		case wire.ClientOK, wire.ClientError:
+			var err error
+			if frame.Op == wire.ClientError {
+				err = errors.New(frame.Error)
+			}
+			c.mu.Lock()
+			if req, exists := c.pending[frame.Seq]; exists {
+				c.settle(req, err)
+			}
+			c.mu.Unlock()
+		case wire.ClientMsg:
+			msg, err := wire.MsgDecodeShared(frame.Msg) // Unmarshal copied frame.Msg out of data
+			if err != nil {
+				c.o.log.Warn("Failed to decode delivered message", zap.String("strand", frame.Strand), zap.Error(err))
+				continue
+			}
+			c.mu.Lock()
+			sub, exists := c.subscriptions[frame.Strand]
+			c.mu.Unlock()
+			if exists {
+				sub.push(msg)
+			}
+		}
+	}
+}
+
+// subscription hands a strand's messages to its handler in order, without blocking the reader,
+// since handlers wait on the reader for their acks' replies.
+type subscription struct {
+	mu     sync.Mutex
+	queue  []Msg
+	ready  chan struct{} // Signaled when queue grows
+	closed chan struct{}
+}
+
+// subscriptionMake starts calling handle with pushed messages.
+func subscriptionMake(handle func(Msg)) *subscription {
+	sub := &subscription{ready: make(chan struct{}, 1), closed: make(chan struct{})}
+	go func() {
+		for {
+			select {
+			case <-sub.closed:
+				return
+			case <-sub.ready:
+			}
+			for {
+				sub.mu.Lock()
+				if len(sub.queue) == 0 {
+					sub.mu.Unlock()
+					break
+				}
+				msg := sub.queue[0]
+				sub.queue = sub.queue[1:]
+				sub.mu.Unlock()
+				handle(msg)
+			}
+		}
+	}()
+	return sub
+}
+
+// push queues msg for the handler.
+func (sub *subscription) push(msg Msg) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, msg)
+	sub.mu.Unlock()
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the handler, dropping queued messages; they stay on the broker until acknowledged.
+func (sub *subscription) close() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	select {
+	case <-sub.closed:
+	default:
+		close(sub.closed)
+	}
+}
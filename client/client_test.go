@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Clients Send, Subscribe, And Ack Across Reconnects
+func TestClient(t *testing.T) {
+	mq := condukt.ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("remote_channel", condukt.StrandConf{Durable: false, Ordered: true}))
+
+	// The broker starts after the client, which buffers its send meanwhile
+	server := httptest.NewUnstartedServer(condukt.ClientHandler(mq, nil))
+	defer server.Close()
+	c := ClientMake("ws://"+server.Listener.Addr().String()+"/client", Backoff(10*time.Millisecond, 50*time.Millisecond))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sent := make(chan error, 1)
+	go func() { sent <- c.Send(ctx, "remote_channel", "Buffered") }()
+	time.Sleep(50 * time.Millisecond)
+	server.Start()
+	assert.NoError(t, <-sent)
+
+	received := make(chan Msg, 4)
+	stop, err := c.Subscribe(ctx, "remote_channel", func(msg Msg) {
+		received <- msg
+		assert.NoError(t, c.Ack(ctx, "remote_channel", msg.ID))
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stop()
+	select {
+	case msg := <-received:
+		assert.Equal(t, "Buffered", msg.Payload)
+	case <-ctx.Done():
+		t.Fatal("message not delivered")
+	}
+
+	// The client reconnects and resubscribes after the broker drops it
+	server.CloseClientConnections()
+	assert.NoError(t, c.Send(ctx, "remote_channel", "Reconnected"))
+	select {
+	case msg := <-received:
+		assert.Equal(t, "Reconnected", msg.Payload)
+	case <-ctx.Done():
+		t.Fatal("message not delivered after reconnect")
+	}
+	assert.Eventually(t, func() bool {
+		info, err := mq.Strand("remote_channel")
+		return err == nil && info.Depth == 0
+	}, time.Second, 10*time.Millisecond, "acked messages leave the broker")
+
+	err = c.Send(ctx, "missing_channel", "Nowhere")
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "strand"), err.Error())
+
+	c.Close()
+	assert.ErrorIs(t, c.Send(ctx, "remote_channel", "Closed"), ErrClosed)
+}
+
+// Test Connection Failures Reach OnError
+func TestClientOnError(t *testing.T) {
+	server := httptest.NewServer(nil)
+	addr := server.Listener.Addr().String()
+	server.Close()
+
+	failures := make(chan error, 10)
+	c := ClientMake("ws://"+addr+"/client", Backoff(10*time.Millisecond, 10*time.Millisecond), OnError(func(err error) {
+		select {
+		case failures <- err:
+		default:
+		}
+	}))
+	defer c.Close()
+	select {
+	case err := <-failures:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("connection failure not reported")
+	}
+}
+
+// Test SendAsync Keeps Up To The Window In Flight, In Order
+func TestClientSendWindow(t *testing.T) {
+	mq := condukt.ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("window_channel", condukt.StrandConf{Ordered: true}))
+	server := httptest.NewUnstartedServer(condukt.ClientHandler(mq, nil))
+	defer server.Close()
+	url := "ws://" + server.Listener.Addr().String() + "/client"
+	c := ClientMake(url, SendWindow(4), Backoff(10*time.Millisecond, 50*time.Millisecond))
+	defer c.Close()
+	assert.Equal(t, float64(4), testutil.ToFloat64(telemetry.ClientSendWindow.WithLabelValues(url)))
+
+	// With the broker down, a full window holds back further sends
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var waits []func(context.Context) error
+	for i := range 4 {
+		wait, err := c.SendAsync(ctx, "window_channel", strconv.Itoa(i))
+		assert.NoError(t, err)
+		waits = append(waits, wait)
+	}
+	assert.Equal(t, float64(4), testutil.ToFloat64(telemetry.ClientSendsInFlight.WithLabelValues(url)))
+	full, stop := context.WithTimeout(ctx, 50*time.Millisecond)
+	_, err := c.SendAsync(full, "window_channel", "Held back")
+	stop()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	server.Start()
+	for i := 4; i < 100; i++ {
+		wait, err := c.SendAsync(ctx, "window_channel", strconv.Itoa(i))
+		if !assert.NoError(t, err) {
+			return
+		}
+		waits = append(waits, wait)
+	}
+	for _, wait := range waits {
+		assert.NoError(t, wait(ctx))
+	}
+	assert.Zero(t, testutil.ToFloat64(telemetry.ClientSendsInFlight.WithLabelValues(url)))
+
+	for i := range 100 {
+		msg, err := mq.Receive("window_channel")
+		if assert.NoError(t, err) {
+			assert.Equal(t, strconv.Itoa(i), msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package condukt
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+//go:embed client.js
+var clientJS []byte
+
+// ClientScriptHandler serves client.js, the browser client for ClientHandler's protocol, as a
+// JavaScript module.
+func ClientScriptHandler(c *Conduktor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err := w.Write(clientJS); err != nil {
+			c.log.Warn("Failed to write client script", zap.Error(err))
+		}
+	})
+}
+
+// ClientHandler serves remote clients, like those of package client, speaking the client protocol
+// over WebSocket. Clients send, subscribe, and acknowledge as the identity authorizer authenticates,
+// subject to the ACL; a nil authorizer makes every client anonymous.
+func ClientHandler(c *Conduktor, authorizer WireAuthorizer) http.Handler {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := ""
+		authCtx := r.Context()
+		if authorizer != nil {
+			var err error
+			if identity, authCtx, err = authorizer.Authenticate(r); err != nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="condukt"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			c.log.Error("Client upgrade failed", zap.Error(err))
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.WithoutCancel(authCtx)) // Keeps the client's token claims
+		session := &clientSession{c: c, conn: conn, identity: identity, ctx: ctx, subscriptions: make(map[string]context.CancelFunc)}
+		c.log.Info("Client connected", zap.String("identity", identity), zap.String("remote", r.RemoteAddr))
+		session.serve()
+		cancel()
+		conn.Close()
+		c.log.Info("Client disconnected", zap.String("identity", identity), zap.String("remote", r.RemoteAddr))
+	})
+}
+
+// clientSession is one client connection.
+type clientSession struct {
+	c        *Conduktor
+	conn     *websocket.Conn
+	identity string
+	ctx      context.Context // Canceled when the connection closes; carries the client's token claims
+
+	mu            sync.Mutex                    // Serializes writes to conn and guards subscriptions
+	subscriptions map[string]context.CancelFunc // Strand -> stops its delivery loop
+}
+
+// serve applies the client's requests until its connection fails.
+func (s *clientSession) serve() {
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame wire.ClientFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			s.c.log.Warn("Failed to unmarshal client frame", zap.Error(err))
+			continue
+		}
+
+		switch frame.Op {
+		case wire.ClientSend:
+			err = s.c.Send(frame.Strand, frame.Payload, SendAs(s.identity), SendHeaders(frame.Headers), SendContext(s.ctx))
+		case wire.ClientAck:
+			if err = s.c.Authorize(s.ctx, s.identity, ACLSubscribe, frame.Strand); err == nil {
+				err = s.c.Acknowledge(frame.Strand, frame.MsgID)
+			}
+		case wire.ClientAckBatch:
+			if err = s.c.Authorize(s.ctx, s.identity, ACLSubscribe, frame.Strand); err == nil {
+				err = s.c.AcknowledgeBatch(frame.Strand, frame.MsgIDs)
+			}
+		case wire.ClientSubscribe:
+			if err = s.c.Authorize(s.ctx, s.identity, ACLSubscribe, frame.Strand); err == nil {
+				s.subscribe(frame.Strand)
+			}
+		case wire.ClientUnsubscribe:
+			s.unsubscribe(frame.Strand)
+		default:
+			err = errors.New("unknown client operation " + frame.Op)
+		}
+		s.reply(frame.Seq, err)
+	}
+}
+
+// reply tells the client how its request with seq went. Requests with seq 0 get no reply.
+func (s *clientSession) reply(seq uint64, err error) {
+	if seq == 0 {
+		return
+	}
+	frame := wire.ClientFrame{Op: wire.ClientOK, Seq: seq}
+	if err != nil {
+		frame = wire.ClientFrame{Op: wire.ClientError, Seq: seq, Error: err.Error()}
+	}
+	s.write(frame)
+}
+
+// write sends frame to the client, reporting whether it was written.
+func (s *clientSession) write(frame wire.ClientFrame) bool {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		s.c.log.Warn("Failed to write client frame", zap.String("op", frame.Op), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// subscribe starts delivering strandID's messages to the client, unless it already is.
+func (s *clientSession) subscribe(strandID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.subscriptions[strandID]; exists {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.subscriptions[strandID] = cancel
+	go s.deliver(ctx, strandID)
+}
+
+// unsubscribe stops delivering strandID's messages to the client.
+func (s *clientSession) unsubscribe(strandID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, exists := s.subscriptions[strandID]; exists {
+		cancel()
+		delete(s.subscriptions, strandID)
+	}
+}
+
+// deliver pushes strandID's messages to the client until ctx is done. Messages stay unacked until
+// the client acknowledges them.
+func (s *clientSession) deliver(ctx context.Context, strandID string) {
+	sub, err := s.c.Subscribe(strandID, ReceiveAs(s.identity), ReceiveContext(s.ctx))
+	if err != nil {
+		s.c.log.Warn("Client subscription failed", zap.String("strand", strandID), zap.Error(err))
+		return
+	}
+	defer sub.Close()
+	for {
+		msg, err := sub.Next(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil { // Refused by the Deliver middleware
+			continue
+		}
+
+		data, err := wire.MsgEncodeJSON(*msg)
+		if err != nil {
+			s.c.log.Error("Failed to encode message for client", zap.String("strand", strandID), zap.String("msgID", msg.ID), zap.Error(err))
+			continue
+		}
+		if !s.write(wire.ClientFrame{Op: wire.ClientMsg, Strand: strandID, Msg: data}) {
+			return
+		}
+	}
+}
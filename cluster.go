@@ -0,0 +1,321 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jkassis/condukt/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// routePrefix names the internal wire channel each node reads forwarded messages from.
+const routePrefix = "_route."
+
+// NodeRole describes what a node does in the cluster.
+type NodeRole string
+
+const (
+	NodeRoleMember   NodeRole = "member"   // Owns strands and accepts forwarded messages
+	NodeRoleStandby  NodeRole = "standby"  // Receives mirrors but owns no strands
+	NodeRoleDraining NodeRole = "draining" // Being decommissioned; receives no new strands
+)
+
+// Node describes a member of a Conduktor cluster.
+type Node struct {
+	ID   string
+	Addr string
+	Role NodeRole
+}
+
+// Cluster tracks membership and strand ownership for a Conduktor.
+type Cluster struct {
+	mu     sync.RWMutex
+	self   string
+	wire   Wire              // Internal wire used for node-to-node traffic
+	nodes  map[string]Node   // Node ID -> Node
+	owners map[string]string // Strand -> owning node ID
+	epochs map[string]uint64 // Strand -> ownership epoch
+	done   chan struct{}
+
+	// Automatic rebalancing
+	rebalance *RebalanceConf
+	strands   map[string]bool // Strands eligible for assignment
+	handoff   func(strandID, to string, interval time.Duration)
+	moving    sync.WaitGroup // Handoffs in progress
+
+	watchers []func() // Called after ownership changes
+
+	// Strand registry
+	registry  map[string]registryEntry // Strand -> cluster-wide definition
+	conflicts []StrandConflict
+
+	// Quorum writes
+	pendingMu sync.Mutex
+	pending   map[string]chan error // Message ID -> replica acknowledgments, or the owner's quorum result
+
+	log *zap.Logger
+}
+
+// ClusterMake initializes cluster state for the local node, using wire for node-to-node traffic.
+func ClusterMake(self Node, wire Wire, options ...MakeOption) *Cluster {
+	if self.Role == "" {
+		self.Role = NodeRoleMember
+	}
+	return &Cluster{
+		self:     self.ID,
+		wire:     wire,
+		nodes:    map[string]Node{self.ID: self},
+		owners:   make(map[string]string),
+		epochs:   make(map[string]uint64),
+		done:     make(chan struct{}),
+		strands:  make(map[string]bool),
+		pending:  make(map[string]chan error),
+		registry: make(map[string]registryEntry),
+		log:      telemetry.MakeOptsApply(options).Log,
+	}
+}
+
+// Self returns the ID of the local node.
+func (cl *Cluster) Self() string {
+	return cl.self
+}
+
+// NodeAdd registers a peer node.
+func (cl *Cluster) NodeAdd(node Node) {
+	cl.mu.Lock()
+	if node.Role == "" {
+		node.Role = NodeRoleMember
+	}
+	cl.nodes[node.ID] = node
+	cl.mu.Unlock()
+
+	cl.log.Info("Cluster node added", zap.String("node", node.ID), zap.String("addr", node.Addr))
+	cl.Rebalance()
+	cl.registrySync(node.ID)
+}
+
+// NodeRemove removes a peer node. Strands it owned fall back to the local node, or are
+// reassigned when rebalancing is enabled.
+func (cl *Cluster) NodeRemove(nodeID string) {
+	cl.mu.Lock()
+	delete(cl.nodes, nodeID)
+	for strandID, owner := range cl.owners {
+		if owner == nodeID {
+			delete(cl.owners, strandID)
+			cl.epochs[strandID]++
+		}
+	}
+	cl.mu.Unlock()
+
+	cl.log.Info("Cluster node removed", zap.String("node", nodeID))
+	cl.Rebalance()
+	cl.notify()
+}
+
+// Assign makes nodeID the owner of strandID at a new epoch and announces it to every peer.
+func (cl *Cluster) Assign(strandID, nodeID string) error {
+	cl.mu.Lock()
+	if _, exists := cl.nodes[nodeID]; !exists {
+		cl.mu.Unlock()
+		return errors.New("node not found")
+	}
+	previous, assigned := cl.owners[strandID]
+	if !assigned {
+		previous = cl.self
+	}
+	cl.owners[strandID] = nodeID
+	cl.epochs[strandID]++
+	cl.mu.Unlock()
+
+	cl.log.Debug("Strand assigned", zap.String("strand", strandID), zap.String("node", nodeID))
+	cl.announce("", strandID)
+	cl.notify()
+	if previous == cl.self && nodeID != cl.self {
+		cl.move(strandID, nodeID, time.Millisecond)
+	}
+	return nil
+}
+
+// Owner returns the node that owns strandID. Unassigned strands are owned locally.
+func (cl *Cluster) Owner(strandID string) string {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	if owner, exists := cl.owners[strandID]; exists {
+		return owner
+	}
+	return cl.self
+}
+
+// Route implements StrandRouter.
+func (cl *Cluster) Route(strandID string) (addr string, local bool) {
+	owner := cl.Owner(strandID)
+	if owner == cl.self {
+		return "", true
+	}
+
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.nodes[owner].Addr, false
+}
+
+// Watch registers fn to be called whenever strand ownership changes.
+func (cl *Cluster) Watch(fn func()) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.watchers = append(cl.watchers, fn)
+}
+
+// notify calls the ownership watchers.
+func (cl *Cluster) notify() {
+	cl.mu.RLock()
+	watchers := cl.watchers
+	cl.mu.RUnlock()
+
+	for _, fn := range watchers {
+		fn()
+	}
+}
+
+// Close stops the node's forwarding loop.
+func (cl *Cluster) Close() {
+	select {
+	case <-cl.done:
+	default:
+		close(cl.done)
+	}
+}
+
+// forward ships msg to the owning node over the internal wire.
+func (cl *Cluster) forward(nodeID string, msg Msg) error {
+	if err := cl.send(nodeID, "", msg); err != nil {
+		cl.log.Error("Message forward failed", zap.String("strand", msg.Strand), zap.String("node", nodeID), zap.Error(err))
+		return err
+	}
+
+	messagesForwarded.WithLabelValues(msg.Strand).Inc()
+	cl.log.Debug("Message forwarded", zap.String("strand", msg.Strand), zap.String("node", nodeID))
+	return nil
+}
+
+// send delivers msg to a peer's route channel, tagged with the operation the peer should apply.
+func (cl *Cluster) send(nodeID, op string, msg Msg) error {
+	return cl.sendHeaders(nodeID, op, msg, nil)
+}
+
+// sendHeaders is send, adding headers to the envelope.
+func (cl *Cluster) sendHeaders(nodeID, op string, msg Msg, headers map[string]string) error {
+	envelope, err := envelopeMake(routePrefix+nodeID, msg)
+	if err != nil {
+		return err
+	}
+
+	envelope.Headers = map[string]string{
+		HeaderNode:  cl.self,
+		HeaderEpoch: strconv.FormatUint(cl.Epoch(msg.Strand), 10),
+	}
+	for name, value := range headers {
+		envelope.Headers[name] = value
+	}
+	if op != "" {
+		envelope.Headers[HeaderOp] = op
+	}
+	return cl.wire.SendMessage(context.Background(), envelope)
+}
+
+// ClusterJoin attaches the Conduktor to a cluster and starts accepting messages forwarded by peers.
+func (c *Conduktor) ClusterJoin(cl *Cluster) {
+	c.mu.Lock()
+	c.id = cl.self
+	c.cluster = cl
+	c.mu.Unlock()
+
+	cl.mu.Lock()
+	cl.handoff = func(strandID, to string, interval time.Duration) {
+		c.handoff(cl, strandID, to, interval)
+	}
+	cl.mu.Unlock()
+
+	go c.clusterServe(cl)
+	c.log.Info("Joined cluster", zap.String("node", cl.self))
+}
+
+// clusterServe applies operations peers send to the local node: forwarded messages, replication, and acknowledgments.
+func (c *Conduktor) clusterServe(cl *Cluster) {
+	channel := routePrefix + cl.self
+	ctx, cancel := doneContext(cl.done)
+	defer cancel()
+	for {
+		select {
+		case <-cl.done:
+			return
+		default:
+		}
+
+		envelope, err := cl.wire.ReceiveMessage(ctx, channel)
+		if err != nil {
+			// The route channel may not exist until a peer forwards to it
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		msg, err := envelopeOpen(envelope)
+		if err != nil {
+			c.log.Warn("Failed to unmarshal cluster message", zap.Error(err))
+			continue
+		}
+
+		from := envelope.Headers[HeaderNode]
+		switch op := envelope.Headers[HeaderOp]; op {
+		case opReplicate:
+			if cl.fenced(msg.Strand, envelope) {
+				// Refuse writes from a deposed owner and tell it who owns the strand now
+				err = cl.send(from, opFenced, Msg{ID: msg.ID, Strand: msg.Strand})
+				cl.announce(from, msg.Strand)
+				break
+			}
+			err = c.replicaApply(cl, from, op, msg)
+		case opRelease:
+			err = c.replicaApply(cl, from, op, msg)
+		case opReplicaAck:
+			cl.quorumAck(msg.ID, nil)
+		case opFenced:
+			cl.quorumAck(msg.ID, ErrFenced)
+		case opQuorum:
+			cl.quorumAck(msg.ID, quorumError(msg.Payload))
+		case opOwner:
+			c.ownerApply(cl, from, envelope, msg)
+		case opDrain, opLeave:
+			cl.drainApply(op, msg.Payload)
+		case opRegister, opUnregister, opConflict:
+			err = c.registryApply(cl, from, op, msg)
+		default:
+			if owner := cl.Owner(msg.Strand); owner != cl.self && cl.fenced(msg.Strand, envelope) {
+				// The sender routed with a stale view; pass the message on to the current owner
+				cl.announce(from, msg.Strand)
+				err = cl.forward(owner, msg)
+				break
+			}
+			// The owner replicates what it accepts, and reports the quorum to a sender waiting for it
+			timeout, quorumErr := time.ParseDuration(envelope.Headers[HeaderQuorum])
+			quorum := quorumErr == nil
+			var wait func(time.Duration) error
+			c.mu.Lock()
+			err = c.accept(context.Background(), msg)
+			if err == nil {
+				wait = c.replicate(msg, c.confs[msg.Strand].ReplicationFactor, quorum)
+			}
+			c.mu.Unlock()
+			if quorum {
+				go cl.quorumReply(from, msg, err, wait, timeout)
+			}
+		}
+		if err != nil {
+			c.log.Error("Failed to apply cluster message", zap.String("strand", msg.Strand), zap.String("node", from), zap.Error(err))
+			c.errs.report(ErrorSourceCluster, msg.Strand, msg.ID, err)
+		}
+	}
+}
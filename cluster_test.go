@@ -0,0 +1,386 @@
+package condukt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// ClusterTestFactory creates two clustered Conduktors (nodes "a" and "b") sharing an internal and a data wire.
+func ClusterTestFactory() (a *Conduktor, b *Conduktor, clA *Cluster, clB *Cluster) {
+	internal := wire.GoChanWireMake()
+	data := wire.GoChanWireMake()
+
+	a = ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), data)
+	b = ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), data)
+
+	clA = ClusterMake(Node{ID: "a"}, internal)
+	clA.NodeAdd(Node{ID: "b"})
+	clB = ClusterMake(Node{ID: "b"}, internal)
+	clB.NodeAdd(Node{ID: "a"})
+
+	a.ClusterJoin(clA)
+	b.ClusterJoin(clB)
+	return a, b, clA, clB
+}
+
+// Test Cross-Node Routing
+func TestClusterForward(t *testing.T) {
+	a, b, clA, clB := ClusterTestFactory()
+	defer clA.Close()
+	defer clB.Close()
+
+	b.StrandAdd("routed_channel", StrandConf{Durable: false, Ordered: true})
+	assert.NoError(t, clA.Assign("routed_channel", "b"))
+
+	// Node a does not have the strand, but forwards to the owner instead of erroring
+	assert.NoError(t, a.Send("routed_channel", "Forwarded 1"))
+
+	// Forwarding is asynchronous, so wait for the owner to deliver
+	var msg *Msg
+	assert.Eventually(t, func() bool {
+		msg, _ = b.Receive("routed_channel")
+		return msg != nil
+	}, time.Second, 10*time.Millisecond)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "Forwarded 1", msg.Payload)
+	}
+}
+
+// Test Mirrored Strand (Warm Standby)
+func TestMirror(t *testing.T) {
+	mirrorWire := wire.GoChanWireMake()
+	standbyWire := wire.GoChanWireMake()
+
+	primary := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	standby := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), standbyWire)
+
+	primary.StrandAdd("mirrored_channel", StrandConf{Durable: false, Ordered: true})
+	standby.StrandAdd("standby_channel", StrandConf{Durable: false, Ordered: true})
+
+	stop := standby.MirrorServe(mirrorWire, "standby_channel")
+	defer stop()
+	assert.NoError(t, primary.MirrorAdd("mirrored_channel", MirrorConf{Wire: mirrorWire, Strand: "standby_channel"}))
+	defer primary.MirrorRemove("mirrored_channel")
+
+	assert.NoError(t, primary.Send("mirrored_channel", "Mirrored 1"))
+
+	var msg *Msg
+	assert.Eventually(t, func() bool {
+		msg, _ = standby.Receive("standby_channel")
+		return msg != nil
+	}, time.Second, 10*time.Millisecond)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "Mirrored 1", msg.Payload)
+		assert.Equal(t, "standby_channel", msg.Strand)
+	}
+}
+
+// Test Federation (Edge -> Core With Renaming)
+func TestFederation(t *testing.T) {
+	edgeWire := wire.GoChanWireMake()
+	coreWire := wire.GoChanWireMake()
+
+	edge := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), edgeWire)
+	core := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), coreWire)
+
+	edge.StrandAdd("edge_channel", StrandConf{Durable: false, Ordered: true})
+	core.StrandAdd("core_channel", StrandConf{Durable: false, Ordered: true})
+
+	assert.NoError(t, core.FederationAdd(FederationLink{
+		Name:    "edge",
+		Wire:    edgeWire,
+		Strands: map[string]string{"edge_channel": "core_channel"},
+	}))
+	defer core.FederationRemove("edge")
+
+	assert.NoError(t, edge.Send("edge_channel", "Federated 1"))
+
+	var msg *Msg
+	assert.Eventually(t, func() bool {
+		msg, _ = core.Receive("core_channel")
+		return msg != nil
+	}, time.Second, 10*time.Millisecond)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "Federated 1", msg.Payload)
+		assert.Equal(t, edge.ID()+","+core.ID(), msg.Headers[HeaderHops])
+	}
+}
+
+// Test Cluster Topology
+func TestTopology(t *testing.T) {
+	a, _, clA, clB := ClusterTestFactory()
+	defer clA.Close()
+	defer clB.Close()
+
+	assert.NoError(t, clA.Assign("owned_by_b", "b"))
+	assert.Error(t, clA.Assign("owned_by_c", "c"))
+
+	topo := a.Topology()
+	assert.Equal(t, "a", topo.Self)
+	if assert.Len(t, topo.Nodes, 2) {
+		assert.Equal(t, "a", topo.Nodes[0].ID)
+		assert.Empty(t, topo.Nodes[0].Strands)
+		assert.Equal(t, "b", topo.Nodes[1].ID)
+		assert.Equal(t, NodeRoleMember, topo.Nodes[1].Role)
+		assert.Equal(t, []string{"owned_by_b"}, topo.Nodes[1].Strands)
+	}
+}
+
+// Test Automatic Rebalancing Hands Off Stored Messages
+func TestRebalance(t *testing.T) {
+	a, b, clA, clB := ClusterTestFactory()
+	defer clA.Close()
+	defer clB.Close()
+
+	strands := []string{"rebalance_1", "rebalance_2", "rebalance_3", "rebalance_4", "rebalance_5", "rebalance_6"}
+	for _, strandID := range strands {
+		a.StrandAdd(strandID, StrandConf{Durable: false, Ordered: true})
+		b.StrandAdd(strandID, StrandConf{Durable: false, Ordered: true})
+		assert.NoError(t, a.Send(strandID, "Moved "+strandID))
+	}
+
+	clA.SetRebalance(RebalanceConf{})
+
+	moved := 0
+	for _, strandID := range strands {
+		if clA.Owner(strandID) != "b" {
+			continue
+		}
+		moved++
+
+		// Drain the copy node a delivered before the move, then expect the one b redelivers
+		msg, _ := a.Receive(strandID)
+		assert.NotNil(t, msg)
+		assert.Eventually(t, func() bool {
+			msg, _ = b.Receive(strandID)
+			return msg != nil
+		}, time.Second, 10*time.Millisecond)
+		if assert.NotNil(t, msg) {
+			assert.Equal(t, "Moved "+strandID, msg.Payload)
+		}
+	}
+	assert.NotZero(t, moved)
+}
+
+// Test Geo-Replication With Strand-Level Home Regions
+func TestGeoReplication(t *testing.T) {
+	wan := wire.GoChanWireMake()
+	euWire := wire.GoChanWireMake()
+
+	us := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	eu := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), euWire)
+
+	us.StrandAdd("geo_channel", StrandConf{Durable: true, Ordered: true})
+	eu.StrandAdd("geo_channel", StrandConf{Durable: true, Ordered: true})
+
+	homes := map[string]string{"geo_channel": "us"}
+	stop := eu.GeoServe(wan, "eu")
+	defer stop()
+	assert.NoError(t, us.GeoAdd(GeoConf{Region: "us", Remote: "eu", Wire: wan, Homes: homes, Linger: 10 * time.Millisecond}))
+	defer us.GeoRemove("eu")
+	assert.NoError(t, eu.GeoAdd(GeoConf{Region: "eu", Remote: "us", Wire: wan, Homes: homes, Linger: 10 * time.Millisecond}))
+	defer eu.GeoRemove("us")
+
+	assert.NoError(t, us.Send("geo_channel", "Replicated 1"))
+	assert.ErrorIs(t, eu.Send("geo_channel", "Conflicting 1"), ErrNotHomeRegion)
+
+	var msg *Msg
+	assert.Eventually(t, func() bool {
+		msg, _ = eu.Receive("geo_channel")
+		return msg != nil
+	}, time.Second, 10*time.Millisecond)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "Replicated 1", msg.Payload)
+	}
+}
+
+// Test Messages Of Strands Without A Home Are Not Shipped Back To Their Region
+func TestGeoUnhomed(t *testing.T) {
+	wan := wire.GoChanWireMake()
+	us := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	eu := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	for _, c := range []*Conduktor{us, eu} {
+		c.StrandAdd("unhomed_channel", StrandConf{Durable: true})
+	}
+
+	stopUS := us.GeoServe(wan, "us")
+	defer stopUS()
+	stopEU := eu.GeoServe(wan, "eu")
+	defer stopEU()
+	assert.NoError(t, us.GeoAdd(GeoConf{Region: "us", Remote: "eu", Wire: wan, Linger: 10 * time.Millisecond}))
+	defer us.GeoRemove("eu")
+	assert.NoError(t, eu.GeoAdd(GeoConf{Region: "eu", Remote: "us", Wire: wan, Linger: 10 * time.Millisecond}))
+	defer eu.GeoRemove("us")
+
+	assert.NoError(t, us.Send("unhomed_channel", "Once"))
+
+	var msg *Msg
+	assert.Eventually(t, func() bool {
+		msg, _ = eu.Receive("unhomed_channel")
+		return msg != nil
+	}, time.Second, 10*time.Millisecond)
+	if assert.NotNil(t, msg) {
+		assert.Equal(t, "Once", msg.Payload)
+		assert.Equal(t, "us", msg.Headers[HeaderRegion])
+	}
+
+	// Neither region receives a copy bounced back from the other
+	receive := func(c *Conduktor) *Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		msg, _ := c.Receive("unhomed_channel", ReceiveContext(ctx))
+		return msg
+	}
+	if msg := receive(us); assert.NotNil(t, msg) {
+		assert.Equal(t, "Once", msg.Payload)
+	}
+	assert.Nil(t, receive(us))
+	assert.Nil(t, receive(eu))
+}
+
+// Test WebSocket Clients Are Redirected To The Owning Node
+func TestWSRedirect(t *testing.T) {
+	wireA := wire.WSWireMake()
+	wireB := wire.WSWireMake()
+	handle := func(wire *wire.WSWire) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wire.HandleWebSocketConnection(w, r, "ws_channel")
+		})
+	}
+	serverA := httptest.NewServer(handle(wireA))
+	defer serverA.Close()
+	serverB := httptest.NewServer(handle(wireB))
+	defer serverB.Close()
+	addrB := strings.TrimPrefix(serverB.URL, "http://")
+
+	cl := ClusterMake(Node{ID: "a", Addr: strings.TrimPrefix(serverA.URL, "http://")}, wire.GoChanWireMake())
+	cl.NodeAdd(Node{ID: "b", Addr: addrB})
+	assert.NoError(t, cl.Assign("ws_channel", "b"))
+	wireA.SetRouter(cl)
+
+	conn, err := wire.WSWireDial(context.Background(), "ws"+strings.TrimPrefix(serverA.URL, "http")+"/strands/ws_channel")
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		assert.Equal(t, addrB, conn.RemoteAddr().String())
+	}
+}
+
+// Test Quorum Writes
+func TestQuorumSend(t *testing.T) {
+	a, b, clA, clB := ClusterTestFactory()
+	defer clA.Close()
+	defer clB.Close()
+
+	conf := StrandConf{Durable: true, Ordered: true, ReplicationFactor: 2}
+	a.StrandAdd("quorum_channel", conf)
+	b.StrandAdd("quorum_channel", conf)
+	assert.NoError(t, a.Send("quorum_channel", "Replicated 1", WaitForQuorum(time.Second)))
+
+	// A majority of five copies needs two replicas, but only node b exists
+	wide := StrandConf{Durable: true, Ordered: true, ReplicationFactor: 5}
+	a.StrandAdd("wide_channel", wide)
+	assert.ErrorIs(t, a.Send("wide_channel", "Replicated 2", WaitForQuorum(200*time.Millisecond)), ErrQuorumTimeout)
+
+	// A send forwarded to the owner waits for the owner's quorum, which node b is part of
+	assert.NoError(t, clA.Assign("quorum_channel", "a"))
+	assert.Eventually(t, func() bool { return clB.Owner("quorum_channel") == "a" }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, b.Send("quorum_channel", "Forwarded 1", WaitForQuorum(time.Second)))
+	depth, err := b.durable.Depth(context.Background(), "quorum_channel")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, depth, "node b holds a copy of both messages")
+	b.StrandAdd("wide_channel", wide)
+	assert.NoError(t, clA.Assign("wide_channel", "a"))
+	assert.Eventually(t, func() bool { return clB.Owner("wide_channel") == "a" }, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, b.Send("wide_channel", "Forwarded 2", WaitForQuorum(200*time.Millisecond)), ErrQuorumTimeout)
+
+	// Without replicas there is no quorum to wait for
+	single := StrandConf{Durable: true, Ordered: true}
+	a.StrandAdd("single_channel", single)
+	assert.NoError(t, clA.Assign("single_channel", "a"))
+	assert.ErrorIs(t, a.Send("single_channel", "Alone 1", WaitForQuorum(time.Second)), ErrNoQuorum)
+	alone := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	alone.StrandAdd("single_channel", single)
+	assert.ErrorIs(t, alone.Send("single_channel", "Alone 2", WaitForQuorum(time.Second)), ErrNoQuorum)
+}
+
+// Test Cluster-Wide Strand Registry
+func TestRegistry(t *testing.T) {
+	a, b, clA, clB := ClusterTestFactory()
+	defer clA.Close()
+	defer clB.Close()
+
+	// A strand added on node a becomes usable on node b
+	conf := StrandConf{Durable: false, Ordered: true}
+	assert.NoError(t, a.StrandAdd("registered_channel", conf))
+	assert.Eventually(t, func() bool {
+		return b.volatile.HasStrand(context.Background(), "registered_channel")
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, conf.Defaults(), clB.Registry()["registered_channel"])
+	assert.ErrorIs(t, b.StrandAdd("registered_channel", StrandConf{Durable: true}), ErrStrandConflict)
+
+	// Racing incompatible definitions are detected on both nodes
+	clA.registryPublish("raced_channel", StrandConf{Durable: false})
+	clB.registryPublish("raced_channel", StrandConf{Durable: true})
+	assert.Eventually(t, func() bool {
+		return len(clA.Conflicts()) > 0 && len(clB.Conflicts()) > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// Test A Deposed Owner Is Fenced By Ownership Epochs
+func TestFencing(t *testing.T) {
+	a, b, clA, clB := ClusterTestFactory()
+	defer clA.Close()
+	defer clB.Close()
+
+	conf := StrandConf{Durable: true, Ordered: true, ReplicationFactor: 2}
+	a.StrandAdd("fenced_channel", conf)
+	assert.NoError(t, clA.Assign("fenced_channel", "a"))
+	assert.Eventually(t, func() bool { return clB.Epoch("fenced_channel") == 1 }, time.Second, 10*time.Millisecond)
+
+	// Node b takes over while node a is partitioned and misses the announcement
+	clB.adopt("fenced_channel", "b", 5)
+
+	// Node a still believes it owns the strand, so its replica write is refused
+	assert.ErrorIs(t, a.Send("fenced_channel", "Stale 1", WaitForQuorum(time.Second)), ErrFenced)
+
+	// Node b's correction deposes node a, which now forwards instead of accepting writes
+	assert.Eventually(t, func() bool { return clA.Owner("fenced_channel") == "b" }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(5), clA.Epoch("fenced_channel"))
+	assert.NoError(t, a.Send("fenced_channel", "Routed 1"))
+
+	var msg *Msg
+	assert.Eventually(t, func() bool {
+		msg, _ = b.Receive("fenced_channel")
+		return msg != nil && msg.Payload == "Routed 1"
+	}, time.Second, 10*time.Millisecond)
+}
+
+// Test Draining A Node Moves Its Strands And Removes It
+func TestDrain(t *testing.T) {
+	a, b, clA, clB := ClusterTestFactory()
+	defer clB.Close()
+
+	a.StrandAdd("drain_channel", StrandConf{Durable: false, Ordered: true})
+	assert.NoError(t, clA.Assign("drain_channel", "a"))
+	assert.NoError(t, a.Send("drain_channel", "Drained 1"))
+	msg, _ := a.Receive("drain_channel")
+	assert.NotNil(t, msg)
+
+	assert.NoError(t, clA.Drain("a", time.Second))
+	assert.Equal(t, "b", clA.Owner("drain_channel"))
+
+	// The unacknowledged message is redelivered by its new owner
+	assert.Eventually(t, func() bool {
+		msg, _ = b.Receive("drain_channel")
+		return msg != nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return len(clB.nodeStates()) == 1 }, time.Second, 10*time.Millisecond)
+}
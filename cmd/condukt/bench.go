@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/jkassis/condukt"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/store"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// benchMain runs `condukt bench`, load-testing a Conduktor on the configured store and wire and
+// printing its throughput and latency percentiles.
+func benchMain(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := flags.String("config", "", "Path to a YAML config file; CONDUKT_* environment variables override it")
+	storeType := flags.String("store", "", "Durable store to test, badger or ram; defaults to store.durable")
+	path := flags.String("path", "", "Badger data directory; defaults to a temporary one, removed afterwards")
+	wireType := flags.String("wire", "gochan", "Wire to test, gochan or udp (at wire.addr)")
+	producers := flags.Int("producers", 4, "Goroutines sending")
+	consumers := flags.Int("consumers", 4, "Goroutines receiving and acking; at least -strands")
+	strands := flags.Int("strands", 4, "Strands the producers and consumers spread over")
+	messages := flags.Int("messages", 10000, "Messages each producer sends; 0 sends until -duration")
+	duration := flags.Duration("duration", 0, "Longest the producers send; 0 is unlimited")
+	size := flags.Int("size", 256, "Payload bytes")
+	preset := flags.String("preset", "", "Strand preset: work_queue, event_log, or realtime")
+	durable := flags.Bool("durable", false, "Keep the strands in the durable store")
+	ordered := flags.Bool("ordered", false, "Deliver each strand's messages in order")
+	flags.Parse(args)
+
+	cfg, err := condukt.ConfigLoad(*configPath)
+	if err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+	telemetry.LogLevel.SetLevel(zapcore.ErrorLevel) // Keep the report readable
+	if *storeType != "" {
+		cfg.Store.Durable = *storeType
+	}
+	cfg.Wire.Type = *wireType
+	if cfg.Wire.Type == "ws" {
+		logger.Fatal("The ws wire only delivers to connected clients; bench gochan or udp")
+	}
+	if _, known := store.Presets[*preset]; *preset != "" && !known {
+		logger.Fatal("Unknown strand preset", zap.String("preset", *preset))
+	}
+	if cfg.Store.Durable == "badger" {
+		cfg.Store.Path = *path
+		if *path == "" {
+			if cfg.Store.Path, err = os.MkdirTemp("", "condukt-bench-"); err != nil {
+				logger.Fatal("Failed to create data directory", zap.Error(err))
+			}
+			defer os.RemoveAll(cfg.Store.Path)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	vStore, dStore, err := cfg.Stores()
+	if err != nil {
+		logger.Fatal("Failed to open stores", zap.Error(err))
+	}
+	transport, err := cfg.WireMake()
+	if err != nil {
+		logger.Fatal("Failed to create wire", zap.Error(err))
+	}
+	mq := condukt.ConduktorMake(vStore, dStore, transport)
+	defer mq.Shutdown(context.Background())
+
+	// Interrupting ends the run early, still reporting what was measured
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	conf := condukt.BenchConf{
+		Producers:   *producers,
+		Consumers:   *consumers,
+		Messages:    *messages,
+		Duration:    *duration,
+		MessageSize: *size,
+		Strands:     *strands,
+		Strand:      condukt.StrandPreset{Preset: *preset, Durable: *durable, Ordered: *ordered}.StrandConf(),
+	}
+	result, err := mq.Bench(ctx, conf)
+	if err != nil && ctx.Err() == nil {
+		logger.Fatal("Benchmark failed", zap.Error(err))
+	}
+
+	fmt.Printf("store       %s (durable strands: %t)\n", cfg.Store.Durable, conf.Strand.Durable)
+	fmt.Printf("wire        %s\n", cfg.Wire.Type)
+	fmt.Printf("sent        %d (%d failed)\n", result.Sent, result.SendErrors)
+	fmt.Printf("received    %d (%d acks failed)\n", result.Received, result.AckErrors)
+	fmt.Printf("elapsed     %v\n", result.Elapsed)
+	fmt.Printf("throughput  %.0f msg/s\n", result.Throughput())
+	fmt.Printf("send        %v\n", result.Send)
+	fmt.Printf("end-to-end  %v\n", result.EndToEnd)
+}
@@ -0,0 +1,377 @@
+// Command condukt runs a Conduktor as a daemon, configured from a YAML file and CONDUKT_*
+// environment variables.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/jkassis/condukt"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// logger is the process-wide logger.
+var logger = telemetry.Logger
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		benchMain(os.Args[2:])
+		return
+	}
+
+	configPath := flag.String("config", "", "Path to a YAML config file; CONDUKT_* environment variables override it")
+	flag.Parse()
+
+	cfg, err := condukt.ConfigLoad(*configPath)
+	if err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	secrets, err := condukt.SecretsMake(context.Background(), cfg.Secrets)
+	if err != nil {
+		logger.Fatal("Failed to open secrets providers", zap.Error(err))
+	}
+	if err := cfg.SecretsResolve(context.Background(), secrets); err != nil {
+		logger.Fatal("Failed to resolve secrets", zap.Error(err))
+	}
+
+	telemetry.LogLevel.SetLevel(cfg.Log.Level)
+	stopTracing, err := condukt.TracingStart(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Fatal("Failed to start tracing", zap.Error(err))
+	}
+
+	daemon := condukt.DaemonMake(cfg.Daemon)
+	if err := daemon.Start(); err != nil {
+		logger.Fatal("Failed to start daemon", zap.Error(err))
+	}
+
+	// Storage and transport
+	vStore, dStore, err := cfg.Stores()
+	if err != nil {
+		logger.Fatal("Failed to open stores", zap.Error(err))
+	}
+	stopGroupCommits := func() {}
+	if badger, ok := dStore.(*store.BadgerStore); ok && cfg.Store.GroupCommit.MaxDelay > 0 {
+		stopGroupCommits = badger.GroupCommitServe(cfg.Store.GroupCommit)
+	}
+	stopSnapshots := func() {}
+	if ram, ok := vStore.(*store.RamStore); ok && cfg.Store.Snapshot.Path != "" {
+		if err := ram.SnapshotRestore(cfg.Store.Snapshot.Path); err != nil {
+			logger.Fatal("Failed to restore volatile store snapshot", zap.Error(err))
+		}
+		stopSnapshots = ram.SnapshotServe(cfg.Store.Snapshot)
+	}
+	transport, err := cfg.WireMake()
+	if err != nil {
+		logger.Fatal("Failed to create wire", zap.Error(err))
+	}
+
+	// Initialize Message Queue
+	mq := condukt.ConduktorMake(vStore, dStore, transport)
+	auditor, err := cfg.Auditor(mq)
+	if err != nil {
+		logger.Fatal("Failed to open audit log", zap.Error(err))
+	}
+	mq.SetAuditor(auditor)
+	if cfg.Events.File != "" {
+		events, err := condukt.EventFileMake(cfg.Events.File)
+		if err != nil {
+			logger.Fatal("Failed to open message event log", zap.Error(err))
+		}
+		mq.SetEventLog(events)
+	}
+	mq.SetLimits(cfg.Limits)
+	mq.SetRecovery(cfg.Recovery)
+	mq.SetDispatch(cfg.Dispatch)
+	mq.SetRetryPolicy(cfg.Retry.Policy())
+	mq.SetAdmission(cfg.Admission)
+	for name, quota := range cfg.Namespaces {
+		if err := mq.SetNamespace(name, quota); err != nil {
+			logger.Fatal("Failed to set namespace quota", zap.String("namespace", name), zap.Error(err))
+		}
+	}
+	if cfg.Signing.Enabled() { // Before encryption, so plaintext is signed and verified
+		keys, err := cfg.Signing.SigningKeys(context.Background())
+		if err != nil {
+			logger.Fatal("Failed to load signing keys", zap.Error(err))
+		}
+		mq.Use(condukt.SigningMiddleware(keys))
+	}
+	if cfg.Encryption.Enabled() {
+		keys, err := cfg.Encryption.KeyRing(context.Background())
+		if err != nil {
+			logger.Fatal("Failed to load encryption keys", zap.Error(err))
+		}
+		mq.Use(condukt.EncryptionMiddleware(keys))
+	}
+	mq.Audit(condukt.ActorSystem, condukt.AuditConfigChange, "", map[string]string{
+		"config":            *configPath,
+		"max_payload_bytes": strconv.Itoa(cfg.Limits.MaxPayloadBytes),
+		"max_strands":       strconv.Itoa(cfg.Limits.MaxStrands),
+	}, nil)
+	if err := cfg.StrandsCreate(mq); err != nil {
+		logger.Fatal("Failed to create preset strand", zap.Error(err))
+	}
+
+	if err := condukt.MetricsRegister(prometheus.DefaultRegisterer, condukt.MetricsPrefix(cfg.Metrics.Prefix), condukt.MetricsLabels(cfg.Metrics.Labels)); err != nil {
+		logger.Fatal("Failed to register metrics", zap.Error(err))
+	}
+	stopMetricsExport := func() {}
+	if cfg.Metrics.Export.Exporter != "" {
+		registry := prometheus.NewRegistry()
+		if err := condukt.MetricsRegister(registry, condukt.MetricsPrefix(cfg.Metrics.Prefix), condukt.MetricsLabels(cfg.Metrics.Labels)); err != nil {
+			logger.Fatal("Failed to register metrics", zap.Error(err))
+		}
+		exporter, err := condukt.MetricsExporterMake(context.Background(), cfg.Metrics.Export)
+		if err != nil {
+			logger.Fatal("Failed to create metrics exporter", zap.Error(err))
+		}
+		stopMetricsExport = condukt.MetricsExportServe(registry, exporter, cfg.Metrics.Export.Interval)
+	}
+	stopReconcile := mq.DepthReconcile(cfg.Metrics.ReconcileInterval)
+	stopAlerts := func() {}
+	if cfg.Alerts.Webhook != "" {
+		stopAlerts = mq.AlertServe(cfg.Alerts)
+	}
+	stopBackups := func() {}
+	if cfg.Backup.Interval > 0 {
+		target, err := condukt.BackupTargetMake(context.Background(), cfg.Backup)
+		if err != nil {
+			logger.Fatal("Failed to open backup target", zap.Error(err))
+		}
+		stopBackups = mq.BackupServe(cfg.Backup, target)
+	}
+	stopSlowConsumers := func() {}
+	if cfg.SlowConsumers.Interval > 0 {
+		stopSlowConsumers = mq.SlowConsumerServe(cfg.SlowConsumers)
+	}
+	stopSinks, err := cfg.SinksServe(context.Background(), mq)
+	if err != nil {
+		logger.Fatal("Failed to start sinks", zap.Error(err))
+	}
+	stopSources, err := cfg.SourcesServe(context.Background(), mq)
+	if err != nil {
+		logger.Fatal("Failed to start sources", zap.Error(err))
+	}
+	stopScheduler, err := mq.SchedulerServe(cfg.Scheduler)
+	if err != nil {
+		logger.Fatal("Failed to start scheduler", zap.Error(err))
+	}
+	stopACL, err := mq.ACLServe(cfg.ACLFile, cfg.ACL)
+	if err != nil {
+		logger.Fatal("Failed to load ACL", zap.Error(err))
+	}
+	auth := condukt.AuthMake(mq, cfg.Auth)
+	if !auth.Enabled() {
+		logger.Warn("Admin APIs are unauthenticated; configure auth.keys, auth.users, auth.jwt, or auth.certs to protect them")
+	}
+	health := condukt.HealthMake(mq)
+	tlsConfig, stopTLS, err := cfg.Listen.TLS.ConfigServe()
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
+	}
+	servers := &listeners{health: health, tls: tlsConfig, listen: cfg.Listen}
+
+	// Start Prometheus server
+	if cfg.Metrics.Addr != "" {
+		metrics := condukt.MetricsServerMake(cfg.Metrics)
+		servers.serve("metrics", cfg.Metrics.Addr, metrics.Serve, metrics.Shutdown)
+	}
+
+	// Start WebSocket listener
+	if ws, ok := transport.(*wire.WSWire); ok && cfg.Listen.Wire != "" {
+		ws.SetAuthorizer(auth)
+		ws.SetClientLimits(cfg.Limits.Clients)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/ws/{strand}", func(w http.ResponseWriter, r *http.Request) {
+			ws.HandleWebSocketConnection(w, r, r.PathValue("strand"))
+		})
+		servers.serveHTTP("wire", cfg.Listen.Wire, &http.Server{Handler: mux})
+	}
+
+	// Start client listener
+	if cfg.Listen.Clients != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/client", condukt.ClientHandler(mq, auth))
+		mux.Handle("GET /client.js", condukt.ClientScriptHandler(mq))
+		servers.serveHTTP("clients", cfg.Listen.Clients, &http.Server{Handler: mux})
+	}
+
+	// Start admin server, which also serves the health probes and HTTP publishing
+	if cfg.Listen.Admin != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/admin/", auth.Handler(condukt.AdminHandler(mq)))
+		mux.Handle("/strands/", condukt.IngestHandler(mq, auth))
+		mux.Handle("/", health.Handler())
+		servers.serveHTTP("admin", cfg.Listen.Admin, &http.Server{Handler: mux})
+	}
+
+	// Start gRPC admin server
+	if cfg.Listen.GRPC != "" {
+		server := condukt.AdminGRPCServerMake(mq, grpc.UnaryInterceptor(auth.UnaryInterceptor()), servers.grpcCreds())
+		servers.serve("grpc", cfg.Listen.GRPC, server.Serve, grpcShutdown(server))
+	}
+
+	// Start gRPC data server
+	if cfg.Listen.GRPCData != "" {
+		server := condukt.DataGRPCServerMake(mq, auth, servers.grpcCreds())
+		// Subscribe streams never finish on their own, so stop at once; their unacked messages stay stored
+		servers.serve("grpc_data", cfg.Listen.GRPCData, server.Serve, func(ctx context.Context) error {
+			server.Stop()
+			return nil
+		})
+	}
+
+	// Resend messages left unacked by the last run
+	err = mq.RecoverUnackedMessages()
+	mq.Audit(condukt.ActorSystem, condukt.AuditRecover, "", nil, err)
+	if err != nil {
+		logger.Error("Recovery failed", zap.Error(err))
+	}
+
+	daemon.Ready()
+	logger.Info("Condukt ready")
+
+	sig := daemon.Wait()
+	logger.Info("Shutting down", zap.String("signal", sig.String()), zap.Duration("timeout", cfg.Daemon.ShutdownTimeout))
+	daemon.Stopping()
+	go func() {
+		sig := daemon.Wait()
+		logger.Warn("Second signal received; exiting without draining", zap.String("signal", sig.String()))
+		os.Exit(1)
+	}()
+
+	// Stop taking requests, then drain the Conduktor, all within the shutdown timeout
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Daemon.ShutdownTimeout)
+	defer cancel()
+	servers.shutdown(ctx)
+	stopTLS()
+	stopReconcile()
+	stopAlerts()
+	stopBackups()
+	stopSlowConsumers()
+	stopScheduler()
+	stopACL()
+	stopSources()
+	stopSinks()
+	if err := mq.Shutdown(ctx); err != nil {
+		logger.Error("Shutdown incomplete", zap.Error(err))
+	}
+	stopGroupCommits()
+	stopSnapshots()
+	stopMetricsExport()
+	if err := stopTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces", zap.Error(err))
+	}
+
+	daemon.Stop()
+	logger.Sync()
+}
+
+// listeners runs the network servers and records their status in health.
+type listeners struct {
+	health *condukt.Health
+	tls    *tls.Config // Of the HTTP and gRPC servers; nil serves plain TCP
+	listen condukt.ListenConfig
+	stops  []func(context.Context) error
+}
+
+// serveHTTP serves server on addr, over TLS if configured.
+func (l *listeners) serveHTTP(name, addr string, server *http.Server) {
+	if l.tls == nil {
+		l.serve(name, addr, server.Serve, server.Shutdown)
+		return
+	}
+	server.TLSConfig = l.tls
+	l.serve(name, addr, func(lis net.Listener) error { return server.ServeTLS(lis, "", "") }, server.Shutdown)
+}
+
+// grpcCreds returns the transport credentials of the gRPC servers, TLS if configured.
+func (l *listeners) grpcCreds() grpc.ServerOption {
+	if l.tls == nil {
+		return grpc.EmptyServerOption{}
+	}
+	return grpc.Creds(credentials.NewTLS(l.tls))
+}
+
+// serve listens on addr and serves in the background. stop gracefully shuts the server down.
+func (l *listeners) serve(name, addr string, serve func(net.Listener) error, stop func(context.Context) error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("Listener failed", zap.String("listener", name), zap.String("addr", addr), zap.Error(err))
+		l.health.Listening(name, err)
+		return
+	}
+	filter, err := l.listen.IPFilter(name)
+	if err != nil {
+		lis.Close()
+		logger.Error("Listener failed", zap.String("listener", name), zap.String("addr", addr), zap.Error(err))
+		l.health.Listening(name, err)
+		return
+	}
+	if filter != nil {
+		lis = filter.Listener(lis) // Rejects addresses before the TLS handshake
+	}
+	l.health.Listening(name, nil)
+	l.stops = append(l.stops, stop)
+	logger.Info("Listener started", zap.String("listener", name), zap.String("addr", addr))
+
+	go func() {
+		err := serve(lis)
+		if errors.Is(err, http.ErrServerClosed) || errors.Is(err, grpc.ErrServerStopped) || err == nil {
+			logger.Info("Listener closed", zap.String("listener", name))
+			err = errors.New("listener closed")
+		} else {
+			logger.Error("Listener stopped", zap.String("listener", name), zap.Error(err))
+		}
+		l.health.Listening(name, err)
+	}()
+}
+
+// shutdown stops every server in parallel, waiting until they finish or ctx expires.
+func (l *listeners) shutdown(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, stop := range l.stops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := stop(ctx); err != nil {
+				logger.Warn("Listener shutdown incomplete", zap.Error(err))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// grpcShutdown returns a shutdown for server that lets calls finish until ctx is done, then cancels
+// those left.
+func grpcShutdown(server *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Command conduktctl connects shell pipelines to a condukt broker, over the client protocol:
+//
+//	conduktctl produce STRAND  Send each line of stdin to STRAND as a message
+//	conduktctl tail STRAND     Print each message of STRAND to stdout, acknowledging it once printed
+//
+// With -json, produce reads and tail writes a JSON object per line, {"payload": "...", "headers":
+// {...}}, which tail gives the message's id, strand, and timestamp too, so
+//
+//	conduktctl tail -json orders | jq -c 'select(.headers.region == "eu")' | conduktctl produce -json orders-eu
+//
+// filters one strand into another. With -key SEP, produce splits each line at its first SEP into
+// the message's "key" header, which sinks and bridges order by, and its payload. The broker is -url, or CONDUKTCTL_URL, and the API key is
+// -api-key, or CONDUKTCTL_API_KEY.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/jkassis/condukt/client"
+	"go.uber.org/zap"
+)
+
+// record is a message as -json reads and writes it.
+type record struct {
+	ID        string            `json:"id,omitempty"`
+	Strand    string            `json:"strand,omitempty"`
+	Timestamp int64             `json:"timestamp,omitempty"` // Unix seconds the message was sent
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   string            `json:"payload"`
+}
+
+// produceInFlight caps the sends awaiting the broker's reply.
+const produceInFlight = 1024
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: conduktctl [flags] produce|tail [-json] [command flags] STRAND")
+		flag.PrintDefaults()
+	}
+	url := flag.String("url", envOr("CONDUKTCTL_URL", "ws://localhost:8081/client"), "Client endpoint of the broker")
+	apiKey := flag.String("api-key", os.Getenv("CONDUKTCTL_API_KEY"), "API key to authenticate with")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options := []client.Option{
+		client.Logger(zap.NewNop()),
+		client.OnError(func(err error) { fmt.Fprintln(os.Stderr, "conduktctl:", err) }),
+	}
+	if *apiKey != "" {
+		options = append(options, client.APIKey(*apiKey))
+	}
+	c := client.ClientMake(*url, options...)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	var err error
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "produce":
+		err = produce(ctx, c, args, os.Stdin)
+	case "tail":
+		err = tail(ctx, c, args, os.Stdout)
+	default:
+		err = fmt.Errorf("unknown command %q (want produce or tail)", command)
+	}
+	stop()
+	c.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "conduktctl:", err)
+		os.Exit(1)
+	}
+}
+
+// envOr returns the environment variable name, or fallback if it is unset.
+func envOr(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+// strandArg parses a command's flags and returns its STRAND argument.
+func strandArg(flags *flag.FlagSet, args []string) string {
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: conduktctl %s [flags] STRAND\n", flags.Name())
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	return flags.Arg(0)
+}
+
+// produce runs `conduktctl produce`, sending each line of in to a strand in order, and stopping
+// at the first failure. It returns once the broker has accepted every message sent.
+func produce(ctx context.Context, c *client.Client, args []string, in io.Reader) error {
+	flags := flag.NewFlagSet("produce", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, `Read a JSON object per line, {"payload": "...", "headers": {...}}, skipping blank lines`)
+	keySep := flags.String("key", "", `Split each line at its first occurrence of this separator into the "key" header and the payload`)
+	strandID := strandArg(flags, args)
+
+	// Sends are pipelined: one goroutine waits for their replies in order while in is read
+	waits := make(chan func(context.Context) error, produceInFlight)
+	failed := make(chan error, 1)
+	sent := 0
+	replied := make(chan struct{})
+	go func() {
+		defer close(replied)
+		for wait := range waits {
+			if err := wait(ctx); err != nil {
+				failed <- fmt.Errorf("message %d: %w", sent+1, err)
+				return
+			}
+			sent++
+		}
+	}()
+
+	err := produceLines(ctx, in, *asJSON, *keySep, func(line int, r record) error {
+		select {
+		case err := <-failed:
+			return err
+		default:
+		}
+		wait, err := c.SendAsync(ctx, strandID, r.Payload, client.SendHeaders(r.Headers))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		select {
+		case waits <- wait:
+			return nil
+		case err := <-failed:
+			return err
+		}
+	})
+	close(waits)
+	<-replied
+	select {
+	case failure := <-failed:
+		err = failure
+	default:
+	}
+	if err != nil {
+		return fmt.Errorf("sent %d messages to %s, then: %w", sent, strandID, err)
+	}
+	return nil
+}
+
+// produceLines calls send with each line of r as a record, numbering lines from 1, until r ends or
+// send fails. A non-empty keySep splits a plain line into its "key" header and payload; lines
+// without it have no key.
+func produceLines(ctx context.Context, r io.Reader, asJSON bool, keySep string, send func(line int, r record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r := record{Payload: scanner.Text()}
+		if asJSON {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			r = record{}
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		} else if key, payload, found := strings.Cut(r.Payload, keySep); keySep != "" && found {
+			r = record{Headers: map[string]string{"key": key}, Payload: payload}
+		}
+		if err := send(line, r); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// tail runs `conduktctl tail`, printing a strand's messages to w until interrupted or -n are
+// printed. Each message is acknowledged once printed and flushed, unless -ack=false, so a pipeline
+// reading stdout sees every message at least once.
+func tail(ctx context.Context, c *client.Client, args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Write a JSON object per message, with its id, strand, timestamp, headers, and payload")
+	limit := flags.Int("n", 0, "Exit after this many messages; 0 runs until interrupted")
+	ack := flags.Bool("ack", true, "Acknowledge messages once printed; unacknowledged ones stay on the broker")
+	strandID := strandArg(flags, args)
+
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	done := make(chan error, 1)
+	finished, printed := false, 0
+	stop, err := c.Subscribe(ctx, strandID, func(msg client.Msg) {
+		if finished {
+			return // Left on the broker
+		}
+		var err error
+		if *asJSON {
+			err = enc.Encode(record{ID: msg.ID, Strand: strandID, Timestamp: msg.Timestamp, Headers: msg.Headers, Payload: msg.Payload})
+		} else {
+			_, err = fmt.Fprintln(out, msg.Payload)
+		}
+		if err == nil {
+			err = out.Flush()
+		}
+		if err == nil && *ack {
+			err = c.Ack(ctx, strandID, msg.ID)
+		}
+		printed++
+		if err != nil || printed == *limit {
+			finished = true
+			done <- err
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
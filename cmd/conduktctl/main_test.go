@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt"
+	"github.com/jkassis/condukt/client"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// brokerMake starts a broker with the given strands, returning it and a client connected to it.
+func brokerMake(t *testing.T, strandIDs ...string) (*condukt.Conduktor, *client.Client) {
+	mq := condukt.ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	for _, strandID := range strandIDs {
+		assert.NoError(t, mq.StrandAdd(strandID, condukt.StrandConf{Durable: false, Ordered: true}))
+	}
+	server := httptest.NewServer(condukt.ClientHandler(mq, nil))
+	t.Cleanup(server.Close)
+	c := client.ClientMake("ws://"+server.Listener.Addr().String()+"/client", client.Logger(zap.NewNop()))
+	t.Cleanup(func() { c.Close() })
+	return mq, c
+}
+
+// lockedBuffer is a bytes.Buffer a test reads while tail writes it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Test Produce Sends Each Line Of Stdin, Splitting Off Keys, And Tail Prints Them As JSON
+func TestProduceTail(t *testing.T) {
+	mq, c := brokerMake(t, "lines")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	in := strings.NewReader("eu|first\nsecond\n\nus|third|with separator\n")
+	if !assert.NoError(t, produce(ctx, c, []string{"-key", "|", "lines"}, in)) {
+		return
+	}
+	info, err := mq.Strand("lines")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, info.Depth, "every line is a message, blank ones too")
+
+	var out bytes.Buffer
+	if !assert.NoError(t, tail(ctx, c, []string{"-json", "-n", "4", "lines"}, &out)) {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if !assert.Len(t, lines, 4) {
+		return
+	}
+	want := []struct{ key, payload string }{{"eu", "first"}, {"", "second"}, {"", ""}, {"us", "third|with separator"}}
+	for i, line := range lines {
+		var r record
+		if !assert.NoError(t, json.Unmarshal([]byte(line), &r), line) {
+			continue
+		}
+		assert.NotEmpty(t, r.ID)
+		assert.Equal(t, "lines", r.Strand)
+		assert.NotZero(t, r.Timestamp)
+		assert.Equal(t, want[i].key, r.Headers["key"], line)
+		assert.Equal(t, want[i].payload, r.Payload, line)
+	}
+	assert.Eventually(t, func() bool {
+		info, err := mq.Strand("lines")
+		return err == nil && info.Depth == 0
+	}, time.Second, 10*time.Millisecond, "tail acknowledges what it prints")
+}
+
+// Test Produce Reads JSON Records And Tail Exits Cleanly When Its Stream Ends
+func TestProduceJSONTail(t *testing.T) {
+	mq, c := brokerMake(t, "records")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	in := strings.NewReader(`{"payload": "one", "headers": {"key": "a"}}` + "\n\n" + `{"payload": "two"}` + "\n")
+	if !assert.NoError(t, produce(ctx, c, []string{"-json", "records"}, in)) {
+		return
+	}
+	err := produce(ctx, c, []string{"-json", "records"}, strings.NewReader("not json\n"))
+	assert.ErrorContains(t, err, "line 1")
+
+	// Without -n, tail prints until interrupted, then returns with its output flushed
+	out := &lockedBuffer{}
+	tailCtx, interrupt := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- tail(tailCtx, c, []string{"-ack=false", "records"}, out) }()
+	assert.Eventually(t, func() bool { return out.String() == "one\ntwo\n" }, time.Second, 10*time.Millisecond)
+	interrupt()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("tail did not exit when interrupted")
+	}
+	assert.Equal(t, "one\ntwo\n", out.String())
+	info, err := mq.Strand("records")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, info.Depth, "-ack=false leaves messages on the broker")
+}
@@ -1,43 +1,151 @@
-package main
+// Package condukt is an embeddable message queue. A Conduktor keeps messages on strands in a
+// volatile or a durable Store and delivers them over a Wire; packages store and wire implement
+// both, and cmd/condukt runs a Conduktor as a daemon.
+package condukt
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/wire"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // Conduktor manages sending and receiving messages through the appropriate store.
 type Conduktor struct {
-	mu       sync.Mutex
-	wire     Wire
-	volatile Store // Non-durable strands
-	durable  Store // Durable strands
+	strands      strandLocks  // Serializes the sends and acks of each strand
+	mu           sync.RWMutex // Guards the fields below; sends and acks read-lock it and lock their strand
+	id           string
+	wire         Wire
+	wireType     string // Type of wire, for traces
+	volatile     Store  // Non-durable strands
+	durable      Store  // Durable strands
+	cluster      *Cluster
+	mirrors      map[string]*mirror     // Strand -> standby mirror
+	links        map[string]*federation // Link name -> federation link
+	region       string
+	homes        map[string]string      // Strand -> home region
+	geo          map[string]*geoShipper // Remote region -> shipper
+	confs        map[string]StrandConf  // Strand -> config, for strands added through this Conduktor
+	paused       map[string]*[]string   // Paused strands -> IDs of the deliveries held back
+	schemas      map[string]Schema      // Strand -> validator of its sent payloads
+	maintained   map[string]bool        // Strands in maintenance mode, rejecting sends
+	throttled    map[string]*[]Msg      // Strands with slow consumers -> deliveries held back
+	limits       Limits
+	recovery     RecoveryConf
+	retry        RetryPolicy  // Retries failed wire sends; nil never retries
+	admission    admission    // Sheds or delays sends to stores under pressure
+	dispatch     *dispatchers // Dispatchers of subscribed strands
+	strandStores strandStores
+	strandSeries strandSeriesCache
+	acl          ACL
+	aclPath      string                // ACL file ACLSet writes; empty while none is served
+	namespaces   map[string]*namespace // Namespace -> quota
+	deliveries   *deliveryLog          // Delivery records of recent messages, for Trace
+	closing      bool                  // Set by Shutdown
+	auditor      Auditor
+	scheduler    *scheduler       // Publishes scheduled messages; nil while no scheduler runs
+	errs         *errorHooks      // OnError handlers
+	now          func() time.Time // Stamps message IDs and timestamps
+	middleware   []Middleware     // Hooks around Send, Receive, and Acknowledge, outermost first
+	log          *zap.Logger
+
+	maintenance bool        // Broker-wide maintenance mode, rejecting sends
+	recovered   atomic.Bool // Set once RecoverUnackedMessages completes
 }
 
 // ConduktorMake initializes a new Conduktor with separate volatile and durable stores.
-func ConduktorMake(volatile Store, durable Store, wire Wire) *Conduktor {
+func ConduktorMake(volatile Store, durable Store, wire Wire, options ...MakeOption) *Conduktor {
+	opts := telemetry.MakeOptsApply(options)
 	return &Conduktor{
+		id:       fmt.Sprintf("%d", opts.Now().UnixNano()),
 		wire:     wire,
+		wireType: fmt.Sprintf("%T", wire),
 		volatile: volatile,
 		durable:  durable,
+		mirrors:  make(map[string]*mirror),
+		links:    make(map[string]*federation),
+		homes:    make(map[string]string),
+		geo:      make(map[string]*geoShipper),
+		confs:    make(map[string]StrandConf),
+		paused:   make(map[string]*[]string),
+		schemas:  make(map[string]Schema),
+
+		maintained: make(map[string]bool),
+		throttled:  make(map[string]*[]Msg),
+		namespaces: make(map[string]*namespace),
+		deliveries: deliveryLogMake(opts.Log),
+		dispatch:   dispatchersMake(),
+		admission:  admission{volatile: storePressureMake("volatile"), durable: storePressureMake("durable")},
+
+		strandStores: strandStores{stores: make(map[string]Store)},
+		strandSeries: strandSeriesCache{strands: make(map[string]*strandSeries), quiet: make(map[string]bool)},
+		errs:         &errorHooks{},
+		now:          opts.Now,
+		log:          opts.Log,
 	}
 }
 
+// ID returns the identity used for loop prevention between Conduktors.
+func (c *Conduktor) ID() string {
+	return c.id
+}
+
 // StrandAdd registers a new strand and determines whether to store it in volatile or durable storage.
+// Unset settings take their defaults; settings that are invalid together are rejected.
 func (c *Conduktor) StrandAdd(strandID string, config StrandConf) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.strandAdd(strandID, config)
+}
+
+// strandAdd registers a new strand. Callers must hold c.mu.
+func (c *Conduktor) strandAdd(strandID string, config StrandConf) error {
+	config = config.Defaults()
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("strand %s: %w", strandID, err)
+	}
+	if c.cluster != nil {
+		if err := c.cluster.registryCheck(strandID, config); err != nil {
+			c.log.Error("Failed to create strand", zap.String("strand", strandID), zap.Error(err))
+			return err
+		}
+	}
+
+	if err := c.limitStrands(); err != nil {
+		c.log.Error("Failed to create strand", zap.String("strand", strandID), zap.Error(err))
+		return err
+	}
+	if err := c.quotaStrands(strandID); err != nil {
+		return err
+	}
+
 	store := c.selectStore(config.Durable)
-	if err := store.CreateStrand(strandID, config); err != nil {
-		logger.Error("Failed to create strand", zap.String("strand", strandID), zap.Error(err))
+	if err := store.CreateStrand(context.Background(), strandID, config); err != nil {
+		c.log.Error("Failed to create strand", zap.String("strand", strandID), zap.Error(err))
 		return err
 	}
 
-	logger.Debug("Strand added",
+	c.strandStores.remember(strandID, store)
+	c.strandSeries.get(strandID) // Resolve them now rather than on the first send
+	c.confs[strandID] = config
+	if c.cluster != nil {
+		c.cluster.registryPublish(strandID, config)
+		c.cluster.Track(strandID)
+	}
+
+	c.log.Debug("Strand added",
 		zap.String("strand", strandID),
 		zap.Bool("durable", config.Durable),
 	)
@@ -45,77 +153,394 @@ func (c *Conduktor) StrandAdd(strandID string, config StrandConf) error {
 }
 
 // Send places a message in the appropriate store and sends it via the configured transport.
-func (c *Conduktor) Send(strandID string, payload string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// Messages for strands owned by another cluster node are forwarded to the owner.
+func (c *Conduktor) Send(strandID string, payload string, opts ...SendOption) error {
+	var o sendOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := c.Authorize(authContext(o.ctx), o.identity, ACLPublish, strandID); err != nil {
+		return err
+	}
 
-	store, err := c.getStore(strandID)
-	if err != nil {
+	wait, err := c.send(strandID, payload, o)
+	if err != nil || wait == nil {
 		return err
 	}
+	return wait(o.timeout)
+}
+
+// sendMsgs recycles the messages send passes through the Send middleware.
+var sendMsgs = sync.Pool{New: func() any { return new(Msg) }}
+
+// send publishes a message through the Send middleware, returning a function that waits for
+// replicas if requested.
+func (c *Conduktor) send(strandID string, payload string, o sendOpts) (wait func(time.Duration) error, err error) {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracer.Start(ctx, "condukt.send", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attrStrand.String(strandID)))
+	defer func() { spanEnd(span, err) }()
 
-	msg := Msg{
-		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+	if err := c.schemaCheck(strandID, payload); err != nil {
+		return nil, err
+	}
+	if err := c.admit(ctx, strandID); err != nil {
+		return nil, err
+	}
+
+	now := c.now()
+	msg := sendMsgs.Get().(*Msg)
+	defer func() {
+		*msg = Msg{}
+		sendMsgs.Put(msg)
+	}()
+	*msg = Msg{
+		ID:        strconv.FormatInt(now.UnixNano(), 10),
 		Strand:    strandID,
 		Payload:   payload,
 		Acked:     false,
-		Timestamp: time.Now().Unix(),
+		Timestamp: now.Unix(),
+		Headers:   make(map[string]string, len(o.headers)+3), // With the hops and trace context headers
 	}
+	for k, v := range o.headers {
+		msg.Headers[k] = v
+	}
+	msg.Headers[HeaderHops] = c.id
+	span.SetAttributes(attrMsgID.String(msg.ID))
+	traceContext.Inject(ctx, propagation.MapCarrier(msg.Headers))
 
-	// Always save the message, regardless of durability
-	if err := store.Save(msg); err != nil {
+	publish := func(ctx context.Context, msg *Msg) (err error) {
+		wait, err = c.publish(ctx, *msg, o)
 		return err
 	}
+	err = c.sendHandler(publish)(WithActor(ctx, o.identity), msg)
+	return wait, err
+}
+
+// publish stores and transmits a message under its strand's lock, or forwards it to the strand's owner.
+func (c *Conduktor) publish(ctx context.Context, msg Msg, o sendOpts) (wait func(time.Duration) error, err error) {
+	defer c.lockStrand(msg.Strand)()
+
+	if c.closing {
+		return nil, ErrShuttingDown
+	}
+	if err := c.checkMaintenance(msg.Strand); err != nil {
+		return nil, err
+	}
+	if c.limits.MaxPayloadBytes > 0 && len(msg.Payload) > c.limits.MaxPayloadBytes {
+		return nil, ErrPayloadTooLarge
+	}
+
+	if home, exists := c.homes[msg.Strand]; exists && home != c.region {
+		return nil, ErrNotHomeRegion
+	}
+	if err := c.quotaSend(msg.Strand, msg.Size()); err != nil {
+		return nil, err
+	}
+
+	if c.cluster == nil {
+		if o.quorum {
+			return nil, ErrNoQuorum
+		}
+	} else if owner := c.cluster.Owner(msg.Strand); owner != c.cluster.Self() {
+		if o.quorum {
+			return c.cluster.forwardQuorum(owner, msg, o.timeout)
+		}
+		return nil, c.cluster.forward(owner, msg)
+	}
+
+	if err := c.overflow(msg); err != nil {
+		return nil, err
+	}
+	if err := c.accept(ctx, msg); err != nil {
+		return nil, err
+	}
+	c.strandSeries.get(msg.Strand).namespaceSent.Inc()
 
-	// Send via transport
-	if err := c.wire.SendMessage(msg); err != nil {
-		logger.Error("Message send failed", zap.String("strand", strandID), zap.Error(err))
+	if c.cluster != nil {
+		return c.replicate(msg, c.confs[msg.Strand].ReplicationFactor, o.quorum), nil
+	}
+	return nil, nil
+}
+
+// accept stores a message and sends it via transport, tracing both as children of the span in ctx,
+// or else of the span in the message's trace context headers. Callers must hold c.mu, or read-lock it
+// and hold the strand's lock.
+func (c *Conduktor) accept(ctx context.Context, msg Msg) error {
+	start := time.Now()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = MsgContext(ctx, msg)
+	}
+	store, err := c.getStore(msg.Strand)
+	if err != nil {
+		return err
+	}
+	metrics := c.strandSeries.get(msg.Strand)
+
+	// Encode once when both the store and the wire take messages encoded with the same codec
+	var data []byte
+	saver, saveEncoded := store.(EncodedSaver)
+	if sender, sendEncoded := c.wire.(EncodedSender); saveEncoded && sendEncoded && saver.Codec() == sender.Codec() {
+		if data, err = wire.MsgEncodeCodec(msg, saver.Codec()); err != nil {
+			return err
+		}
+	}
+
+	// Always save the message, regardless of durability
+	_, span := tracer.Start(ctx, "condukt.store",
+		trace.WithAttributes(attrStrand.String(msg.Strand), attrMsgID.String(msg.ID), attrStore.String(c.storeName(store))))
+	saveStart := time.Now()
+	if data != nil {
+		err = saver.SaveEncoded(ctx, msg, data)
+	} else {
+		err = store.Save(ctx, msg)
+	}
+	spanEnd(span, err)
+	if err != nil {
 		return err
 	}
+	c.pressure(store).observe(time.Since(saveStart))
+	c.deliveries.stored(msg, c.storeName(store), trace.SpanContextFromContext(ctx))
+	if !metrics.quiet {
+		metrics.storeSeconds.Observe(time.Since(start).Seconds())
+	}
+
+	// Send via transport, unless deliveries are paused or held back from a slow consumer
+	paused := c.paused[msg.Strand]
+	held, throttled := c.throttled[msg.Strand]
+	switch {
+	case paused != nil:
+		*paused = append(*paused, msg.ID)
+	case throttled:
+		*held = append(*held, msg)
+	default:
+		if err := c.transmit(ctx, msg, data); err != nil {
+			c.log.Error("Message send failed", zap.String("strand", msg.Strand), zap.Error(err))
+			return err
+		}
+		if !metrics.quiet {
+			metrics.wireSeconds.Observe(time.Since(start).Seconds())
+		}
+	}
+
+	if m, exists := c.mirrors[msg.Strand]; exists {
+		m.enqueue(msg)
+	}
 
-	messagesSent.WithLabelValues(strandID).Inc()
-	logger.Debug("Message sent", zap.String("strand", strandID), zap.String("payload", msg.Payload))
+	// Ship durable messages homed here to other regions, unless replicated from one
+	if origin := msg.Headers[HeaderRegion]; store == c.durable && len(c.geo) > 0 && (origin == "" || origin == c.region) {
+		if home, exists := c.homes[msg.Strand]; !exists || home == c.region {
+			for _, g := range c.geo {
+				g.enqueue(msg)
+			}
+		}
+	}
+
+	if !metrics.quiet {
+		metrics.sent.Inc()
+	}
+	msgLog(c.log, msg).Debug("Message sent", zap.String("strand", msg.Strand), zap.String("payload", msg.Payload))
 	return nil
 }
 
-// Receive retrieves the next message from the queue via transport.
-func (c *Conduktor) Receive(strandID string) (*Msg, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// Receive retrieves the next message from the queue via transport, through the Deliver middleware.
+// The Conduktor lock is not held while waiting, so forwarded and mirrored messages can still be accepted.
+func (c *Conduktor) Receive(strandID string, opts ...ReceiveOption) (*Msg, error) {
+	o := receiveOpts{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := c.Authorize(o.ctx, o.identity, ACLSubscribe, strandID); err != nil {
+		return nil, err
+	}
 
 	// Attempt to receive from the transport
-	msg, err := c.wire.ReceiveMessage(strandID)
+	start := time.Now()
+	msg, err := c.wire.ReceiveMessage(o.ctx, strandID)
 	if err != nil {
-		logger.Warn("No messages available", zap.String("strand", strandID), zap.Error(err))
+		c.log.Warn("No messages available", zap.String("strand", strandID), zap.Error(err))
 		return nil, err
 	}
 
-	messagesReceived.WithLabelValues(strandID).Inc()
-	logger.Debug("Message received", zap.String("strand", strandID), zap.String("payload", msg.Payload))
+	if err := c.received(WithActor(o.ctx, o.identity), strandID, msg, start); err != nil {
+		return nil, err
+	}
 	return msg, nil
 }
 
-// Acknowledge marks a message as processed and removes it from storage.
-func (c *Conduktor) Acknowledge(strandID, msgID string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// received records a message taken off the wire, whose receive began at start, and passes it
+// through the Deliver middleware.
+func (c *Conduktor) received(ctx context.Context, strandID string, msg *Msg, start time.Time) error {
+	_, span := tracer.Start(MsgContext(context.Background(), *msg), "condukt.receive", trace.WithTimestamp(start), trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrStrand.String(strandID), attrMsgID.String(msg.ID), attrWire.String(c.wireType)))
+	span.End()
+
+	deliver := func(ctx context.Context, msg *Msg) error {
+		c.deliveries.received(*msg)
+		if metrics := c.strandSeries.get(strandID); !metrics.quiet {
+			metrics.received.Inc()
+		}
+		msgLog(c.log, *msg).Debug("Message received", zap.String("strand", strandID), zap.String("payload", msg.Payload))
+		return nil
+	}
+	if err := c.deliverHandler(deliver)(ctx, msg); err != nil {
+		c.log.Warn("Delivery refused by middleware", zap.String("strand", strandID), zap.String("msgID", msg.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Messages iterates over the messages received from strandID until ctx is done:
+//
+//	for msg, err := range mq.Messages(ctx, strandID) {
+//
+// Messages still need acknowledging. Receive errors are yielded with a nil message, and unless
+// the loop breaks, receiving resumes after a short backoff, since wires like GoChanWire fail
+// until a strand's first send. Denied receives end the iteration after yielding ErrDenied.
+func (c *Conduktor) Messages(ctx context.Context, strandID string, opts ...ReceiveOption) iter.Seq2[*Msg, error] {
+	opts = append(opts[:len(opts):len(opts)], ReceiveContext(ctx))
+	return func(yield func(*Msg, error) bool) {
+		for ctx.Err() == nil {
+			msg, err := c.Receive(strandID, opts...)
+			if err == nil {
+				if !yield(msg, nil) {
+					return
+				}
+				continue
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+			if !yield(nil, err) || errors.Is(err, ErrDenied) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// Acknowledge marks a message as processed and removes it from storage, through the Acknowledge
+// middleware.
+func (c *Conduktor) Acknowledge(strandID, msgID string) (err error) {
+	if strandID == AuditStrand {
+		return ErrAuditAppendOnly
+	}
+
+	ctx := c.msgContext(msgID)
+	_, span := tracer.Start(ctx, "condukt.ack", trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrStrand.String(strandID), attrMsgID.String(msgID)))
+	defer func() { spanEnd(span, err) }()
+
+	return c.ackHandler(c.acknowledge)(ctx, strandID, msgID)
+}
+
+// acknowledge removes an acknowledged message from storage under its strand's lock.
+func (c *Conduktor) acknowledge(ctx context.Context, strandID, msgID string) error {
+	defer c.lockStrand(strandID)()
 
 	store, err := c.getStore(strandID)
 	if err != nil {
 		return err
 	}
+	stored := c.storedAt(store, strandID, msgID)
 
-	if err := store.Acknowledge(strandID, msgID); err != nil {
-		logger.Error("Acknowledgment failed", zap.String("strand", strandID), zap.String("msgID", msgID), zap.Error(err))
+	if err := store.Acknowledge(ctx, strandID, msgID); err != nil {
+		c.log.Error("Acknowledgment failed", zap.String("strand", strandID), zap.String("msgID", msgID), zap.Error(err))
 		return err
 	}
 
-	logger.Debug("Message acknowledged", zap.String("strand", strandID), zap.String("msgID", msgID))
+	c.acked(ctx, strandID, msgID, stored)
+	c.log.Debug("Message acknowledged", zap.String("strand", strandID), zap.String("msgID", msgID))
 	return nil
 }
 
+// AcknowledgeBatch acknowledges msgIDs of strandID in one store transaction, for consumers that
+// process in bulk. Each message passes through the Acknowledge middleware first; if any is
+// refused, none are acknowledged.
+func (c *Conduktor) AcknowledgeBatch(strandID string, msgIDs []string) (err error) {
+	if strandID == AuditStrand {
+		return ErrAuditAppendOnly
+	}
+
+	ctx, span := tracer.Start(context.Background(), "condukt.ack_batch", trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrStrand.String(strandID), attrMessages.Int(len(msgIDs))))
+	defer func() { spanEnd(span, err) }()
+
+	var accepted []string
+	ack := c.ackHandler(func(ctx context.Context, strandID, msgID string) error {
+		accepted = append(accepted, msgID)
+		return nil
+	})
+	for _, msgID := range msgIDs {
+		if err := ack(c.msgContext(msgID), strandID, msgID); err != nil {
+			return err
+		}
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+
+	defer c.lockStrand(strandID)()
+
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return err
+	}
+	stored := make([]time.Time, len(accepted))
+	for i, msgID := range accepted {
+		stored[i] = c.storedAt(store, strandID, msgID)
+	}
+
+	if err := store.AcknowledgeBatch(ctx, strandID, accepted); err != nil {
+		c.log.Error("Batch acknowledgment failed", zap.String("strand", strandID), zap.Int("messages", len(accepted)), zap.Error(err))
+		return err
+	}
+
+	for i, msgID := range accepted {
+		c.acked(c.msgContext(msgID), strandID, msgID, stored[i])
+	}
+	c.log.Debug("Messages acknowledged", zap.String("strand", strandID), zap.Int("messages", len(accepted)))
+	return nil
+}
+
+// acked releases the replicas of an acknowledged message and records its acknowledgement.
+// Callers must hold c.mu, or read-lock it and hold the strand's lock.
+func (c *Conduktor) acked(ctx context.Context, strandID, msgID string, stored time.Time) {
+	d, _ := c.deliveries.get(msgID)
+	if c.cluster != nil {
+		c.release(strandID, msgID, c.confs[strandID].ReplicationFactor)
+	}
+	c.deliveries.removed(strandID, msgID, DeliveryAcked)
+	if d.Received != 0 {
+		processSpan(ctx, strandID, msgID, d.Received)
+	}
+	if metrics := c.strandSeries.get(strandID); !metrics.quiet {
+		if !stored.IsZero() {
+			metrics.ackSeconds.Observe(time.Since(stored).Seconds())
+		}
+		metrics.acked.Inc()
+	}
+}
+
 // StrandRemove deletes a strand and all of its messages.
-func (c *Conduktor) StrandRemove(strandID string) error {
+func (c *Conduktor) StrandRemove(strandID string, opts ...RemoveOption) error {
+	if strandID == AuditStrand {
+		return ErrAuditAppendOnly
+	}
+	var o removeOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := c.Authorize(authContext(o.ctx), o.identity, ACLAdmin, strandID); err != nil {
+		return err
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -124,72 +549,194 @@ func (c *Conduktor) StrandRemove(strandID string) error {
 		return err
 	}
 
-	if err := store.DeleteStrand(strandID); err != nil {
-		logger.Error("Failed to delete strand", zap.String("strand", strandID), zap.Error(err))
+	if err := store.DeleteStrand(context.Background(), strandID); err != nil {
+		c.log.Error("Failed to delete strand", zap.String("strand", strandID), zap.Error(err))
 		return err
 	}
+	c.strandStores.forget(strandID)
+	c.strandSeries.forget(strandID)
+	delete(c.confs, strandID)
+	delete(c.paused, strandID)
+	delete(c.schemas, strandID)
+	delete(c.maintained, strandID)
+	delete(c.throttled, strandID)
+	consumerLagMessages.DeleteLabelValues(strandID)
+	consumerLagSeconds.DeleteLabelValues(strandID)
+	if c.cluster != nil {
+		c.cluster.registryRetract(strandID)
+	}
 
-	logger.Info("Strand deleted", zap.String("strand", strandID))
+	c.log.Info("Strand deleted", zap.String("strand", strandID))
 	return nil
 }
 
-// selectStore determines which store to use based on strand durability.
-func (c *Conduktor) selectStore(durable bool) Store {
-	if durable {
-		return c.durable
-	}
-	return c.volatile
+// StrandInfo describes a strand and its current depth.
+type StrandInfo struct {
+	ID     string     `json:"id"`
+	Config StrandConf `json:"config"`
+	Depth  int        `json:"depth"`
+	Bytes  int64      `json:"bytes"` // Stored size of the unacked messages
+	Paused bool       `json:"paused"`
+
+	Maintenance bool `json:"maintenance"` // Rejecting sends, by itself or broker-wide
 }
 
-// getStore retrieves the store and configuration for a given strand.
-func (c *Conduktor) getStore(strandID string) (Store, error) {
-	// Check both stores for the strand configuration
+// Strands lists the strands in both stores, sorted by ID.
+func (c *Conduktor) Strands() ([]StrandInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	infos := []StrandInfo{}
 	for _, store := range []Store{c.durable, c.volatile} {
-		if store.HasStrand(strandID) {
-			return store, nil
+		strands, err := store.ListStrands(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for strandID, config := range strands {
+			depth, err := store.Depth(context.Background(), strandID)
+			if err != nil {
+				return nil, err
+			}
+			bytes, err := store.Bytes(context.Background(), strandID)
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, StrandInfo{ID: strandID, Config: config, Depth: depth, Bytes: bytes, Paused: c.paused[strandID] != nil,
+				Maintenance: c.maintenance || c.maintained[strandID]})
 		}
 	}
 
-	logger.Warn("Strand not found", zap.String("strand", strandID))
-	return nil, errors.New("strand not found")
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, nil
 }
 
-// RecoverUnackedMessages iterates through unacknowledged messages and resends them.
-func (c *Conduktor) RecoverUnackedMessages() error {
+// Strand describes a single strand.
+func (c *Conduktor) Strand(strandID string) (StrandInfo, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Retrieve an iterator for unacked messages
-	iterator, err := c.durable.UnackedIterator()
+	store, err := c.getStore(strandID)
 	if err != nil {
-		logger.Error("Failed to get UnackedIterator", zap.Error(err))
-		return err
+		return StrandInfo{}, err
+	}
+
+	strands, err := store.ListStrands(context.Background())
+	if err != nil {
+		return StrandInfo{}, err
+	}
+	depth, err := store.Depth(context.Background(), strandID)
+	if err != nil {
+		return StrandInfo{}, err
+	}
+	bytes, err := store.Bytes(context.Background(), strandID)
+	if err != nil {
+		return StrandInfo{}, err
 	}
-	defer iterator.Close()
+	return StrandInfo{ID: strandID, Config: strands[strandID], Depth: depth, Bytes: bytes, Paused: c.paused[strandID] != nil,
+		Maintenance: c.maintenance || c.maintained[strandID]}, nil
+}
 
-	logger.Info("Starting recovery of unacked messages")
+// Peek returns up to limit of the oldest unacked messages in a strand without consuming them.
+func (c *Conduktor) Peek(strandID string, limit int) ([]Msg, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Process messages one by one
-	for {
-		msg, hasNext := iterator.Next()
-		if !hasNext {
-			break
-		}
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return nil, err
+	}
+	return store.Peek(context.Background(), strandID, limit)
+}
 
-		// Attempt to resend the message
-		if err := c.wire.SendMessage(*msg); err != nil {
-			logger.Error("Failed to resend unacked message",
-				zap.String("msgID", msg.ID),
-				zap.Error(err),
-			)
-			continue
-		}
+// Purge deletes every message in a strand but keeps the strand.
+func (c *Conduktor) Purge(strandID string) (int, error) {
+	if strandID == AuditStrand {
+		return 0, ErrAuditAppendOnly
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		logger.Info("Successfully recovered message",
-			zap.String("msgID", msg.ID),
-		)
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return 0, err
 	}
 
-	logger.Info("Completed recovery for strand")
-	return nil
+	purged, err := store.Purge(context.Background(), strandID)
+	if err != nil {
+		c.log.Error("Failed to purge strand", zap.String("strand", strandID), zap.Error(err))
+		return 0, err
+	}
+
+	c.log.Info("Strand purged", zap.String("strand", strandID), zap.Int("messages", purged))
+	return purged, nil
+}
+
+// selectStore determines which store to use based on strand durability.
+func (c *Conduktor) selectStore(durable bool) Store {
+	if durable {
+		return c.durable
+	}
+	return c.volatile
+}
+
+// strandStores caches which store holds each strand, sparing getStore a lookup in both stores on
+// every send and ack. Strands are cached when found, and dropped when removed.
+type strandStores struct {
+	mu     sync.RWMutex
+	stores map[string]Store
+}
+
+// remember caches strandID as held by store.
+func (ss *strandStores) remember(strandID string, store Store) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.stores[strandID] = store
+}
+
+// forget drops strandID from the cache.
+func (ss *strandStores) forget(strandID string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.stores, strandID)
+}
+
+// lookup returns the cached store of strandID, if any.
+func (ss *strandStores) lookup(strandID string) (Store, bool) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	store, cached := ss.stores[strandID]
+	return store, cached
+}
+
+// getStore retrieves the store of a given strand.
+func (c *Conduktor) getStore(strandID string) (Store, error) {
+	if store, cached := c.strandStores.lookup(strandID); cached {
+		return store, nil
+	}
+
+	// Check both stores for the strand configuration
+	for _, store := range []Store{c.durable, c.volatile} {
+		if store.HasStrand(context.Background(), strandID) {
+			c.strandStores.remember(strandID, store)
+			return store, nil
+		}
+	}
+
+	c.log.Warn("Strand not found", zap.String("strand", strandID))
+	return nil, errors.New("strand not found")
+}
+
+// doneContext returns a context that is canceled when done is closed, so loops stopped by closing
+// done can also unblock the wire receives they are waiting in.
+func doneContext(done <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
 }
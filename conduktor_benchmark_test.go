@@ -1,7 +1,11 @@
-package main
+package condukt
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
 )
 
 // Benchmark Ordered Sends (Durable)
@@ -61,3 +65,24 @@ func BenchmarkUnorderedSendNonDurable(b *testing.B) {
 		receiver.Receive("unordered_non_durable") // Ensure messages are received
 	}
 }
+
+// Benchmark Non-Durable Sends And Receives With Per-Message Metrics On And Off
+func BenchmarkStrandMetrics(b *testing.B) {
+	for _, quiet := range []bool{false, true} {
+		b.Run(fmt.Sprintf("quiet=%t", quiet), func(b *testing.B) {
+			mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+			strandID := fmt.Sprintf("metrics_%t", quiet)
+			mq.StrandAdd(strandID, StrandConf{})
+			mq.SetStrandMetrics(strandID, !quiet)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for range b.N {
+				mq.Send(strandID, "Hot Message")
+				msg, _ := mq.Receive(strandID)
+				mq.Acknowledge(strandID, msg.ID)
+			}
+		})
+	}
+}
@@ -1,25 +1,51 @@
-package main
+package condukt
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/jkassis/condukt/adminpb"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/protobuf/proto"
 )
 
 // ConduktorTestFactory creates two Conduktors (sender & receiver) communicating over the same wire.
 func ConduktorTestFactory() (sender *Conduktor, receiver *Conduktor, reload func()) {
 	// Create separate stores for sender and receiver
 	os.RemoveAll("/tmp/badger_test_db_sender")
-	senderVolatileStore := RamStoreMake()
-	senderDurableStore, _ := BadgerStoreMake("/tmp/badger_test_db_sender")
+	senderVolatileStore := store.RamStoreMake()
+	senderDurableStore, _ := store.BadgerStoreMake("/tmp/badger_test_db_sender")
 
 	os.RemoveAll("/tmp/badger_test_db_receiver")
-	receiverVolatileStore := RamStoreMake()
-	receiverDurableStore, _ := BadgerStoreMake("/tmp/badger_test_db_receiver")
+	receiverVolatileStore := store.RamStoreMake()
+	receiverDurableStore, _ := store.BadgerStoreMake("/tmp/badger_test_db_receiver")
 
 	// Create a shared wire for communication
-	wire := GoChanWireMake()
+	wire := wire.GoChanWireMake()
 
 	// Initialize sender and receiver Conduktors
 	sender = ConduktorMake(senderVolatileStore, senderDurableStore, wire)
@@ -28,10 +54,10 @@ func ConduktorTestFactory() (sender *Conduktor, receiver *Conduktor, reload func
 	// Reload function to clear both stores
 	reload = func() {
 		wire.Reset()
-		senderVolatileStore.Reload()
-		senderDurableStore.Reload()
-		receiverVolatileStore.Reload()
-		receiverDurableStore.Reload()
+		senderVolatileStore.Reload(context.Background())
+		senderDurableStore.Reload(context.Background())
+		receiverVolatileStore.Reload(context.Background())
+		receiverDurableStore.Reload(context.Background())
 	}
 
 	return sender, receiver, reload
@@ -126,3 +152,988 @@ func TestNonDurableQueue(t *testing.T) {
 	msg1, _ := receiver.Receive("non_durable_channel")
 	assert.Nil(t, msg1)
 }
+
+// Test Graceful Shutdown Flushes Queues And Rejects Sends
+func TestShutdown(t *testing.T) {
+	geoWire := wire.GoChanWireMake()
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("shutdown_channel", StrandConf{Durable: true})
+
+	// A long linger keeps the message batched until shutdown flushes it
+	assert.NoError(t, mq.GeoAdd(GeoConf{Region: "us", Remote: "eu", Wire: geoWire, Linger: time.Hour}))
+	assert.NoError(t, mq.Send("shutdown_channel", "Last words"))
+
+	assert.NoError(t, mq.Shutdown(context.Background()))
+	assert.ErrorIs(t, mq.Send("shutdown_channel", "Too late"), ErrShuttingDown)
+
+	envelope, err := geoWire.ReceiveMessage(context.Background(), geoPrefix+"eu")
+	if assert.NoError(t, err) {
+		batch, err := geoDecode(envelope.Payload)
+		if assert.NoError(t, err) && assert.Len(t, batch, 1) {
+			assert.Equal(t, "Last words", batch[0].Payload)
+		}
+	}
+}
+
+// Test Recovery Of An Empty Durable Store Succeeds
+func TestRecoverEmpty(t *testing.T) {
+	os.RemoveAll("/tmp/badger_test_db_empty")
+	durable, err := store.BadgerStoreMake("/tmp/badger_test_db_empty")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer durable.Close()
+
+	mq := ConduktorMake(store.RamStoreMake(), durable, wire.GoChanWireMake())
+	assert.NoError(t, mq.RecoverUnackedMessages())
+	assert.True(t, mq.recovered.Load())
+}
+
+// Test Recovery Workers Resend Every Strand In Order
+func TestRecoveryWorkers(t *testing.T) {
+	ctx := context.Background()
+	durable := store.RamStoreMake()
+	w := wire.GoChanWireMake()
+	mq := ConduktorMake(store.RamStoreMake(), durable, w)
+	mq.SetRecovery(RecoveryConf{Workers: 4})
+	strands := []string{"a_channel", "b_channel", "c_channel", "d_channel", "e_channel"}
+	for _, strandID := range strands {
+		assert.NoError(t, mq.StrandAdd(strandID, StrandConf{Durable: true, Ordered: true}))
+		for i := range 50 { // Saved without sending, as if left by the last run
+			assert.NoError(t, durable.Save(ctx, Msg{ID: fmt.Sprintf("%03d", i), Strand: strandID, Payload: strconv.Itoa(i)}))
+		}
+	}
+
+	assert.NoError(t, mq.RecoverUnackedMessages())
+	for _, strandID := range strands {
+		for i := range 50 {
+			msg, err := w.ReceiveMessage(ctx, strandID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, strconv.Itoa(i), msg.Payload, strandID)
+			}
+		}
+	}
+	assert.Error(t, RecoveryConf{Workers: -1}.Validate())
+}
+
+// countingStore counts HasStrand lookups.
+type countingStore struct {
+	Store
+	lookups int
+}
+
+func (s *countingStore) HasStrand(ctx context.Context, strandID string) bool {
+	s.lookups++
+	return s.Store.HasStrand(ctx, strandID)
+}
+
+// Test Strand Store Lookups Are Cached Until The Strand Is Removed
+func TestStrandStoreCache(t *testing.T) {
+	durable := &countingStore{Store: store.RamStoreMake()}
+	mq := ConduktorMake(store.RamStoreMake(), durable, wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("cached_channel", StrandConf{Durable: true}))
+	lookups := durable.lookups
+	for range 5 {
+		assert.NoError(t, mq.Send("cached_channel", "Cached"))
+		msg, err := mq.Receive("cached_channel")
+		if assert.NoError(t, err) {
+			assert.NoError(t, mq.Acknowledge("cached_channel", msg.ID))
+		}
+	}
+	assert.Equal(t, lookups, durable.lookups, "sends and acks use the cache")
+
+	assert.NoError(t, mq.StrandRemove("cached_channel"))
+	assert.Error(t, mq.Send("cached_channel", "Removed"))
+	assert.NoError(t, mq.StrandAdd("cached_channel", StrandConf{}))
+	assert.NoError(t, mq.Send("cached_channel", "Volatile"))
+	cached, err := mq.getStore("cached_channel")
+	assert.NoError(t, err)
+	assert.Equal(t, mq.volatile, cached, "re-added to the volatile store")
+}
+
+// Test queue_size Tracks Depth
+func TestQueueSize(t *testing.T) {
+	os.RemoveAll("/tmp/badger_test_db_depth")
+	durable, err := store.BadgerStoreMake("/tmp/badger_test_db_depth")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer durable.Close()
+
+	mq := ConduktorMake(store.RamStoreMake(), durable, wire.GoChanWireMake())
+	mq.StrandAdd("depth_channel", StrandConf{Durable: true})
+	for _, payload := range []string{"One", "Two", "Three"} {
+		assert.NoError(t, mq.Send("depth_channel", payload))
+	}
+	msg, err := mq.Receive("depth_channel")
+	if assert.NoError(t, err) {
+		assert.NoError(t, mq.Acknowledge("depth_channel", msg.ID))
+		assert.NoError(t, mq.Acknowledge("depth_channel", msg.ID), "acking twice does not double count")
+	}
+
+	depth, _ := durable.Depth(context.Background(), "depth_channel")
+	assert.Equal(t, 2, depth)
+	assert.Equal(t, float64(2), testutil.ToFloat64(queueSize.WithLabelValues("depth_channel")))
+
+	bytes, _ := durable.Bytes(context.Background(), "depth_channel")
+	assert.Greater(t, bytes, int64(0))
+
+	_, err = mq.Purge("depth_channel")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), testutil.ToFloat64(queueSize.WithLabelValues("depth_channel")))
+	bytes, _ = durable.Bytes(context.Background(), "depth_channel")
+	assert.Equal(t, int64(0), bytes)
+}
+
+// s3Fake is an in-memory S3API.
+type s3Fake struct {
+	objects map[string][]byte
+}
+
+func (f *s3Fake) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	f.objects[*params.Key] = data
+	return &s3.PutObjectOutput{}, err
+}
+
+func (f *s3Fake) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out := &s3.ListObjectsV2Output{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, *params.Prefix) {
+			out.Contents = append(out.Contents, s3types.Object{Key: aws.String(key)})
+		}
+	}
+	return out, nil
+}
+
+func (f *s3Fake) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	delete(f.objects, *params.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestBackup(t *testing.T) {
+	os.RemoveAll("/tmp/badger_test_db_backup")
+	durable, err := store.BadgerStoreMake("/tmp/badger_test_db_backup")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer durable.Close()
+
+	mq := ConduktorMake(store.RamStoreMake(), durable, wire.GoChanWireMake())
+	mq.StrandAdd("backup_channel", StrandConf{Durable: true})
+	assert.NoError(t, mq.Send("backup_channel", "One"))
+	assert.NoError(t, mq.Send("backup_channel", "Two"))
+
+	// Only the newest backups are kept
+	dir, _ := BackupDirMake(t.TempDir())
+	var info BackupInfo
+	for i := 0; i < 3; i++ {
+		info, err = mq.Backup(context.Background(), dir, 2)
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond) // Backup names have millisecond resolution
+	}
+	names, _ := dir.List(context.Background())
+	assert.Len(t, names, 2)
+	assert.Contains(t, names, info.Name)
+	assert.Len(t, info.Deleted, 1)
+
+	// The backup restores into a new store
+	os.RemoveAll("/tmp/badger_test_db_restore")
+	db, err := badger.Open(badger.DefaultOptions("/tmp/badger_test_db_restore").WithLoggingLevel(badger.ERROR))
+	if !assert.NoError(t, err) {
+		return
+	}
+	file, _ := os.Open(filepath.Join(dir.dir, info.Name))
+	assert.NoError(t, db.Load(file, 16))
+	file.Close()
+	db.Close()
+	restored, err := store.BadgerStoreMake("/tmp/badger_test_db_restore")
+	if assert.NoError(t, err) {
+		depth, _ := restored.Depth(context.Background(), "backup_channel")
+		assert.Equal(t, 2, depth)
+		restored.Close()
+	}
+
+	// S3 keeps backups under the prefix
+	fake := &s3Fake{objects: map[string][]byte{"condukt/other.txt": nil}}
+	target := BackupS3Make(fake, "bucket", "condukt")
+	for i := 0; i < 2; i++ {
+		_, err = mq.Backup(context.Background(), target, 1)
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Len(t, fake.objects, 2, "one backup and the unrelated object")
+	assert.Contains(t, fake.objects, "condukt/other.txt")
+
+	_, err = ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake()).Backup(context.Background(), dir, 2)
+	assert.ErrorIs(t, err, ErrBackupUnsupported)
+}
+
+func TestStrandMaxBytes(t *testing.T) {
+	os.RemoveAll("/tmp/badger_test_db_maxbytes")
+	durable, err := store.BadgerStoreMake("/tmp/badger_test_db_maxbytes")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer durable.Close()
+
+	mq := ConduktorMake(store.RamStoreMake(), durable, wire.GoChanWireMake())
+	assert.Error(t, mq.StrandAdd("bad_channel", StrandConf{MaxBytes: 10, Overflow: "drop"}))
+
+	// Reject: sends fail once the strand is full
+	assert.NoError(t, mq.StrandAdd("reject_channel", StrandConf{MaxBytes: 300}))
+	assert.NoError(t, mq.Send("reject_channel", strings.Repeat("x", 100)))
+	assert.ErrorIs(t, mq.Send("reject_channel", strings.Repeat("x", 200)), ErrStrandFull)
+	info, _ := mq.Strand("reject_channel")
+	assert.Equal(t, 1, info.Depth)
+	assert.Equal(t, float64(info.Bytes), testutil.ToFloat64(queueBytes.WithLabelValues("reject_channel")))
+
+	// Evict: the oldest messages make room, with bytes counted as Badger stores them
+	assert.NoError(t, mq.StrandAdd("evict_channel", StrandConf{Durable: true, MaxBytes: 600, Overflow: OverflowEvict}))
+	for _, payload := range []string{"One", "Two", "Three", "Four", "Five"} {
+		assert.NoError(t, mq.Send("evict_channel", strings.Repeat(payload, 20)))
+	}
+	info, _ = mq.Strand("evict_channel")
+	assert.LessOrEqual(t, info.Bytes, int64(600))
+	msgs, _ := mq.Peek("evict_channel", 0)
+	if assert.NotEmpty(t, msgs) {
+		assert.Less(t, len(msgs), 5)
+		assert.Equal(t, strings.Repeat("Five", 20), msgs[len(msgs)-1].Payload)
+	}
+	assert.Equal(t, float64(5-len(msgs)), testutil.ToFloat64(strandOverflows.WithLabelValues("evict_channel", OverflowEvict)))
+	assert.ErrorIs(t, mq.Send("evict_channel", strings.Repeat("x", 700)), ErrStrandFull, "a message larger than the strand is rejected")
+}
+
+// Test Strand Presets, Defaults, And Validation
+func TestStrandConfValidate(t *testing.T) {
+	err := StrandConf{ReplicationFactor: 3, Overflow: OverflowEvict}.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ReplicationFactor requires a durable strand")
+		assert.Contains(t, err.Error(), "Overflow evict requires MaxBytes")
+	}
+	for _, preset := range []StrandConf{PresetWorkQueue, PresetEventLog, PresetRealtime} {
+		assert.NoError(t, preset.Validate())
+	}
+
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.ErrorContains(t, mq.StrandAdd("bad_channel", StrandConf{MaxBytes: -1}), "strand bad_channel: MaxBytes must not be negative")
+	assert.NoError(t, mq.StrandAdd("plain_channel", StrandConf{}))
+	info, _ := mq.Strand("plain_channel")
+	assert.Equal(t, OverflowReject, info.Config.Overflow, "unset settings take their defaults")
+	assert.NoError(t, mq.StrandAdd("log_channel", PresetEventLog))
+	info, _ = mq.Strand("log_channel")
+	assert.Equal(t, PresetEventLog, info.Config)
+
+	preset := StrandPreset{ID: "live", Preset: "realtime", MaxBytes: 1 << 20}
+	assert.Equal(t, StrandConf{Ordered: true, MaxBytes: 1 << 20, Overflow: OverflowEvict}, preset.StrandConf())
+}
+
+// Test Latency Histograms
+func TestLatencyHistograms(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	series := testutil.CollectAndCount(sendAckSeconds)
+	mq.StrandAdd("latency_channel", StrandConf{})
+
+	assert.NoError(t, mq.Send("latency_channel", "Latency"))
+	msg, err := mq.Receive("latency_channel")
+	assert.NoError(t, err)
+	assert.NoError(t, mq.Acknowledge("latency_channel", msg.ID))
+
+	// Each histogram gains a series for the strand
+	assert.Equal(t, series+1, testutil.CollectAndCount(sendAckSeconds))
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(sendStoreSeconds), 1)
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(sendWireSeconds), 1)
+}
+
+// Test Quiet Strands Skip Per-Message Metrics
+func TestStrandMetricsQuiet(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("loud_channel", StrandConf{})
+	mq.StrandAdd("hot/quiet_channel", StrandConf{})
+	assert.Error(t, mq.SetStrandMetrics("missing_channel", false))
+	roundTrip := func(strandID string) {
+		assert.NoError(t, mq.Send(strandID, "Hot"))
+		msg, err := mq.Receive(strandID)
+		if assert.NoError(t, err) {
+			assert.NoError(t, mq.Acknowledge(strandID, msg.ID))
+		}
+	}
+
+	roundTrip("hot/quiet_channel")
+	assert.Equal(t, float64(1), testutil.ToFloat64(messagesAcked.WithLabelValues("hot/quiet_channel")))
+	assert.NoError(t, mq.SetStrandMetrics("hot/quiet_channel", false))
+	namespaceSent := testutil.ToFloat64(namespaceMessagesSent.WithLabelValues("hot"))
+	series := testutil.CollectAndCount(sendAckSeconds)
+
+	roundTrip("loud_channel")
+	roundTrip("hot/quiet_channel")
+	assert.Equal(t, float64(1), testutil.ToFloat64(messagesAcked.WithLabelValues("loud_channel")))
+	assert.Equal(t, series, testutil.CollectAndCount(sendAckSeconds), "the quiet strand's series are removed and stay so")
+	assert.Equal(t, namespaceSent+1, testutil.ToFloat64(namespaceMessagesSent.WithLabelValues("hot")), "namespaces still count")
+
+	assert.NoError(t, mq.SetStrandMetrics("hot/quiet_channel", true))
+	roundTrip("hot/quiet_channel")
+	assert.Equal(t, float64(1), testutil.ToFloat64(messagesAcked.WithLabelValues("hot/quiet_channel")))
+}
+
+// Test Message Journey Spans
+func TestTracingSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("traced_channel", StrandConf{})
+	assert.NoError(t, mq.Send("traced_channel", "Traced"))
+	msg, err := mq.Receive("traced_channel")
+	assert.NoError(t, err)
+	assert.NoError(t, mq.Acknowledge("traced_channel", msg.ID))
+
+	// Every step of the message's journey joins the send's trace
+	spans := recorder.Ended()
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+		assert.Equal(t, spans[0].SpanContext().TraceID(), span.SpanContext().TraceID(), span.Name())
+	}
+	assert.ElementsMatch(t, []string{"condukt.store", "condukt.transmit", "condukt.send", "condukt.receive", "condukt.process", "condukt.ack"}, names)
+}
+
+// Test Trace Context Propagation in Headers
+func TestTraceContextHeaders(t *testing.T) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	producer, parent := otel.Tracer("producer").Start(context.Background(), "produce")
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("propagated_channel", StrandConf{})
+	assert.NoError(t, mq.Send("propagated_channel", "Propagated", SendContext(producer)))
+	parent.End()
+
+	// The consumer's context continues the producer's trace
+	msg, err := mq.Receive("propagated_channel")
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, msg.Headers[HeaderTraceParent])
+		consumer := trace.SpanContextFromContext(MsgContext(context.Background(), *msg))
+		assert.True(t, consumer.IsRemote())
+		assert.Equal(t, parent.SpanContext().TraceID(), consumer.TraceID())
+	}
+}
+
+// Test Slow-Consumer Policies
+func TestSlowConsumers(t *testing.T) {
+	conf := SlowConsumerConf{Interval: time.Hour, MaxInFlight: 2, Policy: SlowThrottle}
+	assert.NoError(t, conf.Validate())
+	assert.Error(t, SlowConsumerConf{Interval: time.Second, Policy: "drop"}.Validate())
+
+	// Throttling holds back deliveries until the consumer catches up
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.StrandAdd("slow_channel", StrandConf{})
+	mq.Send("slow_channel", "Slow 1")
+	mq.Send("slow_channel", "Slow 2")
+	assert.NoError(t, mq.slowConsumerCheck(conf))
+	mq.Send("slow_channel", "Slow 3")
+
+	first, _ := mq.Receive("slow_channel")
+	second, _ := mq.Receive("slow_channel")
+	assert.NoError(t, mq.Acknowledge("slow_channel", first.ID))
+	assert.NoError(t, mq.Acknowledge("slow_channel", second.ID))
+	trace, _ := mq.Trace(second.ID)
+	assert.Equal(t, DeliveryAcked, trace.State)
+	msgs, _ := mq.Peek("slow_channel", 0)
+	if assert.Len(t, msgs, 1) {
+		trace, _ = mq.Trace(msgs[0].ID)
+		assert.Equal(t, DeliveryPending, trace.State, "held back while throttled")
+	}
+	assert.NoError(t, mq.slowConsumerCheck(conf))
+	third, _ := mq.Receive("slow_channel")
+	assert.Equal(t, "Slow 3", third.Payload)
+
+	// The dlq policy diverts overdue messages
+	conf = SlowConsumerConf{Interval: time.Hour, MaxAckLatency: time.Millisecond, Policy: SlowDLQ}
+	time.Sleep(5 * time.Millisecond)
+	assert.NoError(t, mq.slowConsumerCheck(conf))
+	dead, _ := mq.DeadLetters("slow_channel", 0)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, "slow consumer", dead[0].Reason)
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(slowConsumerActions.WithLabelValues("slow_channel", SlowDLQ)))
+}
+
+// Test Injecting A Logger Into The Conduktor, Its Stores, And Its Wire
+func TestMakeLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core)
+	mq := ConduktorMake(store.RamStoreMake(MakeLogger(log)), store.RamStoreMake(MakeLogger(log)), wire.GoChanWireMake(MakeLogger(log)), MakeLogger(log))
+
+	assert.NoError(t, mq.StrandAdd("logged_channel", StrandConf{Durable: false}))
+	assert.NoError(t, mq.Send("logged_channel", "Logged message"))
+
+	assert.Equal(t, 1, logs.FilterMessage("Strand added").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Message sent via GoChanWire").Len())
+	assert.Equal(t, 1, logs.FilterMessage("Message sent").Len())
+}
+
+// Test Messages Marked For Debugging Are Logged At Every Hop Above The Log Level
+func TestDebugHeader(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	log := zap.New(core)
+	mq := ConduktorMake(store.RamStoreMake(MakeLogger(log)), store.RamStoreMake(MakeLogger(log)), wire.GoChanWireMake(MakeLogger(log)), MakeLogger(log))
+	assert.NoError(t, mq.StrandAdd("debug_channel", StrandConf{Durable: false}))
+
+	assert.NoError(t, mq.Send("debug_channel", "Quiet"))
+	msg, err := mq.Receive("debug_channel")
+	if assert.NoError(t, err) {
+		assert.False(t, msg.Debug())
+		assert.NoError(t, mq.Acknowledge("debug_channel", msg.ID))
+	}
+	assert.Equal(t, 0, logs.Len(), "unmarked messages log at debug level only")
+
+	assert.NoError(t, mq.Send("debug_channel", "Loud", SendHeaders(map[string]string{HeaderDebug: "true"})))
+	msg, err = mq.Receive("debug_channel")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, msg.Debug())
+	assert.NoError(t, mq.Acknowledge("debug_channel", msg.ID))
+
+	for _, hop := range []string{"Message sent via GoChanWire", "Message sent", "Message received via GoChanWire", "Message received"} {
+		assert.Equal(t, 1, logs.FilterMessage(hop).Len(), hop)
+	}
+	for _, event := range []string{EventSaved, EventSent, EventReceived, EventAcked} {
+		assert.Equal(t, 1, logs.FilterMessage("Message hop").FilterField(zap.String("event", event)).Len(), event)
+	}
+	assert.Equal(t, 0, logs.FilterLevelExact(zapcore.InfoLevel).Len(), "hops log at debug level")
+}
+
+// Test Canceled Contexts Stop Blocking Receives And Store Operations
+func TestContextCancel(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("ctx_channel", StrandConf{Durable: false}))
+	assert.NoError(t, mq.Send("ctx_channel", "First"))
+	msg, err := mq.Receive("ctx_channel")
+	if assert.NoError(t, err) {
+		assert.NoError(t, mq.Acknowledge("ctx_channel", msg.ID))
+	}
+
+	// The strand is empty, so Receive waits until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = mq.Receive("ctx_channel", ReceiveContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, mq.Send("ctx_channel", "Too late", SendContext(canceled)), context.Canceled)
+	depth, _ := mq.volatile.Depth(context.Background(), "ctx_channel")
+	assert.Equal(t, 0, depth, "nothing is stored once the send's context is done")
+
+	os.RemoveAll("/tmp/badger_test_db_ctx")
+	durable, err := store.BadgerStoreMake("/tmp/badger_test_db_ctx")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer durable.Close()
+	assert.ErrorIs(t, durable.CreateStrand(canceled, "ctx_channel", StrandConf{Durable: true}), context.Canceled)
+	assert.False(t, durable.HasStrand(context.Background(), "ctx_channel"))
+}
+
+// Test Typed Strands Encode, Decode, And Dead-Letter Values
+func TestTypedStrand(t *testing.T) {
+	type order struct {
+		ID    string `json:"id"`
+		Total int    `json:"total"`
+	}
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("typed_channel", StrandConf{Durable: false, Ordered: true}))
+	orders := TypedStrandMake[order](mq, "typed_channel", nil)
+
+	assert.NoError(t, orders.Send(order{ID: "a", Total: 3}))
+	got, err := orders.Receive()
+	assert.NoError(t, err)
+	assert.Equal(t, order{ID: "a", Total: 3}, got)
+
+	// Undecodable payloads are dead-lettered
+	assert.NoError(t, mq.Send("typed_channel", "not json"))
+	_, err = orders.Receive()
+	assert.ErrorContains(t, err, "decode")
+
+	// Handler failures are dead-lettered, successes acknowledged
+	handled := make(chan order, 2)
+	stop := orders.Subscribe(func(o order) error {
+		handled <- o
+		if o.Total < 0 {
+			return errors.New("negative total")
+		}
+		return nil
+	})
+	defer stop()
+	assert.NoError(t, orders.Send(order{ID: "b", Total: -1}))
+	assert.NoError(t, orders.Send(order{ID: "c", Total: 5}))
+	for range 2 {
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Fatal("handler not called")
+		}
+	}
+	assert.Eventually(t, func() bool {
+		depth, _ := mq.volatile.Depth(context.Background(), "typed_channel")
+		return depth == 0
+	}, time.Second, 10*time.Millisecond)
+	dead, err := mq.DeadLetters("typed_channel", 0)
+	if assert.NoError(t, err) && assert.Len(t, dead, 2) {
+		assert.Contains(t, dead[0].Reason, "decode")
+		assert.Equal(t, "negative total", dead[1].Reason)
+	}
+
+	conf := &adminpb.StrandConfig{Durable: true, MaxBytes: 64}
+	payload, err := ProtoJSONCodec{}.Marshal(conf)
+	if assert.NoError(t, err) {
+		var decoded *adminpb.StrandConfig
+		assert.NoError(t, ProtoJSONCodec{}.Unmarshal(payload, &decoded))
+		assert.True(t, proto.Equal(conf, decoded))
+	}
+}
+
+// Test Middleware Around Send, Deliver, And Acknowledge
+func TestMiddleware(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("middleware_channel", StrandConf{}))
+
+	var calls []string
+	errForbidden := errors.New("forbidden payload")
+	mq.Use(Middleware{
+		Send: func(next SendHandler) SendHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				calls = append(calls, "outer send")
+				if msg.Payload == "Forbidden" {
+					return errForbidden
+				}
+				msg.Headers["sender"] = ActorFrom(ctx, "anonymous")
+				return next(ctx, msg)
+			}
+		},
+		Acknowledge: func(next AckHandler) AckHandler {
+			return func(ctx context.Context, strandID, msgID string) error {
+				calls = append(calls, "ack")
+				return next(ctx, strandID, msgID)
+			}
+		},
+	})
+	mq.Use(Middleware{
+		Send: func(next SendHandler) SendHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				calls = append(calls, "inner send")
+				return next(ctx, msg)
+			}
+		},
+		Deliver: func(next DeliverHandler) DeliverHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				msg.Payload = strings.ToUpper(msg.Payload)
+				return next(ctx, msg)
+			}
+		},
+	})
+
+	// Refused sends store nothing
+	assert.ErrorIs(t, mq.Send("middleware_channel", "Forbidden"), errForbidden)
+	info, _ := mq.Strand("middleware_channel")
+	assert.Equal(t, 0, info.Depth)
+
+	assert.NoError(t, mq.Send("middleware_channel", "Hello", SendAs("producer")))
+	msg, err := mq.Receive("middleware_channel")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "HELLO", msg.Payload)
+		assert.Equal(t, "producer", msg.Headers["sender"])
+		assert.NoError(t, mq.Acknowledge("middleware_channel", msg.ID))
+	}
+	assert.Equal(t, []string{"outer send", "outer send", "inner send", "ack"}, calls)
+	info, _ = mq.Strand("middleware_channel")
+	assert.Equal(t, 0, info.Depth)
+}
+
+// Test Per-Strand Payload Schemas
+func TestSchema(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	schemaPath := filepath.Join(t.TempDir(), "orders.schema.json")
+	assert.NoError(t, os.WriteFile(schemaPath, []byte(`{
+		"type": "object",
+		"properties": {"id": {"type": "integer"}},
+		"required": ["id"]
+	}`), 0o644))
+	cfg := Config{Strands: []StrandPreset{{ID: "schema_channel", Schema: schemaPath}}}
+	assert.NoError(t, cfg.StrandsCreate(mq))
+
+	err := mq.Send("schema_channel", `{"id": "one"}`)
+	if assert.ErrorIs(t, err, ErrSchemaMismatch) {
+		assert.Contains(t, err.Error(), "/id")
+	}
+	assert.ErrorIs(t, mq.Send("schema_channel", `not json`), ErrSchemaMismatch)
+	assert.NoError(t, mq.Send("schema_channel", `{"id": 1}`))
+	info, _ := mq.Strand("schema_channel")
+	assert.Equal(t, 1, info.Depth)
+
+	// Protobuf schemas accept the protojson encoding of their message type
+	assert.NoError(t, mq.SetSchema("schema_channel", ProtoSchemaMake(&adminpb.StrandConfig{})))
+	assert.ErrorIs(t, mq.Send("schema_channel", `{"id": 1}`), ErrSchemaMismatch)
+	assert.NoError(t, mq.Send("schema_channel", `{"durable": true}`))
+
+	assert.NoError(t, mq.SetSchema("schema_channel", nil))
+	assert.NoError(t, mq.Send("schema_channel", "anything"))
+	assert.Error(t, mq.SetSchema("missing_channel", nil))
+	_, err = JSONSchemaMake([]byte(`{"type": 5}`))
+	assert.Error(t, err)
+}
+
+// Test Resume Sends Only The Messages Held Back While Paused
+func TestPause(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("paused_channel", StrandConf{}))
+	assert.Error(t, mq.Pause("missing_channel"))
+
+	assert.NoError(t, mq.Send("paused_channel", "Before"))
+	assert.NoError(t, mq.Pause("paused_channel"))
+	assert.NoError(t, mq.Send("paused_channel", "Acked"))
+	assert.NoError(t, mq.Send("paused_channel", "During"))
+	info, _ := mq.Strand("paused_channel")
+	assert.True(t, info.Paused)
+	held, _ := mq.Peek("paused_channel", 0)
+	if assert.Len(t, held, 3) {
+		assert.NoError(t, mq.Acknowledge("paused_channel", held[1].ID))
+	}
+
+	assert.NoError(t, mq.Resume("paused_channel"))
+	info, _ = mq.Strand("paused_channel")
+	assert.False(t, info.Paused)
+	for _, want := range []string{"Before", "During"} {
+		msg, err := mq.Receive("paused_channel")
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, msg.Payload)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := mq.Receive("paused_channel", ReceiveContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// Test Payload Encryption With Key Rotation
+func TestEncryption(t *testing.T) {
+	volatile := store.RamStoreMake()
+	mq := ConduktorMake(volatile, store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("secret_channel", StrandConf{}))
+	keys := KeyRingMake()
+	assert.Error(t, keys.Add("secret_channel", "bad", []byte("short")))
+	assert.NoError(t, keys.Add("secret_channel", "2024", []byte("0123456789abcdef0123456789abcdef")))
+	mq.Use(EncryptionMiddleware(keys))
+
+	assert.NoError(t, mq.Send("secret_channel", "Old secret"))
+	assert.NoError(t, keys.Add("secret_channel", "2025", []byte("fedcba9876543210fedcba9876543210")))
+	assert.NoError(t, mq.Send("secret_channel", "New secret"))
+
+	// Stores hold only ciphertext, labeled with the key it needs
+	stored, err := volatile.Peek(context.Background(), "secret_channel", 0)
+	if assert.NoError(t, err) && assert.Len(t, stored, 2) {
+		assert.NotContains(t, stored[0].Payload, "secret")
+		assert.Equal(t, "2024", stored[0].Headers[HeaderKeyID])
+		assert.Equal(t, "2025", stored[1].Headers[HeaderKeyID])
+	}
+
+	for _, want := range []string{"Old secret", "New secret"} {
+		msg, err := mq.Receive("secret_channel")
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, msg.Payload)
+			assert.NotContains(t, msg.Headers, HeaderKeyID)
+		}
+	}
+
+	// Messages whose key was removed cannot be read
+	assert.NoError(t, mq.Pause("secret_channel"))
+	assert.NoError(t, mq.Send("secret_channel", "Lost secret"))
+	keys.Remove("secret_channel", "2025")
+	assert.NoError(t, mq.Send("secret_channel", "Plain"))
+	assert.NoError(t, mq.Resume("secret_channel")) // Delivers the held messages, oldest first
+	_, err = mq.Receive("secret_channel")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	msg, err := mq.Receive("secret_channel")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Plain", msg.Payload)
+	}
+}
+
+// kmsXOR is a KMS whose keys are wrapped by XOR with a byte, for tests.
+type kmsXOR byte
+
+func (k kmsXOR) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	raw := make([]byte, len(wrapped))
+	for i, b := range wrapped {
+		raw[i] = b ^ byte(k)
+	}
+	return raw, nil
+}
+
+// Test Encryption Keys From Config, Env, And A KMS, Bound To Strand Patterns
+func TestEncryptionConf(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, _ := kmsXOR(7).Decrypt(context.Background(), key)
+	KMSRegister("xor", kmsXOR(7))
+	t.Setenv("CONDUKT_TEST_KEY", base64.StdEncoding.EncodeToString(key))
+	conf := EncryptionConf{Keys: []StrandKey{
+		{Strands: "payments/*", ID: "literal", Key: base64.StdEncoding.EncodeToString(key)},
+		{Strands: "payments/*", ID: "env", KeyEnv: "CONDUKT_TEST_KEY"},
+		{Strands: "payments/refunds", ID: "kms", KMS: "xor", Wrapped: base64.StdEncoding.EncodeToString(wrapped)},
+	}}
+	assert.NoError(t, conf.Validate())
+	keys, err := conf.KeyRing(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The last key of a pattern encrypts; a strand's own key takes precedence
+	for strand, keyID := range map[string]string{"payments/cards": "env", "payments/refunds": "kms", "orders": ""} {
+		msg := &Msg{ID: "1", Strand: strand, Payload: "secret", Headers: map[string]string{}}
+		keys.encrypt(msg)
+		assert.Equal(t, keyID, msg.Headers[HeaderKeyID], strand)
+		assert.NoError(t, keys.decrypt(msg))
+		assert.Equal(t, "secret", msg.Payload)
+	}
+	old := &Msg{ID: "2", Strand: "payments/refunds", Payload: "old", Headers: map[string]string{}}
+	keys.Remove("payments/refunds", "kms")
+	keys.encrypt(old)
+	assert.Equal(t, "env", old.Headers[HeaderKeyID])
+
+	conf.Keys[2].KMS = "missing"
+	_, err = conf.KeyRing(context.Background())
+	assert.ErrorContains(t, err, "encryption.keys[2]: kms")
+	assert.Error(t, EncryptionConf{Keys: []StrandKey{{Strands: "x", ID: "1", Key: "a", KeyEnv: "B"}}}.Validate())
+}
+
+// Test Signed Messages Are Verified At Receive, Through Encryption And Key Rotation
+func TestSigning(t *testing.T) {
+	volatile := store.RamStoreMake()
+	mq := ConduktorMake(volatile, store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("bridged/orders", StrandConf{}))
+	signing := SigningKeysMake()
+	assert.Error(t, signing.Add("bridged/*", "short", []byte("0123456789abcdef")))
+	assert.NoError(t, signing.Add("bridged/*", "2024", []byte("0123456789abcdef0123456789abcdef")))
+	encryption := KeyRingMake()
+	assert.NoError(t, encryption.Add("bridged/orders", "1", []byte("0123456789abcdef")))
+	mq.Use(SigningMiddleware(signing))
+	mq.Use(EncryptionMiddleware(encryption))
+
+	assert.NoError(t, mq.Send("bridged/orders", "Old", SendHeaders(map[string]string{"tenant": "a"})))
+	assert.NoError(t, signing.Add("bridged/*", "2025", []byte("fedcba9876543210fedcba9876543210")))
+	assert.NoError(t, mq.Send("bridged/orders", "New"))
+	for _, want := range []string{"keyid=2024;headers=tenant;sig=", "keyid=2025;headers=;sig="} {
+		msg, err := mq.Receive("bridged/orders")
+		if assert.NoError(t, err) {
+			assert.Contains(t, msg.Headers[HeaderSignature], want)
+		}
+	}
+
+	// Altered, stripped, and unknown signatures are refused
+	keys := SigningKeysMake()
+	assert.NoError(t, keys.Add("bridged/orders", "1", []byte("0123456789abcdef0123456789abcdef")))
+	msg := &Msg{ID: "1", Strand: "bridged/orders", Payload: "pay 10", Headers: map[string]string{"to": "bob", "traceparent": "00-1"}}
+	keys.sign(msg)
+	assert.Contains(t, msg.Headers[HeaderSignature], "headers=to;")
+	msg.Headers["traceparent"], msg.Headers["x-sqs-message-id"] = "00-2", "m"
+	assert.NoError(t, keys.verify(msg))
+	for _, alter := range []func(m *Msg){
+		func(m *Msg) { m.Payload = "pay 1000" },
+		func(m *Msg) { m.Headers["to"] = "eve" },
+		func(m *Msg) { delete(m.Headers, "to") },
+		func(m *Msg) { delete(m.Headers, HeaderSignature) },
+		func(m *Msg) {
+			m.Headers[HeaderSignature] = strings.Replace(m.Headers[HeaderSignature], "keyid=1", "keyid=2", 1)
+		},
+	} {
+		altered := *msg
+		altered.Headers = maps.Clone(msg.Headers)
+		alter(&altered)
+		assert.ErrorIs(t, keys.verify(&altered), ErrBadSignature)
+	}
+	msg.Strand = "other"
+	assert.NoError(t, keys.verify(msg)) // Unsigned strands are not checked
+
+	conf := SigningConf{Keys: []StrandKey{{Strands: "bridged/*", ID: "1", Key: base64.StdEncoding.EncodeToString([]byte("short"))}}}
+	assert.ErrorContains(t, conf.Validate(), "keys[0]: key: 5 bytes")
+}
+
+// Test Batch Acknowledgement
+func TestAcknowledgeBatch(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("batch_ack_channel", StrandConf{}))
+	var ids []string
+	for _, payload := range []string{"One", "Two", "Three"} {
+		assert.NoError(t, mq.Send("batch_ack_channel", payload))
+		msg, err := mq.Receive("batch_ack_channel")
+		if assert.NoError(t, err) {
+			ids = append(ids, msg.ID)
+		}
+	}
+	depth := func() int {
+		info, _ := mq.Strand("batch_ack_channel")
+		return info.Depth
+	}
+
+	// A missing message fails the whole batch
+	assert.Error(t, mq.AcknowledgeBatch("batch_ack_channel", []string{ids[0], "missing"}))
+	assert.Equal(t, 3, depth())
+
+	// So does middleware refusing any message
+	errHeld := errors.New("held")
+	mq.Use(Middleware{Acknowledge: func(next AckHandler) AckHandler {
+		return func(ctx context.Context, strandID, msgID string) error {
+			if msgID == ids[2] {
+				return errHeld
+			}
+			return next(ctx, strandID, msgID)
+		}
+	}})
+	assert.ErrorIs(t, mq.AcknowledgeBatch("batch_ack_channel", ids), errHeld)
+	assert.Equal(t, 3, depth())
+
+	assert.NoError(t, mq.AcknowledgeBatch("batch_ack_channel", ids[:2]))
+	assert.Equal(t, 1, depth())
+	assert.Equal(t, float64(2), testutil.ToFloat64(messagesAcked.WithLabelValues("batch_ack_channel")))
+	assert.ErrorIs(t, mq.AcknowledgeBatch(AuditStrand, ids), ErrAuditAppendOnly)
+}
+
+// Test Ranging Over Received Messages
+func TestMessages(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("range_channel", StrandConf{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Errors before the first send are yielded, and receiving resumes
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		for _, payload := range []string{"One", "Two", "Three"} {
+			mq.Send("range_channel", payload)
+		}
+	}()
+	var payloads []string
+	errs := 0
+	for msg, err := range mq.Messages(ctx, "range_channel") {
+		if err != nil {
+			errs++
+			continue
+		}
+		payloads = append(payloads, msg.Payload)
+		assert.NoError(t, mq.Acknowledge("range_channel", msg.ID))
+		if len(payloads) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"One", "Two"}, payloads)
+	assert.Positive(t, errs)
+
+	// Canceling ctx ends the loop cleanly
+	time.AfterFunc(50*time.Millisecond, cancel)
+	for msg, err := range mq.Messages(ctx, "range_channel") {
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Three", msg.Payload)
+		}
+	}
+
+	mq.SetACL(ACL{{Identity: "*", Strands: "other", Ops: []string{ACLSubscribe}}})
+	for _, err := range mq.Messages(context.Background(), "range_channel", ReceiveAs("intruder")) {
+		assert.ErrorIs(t, err, ErrDenied)
+	}
+}
+
+// failingWire fails sends once fail is set.
+type failingWire struct {
+	wire.Wire
+	fail bool
+}
+
+func (w *failingWire) SendMessage(ctx context.Context, msg Msg) error {
+	if w.fail {
+		return errors.New("wire down")
+	}
+	return w.Wire.SendMessage(ctx, msg)
+}
+
+// Test Asynchronous Failures Reach OnError
+func TestOnError(t *testing.T) {
+	w := &failingWire{Wire: wire.GoChanWireMake()}
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), w)
+	assert.NoError(t, mq.StrandAdd("error_channel", StrandConf{Durable: true}))
+	assert.NoError(t, mq.Send("error_channel", "Unacked"))
+	info, _ := mq.Strand("error_channel")
+	assert.Equal(t, 1, info.Depth)
+
+	var events []ErrorEvent
+	mq.OnError(func(event ErrorEvent) { events = append(events, event) })
+	w.fail = true
+	assert.NoError(t, mq.RecoverUnackedMessages())
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, ErrorSourceRecovery, events[0].Source)
+		assert.Equal(t, "error_channel", events[0].Strand)
+		assert.NotEmpty(t, events[0].MsgID)
+		assert.EqualError(t, events[0].Err, "wire down")
+	}
+}
+
+// encodingStore records the encodings it is asked to save.
+type encodingStore struct {
+	*store.RamStore
+	codec string
+	saved [][]byte
+}
+
+func (s *encodingStore) Codec() string {
+	return s.codec
+}
+
+func (s *encodingStore) SaveEncoded(ctx context.Context, msg Msg, data []byte) error {
+	s.saved = append(s.saved, data)
+	return s.Save(ctx, msg)
+}
+
+// encodingWire records the encodings it is asked to send.
+type encodingWire struct {
+	wire.Wire
+	codec string
+	sent  [][]byte
+}
+
+func (w *encodingWire) Codec() string {
+	return w.codec
+}
+
+func (w *encodingWire) SendEncoded(ctx context.Context, msg Msg, data []byte) error {
+	w.sent = append(w.sent, data)
+	return w.SendMessage(ctx, msg)
+}
+
+// Test A Message Stored And Sent Is Encoded Once, For Both
+func TestEncodeOnce(t *testing.T) {
+	s := &encodingStore{RamStore: store.RamStoreMake()}
+	w := &encodingWire{Wire: wire.GoChanWireMake()}
+	mq := ConduktorMake(store.RamStoreMake(), s, w)
+	assert.NoError(t, mq.StrandAdd("encoded_channel", StrandConf{Durable: true}))
+	assert.NoError(t, mq.Send("encoded_channel", "Encoded"))
+	if !assert.Len(t, s.saved, 1) || !assert.Len(t, w.sent, 1) {
+		return
+	}
+	assert.Same(t, &s.saved[0][0], &w.sent[0][0], "store and wire share one encoding")
+
+	msg, err := mq.Receive("encoded_channel")
+	if assert.NoError(t, err) {
+		data, err := wire.MsgEncode(*msg)
+		assert.NoError(t, err)
+		assert.Equal(t, data, w.sent[0])
+	}
+
+	// A store and wire with different codecs each encode with their own
+	s = &encodingStore{RamStore: store.RamStoreMake(), codec: wire.CodecJSON}
+	w = &encodingWire{Wire: wire.GoChanWireMake()}
+	mq = ConduktorMake(store.RamStoreMake(), s, w)
+	assert.NoError(t, mq.StrandAdd("encoded_channel", StrandConf{Durable: true}))
+	assert.NoError(t, mq.Send("encoded_channel", "Encoded"))
+	assert.Empty(t, s.saved)
+	assert.Empty(t, w.sent)
+}
@@ -0,0 +1,49 @@
+package condukttest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt"
+)
+
+// Benchmark Concurrent Sends And Acks Over A Store Taking 1ms Per Write, On One Strand And On A
+// Strand Per Goroutine. Sends and acks of one strand wait for each other; those of different
+// strands overlap their store writes.
+func BenchmarkStrandConcurrency(b *testing.B) {
+	for _, shared := range []bool{true, false} {
+		name := "StrandEach"
+		if shared {
+			name = "OneStrand"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := FakeStoreMake(nil)
+			s.Inject("Save", Fault{Latency: time.Millisecond})
+			s.Inject("Acknowledge", Fault{Latency: time.Millisecond})
+			mq := condukt.ConduktorMake(s, FakeStoreMake(nil), FakeWireMake())
+			mq.StrandAdd("concurrent_0", condukt.StrandConf{})
+
+			var goroutines atomic.Int32
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				strandID := "concurrent_0"
+				if !shared {
+					strandID = fmt.Sprintf("concurrent_%d", goroutines.Add(1))
+					mq.StrandAdd(strandID, condukt.StrandConf{})
+				}
+				for pb.Next() {
+					if err := mq.Send(strandID, "Concurrent Message"); err != nil {
+						b.Error(err)
+						return
+					}
+					if msg, err := mq.Receive(strandID); err == nil {
+						mq.Acknowledge(strandID, msg.ID)
+					}
+				}
+			})
+		})
+	}
+}
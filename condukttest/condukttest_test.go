@@ -0,0 +1,190 @@
+package condukttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// Test The Fakes Pass The Conformance Suites
+func TestFakeConformance(t *testing.T) {
+	StoreConformance(t, func(t *testing.T) store.Store { return FakeStoreMake(nil) })
+	WireConformance(t, func(t *testing.T) wire.Wire { return FakeWireMake() })
+}
+
+// Test Scripted Faults Reach A Conduktor
+func TestFaults(t *testing.T) {
+	fs := FakeStoreMake(nil)
+	fw := FakeWireMake()
+	mq := condukt.ConduktorMake(fs, store.RamStoreMake(), fw)
+	assert.NoError(t, mq.StrandAdd("fault_channel", condukt.StrandConf{}))
+
+	// An error injected once fails one send
+	diskFull := errors.New("disk full")
+	fs.Inject("Save", Fault{Err: diskFull, Times: 1})
+	assert.ErrorIs(t, mq.Send("fault_channel", "Lost"), diskFull)
+	assert.NoError(t, mq.Send("fault_channel", "Kept"))
+	assert.Equal(t, 2, fs.Calls("Save"))
+	msg, err := mq.Receive("fault_channel")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Kept", msg.Payload)
+		assert.NoError(t, mq.Acknowledge("fault_channel", msg.ID))
+	}
+
+	// Dropped sends are recorded but never arrive
+	fw.Inject("SendMessage", Fault{Drop: true, Times: 1})
+	assert.NoError(t, mq.Send("fault_channel", "Dropped"))
+	assert.Equal(t, "Dropped", fw.Sent()[len(fw.Sent())-1].Payload)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = mq.Receive("fault_channel", condukt.ReceiveContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Latency gives way to ctx, and lasts until cleared
+	fs.Inject("Ping", Fault{Latency: time.Hour})
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, fs.Ping(ctx), context.DeadlineExceeded)
+	fs.Clear()
+	assert.NoError(t, fs.Ping(context.Background()))
+}
+
+// simQueue simulates a producer, a consumer, and a crashing broker on a durable strand, with
+// failing saves and acks and dropped transmits, checking that deliveries keep their order within
+// each broker run, acked messages are never redelivered, and recovery loses nothing. It returns
+// the run's trace.
+func simQueue(seed int64, steps int) ([]string, error) {
+	sim := SimMake(seed)
+	durable := FakeStoreMake(nil)
+	fw := FakeWireMake()
+	options := []condukt.MakeOption{condukt.MakeLogger(zap.NewNop()), condukt.MakeClock(sim.Clock.Now)}
+	mq := condukt.ConduktorMake(store.RamStoreMake(), durable, fw, options...)
+	if err := mq.StrandAdd("sim_channel", condukt.StrandConf{Durable: true, Ordered: true}); err != nil {
+		return nil, err
+	}
+	errSim := errors.New("simulated failure")
+	sim.Fault(durable, "Save", 0.05, Fault{Err: errSim})
+	sim.Fault(durable, "Acknowledge", 0.05, Fault{Err: errSim})
+	sim.Fault(fw, "SendMessage", 0.1, Fault{Drop: true})
+
+	var trace []string
+	sent := map[int]bool{}
+	acked := map[int]bool{}
+	delivered := map[string]int{} // Unacked delivered message ID -> sequence number
+	next, last := 0, 0            // Last sequence number delivered in this broker run
+	polled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	receive := func() (bool, error) {
+		msg, err := mq.Receive("sim_channel", condukt.ReceiveContext(polled))
+		if err != nil {
+			return false, nil
+		}
+		seq, _ := strconv.Atoi(msg.Payload)
+		trace = append(trace, "receive "+msg.Payload)
+		switch {
+		case !sent[seq]:
+			return true, fmt.Errorf("delivered unsent message %d", seq)
+		case acked[seq]:
+			return true, fmt.Errorf("redelivered acked message %d", seq)
+		case seq <= last:
+			return true, fmt.Errorf("delivered message %d after %d", seq, last)
+		}
+		last = seq
+		delivered[msg.ID] = seq
+		return true, nil
+	}
+	restart := func() error {
+		fw.Reset()
+		mq = condukt.ConduktorMake(store.RamStoreMake(), durable, fw, options...)
+		last = 0
+		clear(delivered)
+		trace = append(trace, "restart")
+		return mq.RecoverUnackedMessages()
+	}
+
+	sim.Actor("send", 4, func() error {
+		next++
+		err := mq.Send("sim_channel", strconv.Itoa(next))
+		sent[next] = err == nil
+		trace = append(trace, fmt.Sprintf("send %d %v", next, err == nil))
+		return nil
+	})
+	sim.Actor("receive", 4, func() error {
+		_, err := receive()
+		return err
+	})
+	sim.Actor("ack", 3, func() error {
+		ids := slices.Sorted(maps.Keys(delivered))
+		if len(ids) == 0 {
+			return nil
+		}
+		id := ids[sim.Rand.Intn(len(ids))]
+		seq := delivered[id]
+		err := mq.Acknowledge("sim_channel", id)
+		if err == nil {
+			acked[seq] = true
+			delete(delivered, id)
+		}
+		trace = append(trace, fmt.Sprintf("ack %d %v", seq, err == nil))
+		return nil
+	})
+	sim.Actor("crash", 1, restart)
+	if err := sim.Run(steps); err != nil {
+		return trace, err
+	}
+
+	// A clean restart delivers every unacked message
+	durable.Clear()
+	fw.Clear()
+	if err := restart(); err != nil {
+		return trace, err
+	}
+	for {
+		if ok, err := receive(); err != nil {
+			return trace, err
+		} else if !ok {
+			break
+		}
+	}
+	drained := map[int]bool{}
+	for _, seq := range delivered {
+		drained[seq] = true
+	}
+	for seq, ok := range sent {
+		if ok && !acked[seq] && !drained[seq] {
+			return trace, fmt.Errorf("seed %d: lost message %d", seed, seq)
+		}
+	}
+	return trace, nil
+}
+
+// Test Simulated Runs Keep Delivery Invariants And Replay From Their Seeds
+func TestSim(t *testing.T) {
+	seed := SimSeed(t)
+	for i := int64(0); i < 10; i++ {
+		_, err := simQueue(seed+i, 300)
+		assert.NoError(t, err)
+	}
+
+	first, err := simQueue(seed, 200)
+	assert.NoError(t, err)
+	replay, _ := simQueue(seed, 200)
+	assert.Equal(t, first, replay)
+
+	clock := VirtualClockMake(time.Unix(0, 0))
+	assert.NotEqual(t, clock.Now(), clock.Now())
+	clock.Advance(time.Hour)
+	assert.True(t, clock.Now().After(time.Unix(3600, 0)))
+}
@@ -0,0 +1,242 @@
+package condukttest
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// StoreConformance checks the behavior a Conduktor relies on from a Store. makeStore is called for
+// each subtest and should return an empty store, registering its cleanup with t.Cleanup.
+func StoreConformance(t *testing.T, makeStore func(t *testing.T) store.Store) {
+	ctx := context.Background()
+
+	t.Run("Strands", func(t *testing.T) {
+		s := makeStore(t)
+		conf := store.StrandConf{Durable: true, Ordered: true, MaxBytes: 1024, Overflow: store.OverflowEvict}
+		assert.False(t, s.HasStrand(ctx, "conformance_strand"))
+		assert.NoError(t, s.CreateStrand(ctx, "conformance_strand", conf))
+		assert.True(t, s.HasStrand(ctx, "conformance_strand"))
+
+		strands, err := s.ListStrands(ctx)
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]store.StrandConf{"conformance_strand": conf}, strands)
+		}
+
+		assert.NoError(t, s.DeleteStrand(ctx, "conformance_strand"))
+		assert.False(t, s.HasStrand(ctx, "conformance_strand"))
+		strands, err = s.ListStrands(ctx)
+		if assert.NoError(t, err) {
+			assert.Empty(t, strands)
+		}
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		s := makeStore(t)
+		assert.NoError(t, s.CreateStrand(ctx, "conformance_strand", store.StrandConf{Durable: true}))
+		sizes := map[string]int64{}
+		for _, id := range []string{"1", "2", "3"} {
+			msg := wire.Msg{ID: id, Strand: "conformance_strand", Payload: "Payload " + id, Headers: map[string]string{"id": id}}
+			assert.NoError(t, s.Save(ctx, msg))
+			sizes[id] = msg.Size()
+		}
+		depth, err := s.Depth(ctx, "conformance_strand")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, depth)
+		full, err := s.Bytes(ctx, "conformance_strand") // Counted in Msg.Size, whatever the store's encoding
+		assert.NoError(t, err)
+		assert.Equal(t, sizes["1"]+sizes["2"]+sizes["3"], full)
+
+		msg, err := s.Get(ctx, "conformance_strand", "2")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Payload 2", msg.Payload)
+			assert.Equal(t, map[string]string{"id": "2"}, msg.Headers)
+		}
+		peeked, err := s.Peek(ctx, "conformance_strand", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2"}, msgIDs(peeked))
+
+		// Acknowledged messages are gone
+		assert.NoError(t, s.Acknowledge(ctx, "conformance_strand", "2"))
+		_, err = s.Get(ctx, "conformance_strand", "2")
+		assert.Error(t, err)
+		peeked, err = s.Peek(ctx, "conformance_strand", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "3"}, msgIDs(peeked))
+		depth, _ = s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 2, depth)
+		bytes, _ := s.Bytes(ctx, "conformance_strand")
+		assert.Equal(t, full-sizes["2"], bytes)
+
+		// Saving an ID again replaces its message
+		replaced := wire.Msg{ID: "3", Strand: "conformance_strand", Payload: "Replaced"}
+		assert.NoError(t, s.Save(ctx, replaced))
+		bytes, _ = s.Bytes(ctx, "conformance_strand")
+		assert.Equal(t, sizes["1"]+replaced.Size(), bytes)
+		depth, _ = s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 2, depth)
+		if msg, err := s.Get(ctx, "conformance_strand", "3"); assert.NoError(t, err) {
+			assert.Equal(t, "Replaced", msg.Payload)
+		}
+		assert.NoError(t, s.Reconcile(ctx))
+		depth, _ = s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 2, depth)
+		bytes, _ = s.Bytes(ctx, "conformance_strand")
+		assert.Equal(t, sizes["1"]+replaced.Size(), bytes)
+	})
+
+	t.Run("AcknowledgeBatch", func(t *testing.T) {
+		s := makeStore(t)
+		assert.NoError(t, s.CreateStrand(ctx, "conformance_strand", store.StrandConf{Durable: true}))
+		for _, id := range []string{"1", "2", "3", "4"} {
+			assert.NoError(t, s.Save(ctx, wire.Msg{ID: id, Strand: "conformance_strand", Payload: "x"}))
+		}
+		assert.NoError(t, s.AcknowledgeBatch(ctx, "conformance_strand", []string{"1", "3"}))
+		peeked, err := s.Peek(ctx, "conformance_strand", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2", "4"}, msgIDs(peeked))
+		depth, _ := s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 2, depth)
+		assert.NoError(t, s.AcknowledgeBatch(ctx, "conformance_strand", nil))
+
+		// A missing message fails the whole batch; repeated ones are acknowledged once
+		assert.Error(t, s.AcknowledgeBatch(ctx, "conformance_strand", []string{"2", "3"}))
+		depth, _ = s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 2, depth)
+		assert.NoError(t, s.AcknowledgeBatch(ctx, "conformance_strand", []string{"2", "2"}))
+		peeked, _ = s.Peek(ctx, "conformance_strand", 0)
+		assert.Equal(t, []string{"4"}, msgIDs(peeked))
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		s := makeStore(t)
+		assert.NoError(t, s.CreateStrand(ctx, "conformance_strand", store.StrandConf{Durable: true}))
+		for _, id := range []string{"1", "2"} {
+			assert.NoError(t, s.Save(ctx, wire.Msg{ID: id, Strand: "conformance_strand", Payload: "x"}))
+		}
+		purged, err := s.Purge(ctx, "conformance_strand")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, purged)
+		assert.True(t, s.HasStrand(ctx, "conformance_strand"))
+		depth, _ := s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 0, depth)
+		bytes, _ := s.Bytes(ctx, "conformance_strand")
+		assert.Equal(t, int64(0), bytes)
+	})
+
+	t.Run("UnackedIterator", func(t *testing.T) {
+		s := makeStore(t)
+		for _, strandID := range []string{"conformance_a", "conformance_b"} {
+			assert.NoError(t, s.CreateStrand(ctx, strandID, store.StrandConf{Durable: true}))
+			assert.NoError(t, s.Save(ctx, wire.Msg{ID: "1", Strand: strandID, Payload: "x"}))
+			assert.NoError(t, s.Save(ctx, wire.Msg{ID: "2", Strand: strandID, Payload: "x"}))
+		}
+		assert.NoError(t, s.Acknowledge(ctx, "conformance_b", "1"))
+
+		it, err := s.UnackedIterator(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer it.Close()
+		unacked := []string{}
+		for msg, ok := it.Next(); ok; msg, ok = it.Next() {
+			unacked = append(unacked, msg.Strand+"/"+msg.ID)
+		}
+		sort.Strings(unacked)
+		assert.Equal(t, []string{"conformance_a/1", "conformance_a/2", "conformance_b/2"}, unacked)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		s := makeStore(t)
+		assert.NoError(t, s.Ping(ctx))
+		assert.NoError(t, s.CreateStrand(ctx, "conformance_strand", store.StrandConf{Durable: true}))
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(t, s.Save(canceled, wire.Msg{ID: "1", Strand: "conformance_strand", Payload: "x"}), context.Canceled)
+		assert.ErrorIs(t, s.CreateStrand(canceled, "conformance_other", store.StrandConf{}), context.Canceled)
+		assert.False(t, s.HasStrand(canceled, "conformance_strand"))
+		_, err := s.Peek(canceled, "conformance_strand", 0)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Error(t, s.Ping(canceled))
+		depth, _ := s.Depth(ctx, "conformance_strand")
+		assert.Equal(t, 0, depth, "canceled saves store nothing")
+	})
+}
+
+// WireConformance checks the behavior a Conduktor relies on from a Wire that carries messages
+// from its senders to its receivers in the same process. makeWire is called for each subtest.
+func WireConformance(t *testing.T, makeWire func(t *testing.T) wire.Wire) {
+	ctx := context.Background()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		w := makeWire(t)
+		for _, id := range []string{"1", "2", "3"} {
+			msg := wire.Msg{ID: id, Strand: "conformance_strand", Payload: "Payload " + id, Headers: map[string]string{"id": id}}
+			assert.NoError(t, w.SendMessage(ctx, msg))
+		}
+		for _, id := range []string{"1", "2", "3"} {
+			msg, err := receive(w, "conformance_strand")
+			if assert.NoError(t, err) {
+				assert.Equal(t, id, msg.ID, "messages arrive in order")
+				assert.Equal(t, "conformance_strand", msg.Strand)
+				assert.Equal(t, "Payload "+id, msg.Payload)
+				assert.Equal(t, map[string]string{"id": id}, msg.Headers)
+			}
+		}
+	})
+
+	t.Run("Channels", func(t *testing.T) {
+		w := makeWire(t)
+		assert.NoError(t, w.SendMessage(ctx, wire.Msg{ID: "a", Strand: "conformance_a", Payload: "x"}))
+		assert.NoError(t, w.SendMessage(ctx, wire.Msg{ID: "b", Strand: "conformance_b", Payload: "x"}))
+		msg, err := receive(w, "conformance_b")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "b", msg.ID)
+		}
+		msg, err = receive(w, "conformance_a")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "a", msg.ID)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		w := makeWire(t)
+		assert.NoError(t, w.SendMessage(ctx, wire.Msg{ID: "1", Strand: "conformance_strand", Payload: "x"}))
+		_, err := receive(w, "conformance_strand")
+		assert.NoError(t, err)
+
+		// Receives on an empty channel give up when ctx is done
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = w.ReceiveMessage(timeout, "conformance_strand")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.Error(t, w.SendMessage(canceled, wire.Msg{ID: "2", Strand: "conformance_strand", Payload: "x"}))
+	})
+}
+
+// receive receives a message from channel, giving up after a second.
+func receive(w wire.Wire, channel string) (*wire.Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return w.ReceiveMessage(ctx, channel)
+}
+
+// msgIDs returns the IDs of messages.
+func msgIDs(messages []wire.Msg) []string {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	return ids
+}
@@ -0,0 +1,82 @@
+// Package condukttest provides fakes of condukt's Store and Wire for unit tests, with scriptable
+// errors and latencies, and conformance suites that custom Store and Wire implementations can run
+// to check they behave as a Conduktor expects.
+package condukttest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Fault scripts how a fake's operation misbehaves.
+type Fault struct {
+	Err     error         // Returned instead of performing the operation, if set
+	Latency time.Duration // Waited before the operation, giving up early with ctx.Err()
+	Drop    bool          // FakeWire sends only: report success but discard the message
+	Times   int           // Operations it applies to before expiring; 0 applies until Clear
+}
+
+// faults holds the faults injected into a fake's operations and counts the operations called.
+type faults struct {
+	mu      sync.Mutex
+	scripts map[string][]Fault // Operation -> its faults, applied in order
+	calls   map[string]int
+}
+
+// Inject scripts fault for op, the name of a fake's method like "Save" or "SendMessage". Faults
+// injected for the same op apply one after another as each expires.
+func (f *faults) Inject(op string, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.scripts == nil {
+		f.scripts = make(map[string][]Fault)
+	}
+	f.scripts[op] = append(f.scripts[op], fault)
+}
+
+// Clear removes every injected fault.
+func (f *faults) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts = nil
+}
+
+// Calls returns how many times op has been called, faulted or not.
+func (f *faults) Calls(op string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// apply counts a call of op and applies its next fault, returning the fault and the error the
+// operation should fail with, if any.
+func (f *faults) apply(ctx context.Context, op string) (Fault, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[op]++
+	var fault Fault
+	if script := f.scripts[op]; len(script) > 0 {
+		fault = script[0]
+		if script[0].Times > 0 {
+			script[0].Times--
+			if script[0].Times == 0 {
+				f.scripts[op] = script[1:]
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return fault, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return fault, fault.Err
+}
@@ -0,0 +1,137 @@
+package condukttest
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// SimSeedEnv names the environment variable that replays a simulation with a given seed.
+const SimSeedEnv = "CONDUKT_SIM_SEED"
+
+// SimSeed returns the seed in $CONDUKT_SIM_SEED, to replay a failed run, or else a fresh one.
+// It logs the seed with t, so failures can be replayed.
+func SimSeed(t testing.TB) int64 {
+	t.Helper()
+	seed := time.Now().UnixNano()
+	if env := os.Getenv(SimSeedEnv); env != "" {
+		var err error
+		if seed, err = strconv.ParseInt(env, 10, 64); err != nil {
+			t.Fatalf("%s: %v", SimSeedEnv, err)
+		}
+	}
+	t.Logf("simulation seed %d; replay with %s=%d", seed, SimSeedEnv, seed)
+	return seed
+}
+
+// VirtualClock is a clock that only moves when told to, for Conduktors made with MakeClock.
+// Each reading also advances it a nanosecond, so message IDs stamped from it stay unique.
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// VirtualClockMake starts a clock at start.
+func VirtualClockMake(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now reads the clock.
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(time.Nanosecond)
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Injector is a fake that takes scripted faults, like FakeStore and FakeWire.
+type Injector interface {
+	Inject(op string, fault Fault)
+}
+
+// Sim runs a deterministic simulation. Its scheduler runs one actor step at a time on the
+// calling goroutine, choosing actors, clock advances, and faults from a random source seeded
+// with Seed, so a run replays exactly from its seed. Actors should not start goroutines, and
+// faults should not have Latency, which waits in real time.
+type Sim struct {
+	Seed  int64
+	Rand  *rand.Rand
+	Clock *VirtualClock
+	Step  int // Steps run so far
+
+	actors []simActor
+	faults []simFault
+}
+
+type simActor struct {
+	name   string
+	weight int
+	step   func() error
+}
+
+type simFault struct {
+	target      Injector
+	op          string
+	probability float64
+	fault       Fault
+}
+
+// SimMake makes a simulation seeded with seed, its clock starting at the Unix epoch.
+func SimMake(seed int64) *Sim {
+	return &Sim{Seed: seed, Rand: rand.New(rand.NewSource(seed)), Clock: VirtualClockMake(time.Unix(0, 0))}
+}
+
+// Actor adds an actor the scheduler runs with a probability proportional to weight. An error
+// from step fails the run.
+func (s *Sim) Actor(name string, weight int, step func() error) {
+	s.actors = append(s.actors, simActor{name: name, weight: weight, step: step})
+}
+
+// Fault injects fault, once, into target's op before each step with the given probability.
+func (s *Sim) Fault(target Injector, op string, probability float64, fault Fault) {
+	fault.Times = 1
+	s.faults = append(s.faults, simFault{target: target, op: op, probability: probability, fault: fault})
+}
+
+// Run runs steps steps, each advancing the clock up to a millisecond, injecting faults, and
+// running one actor. It returns the first actor error, naming the seed that replays it.
+func (s *Sim) Run(steps int) error {
+	total := 0
+	for _, actor := range s.actors {
+		total += actor.weight
+	}
+	if total <= 0 {
+		return fmt.Errorf("simulation has no actors")
+	}
+
+	for end := s.Step + steps; s.Step < end; s.Step++ {
+		s.Clock.Advance(time.Duration(s.Rand.Int63n(int64(time.Millisecond))))
+		for _, f := range s.faults {
+			if s.Rand.Float64() < f.probability {
+				f.target.Inject(f.op, f.fault)
+			}
+		}
+
+		pick := s.Rand.Intn(total)
+		for _, actor := range s.actors {
+			if pick -= actor.weight; pick < 0 {
+				if err := actor.step(); err != nil {
+					return fmt.Errorf("seed %d step %d %s: %w", s.Seed, s.Step, actor.name, err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package condukttest
+
+import (
+	"context"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+)
+
+// FakeStore is a Store that passes operations to a backing store unless an injected Fault stops
+// them. Faults are injected by method name, like "Save".
+type FakeStore struct {
+	faults
+	backing store.Store
+}
+
+// FakeStoreMake wraps backing, or a new RamStore if it is nil.
+func FakeStoreMake(backing store.Store) *FakeStore {
+	if backing == nil {
+		backing = store.RamStoreMake()
+	}
+	return &FakeStore{backing: backing}
+}
+
+// CreateStrand creates the strand in the backing store.
+func (s *FakeStore) CreateStrand(ctx context.Context, strandID string, config store.StrandConf) error {
+	if _, err := s.apply(ctx, "CreateStrand"); err != nil {
+		return err
+	}
+	return s.backing.CreateStrand(ctx, strandID, config)
+}
+
+// DeleteStrand deletes the strand from the backing store.
+func (s *FakeStore) DeleteStrand(ctx context.Context, strandID string) error {
+	if _, err := s.apply(ctx, "DeleteStrand"); err != nil {
+		return err
+	}
+	return s.backing.DeleteStrand(ctx, strandID)
+}
+
+// HasStrand checks the backing store, reporting false when faulted.
+func (s *FakeStore) HasStrand(ctx context.Context, strandID string) bool {
+	if _, err := s.apply(ctx, "HasStrand"); err != nil {
+		return false
+	}
+	return s.backing.HasStrand(ctx, strandID)
+}
+
+// Save saves msg in the backing store.
+func (s *FakeStore) Save(ctx context.Context, msg wire.Msg) error {
+	if _, err := s.apply(ctx, "Save"); err != nil {
+		return err
+	}
+	return s.backing.Save(ctx, msg)
+}
+
+// Acknowledge acknowledges the message in the backing store.
+func (s *FakeStore) Acknowledge(ctx context.Context, strandID, msgID string) error {
+	if _, err := s.apply(ctx, "Acknowledge"); err != nil {
+		return err
+	}
+	return s.backing.Acknowledge(ctx, strandID, msgID)
+}
+
+// AcknowledgeBatch acknowledges the messages in the backing store.
+func (s *FakeStore) AcknowledgeBatch(ctx context.Context, strandID string, msgIDs []string) error {
+	if _, err := s.apply(ctx, "AcknowledgeBatch"); err != nil {
+		return err
+	}
+	return s.backing.AcknowledgeBatch(ctx, strandID, msgIDs)
+}
+
+// ListStrands lists the backing store's strands.
+func (s *FakeStore) ListStrands(ctx context.Context) (map[string]store.StrandConf, error) {
+	if _, err := s.apply(ctx, "ListStrands"); err != nil {
+		return nil, err
+	}
+	return s.backing.ListStrands(ctx)
+}
+
+// Get gets the message from the backing store.
+func (s *FakeStore) Get(ctx context.Context, strandID, msgID string) (*wire.Msg, error) {
+	if _, err := s.apply(ctx, "Get"); err != nil {
+		return nil, err
+	}
+	return s.backing.Get(ctx, strandID, msgID)
+}
+
+// Peek peeks at the backing store's messages.
+func (s *FakeStore) Peek(ctx context.Context, strandID string, limit int) ([]wire.Msg, error) {
+	if _, err := s.apply(ctx, "Peek"); err != nil {
+		return nil, err
+	}
+	return s.backing.Peek(ctx, strandID, limit)
+}
+
+// Depth returns the strand's depth in the backing store.
+func (s *FakeStore) Depth(ctx context.Context, strandID string) (int, error) {
+	if _, err := s.apply(ctx, "Depth"); err != nil {
+		return 0, err
+	}
+	return s.backing.Depth(ctx, strandID)
+}
+
+// Bytes returns the strand's bytes in the backing store.
+func (s *FakeStore) Bytes(ctx context.Context, strandID string) (int64, error) {
+	if _, err := s.apply(ctx, "Bytes"); err != nil {
+		return 0, err
+	}
+	return s.backing.Bytes(ctx, strandID)
+}
+
+// Purge purges the strand in the backing store.
+func (s *FakeStore) Purge(ctx context.Context, strandID string) (int, error) {
+	if _, err := s.apply(ctx, "Purge"); err != nil {
+		return 0, err
+	}
+	return s.backing.Purge(ctx, strandID)
+}
+
+// Reconcile reconciles the backing store.
+func (s *FakeStore) Reconcile(ctx context.Context) error {
+	if _, err := s.apply(ctx, "Reconcile"); err != nil {
+		return err
+	}
+	return s.backing.Reconcile(ctx)
+}
+
+// UnackedIterator iterates over the backing store's unacked messages.
+func (s *FakeStore) UnackedIterator(ctx context.Context) (store.UnackedMessageIterator, error) {
+	if _, err := s.apply(ctx, "UnackedIterator"); err != nil {
+		return nil, err
+	}
+	return s.backing.UnackedIterator(ctx)
+}
+
+// Ping pings the backing store.
+func (s *FakeStore) Ping(ctx context.Context) error {
+	if _, err := s.apply(ctx, "Ping"); err != nil {
+		return err
+	}
+	return s.backing.Ping(ctx)
+}
+
+// Close closes the backing store.
+func (s *FakeStore) Close() error {
+	if _, err := s.apply(context.Background(), "Close"); err != nil {
+		return err
+	}
+	return s.backing.Close()
+}
+
+// Reload reloads the backing store.
+func (s *FakeStore) Reload(ctx context.Context) error {
+	if _, err := s.apply(ctx, "Reload"); err != nil {
+		return err
+	}
+	return s.backing.Reload(ctx)
+}
+
+// Reset resets the backing store.
+func (s *FakeStore) Reset(ctx context.Context) error {
+	if _, err := s.apply(ctx, "Reset"); err != nil {
+		return err
+	}
+	return s.backing.Reset(ctx)
+}
@@ -0,0 +1,94 @@
+package condukttest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jkassis/condukt/wire"
+)
+
+// FakeWire is an in-memory Wire that records what it sends. Faults are injected by method name:
+// "SendMessage" or "ReceiveMessage". Unlike GoChanWire, receives wait for a channel's first message.
+type FakeWire struct {
+	faults
+	mu       sync.Mutex
+	channels map[string]chan wire.Msg
+	sent     []wire.Msg
+}
+
+// FakeWireMake makes an empty FakeWire.
+func FakeWireMake() *FakeWire {
+	return &FakeWire{channels: make(map[string]chan wire.Msg)}
+}
+
+// SendMessage queues msg on its strand's channel, unless a fault fails or drops it.
+func (w *FakeWire) SendMessage(ctx context.Context, msg wire.Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fault, err := w.apply(ctx, "SendMessage")
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sent = append(w.sent, msg)
+	if fault.Drop {
+		return nil
+	}
+	select {
+	case w.channel(msg.Strand) <- msg:
+		return nil
+	default:
+		return errors.New("channel buffer full")
+	}
+}
+
+// ReceiveMessage waits for the next message on channel, or for ctx to be done. Queued messages
+// are returned even if ctx is done, so a receive with a done ctx polls deterministically.
+func (w *FakeWire) ReceiveMessage(ctx context.Context, channel string) (*wire.Msg, error) {
+	if _, err := w.apply(ctx, "ReceiveMessage"); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	ch := w.channel(channel)
+	w.mu.Unlock()
+	select {
+	case msg := <-ch:
+		return &msg, nil
+	default:
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-ch:
+		return &msg, nil
+	}
+}
+
+// Reset discards the queued messages of every channel, as a crashed wire loses them.
+func (w *FakeWire) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.channels = make(map[string]chan wire.Msg)
+}
+
+// Sent returns every message SendMessage accepted, including dropped ones, in order.
+func (w *FakeWire) Sent() []wire.Msg {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]wire.Msg{}, w.sent...)
+}
+
+// channel returns the named channel, making it if needed. Callers must hold w.mu.
+func (w *FakeWire) channel(name string) chan wire.Msg {
+	ch, exists := w.channels[name]
+	if !exists {
+		ch = make(chan wire.Msg, 100000)
+		w.channels[name] = ch
+	}
+	return ch
+}
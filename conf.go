@@ -1,7 +0,0 @@
-package main
-
-// StrandConf holds per-queue settings.
-type StrandConf struct {
-	Durable bool
-	Ordered bool
-}
@@ -0,0 +1,850 @@
+package condukt
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// configEnvPrefix prefixes environment variables that override config file settings.
+// Nested keys are joined with underscores, so listen.admin is CONDUKT_LISTEN_ADMIN.
+const configEnvPrefix = "CONDUKT"
+
+// Config is the startup configuration of the condukt server.
+type Config struct {
+	Store      StoreConfig    `yaml:"store"`
+	Wire       WireConfig     `yaml:"wire"`
+	Listen     ListenConfig   `yaml:"listen"`
+	Metrics    MetricsConfig  `yaml:"metrics"`
+	Daemon     DaemonConfig   `yaml:"daemon"`
+	Audit      AuditConfig    `yaml:"audit"`
+	Events     EventsConfig   `yaml:"events"`
+	Auth       AuthConf       `yaml:"auth"`
+	ACL        ACL            `yaml:"acl"`        // Per-strand permissions of authenticated identities; empty allows everything
+	ACLFile    string         `yaml:"acl_file"`   // YAML file of the ACL, seeded from acl if missing, reloaded on change, and written by PUT /admin/acl
+	Encryption EncryptionConf `yaml:"encryption"` // Encrypting payloads before they are stored
+	Signing    SigningConf    `yaml:"signing"`    // Signing messages at send and verifying them at receive
+	Secrets    SecretsConf    `yaml:"secrets"`    // Providers of the secrets *_secret and secret settings reference
+	Alerts     AlertConf      `yaml:"alerts"`
+	Backup     BackupConf     `yaml:"backup"`
+	Tracing    TracingConf    `yaml:"tracing"`
+	Log        LogConfig      `yaml:"log"`
+	Strands    []StrandPreset `yaml:"strands"` // Strands created at startup
+	Limits     Limits         `yaml:"limits"`
+
+	Namespaces    map[string]NamespaceQuota `yaml:"namespaces"` // Namespace -> quota of its strands
+	SlowConsumers SlowConsumerConf          `yaml:"slow_consumers"`
+	Recovery      RecoveryConf              `yaml:"recovery"`  // Resending unacked messages at startup
+	Dispatch      DispatchConf              `yaml:"dispatch"`  // Delivering subscribed strands
+	Retry         RetryConf                 `yaml:"retry"`     // Retrying failed wire sends
+	Admission     AdmissionConf             `yaml:"admission"` // Shedding sends to stores under pressure
+	Sinks         SinksConfig               `yaml:"sinks"`     // Exporting strands to other systems
+	Sources       SourcesConfig             `yaml:"sources"`   // Importing messages from other systems
+	Scheduler     SchedulerConf             `yaml:"scheduler"` // Publishing messages on cron schedules
+}
+
+// StoreConfig selects the volatile and durable stores.
+type StoreConfig struct {
+	Volatile string `yaml:"volatile"` // ram
+	Durable  string `yaml:"durable"`  // badger or ram
+	Path     string `yaml:"path"`     // Badger data directory
+
+	GroupCommit GroupCommitConf `yaml:"group_commit"` // Badger only; max_delay 0 commits each save alone
+	Snapshot    SnapshotConf    `yaml:"snapshot"`     // Of the volatile store; unset takes no snapshots
+}
+
+// WireConfig selects the transport.
+type WireConfig struct {
+	Type         string `yaml:"type"`          // ws, udp, or gochan
+	Addr         string `yaml:"addr"`          // Remote address for udp
+	DatagramSize int    `yaml:"datagram_size"` // Largest encoded udp message; 0 is 4096. Every node must agree
+	// Codec messages are stored and carried between nodes with: binary (default) or json, for
+	// debugging or while upgrading from a release without the binary codec. It applies to the stores
+	// and wire the config makes.
+	Codec string `yaml:"codec"`
+
+	Coalesce CoalesceConf `yaml:"coalesce"` // ws only; off unless interval is set
+	Buffer   RingConf     `yaml:"buffer"`   // gochan only; per-strand buffers
+}
+
+// ListenConfig holds listen addresses. An empty address disables the listener.
+type ListenConfig struct {
+	Admin    string  `yaml:"admin"`     // JSON admin API, dashboard, and HTTP publishing
+	GRPC     string  `yaml:"grpc"`      // gRPC admin API
+	GRPCData string  `yaml:"grpc_data"` // gRPC data-plane API, streaming strands to consumers
+	Wire     string  `yaml:"wire"`      // WebSocket clients, at /ws/{strand}
+	Clients  string  `yaml:"clients"`   // Remote clients at /client, and the browser client at /client.js
+	TLS      TLSConf `yaml:"tls"`       // Of every listener but metrics
+	// Filters holds the IP allow and deny rules of each listener: metrics, admin, grpc, grpc_data,
+	// wire (WebSocket or UDP), and clients. Listeners without rules accept any address.
+	Filters map[string]IPFilterConf `yaml:"filters"`
+}
+
+// listenerNames are the listeners IP filters apply to.
+var listenerNames = []string{"metrics", "admin", "grpc", "grpc_data", "wire", "clients"}
+
+// IPFilter returns the IP filter of listener, or nil if it has no rules.
+func (conf ListenConfig) IPFilter(listener string) (*IPFilter, error) {
+	filter, exists := conf.Filters[listener]
+	if !exists || !filter.Enabled() {
+		return nil, nil
+	}
+	return IPFilterMake(listener, filter)
+}
+
+// AuditConfig selects where administrative operations are recorded.
+type AuditConfig struct {
+	File   string `yaml:"file"`   // Append JSON lines to this file; empty disables it
+	Strand bool   `yaml:"strand"` // Append to the durable _audit strand
+}
+
+// EventsConfig selects where message lifecycle events are recorded.
+type EventsConfig struct {
+	File string `yaml:"file"` // Append JSON lines to this file; empty disables the event log
+}
+
+// SinksConfig configures the sinks strands are exported to, each started at startup.
+type SinksConfig struct {
+	Kafka      []KafkaSinkConf      `yaml:"kafka"`
+	S3         []S3SinkConf         `yaml:"s3"`
+	File       []FileSinkConf       `yaml:"file"`
+	Redis      []RedisSinkConf      `yaml:"redis"`
+	SQS        []SQSSinkConf        `yaml:"sqs"`
+	PubSub     []PubSubSinkConf     `yaml:"pubsub"`
+	ServiceBus []ServiceBusSinkConf `yaml:"servicebus"`
+}
+
+// SourcesConfig configures the sources messages are imported from, each started at startup.
+type SourcesConfig struct {
+	JetStream  []JetStreamSourceConf  `yaml:"jetstream"`
+	Postgres   []PostgresSourceConf   `yaml:"postgres"`
+	Redis      []RedisSourceConf      `yaml:"redis"`
+	SQS        []SQSSourceConf        `yaml:"sqs"`
+	PubSub     []PubSubSourceConf     `yaml:"pubsub"`
+	ServiceBus []ServiceBusSourceConf `yaml:"servicebus"`
+}
+
+// LogConfig configures logging.
+type LogConfig struct {
+	Level zapcore.Level `yaml:"level"` // debug, info, warn, or error; changeable at runtime through the admin API
+}
+
+// StrandPreset is a strand created at startup.
+type StrandPreset struct {
+	ID                string `yaml:"id"`
+	Preset            string `yaml:"preset"` // work_queue, event_log, or realtime, which the settings below add to
+	Durable           bool   `yaml:"durable"`
+	Ordered           bool   `yaml:"ordered"`
+	ReplicationFactor int    `yaml:"replication_factor"`
+	MaxBytes          int64  `yaml:"max_bytes"`     // Cap on stored unacked bytes; 0 is unlimited
+	Overflow          string `yaml:"overflow"`      // reject (default) or evict, when a send would exceed max_bytes
+	Schema            string `yaml:"schema"`        // JSON Schema file sent payloads must match; empty accepts any payload
+	QuietMetrics      bool   `yaml:"quiet_metrics"` // Skip per-message metrics, for very hot strands
+}
+
+// ConfigDefault returns the configuration used when no file or environment overrides are given.
+func ConfigDefault() Config {
+	return Config{
+		Store:   StoreConfig{Volatile: "ram", Durable: "badger", Path: "/tmp/badgerdb"},
+		Wire:    WireConfig{Type: "ws"},
+		Listen:  ListenConfig{Admin: ":9091", GRPC: ":9092", Wire: ":8080"},
+		Metrics: MetricsConfig{Addr: ":9090", Path: "/metrics", ReconcileInterval: time.Minute},
+		Daemon:  DaemonConfig{ShutdownTimeout: 30 * time.Second},
+	}
+}
+
+// ConfigLoad layers the YAML file at path (if any) and CONDUKT_* environment variables over the defaults,
+// then validates the result.
+func ConfigLoad(path string) (Config, error) {
+	cfg := ConfigDefault()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+
+	if err := configEnv(reflect.ValueOf(&cfg).Elem(), configEnvPrefix); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// configEnv overrides the fields of v from environment variables named after their yaml keys.
+func configEnv(v reflect.Value, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := configEnv(fv, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, set := os.LookupEnv(name)
+		if !set {
+			continue
+		}
+		if err := configSet(fv, raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// configSet parses raw into a scalar config field.
+func configSet(fv reflect.Value, raw string) error {
+	switch {
+	case fv.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+	case fv.CanAddr() && fv.Addr().Type().Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()):
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	case fv.Kind() == reflect.String:
+		fv.SetString(raw)
+	case fv.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case fv.Kind() == reflect.Int || fv.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case fv.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration.
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if cfg.Store.Volatile != "ram" {
+		errs = append(errs, fmt.Errorf("store.volatile: unknown store %q (want ram)", cfg.Store.Volatile))
+	}
+	switch cfg.Store.Durable {
+	case "ram":
+	case "badger":
+		if cfg.Store.Path == "" {
+			errs = append(errs, errors.New("store.path: required for the badger store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("store.durable: unknown store %q (want badger or ram)", cfg.Store.Durable))
+	}
+	if err := cfg.Store.GroupCommit.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("store.%w", err))
+	}
+	if err := cfg.Store.Snapshot.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("store.%w", err))
+	}
+
+	switch cfg.Wire.Type {
+	case "ws", "gochan":
+	case "udp":
+		if cfg.Wire.Addr == "" {
+			errs = append(errs, errors.New("wire.addr: required for the udp wire"))
+		}
+		if cfg.Wire.DatagramSize < 0 || cfg.Wire.DatagramSize > wire.UDPDatagramMax {
+			errs = append(errs, fmt.Errorf("wire.datagram_size: must be 0 to %d", wire.UDPDatagramMax))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("wire.type: unknown wire %q (want ws, udp, or gochan)", cfg.Wire.Type))
+	}
+	if err := wire.MsgCodecCheck(cfg.Wire.Codec); err != nil {
+		errs = append(errs, fmt.Errorf("wire.codec: %w", err))
+	}
+	if err := cfg.Wire.Coalesce.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("wire.%w", err))
+	}
+	if err := cfg.Wire.Buffer.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("wire.%w", err))
+	}
+
+	if (cfg.Metrics.TLSCert == "") != (cfg.Metrics.TLSKey == "") {
+		errs = append(errs, errors.New("metrics: tls_cert and tls_key must be set together"))
+	}
+	for key, file := range map[string]string{"metrics.tls_cert": cfg.Metrics.TLSCert, "metrics.tls_key": cfg.Metrics.TLSKey} {
+		if _, err := os.Stat(file); file != "" && err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	if cfg.Metrics.Path != "" && !strings.HasPrefix(cfg.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("metrics.path: %q must start with /", cfg.Metrics.Path))
+	}
+	if cfg.Metrics.Prefix != "" && !model.IsValidLegacyMetricName(model.LabelValue(cfg.Metrics.Prefix+"x")) {
+		errs = append(errs, fmt.Errorf("metrics.prefix: %q is not a valid metric name prefix", cfg.Metrics.Prefix))
+	}
+	for name := range cfg.Metrics.Labels {
+		if !model.LabelName(name).IsValid() {
+			errs = append(errs, fmt.Errorf("metrics.labels: %q is not a valid label name", name))
+		}
+	}
+
+	if cfg.Metrics.ReconcileInterval <= 0 {
+		errs = append(errs, errors.New("metrics.reconcile_interval: must be positive"))
+	}
+	if cfg.Alerts.Webhook != "" {
+		if u, err := url.Parse(cfg.Alerts.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("alerts.webhook: %q is not an http(s) URL", cfg.Alerts.Webhook))
+		}
+		if cfg.Alerts.DepthAbove <= 0 && cfg.Alerts.DLQDepthAbove <= 0 && cfg.Alerts.UnackedAgeAbove <= 0 {
+			errs = append(errs, errors.New("alerts: a webhook is set but no threshold is"))
+		}
+	}
+	if cfg.Daemon.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("daemon.shutdown_timeout: must be positive"))
+	}
+
+	if err := cfg.Listen.TLS.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("listen.tls: %w", err))
+	}
+	for listener, filter := range cfg.Listen.Filters {
+		if !slices.Contains(listenerNames, listener) {
+			errs = append(errs, fmt.Errorf("listen.filters: unknown listener %q (want %s)", listener, strings.Join(listenerNames, ", ")))
+		}
+		if err := filter.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("listen.filters.%s.%w", listener, err))
+		}
+	}
+	if err := cfg.Auth.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.ACL.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Encryption.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("encryption.%w", err))
+	}
+	if err := cfg.Signing.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("signing.%w", err))
+	}
+	if err := cfg.secretsValidate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Tracing.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.SlowConsumers.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Recovery.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Dispatch.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Retry.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Admission.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Metrics.Export.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if cfg.Backup.Interval > 0 {
+		if cfg.Store.Durable != "badger" {
+			errs = append(errs, errors.New("backup: requires the badger durable store"))
+		}
+		if cfg.Backup.S3 != "" {
+			if _, _, err := backupS3URL(cfg.Backup.S3); err != nil {
+				errs = append(errs, fmt.Errorf("backup.s3: %w", err))
+			}
+		} else if cfg.Backup.Path == "" {
+			errs = append(errs, errors.New("backup: path or s3 is required"))
+		}
+	}
+	if cfg.Backup.Interval < 0 || cfg.Backup.Keep < 0 {
+		errs = append(errs, errors.New("backup: interval and keep must not be negative"))
+	}
+
+	seen := make(map[string]bool)
+	for i, preset := range cfg.Strands {
+		if preset.ID == "" {
+			errs = append(errs, fmt.Errorf("strands[%d].id: required", i))
+		} else if seen[preset.ID] {
+			errs = append(errs, fmt.Errorf("strands[%d].id: duplicate strand %q", i, preset.ID))
+		}
+		seen[preset.ID] = true
+		if _, ok := store.Presets[preset.Preset]; preset.Preset != "" && !ok {
+			errs = append(errs, fmt.Errorf("strands[%d].preset: unknown preset %q (want work_queue, event_log, or realtime)", i, preset.Preset))
+		}
+		if err := preset.StrandConf().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("strands[%d]: %w", i, err))
+		}
+	}
+
+	if cfg.Limits.MaxPayloadBytes < 0 {
+		errs = append(errs, errors.New("limits.max_payload_bytes: must not be negative"))
+	}
+	if cfg.Limits.Clients.MaxConnections < 0 || cfg.Limits.Clients.MaxRate < 0 || cfg.Limits.Clients.MaxBytesRate < 0 {
+		errs = append(errs, errors.New("limits.clients: limits must not be negative"))
+	}
+	if cfg.Limits.MaxStrands < 0 {
+		errs = append(errs, errors.New("limits.max_strands: must not be negative"))
+	}
+	if cfg.Limits.MaxStrands > 0 && len(cfg.Strands) > cfg.Limits.MaxStrands {
+		errs = append(errs, fmt.Errorf("strands: %d presets exceed limits.max_strands (%d)", len(cfg.Strands), cfg.Limits.MaxStrands))
+	}
+
+	sinks := make(map[string]bool)
+	for i, sink := range cfg.Sinks.Kafka {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.kafka[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.kafka[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+	for i, sink := range cfg.Sinks.S3 {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.s3[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.s3[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+	for i, sink := range cfg.Sinks.File {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.file[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.file[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+	for i, sink := range cfg.Sinks.Redis {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.redis[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.redis[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+	for i, sink := range cfg.Sinks.SQS {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.sqs[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.sqs[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+	for i, sink := range cfg.Sinks.PubSub {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.pubsub[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.pubsub[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+	for i, sink := range cfg.Sinks.ServiceBus {
+		if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sinks.servicebus[%d]: %w", i, err))
+		}
+		if sinks[sink.Name] {
+			errs = append(errs, fmt.Errorf("sinks.servicebus[%d].name: duplicate sink %q", i, sink.Name))
+		}
+		sinks[sink.Name] = true
+	}
+
+	sources := make(map[string]bool)
+	for i, source := range cfg.Sources.JetStream {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources.jetstream[%d]: %w", i, err))
+		}
+		if sources[source.Name] {
+			errs = append(errs, fmt.Errorf("sources.jetstream[%d].name: duplicate source %q", i, source.Name))
+		}
+		sources[source.Name] = true
+	}
+	for i, source := range cfg.Sources.Postgres {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources.postgres[%d]: %w", i, err))
+		}
+		if sources[source.Name] {
+			errs = append(errs, fmt.Errorf("sources.postgres[%d].name: duplicate source %q", i, source.Name))
+		}
+		sources[source.Name] = true
+	}
+	for i, source := range cfg.Sources.Redis {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources.redis[%d]: %w", i, err))
+		}
+		if sources[source.Name] {
+			errs = append(errs, fmt.Errorf("sources.redis[%d].name: duplicate source %q", i, source.Name))
+		}
+		sources[source.Name] = true
+	}
+	for i, source := range cfg.Sources.SQS {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources.sqs[%d]: %w", i, err))
+		}
+		if sources[source.Name] {
+			errs = append(errs, fmt.Errorf("sources.sqs[%d].name: duplicate source %q", i, source.Name))
+		}
+		sources[source.Name] = true
+	}
+	for i, source := range cfg.Sources.PubSub {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources.pubsub[%d]: %w", i, err))
+		}
+		if sources[source.Name] {
+			errs = append(errs, fmt.Errorf("sources.pubsub[%d].name: duplicate source %q", i, source.Name))
+		}
+		sources[source.Name] = true
+	}
+	for i, source := range cfg.Sources.ServiceBus {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("sources.servicebus[%d]: %w", i, err))
+		}
+		if sources[source.Name] {
+			errs = append(errs, fmt.Errorf("sources.servicebus[%d].name: duplicate source %q", i, source.Name))
+		}
+		sources[source.Name] = true
+	}
+
+	schedules := make(map[string]bool)
+	for i, schedule := range cfg.Scheduler.Schedules {
+		if err := schedule.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("scheduler.schedules[%d]: %w", i, err))
+		}
+		if schedules[schedule.Name] {
+			errs = append(errs, fmt.Errorf("scheduler.schedules[%d].name: duplicate schedule %q", i, schedule.Name))
+		}
+		schedules[schedule.Name] = true
+	}
+
+	for name, quota := range cfg.Namespaces {
+		if err := ValidNamespace(name); err != nil {
+			errs = append(errs, fmt.Errorf("namespaces: %w", err))
+		}
+		if quota.MaxStrands < 0 || quota.MaxBytes < 0 || quota.MaxRate < 0 {
+			errs = append(errs, fmt.Errorf("namespaces.%s: quotas must not be negative", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stores opens the configured volatile and durable stores, encoding messages with the configured codec.
+func (cfg Config) Stores(options ...MakeOption) (volatile Store, durable Store, err error) {
+	options = append(options[:len(options):len(options)], MakeCodec(cfg.Wire.Codec))
+	volatile = store.RamStoreMake(options...)
+	if cfg.Store.Durable == "ram" {
+		return volatile, store.RamStoreMake(options...), nil
+	}
+	badger, err := store.BadgerStoreMake(cfg.Store.Path, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return volatile, badger, nil
+}
+
+// Auditor creates the configured auditors, or nil if auditing is disabled.
+func (cfg Config) Auditor(c *Conduktor) (Auditor, error) {
+	var auditors Auditors
+	if cfg.Audit.File != "" {
+		file, err := AuditFileMake(cfg.Audit.File)
+		if err != nil {
+			return nil, err
+		}
+		auditors = append(auditors, file)
+	}
+	if cfg.Audit.Strand {
+		strand, err := AuditStrandMake(c)
+		if err != nil {
+			return nil, err
+		}
+		auditors = append(auditors, strand)
+	}
+
+	if len(auditors) == 0 {
+		return nil, nil
+	}
+	return auditors, nil
+}
+
+// StrandsCreate creates the configured preset strands that do not exist yet, auditing each, and
+// sets the schemas and metrics of all of them.
+func (cfg Config) StrandsCreate(c *Conduktor) error {
+	for _, preset := range cfg.Strands {
+		if !c.hasStrand(preset.ID) {
+			err := c.StrandAdd(preset.ID, preset.StrandConf())
+			c.Audit(ActorSystem, AuditStrandCreate, preset.ID, auditConf(preset.StrandConf()), err)
+			if err != nil {
+				return fmt.Errorf("strand %s: %w", preset.ID, err)
+			}
+		}
+		if preset.QuietMetrics {
+			if err := c.SetStrandMetrics(preset.ID, false); err != nil {
+				return fmt.Errorf("strand %s: %w", preset.ID, err)
+			}
+		}
+
+		if preset.Schema == "" {
+			continue
+		}
+		document, err := os.ReadFile(preset.Schema)
+		if err != nil {
+			return fmt.Errorf("strand %s: %w", preset.ID, err)
+		}
+		schema, err := JSONSchemaMake(document)
+		if err != nil {
+			return fmt.Errorf("strand %s: %s: %w", preset.ID, preset.Schema, err)
+		}
+		if err := c.SetSchema(preset.ID, schema); err != nil {
+			return fmt.Errorf("strand %s: %w", preset.ID, err)
+		}
+	}
+	return nil
+}
+
+// SinksServe starts the configured sinks. stop stops them all, waiting for their writes to end.
+func (cfg Config) SinksServe(ctx context.Context, c *Conduktor) (stop func(), err error) {
+	var stops []func()
+	stop = func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	for _, conf := range cfg.Sinks.Kafka {
+		stops = append(stops, c.SinkServe(KafkaSinkMake(conf), conf.SinkConf))
+	}
+	for _, conf := range cfg.Sinks.S3 {
+		sink, err := S3SinkOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("sink %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SinkServe(sink, conf.sinkConf()))
+	}
+	for _, conf := range cfg.Sinks.File {
+		sink, err := FileSinkMake(conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("sink %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SinkServe(sink, conf.SinkConf))
+	}
+	for _, conf := range cfg.Sinks.Redis {
+		sink, err := RedisSinkOpen(conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("sink %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SinkServe(sink, conf.SinkConf))
+	}
+	for _, conf := range cfg.Sinks.SQS {
+		sink, err := SQSSinkOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("sink %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SinkServe(sink, conf.SinkConf))
+	}
+	for _, conf := range cfg.Sinks.PubSub {
+		sink, err := PubSubSinkOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("sink %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SinkServe(sink, conf.SinkConf))
+	}
+	for _, conf := range cfg.Sinks.ServiceBus {
+		sink, err := ServiceBusSinkOpen(conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("sink %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SinkServe(sink, conf.SinkConf))
+	}
+	return stop, nil
+}
+
+// SourcesServe starts the configured sources. stop stops them all, waiting for their sends to end.
+func (cfg Config) SourcesServe(ctx context.Context, c *Conduktor) (stop func(), err error) {
+	var stops []func()
+	stop = func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	for _, conf := range cfg.Sources.JetStream {
+		source, err := JetStreamSourceOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SourceServe(source, conf.SourceConf))
+	}
+	for _, conf := range cfg.Sources.Postgres {
+		source, err := PostgresSourceOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SourceServe(source, conf.SourceConf))
+	}
+	for _, conf := range cfg.Sources.Redis {
+		source, err := RedisSourceOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SourceServe(source, conf.SourceConf))
+	}
+	for _, conf := range cfg.Sources.SQS {
+		if info, err := c.Strand(conf.Strand); err == nil && sqsFIFO(conf.Queue) && !info.Config.Ordered {
+			stop()
+			return nil, fmt.Errorf("source %s: FIFO queue needs an ordered strand, and %s is not", conf.Name, conf.Strand)
+		}
+		source, err := SQSSourceOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SourceServe(source, conf.SourceConf))
+	}
+	for _, conf := range cfg.Sources.PubSub {
+		source, err := PubSubSourceOpen(ctx, conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		if info, err := c.Strand(conf.Strand); err == nil && source.Ordered() && !info.Config.Ordered {
+			source.Close()
+			stop()
+			return nil, fmt.Errorf("source %s: ordered subscription needs an ordered strand, and %s is not", conf.Name, conf.Strand)
+		}
+		stops = append(stops, c.SourceServe(source, conf.SourceConf))
+	}
+	for _, conf := range cfg.Sources.ServiceBus {
+		if info, err := c.Strand(conf.Strand); err == nil && conf.Sessions && !info.Config.Ordered {
+			stop()
+			return nil, fmt.Errorf("source %s: sessions need an ordered strand, and %s is not", conf.Name, conf.Strand)
+		}
+		source, err := ServiceBusSourceOpen(conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SourceServe(source, conf.SourceConf))
+		if !conf.DeadLetters {
+			continue
+		}
+		if err := c.DeadLetterStrandAdd(conf.Strand); err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		dlq, err := ServiceBusDeadLetterSourceOpen(conf)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("source %s: %w", conf.Name, err)
+		}
+		stops = append(stops, c.SourceServe(dlq, conf.deadLetterConf()))
+	}
+	return stop, nil
+}
+
+// WireMake creates the configured wire, encoding messages with the configured codec.
+func (cfg Config) WireMake(options ...MakeOption) (Wire, error) {
+	if err := wire.MsgCodecCheck(cfg.Wire.Codec); err != nil {
+		return nil, err
+	}
+	options = append(options[:len(options):len(options)], MakeCodec(cfg.Wire.Codec))
+	switch cfg.Wire.Type {
+	case "udp":
+		udp, err := wire.UDPWireMake(cfg.Wire.Addr, options...)
+		if err != nil {
+			return nil, err
+		}
+		if err := udp.SetDatagramSize(cfg.Wire.DatagramSize); err != nil {
+			udp.Close()
+			return nil, err
+		}
+		filter, err := cfg.Listen.IPFilter("wire")
+		if err != nil {
+			udp.Close()
+			return nil, err
+		}
+		if filter != nil {
+			udp.SetSourceFilter(filter.Allowed)
+		}
+		return udp, nil
+	case "gochan":
+		gochan := wire.GoChanWireMake(options...)
+		gochan.SetDefaultRing(cfg.Wire.Buffer)
+		return gochan, nil
+	default:
+		ws := wire.WSWireMake(options...)
+		ws.SetCoalescing(cfg.Wire.Coalesce)
+		return ws, nil
+	}
+}
+
+// StrandConf converts a preset to a strand config, starting from the named preset, if any, and
+// applying the settings that are set.
+func (preset StrandPreset) StrandConf() StrandConf {
+	conf := store.Presets[preset.Preset]
+	conf.Durable = conf.Durable || preset.Durable
+	conf.Ordered = conf.Ordered || preset.Ordered
+	if preset.ReplicationFactor != 0 {
+		conf.ReplicationFactor = preset.ReplicationFactor
+	}
+	if preset.MaxBytes != 0 {
+		conf.MaxBytes = preset.MaxBytes
+	}
+	if preset.Overflow != "" {
+		conf.Overflow = preset.Overflow
+	}
+	return conf
+}
@@ -0,0 +1,251 @@
+package condukt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+// Test Config File With Env Overrides
+func TestConfigLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "condukt.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+store:
+  durable: ram
+wire:
+  type: gochan
+listen:
+  grpc: ""
+strands:
+  - id: preset_channel
+    durable: true
+limits:
+  max_payload_bytes: 4
+log:
+  level: warn
+`), 0o644))
+	t.Setenv("CONDUKT_LISTEN_ADMIN", ":19091")
+	t.Setenv("CONDUKT_LIMITS_MAX_STRANDS", "2")
+
+	cfg, err := ConfigLoad(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ram", cfg.Store.Durable)
+	assert.Equal(t, ":19091", cfg.Listen.Admin)
+	assert.Empty(t, cfg.Listen.GRPC)
+	assert.Equal(t, 2, cfg.Limits.MaxStrands)
+	assert.Equal(t, zapcore.WarnLevel, cfg.Log.Level)
+
+	vStore, dStore, err := cfg.Stores()
+	assert.NoError(t, err)
+	wire, err := cfg.WireMake()
+	assert.NoError(t, err)
+	mq := ConduktorMake(vStore, dStore, wire)
+	mq.SetLimits(cfg.Limits)
+	for _, preset := range cfg.Strands {
+		assert.NoError(t, mq.StrandAdd(preset.ID, preset.StrandConf()))
+	}
+
+	assert.NoError(t, mq.Send("preset_channel", "ok"))
+	assert.ErrorIs(t, mq.Send("preset_channel", "too long"), ErrPayloadTooLarge)
+	assert.NoError(t, mq.StrandAdd("second_channel", StrandConf{}))
+	assert.ErrorIs(t, mq.StrandAdd("third_channel", StrandConf{}), ErrTooManyStrands)
+}
+
+// Test Config Validation Errors
+func TestConfigValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "condukt.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+store:
+  durable: sqlite
+  group_commit:
+    max_batch: -1
+  snapshot:
+    interval: 5s
+wire:
+  type: udp
+  codec: xml
+  datagram_size: 70000
+  coalesce:
+    interval: -1s
+  buffer:
+    overflow: spill
+strands:
+  - id: dup
+  - id: dup
+  - id: firehose
+    preset: firehose
+namespaces:
+  a/b:
+    max_rate: -1
+backup:
+  interval: 1h
+  s3: https://bucket
+recovery:
+  workers: -1
+  arena_chunk: -1
+dispatch:
+  prefetch: -1
+retry:
+  strategy: forever
+admission:
+  action: drop
+listen:
+  tls:
+    client_ca: ca.pem
+    reload: -1s
+  filters:
+    ftp: {}
+    admin: {allow: [10.0.0.0/33]}
+acl:
+  - {identity: ops, strands: "*", role: owner}
+encryption:
+  keys:
+    - {strands: "payments/*", id: "1", key: c2hvcnQ=}
+signing:
+  keys:
+    - {strands: "bridged/*", key_env: CONDUKT_SIGNING_KEY}
+    - {strands: "bridged/*", id: "2", secret: "ssm:/condukt/signing"}
+auth:
+  jwt:
+    secret: short
+  cert_identity: serial
+  lockout:
+    threshold: -1
+sinks:
+  kafka:
+    - name: analytics
+      strands: [orders]
+  s3:
+    - name: analytics
+      strands: [orders]
+      url: https://bucket
+      compression: zstd
+  file:
+    - name: trail
+      strands: [orders]
+      keep: -1
+  redis:
+    - name: live
+      strands: [orders]
+  sqs:
+    - name: jobs-out
+      strands: [jobs]
+  pubsub:
+    - name: events-out
+      strands: [events]
+      project: my-project
+  servicebus:
+    - name: bus-out
+      strands: [jobs]
+      connection_string: Endpoint=sb://shop.servicebus.windows.net/
+      queue: jobs
+      topic: jobs
+sources:
+  jetstream:
+    - name: edge
+      strand: orders
+      url: nats://localhost:4222
+  postgres:
+    - name: edge
+      strand: orders
+      url: postgres://localhost/shop
+  redis:
+    - name: live
+      strand: orders
+      url: redis://localhost:6379
+  sqs:
+    - name: jobs-in
+      strand: jobs
+      queue: https://sqs.us-east-1.amazonaws.com/123456789012/jobs
+      batch: 11
+  pubsub:
+    - name: events-in
+      strand: events
+      subscription: condukt-events
+  servicebus:
+    - name: bus-in
+      strand: jobs
+      connection_string: Endpoint=sb://shop.servicebus.windows.net/
+      topic: jobs
+scheduler:
+  schedules:
+    - name: hourly
+      cron: every hour
+      strand: jobs.hourly
+tracing:
+  exporter: jaeger
+`), 0o644))
+
+	_, err := ConfigLoad(path)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "store.durable")
+		assert.Contains(t, err.Error(), "store.group_commit")
+		assert.Contains(t, err.Error(), "store.snapshot.path")
+		assert.Contains(t, err.Error(), "wire.addr")
+		assert.Contains(t, err.Error(), "wire.codec")
+		assert.Contains(t, err.Error(), "recovery.workers")
+		assert.Contains(t, err.Error(), "recovery.arena_chunk")
+		assert.Contains(t, err.Error(), "dispatch: prefetch")
+		assert.Contains(t, err.Error(), "retry.strategy")
+		assert.Contains(t, err.Error(), "admission.action")
+		assert.Contains(t, err.Error(), "auth.jwt: secret")
+		assert.Contains(t, err.Error(), "acl[0].role")
+		assert.Contains(t, err.Error(), "encryption.keys[0]: key")
+		assert.Contains(t, err.Error(), "signing.keys[0]: id")
+		assert.Contains(t, err.Error(), `signing.keys[1].secret: "ssm:/condukt/signing": unknown scheme`)
+		assert.Contains(t, err.Error(), "listen.tls: client_ca")
+		assert.Contains(t, err.Error(), "reload: must not be negative")
+		assert.Contains(t, err.Error(), `listen.filters: unknown listener "ftp"`)
+		assert.Contains(t, err.Error(), "listen.filters.admin.allow[0]")
+		assert.Contains(t, err.Error(), "auth.cert_identity")
+		assert.Contains(t, err.Error(), "auth.lockout: threshold")
+		assert.Contains(t, err.Error(), "sinks.kafka[0]: brokers")
+		assert.Contains(t, err.Error(), "sinks.s3[0].name: duplicate")
+		assert.Contains(t, err.Error(), "sinks.s3[0]: url")
+		assert.Contains(t, err.Error(), "compression")
+		assert.Contains(t, err.Error(), "sinks.file[0]: dir")
+		assert.Contains(t, err.Error(), "keep must not be negative")
+		assert.Contains(t, err.Error(), "sources.jetstream[0]: stream")
+		assert.Contains(t, err.Error(), "sources.postgres[0].name: duplicate")
+		assert.Contains(t, err.Error(), "sinks.redis[0]: url")
+		assert.Contains(t, err.Error(), "sources.redis[0]: channels or patterns")
+		assert.Contains(t, err.Error(), "sinks.sqs[0]: queue")
+		assert.Contains(t, err.Error(), "sources.sqs[0]: batch")
+		assert.Contains(t, err.Error(), "sinks.pubsub[0]: topic")
+		assert.Contains(t, err.Error(), "sources.pubsub[0]: project")
+		assert.Contains(t, err.Error(), "sinks.servicebus[0]: queue or topic")
+		assert.Contains(t, err.Error(), "sources.servicebus[0]: subscription")
+		assert.Contains(t, err.Error(), "scheduler.schedules[0]: cron")
+		assert.Contains(t, err.Error(), "wire.datagram_size")
+		assert.Contains(t, err.Error(), "wire.coalesce")
+		assert.Contains(t, err.Error(), "wire.buffer.overflow")
+		assert.Contains(t, err.Error(), "duplicate strand")
+		assert.Contains(t, err.Error(), "unknown preset")
+		assert.Contains(t, err.Error(), "invalid namespace")
+		assert.Contains(t, err.Error(), "backup: requires the badger durable store")
+		assert.Contains(t, err.Error(), "backup.s3")
+		assert.Contains(t, err.Error(), "must not be negative")
+		assert.Contains(t, err.Error(), "tracing.exporter")
+	}
+
+	t.Setenv("CONDUKT_METRICS_TLS_CERT", filepath.Join(t.TempDir(), "missing.crt"))
+	_, err = ConfigLoad("")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "tls_cert and tls_key must be set together")
+		assert.Contains(t, err.Error(), "metrics.tls_cert")
+	}
+	t.Setenv("CONDUKT_METRICS_TLS_CERT", "")
+
+	assert.NoError(t, os.WriteFile(path, []byte("stores: {}\n"), 0o644))
+	_, err = ConfigLoad(path)
+	assert.Error(t, err, "unknown keys are rejected")
+
+	t.Setenv("CONDUKT_LIMITS_MAX_STRANDS", "many")
+	_, err = ConfigLoad("")
+	assert.ErrorContains(t, err, "CONDUKT_LIMITS_MAX_STRANDS")
+}
@@ -0,0 +1,158 @@
+package condukt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DaemonConfig configures running condukt as a service.
+type DaemonConfig struct {
+	Pidfile         string        `yaml:"pidfile"`          // Written at startup and removed at exit; empty disables it
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // How long shutdown may drain before it is forced
+}
+
+// Daemon runs condukt as a first-class systemd service: it writes a pidfile, reports readiness and
+// watchdog pings over sd_notify, and waits for a termination signal.
+type Daemon struct {
+	conf    DaemonConfig
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+// DaemonMake creates a Daemon. Termination signals are captured from this point on.
+func DaemonMake(conf DaemonConfig) *Daemon {
+	d := &Daemon{conf: conf, signals: make(chan os.Signal, 1), done: make(chan struct{})}
+	signal.Notify(d.signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	return d
+}
+
+// Start writes the pidfile and, when systemd enabled the watchdog, starts pinging it.
+func (d *Daemon) Start() error {
+	if d.conf.Pidfile != "" {
+		if err := PidfileWrite(d.conf.Pidfile); err != nil {
+			return err
+		}
+	}
+
+	if interval := sdWatchdogInterval(); interval > 0 {
+		go d.watchdog(interval / 2)
+	}
+	return nil
+}
+
+// Ready tells systemd that startup has finished.
+func (d *Daemon) Ready() {
+	if _, err := SdNotify(fmt.Sprintf("READY=1\nMAINPID=%d", os.Getpid())); err != nil {
+		logger.Warn("sd_notify READY failed", zap.Error(err))
+	}
+}
+
+// Wait blocks until SIGTERM, SIGINT, or SIGQUIT arrives and returns it.
+func (d *Daemon) Wait() os.Signal {
+	return <-d.signals
+}
+
+// Stopping tells systemd the service is shutting down.
+func (d *Daemon) Stopping() {
+	if _, err := SdNotify("STOPPING=1"); err != nil {
+		logger.Warn("sd_notify STOPPING failed", zap.Error(err))
+	}
+}
+
+// Stop stops the watchdog, releases the signals, and removes the pidfile.
+func (d *Daemon) Stop() {
+	signal.Stop(d.signals)
+	close(d.done)
+
+	if d.conf.Pidfile != "" {
+		if err := os.Remove(d.conf.Pidfile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to remove pidfile", zap.String("pidfile", d.conf.Pidfile), zap.Error(err))
+		}
+	}
+}
+
+// watchdog pings the systemd watchdog until Stop.
+func (d *Daemon) watchdog(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			if _, err := SdNotify("WATCHDOG=1"); err != nil {
+				logger.Warn("sd_notify WATCHDOG failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// SdNotify sends state to the systemd notification socket named by $NOTIFY_SOCKET.
+// It reports false without error when not running under systemd.
+func SdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // Abstract namespace
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sdWatchdogInterval returns the watchdog timeout systemd requested for this process, or 0 if none.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// PidfileWrite records the current pid at path. It refuses to overwrite the pidfile of a running process.
+func PidfileWrite(path string) error {
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() && pidAlive(pid) {
+			return fmt.Errorf("pidfile %s: process %d is still running", path, pid)
+		}
+		logger.Warn("Replacing stale pidfile", zap.String("pidfile", path))
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// pidAlive reports whether a process with pid exists.
+func pidAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,68 @@
+package condukt
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test sd_notify, Pidfile, And Signal Handling
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	pidfile := filepath.Join(dir, "condukt.pid")
+	daemon := DaemonMake(DaemonConfig{Pidfile: pidfile})
+	assert.NoError(t, daemon.Start())
+	data, err := os.ReadFile(pidfile)
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+
+	daemon.Ready()
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(buf[:n]), "READY=1")
+	}
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+	assert.Equal(t, syscall.SIGTERM, daemon.Wait())
+
+	daemon.Stopping()
+	n, err = conn.Read(buf)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "STOPPING=1", string(buf[:n]))
+	}
+	daemon.Stop()
+	_, err = os.Stat(pidfile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Test Pidfile Of A Running Process Is Not Replaced
+func TestPidfileRunning(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "condukt.pid")
+
+	// Parent process is alive
+	assert.NoError(t, os.WriteFile(pidfile, []byte(strconv.Itoa(os.Getppid())), 0o644))
+	assert.Error(t, PidfileWrite(pidfile))
+
+	// Stale pidfile is replaced
+	assert.NoError(t, os.WriteFile(pidfile, []byte("999999999"), 0o644))
+	assert.NoError(t, PidfileWrite(pidfile))
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := SdNotify("READY=1")
+	assert.False(t, sent)
+	assert.NoError(t, err)
+}
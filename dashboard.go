@@ -0,0 +1,153 @@
+package condukt
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// WireState describes a wire the Conduktor is using.
+type WireState struct {
+	Role        string `json:"role"` // primary, mirror, federation, or geo
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Connections int    `json:"connections"` // Open client connections, for wires that track them
+}
+
+// StrandMetrics are the cumulative message counters of a strand, read from the Prometheus registry.
+type StrandMetrics struct {
+	Sent         float64 `json:"sent"`
+	Received     float64 `json:"received"`
+	Acked        float64 `json:"acked"`
+	DeadLettered float64 `json:"dead_lettered"`
+	InFlight     float64 `json:"in_flight"` // Received but not yet acked
+}
+
+// DashboardData is one refresh of the dashboard.
+type DashboardData struct {
+	Time    int64                    `json:"time"`
+	Strands []StrandInfo             `json:"strands"`
+	Metrics map[string]StrandMetrics `json:"metrics"`
+	Wires   []WireState              `json:"wires"`
+}
+
+// Wires lists the primary wire and the wires of mirrors, federation links, and geo shippers.
+func (c *Conduktor) Wires() []WireState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wires := []WireState{wireState("primary", c.id, c.wire)}
+	for strandID, m := range c.mirrors {
+		wires = append(wires, wireState("mirror", strandID, m.conf.Wire))
+	}
+	for name, f := range c.links {
+		wires = append(wires, wireState("federation", name, f.link.Wire))
+	}
+	for remote, g := range c.geo {
+		wires = append(wires, wireState("geo", remote, g.conf.Wire))
+	}
+
+	sort.SliceStable(wires[1:], func(i, j int) bool {
+		a, b := wires[i+1], wires[j+1]
+		return a.Role < b.Role || (a.Role == b.Role && a.Name < b.Name)
+	})
+	return wires
+}
+
+// wireState describes a wire, counting its connections if it tracks them.
+func wireState(role, name string, wire Wire) WireState {
+	typ := fmt.Sprintf("%T", wire)
+	state := WireState{Role: role, Name: name, Type: typ[strings.LastIndex(typ, ".")+1:]}
+	if counter, ok := wire.(interface{ Connections() int }); ok {
+		state.Connections = counter.Connections()
+	}
+	return state
+}
+
+// strandMetrics reads per-strand message counters from condukt's Prometheus registry, whatever prefix it is exported with.
+func strandMetrics() (map[string]StrandMetrics, error) {
+	families, err := metricsRegistry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]StrandMetrics)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			strandID := ""
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "channel" || label.GetName() == "strand" {
+					strandID = label.GetValue()
+				}
+			}
+			if strandID == "" || m.GetCounter() == nil {
+				continue
+			}
+
+			sm := metrics[strandID]
+			switch family.GetName() {
+			case "messages_sent_total":
+				sm.Sent = m.GetCounter().GetValue()
+			case "messages_received_total":
+				sm.Received = m.GetCounter().GetValue()
+			case "messages_acked_total":
+				sm.Acked = m.GetCounter().GetValue()
+			case "messages_dead_lettered_total":
+				sm.DeadLettered = m.GetCounter().GetValue()
+			default:
+				continue
+			}
+			metrics[strandID] = sm
+		}
+	}
+
+	for strandID, sm := range metrics {
+		sm.InFlight = max(sm.Received-sm.Acked-sm.DeadLettered, 0)
+		metrics[strandID] = sm
+	}
+	return metrics, nil
+}
+
+// DashboardHandler serves the embedded web dashboard and the data it polls:
+//
+//	GET /admin/dashboard        Single-page dashboard
+//	GET /admin/dashboard/data   Strands, per-strand counters, and wires as JSON
+func DashboardHandler(c *Conduktor) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := w.Write(dashboardHTML); err != nil {
+			c.log.Warn("Failed to write dashboard", zap.Error(err))
+		}
+	})
+
+	mux.HandleFunc("GET /admin/dashboard/data", func(w http.ResponseWriter, r *http.Request) {
+		strands, err := c.Strands()
+		if err != nil {
+			adminReply(w, nil, err)
+			return
+		}
+		metrics, err := strandMetrics()
+		if err != nil {
+			adminReply(w, nil, err)
+			return
+		}
+		adminWrite(w, http.StatusOK, DashboardData{
+			Time:    time.Now().UnixMilli(),
+			Strands: strands,
+			Metrics: metrics,
+			Wires:   c.Wires(),
+		})
+	})
+
+	return mux
+}
@@ -0,0 +1,201 @@
+package condukt
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jkassis/condukt/datapb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dataMaxInFlight is how many messages a Subscribe stream may hold unacked when it asks for no limit.
+const dataMaxInFlight = 16
+
+// GRPCAuthenticator identifies data-plane gRPC callers, as WireAuthorizer does wire clients.
+type GRPCAuthenticator interface {
+	AuthenticateGRPC(ctx context.Context) (identity string, authCtx context.Context, err error) // authCtx carries the caller's token claims
+}
+
+// dataGRPC implements the gRPC Data service defined in proto/data.proto.
+type dataGRPC struct {
+	datapb.UnimplementedDataServer
+	c             *Conduktor
+	authenticator GRPCAuthenticator
+
+	mu       sync.Mutex
+	groups   map[string]*dataGroup   // Strand -> its consumer group, while it has subscribers
+	inFlight map[dataKey]*dataStream // Delivered, unacked messages -> the stream they went to
+}
+
+// dataGroup is the consumer group of a strand.
+type dataGroup struct {
+	name    string
+	streams int
+}
+
+// dataKey identifies a message of a strand.
+type dataKey struct{ strand, msgID string }
+
+// dataStream is a Subscribe stream. credit holds a token for each message delivered and unacked,
+// so a full channel holds back deliveries until acks take tokens out.
+type dataStream struct {
+	credit chan struct{}
+	keys   map[dataKey]bool // Guarded by dataGRPC.mu
+}
+
+// DataGRPCServerMake creates a gRPC server exposing the data-plane API, for consumers to stream
+// strands' messages with flow control. Callers are identified by authenticator, subject to the
+// ACL; a nil authenticator makes every caller anonymous.
+func DataGRPCServerMake(c *Conduktor, authenticator GRPCAuthenticator, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	datapb.RegisterDataServer(server, &dataGRPC{
+		c:             c,
+		authenticator: authenticator,
+		groups:        make(map[string]*dataGroup),
+		inFlight:      make(map[dataKey]*dataStream),
+	})
+	return server
+}
+
+// identity authenticates the caller of ctx, returning ctx carrying its token claims for Authorize.
+func (s *dataGRPC) identity(ctx context.Context) (string, context.Context, error) {
+	if s.authenticator == nil {
+		return "", ctx, nil
+	}
+	identity, authCtx, err := s.authenticator.AuthenticateGRPC(ctx)
+	if err != nil {
+		return "", nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return identity, authCtx, nil
+}
+
+// Subscribe streams a strand's messages, holding back deliveries while max_in_flight are unacked.
+func (s *dataGRPC) Subscribe(req *datapb.SubscribeRequest, stream datapb.Data_SubscribeServer) error {
+	identity, ctx, err := s.identity(stream.Context())
+	if err != nil {
+		return err
+	}
+	strandID, group := req.GetStrand(), req.GetGroup()
+	if strandID == "" {
+		return status.Error(codes.InvalidArgument, "strand is required")
+	}
+	if req.GetMaxInFlight() < 0 {
+		return status.Error(codes.InvalidArgument, "max_in_flight must not be negative")
+	}
+	maxInFlight := int(req.GetMaxInFlight())
+	if maxInFlight == 0 {
+		maxInFlight = dataMaxInFlight
+	}
+
+	if err := s.join(strandID, group); err != nil {
+		return err
+	}
+	defer s.leave(strandID)
+	sub, err := s.c.Subscribe(strandID, ReceiveAs(identity), ReceiveContext(ctx))
+	if err != nil {
+		return adminStatus(err)
+	}
+	defer sub.Close()
+
+	ds := &dataStream{credit: make(chan struct{}, maxInFlight), keys: make(map[dataKey]bool)}
+	defer s.forget(ds)
+	s.c.log.Info("gRPC subscriber connected", zap.String("strand", strandID), zap.String("group", group), zap.String("identity", identity))
+	defer s.c.log.Info("gRPC subscriber disconnected", zap.String("strand", strandID), zap.String("group", group), zap.String("identity", identity))
+	for {
+		select {
+		case ds.credit <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		msg, err := sub.Next(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil { // Refused by the Deliver middleware
+			<-ds.credit
+			continue
+		}
+
+		s.mu.Lock()
+		key := dataKey{strandID, msg.ID}
+		s.inFlight[key] = ds
+		ds.keys[key] = true
+		s.mu.Unlock()
+		if err := stream.Send(&datapb.Message{
+			Id:        msg.ID,
+			Strand:    strandID,
+			Payload:   msg.Payload,
+			Timestamp: msg.Timestamp,
+			Headers:   msg.Headers,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// join adds a subscriber of group to strandID, unless the strand's group is another.
+func (s *dataGRPC) join(strandID, group string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, exists := s.groups[strandID]
+	if !exists {
+		g = &dataGroup{name: group}
+		s.groups[strandID] = g
+	}
+	if g.name != group {
+		return status.Errorf(codes.FailedPrecondition, "strand %s is consumed by group %q; a strand has one group at a time", strandID, g.name)
+	}
+	g.streams++
+	return nil
+}
+
+// leave removes a subscriber from strandID's group, dropping the group with its last subscriber.
+func (s *dataGRPC) leave(strandID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g := s.groups[strandID]; g != nil {
+		if g.streams--; g.streams == 0 {
+			delete(s.groups, strandID)
+		}
+	}
+}
+
+// forget stops tracking the messages ds delivered. Those still unacked stay stored, and may be
+// acked later, through another stream.
+func (s *dataGRPC) forget(ds *dataStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range ds.keys {
+		delete(s.inFlight, key)
+	}
+}
+
+// Ack acknowledges messages, letting the streams they were delivered on deliver more.
+func (s *dataGRPC) Ack(ctx context.Context, req *datapb.AckRequest) (*datapb.AckResponse, error) {
+	identity, ctx, err := s.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	strandID := req.GetStrand()
+	if err := s.c.Authorize(ctx, identity, ACLSubscribe, strandID); err != nil {
+		return nil, adminStatus(err)
+	}
+	if err := s.c.AcknowledgeBatch(strandID, req.GetMsgIds()); err != nil {
+		return nil, adminStatus(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, msgID := range req.GetMsgIds() {
+		key := dataKey{strandID, msgID}
+		if ds, exists := s.inFlight[key]; exists {
+			delete(s.inFlight, key)
+			delete(ds.keys, key)
+			<-ds.credit
+		}
+	}
+	return &datapb.AckResponse{}, nil
+}
@@ -0,0 +1,95 @@
+package condukt
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/datapb"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Test gRPC Subscribers Get At Most max_in_flight Unacked Messages, And One Group Per Strand
+func TestDataGRPC(t *testing.T) {
+	volatile := store.RamStoreMake()
+	mq := ConduktorMake(volatile, store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("data_channel", StrandConf{}))
+	for i := range 3 {
+		assert.NoError(t, mq.Send("data_channel", strconv.Itoa(i)))
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := DataGRPCServerMake(mq, nil)
+	go server.Serve(lis)
+	defer server.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	client := datapb.NewDataClient(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx, &datapb.SubscribeRequest{Strand: "data_channel", Group: "workers", MaxInFlight: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	received := make(chan *datapb.Message, 3)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			received <- msg
+		}
+	}()
+	var first *datapb.Message
+	for i := range 2 {
+		select {
+		case msg := <-received:
+			if i == 0 {
+				first = msg
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Message not delivered")
+		}
+	}
+
+	// The third waits for an ack
+	select {
+	case <-received:
+		t.Fatal("Delivered past max_in_flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = client.Ack(ctx, &datapb.AckRequest{Strand: "data_channel", MsgIds: []string{first.GetId()}})
+	assert.NoError(t, err)
+	select {
+	case msg := <-received:
+		assert.Equal(t, "data_channel", msg.GetStrand())
+	case <-time.After(time.Second):
+		t.Fatal("Ack did not let the next message through")
+	}
+	depth, err := volatile.Depth(ctx, "data_channel")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, depth)
+
+	// Another group may not join the strand
+	other, err := client.Subscribe(ctx, &datapb.SubscribeRequest{Strand: "data_channel", Group: "auditors"})
+	if assert.NoError(t, err) {
+		_, err = other.Recv()
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	}
+}
@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: data.proto
+
+// Data-plane API for consuming strands, separate from the admin API.
+
+package datapb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Data_Subscribe_FullMethodName = "/condukt.data.v1.Data/Subscribe"
+	Data_Ack_FullMethodName       = "/condukt.data.v1.Data/Ack"
+)
+
+// DataClient is the client API for Data service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DataClient interface {
+	// Stream a strand's messages as they arrive. At most max_in_flight are delivered and unacked at
+	// a time; acking them with Ack lets more through. Messages unacked when the stream ends stay
+	// stored.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error)
+	// Acknowledge messages delivered by Subscribe, removing them from the strand.
+	Ack(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error)
+}
+
+type dataClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataClient(cc grpc.ClientConnInterface) DataClient {
+	return &dataClient{cc}
+}
+
+func (c *dataClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Data_ServiceDesc.Streams[0], Data_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Message]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Data_SubscribeClient = grpc.ServerStreamingClient[Message]
+
+func (c *dataClient) Ack(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AckResponse)
+	err := c.cc.Invoke(ctx, Data_Ack_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataServer is the server API for Data service.
+// All implementations must embed UnimplementedDataServer
+// for forward compatibility.
+type DataServer interface {
+	// Stream a strand's messages as they arrive. At most max_in_flight are delivered and unacked at
+	// a time; acking them with Ack lets more through. Messages unacked when the stream ends stay
+	// stored.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Message]) error
+	// Acknowledge messages delivered by Subscribe, removing them from the strand.
+	Ack(context.Context, *AckRequest) (*AckResponse, error)
+	mustEmbedUnimplementedDataServer()
+}
+
+// UnimplementedDataServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDataServer struct{}
+
+func (UnimplementedDataServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[Message]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedDataServer) Ack(context.Context, *AckRequest) (*AckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ack not implemented")
+}
+func (UnimplementedDataServer) mustEmbedUnimplementedDataServer() {}
+func (UnimplementedDataServer) testEmbeddedByValue()              {}
+
+// UnsafeDataServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataServer will
+// result in compilation errors.
+type UnsafeDataServer interface {
+	mustEmbedUnimplementedDataServer()
+}
+
+func RegisterDataServer(s grpc.ServiceRegistrar, srv DataServer) {
+	// If the following call pancis, it indicates UnimplementedDataServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Data_ServiceDesc, srv)
+}
+
+func _Data_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Data_SubscribeServer = grpc.ServerStreamingServer[Message]
+
+func _Data_Ack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServer).Ack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Data_Ack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServer).Ack(ctx, req.(*AckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Data_ServiceDesc is the grpc.ServiceDesc for Data service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Data_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "condukt.data.v1.Data",
+	HandlerType: (*DataServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Ack",
+			Handler:    _Data_Ack_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Data_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "data.proto",
+}
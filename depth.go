@@ -0,0 +1,41 @@
+package condukt
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DepthReconcile periodically recounts strand depths in both stores, correcting drift in tracked depths
+// and the queue_size gauge, and refreshes the namespace and consumer lag gauges, until stop is called.
+func (c *Conduktor) DepthReconcile(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				for _, store := range []Store{c.durable, c.volatile} {
+					if err := store.Reconcile(context.Background()); err != nil {
+						c.log.Warn("Failed to reconcile strand depths", zap.Error(err))
+						c.errs.report(ErrorSourceMaintenance, "", "", err)
+					}
+				}
+				if _, err := c.Namespaces(); err != nil {
+					c.log.Warn("Failed to refresh namespace gauges", zap.Error(err))
+					c.errs.report(ErrorSourceMaintenance, "", "", err)
+				}
+				if _, err := c.Lag(); err != nil {
+					c.log.Warn("Failed to refresh consumer lag gauges", zap.Error(err))
+					c.errs.report(ErrorSourceMaintenance, "", "", err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,343 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrSubscriptionClosed is returned by Next once a Subscription is closed.
+var ErrSubscriptionClosed = errors.New("subscription closed")
+
+// DispatchConf configures the dispatchers that deliver subscribed strands' messages.
+type DispatchConf struct {
+	Prefetch int `yaml:"prefetch"` // Messages queued for each subscriber at most; 0 is 16
+	// IdleAfter parks a strand's dispatcher once it has received nothing for this long, so idle
+	// strands hold no goroutine. Sends through this Conduktor wake it at once; messages arriving on
+	// the wire from elsewhere wait until the next send or subscribe. 0 never parks a subscribed strand.
+	IdleAfter time.Duration `yaml:"idle_after"`
+}
+
+// Validate reports negative settings.
+func (conf DispatchConf) Validate() error {
+	if conf.Prefetch < 0 || conf.IdleAfter < 0 {
+		return errors.New("dispatch: prefetch and idle_after must not be negative")
+	}
+	return nil
+}
+
+// SetDispatch replaces the dispatch config. Running dispatchers keep the config they started with.
+func (c *Conduktor) SetDispatch(conf DispatchConf) {
+	c.dispatch.mu.Lock()
+	defer c.dispatch.mu.Unlock()
+	c.dispatch.conf = conf
+}
+
+// dispatchers holds the dispatchers of subscribed strands.
+type dispatchers struct {
+	mu      sync.RWMutex
+	conf    DispatchConf
+	strands map[string]*dispatcher
+}
+
+// dispatchersMake returns an empty registry.
+func dispatchersMake() *dispatchers {
+	return &dispatchers{strands: make(map[string]*dispatcher)}
+}
+
+// wake restarts strandID's dispatcher if it is parked, or cuts short its backoff.
+func (ds *dispatchers) wake(strandID string) {
+	ds.mu.RLock()
+	d, exists := ds.strands[strandID]
+	ds.mu.RUnlock()
+	if exists {
+		d.wake()
+	}
+}
+
+// dispatcher receives one strand's messages from the wire and queues each for one of its
+// subscribers, so a slow strand holds up only its own subscribers.
+type dispatcher struct {
+	c        *Conduktor
+	strandID string
+	conf     DispatchConf
+
+	mu      sync.Mutex
+	subs    []*Subscription
+	running bool               // A goroutine is receiving for the strand; unset while parked
+	cancel  context.CancelFunc // Ends the running goroutine
+	room    chan struct{}      // Signaled when a subscriber takes a message
+	woken   chan struct{}      // Signaled when a send may have put a message on the wire
+}
+
+// queued is a message received for a subscriber, with when its receive began.
+type queued struct {
+	msg   *Msg
+	start time.Time
+}
+
+// Subscription receives a strand's messages from its dispatcher. A strand's subscriptions share
+// its messages: each goes to one of them, and a subscription with nothing queued takes messages
+// queued for another. Messages still need acknowledging.
+type Subscription struct {
+	d        *dispatcher
+	identity string
+	ready    chan struct{} // Signaled when a message is queued
+	queue    []queued      // Guarded by d.mu
+	closed   bool          // Guarded by d.mu
+}
+
+// Subscribe subscribes to strandID's messages, which Next returns. Unlike Receive, subscribers
+// share one receive of the wire per strand, so many subscribers add no wire polling. Close the
+// subscription when done.
+func (c *Conduktor) Subscribe(strandID string, opts ...ReceiveOption) (*Subscription, error) {
+	o := receiveOpts{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := c.Authorize(authContext(o.ctx), o.identity, ACLSubscribe, strandID); err != nil {
+		return nil, err
+	}
+
+	ds := c.dispatch
+	ds.mu.Lock()
+	d, exists := ds.strands[strandID]
+	if !exists {
+		d = &dispatcher{c: c, strandID: strandID, conf: ds.conf, room: make(chan struct{}, 1), woken: make(chan struct{}, 1)}
+		if d.conf.Prefetch == 0 {
+			d.conf.Prefetch = 16
+		}
+		ds.strands[strandID] = d
+	}
+	sub := &Subscription{d: d, identity: o.identity, ready: make(chan struct{}, 1)}
+	d.mu.Lock()
+	d.subs = append(d.subs, sub)
+	d.mu.Unlock()
+	ds.mu.Unlock()
+
+	d.wake()
+	return sub, nil
+}
+
+// Next returns the next message for s, waiting for one until ctx is done. Like Receive, it passes
+// the message through the Deliver middleware.
+func (s *Subscription) Next(ctx context.Context) (*Msg, error) {
+	d := s.d
+	for {
+		d.mu.Lock()
+		if s.closed {
+			d.mu.Unlock()
+			return nil, ErrSubscriptionClosed
+		}
+		q, ok := s.take()
+		d.mu.Unlock()
+		if ok {
+			wakeOne(d.room)
+			return q.msg, d.c.received(WithActor(ctx, s.identity), d.strandID, q.msg, q.start)
+		}
+
+		select {
+		case <-s.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// take removes the oldest message queued for s or, if there is none, steals the newest queued for
+// the sibling with the most. Callers must hold s.d.mu.
+func (s *Subscription) take() (queued, bool) {
+	if len(s.queue) > 0 {
+		q := s.queue[0]
+		s.queue = s.queue[1:]
+		return q, true
+	}
+	var victim *Subscription
+	for _, sub := range s.d.subs {
+		if len(sub.queue) > 0 && (victim == nil || len(sub.queue) > len(victim.queue)) {
+			victim = sub
+		}
+	}
+	if victim == nil {
+		return queued{}, false
+	}
+	q := victim.queue[len(victim.queue)-1]
+	victim.queue = victim.queue[:len(victim.queue)-1]
+	return q, true
+}
+
+// Close ends s. Messages queued for it go to the strand's other subscribers or, if it was the last,
+// back onto the wire, and the strand's dispatcher stops.
+func (s *Subscription) Close() {
+	d := s.d
+	ds := d.c.dispatch
+	ds.mu.Lock()
+	d.mu.Lock()
+	if s.closed {
+		d.mu.Unlock()
+		ds.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for i, sub := range d.subs {
+		if sub == s {
+			d.subs = append(d.subs[:i], d.subs[i+1:]...)
+			break
+		}
+	}
+	leftover := s.queue
+	s.queue = nil
+	if len(d.subs) > 0 {
+		for _, q := range leftover {
+			d.assign(q)
+		}
+		leftover = nil
+	} else {
+		if d.cancel != nil {
+			d.cancel()
+		}
+		if ds.strands[d.strandID] == d {
+			delete(ds.strands, d.strandID)
+		}
+	}
+	d.mu.Unlock()
+	ds.mu.Unlock()
+
+	d.requeue(leftover)
+}
+
+// wake starts d's goroutine if it is parked, or cuts short its backoff.
+func (d *dispatcher) wake() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running {
+		wakeOne(d.woken)
+		return
+	}
+	if len(d.subs) == 0 {
+		return
+	}
+	var ctx context.Context
+	ctx, d.cancel = context.WithCancel(context.Background())
+	d.running = true
+	dispatchersRunning.Inc()
+	go d.run(ctx)
+}
+
+// run receives the strand's messages and queues them for its subscribers until ctx is done or,
+// with IdleAfter set, the strand goes idle.
+func (d *dispatcher) run(ctx context.Context) {
+	defer dispatchersRunning.Dec()
+	for {
+		if !d.waitRoom(ctx) {
+			d.park()
+			return
+		}
+
+		receiveCtx, cancel := ctx, context.CancelFunc(func() {})
+		if d.conf.IdleAfter > 0 {
+			receiveCtx, cancel = context.WithTimeout(ctx, d.conf.IdleAfter)
+		}
+		start := time.Now()
+		msg, err := d.c.wire.ReceiveMessage(receiveCtx, d.strandID)
+		cancel()
+		if err == nil {
+			d.mu.Lock()
+			if len(d.subs) == 0 { // The last subscriber closed while this was received
+				d.mu.Unlock()
+				d.requeue([]queued{{msg: msg, start: start}})
+			} else {
+				d.assign(queued{msg: msg, start: start})
+				d.mu.Unlock()
+			}
+			continue
+		}
+
+		if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+			d.park()
+			return
+		}
+		// Such as when the wire has not seen the strand yet
+		d.c.log.Debug("Dispatcher receive failed", zap.String("strand", d.strandID), zap.Error(err))
+		select {
+		case <-d.woken:
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			d.park()
+			return
+		}
+	}
+}
+
+// park marks d's goroutine as ended, unless a wake raced with the end of its receive, in which
+// case the caller's replacement is already running.
+func (d *dispatcher) park() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cancel()
+	d.running = false
+	select {
+	case <-d.woken: // Woken while deciding to park; start again so the send is not missed
+		if len(d.subs) > 0 {
+			var ctx context.Context
+			ctx, d.cancel = context.WithCancel(context.Background())
+			d.running = true
+			dispatchersRunning.Inc()
+			go d.run(ctx)
+		}
+	default:
+	}
+}
+
+// waitRoom waits until a subscriber has room in its queue, reporting false if ctx ends first.
+func (d *dispatcher) waitRoom(ctx context.Context) bool {
+	for {
+		d.mu.Lock()
+		for _, sub := range d.subs {
+			if len(sub.queue) < d.conf.Prefetch {
+				d.mu.Unlock()
+				return true
+			}
+		}
+		d.mu.Unlock()
+		select {
+		case <-d.room:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// assign queues q for the subscriber with the fewest queued. Callers must hold d.mu, and d must
+// have a subscriber.
+func (d *dispatcher) assign(q queued) {
+	target := d.subs[0]
+	for _, sub := range d.subs[1:] {
+		if len(sub.queue) < len(target.queue) {
+			target = sub
+		}
+	}
+	target.queue = append(target.queue, q)
+	wakeOne(target.ready)
+}
+
+// requeue puts messages received for subscribers that have all closed back onto the wire.
+func (d *dispatcher) requeue(leftover []queued) {
+	for _, q := range leftover {
+		if err := d.c.wire.SendMessage(MsgContext(context.Background(), *q.msg), *q.msg); err != nil {
+			d.c.log.Warn("Failed to return undelivered message to the wire; it is redelivered on recovery",
+				zap.String("strand", d.strandID), zap.String("msgID", q.msg.ID), zap.Error(err))
+		}
+	}
+}
+
+// wakeOne wakes one waiter on ch, if any.
+func wakeOne(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,88 @@
+package condukt
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// queuedLen returns how many messages are queued for sub.
+func queuedLen(sub *Subscription) int {
+	sub.d.mu.Lock()
+	defer sub.d.mu.Unlock()
+	return len(sub.queue)
+}
+
+// Test Subscribers Share A Strand, Steal Each Other's Queued Messages, And Hand Them Back On Close
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.SetDispatch(DispatchConf{Prefetch: 4})
+	assert.NoError(t, mq.StrandAdd("shared_channel", StrandConf{}))
+	a, err := mq.Subscribe("shared_channel")
+	assert.NoError(t, err)
+	b, err := mq.Subscribe("shared_channel")
+	assert.NoError(t, err)
+
+	for i := range 8 {
+		assert.NoError(t, mq.Send("shared_channel", strconv.Itoa(i)))
+	}
+	assert.Eventually(t, func() bool { return queuedLen(a) == 4 && queuedLen(b) == 4 }, time.Second, time.Millisecond)
+
+	// b takes its own, then a's, which a is too busy for
+	seen := map[string]bool{}
+	for range 6 {
+		msg, err := b.Next(ctx)
+		if assert.NoError(t, err) {
+			seen[msg.Payload] = true
+		}
+	}
+	assert.Equal(t, 2, queuedLen(a))
+
+	// Closing a hands its queue to b
+	a.Close()
+	_, err = a.Next(ctx)
+	assert.ErrorIs(t, err, ErrSubscriptionClosed)
+	for range 2 {
+		msg, err := b.Next(ctx)
+		if assert.NoError(t, err) {
+			seen[msg.Payload] = true
+		}
+	}
+	assert.Len(t, seen, 8)
+	b.Close()
+}
+
+// Test An Idle Strand's Dispatcher Parks And A Send Wakes It
+func TestSubscribeParks(t *testing.T) {
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	mq.SetDispatch(DispatchConf{IdleAfter: 20 * time.Millisecond})
+	assert.NoError(t, mq.StrandAdd("idle_channel", StrandConf{}))
+	assert.NoError(t, mq.Send("idle_channel", "first")) // So the wire knows the strand
+	sub, err := mq.Subscribe("idle_channel")
+	assert.NoError(t, err)
+	defer sub.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := sub.Next(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "first", msg.Payload)
+	}
+
+	running := func() bool {
+		sub.d.mu.Lock()
+		defer sub.d.mu.Unlock()
+		return sub.d.running
+	}
+	assert.Eventually(t, func() bool { return !running() }, time.Second, time.Millisecond)
+	assert.NoError(t, mq.Send("idle_channel", "second"))
+	msg, err = sub.Next(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "second", msg.Payload)
+	}
+}
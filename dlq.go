@@ -0,0 +1,239 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// dlqSuffix names the dead-letter strand of a strand.
+const dlqSuffix = ".dlq"
+
+// Dead-letter headers.
+const (
+	HeaderDLQReason = "x-condukt-dlq-reason" // Why the message was dead-lettered
+	HeaderDLQSource = "x-condukt-dlq-source" // Strand the message was dead-lettered from
+)
+
+// DeadLetterStrand returns the name of the dead-letter strand for strandID.
+func DeadLetterStrand(strandID string) string {
+	return strandID + dlqSuffix
+}
+
+// DeadLetter moves an unacked message to the strand's dead-letter strand, recording why.
+// The dead-letter strand is created on first use with the source strand's durability.
+func (c *Conduktor) DeadLetter(strandID, msgID, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadLetter(strandID, msgID, reason)
+}
+
+// deadLetter moves an unacked message to the strand's dead-letter strand. Callers must hold c.mu.
+func (c *Conduktor) deadLetter(strandID, msgID, reason string) error {
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return err
+	}
+
+	msg, err := store.Get(context.Background(), strandID, msgID)
+	if err != nil {
+		return err
+	}
+
+	dlqID := DeadLetterStrand(strandID)
+	dlq, err := c.deadLetterStore(strandID, store)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(msg.Headers)+2)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HeaderDLQReason] = reason
+	headers[HeaderDLQSource] = strandID
+	msg.Headers = headers
+	msg.Strand = dlqID
+
+	if err := dlq.Save(context.Background(), *msg); err != nil {
+		return err
+	}
+	if err := store.Acknowledge(context.Background(), strandID, msgID); err != nil {
+		return err
+	}
+	c.deliveries.deadLettered(strandID, msgID, dlqID, reason)
+
+	messagesDeadLettered.WithLabelValues(strandID).Inc()
+	c.log.Warn("Message dead-lettered", zap.String("strand", strandID), zap.String("msgID", msgID), zap.String("reason", reason))
+	return nil
+}
+
+// DeadLetterStrandAdd creates the strand's dead-letter strand, with the strand's durability, if
+// it does not exist yet.
+func (c *Conduktor) DeadLetterStrandAdd(strandID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return err
+	}
+	_, err = c.deadLetterStore(strandID, store)
+	return err
+}
+
+// deadLetterStore returns the store of the strand's dead-letter strand, creating it in store
+// if missing. Callers must hold c.mu.
+func (c *Conduktor) deadLetterStore(strandID string, store Store) (Store, error) {
+	dlqID := DeadLetterStrand(strandID)
+	if dlq, err := c.getStore(dlqID); err == nil {
+		return dlq, nil
+	}
+	if err := c.strandAdd(dlqID, StrandConf{Durable: store == c.durable}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// DeadLetterMsg is a dead-lettered message with why and where from.
+type DeadLetterMsg struct {
+	Msg
+	Reason string
+	Source string
+}
+
+// DeadLetters returns up to limit of the oldest messages in the strand's dead-letter strand.
+func (c *Conduktor) DeadLetters(strandID string, limit int) ([]DeadLetterMsg, error) {
+	msgs, err := c.Peek(DeadLetterStrand(strandID), limit)
+	if err != nil && !c.hasStrand(strandID) {
+		return nil, errors.New("strand not found")
+	}
+	if err != nil {
+		// No message was ever dead-lettered
+		return []DeadLetterMsg{}, nil
+	}
+
+	dead := make([]DeadLetterMsg, len(msgs))
+	for i, msg := range msgs {
+		dead[i] = DeadLetterMsg{Msg: msg, Reason: msg.Headers[HeaderDLQReason], Source: msg.Headers[HeaderDLQSource]}
+	}
+	return dead, nil
+}
+
+// Redrive moves dead-lettered messages back to the strand they were dead-lettered from, keeping
+// their IDs. With no msgIDs, every dead-lettered message is redriven. Headers are set on each
+// message first; an empty value removes a header. Returns how many messages were redriven.
+func (c *Conduktor) Redrive(strandID string, msgIDs []string, headers map[string]string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closing {
+		return 0, ErrShuttingDown
+	}
+	if err := c.checkMaintenance(strandID); err != nil {
+		return 0, err
+	}
+	dlqID := DeadLetterStrand(strandID)
+	dlq, err := c.getStore(dlqID)
+	if err != nil {
+		return 0, err
+	}
+	msgs, err := c.deadLetterSelect(dlq, dlqID, msgIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	redriven := 0
+	for _, dead := range msgs {
+		msg := dead
+		msg.Strand = strandID
+		if source := dead.Headers[HeaderDLQSource]; source != "" {
+			msg.Strand = source
+		}
+		msg.Headers = make(map[string]string, len(dead.Headers)+len(headers))
+		for k, v := range dead.Headers {
+			msg.Headers[k] = v
+		}
+		delete(msg.Headers, HeaderDLQReason)
+		delete(msg.Headers, HeaderDLQSource)
+		for k, v := range headers {
+			if v == "" {
+				delete(msg.Headers, k)
+			} else {
+				msg.Headers[k] = v
+			}
+		}
+
+		if err := c.overflow(msg); err != nil {
+			return redriven, err
+		}
+		if err := c.accept(context.Background(), msg); err != nil {
+			return redriven, err
+		}
+		if err := dlq.Acknowledge(context.Background(), dlqID, dead.ID); err != nil {
+			return redriven, err
+		}
+		messagesRedriven.WithLabelValues(msg.Strand).Inc()
+		redriven++
+	}
+
+	c.log.Info("Dead letters redriven", zap.String("strand", strandID), zap.Int("messages", redriven))
+	return redriven, nil
+}
+
+// DeadLetterPurge deletes dead-lettered messages. With no msgIDs, every dead-lettered message is
+// deleted. Returns how many messages were deleted.
+func (c *Conduktor) DeadLetterPurge(strandID string, msgIDs []string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dlqID := DeadLetterStrand(strandID)
+	dlq, err := c.getStore(dlqID)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgIDs) == 0 {
+		purged, err := dlq.Purge(context.Background(), dlqID)
+		if err != nil {
+			return 0, err
+		}
+		c.log.Info("Dead letters purged", zap.String("strand", strandID), zap.Int("messages", purged))
+		return purged, nil
+	}
+
+	msgs, err := c.deadLetterSelect(dlq, dlqID, msgIDs)
+	if err != nil {
+		return 0, err
+	}
+	for _, msg := range msgs {
+		if err := dlq.Acknowledge(context.Background(), dlqID, msg.ID); err != nil {
+			return 0, err
+		}
+		c.deliveries.removed(dlqID, msg.ID, DeliveryAcked)
+	}
+	c.log.Info("Dead letters purged", zap.String("strand", strandID), zap.Int("messages", len(msgs)))
+	return len(msgs), nil
+}
+
+// deadLetterSelect loads the messages of dlqID with msgIDs, or all of them without any,
+// failing if any is missing. Callers must hold c.mu.
+func (c *Conduktor) deadLetterSelect(dlq Store, dlqID string, msgIDs []string) ([]Msg, error) {
+	if len(msgIDs) == 0 {
+		return dlq.Peek(context.Background(), dlqID, 0)
+	}
+	msgs := make([]Msg, 0, len(msgIDs))
+	for _, msgID := range msgIDs {
+		msg, err := dlq.Get(context.Background(), dlqID, msgID)
+		if err != nil {
+			return nil, fmt.Errorf("dead letter %s: %w", msgID, err)
+		}
+		msgs = append(msgs, *msg)
+	}
+	return msgs, nil
+}
+
+// hasStrand reports whether either store has strandID.
+func (c *Conduktor) hasStrand(strandID string) bool {
+	return c.durable.HasStrand(context.Background(), strandID) || c.volatile.HasStrand(context.Background(), strandID)
+}
@@ -0,0 +1,102 @@
+package condukt
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Cluster operations for decommissioning nodes.
+const (
+	opDrain = "drain" // Stop assigning strands to a node
+	opLeave = "leave" // Remove a node from membership
+)
+
+// ErrDrainTimeout is returned when in-flight handoffs do not finish before the drain timeout.
+var ErrDrainTimeout = errors.New("timed out waiting for handoffs to finish")
+
+// Drain decommissions nodeID: it stops assigning strands to the node, moves the strands it owns to
+// other members, waits up to timeout for local handoffs to finish, and then removes the node from membership.
+// Draining the local node leaves the cluster.
+func (cl *Cluster) Drain(nodeID string, timeout time.Duration) error {
+	cl.mu.Lock()
+	node, exists := cl.nodes[nodeID]
+	if !exists {
+		cl.mu.Unlock()
+		return errors.New("node not found")
+	}
+	node.Role = NodeRoleDraining
+	cl.nodes[nodeID] = node
+
+	nodes := make([]Node, 0, len(cl.nodes))
+	for _, n := range cl.nodes {
+		nodes = append(nodes, n)
+	}
+	var owned []string
+	for strandID := range cl.strands {
+		if owner, assigned := cl.owners[strandID]; owner == nodeID || (!assigned && nodeID == cl.self) {
+			owned = append(owned, strandID)
+		}
+	}
+	for strandID, owner := range cl.owners {
+		if owner == nodeID && !cl.strands[strandID] {
+			owned = append(owned, strandID)
+		}
+	}
+	cl.mu.Unlock()
+
+	cl.log.Info("Draining node", zap.String("node", nodeID), zap.Int("strands", len(owned)))
+	cl.broadcast(opDrain, Msg{Payload: nodeID})
+
+	// Move each strand to the next member in line
+	for _, strandID := range owned {
+		ranked := rendezvousRank(strandID, nodes)
+		if len(ranked) == 0 {
+			return errors.New("no member left to take over strands")
+		}
+		if err := cl.Assign(strandID, ranked[0].ID); err != nil {
+			return err
+		}
+	}
+
+	// Wait for in-flight handoffs of local messages
+	done := make(chan struct{})
+	go func() {
+		cl.moving.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		return ErrDrainTimeout
+	}
+
+	cl.broadcast(opLeave, Msg{Payload: nodeID})
+	if nodeID == cl.self {
+		cl.log.Info("Left cluster after drain", zap.String("node", nodeID))
+		cl.Close()
+		return nil
+	}
+	cl.NodeRemove(nodeID)
+	return nil
+}
+
+// drainApply marks a node as draining or removes it, on behalf of the peer running Drain.
+func (cl *Cluster) drainApply(op, nodeID string) {
+	if op == opLeave {
+		if nodeID == cl.self {
+			return
+		}
+		cl.NodeRemove(nodeID)
+		return
+	}
+
+	cl.mu.Lock()
+	if node, exists := cl.nodes[nodeID]; exists {
+		node.Role = NodeRoleDraining
+		cl.nodes[nodeID] = node
+	}
+	cl.mu.Unlock()
+	cl.log.Info("Node draining", zap.String("node", nodeID))
+}
@@ -0,0 +1,164 @@
+package condukt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// HeaderKeyID names the key a message's payload was encrypted with.
+const HeaderKeyID = "x-condukt-key-id"
+
+// ErrUnknownKey is returned when a message was encrypted with a key the KeyRing does not hold.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// KeyRing holds the AES keys of each strand, or strand pattern, by key ID. Sends encrypt with a
+// strand's current key, and receives decrypt with the key their message names, so keys can be
+// rotated while messages encrypted with older ones are still unacked. A strand's own keys take
+// precedence over those of patterns, which apply in the order they were first keyed.
+type KeyRing struct {
+	keys strandKeys[cipher.AEAD]
+}
+
+// KeyRingMake makes an empty KeyRing.
+func KeyRingMake() *KeyRing {
+	return &KeyRing{keys: strandKeysMake[cipher.AEAD]()}
+}
+
+// Add adds a 16, 24, or 32 byte AES key for strandID, which may be a * pattern, and makes it the
+// key sends encrypt with.
+func (k *KeyRing) Add(strandID, keyID string, key []byte) error {
+	if keyID == "" {
+		return errors.New("key ID is required")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	k.keys.add(strandID, keyID, aead)
+	return nil
+}
+
+// Remove drops a key of strandID once no unacked message needs it. Removing the current key
+// leaves the strand's sends unencrypted until another key is added.
+func (k *KeyRing) Remove(strandID, keyID string) {
+	k.keys.remove(strandID, keyID)
+}
+
+// encrypt encrypts msg's payload with its strand's current key, if it has one.
+func (k *KeyRing) encrypt(msg *Msg) {
+	keyID, aead, exists := k.keys.current(msg.Strand)
+	if !exists {
+		return
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	sealed := aead.Seal(nonce, nonce, []byte(msg.Payload), []byte(msg.Strand))
+	msg.Payload = base64.StdEncoding.EncodeToString(sealed)
+	msg.Headers[HeaderKeyID] = keyID
+}
+
+// decrypt decrypts msg's payload with the key it names, if it names one.
+func (k *KeyRing) decrypt(msg *Msg) error {
+	keyID, exists := msg.Headers[HeaderKeyID]
+	if !exists {
+		return nil
+	}
+	aead, exists := k.keys.get(msg.Strand, keyID)
+	if !exists {
+		return fmt.Errorf("%w %s for strand %s", ErrUnknownKey, keyID, msg.Strand)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(msg.Payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return fmt.Errorf("malformed encrypted payload of message %s", msg.ID)
+	}
+	payload, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(msg.Strand))
+	if err != nil {
+		return fmt.Errorf("decrypting message %s: %w", msg.ID, err)
+	}
+	msg.Payload = string(payload)
+	msg.Headers = maps.Clone(msg.Headers) // Stores may share the map with the message
+	delete(msg.Headers, HeaderKeyID)
+	return nil
+}
+
+// EncryptionMiddleware encrypts payloads with AES-GCM as they are sent to strands with keys in
+// keys, and decrypts them as they are received, so stores, wires, and replicas only see
+// ciphertext. Messages the receiving Conduktor cannot decrypt stay unacked in their store.
+func EncryptionMiddleware(keys *KeyRing) Middleware {
+	return Middleware{
+		Send: func(next SendHandler) SendHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				keys.encrypt(msg)
+				return next(ctx, msg)
+			}
+		},
+		Deliver: func(next DeliverHandler) DeliverHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				if err := keys.decrypt(msg); err != nil {
+					return err
+				}
+				return next(ctx, msg)
+			}
+		},
+	}
+}
+
+// EncryptionConf configures at-rest encryption of message payloads. Of the keys of one strand or
+// pattern, the last listed encrypts sends; earlier ones only decrypt, so a key is rotated by
+// appending its successor and removed once no unacked message needs it.
+type EncryptionConf struct {
+	Keys []StrandKey `yaml:"keys"`
+}
+
+// Enabled reports whether any key is configured.
+func (conf EncryptionConf) Enabled() bool {
+	return len(conf.Keys) > 0
+}
+
+// Validate reports keys without strands, ID, or exactly one source, and literal keys that are not
+// 16, 24, or 32 bytes.
+func (conf EncryptionConf) Validate() error {
+	var errs []error
+	for i, key := range conf.Keys {
+		if err := key.validate(encryptionKeyCheck); err != nil {
+			errs = append(errs, fmt.Errorf("keys[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// KeyRing returns the configured keys, reading them from the environment or unwrapping them with
+// their KMS as needed.
+func (conf EncryptionConf) KeyRing(ctx context.Context) (*KeyRing, error) {
+	keys := KeyRingMake()
+	for i, key := range conf.Keys {
+		raw, err := key.load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("encryption.keys[%d]: %w", i, err)
+		}
+		if err := keys.Add(key.Strands, key.ID, raw); err != nil {
+			return nil, fmt.Errorf("encryption.keys[%d]: %w", i, err)
+		}
+	}
+	return keys, nil
+}
+
+// encryptionKeyCheck refuses keys that are not AES-128, AES-192, or AES-256 keys.
+func encryptionKeyCheck(key []byte) error {
+	if n := len(key); n != 16 && n != 24 && n != 32 {
+		return fmt.Errorf("%d bytes, want 16, 24, or 32", n)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package condukt
+
+import (
+	"sync"
+	"time"
+)
+
+// Sources of ErrorEvents.
+const (
+	ErrorSourceRecovery     = "recovery"      // Resending unacked messages on recovery
+	ErrorSourceRedelivery   = "redelivery"    // Delivering messages held back from a slow consumer
+	ErrorSourceSlowConsumer = "slow_consumer" // Checking for and disconnecting slow consumers
+	ErrorSourceMirror       = "mirror"        // Shipping to or accepting from a standby mirror
+	ErrorSourceGeo          = "geo"           // Shipping or applying geo-replicated batches
+	ErrorSourceFederation   = "federation"    // Republishing federated messages
+	ErrorSourceCluster      = "cluster"       // Replicating, releasing, moving, or applying cluster messages
+	ErrorSourceBackup       = "backup"        // Scheduled backups
+	ErrorSourceSink         = "sink"          // Exporting messages to a sink
+	ErrorSourceImport       = "import"        // Importing messages from a source
+	ErrorSourceSchedule     = "schedule"      // Publishing scheduled messages
+	ErrorSourceACL          = "acl"           // Reloading the ACL file
+	ErrorSourceAlert        = "alert"         // Evaluating alerts and calling their webhook
+	ErrorSourceMaintenance  = "maintenance"   // Refreshing depths, namespace gauges, and lag
+)
+
+// ErrorEvent is a failure that happened outside any caller's stack, in a background loop or in
+// work a call left running.
+type ErrorEvent struct {
+	Time   time.Time
+	Source string // One of the ErrorSource constants
+	Strand string // Strand involved, if any
+	MsgID  string // Message involved, if any
+	Err    error
+}
+
+// errorHooks calls the OnError handlers.
+type errorHooks struct {
+	mu       sync.Mutex
+	handlers []func(ErrorEvent)
+}
+
+// report calls every handler with a failure.
+func (h *errorHooks) report(source, strandID, msgID string, err error) {
+	h.mu.Lock()
+	handlers := h.handlers
+	h.mu.Unlock()
+
+	event := ErrorEvent{Time: time.Now(), Source: source, Strand: strandID, MsgID: msgID, Err: err}
+	for _, handle := range handlers {
+		handle(event)
+	}
+}
+
+// OnError adds a handler for failures outside any caller's stack, which are otherwise only
+// logged. Handlers run on the failing goroutine, sometimes under the Conduktor's lock, so they
+// must return quickly and leave calls back into the Conduktor to another goroutine.
+func (c *Conduktor) OnError(handle func(ErrorEvent)) {
+	c.errs.mu.Lock()
+	defer c.errs.mu.Unlock()
+	c.errs.handlers = append(c.errs.handlers, handle)
+}
@@ -0,0 +1,163 @@
+package condukt
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Message lifecycle events.
+const (
+	EventSaved        = "saved"         // Stored
+	EventSent         = "sent"          // First sent on the wire
+	EventRedelivered  = "redelivered"   // Sent on the wire again, on resume or recovery
+	EventReceived     = "received"      // Received from the wire
+	EventAcked        = "acked"         // Acknowledged and removed from the store
+	EventDeadLettered = "dead_lettered" // Moved to the dead-letter strand
+	EventEvicted      = "evicted"       // Dropped to make room under the strand's MaxBytes
+)
+
+// ErrNoEventLog is returned when querying events without an event log.
+var ErrNoEventLog = errors.New("message event log is disabled")
+
+// MsgEvent records one step in a message's lifecycle.
+type MsgEvent struct {
+	Time    int64  `json:"time"` // Unix nanoseconds
+	Event   string `json:"event"`
+	Strand  string `json:"strand"`
+	MsgID   string `json:"msg_id"`
+	Attempt int    `json:"attempt,omitempty"` // Which delivery, for sent and redelivered
+	Store   string `json:"store,omitempty"`   // durable or volatile, for saved
+	Reason  string `json:"reason,omitempty"`  // Why, for dead_lettered
+}
+
+// EventQuery selects events. Empty fields match every event.
+type EventQuery struct {
+	MsgID  string
+	Strand string
+	Since  int64 // Unix nanoseconds
+	Limit  int   // Most events returned, oldest first; 0 is unlimited
+}
+
+// match reports whether event is selected by q.
+func (q EventQuery) match(event MsgEvent) bool {
+	return (q.MsgID == "" || event.MsgID == q.MsgID) &&
+		(q.Strand == "" || event.Strand == q.Strand) &&
+		event.Time >= q.Since
+}
+
+// EventLog appends message lifecycle events and answers queries over them.
+type EventLog interface {
+	Append(event MsgEvent) error
+	Events(q EventQuery) ([]MsgEvent, error)
+}
+
+// SetEventLog sets where the Conduktor records message lifecycle events. nil disables the event log.
+func (c *Conduktor) SetEventLog(events EventLog) {
+	c.deliveries.mu.Lock()
+	defer c.deliveries.mu.Unlock()
+	c.deliveries.events = events
+}
+
+// Events returns the recorded lifecycle events selected by q.
+func (c *Conduktor) Events(q EventQuery) ([]MsgEvent, error) {
+	c.deliveries.mu.Lock()
+	events := c.deliveries.events
+	c.deliveries.mu.Unlock()
+	if events == nil {
+		return nil, ErrNoEventLog
+	}
+	return events.Events(q)
+}
+
+// emit appends event to the event log, if there is one, and logs it if the message is marked with
+// HeaderDebug. Failures are logged, not returned, so the event log never blocks deliveries.
+func (l *deliveryLog) emit(event MsgEvent) {
+	l.mu.Lock()
+	events := l.events
+	d, known := l.records[event.MsgID]
+	debug := known && d.debug
+	l.mu.Unlock()
+
+	if debug {
+		debugLog(l.log).Debug("Message hop", zap.String("event", event.Event),
+			zap.String("strand", event.Strand), zap.String("msgID", event.MsgID), zap.Int("attempt", event.Attempt),
+			zap.String("store", event.Store), zap.String("reason", event.Reason))
+	}
+	if events == nil {
+		return
+	}
+
+	event.Time = time.Now().UnixNano()
+	if err := events.Append(event); err != nil {
+		l.log.Error("Failed to record message event", zap.String("event", event.Event), zap.String("msgID", event.MsgID), zap.Error(err))
+	}
+}
+
+// EventFile appends events to a file as JSON lines and answers queries by scanning it.
+type EventFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// EventFileMake opens path for appending, creating it if needed.
+func EventFileMake(path string) (*EventFile, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &EventFile{path: path, file: file}, nil
+}
+
+// Append appends event to the file.
+func (e *EventFile) Append(event MsgEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.file.Write(append(line, '\n'))
+	return err
+}
+
+// Events scans the file for the events selected by q.
+func (e *EventFile) Events(q EventQuery) ([]MsgEvent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	file, err := os.Open(e.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events := []MsgEvent{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event MsgEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, err
+		}
+		if !q.match(event) {
+			continue
+		}
+		events = append(events, event)
+		if q.Limit > 0 && len(events) >= q.Limit {
+			break
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Close closes the file.
+func (e *EventFile) Close() error {
+	return e.file.Close()
+}
@@ -0,0 +1,125 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FederationLink subscribes to strands on a remote Conduktor and republishes them locally.
+type FederationLink struct {
+	Name    string
+	Wire    Wire              // Wire the remote Conduktor delivers on
+	Strands map[string]string // Remote strand -> local strand ("" keeps the remote name)
+}
+
+// federation is a running FederationLink.
+type federation struct {
+	link FederationLink
+	done chan struct{}
+}
+
+// FederationAdd starts republishing the link's remote strands into local strands.
+func (c *Conduktor) FederationAdd(link FederationLink) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if link.Name == "" || link.Wire == nil || len(link.Strands) == 0 {
+		return errors.New("federation link requires a name, a wire, and at least one strand")
+	}
+	if _, exists := c.links[link.Name]; exists {
+		return errors.New("federation link already exists")
+	}
+
+	f := &federation{link: link, done: make(chan struct{})}
+	c.links[link.Name] = f
+	for remote, local := range link.Strands {
+		if local == "" {
+			local = remote
+		}
+		go c.federate(f, remote, local)
+	}
+
+	c.log.Info("Federation link added", zap.String("link", link.Name), zap.Int("strands", len(link.Strands)))
+	return nil
+}
+
+// FederationRemove stops a federation link.
+func (c *Conduktor) FederationRemove(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, exists := c.links[name]
+	if !exists {
+		return errors.New("federation link not found")
+	}
+
+	close(f.done)
+	delete(c.links, name)
+
+	c.log.Info("Federation link removed", zap.String("link", name))
+	return nil
+}
+
+// federate consumes remote messages and republishes them on the local strand.
+func (c *Conduktor) federate(f *federation, remote, local string) {
+	ctx, cancel := doneContext(f.done)
+	defer cancel()
+	for {
+		select {
+		case <-f.done:
+			return
+		default:
+		}
+
+		msg, err := f.link.Wire.ReceiveMessage(ctx, remote)
+		if err != nil {
+			// The remote strand may not have delivered anything yet
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		// Drop messages that already passed through this Conduktor
+		hops := msg.Headers[HeaderHops]
+		if hopsContains(hops, c.id) {
+			messagesFederationLooped.WithLabelValues(local).Inc()
+			c.log.Debug("Dropped federation loop", zap.String("link", f.link.Name), zap.String("msgID", msg.ID))
+			continue
+		}
+
+		headers := make(map[string]string, len(msg.Headers)+1)
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		if hops == "" {
+			headers[HeaderHops] = c.id
+		} else {
+			headers[HeaderHops] = hops + "," + c.id
+		}
+		msg.Headers = headers
+		msg.Strand = local
+
+		c.mu.Lock()
+		err = c.accept(context.Background(), *msg)
+		c.mu.Unlock()
+		if err != nil {
+			c.log.Error("Failed to republish federated message", zap.String("link", f.link.Name), zap.String("strand", local), zap.Error(err))
+			c.errs.report(ErrorSourceFederation, local, msg.ID, err)
+			continue
+		}
+		messagesFederated.WithLabelValues(local).Inc()
+	}
+}
+
+// hopsContains reports whether id appears in a comma-separated hops header.
+func hopsContains(hops, id string) bool {
+	for _, hop := range strings.Split(hops, ",") {
+		if hop == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package condukt
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Cluster operations for ownership fencing.
+const (
+	opOwner  = "owner"  // Announces a strand's owner at an epoch
+	opFenced = "fenced" // The receiver rejected a replica write carrying a stale epoch
+)
+
+// ErrFenced is returned when a write carries an ownership epoch older than the cluster's.
+var ErrFenced = errors.New("strand ownership epoch is stale")
+
+// Epoch returns the ownership epoch of strandID. Every ownership change increments it.
+func (cl *Cluster) Epoch(strandID string) uint64 {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.epochs[strandID]
+}
+
+// adopt accepts an ownership announcement if its epoch is newer than the local one.
+// It reports whether the local node was deposed as owner.
+func (cl *Cluster) adopt(strandID, nodeID string, epoch uint64) (adopted, deposed bool) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if epoch <= cl.epochs[strandID] {
+		return false, false
+	}
+
+	previous, assigned := cl.owners[strandID]
+	if !assigned {
+		previous = cl.self
+	}
+	cl.owners[strandID] = nodeID
+	cl.epochs[strandID] = epoch
+	return true, previous == cl.self && nodeID != cl.self
+}
+
+// fenced reports whether an envelope for strandID carries an epoch older than the local one.
+func (cl *Cluster) fenced(strandID string, envelope *Msg) bool {
+	epoch, _ := strconv.ParseUint(envelope.Headers[HeaderEpoch], 10, 64)
+	return epoch < cl.Epoch(strandID)
+}
+
+// announce tells nodeID, or every peer when nodeID is empty, who owns strandID at the current epoch.
+func (cl *Cluster) announce(nodeID, strandID string) {
+	cl.mu.RLock()
+	owner, assigned := cl.owners[strandID]
+	if !assigned {
+		owner = cl.self
+	}
+	cl.mu.RUnlock()
+
+	msg := Msg{Strand: strandID, Payload: owner}
+	if nodeID == "" {
+		cl.broadcast(opOwner, msg)
+		return
+	}
+	if err := cl.send(nodeID, opOwner, msg); err != nil {
+		cl.log.Warn("Ownership announcement failed", zap.String("strand", strandID), zap.String("node", nodeID), zap.Error(err))
+	}
+}
+
+// ownerApply adopts a newer ownership announcement, handing off local messages if this node was deposed,
+// and corrects peers that announce a stale owner.
+func (c *Conduktor) ownerApply(cl *Cluster, from string, envelope *Msg, msg Msg) {
+	epoch, _ := strconv.ParseUint(envelope.Headers[HeaderEpoch], 10, 64)
+	adopted, deposed := cl.adopt(msg.Strand, msg.Payload, epoch)
+	if !adopted {
+		if epoch < cl.Epoch(msg.Strand) {
+			cl.announce(from, msg.Strand)
+		}
+		return
+	}
+
+	c.log.Info("Strand ownership changed", zap.String("strand", msg.Strand), zap.String("node", msg.Payload), zap.Uint64("epoch", epoch))
+	if deposed {
+		strandsFenced.Inc()
+		cl.move(msg.Strand, msg.Payload, time.Millisecond)
+	}
+	cl.notify()
+}
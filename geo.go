@@ -0,0 +1,304 @@
+package condukt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+// geoPrefix names the wire channel a region reads replicated batches from.
+const geoPrefix = "_geo."
+
+// ErrNotHomeRegion is returned when sending to a strand whose home is another region.
+var ErrNotHomeRegion = errors.New("strand is homed in another region")
+
+// GeoConf configures asynchronous replication of durable strands to a remote region.
+type GeoConf struct {
+	Region    string            // Local region name
+	Remote    string            // Remote region name
+	Wire      Wire              // WAN wire reaching the remote region
+	Homes     map[string]string // Strand -> home region. Only the home region accepts sends for a strand.
+	BatchSize int               // Messages per shipped batch (default 500)
+	Linger    time.Duration     // Max time a message waits for a batch to fill (default 1s)
+	Buffer    int               // Messages queued before the shipper starts dropping (default 100000)
+}
+
+// geoShipper batches, compresses, and ships durable messages to one remote region.
+type geoShipper struct {
+	conf    GeoConf
+	queue   chan Msg
+	done    chan struct{} // Closed to stop, discarding queued messages
+	flush   chan struct{} // Closed to stop after shipping queued messages
+	stopped chan struct{} // Closed when run returns
+	errs    *errorHooks
+	log     *zap.Logger
+}
+
+// GeoAdd starts shipping durable messages homed in the local region to conf.Remote.
+func (c *Conduktor) GeoAdd(conf GeoConf) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conf.Region == "" || conf.Remote == "" || conf.Wire == nil {
+		return errors.New("geo-replication requires a region, a remote region, and a wire")
+	}
+	if c.region != "" && c.region != conf.Region {
+		return fmt.Errorf("conduktor already belongs to region %s", c.region)
+	}
+	if _, exists := c.geo[conf.Remote]; exists {
+		return errors.New("region already replicated")
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 500
+	}
+	if conf.Linger <= 0 {
+		conf.Linger = time.Second
+	}
+	if conf.Buffer <= 0 {
+		conf.Buffer = 100000
+	}
+
+	c.region = conf.Region
+	for strandID, home := range conf.Homes {
+		c.homes[strandID] = home
+	}
+
+	g := &geoShipper{
+		conf:    conf,
+		queue:   make(chan Msg, conf.Buffer),
+		done:    make(chan struct{}),
+		flush:   make(chan struct{}),
+		stopped: make(chan struct{}),
+		errs:    c.errs,
+		log:     c.log,
+	}
+	c.geo[conf.Remote] = g
+	go g.run()
+
+	c.log.Info("Geo-replication added", zap.String("region", conf.Region), zap.String("remote", conf.Remote))
+	return nil
+}
+
+// GeoRemove stops shipping to a remote region. Queued messages are discarded.
+func (c *Conduktor) GeoRemove(remote string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, exists := c.geo[remote]
+	if !exists {
+		return errors.New("region not replicated")
+	}
+
+	close(g.done)
+	delete(c.geo, remote)
+	geoLagMessages.DeleteLabelValues(remote)
+	geoLagSeconds.DeleteLabelValues(remote)
+
+	c.log.Info("Geo-replication removed", zap.String("remote", remote))
+	return nil
+}
+
+// GeoServe applies batches replicated to region over wire, until stop is called. Messages for
+// strands homed in the local region are rejected, so each strand has exactly one writer. Applied
+// messages keep the region they were first accepted in, and are not shipped on again.
+func (c *Conduktor) GeoServe(wire Wire, region string) (stop func()) {
+	c.mu.Lock()
+	c.region = region
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		channel := geoPrefix + region
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			envelope, err := wire.ReceiveMessage(ctx, channel)
+			if err != nil {
+				// The region channel may not exist until a peer ships to it
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+
+			source := envelope.Headers[HeaderRegion]
+			batch, err := geoDecode(envelope.Payload)
+			if err != nil {
+				c.log.Warn("Failed to decode geo batch", zap.String("region", source), zap.Error(err))
+				continue
+			}
+
+			c.mu.Lock()
+			for _, msg := range batch {
+				if home, exists := c.homes[msg.Strand]; exists && home != source {
+					c.log.Warn("Rejected geo message for strand homed elsewhere",
+						zap.String("strand", msg.Strand), zap.String("home", home), zap.String("region", source))
+					continue
+				}
+				if msg.Headers == nil {
+					msg.Headers = map[string]string{}
+				}
+				if msg.Headers[HeaderRegion] == "" {
+					msg.Headers[HeaderRegion] = source
+				}
+				if err := c.accept(context.Background(), msg); err != nil {
+					c.log.Error("Failed to apply geo message", zap.String("strand", msg.Strand), zap.Error(err))
+					c.errs.report(ErrorSourceGeo, msg.Strand, msg.ID, err)
+				}
+			}
+			c.mu.Unlock()
+
+			geoApplied.WithLabelValues(source).Add(float64(len(batch)))
+		}
+	}()
+
+	return cancel
+}
+
+// enqueue hands msg to the shipper without blocking the send path.
+func (g *geoShipper) enqueue(msg Msg) {
+	select {
+	case g.queue <- msg:
+	default:
+		geoDropped.WithLabelValues(g.conf.Remote).Inc()
+		g.log.Warn("Geo buffer full", zap.String("remote", g.conf.Remote))
+	}
+}
+
+// run accumulates messages and ships a batch when it is full or has lingered long enough.
+func (g *geoShipper) run() {
+	ticker := time.NewTicker(g.conf.Linger)
+	defer ticker.Stop()
+
+	defer close(g.stopped)
+
+	batch := make([]Msg, 0, g.conf.BatchSize)
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-g.flush:
+			for drained := false; !drained; {
+				select {
+				case msg := <-g.queue:
+					batch = append(batch, msg)
+					if len(batch) == g.conf.BatchSize {
+						g.ship(batch)
+						batch = batch[:0]
+					}
+				default:
+					drained = true
+				}
+			}
+			if len(batch) > 0 {
+				g.ship(batch)
+			}
+			return
+		case msg := <-g.queue:
+			batch = append(batch, msg)
+			if len(batch) < g.conf.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		g.ship(batch)
+		batch = batch[:0]
+	}
+}
+
+// ship compresses and sends one batch.
+func (g *geoShipper) ship(batch []Msg) {
+	payload, err := geoEncode(batch)
+	if err != nil {
+		g.log.Error("Failed to encode geo batch", zap.String("remote", g.conf.Remote), zap.Error(err))
+		g.errs.report(ErrorSourceGeo, "", "", err)
+		return
+	}
+
+	envelope := Msg{
+		ID:        batch[0].ID,
+		Strand:    geoPrefix + g.conf.Remote,
+		Payload:   payload,
+		Timestamp: time.Now().Unix(),
+		Headers:   map[string]string{HeaderRegion: g.conf.Region},
+	}
+	if err := g.conf.Wire.SendMessage(context.Background(), envelope); err != nil {
+		geoDropped.WithLabelValues(g.conf.Remote).Add(float64(len(batch)))
+		g.log.Error("Geo batch send failed", zap.String("remote", g.conf.Remote), zap.Int("messages", len(batch)), zap.Error(err))
+		g.errs.report(ErrorSourceGeo, "", "", err)
+		return
+	}
+
+	geoBatches.WithLabelValues(g.conf.Remote).Inc()
+	geoBytes.WithLabelValues(g.conf.Remote).Add(float64(len(payload)))
+	geoLagMessages.WithLabelValues(g.conf.Remote).Set(float64(len(g.queue)))
+	geoLagSeconds.WithLabelValues(g.conf.Remote).Set(float64(time.Now().Unix() - batch[0].Timestamp))
+	g.log.Debug("Geo batch shipped", zap.String("remote", g.conf.Remote), zap.Int("messages", len(batch)), zap.Int("bytes", len(payload)))
+}
+
+// geoEncode serializes a batch as base64-encoded gzipped JSON, safe for any wire's string payload.
+func geoEncode(batch []Msg) (string, error) {
+	encoded := make([]json.RawMessage, len(batch))
+	for i, msg := range batch {
+		data, err := wire.MsgEncodeJSON(msg) // RawMessage must be JSON
+		if err != nil {
+			return "", err
+		}
+		encoded[i] = data
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(encoded); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// geoDecode reverses geoEncode.
+func geoDecode(payload string) ([]Msg, error) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded []json.RawMessage
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+	batch := make([]Msg, len(encoded))
+	for i, data := range encoded {
+		if batch[i], err = wire.MsgDecodeShared(data); err != nil { // Unmarshal copied each out of raw
+			return nil, err
+		}
+	}
+	return batch, nil
+}
@@ -0,0 +1,109 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Health tracks what the liveness and readiness probes check: store writability, listener status,
+// and whether startup recovery has completed.
+type Health struct {
+	mu        sync.Mutex
+	c         *Conduktor
+	listeners map[string]error // Listener name -> nil while serving, or why it is down
+}
+
+// HealthCheck is the result of one probe check.
+type HealthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthMake creates a Health for a Conduktor.
+func HealthMake(c *Conduktor) *Health {
+	return &Health{c: c, listeners: make(map[string]error)}
+}
+
+// Listening records the status of a listener: nil once it is serving, or the error that stopped it.
+func (h *Health) Listening(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners[name] = err
+}
+
+// Live checks that both stores accept writes.
+func (h *Health) Live() []HealthCheck {
+	return []HealthCheck{
+		healthCheck("store.durable", h.c.durable.Ping(context.Background())),
+		healthCheck("store.volatile", h.c.volatile.Ping(context.Background())),
+	}
+}
+
+// Ready runs the liveness checks and also checks that every listener is serving and recovery has completed.
+func (h *Health) Ready() []HealthCheck {
+	checks := h.Live()
+
+	h.mu.Lock()
+	names := make([]string, 0, len(h.listeners))
+	for name := range h.listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checks = append(checks, healthCheck("listener."+name, h.listeners[name]))
+	}
+	h.mu.Unlock()
+
+	var err error
+	if !h.c.recovered.Load() {
+		err = errors.New("recovery has not completed")
+	}
+	return append(checks, healthCheck("recovery", err))
+}
+
+// Handler serves the probes:
+//
+//	GET /livez     Process is up
+//	GET /healthz   Stores accept writes
+//	GET /readyz    Stores accept writes, listeners are serving, and recovery has completed
+//
+// Failing probes respond 503 with the checks that failed.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		healthReply(w, nil)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		healthReply(w, h.Live())
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		healthReply(w, h.Ready())
+	})
+	return mux
+}
+
+// healthCheck records the outcome of a check.
+func healthCheck(name string, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Name: name, Error: err.Error()}
+	}
+	return HealthCheck{Name: name, OK: true}
+}
+
+// healthReply writes the checks with 200 if all passed, or 503 otherwise.
+func healthReply(w http.ResponseWriter, checks []HealthCheck) {
+	status := http.StatusOK
+	for _, check := range checks {
+		if !check.OK {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if checks == nil {
+		checks = []HealthCheck{}
+	}
+	adminWrite(w, status, map[string]any{"ok": status == http.StatusOK, "checks": checks})
+}
@@ -0,0 +1,141 @@
+package condukt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ingestMaxBody caps the body of a single-message ingest request when limits set no
+// MaxPayloadBytes.
+const ingestMaxBody = 16 << 20
+
+// IngestHeaderPrefix prefixes the request headers that become message headers on single-message
+// ingest requests, e.g. "X-Msg-Order-Id: 42" sets header "order-id".
+const IngestHeaderPrefix = "X-Msg-"
+
+// ingestRecord is a message of an NDJSON ingest batch.
+type ingestRecord struct {
+	Payload string            `json:"payload"`
+	Headers map[string]string `json:"headers"`
+}
+
+// IngestHandler lets scripts, webhooks, and curl publish over plain HTTP, without a client library
+// or wire:
+//
+//	POST /strands/{id}/messages  Send the body as one message, with IngestHeaderPrefix headers as its headers
+//	                             or, as Content-Type application/x-ndjson, a message per line:
+//	                             {"payload": "...", "headers": {...}}
+//
+// Namespaced strands escape their slash, as in /strands/team-a%2Forders/messages. Both reply
+// {"sent": N}. A batch is sent in order and stops at its first failure, replying with the
+// messages sent before it and the error. Callers authenticate as wire clients do, with the
+// authorizer, and need publish on the strand; a nil authorizer makes every caller anonymous.
+func IngestHandler(c *Conduktor, authorizer WireAuthorizer) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /strands/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		identity, ctx := "", r.Context()
+		if authorizer != nil {
+			var err error
+			if identity, ctx, err = authorizer.Authenticate(r); err != nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="condukt"`)
+				adminError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+		strandID := r.PathValue("id")
+		send := func(record ingestRecord) error {
+			return c.Send(strandID, record.Payload, SendAs(identity), SendHeaders(record.Headers), SendContext(ctx))
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/x-ndjson" {
+			sent := 0
+			record, err := ingestSingle(c, w, r)
+			if err == nil {
+				if err = send(record); err == nil {
+					sent = 1
+				}
+			}
+			ingestReply(c, w, strandID, sent, err)
+			return
+		}
+
+		sent := 0
+		dec := json.NewDecoder(r.Body)
+		for {
+			var record ingestRecord
+			err := dec.Decode(&record)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				ingestReply(c, w, strandID, sent, &ingestBadRequest{err})
+				return
+			}
+			if err := send(record); err != nil {
+				ingestReply(c, w, strandID, sent, err)
+				return
+			}
+			sent++
+		}
+		ingestReply(c, w, strandID, sent, nil)
+	})
+	return mux
+}
+
+// ingestSingle reads a single-message request: its body is the payload, and its IngestHeaderPrefix
+// headers are the message's headers.
+func ingestSingle(c *Conduktor, w http.ResponseWriter, r *http.Request) (ingestRecord, error) {
+	c.mu.RLock()
+	limit := int64(c.limits.MaxPayloadBytes)
+	c.mu.RUnlock()
+	if limit <= 0 {
+		limit = ingestMaxBody
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if maxBytes := (*http.MaxBytesError)(nil); errors.As(err, &maxBytes) {
+		return ingestRecord{}, ErrPayloadTooLarge
+	}
+	if err != nil {
+		return ingestRecord{}, &ingestBadRequest{err}
+	}
+
+	record := ingestRecord{Payload: string(body)}
+	for name, values := range r.Header {
+		if key, found := strings.CutPrefix(name, IngestHeaderPrefix); found && key != "" && len(values) > 0 {
+			if record.Headers == nil {
+				record.Headers = make(map[string]string)
+			}
+			record.Headers[strings.ToLower(key)] = values[0]
+		}
+	}
+	return record, nil
+}
+
+// ingestBadRequest is a request body that could not be read or parsed.
+type ingestBadRequest struct{ err error }
+
+func (e *ingestBadRequest) Error() string { return "bad request body: " + e.err.Error() }
+func (e *ingestBadRequest) Unwrap() error { return e.err }
+
+// ingestReply reports how many messages were sent and, if one failed, why.
+func ingestReply(c *Conduktor, w http.ResponseWriter, strandID string, sent int, err error) {
+	if err == nil {
+		adminWrite(w, http.StatusCreated, map[string]int{"sent": sent})
+		return
+	}
+	status := adminStatusCode(err)
+	if bad := (*ingestBadRequest)(nil); errors.As(err, &bad) {
+		status = http.StatusBadRequest
+	}
+	if status == http.StatusInternalServerError {
+		c.log.Warn("Ingest failed", zap.String("strand", strandID), zap.Int("sent", sent), zap.Error(err))
+	}
+	adminWrite(w, status, map[string]any{"sent": sent, "error": err.Error()})
+}
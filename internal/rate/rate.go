@@ -0,0 +1,35 @@
+// Package rate implements the token buckets behind condukt's rate limits.
+package rate
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilling at rate tokens per second up to burst. It is safe for
+// concurrent use.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	refill time.Time
+}
+
+// BucketMake returns a full bucket.
+func BucketMake(rate, burst float64) *Bucket {
+	return &Bucket{rate: rate, burst: burst, tokens: burst, refill: time.Now()}
+}
+
+// Take removes n tokens if there are enough, reporting whether it did.
+func (b *Bucket) Take(n float64, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+now.Sub(b.refill).Seconds()*b.rate, b.burst)
+	b.refill = now
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
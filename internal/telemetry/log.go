@@ -0,0 +1,104 @@
+// Package telemetry holds the logging and metrics shared by condukt's packages.
+package telemetry
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger is the process-wide logger, used by components made without MakeLogger.
+var Logger *zap.Logger
+
+// LogLevel is Logger's level, changeable at runtime.
+var LogLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+func init() {
+	cfg := zap.NewProductionConfig()
+	cfg.Level = LogLevel
+	cfg.OutputPaths = []string{"stdout"}
+	cfg.ErrorOutputPaths = []string{"stderr"}
+
+	var err error
+	Logger, err = cfg.Build()
+	if err != nil {
+		panic("Failed to initialize logger: " + err.Error())
+	}
+}
+
+// MakeOpts are the settings MakeOptions configure.
+type MakeOpts struct {
+	Log   *zap.Logger
+	Now   func() time.Time
+	Codec string // Codec a store or wire encodes messages with; empty is binary
+}
+
+// MakeOption configures a Conduktor, Cluster, store, or wire as it is made.
+type MakeOption func(*MakeOpts)
+
+// MakeLogger sets where a Conduktor, Cluster, store, or wire logs, controlling the destination,
+// level, and sampling of its logs. Without it, they log to Logger.
+func MakeLogger(log *zap.Logger) MakeOption {
+	return func(o *MakeOpts) {
+		o.Log = log
+	}
+}
+
+// MakeClock sets the clock a Conduktor stamps messages with. Without it, they use time.Now.
+func MakeClock(now func() time.Time) MakeOption {
+	return func(o *MakeOpts) {
+		o.Now = now
+	}
+}
+
+// MakeCodec sets the codec a store or wire encodes messages with.
+func MakeCodec(codec string) MakeOption {
+	return func(o *MakeOpts) {
+		o.Codec = codec
+	}
+}
+
+// MakeOptsApply applies options over the defaults.
+func MakeOptsApply(options []MakeOption) MakeOpts {
+	opts := MakeOpts{Log: Logger, Now: time.Now}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.Log == nil {
+		opts.Log = zap.NewNop()
+	}
+	return opts
+}
+
+// debugCore writes every entry, whatever its level, so messages marked for debugging are logged
+// even when debug logging is off.
+type debugCore struct {
+	zapcore.Core
+}
+
+func (d debugCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (d debugCore) With(fields []zapcore.Field) zapcore.Core {
+	return debugCore{d.Core.With(fields)}
+}
+
+func (d debugCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, d)
+}
+
+// DebugLog returns log ignoring its level.
+func DebugLog(log *zap.Logger) *zap.Logger {
+	return log.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core { return debugCore{core} }))
+}
+
+// MsgLog returns the logger for a step of a message: log, or for messages marked for debugging,
+// log ignoring its level.
+func MsgLog(log *zap.Logger, debug bool) *zap.Logger {
+	if !debug {
+		return log
+	}
+	return DebugLog(log)
+}
@@ -0,0 +1,42 @@
+package telemetry
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics updated by the Conduktor, stores, wires, and remote clients alike. The condukt package
+// registers them.
+var (
+	MessagesSent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_sent_total", Help: "Total messages sent"},
+		[]string{"channel"},
+	)
+
+	MessagesReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_received_total", Help: "Total messages received"},
+		[]string{"channel"},
+	)
+
+	ClientRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "client_rejections_total", Help: "Wire connections and messages refused by per-client limits"},
+		[]string{"reason"},
+	)
+
+	QueueSize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "queue_size", Help: "Current message queue size"},
+		[]string{"channel"},
+	)
+
+	QueueBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "queue_bytes", Help: "Bytes of a strand's unacked messages, as the sum of their message sizes"},
+		[]string{"channel"},
+	)
+
+	ClientSendWindow = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "client_send_window", Help: "Sends a remote client may have awaiting the broker's reply"},
+		[]string{"broker"},
+	)
+
+	ClientSendsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "client_sends_in_flight", Help: "Sends of a remote client awaiting the broker's reply"},
+		[]string{"broker"},
+	)
+)
@@ -0,0 +1,119 @@
+package condukt
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IPFilterConf holds the CIDR rules of a listener. Addresses matching a deny rule are rejected;
+// with allow rules, so are those matching none of them. Rules are CIDRs, as 10.0.0.0/8, or single
+// addresses.
+type IPFilterConf struct {
+	Allow []string `yaml:"allow"` // Empty allows every address not denied
+	Deny  []string `yaml:"deny"`
+}
+
+// Enabled reports whether the filter has any rule.
+func (conf IPFilterConf) Enabled() bool {
+	return len(conf.Allow) > 0 || len(conf.Deny) > 0
+}
+
+// Validate reports rules that are neither CIDRs nor addresses.
+func (conf IPFilterConf) Validate() error {
+	_, err := IPFilterMake("", conf)
+	return err
+}
+
+// IPFilter decides which remote addresses may connect to a listener, counting those it rejects.
+type IPFilter struct {
+	listener    string
+	allow, deny []netip.Prefix
+}
+
+// IPFilterMake compiles the rules of listener, which labels its rejections.
+func IPFilterMake(listener string, conf IPFilterConf) (*IPFilter, error) {
+	var errs []error
+	parse := func(key string, rules []string) []netip.Prefix {
+		prefixes := make([]netip.Prefix, 0, len(rules))
+		for i, rule := range rules {
+			prefix, err := ipPrefixParse(rule)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s[%d]: %w", key, i, err))
+				continue
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		return prefixes
+	}
+	f := &IPFilter{listener: listener, allow: parse("allow", conf.Allow), deny: parse("deny", conf.Deny)}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ipPrefixParse parses a CIDR, or an address as the prefix holding only it.
+func ipPrefixParse(rule string) (netip.Prefix, error) {
+	if strings.Contains(rule, "/") {
+		prefix, err := netip.ParsePrefix(rule)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(rule)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Allowed reports whether addr may connect, counting it as rejected if not. IPv4 addresses mapped
+// into IPv6 match IPv4 rules.
+func (f *IPFilter) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	allowed := len(f.allow) == 0
+	for _, prefix := range f.allow {
+		if prefix.Contains(addr) {
+			allowed = true
+			break
+		}
+	}
+	for _, prefix := range f.deny {
+		if prefix.Contains(addr) {
+			allowed = false
+			break
+		}
+	}
+	if !allowed {
+		listenerRejections.WithLabelValues(f.listener).Inc()
+	}
+	return allowed
+}
+
+// Listener wraps lis so connections from rejected addresses are closed as they are accepted,
+// before any TLS handshake or protocol processing.
+func (f *IPFilter) Listener(lis net.Listener) net.Listener {
+	return &ipFilterListener{Listener: lis, filter: f}
+}
+
+// ipFilterListener is a listener accepting only connections its filter allows.
+type ipFilterListener struct {
+	net.Listener
+	filter *IPFilter
+}
+
+// Accept returns the next allowed connection, closing rejected ones.
+func (l *ipFilterListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		addr, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+		if err == nil && l.filter.Allowed(addr.Addr()) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
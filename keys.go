@@ -0,0 +1,204 @@
+package condukt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// strandKeys holds the keys of each strand, or strand pattern, by key ID, and the current key of
+// each. A strand's own keys take precedence over those of patterns, which apply in the order they
+// were first keyed.
+type strandKeys[K any] struct {
+	mu       sync.Mutex
+	keys     map[string]map[string]K // Strand or pattern -> key ID -> key
+	latest   map[string]string       // Strand or pattern -> ID of its current key
+	patterns []string                // Keyed patterns, as "orders/*"
+}
+
+// strandKeysMake makes an empty strandKeys.
+func strandKeysMake[K any]() strandKeys[K] {
+	return strandKeys[K]{keys: make(map[string]map[string]K), latest: make(map[string]string)}
+}
+
+// add adds key for strandID, which may be a * pattern, and makes it current.
+func (s *strandKeys[K]) add(strandID, keyID string, key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys[strandID] == nil {
+		s.keys[strandID] = make(map[string]K)
+		if strings.Contains(strandID, "*") {
+			s.patterns = append(s.patterns, strandID)
+		}
+	}
+	s.keys[strandID][keyID] = key
+	s.latest[strandID] = keyID
+}
+
+// remove drops a key of strandID, leaving it without a current key if it was current.
+func (s *strandKeys[K]) remove(strandID, keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys[strandID], keyID)
+	if s.latest[strandID] == keyID {
+		delete(s.latest, strandID)
+	}
+	if len(s.keys[strandID]) == 0 {
+		delete(s.keys, strandID)
+		s.patterns = slices.DeleteFunc(s.patterns, func(pattern string) bool { return pattern == strandID })
+	}
+}
+
+// current returns the ID and key of strandID's current key, if it has one.
+func (s *strandKeys[K]) current(strandID string) (string, K, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ring := range s.rings(strandID) {
+		if keyID, exists := s.latest[ring]; exists {
+			return keyID, s.keys[ring][keyID], true
+		}
+	}
+	var none K
+	return "", none, false
+}
+
+// get returns the key of strandID named keyID, if it has one.
+func (s *strandKeys[K]) get(strandID, keyID string) (K, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ring := range s.rings(strandID) {
+		if key, exists := s.keys[ring][keyID]; exists {
+			return key, true
+		}
+	}
+	var none K
+	return none, false
+}
+
+// keyed reports whether strandID has any key.
+func (s *strandKeys[K]) keyed(strandID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.rings(strandID)) > 0
+}
+
+// rings returns the strand and patterns holding keys of strandID, most specific first. Callers
+// must hold s.mu.
+func (s *strandKeys[K]) rings(strandID string) []string {
+	var rings []string
+	if _, exists := s.keys[strandID]; exists {
+		rings = append(rings, strandID)
+	}
+	for _, pattern := range s.patterns {
+		if aclMatch(pattern, strandID) {
+			rings = append(rings, pattern)
+		}
+	}
+	return rings
+}
+
+// KMS unwraps data keys stored encrypted under a key management service's master key, so the
+// config holds no plaintext keys. Programs embedding condukt register theirs with KMSRegister.
+type KMS interface {
+	Decrypt(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// kmsPlugins holds the registered KMSs, by name.
+var kmsPlugins = struct {
+	sync.Mutex
+	byName map[string]KMS
+}{byName: make(map[string]KMS)}
+
+// KMSRegister makes kms available to encryption and signing keys configured with kms: name. It
+// replaces any KMS registered under the name.
+func KMSRegister(name string, kms KMS) {
+	kmsPlugins.Lock()
+	defer kmsPlugins.Unlock()
+	kmsPlugins.byName[name] = kms
+}
+
+// StrandKey configures a key of the strands matching a pattern, given in exactly one of key,
+// key_env, secret, or kms with wrapped.
+type StrandKey struct {
+	Strands string `yaml:"strands"` // Strand ID or * pattern the key applies to
+	ID      string `yaml:"id"`      // Key ID stored with each message, as "2025-01"
+	Key     string `yaml:"key"`     // Base64 key
+	KeyEnv  string `yaml:"key_env"` // Environment variable holding the base64 key
+	Secret  string `yaml:"secret"`  // Secret reference to the base64 key, as "vault:condukt/keys#payments"
+	KMS     string `yaml:"kms"`     // Registered KMS unwrapping wrapped
+	Wrapped string `yaml:"wrapped"` // Base64 key encrypted by the KMS
+}
+
+// validate reports a key without strands, ID, or exactly one source, and a literal key check
+// refuses.
+func (key StrandKey) validate(check func([]byte) error) error {
+	var errs []error
+	if key.Strands == "" {
+		errs = append(errs, errors.New("strands: required"))
+	}
+	if key.ID == "" {
+		errs = append(errs, errors.New("id: required"))
+	}
+	sources := 0
+	for _, source := range []string{key.Key, key.KeyEnv, key.Secret, key.KMS} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		errs = append(errs, errors.New("exactly one of key, key_env, secret, or kms is required"))
+	}
+	if (key.KMS == "") != (key.Wrapped == "") {
+		errs = append(errs, errors.New("kms and wrapped must be set together"))
+	}
+	if key.Key != "" {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key.Key))
+		if err == nil {
+			err = check(raw)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// load returns the key from its source, reading the environment or unwrapping it with its KMS as
+// needed.
+func (key StrandKey) load(ctx context.Context) ([]byte, error) {
+	switch {
+	case key.KeyEnv != "":
+		value, set := os.LookupEnv(key.KeyEnv)
+		if !set {
+			return nil, fmt.Errorf("key_env: %s is not set", key.KeyEnv)
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("key_env: %w", err)
+		}
+		return raw, nil
+	case key.KMS != "":
+		kmsPlugins.Lock()
+		kms, exists := kmsPlugins.byName[key.KMS]
+		kmsPlugins.Unlock()
+		if !exists {
+			return nil, fmt.Errorf("kms: %q is not registered", key.KMS)
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(key.Wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("wrapped: %w", err)
+		}
+		raw, err := kms.Decrypt(ctx, wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("kms %s: %w", key.KMS, err)
+		}
+		return raw, nil
+	default:
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(key.Key))
+	}
+}
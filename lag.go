@@ -0,0 +1,59 @@
+package condukt
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StrandLag is how far a strand's consumers are behind. A strand's consumers form a single group
+// competing for its messages, so the strand's lag is its group's lag.
+type StrandLag struct {
+	Strand   string  `json:"strand"`
+	Messages int     `json:"messages"` // Unacked messages: the latest sent minus the consumers' ack cursor
+	Seconds  float64 `json:"seconds"`  // Age of the oldest unacked message; 0 when caught up
+}
+
+// Lag reports the consumer lag of every strand, sorted by strand, and updates the lag gauges.
+// Dead-letter strands and the audit strand have no consumers and are skipped.
+func (c *Conduktor) Lag() ([]StrandLag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lags := []StrandLag{}
+	now := time.Now()
+	for _, store := range []Store{c.durable, c.volatile} {
+		strands, err := store.ListStrands(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for strandID := range strands {
+			if strandID == AuditStrand || strings.HasSuffix(strandID, dlqSuffix) {
+				continue
+			}
+			depth, err := store.Depth(context.Background(), strandID)
+			if err != nil {
+				return nil, err
+			}
+			lag := StrandLag{Strand: strandID, Messages: depth}
+			if depth > 0 {
+				oldest, err := store.Peek(context.Background(), strandID, 1)
+				if err != nil {
+					return nil, err
+				}
+				if len(oldest) > 0 {
+					if stored := c.storedAt(store, strandID, oldest[0].ID); !stored.IsZero() {
+						lag.Seconds = max(now.Sub(stored).Seconds(), 0)
+					}
+				}
+			}
+			consumerLagMessages.WithLabelValues(strandID).Set(float64(lag.Messages))
+			consumerLagSeconds.WithLabelValues(strandID).Set(lag.Seconds)
+			lags = append(lags, lag)
+		}
+	}
+
+	sort.Slice(lags, func(i, j int) bool { return lags[i].Strand < lags[j].Strand })
+	return lags, nil
+}
@@ -0,0 +1,47 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+)
+
+// Limits caps resource use of a Conduktor. Zero values disable a limit.
+type Limits struct {
+	MaxPayloadBytes int `yaml:"max_payload_bytes"` // Largest accepted message payload
+	MaxStrands      int `yaml:"max_strands"`       // Most strands across both stores
+
+	Clients ClientLimits `yaml:"clients"` // Per-client caps on wire connections and traffic
+}
+
+// Limit errors.
+var (
+	ErrPayloadTooLarge = errors.New("payload exceeds the maximum size")
+	ErrTooManyStrands  = errors.New("strand limit reached")
+)
+
+// SetLimits replaces the Conduktor's limits.
+func (c *Conduktor) SetLimits(limits Limits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limits = limits
+}
+
+// limitStrands checks that one more strand fits. Callers must hold c.mu.
+func (c *Conduktor) limitStrands() error {
+	if c.limits.MaxStrands <= 0 {
+		return nil
+	}
+
+	count := 0
+	for _, store := range []Store{c.durable, c.volatile} {
+		strands, err := store.ListStrands(context.Background())
+		if err != nil {
+			return err
+		}
+		count += len(strands)
+	}
+	if count >= c.limits.MaxStrands {
+		return ErrTooManyStrands
+	}
+	return nil
+}
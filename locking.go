@@ -0,0 +1,60 @@
+package condukt
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// strandLockShards is how many locks strands hash onto. Strands on different shards send and
+// acknowledge in parallel.
+const strandLockShards = 64
+
+// strandLocks serializes the sends and acks of each strand, keeping an ordered strand's messages
+// on the wire in the order they were stored.
+type strandLocks [strandLockShards]sync.Mutex
+
+// shard returns the lock of strandID.
+func (l *strandLocks) shard(strandID string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(strandID))
+	return &l[h.Sum32()%strandLockShards]
+}
+
+// lockStrand read-locks c.mu and locks strandID, returning the function that unlocks both.
+// Config of strands this Conduktor did not add, like those recovered from the durable store, is
+// cached first, since that needs c.mu locked for writing.
+func (c *Conduktor) lockStrand(strandID string) (unlock func()) {
+	c.mu.RLock()
+	if _, cached := c.confs[strandID]; !cached && c.hasStrand(strandID) {
+		c.mu.RUnlock()
+		c.mu.Lock()
+		c.strandConfCache(strandID)
+		c.mu.Unlock()
+		c.mu.RLock()
+	}
+
+	shard := c.strands.shard(strandID)
+	shard.Lock()
+	return func() {
+		shard.Unlock()
+		c.mu.RUnlock()
+	}
+}
+
+// strandConfCache loads the config of strandID from its store into c.confs, unless it is already
+// there. Callers must hold c.mu for writing.
+func (c *Conduktor) strandConfCache(strandID string) {
+	if _, cached := c.confs[strandID]; cached {
+		return
+	}
+	for _, store := range []Store{c.durable, c.volatile} {
+		if !store.HasStrand(context.Background(), strandID) {
+			continue
+		}
+		if strands, err := store.ListStrands(context.Background()); err == nil {
+			c.confs[strandID] = strands[strandID]
+		}
+		return
+	}
+}
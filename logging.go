@@ -0,0 +1,46 @@
+package condukt
+
+import (
+	"time"
+
+	"github.com/jkassis/condukt/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// logger is the process-wide logger, used by components made without MakeLogger.
+var logger = telemetry.Logger
+
+// logLevel is the logger's level, changeable at runtime.
+var logLevel = telemetry.LogLevel
+
+// MakeOption configures a Conduktor, Cluster, store, or wire as it is made.
+type MakeOption = telemetry.MakeOption
+
+// MakeLogger sets where a Conduktor, Cluster, store, or wire logs, controlling the destination,
+// level, and sampling of its logs. Without it, they log to the process-wide logger.
+func MakeLogger(log *zap.Logger) MakeOption {
+	return telemetry.MakeLogger(log)
+}
+
+// MakeClock sets the clock a Conduktor stamps messages with, like a simulation's virtual clock.
+// Without it, they use time.Now.
+func MakeClock(now func() time.Time) MakeOption {
+	return telemetry.MakeClock(now)
+}
+
+// MakeCodec sets the codec a store or wire encodes messages with: "binary", the default, or "json".
+// Stores and wires that do not encode messages ignore it.
+func MakeCodec(codec string) MakeOption {
+	return telemetry.MakeCodec(codec)
+}
+
+// debugLog returns log ignoring its level.
+func debugLog(log *zap.Logger) *zap.Logger {
+	return telemetry.DebugLog(log)
+}
+
+// msgLog returns the logger for a step of msg: log, or for messages marked with HeaderDebug, log
+// ignoring its level.
+func msgLog(log *zap.Logger, msg Msg) *zap.Logger {
+	return telemetry.MsgLog(log, msg.Debug())
+}
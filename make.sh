@@ -99,12 +99,26 @@ test() {
   echo "🔗 Using DB_CONN_STRING=$DB_CONN_STRING"
 
   echo "🛠 Running tests..."
-  go test -p 1 .
+  go test -p 1 ./...
+}
+
+# Regenerate the gRPC admin and data APIs from proto/admin.proto and proto/data.proto
+proto() {
+  echo "🛠 Generating adminpb and datapb..."
+  protoc -I proto \
+    --go_out=adminpb --go_opt=paths=source_relative \
+    --go-grpc_out=adminpb --go-grpc_opt=paths=source_relative \
+    proto/admin.proto
+  protoc -I proto \
+    --go_out=datapb --go_opt=paths=source_relative \
+    --go-grpc_out=datapb --go-grpc_opt=paths=source_relative \
+    proto/data.proto
+  echo "✅ adminpb and datapb generated."
 }
 
 # Show usage if no command is provided
 if [[ $# -eq 0 ]]; then
-  echo "Usage: $0 {setupdb|tune|startdb|stopdb|test}"
+  echo "Usage: $0 {setupdb|tune|startdb|stopdb|test|proto}"
   exit 1
 fi
 
@@ -1,26 +1,332 @@
-package main
+package condukt
 
 import (
+	"errors"
+
+	"github.com/jkassis/condukt/internal/telemetry"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Metrics shared with the store and wire packages.
+var (
+	messagesSent     = telemetry.MessagesSent
+	messagesReceived = telemetry.MessagesReceived
+	clientRejections = telemetry.ClientRejections
+	queueSize        = telemetry.QueueSize
+	queueBytes       = telemetry.QueueBytes
+
+	clientSendWindow    = telemetry.ClientSendWindow
+	clientSendsInFlight = telemetry.ClientSendsInFlight
+)
+
 var (
-	messagesSent = prometheus.NewCounterVec(
-		prometheus.CounterOpts{Name: "messages_sent_total", Help: "Total messages sent"},
+	messagesAcked = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_acked_total", Help: "Total messages acknowledged"},
+		[]string{"channel"},
+	)
+	messagesDeadLettered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_dead_lettered_total", Help: "Total messages moved to a dead-letter strand"},
+		[]string{"channel"},
+	)
+
+	sendStoreSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{Name: "send_store_seconds", Help: "Time from accepting a message to storing it", Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10)},
+		[]string{"channel"},
+	)
+
+	sendWireSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{Name: "send_wire_seconds", Help: "Time from accepting a message to sending it on the wire", Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10)},
+		[]string{"channel"},
+	)
+
+	sendAckSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{Name: "send_ack_seconds", Help: "Time from storing a message to its acknowledgment", Buckets: prometheus.ExponentialBuckets(0.001, 4, 12)},
 		[]string{"channel"},
 	)
 
-	messagesReceived = prometheus.NewCounterVec(
-		prometheus.CounterOpts{Name: "messages_received_total", Help: "Total messages received"},
+	messagesRedriven = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_redriven_total", Help: "Total dead-lettered messages moved back to their strand"},
+		[]string{"channel"},
+	)
+
+	messagesForwarded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_forwarded_total", Help: "Total messages forwarded to the owning cluster node"},
+		[]string{"channel"},
+	)
+
+	messagesMoved = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_moved_total", Help: "Stored messages handed off to a new owner during rebalancing"},
+		[]string{"channel"},
+	)
+
+	quorumTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "quorum_timeouts_total", Help: "Sends that timed out waiting for a replica quorum"},
+		[]string{"channel"},
+	)
+
+	strandConflicts = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "strand_conflicts_total", Help: "Strands created with incompatible configs on different cluster nodes"},
+	)
+
+	strandsFenced = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "strands_fenced_total", Help: "Strands this node stopped owning after learning of a newer ownership epoch"},
+	)
+
+	mirrorLagMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "mirror_lag_messages", Help: "Messages queued for a standby mirror"},
 		[]string{"channel"},
 	)
 
-	queueSize = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{Name: "queue_size", Help: "Current message queue size"},
+	mirrorLagSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "mirror_lag_seconds", Help: "Age of the last message shipped to a standby mirror"},
+		[]string{"channel"},
+	)
+
+	mirrorDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "mirror_dropped_total", Help: "Messages that could not be mirrored"},
+		[]string{"channel"},
+	)
+
+	messagesFederated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_federated_total", Help: "Messages republished from a remote Conduktor"},
+		[]string{"channel"},
+	)
+
+	messagesFederationLooped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "messages_federation_looped_total", Help: "Federated messages dropped because they already passed through this Conduktor"},
+		[]string{"channel"},
+	)
+
+	geoLagMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "geo_lag_messages", Help: "Messages queued for a remote region"},
+		[]string{"region"},
+	)
+
+	geoLagSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "geo_lag_seconds", Help: "Age of the oldest message in the last batch shipped to a remote region"},
+		[]string{"region"},
+	)
+
+	geoBatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "geo_batches_total", Help: "Batches shipped to a remote region"},
+		[]string{"region"},
+	)
+
+	geoBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "geo_bytes_total", Help: "Compressed bytes shipped to a remote region"},
+		[]string{"region"},
+	)
+
+	geoDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "geo_dropped_total", Help: "Messages that could not be shipped to a remote region"},
+		[]string{"region"},
+	)
+
+	geoApplied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "geo_applied_total", Help: "Messages applied from a remote region"},
+		[]string{"region"},
+	)
+
+	alertsNotified = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "alerts_notified_total", Help: "Alert events accepted by the webhook"},
+		[]string{"alert", "status"},
+	)
+
+	alertWebhookFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "alert_webhook_failures_total", Help: "Alert events the webhook did not accept"},
+	)
+
+	namespaceMessagesSent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "namespace_messages_sent_total", Help: "Total messages sent to the strands of a namespace"},
+		[]string{"namespace"},
+	)
+
+	namespaceRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "namespace_quota_rejections_total", Help: "Strands and messages rejected because they exceeded a namespace quota"},
+		[]string{"namespace", "quota"},
+	)
+
+	namespaceStrands = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "namespace_strands", Help: "Strands in a namespace"},
+		[]string{"namespace"},
+	)
+
+	namespaceBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "namespace_bytes", Help: "Bytes of unacked messages stored for a namespace"},
+		[]string{"namespace"},
+	)
+
+	backupsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "backups_total", Help: "Scheduled backups of the durable store"},
+		[]string{"status"},
+	)
+
+	backupDuration = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "backup_duration_seconds", Help: "How long the last successful backup took"},
+	)
+
+	backupBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "backup_bytes", Help: "Size of the last successful backup"},
+	)
+
+	backupLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "backup_last_success_timestamp_seconds", Help: "When the last successful backup finished"},
+	)
+
+	listenerRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "listener_rejections_total", Help: "Connections and datagrams rejected by a listener's IP filter"},
+		[]string{"listener"},
+	)
+
+	authFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "auth_failures_total", Help: "Failed authentications, by reason: invalid, forbidden, or locked_out"},
+		[]string{"reason"},
+	)
+
+	authLockouts = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "auth_lockouts_total", Help: "Sources locked out after repeated authentication failures"},
+	)
+
+	aclDenials = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "acl_denials_total", Help: "Operations denied by the ACL"},
+		[]string{"op"},
+	)
+
+	strandOverflows = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "strand_overflows_total", Help: "Sends rejected and messages evicted because a strand reached its MaxBytes"},
+		[]string{"channel", "action"},
+	)
+
+	slowConsumers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "slow_consumers_total", Help: "Checks that found a strand's consumers too slow, by reason"},
+		[]string{"channel", "reason"},
+	)
+
+	slowConsumerActions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "slow_consumer_actions_total", Help: "Slow-consumer policies applied, by policy"},
+		[]string{"channel", "policy"},
+	)
+
+	consumerLagMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "consumer_lag_messages", Help: "Messages a strand's consumers have yet to ack"},
+		[]string{"channel"},
+	)
+
+	wireSendRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "wire_send_retries_total", Help: "Failed wire sends retried under the retry policy"},
+		[]string{"channel"},
+	)
+
+	sendsShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "sends_shed_total", Help: "Sends refused by admission control because their store was under pressure"},
+		[]string{"store"},
+	)
+
+	storeSaveSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "store_save_seconds_average", Help: "Moving average of a store's save times, which admission control compares with its slow_save"},
+		[]string{"store"},
+	)
+
+	sinkMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "sink_messages_total", Help: "Messages written to a sink and acknowledged"},
+		[]string{"sink"},
+	)
+
+	sinkFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "sink_write_failures_total", Help: "Failed writes of a batch to a sink, each retried"},
+		[]string{"sink"},
+	)
+
+	sourceMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "source_messages_total", Help: "Messages imported from a source into its strand"},
+		[]string{"source"},
+	)
+
+	sourceFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "source_failures_total", Help: "Failed reads, sends, and commits of a source, each retried"},
+		[]string{"source"},
+	)
+
+	scheduleRuns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "schedule_runs_total", Help: "Scheduled publishes, by schedule and status"},
+		[]string{"schedule", "status"},
+	)
+
+	dispatchersRunning = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "strand_dispatchers_running", Help: "Strand dispatchers receiving for subscribers; parked ones are not counted"},
+	)
+
+	consumerLagSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "consumer_lag_seconds", Help: "Age of a strand's oldest unacked message"},
 		[]string{"channel"},
 	)
 )
 
+// metricCollectors are every collector condukt updates, shared by all Conduktors, stores, and wires in the process.
+var metricCollectors = []prometheus.Collector{
+	messagesSent, messagesReceived, messagesAcked, messagesDeadLettered, messagesRedriven, messagesForwarded, messagesMoved, quorumTimeouts, strandConflicts, strandsFenced,
+	mirrorLagMessages, mirrorLagSeconds, mirrorDropped,
+	messagesFederated, messagesFederationLooped,
+	geoLagMessages, geoLagSeconds, geoBatches, geoBytes, geoDropped, geoApplied,
+	alertsNotified, alertWebhookFailures,
+	namespaceMessagesSent, namespaceRejections, namespaceStrands, namespaceBytes,
+	backupsTotal, backupDuration, backupBytes, backupLastSuccess,
+	clientRejections, listenerRejections, authFailures, authLockouts, aclDenials, strandOverflows, queueSize, queueBytes,
+	sendStoreSeconds, sendWireSeconds, sendAckSeconds, consumerLagMessages, consumerLagSeconds,
+	slowConsumers, slowConsumerActions, dispatchersRunning, wireSendRetries, sendsShed, storeSaveSeconds,
+	sinkMessages, sinkFailures, sourceMessages, sourceFailures, scheduleRuns,
+	clientSendWindow, clientSendsInFlight,
+}
+
+// metricsRegistry holds the unprefixed metrics, for the dashboard to read, without touching the default registry.
+var metricsRegistry = prometheus.NewRegistry()
+
 func init() {
-	prometheus.MustRegister(messagesSent, messagesReceived, queueSize)
+	metricsRegistry.MustRegister(metricCollectors...)
+}
+
+type metricsOpts struct {
+	prefix string
+	labels prometheus.Labels
+}
+
+// MetricsOption configures MetricsRegister.
+type MetricsOption func(*metricsOpts)
+
+// MetricsPrefix prefixes every metric name, e.g. "condukt_".
+func MetricsPrefix(prefix string) MetricsOption {
+	return func(o *metricsOpts) {
+		o.prefix = prefix
+	}
+}
+
+// MetricsLabels adds constant labels to every metric.
+func MetricsLabels(labels map[string]string) MetricsOption {
+	return func(o *metricsOpts) {
+		o.labels = labels
+	}
+}
+
+// MetricsRegister registers condukt's metrics with reg. Nothing is registered with the default
+// registry until this is called, so apps embedding condukt can keep its metrics apart from their
+// own, or rename them to avoid collisions.
+func MetricsRegister(reg prometheus.Registerer, options ...MetricsOption) error {
+	opts := metricsOpts{}
+	for _, option := range options {
+		option(&opts)
+	}
+	if len(opts.labels) > 0 {
+		reg = prometheus.WrapRegistererWith(opts.labels, reg)
+	}
+	if opts.prefix != "" {
+		reg = prometheus.WrapRegistererWithPrefix(opts.prefix, reg)
+	}
+
+	var errs []error
+	for _, collector := range metricCollectors {
+		if err := reg.Register(collector); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
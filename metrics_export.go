@@ -0,0 +1,369 @@
+package condukt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+// Metrics exporters, for deployments that do not scrape the Prometheus listener.
+const (
+	MetricsExportPushgateway = "pushgateway" // Prometheus text format pushed to a Pushgateway
+	MetricsExportOTLP        = "otlp"        // OTLP over HTTP
+	MetricsExportStatsD      = "statsd"      // StatsD lines over UDP, with DogStatsD tags
+)
+
+// statsdPacket is the most bytes sent in one StatsD datagram, keeping it within a typical MTU.
+const statsdPacket = 1432
+
+// MetricsExportConf configures pushing metrics to a monitoring system.
+type MetricsExportConf struct {
+	Exporter string        `yaml:"exporter"` // pushgateway, otlp, or statsd; empty disables pushing
+	Endpoint string        `yaml:"endpoint"` // Pushgateway URL, OTLP collector host:port (default localhost:4318), or StatsD host:port (default localhost:8125)
+	Insecure bool          `yaml:"insecure"` // Send OTLP over plain HTTP
+	Interval time.Duration `yaml:"interval"` // How often metrics are pushed (default 15s)
+	Job      string        `yaml:"job"`      // Pushgateway job, or OTLP service name (default condukt)
+}
+
+// Validate reports an unknown exporter or a missing Pushgateway URL.
+func (conf MetricsExportConf) Validate() error {
+	switch conf.Exporter {
+	case "", MetricsExportOTLP, MetricsExportStatsD:
+	case MetricsExportPushgateway:
+		if conf.Endpoint == "" {
+			return fmt.Errorf("metrics.export.endpoint: required for the pushgateway exporter")
+		}
+	default:
+		return fmt.Errorf("metrics.export.exporter: unknown exporter %q (want pushgateway, otlp, or statsd)", conf.Exporter)
+	}
+	if conf.Interval < 0 {
+		return fmt.Errorf("metrics.export.interval: must not be negative")
+	}
+	return nil
+}
+
+// MetricsExporter pushes gathered metrics to a monitoring system.
+type MetricsExporter interface {
+	Export(ctx context.Context, families []*dto.MetricFamily) error
+	Shutdown(ctx context.Context) error
+}
+
+// MetricsExporterMake creates the exporter configured by conf.
+func MetricsExporterMake(ctx context.Context, conf MetricsExportConf) (MetricsExporter, error) {
+	job := conf.Job
+	if job == "" {
+		job = "condukt"
+	}
+
+	switch conf.Exporter {
+	case MetricsExportPushgateway:
+		return &MetricsPush{url: conf.Endpoint, job: job}, nil
+	case MetricsExportOTLP:
+		opts := []otlpmetrichttp.Option{}
+		if conf.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err := otlpmetrichttp.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return MetricsOTLPMake(exporter, job), nil
+	case MetricsExportStatsD:
+		addr := conf.Endpoint
+		if addr == "" {
+			addr = "localhost:8125"
+		}
+		return MetricsStatsDMake(addr)
+	default:
+		return nil, fmt.Errorf("unknown metrics exporter %q", conf.Exporter)
+	}
+}
+
+// MetricsExportServe gathers metrics from gatherer and exports them every interval until stop is
+// called, which exports once more and shuts the exporter down.
+func MetricsExportServe(gatherer prometheus.Gatherer, exporter MetricsExporter, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	export := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		families, err := gatherer.Gather()
+		if err == nil {
+			err = exporter.Export(ctx, families)
+		}
+		if err != nil {
+			logger.Warn("Failed to export metrics", zap.Error(err))
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				export()
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				defer cancel()
+				if err := exporter.Shutdown(ctx); err != nil {
+					logger.Warn("Failed to shut down metrics exporter", zap.Error(err))
+				}
+				return
+			case <-ticker.C:
+				export()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// MetricsPush pushes metrics to a Prometheus Pushgateway, replacing those it pushed before.
+type MetricsPush struct {
+	url string
+	job string
+}
+
+// Export pushes families.
+func (m *MetricsPush) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil })
+	return push.New(m.url, m.job).Gatherer(gatherer).PushContext(ctx)
+}
+
+// Shutdown leaves the pushed metrics in place, so the last values remain visible.
+func (m *MetricsPush) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// metricsOTLPExporter is the part of an OTLP metric exporter MetricsOTLP uses.
+type metricsOTLPExporter interface {
+	Export(ctx context.Context, rm *metricdata.ResourceMetrics) error
+	Shutdown(ctx context.Context) error
+}
+
+// MetricsOTLP converts metrics to OpenTelemetry's data model and exports them over OTLP, as
+// cumulative sums, gauges, and explicit-bucket histograms.
+type MetricsOTLP struct {
+	exporter metricsOTLPExporter
+	resource *resource.Resource
+	start    time.Time // Start of every cumulative series
+}
+
+// MetricsOTLPMake exports through exporter, reporting service as the service name.
+func MetricsOTLPMake(exporter metricsOTLPExporter, service string) *MetricsOTLP {
+	return &MetricsOTLP{
+		exporter: exporter,
+		resource: resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)),
+		start:    time.Now(),
+	}
+}
+
+// Export converts and exports families.
+func (m *MetricsOTLP) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	now := time.Now()
+	metrics := []metricdata.Metrics{}
+	for _, family := range families {
+		if data := m.aggregation(family, now); data != nil {
+			metrics = append(metrics, metricdata.Metrics{Name: family.GetName(), Description: family.GetHelp(), Data: data})
+		}
+	}
+
+	return m.exporter.Export(ctx, &metricdata.ResourceMetrics{
+		Resource: m.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope:   instrumentation.Scope{Name: "github.com/jkassis/condukt"},
+			Metrics: metrics,
+		}},
+	})
+}
+
+// aggregation converts family to OpenTelemetry data points, or nil for unsupported types.
+func (m *MetricsOTLP) aggregation(family *dto.MetricFamily, now time.Time) metricdata.Aggregation {
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, metric := range family.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: otlpAttributes(metric), StartTime: m.start, Time: now, Value: metric.GetCounter().GetValue(),
+			})
+		}
+		return sum
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := metricdata.Gauge[float64]{}
+		for _, metric := range family.GetMetric() {
+			value := metric.GetGauge().GetValue()
+			if family.GetType() == dto.MetricType_UNTYPED {
+				value = metric.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: otlpAttributes(metric), Time: now, Value: value,
+			})
+		}
+		return gauge
+	case dto.MetricType_HISTOGRAM:
+		histogram := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, metric := range family.GetMetric() {
+			h := metric.GetHistogram()
+			point := metricdata.HistogramDataPoint[float64]{
+				Attributes: otlpAttributes(metric), StartTime: m.start, Time: now,
+				Count: h.GetSampleCount(), Sum: h.GetSampleSum(),
+			}
+			// Prometheus buckets count every sample up to their bound; OTLP buckets count only their own
+			previous := uint64(0)
+			for _, bucket := range h.GetBucket() {
+				if math.IsInf(bucket.GetUpperBound(), 1) {
+					continue
+				}
+				point.Bounds = append(point.Bounds, bucket.GetUpperBound())
+				point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+				previous = bucket.GetCumulativeCount()
+			}
+			point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-previous)
+			histogram.DataPoints = append(histogram.DataPoints, point)
+		}
+		return histogram
+	default:
+		return nil
+	}
+}
+
+// Shutdown flushes and closes the OTLP exporter.
+func (m *MetricsOTLP) Shutdown(ctx context.Context) error {
+	return m.exporter.Shutdown(ctx)
+}
+
+// otlpAttributes converts metric's labels to attributes.
+func otlpAttributes(metric *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		kvs = append(kvs, attribute.String(label.GetName(), label.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
+
+// MetricsStatsD sends metrics as StatsD lines: gauges as gauges, and counters, histogram counts,
+// and histogram sums as counters of their increase since the previous export. Labels are sent
+// as DogStatsD tags.
+type MetricsStatsD struct {
+	conn net.Conn
+	last map[string]float64 // Series -> counter value at the previous export
+}
+
+// MetricsStatsDMake sends to the StatsD server at addr.
+func MetricsStatsDMake(addr string) (*MetricsStatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsStatsD{conn: conn, last: make(map[string]float64)}, nil
+}
+
+// Export sends families, batching lines into datagrams.
+func (m *MetricsStatsD) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	lines := []string{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			tags := statsdTags(metric)
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = m.count(lines, family.GetName(), tags, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				lines = append(lines, statsdLine(family.GetName(), metric.GetGauge().GetValue(), "g", tags))
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, statsdLine(family.GetName(), metric.GetUntyped().GetValue(), "g", tags))
+			case dto.MetricType_HISTOGRAM:
+				lines = m.count(lines, family.GetName()+"_count", tags, float64(metric.GetHistogram().GetSampleCount()))
+				lines = m.count(lines, family.GetName()+"_sum", tags, metric.GetHistogram().GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				lines = m.count(lines, family.GetName()+"_count", tags, float64(metric.GetSummary().GetSampleCount()))
+				lines = m.count(lines, family.GetName()+"_sum", tags, metric.GetSummary().GetSampleSum())
+			}
+		}
+	}
+
+	var packet bytes.Buffer
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdPacket {
+			if _, err := m.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		if _, err := m.conn.Write(packet.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// count appends a counter line for the increase of a cumulative value since the previous export.
+func (m *MetricsStatsD) count(lines []string, name, tags string, value float64) []string {
+	series := name + "|" + tags
+	delta := value - m.last[series]
+	m.last[series] = value
+	if delta < 0 {
+		delta = value // Reset since the previous export
+	}
+	if delta == 0 {
+		return lines
+	}
+	return append(lines, statsdLine(name, delta, "c", tags))
+}
+
+// Shutdown closes the connection.
+func (m *MetricsStatsD) Shutdown(ctx context.Context) error {
+	return m.conn.Close()
+}
+
+// statsdLine formats one StatsD line.
+func statsdLine(name string, value float64, kind, tags string) string {
+	line := name + ":" + strconv.FormatFloat(value, 'g', -1, 64) + "|" + kind
+	if tags != "" {
+		line += "|#" + tags
+	}
+	return line
+}
+
+// statsdTags formats metric's labels as sorted DogStatsD tags.
+func statsdTags(metric *dto.Metric) string {
+	tags := make([]string, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		tags = append(tags, label.GetName()+":"+label.GetValue())
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ",")
+}
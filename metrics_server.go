@@ -0,0 +1,55 @@
+package condukt
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsConfig configures the Prometheus listener.
+type MetricsConfig struct {
+	Addr    string `yaml:"addr"`     // Listen address; empty disables the listener
+	Path    string `yaml:"path"`     // Scrape path
+	TLSCert string `yaml:"tls_cert"` // Certificate file; with TLSKey, serves HTTPS
+	TLSKey  string `yaml:"tls_key"`  // Private key file
+
+	Prefix string            `yaml:"prefix"` // Prepended to every metric name, e.g. condukt_
+	Labels map[string]string `yaml:"labels"` // Constant labels added to every metric, e.g. cluster: east
+
+	Export MetricsExportConf `yaml:"export"` // Pushes metrics to systems that do not scrape Prometheus
+
+	ReconcileInterval time.Duration `yaml:"reconcile_interval"` // How often strand depths and consumer lag are recounted
+}
+
+// MetricsServer serves Prometheus metrics over HTTP or HTTPS.
+type MetricsServer struct {
+	conf   MetricsConfig
+	server *http.Server
+}
+
+// MetricsServerMake creates a metrics server. Call Serve to start it and Shutdown to stop it gracefully.
+func MetricsServerMake(conf MetricsConfig) *MetricsServer {
+	if conf.Path == "" {
+		conf.Path = "/metrics"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(conf.Path, promhttp.Handler())
+	return &MetricsServer{conf: conf, server: &http.Server{Handler: mux}}
+}
+
+// Serve serves metrics on lis until Shutdown, returning http.ErrServerClosed after a graceful shutdown.
+func (m *MetricsServer) Serve(lis net.Listener) error {
+	if m.conf.TLSCert != "" {
+		return m.server.ServeTLS(lis, m.conf.TLSCert, m.conf.TLSKey)
+	}
+	return m.server.Serve(lis)
+}
+
+// Shutdown stops accepting scrapes and waits for in-progress scrapes to finish or ctx to expire.
+func (m *MetricsServer) Shutdown(ctx context.Context) error {
+	return m.server.Shutdown(ctx)
+}
@@ -0,0 +1,69 @@
+package condukt
+
+import "context"
+
+// Handlers wrapped by Middleware. Each does the rest of its operation: the next middleware's
+// hook, or the Conduktor's own work.
+type (
+	SendHandler    func(ctx context.Context, msg *Msg) error
+	DeliverHandler func(ctx context.Context, msg *Msg) error
+	AckHandler     func(ctx context.Context, strandID, msgID string) error
+)
+
+// Middleware hooks into a Conduktor's sends, deliveries, and acknowledgements, for validation,
+// enrichment, policy, or metrics. Each hook wraps the handler that does the rest of the operation:
+// it may change the message before calling next, or return an error instead to refuse it. Hooks
+// run without the Conduktor's lock, so they may call back into it. Nil hooks pass through.
+//
+// Send hooks must not keep msg after returning: the Conduktor reuses it for later sends.
+type Middleware struct {
+	// Send sees each message sent with Send, with its ID and headers set, before it is stored
+	// and transmitted. ActorFrom(ctx, "") returns the sender's identity.
+	Send func(next SendHandler) SendHandler
+
+	// Deliver sees each message Receive takes from the wire before it is returned. Messages it
+	// refuses stay unacked in their store. ActorFrom(ctx, "") returns the receiver's identity.
+	Deliver func(next DeliverHandler) DeliverHandler
+
+	// Acknowledge sees each Acknowledge before the message is removed from its store.
+	Acknowledge func(next AckHandler) AckHandler
+}
+
+// Use adds middleware to the Conduktor. The first middleware added runs outermost.
+func (c *Conduktor) Use(m Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middleware = append(c.middleware, m)
+}
+
+// sendHandler wraps send in the Send hooks.
+func (c *Conduktor) sendHandler(send SendHandler) SendHandler {
+	return middlewareWrap(c.middlewares(), func(m Middleware) func(SendHandler) SendHandler { return m.Send }, send)
+}
+
+// deliverHandler wraps deliver in the Deliver hooks.
+func (c *Conduktor) deliverHandler(deliver DeliverHandler) DeliverHandler {
+	return middlewareWrap(c.middlewares(), func(m Middleware) func(DeliverHandler) DeliverHandler { return m.Deliver }, deliver)
+}
+
+// ackHandler wraps ack in the Acknowledge hooks.
+func (c *Conduktor) ackHandler(ack AckHandler) AckHandler {
+	return middlewareWrap(c.middlewares(), func(m Middleware) func(AckHandler) AckHandler { return m.Acknowledge }, ack)
+}
+
+// middlewares returns the middleware in use.
+func (c *Conduktor) middlewares() []Middleware {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.middleware
+}
+
+// middlewareWrap wraps h in the hook of each middleware, the first outermost.
+func middlewareWrap[H any](middleware []Middleware, hook func(Middleware) func(H) H, h H) H {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if wrap := hook(middleware[i]); wrap != nil {
+			h = wrap(h)
+		}
+	}
+	return h
+}
@@ -0,0 +1,175 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// mirrorPrefix names the wire channel a standby reads mirrored messages from.
+const mirrorPrefix = "_mirror."
+
+// MirrorConf describes where a strand's messages are copied for warm standby.
+type MirrorConf struct {
+	Wire   Wire   // Wire reaching the standby Conduktor
+	Strand string // Strand name on the standby
+	Buffer int    // Messages queued before the mirror starts dropping (default 10000)
+}
+
+// mirror asynchronously copies messages of one strand to a standby.
+type mirror struct {
+	strandID string
+	conf     MirrorConf
+	queue    chan Msg
+	done     chan struct{} // Closed to stop, discarding queued messages
+	flush    chan struct{} // Closed to stop after shipping queued messages
+	stopped  chan struct{} // Closed when run returns
+	lag      atomic.Int64  // Seconds between send and shipment of the last mirrored message
+	errs     *errorHooks
+	log      *zap.Logger
+}
+
+// MirrorAdd starts copying every message accepted on strandID to a strand on another Conduktor.
+func (c *Conduktor) MirrorAdd(strandID string, conf MirrorConf) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conf.Wire == nil || conf.Strand == "" {
+		return errors.New("mirror requires a wire and a target strand")
+	}
+	if _, exists := c.mirrors[strandID]; exists {
+		return errors.New("strand already mirrored")
+	}
+	if conf.Buffer <= 0 {
+		conf.Buffer = 10000
+	}
+
+	m := &mirror{
+		strandID: strandID,
+		conf:     conf,
+		queue:    make(chan Msg, conf.Buffer),
+		done:     make(chan struct{}),
+		flush:    make(chan struct{}),
+		stopped:  make(chan struct{}),
+		errs:     c.errs,
+		log:      c.log,
+	}
+	c.mirrors[strandID] = m
+	go m.run()
+
+	c.log.Info("Mirror added", zap.String("strand", strandID), zap.String("target", conf.Strand))
+	return nil
+}
+
+// MirrorRemove stops mirroring strandID. Queued messages are discarded.
+func (c *Conduktor) MirrorRemove(strandID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, exists := c.mirrors[strandID]
+	if !exists {
+		return errors.New("strand not mirrored")
+	}
+
+	close(m.done)
+	delete(c.mirrors, strandID)
+	mirrorLagMessages.DeleteLabelValues(strandID)
+	mirrorLagSeconds.DeleteLabelValues(strandID)
+
+	c.log.Info("Mirror removed", zap.String("strand", strandID))
+	return nil
+}
+
+// MirrorServe accepts messages mirrored to strandID from a primary over wire, until stop is called.
+func (c *Conduktor) MirrorServe(wire Wire, strandID string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		channel := mirrorPrefix + strandID
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			envelope, err := wire.ReceiveMessage(ctx, channel)
+			if err != nil {
+				// The mirror channel may not exist until the primary sends to it
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+
+			msg, err := envelopeOpen(envelope)
+			if err != nil {
+				c.log.Warn("Failed to unmarshal mirrored message", zap.Error(err))
+				continue
+			}
+			msg.Strand = strandID
+
+			c.mu.Lock()
+			err = c.accept(context.Background(), msg)
+			c.mu.Unlock()
+			if err != nil {
+				c.log.Error("Failed to accept mirrored message", zap.String("strand", strandID), zap.Error(err))
+				c.errs.report(ErrorSourceMirror, strandID, msg.ID, err)
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// enqueue hands msg to the mirror without blocking the send path.
+func (m *mirror) enqueue(msg Msg) {
+	select {
+	case m.queue <- msg:
+		mirrorLagMessages.WithLabelValues(m.strandID).Set(float64(len(m.queue)))
+	default:
+		mirrorDropped.WithLabelValues(m.strandID).Inc()
+		m.log.Warn("Mirror buffer full", zap.String("strand", m.strandID))
+	}
+}
+
+// run ships queued messages to the standby.
+func (m *mirror) run() {
+	defer close(m.stopped)
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.flush:
+			for {
+				select {
+				case msg := <-m.queue:
+					m.ship(msg)
+				default:
+					return
+				}
+			}
+		case msg := <-m.queue:
+			m.ship(msg)
+		}
+	}
+}
+
+// ship sends one message to the standby.
+func (m *mirror) ship(msg Msg) {
+	envelope, err := envelopeMake(mirrorPrefix+m.conf.Strand, msg)
+	if err == nil {
+		err = m.conf.Wire.SendMessage(context.Background(), envelope)
+	}
+	if err != nil {
+		mirrorDropped.WithLabelValues(m.strandID).Inc()
+		m.log.Error("Mirror send failed", zap.String("strand", m.strandID), zap.Error(err))
+		m.errs.report(ErrorSourceMirror, m.strandID, msg.ID, err)
+		return
+	}
+
+	lag := time.Now().Unix() - msg.Timestamp
+	m.lag.Store(lag)
+	mirrorLagMessages.WithLabelValues(m.strandID).Set(float64(len(m.queue)))
+	mirrorLagSeconds.WithLabelValues(m.strandID).Set(float64(lag))
+}
@@ -1,9 +1,54 @@
-package main
-
-type Msg struct {
-	ID        string
-	Strand    string
-	Payload   string
-	Acked     bool
-	Timestamp int64
+package condukt
+
+import "github.com/jkassis/condukt/wire"
+
+// Msg is a message sent through a Conduktor.
+type Msg = wire.Msg
+
+// MsgVersion is the schema version messages are encoded with.
+const MsgVersion = wire.MsgVersion
+
+// ErrMsgVersion is returned when decoding a message encoded by a newer schema version.
+var ErrMsgVersion = wire.ErrMsgVersion
+
+// Well-known message headers.
+const (
+	HeaderHops   = "x-condukt-hops"   // Comma-separated IDs of the Conduktors a message has passed through
+	HeaderRegion = "x-condukt-region" // Region that shipped a geo-replicated batch, or first accepted a replicated message
+	HeaderNode   = "x-condukt-node"   // Cluster node that sent an internal envelope
+	HeaderOp     = "x-condukt-op"     // Operation a cluster node should apply to an internal envelope
+	HeaderEpoch  = "x-condukt-epoch"  // Sender's ownership epoch for the envelope's strand
+	HeaderQuorum = "x-condukt-quorum" // How long the sender of a forwarded envelope waits for the owner's replica quorum
+
+	HeaderTraceParent = "traceparent"    // W3C trace context of the span that sent the message
+	HeaderTraceState  = "tracestate"     // W3C vendor-specific trace state
+	HeaderDebug       = wire.HeaderDebug // "true" logs the message at every hop, whatever the log level
+)
+
+// SendHeaders adds headers to the sent message, like HeaderDebug. Headers condukt sets itself,
+// like HeaderHops and the trace context, take precedence.
+func SendHeaders(headers map[string]string) SendOption {
+	return func(o *sendOpts) {
+		o.headers = headers
+	}
+}
+
+// envelopeMake wraps msg for node-to-node transfer on an internal wire channel.
+func envelopeMake(channel string, msg Msg) (Msg, error) {
+	data, err := wire.MsgEncodeJSON(msg) // Payloads are text on every wire
+	if err != nil {
+		return Msg{}, err
+	}
+
+	return Msg{
+		ID:        msg.ID,
+		Strand:    channel,
+		Payload:   string(data),
+		Timestamp: msg.Timestamp,
+	}, nil
+}
+
+// envelopeOpen unwraps a message created by envelopeMake.
+func envelopeOpen(envelope *Msg) (Msg, error) {
+	return wire.MsgDecode([]byte(envelope.Payload))
 }
@@ -0,0 +1,226 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jkassis/condukt/internal/rate"
+	"go.uber.org/zap"
+)
+
+// NamespaceSep separates a strand's namespace from the rest of its ID, as in "team-a/orders".
+const NamespaceSep = "/"
+
+// Quotas a namespace can exceed, as reported in ErrQuotaExceeded and the rejection metric.
+const (
+	QuotaStrands = "strands"
+	QuotaBytes   = "bytes"
+	QuotaRate    = "rate"
+)
+
+// ErrQuotaExceeded is returned when an operation would take a namespace over its quota.
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// Namespace returns the namespace of a strand: the part of its ID before the first NamespaceSep,
+// or "" for strands outside any namespace. Dead-letter strands share their strand's namespace.
+func Namespace(strandID string) string {
+	ns, _, found := strings.Cut(strandID, NamespaceSep)
+	if !found {
+		return ""
+	}
+	return ns
+}
+
+// NamespaceQuota caps the resources of the strands in a namespace. Zero values disable a quota.
+type NamespaceQuota struct {
+	MaxStrands int     `yaml:"max_strands" json:"max_strands"` // Most strands in the namespace
+	MaxBytes   int64   `yaml:"max_bytes" json:"max_bytes"`     // Most bytes of unacked messages across the namespace's strands
+	MaxRate    float64 `yaml:"max_rate" json:"max_rate"`       // Most messages sent per second, with bursts of up to one second's worth
+}
+
+// NamespaceInfo describes a namespace's quota and current usage.
+type NamespaceInfo struct {
+	Name    string         `json:"name"`
+	Quota   NamespaceQuota `json:"quota"`
+	Strands int            `json:"strands"`
+	Depth   int            `json:"depth"`
+	Bytes   int64          `json:"bytes"`
+}
+
+// namespace tracks the quota of a namespace and the rate limiter enforcing it.
+type namespace struct {
+	quota NamespaceQuota
+	rate  *rate.Bucket // Enforces MaxRate; nil without one
+}
+
+// ValidNamespace checks that name can be used as a namespace.
+func ValidNamespace(name string) error {
+	if name == "" || strings.Contains(name, NamespaceSep) {
+		return fmt.Errorf("invalid namespace %q: must be non-empty and not contain %q", name, NamespaceSep)
+	}
+	return nil
+}
+
+// SetNamespace sets the quota of a namespace, replacing any previous quota. Existing strands
+// are kept even if they exceed it; the quota applies to new strands and messages.
+func (c *Conduktor) SetNamespace(name string, quota NamespaceQuota) error {
+	if err := ValidNamespace(name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ns := &namespace{quota: quota}
+	if quota.MaxRate > 0 {
+		ns.rate = rate.BucketMake(quota.MaxRate, max(quota.MaxRate, 1))
+	}
+	c.namespaces[name] = ns
+	c.log.Info("Namespace quota set", zap.String("namespace", name), zap.Int("maxStrands", quota.MaxStrands),
+		zap.Int64("maxBytes", quota.MaxBytes), zap.Float64("maxRate", quota.MaxRate))
+	return nil
+}
+
+// NamespaceRemove removes a namespace's quota. Its strands are kept.
+func (c *Conduktor) NamespaceRemove(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.namespaces[name]; !exists {
+		return fmt.Errorf("namespace %q not found", name)
+	}
+	delete(c.namespaces, name)
+	namespaceStrands.DeleteLabelValues(name)
+	namespaceBytes.DeleteLabelValues(name)
+	c.log.Info("Namespace quota removed", zap.String("namespace", name))
+	return nil
+}
+
+// Namespaces lists namespaces that have a quota or strands, sorted by name, and updates their gauges.
+func (c *Conduktor) Namespaces() ([]NamespaceInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	usage, err := c.namespaceUsage()
+	if err != nil {
+		return nil, err
+	}
+	for name, ns := range c.namespaces {
+		if _, exists := usage[name]; !exists {
+			usage[name] = &NamespaceInfo{Name: name}
+		}
+		usage[name].Quota = ns.quota
+	}
+
+	infos := make([]NamespaceInfo, 0, len(usage))
+	for _, info := range usage {
+		infos = append(infos, *info)
+		if info.Name != "" {
+			namespaceStrands.WithLabelValues(info.Name).Set(float64(info.Strands))
+			namespaceBytes.WithLabelValues(info.Name).Set(float64(info.Bytes))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// NamespaceGet describes a single namespace.
+func (c *Conduktor) NamespaceGet(name string) (NamespaceInfo, error) {
+	infos, err := c.Namespaces()
+	if err != nil {
+		return NamespaceInfo{}, err
+	}
+	for _, info := range infos {
+		if info.Name == name {
+			return info, nil
+		}
+	}
+	return NamespaceInfo{}, fmt.Errorf("namespace %q not found", name)
+}
+
+// namespaceUsage totals the strands, depth, and bytes of every namespace. Strands outside a
+// namespace are totaled under "". Callers must hold c.mu.
+func (c *Conduktor) namespaceUsage() (map[string]*NamespaceInfo, error) {
+	usage := make(map[string]*NamespaceInfo)
+	for _, store := range []Store{c.durable, c.volatile} {
+		strands, err := store.ListStrands(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for strandID := range strands {
+			name := Namespace(strandID)
+			info, exists := usage[name]
+			if !exists {
+				info = &NamespaceInfo{Name: name}
+				usage[name] = info
+			}
+			depth, err := store.Depth(context.Background(), strandID)
+			if err != nil {
+				return nil, err
+			}
+			bytes, err := store.Bytes(context.Background(), strandID)
+			if err != nil {
+				return nil, err
+			}
+			info.Strands++
+			info.Depth += depth
+			info.Bytes += bytes
+		}
+	}
+	return usage, nil
+}
+
+// quotaStrands checks that one more strand fits in strandID's namespace. Callers must hold c.mu.
+func (c *Conduktor) quotaStrands(strandID string) error {
+	name := Namespace(strandID)
+	ns, exists := c.namespaces[name]
+	if !exists || ns.quota.MaxStrands <= 0 {
+		return nil
+	}
+
+	usage, err := c.namespaceUsage()
+	if err != nil {
+		return err
+	}
+	if info, exists := usage[name]; exists && info.Strands >= ns.quota.MaxStrands {
+		return c.quotaReject(name, QuotaStrands)
+	}
+	return nil
+}
+
+// quotaSend checks that a message of size bytes fits in strandID's namespace and takes a token
+// from its rate limiter. Callers must hold c.mu, or read-lock it, in which case concurrent sends to
+// other strands of the namespace may overshoot MaxBytes between them.
+func (c *Conduktor) quotaSend(strandID string, size int64) error {
+	name := Namespace(strandID)
+	ns, exists := c.namespaces[name]
+	if !exists {
+		return nil
+	}
+
+	if ns.quota.MaxBytes > 0 {
+		usage, err := c.namespaceUsage()
+		if err != nil {
+			return err
+		}
+		if info, exists := usage[name]; exists && info.Bytes+size > ns.quota.MaxBytes {
+			return c.quotaReject(name, QuotaBytes)
+		}
+	}
+
+	if ns.rate != nil && !ns.rate.Take(1, time.Now()) {
+		return c.quotaReject(name, QuotaRate)
+	}
+	return nil
+}
+
+// quotaReject counts and reports a quota rejection.
+func (c *Conduktor) quotaReject(name, quota string) error {
+	namespaceRejections.WithLabelValues(name, quota).Inc()
+	c.log.Warn("Namespace quota exceeded", zap.String("namespace", name), zap.String("quota", quota))
+	return fmt.Errorf("%w: %s %s", ErrQuotaExceeded, name, quota)
+}
@@ -0,0 +1,82 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ErrStrandFull is returned when a send would take a strand over its MaxBytes.
+var ErrStrandFull = errors.New("strand is full")
+
+// strandConf returns a strand's config, loading it from store if it was not added through this
+// Conduktor or cached by lockStrand. Callers must hold c.mu, or read-lock it.
+func (c *Conduktor) strandConf(store Store, strandID string) (StrandConf, error) {
+	if conf, exists := c.confs[strandID]; exists {
+		return conf, nil
+	}
+	strands, err := store.ListStrands(context.Background())
+	if err != nil {
+		return StrandConf{}, err
+	}
+	return strands[strandID], nil
+}
+
+// overflow makes room for msg under its strand's MaxBytes, evicting the oldest messages or
+// rejecting msg as the strand's Overflow says. Callers must hold c.mu, or read-lock it and hold the
+// strand's lock.
+func (c *Conduktor) overflow(msg Msg) error {
+	store, err := c.getStore(msg.Strand)
+	if err != nil {
+		return err
+	}
+	conf, err := c.strandConf(store, msg.Strand)
+	if err != nil || conf.MaxBytes <= 0 {
+		return err
+	}
+
+	size := msg.Size()
+	stored, err := store.Bytes(context.Background(), msg.Strand)
+	if err != nil {
+		return err
+	}
+	if stored+size <= conf.MaxBytes {
+		return nil
+	}
+	if conf.Overflow != OverflowEvict || size > conf.MaxBytes {
+		strandOverflows.WithLabelValues(msg.Strand, OverflowReject).Inc()
+		return fmt.Errorf("%w: %s holds %d of %d bytes", ErrStrandFull, msg.Strand, stored, conf.MaxBytes)
+	}
+
+	// Evict the oldest messages, a batch at a time, until msg fits
+	for stored+size > conf.MaxBytes {
+		oldest, err := store.Peek(context.Background(), msg.Strand, 16)
+		if err != nil {
+			return err
+		}
+		if len(oldest) == 0 {
+			break
+		}
+		for _, victim := range oldest {
+			if err := store.Acknowledge(context.Background(), msg.Strand, victim.ID); err != nil {
+				return err
+			}
+			if c.cluster != nil {
+				c.release(msg.Strand, victim.ID, conf.ReplicationFactor)
+			}
+			c.deliveries.removed(msg.Strand, victim.ID, DeliveryEvicted)
+			strandOverflows.WithLabelValues(msg.Strand, OverflowEvict).Inc()
+			c.log.Debug("Message evicted", zap.String("strand", msg.Strand), zap.String("msgID", victim.ID))
+
+			if stored, err = store.Bytes(context.Background(), msg.Strand); err != nil {
+				return err
+			}
+			if stored+size <= conf.MaxBytes {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package condukt
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Assigner decides which node owns each strand.
+type Assigner interface {
+	Assign(strands []string, nodes []Node) map[string]string // Strand -> node ID
+}
+
+// RendezvousAssigner assigns each strand to the member with the highest hash weight,
+// so membership changes only move the strands of nodes that joined or left.
+type RendezvousAssigner struct{}
+
+// Assign implements Assigner. Standby and draining nodes are never assigned strands.
+func (RendezvousAssigner) Assign(strands []string, nodes []Node) map[string]string {
+	owners := make(map[string]string, len(strands))
+	for _, strandID := range strands {
+		if ranked := rendezvousRank(strandID, nodes); len(ranked) > 0 {
+			owners[strandID] = ranked[0].ID
+		}
+	}
+	return owners
+}
+
+// rendezvousRank orders the nodes that may own strands by their hash weight for strandID, highest first.
+func rendezvousRank(strandID string, nodes []Node) []Node {
+	type weighted struct {
+		node   Node
+		weight uint64
+	}
+
+	candidates := make([]weighted, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Role == NodeRoleStandby || node.Role == NodeRoleDraining {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(node.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(strandID))
+		candidates = append(candidates, weighted{node, mix64(h.Sum64())})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].weight > candidates[j].weight })
+
+	ranked := make([]Node, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.node
+	}
+	return ranked
+}
+
+// mix64 is the murmur3 finalizer. FNV alone weights similar keys too uniformly for rendezvous hashing.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// RebalanceConf enables automatic rebalancing on membership changes.
+type RebalanceConf struct {
+	Assigner       Assigner // Defaults to RendezvousAssigner
+	MovesPerSecond int      // Throttle for moving stored messages to a new owner (default 1000)
+}
+
+// SetRebalance enables automatic rebalancing whenever nodes join or leave, and rebalances immediately.
+func (cl *Cluster) SetRebalance(conf RebalanceConf) {
+	if conf.Assigner == nil {
+		conf.Assigner = RendezvousAssigner{}
+	}
+	if conf.MovesPerSecond <= 0 {
+		conf.MovesPerSecond = 1000
+	}
+
+	cl.mu.Lock()
+	cl.rebalance = &conf
+	cl.mu.Unlock()
+
+	cl.Rebalance()
+}
+
+// Track makes strandID eligible for automatic assignment.
+func (cl *Cluster) Track(strandID string) {
+	cl.mu.Lock()
+	cl.strands[strandID] = true
+	cl.mu.Unlock()
+
+	cl.Rebalance()
+}
+
+// Rebalance recomputes strand ownership with the configured Assigner and hands off
+// the local node's messages for strands it no longer owns. It is a no-op unless rebalancing is enabled.
+func (cl *Cluster) Rebalance() {
+	cl.mu.Lock()
+	if cl.rebalance == nil {
+		cl.mu.Unlock()
+		return
+	}
+
+	strands := make([]string, 0, len(cl.strands))
+	for strandID := range cl.strands {
+		strands = append(strands, strandID)
+	}
+	nodes := make([]Node, 0, len(cl.nodes))
+	for _, node := range cl.nodes {
+		nodes = append(nodes, node)
+	}
+
+	type move struct{ strandID, to string }
+	var moves []move
+	for strandID, owner := range cl.rebalance.Assigner.Assign(strands, nodes) {
+		previous, assigned := cl.owners[strandID]
+		if !assigned {
+			previous = cl.self
+		}
+		if previous == cl.self && owner != cl.self {
+			moves = append(moves, move{strandID, owner})
+		}
+		if previous != owner {
+			cl.epochs[strandID]++
+		}
+		cl.owners[strandID] = owner
+	}
+	interval := time.Second / time.Duration(cl.rebalance.MovesPerSecond)
+	cl.mu.Unlock()
+
+	cl.log.Info("Cluster rebalanced", zap.Int("strands", len(strands)), zap.Int("nodes", len(nodes)), zap.Int("moves", len(moves)))
+	cl.notify()
+	for _, m := range moves {
+		cl.move(m.strandID, m.to, interval)
+	}
+}
+
+// move hands off the local messages of strandID to a new owner in the background.
+func (cl *Cluster) move(strandID, to string, interval time.Duration) {
+	cl.mu.RLock()
+	handoff := cl.handoff
+	cl.mu.RUnlock()
+	if handoff == nil {
+		return
+	}
+
+	cl.moving.Add(1)
+	go func() {
+		defer cl.moving.Done()
+		handoff(strandID, to, interval)
+	}()
+}
+
+// handoff moves the locally stored messages of strandID to its new owner, one message per interval.
+func (c *Conduktor) handoff(cl *Cluster, strandID, to string, interval time.Duration) {
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return
+	}
+
+	iterator, err := store.UnackedIterator(context.Background())
+	if err != nil {
+		c.log.Error("Failed to read strand for handoff", zap.String("strand", strandID), zap.String("node", to), zap.Error(err))
+		c.errs.report(ErrorSourceCluster, strandID, "", err)
+		return
+	}
+	defer iterator.Close()
+
+	moved := 0
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		msg, hasNext := iterator.Next()
+		if !hasNext {
+			break
+		}
+		if msg.Strand != strandID {
+			continue
+		}
+
+		<-ticker.C
+		if err := cl.forward(to, *msg); err != nil {
+			c.log.Error("Failed to move message", zap.String("strand", strandID), zap.String("node", to), zap.Error(err))
+			c.errs.report(ErrorSourceCluster, strandID, msg.ID, err)
+			continue
+		}
+		if err := store.Acknowledge(context.Background(), strandID, msg.ID); err != nil {
+			c.log.Warn("Failed to release moved message", zap.String("strand", strandID), zap.String("msgID", msg.ID), zap.Error(err))
+			c.errs.report(ErrorSourceCluster, strandID, msg.ID, err)
+		}
+		messagesMoved.WithLabelValues(strandID).Inc()
+		moved++
+	}
+
+	c.log.Info("Strand handed off", zap.String("strand", strandID), zap.String("node", to), zap.Int("messages", moved))
+}
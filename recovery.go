@@ -0,0 +1,125 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+// RecoveryConf configures RecoverUnackedMessages.
+type RecoveryConf struct {
+	Workers int `yaml:"workers"` // Strands resent in parallel; 0 is GOMAXPROCS
+
+	// ArenaChunk decodes recovered messages into a wire.Arena allocating this many bytes at a time,
+	// easing the garbage collector when recovering millions of messages; 0 decodes each alone.
+	ArenaChunk int `yaml:"arena_chunk"`
+}
+
+// Validate reports negative settings.
+func (conf RecoveryConf) Validate() error {
+	var errs []error
+	if conf.Workers < 0 {
+		errs = append(errs, errors.New("recovery.workers: must not be negative"))
+	}
+	if conf.ArenaChunk < 0 {
+		errs = append(errs, errors.New("recovery.arena_chunk: must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// SetRecovery replaces the Conduktor's recovery config.
+func (c *Conduktor) SetRecovery(conf RecoveryConf) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recovery = conf
+}
+
+// RecoverUnackedMessages iterates through unacknowledged messages and resends them: the durable
+// store's, then the volatile store's, such as those restored from a snapshot. Strands are
+// partitioned across the recovery workers, so each strand's messages are resent in order.
+func (c *Conduktor) RecoverUnackedMessages() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Retrieve an iterator for unacked messages of each store
+	ctx := context.Background()
+	if c.recovery.ArenaChunk > 0 {
+		ctx = wire.WithArena(ctx, wire.ArenaMake(c.recovery.ArenaChunk))
+	}
+	var iterators []UnackedMessageIterator
+	defer func() {
+		for _, iterator := range iterators {
+			iterator.Close()
+		}
+	}()
+	for _, store := range []Store{c.durable, c.volatile} {
+		iterator, err := store.UnackedIterator(ctx)
+		if err != nil {
+			c.log.Error("Failed to get UnackedIterator", zap.Error(err))
+			return err
+		}
+		iterators = append(iterators, iterator)
+	}
+
+	workers := c.recovery.Workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	c.log.Info("Starting recovery of unacked messages", zap.Int("workers", workers))
+
+	var recovered, failed atomic.Int64
+	var wg sync.WaitGroup
+	queues := make([]chan Msg, workers)
+	for i := range queues {
+		queues[i] = make(chan Msg, 64)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range queues[i] {
+				if err := c.transmit(MsgContext(context.Background(), msg), msg, nil); err != nil {
+					c.log.Error("Failed to resend unacked message",
+						zap.String("msgID", msg.ID),
+						zap.Error(err),
+					)
+					c.errs.report(ErrorSourceRecovery, msg.Strand, msg.ID, err)
+					failed.Add(1)
+					continue
+				}
+				recovered.Add(1)
+				c.log.Debug("Successfully recovered message",
+					zap.String("msgID", msg.ID),
+				)
+			}
+		}()
+	}
+
+	// Hand each message to its strand's worker
+	for _, iterator := range iterators {
+		for {
+			msg, hasNext := iterator.Next()
+			if !hasNext {
+				break
+			}
+			h := fnv.New32a()
+			h.Write([]byte(msg.Strand))
+			queues[h.Sum32()%uint32(workers)] <- *msg
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	c.recovered.Store(true)
+	c.log.Info("Completed recovery of unacked messages",
+		zap.Int64("recovered", recovered.Load()),
+		zap.Int64("failed", failed.Load()),
+	)
+	return nil
+}
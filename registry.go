@@ -0,0 +1,188 @@
+package condukt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Cluster operations for the strand registry.
+const (
+	opRegister   = "register"   // A node created a strand
+	opUnregister = "unregister" // A node removed a strand
+	opConflict   = "conflict"   // The receiver holds an incompatible config for a registered strand
+)
+
+// ErrStrandConflict is returned by StrandAdd when the strand exists in the cluster with an incompatible config.
+var ErrStrandConflict = errors.New("strand exists in the cluster with an incompatible config")
+
+// StrandConflict records a strand created with incompatible configs on two nodes.
+type StrandConflict struct {
+	Strand string
+	Node   string // Node holding the remote definition
+	Local  StrandConf
+	Remote StrandConf
+}
+
+// registryEntry is a strand definition and the node that created it.
+type registryEntry struct {
+	conf StrandConf
+	node string
+}
+
+// Registry returns the strand configurations known across the cluster.
+func (cl *Cluster) Registry() map[string]StrandConf {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	registry := make(map[string]StrandConf, len(cl.registry))
+	for strandID, entry := range cl.registry {
+		registry[strandID] = entry.conf
+	}
+	return registry
+}
+
+// Conflicts returns the strands that were created with incompatible configs on different nodes.
+func (cl *Cluster) Conflicts() []StrandConflict {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	conflicts := append([]StrandConflict(nil), cl.conflicts...)
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Strand < conflicts[j].Strand })
+	return conflicts
+}
+
+// registryCheck rejects a local definition that conflicts with the registry.
+func (cl *Cluster) registryCheck(strandID string, conf StrandConf) error {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	if entry, exists := cl.registry[strandID]; exists && entry.conf != conf {
+		return ErrStrandConflict
+	}
+	return nil
+}
+
+// registryPublish records a locally created strand and announces it to every peer.
+func (cl *Cluster) registryPublish(strandID string, conf StrandConf) {
+	cl.mu.Lock()
+	cl.registry[strandID] = registryEntry{conf: conf, node: cl.self}
+	cl.mu.Unlock()
+
+	cl.broadcast(opRegister, registryMsg(strandID, conf))
+}
+
+// registryRetract forgets a locally removed strand and announces the removal to every peer.
+func (cl *Cluster) registryRetract(strandID string) {
+	cl.mu.Lock()
+	delete(cl.registry, strandID)
+	cl.mu.Unlock()
+
+	cl.broadcast(opUnregister, Msg{Strand: strandID})
+}
+
+// registrySync sends every registered strand to a node that just joined.
+func (cl *Cluster) registrySync(nodeID string) {
+	cl.mu.RLock()
+	entries := make(map[string]StrandConf, len(cl.registry))
+	for strandID, entry := range cl.registry {
+		entries[strandID] = entry.conf
+	}
+	cl.mu.RUnlock()
+
+	for strandID, conf := range entries {
+		if err := cl.send(nodeID, opRegister, registryMsg(strandID, conf)); err != nil {
+			cl.log.Warn("Registry sync failed", zap.String("strand", strandID), zap.String("node", nodeID), zap.Error(err))
+		}
+	}
+}
+
+// broadcast sends an operation to every peer.
+func (cl *Cluster) broadcast(op string, msg Msg) {
+	cl.mu.RLock()
+	peers := make([]string, 0, len(cl.nodes))
+	for nodeID := range cl.nodes {
+		if nodeID != cl.self {
+			peers = append(peers, nodeID)
+		}
+	}
+	cl.mu.RUnlock()
+
+	for _, nodeID := range peers {
+		if err := cl.send(nodeID, op, msg); err != nil {
+			cl.log.Warn("Cluster broadcast failed", zap.String("op", op), zap.String("node", nodeID), zap.Error(err))
+		}
+	}
+}
+
+// registryApply applies a registry operation from a peer, creating or removing the strand locally.
+func (c *Conduktor) registryApply(cl *Cluster, from, op string, msg Msg) error {
+	if op == opUnregister {
+		cl.mu.Lock()
+		delete(cl.registry, msg.Strand)
+		cl.mu.Unlock()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if store, err := c.getStore(msg.Strand); err == nil {
+			c.strandStores.forget(msg.Strand)
+			c.strandSeries.forget(msg.Strand)
+			delete(c.confs, msg.Strand)
+			return store.DeleteStrand(context.Background(), msg.Strand)
+		}
+		return nil
+	}
+
+	var conf StrandConf
+	if err := json.Unmarshal([]byte(msg.Payload), &conf); err != nil {
+		return err
+	}
+	conf = conf.Defaults() // Peers from before defaulting publish unset settings
+
+	cl.mu.Lock()
+	entry, exists := cl.registry[msg.Strand]
+	if exists && entry.conf != conf {
+		cl.conflicts = append(cl.conflicts, StrandConflict{Strand: msg.Strand, Node: from, Local: entry.conf, Remote: conf})
+		cl.mu.Unlock()
+
+		strandConflicts.Inc()
+		c.log.Error("Strand config conflict", zap.String("strand", msg.Strand), zap.String("node", from))
+		if op == opRegister {
+			return cl.send(from, opConflict, registryMsg(msg.Strand, entry.conf))
+		}
+		return nil
+	}
+	if op == opConflict {
+		cl.mu.Unlock()
+		return nil
+	}
+	cl.registry[msg.Strand] = registryEntry{conf: conf, node: from}
+	cl.mu.Unlock()
+
+	// Make the strand usable locally so forwarded and replicated messages can be stored
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.getStore(msg.Strand); err == nil {
+		return nil
+	}
+	store := c.selectStore(conf.Durable)
+	if err := store.CreateStrand(context.Background(), msg.Strand, conf); err != nil {
+		return err
+	}
+	c.strandStores.remember(msg.Strand, store)
+	c.strandSeries.get(msg.Strand)
+	c.confs[msg.Strand] = conf
+	cl.Track(msg.Strand)
+
+	c.log.Debug("Strand registered from peer", zap.String("strand", msg.Strand), zap.String("node", from))
+	return nil
+}
+
+// registryMsg carries a strand definition between nodes.
+func registryMsg(strandID string, conf StrandConf) Msg {
+	data, _ := json.Marshal(conf)
+	return Msg{Strand: strandID, Payload: string(data)}
+}
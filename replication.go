@@ -0,0 +1,233 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Cluster operations for strand replication.
+const (
+	opReplicate  = "replicate"   // Persist a copy of the message
+	opReplicaAck = "replica-ack" // A replica persisted the message
+	opRelease    = "release"     // The owner acknowledged the message; drop the copy
+	opQuorum     = "quorum"      // The owner's result of a forwarded WaitForQuorum send; empty for success
+)
+
+// Quorum write errors. The message is stored by its owner either way, but not known to be replicated.
+var (
+	ErrQuorumTimeout = errors.New("timed out waiting for replica quorum")
+	ErrNoQuorum      = errors.New("strand has no replicas to form a quorum")
+)
+
+// SendOption tunes a single Send.
+type SendOption func(*sendOpts)
+
+type sendOpts struct {
+	quorum   bool
+	timeout  time.Duration
+	identity string          // Who is sending, for the ACL; empty for trusted callers
+	ctx      context.Context // Parent of the send's span; nil for a new trace
+	headers  map[string]string
+}
+
+// WaitForQuorum makes Send return only after a majority of the strand's ReplicationFactor
+// replicas, counting the owner, have persisted the message. A send forwarded to the strand's owner
+// waits for the owner's quorum. Send fails with ErrNoQuorum if the Conduktor is not clustered or
+// the strand has no replicas.
+func WaitForQuorum(timeout time.Duration) SendOption {
+	return func(o *sendOpts) {
+		o.quorum = true
+		o.timeout = timeout
+	}
+}
+
+// replicas picks the nodes other than the owner that hold copies of strandID.
+func (cl *Cluster) replicas(strandID string, replicationFactor int) []string {
+	if replicationFactor <= 1 {
+		return nil
+	}
+
+	cl.mu.RLock()
+	nodes := make([]Node, 0, len(cl.nodes))
+	for _, node := range cl.nodes {
+		if node.ID != cl.self {
+			nodes = append(nodes, node)
+		}
+	}
+	cl.mu.RUnlock()
+
+	ranked := rendezvousRank(strandID, nodes)
+	if len(ranked) > replicationFactor-1 {
+		ranked = ranked[:replicationFactor-1]
+	}
+
+	ids := make([]string, len(ranked))
+	for i, node := range ranked {
+		ids[i] = node.ID
+	}
+	return ids
+}
+
+// replicate sends copies of msg to the strand's replicas. When quorum is set, the returned wait function
+// blocks until a majority of replicas, counting the owner, have persisted the message, or fails with
+// ErrNoQuorum if there are none. Otherwise it is nil.
+func (c *Conduktor) replicate(msg Msg, replicationFactor int, quorum bool) (wait func(timeout time.Duration) error) {
+	replicas := c.cluster.replicas(msg.Strand, replicationFactor)
+	if len(replicas) == 0 {
+		if quorum {
+			return func(time.Duration) error { return ErrNoQuorum }
+		}
+		return nil
+	}
+
+	cl := c.cluster
+	var acks chan error
+	if quorum {
+		acks = cl.quorumExpect(msg.ID, len(replicas))
+	}
+	for _, nodeID := range replicas {
+		if err := cl.send(nodeID, opReplicate, msg); err != nil {
+			c.log.Error("Replication failed", zap.String("strand", msg.Strand), zap.String("node", nodeID), zap.Error(err))
+			c.errs.report(ErrorSourceCluster, msg.Strand, msg.ID, err)
+		}
+	}
+
+	if !quorum {
+		return nil
+	}
+
+	// A majority is replicationFactor/2+1 copies, and the owner already holds one
+	need := replicationFactor / 2
+	return func(timeout time.Duration) error {
+		defer cl.quorumDone(msg.ID)
+
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		for got := 0; got < need; got++ {
+			select {
+			case err := <-acks:
+				if err != nil {
+					return err
+				}
+			case <-deadline.C:
+				quorumTimeouts.WithLabelValues(msg.Strand).Inc()
+				return ErrQuorumTimeout
+			}
+		}
+		return nil
+	}
+}
+
+// release tells the strand's replicas to drop their copy of an acknowledged message.
+func (c *Conduktor) release(strandID, msgID string, replicationFactor int) {
+	for _, nodeID := range c.cluster.replicas(strandID, replicationFactor) {
+		if err := c.cluster.send(nodeID, opRelease, Msg{ID: msgID, Strand: strandID}); err != nil {
+			c.log.Warn("Replica release failed", zap.String("strand", strandID), zap.String("node", nodeID), zap.Error(err))
+			c.errs.report(ErrorSourceCluster, strandID, msgID, err)
+		}
+	}
+}
+
+// replicaApply persists or drops a replica copy on behalf of the owning node.
+func (c *Conduktor) replicaApply(cl *Cluster, from, op string, msg Msg) error {
+	store, err := c.getStore(msg.Strand)
+	if err != nil {
+		return err
+	}
+
+	if op == opRelease {
+		return store.Acknowledge(context.Background(), msg.Strand, msg.ID)
+	}
+
+	if err := store.Save(context.Background(), msg); err != nil {
+		return err
+	}
+	return cl.send(from, opReplicaAck, Msg{ID: msg.ID, Strand: msg.Strand})
+}
+
+// forwardQuorum ships msg to the owning node, which replicates it. The returned wait function blocks
+// until the owner reports a majority of replicas have persisted the message.
+func (cl *Cluster) forwardQuorum(nodeID string, msg Msg, timeout time.Duration) (wait func(timeout time.Duration) error, err error) {
+	result := cl.quorumExpect(msg.ID, 1)
+	if err := cl.sendHeaders(nodeID, "", msg, map[string]string{HeaderQuorum: timeout.String()}); err != nil {
+		cl.quorumDone(msg.ID)
+		cl.log.Error("Message forward failed", zap.String("strand", msg.Strand), zap.String("node", nodeID), zap.Error(err))
+		return nil, err
+	}
+	messagesForwarded.WithLabelValues(msg.Strand).Inc()
+
+	return func(timeout time.Duration) error {
+		defer cl.quorumDone(msg.ID)
+
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		select {
+		case err := <-result:
+			return err
+		case <-deadline.C:
+			quorumTimeouts.WithLabelValues(msg.Strand).Inc()
+			return ErrQuorumTimeout
+		}
+	}, nil
+}
+
+// quorumReply tells the node that forwarded msg whether it was accepted and replicated to a quorum.
+func (cl *Cluster) quorumReply(to string, msg Msg, err error, wait func(time.Duration) error, timeout time.Duration) {
+	if err == nil {
+		err = wait(timeout)
+	}
+	result := Msg{ID: msg.ID, Strand: msg.Strand}
+	if err != nil {
+		result.Payload = err.Error()
+	}
+	if err := cl.send(to, opQuorum, result); err != nil {
+		cl.log.Warn("Quorum reply failed", zap.String("strand", msg.Strand), zap.String("node", to), zap.Error(err))
+	}
+}
+
+// quorumError returns the error an owner's quorum reply carries, or nil for success.
+func quorumError(reply string) error {
+	if reply == "" {
+		return nil
+	}
+	for _, err := range []error{ErrQuorumTimeout, ErrNoQuorum, ErrFenced} {
+		if reply == err.Error() {
+			return err
+		}
+	}
+	return errors.New(reply)
+}
+
+// quorumExpect starts collecting replica acknowledgments for msgID.
+func (cl *Cluster) quorumExpect(msgID string, replicas int) chan error {
+	cl.pendingMu.Lock()
+	defer cl.pendingMu.Unlock()
+
+	acks := make(chan error, replicas)
+	cl.pending[msgID] = acks
+	return acks
+}
+
+// quorumAck records one replica response for msgID: nil when it persisted the message, ErrFenced
+// when it fenced the write, or the owner's quorum result for a forwarded send.
+func (cl *Cluster) quorumAck(msgID string, err error) {
+	cl.pendingMu.Lock()
+	defer cl.pendingMu.Unlock()
+
+	if acks, exists := cl.pending[msgID]; exists {
+		select {
+		case acks <- err:
+		default:
+		}
+	}
+}
+
+// quorumDone stops collecting acknowledgments for msgID.
+func (cl *Cluster) quorumDone(msgID string) {
+	cl.pendingMu.Lock()
+	defer cl.pendingMu.Unlock()
+	delete(cl.pending, msgID)
+}
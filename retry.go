@@ -0,0 +1,144 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jkassis/condukt/wire"
+)
+
+// RetryPolicy decides whether, and after how long, a failed wire send is retried.
+type RetryPolicy interface {
+	// Backoff returns the wait before retry number attempt (1 for the first retry) of a send that
+	// failed with err, or false to give up.
+	Backoff(attempt int, err error) (time.Duration, bool)
+}
+
+// RetryPolicyFunc adapts a function to a RetryPolicy.
+type RetryPolicyFunc func(attempt int, err error) (time.Duration, bool)
+
+// Backoff calls f.
+func (f RetryPolicyFunc) Backoff(attempt int, err error) (time.Duration, bool) {
+	return f(attempt, err)
+}
+
+// RetryNone never retries: a failed send fails at once.
+func RetryNone() RetryPolicy {
+	return RetryPolicyFunc(func(int, error) (time.Duration, bool) { return 0, false })
+}
+
+// RetryFixed retries transient failures up to retries times, delay apart.
+func RetryFixed(delay time.Duration, retries int) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int, err error) (time.Duration, bool) {
+		return delay, attempt <= retries && retryable(err)
+	})
+}
+
+// RetryExponential retries transient failures up to retries times, waiting initial before the
+// first and doubling each time up to max. Each wait is shortened by a random fraction of up to
+// jitter, so senders that failed together do not retry together.
+func RetryExponential(initial, max time.Duration, retries int, jitter float64) RetryPolicy {
+	return RetryPolicyFunc(func(attempt int, err error) (time.Duration, bool) {
+		if attempt > retries || !retryable(err) {
+			return 0, false
+		}
+		delay := initial
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		delay = min(delay, max)
+		return delay - time.Duration(jitter*rand.Float64()*float64(delay)), true
+	})
+}
+
+// retryable reports whether err may pass if the send is retried: not when the send's context has
+// ended, or the message can never fit the wire.
+func retryable(err error) bool {
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, wire.ErrMsgTooLarge)
+}
+
+// Retry strategies of RetryConf.
+const (
+	RetryStrategyNone        = "none"
+	RetryStrategyFixed       = "fixed"
+	RetryStrategyExponential = "exponential"
+)
+
+// RetryConf configures a RetryPolicy.
+type RetryConf struct {
+	Strategy string        `yaml:"strategy"`  // none (default), fixed, or exponential
+	Retries  int           `yaml:"retries"`   // Retries after the first attempt; 0 is 3
+	Delay    time.Duration `yaml:"delay"`     // Wait before the first retry, and between fixed ones; 0 is 50ms
+	MaxDelay time.Duration `yaml:"max_delay"` // Longest exponential wait; 0 is 2s
+	Jitter   float64       `yaml:"jitter"`    // Fraction of each exponential wait randomized, 0 to 1
+}
+
+// Validate reports an unknown strategy or out-of-range settings.
+func (conf RetryConf) Validate() error {
+	var errs []error
+	switch conf.Strategy {
+	case "", RetryStrategyNone, RetryStrategyFixed, RetryStrategyExponential:
+	default:
+		errs = append(errs, fmt.Errorf("retry.strategy: unknown strategy %q (want none, fixed, or exponential)", conf.Strategy))
+	}
+	if conf.Retries < 0 || conf.Delay < 0 || conf.MaxDelay < 0 {
+		errs = append(errs, errors.New("retry: retries, delay, and max_delay must not be negative"))
+	}
+	if conf.Jitter < 0 || conf.Jitter > 1 {
+		errs = append(errs, errors.New("retry.jitter: must be 0 to 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// Policy returns the policy conf describes, with defaults for unset settings.
+func (conf RetryConf) Policy() RetryPolicy {
+	if conf.Retries == 0 {
+		conf.Retries = 3
+	}
+	if conf.Delay == 0 {
+		conf.Delay = 50 * time.Millisecond
+	}
+	if conf.MaxDelay == 0 {
+		conf.MaxDelay = 2 * time.Second
+	}
+	switch conf.Strategy {
+	case RetryStrategyFixed:
+		return RetryFixed(conf.Delay, conf.Retries)
+	case RetryStrategyExponential:
+		return RetryExponential(conf.Delay, conf.MaxDelay, conf.Retries, conf.Jitter)
+	default:
+		return RetryNone()
+	}
+}
+
+// SetRetryPolicy sets how failed wire sends are retried, by Send, recovery, and redelivery. A
+// retried send holds up its strand's later sends, keeping them in order. nil is RetryNone.
+func (c *Conduktor) SetRetryPolicy(policy RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retry = policy
+}
+
+// retryWait waits before retry number attempt of a send that failed with err, reporting false if
+// the policy gives up or ctx ends first. Callers must hold c.mu.
+func (c *Conduktor) retryWait(ctx context.Context, strandID string, attempt int, err error) bool {
+	if c.retry == nil {
+		return false
+	}
+	delay, retry := c.retry.Backoff(attempt, err)
+	if !retry {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		wireSendRetries.WithLabelValues(strandID).Inc()
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,72 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyWire fails its next failures sends with err.
+type flakyWire struct {
+	Wire
+	failures int
+	err      error
+	attempts int
+}
+
+func (w *flakyWire) SendMessage(ctx context.Context, msg Msg) error {
+	w.attempts++
+	if w.failures > 0 {
+		w.failures--
+		return w.err
+	}
+	return w.Wire.SendMessage(ctx, msg)
+}
+
+// Test Failed Wire Sends Are Retried Under The Policy
+func TestRetryPolicy(t *testing.T) {
+	transient := errors.New("connection refused")
+	w := &flakyWire{Wire: wire.GoChanWireMake(), err: transient}
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), w)
+	assert.NoError(t, mq.StrandAdd("retry_channel", StrandConf{}))
+
+	// Without a policy, the first failure fails the send
+	w.failures = 1
+	assert.ErrorIs(t, mq.Send("retry_channel", "Once"), transient)
+	assert.Equal(t, 1, w.attempts)
+
+	mq.SetRetryPolicy(RetryFixed(time.Millisecond, 3))
+	w.failures, w.attempts = 2, 0
+	assert.NoError(t, mq.Send("retry_channel", "Twice"))
+	assert.Equal(t, 3, w.attempts)
+	w.failures, w.attempts = 5, 0
+	assert.ErrorIs(t, mq.Send("retry_channel", "Never"), transient)
+	assert.Equal(t, 4, w.attempts, "the first attempt and 3 retries")
+
+	// Errors retrying cannot fix are not retried
+	w.failures, w.attempts, w.err = 5, 0, wire.ErrMsgTooLarge
+	assert.ErrorIs(t, mq.Send("retry_channel", "Huge"), wire.ErrMsgTooLarge)
+	assert.Equal(t, 1, w.attempts)
+
+	// Exponential waits double up to the max, shortened by up to the jitter
+	policy := RetryExponential(10*time.Millisecond, 50*time.Millisecond, 5, 0.5)
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 4: 50 * time.Millisecond} {
+		delay, retry := policy.Backoff(attempt, transient)
+		assert.True(t, retry)
+		assert.LessOrEqual(t, delay, want)
+		assert.GreaterOrEqual(t, delay, want/2)
+	}
+	_, retry := policy.Backoff(6, transient)
+	assert.False(t, retry)
+
+	_, retry = RetryConf{}.Policy().Backoff(1, transient)
+	assert.False(t, retry, "none by default")
+	delay, retry := RetryConf{Strategy: RetryStrategyFixed}.Policy().Backoff(3, transient)
+	assert.True(t, retry)
+	assert.Equal(t, 50*time.Millisecond, delay)
+}
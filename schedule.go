@@ -0,0 +1,407 @@
+package condukt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// HeaderSchedule is the header carrying the name of the schedule that published a message.
+const HeaderSchedule = "x-condukt-schedule"
+
+// ErrSchedulerStopped is returned when managing schedules while no scheduler is running.
+var ErrSchedulerStopped = errors.New("scheduler is not running")
+
+// ScheduleConf configures a schedule publishing a templated message to a strand, as in "emit a
+// tick to jobs.hourly every hour".
+type ScheduleConf struct {
+	Name string `yaml:"name" json:"name"`
+	// Cron is when to publish: five fields (minute hour day-of-month month day-of-week), in the
+	// server's time zone unless prefixed with CRON_TZ=Zone, or a descriptor like @hourly or @every 10m.
+	Cron    string            `yaml:"cron" json:"cron"`
+	Strand  string            `yaml:"strand" json:"strand"`             // Strand messages are sent to
+	Payload string            `yaml:"payload" json:"payload"`           // text/template executed with a ScheduleTick
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"` // Sent with each message, plus HeaderSchedule
+	// Identity the schedule publishes as, subject to the ACL, so revoking its access stops the
+	// schedule too. The admin API sets it to the caller; empty publishes unchecked.
+	Identity string `yaml:"identity" json:"identity,omitempty"`
+}
+
+// ScheduleTick is what a schedule's payload template is executed with.
+type ScheduleTick struct {
+	Name   string    // Schedule publishing
+	Strand string    // Strand published to
+	Time   time.Time // When the publish was due, in the schedule's time zone
+}
+
+// ScheduleInfo describes a schedule and its runs.
+type ScheduleInfo struct {
+	ScheduleConf
+	Next      time.Time `json:"next"`                 // When it next publishes; zero if it never matches again
+	Last      time.Time `json:"last"`                 // When it last published since startup; zero if it has not
+	LastError string    `json:"last_error,omitempty"` // Why its last publish failed, if it did
+}
+
+// SchedulerConf configures the scheduler.
+type SchedulerConf struct {
+	// Path is the file schedules are persisted to, so those set through the admin API survive
+	// restarts; empty keeps them in memory.
+	Path      string         `yaml:"path"`
+	Schedules []ScheduleConf `yaml:"schedules"` // Set at startup, replacing persisted schedules of the same name
+}
+
+// scheduleParser parses standard cron expressions and descriptors.
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Validate reports a missing name or strand, and an unparsable cron expression or payload template.
+func (conf ScheduleConf) Validate() error {
+	_, _, err := conf.compile()
+	return err
+}
+
+// compile parses the schedule's cron expression and payload template.
+func (conf ScheduleConf) compile() (cron.Schedule, *template.Template, error) {
+	var errs []error
+	if conf.Name == "" {
+		errs = append(errs, errors.New("name: required"))
+	}
+	if conf.Strand == "" {
+		errs = append(errs, errors.New("strand: required"))
+	}
+	schedule, err := scheduleParser.Parse(conf.Cron)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cron: %w", err))
+	}
+	payload, err := template.New(conf.Name).Option("missingkey=error").Parse(conf.Payload)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("payload: %w", err))
+	}
+	return schedule, payload, errors.Join(errs...)
+}
+
+// scheduleEntry is a schedule and the state of its runs.
+type scheduleEntry struct {
+	conf     ScheduleConf
+	schedule cron.Schedule
+	payload  *template.Template
+	next     time.Time
+	last     time.Time
+	lastErr  string
+}
+
+// info describes the entry.
+func (e *scheduleEntry) info() ScheduleInfo {
+	return ScheduleInfo{ScheduleConf: e.conf, Next: e.next, Last: e.last, LastError: e.lastErr}
+}
+
+// scheduler publishes the messages of its schedules when they are due.
+type scheduler struct {
+	c       *Conduktor
+	path    string
+	mu      sync.Mutex
+	entries map[string]*scheduleEntry // Name -> schedule
+	wake    chan struct{}             // Signaled when schedules change
+}
+
+// SchedulerServe loads the schedules persisted at conf.Path, sets conf.Schedules, and publishes
+// each schedule's message when it is due until stop is called. Runs missed while the server was
+// down or a publish was slow are skipped, not caught up. Sends are not retried; a failed run is
+// reported and the schedule publishes again at its next time.
+func (c *Conduktor) SchedulerServe(conf SchedulerConf) (stop func(), err error) {
+	s := &scheduler{c: c, path: conf.Path, entries: make(map[string]*scheduleEntry), wake: make(chan struct{}, 1)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	for _, schedule := range conf.Schedules {
+		if err := s.set(schedule); err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+	}
+
+	c.mu.Lock()
+	c.scheduler = s
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			wait := time.Hour // Until woken by a new schedule
+			if next := s.tick(ctx, c.now()); !next.IsZero() {
+				wait = max(next.Sub(c.now()), 0)
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-s.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+	c.log.Info("Scheduler started", zap.Int("schedules", len(s.entries)), zap.String("path", conf.Path))
+
+	return func() {
+		c.mu.Lock()
+		c.scheduler = nil
+		c.mu.Unlock()
+		cancel()
+		<-stopped
+	}, nil
+}
+
+// tick publishes the schedules due at now and returns when the next one is due, or zero if none is.
+func (s *scheduler) tick(ctx context.Context, now time.Time) time.Time {
+	s.mu.Lock()
+	var due []*scheduleEntry
+	for _, entry := range s.entries {
+		if !entry.next.IsZero() && !entry.next.After(now) {
+			due = append(due, entry)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].conf.Name < due[j].conf.Name })
+	for _, entry := range due {
+		s.publish(ctx, entry, now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next time.Time
+	for _, entry := range s.entries {
+		if !entry.next.IsZero() && (next.IsZero() || entry.next.Before(next)) {
+			next = entry.next
+		}
+	}
+	return next
+}
+
+// publish sends entry's message for the run due at entry.next, then moves entry to its next run
+// after now.
+func (s *scheduler) publish(ctx context.Context, entry *scheduleEntry, now time.Time) {
+	s.mu.Lock()
+	conf, payload, due := entry.conf, entry.payload, entry.next
+	s.mu.Unlock()
+
+	var body bytes.Buffer
+	err := payload.Execute(&body, ScheduleTick{Name: conf.Name, Strand: conf.Strand, Time: due})
+	if err == nil {
+		headers := map[string]string{HeaderSchedule: conf.Name}
+		for name, value := range conf.Headers {
+			headers[name] = value
+		}
+		err = s.c.Send(conf.Strand, body.String(), SendHeaders(headers), SendAs(conf.Identity), SendContext(ctx))
+	}
+	if err != nil {
+		scheduleRuns.WithLabelValues(conf.Name, "failure").Inc()
+		s.c.log.Warn("Scheduled publish failed", zap.String("schedule", conf.Name), zap.String("strand", conf.Strand), zap.Error(err))
+		s.c.errs.report(ErrorSourceSchedule, conf.Strand, "", err)
+	} else {
+		scheduleRuns.WithLabelValues(conf.Name, "success").Inc()
+		s.c.log.Debug("Scheduled publish", zap.String("schedule", conf.Name), zap.String("strand", conf.Strand))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries[conf.Name] != entry {
+		return // Replaced or removed while publishing
+	}
+	entry.last = now
+	entry.lastErr = ""
+	if err != nil {
+		entry.lastErr = err.Error()
+	}
+	entry.next = entry.schedule.Next(now)
+}
+
+// set adds or replaces a schedule and persists the schedules.
+func (s *scheduler) set(conf ScheduleConf) error {
+	schedule, payload, err := conf.compile()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.entries[conf.Name]
+	s.entries[conf.Name] = &scheduleEntry{conf: conf, schedule: schedule, payload: payload, next: schedule.Next(s.c.now())}
+	if err := s.save(); err != nil {
+		if previous != nil {
+			s.entries[conf.Name] = previous
+		} else {
+			delete(s.entries, conf.Name)
+		}
+		return err
+	}
+	s.signal()
+	return nil
+}
+
+// remove deletes a schedule and persists the schedules.
+func (s *scheduler) remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("schedule %q not found", name)
+	}
+	delete(s.entries, name)
+	if err := s.save(); err != nil {
+		s.entries[name] = previous
+		return err
+	}
+	s.signal()
+	return nil
+}
+
+// signal wakes the scheduler to recompute when the next schedule is due.
+func (s *scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// load reads the schedules persisted at the scheduler's path, if any.
+func (s *scheduler) load() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var confs []ScheduleConf
+	if err := json.Unmarshal(data, &confs); err != nil {
+		return fmt.Errorf("schedules %s: %w", s.path, err)
+	}
+	now := s.c.now()
+	for _, conf := range confs {
+		schedule, payload, err := conf.compile()
+		if err != nil {
+			return fmt.Errorf("schedules %s: schedule %s: %w", s.path, conf.Name, err)
+		}
+		s.entries[conf.Name] = &scheduleEntry{conf: conf, schedule: schedule, payload: payload, next: schedule.Next(now)}
+	}
+	return nil
+}
+
+// save atomically replaces the file at the scheduler's path with its schedules. Callers must hold s.mu.
+func (s *scheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+	confs := make([]ScheduleConf, 0, len(s.entries))
+	for _, entry := range s.entries {
+		confs = append(confs, entry.conf)
+	}
+	sort.Slice(confs, func(i, j int) bool { return confs[i].Name < confs[j].Name })
+	data, err := json.MarshalIndent(confs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".tmp", s.path)
+}
+
+// schedulerGet returns the running scheduler, or ErrSchedulerStopped.
+func (c *Conduktor) schedulerGet() (*scheduler, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.scheduler == nil {
+		return nil, ErrSchedulerStopped
+	}
+	return c.scheduler, nil
+}
+
+// Schedules describes the scheduler's schedules, ordered by name.
+func (c *Conduktor) Schedules() ([]ScheduleInfo, error) {
+	s, err := c.schedulerGet()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]ScheduleInfo, 0, len(s.entries))
+	for _, entry := range s.entries {
+		infos = append(infos, entry.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// ScheduleGet describes a schedule.
+func (c *Conduktor) ScheduleGet(name string) (ScheduleInfo, error) {
+	s, err := c.schedulerGet()
+	if err != nil {
+		return ScheduleInfo{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[name]
+	if !ok {
+		return ScheduleInfo{}, fmt.Errorf("schedule %q not found", name)
+	}
+	return entry.info(), nil
+}
+
+// ScheduleSet adds a schedule or replaces the one of the same name, persisting it.
+func (c *Conduktor) ScheduleSet(conf ScheduleConf) error {
+	s, err := c.schedulerGet()
+	if err != nil {
+		return err
+	}
+	if err := s.set(conf); err != nil {
+		return err
+	}
+	c.log.Info("Schedule set", zap.String("schedule", conf.Name), zap.String("cron", conf.Cron), zap.String("strand", conf.Strand))
+	return nil
+}
+
+// ScheduleRemove deletes a schedule, persisting its removal.
+func (c *Conduktor) ScheduleRemove(name string) error {
+	s, err := c.schedulerGet()
+	if err != nil {
+		return err
+	}
+	if err := s.remove(name); err != nil {
+		return err
+	}
+	c.log.Info("Schedule removed", zap.String("schedule", name))
+	return nil
+}
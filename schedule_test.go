@@ -0,0 +1,87 @@
+package condukt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Schedules Publish Their Templated Messages When Due And Survive A Restart
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	mq := ConduktorMake(store.RamStoreMake(), store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("jobs.hourly", StrandConf{}))
+
+	_, err := mq.Schedules()
+	assert.ErrorIs(t, err, ErrSchedulerStopped)
+
+	stop, err := mq.SchedulerServe(SchedulerConf{Path: path})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Error(t, mq.ScheduleSet(ScheduleConf{Name: "bad", Cron: "every hour", Strand: "jobs.hourly"}))
+	assert.NoError(t, mq.ScheduleSet(ScheduleConf{
+		Name:    "hourly",
+		Cron:    "CRON_TZ=UTC @hourly",
+		Strand:  "jobs.hourly",
+		Payload: `{{.Name}} {{.Time.Format "15:04"}}`,
+		Headers: map[string]string{"kind": "tick"},
+	}))
+	info, err := mq.ScheduleGet("hourly")
+	assert.NoError(t, err)
+	assert.Zero(t, info.Next.Minute())
+	stop()
+
+	// A restarted scheduler loads the persisted schedule
+	stop, err = mq.SchedulerServe(SchedulerConf{Path: path})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stop()
+	infos, err := mq.Schedules()
+	assert.NoError(t, err)
+	if !assert.Len(t, infos, 1) {
+		return
+	}
+	assert.Equal(t, "jobs.hourly", infos[0].Strand)
+
+	// Ticking past the due time publishes once and moves to the next hour
+	s, _ := mq.schedulerGet()
+	due := infos[0].Next
+	next := s.tick(ctx, due.Add(time.Minute))
+	assert.Equal(t, due.Add(time.Hour), next)
+	info, _ = mq.ScheduleGet("hourly")
+	assert.Empty(t, info.LastError)
+	assert.Equal(t, due.Add(time.Minute), info.Last)
+
+	sub, err := mq.Subscribe("jobs.hourly")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sub.Close()
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	msg, err := sub.Next(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "hourly "+due.UTC().Format("15:04"), msg.Payload)
+		assert.Equal(t, "hourly", msg.Headers[HeaderSchedule])
+		assert.Equal(t, "tick", msg.Headers["kind"])
+	}
+
+	// A schedule publishes as its identity, so revoking the identity's access stops it
+	assert.NoError(t, mq.ScheduleSet(ScheduleConf{Name: "hourly", Cron: "@hourly", Strand: "jobs.hourly", Identity: "cron"}))
+	mq.SetACL(ACL{{Identity: "ops", Strands: "*", Role: RoleAdmin}})
+	info, _ = mq.ScheduleGet("hourly")
+	s.tick(ctx, info.Next)
+	info, _ = mq.ScheduleGet("hourly")
+	assert.Contains(t, info.LastError, ErrDenied.Error())
+
+	assert.NoError(t, mq.ScheduleRemove("hourly"))
+	assert.Error(t, mq.ScheduleRemove("hourly"))
+}
@@ -0,0 +1,99 @@
+package condukt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrSchemaMismatch is returned when a sent payload does not match its strand's schema.
+var ErrSchemaMismatch = errors.New("payload does not match strand schema")
+
+// Schema validates the payloads sent to a strand.
+type Schema interface {
+	Validate(payload string) error
+}
+
+// jsonSchema checks payloads are JSON matching a JSON Schema.
+type jsonSchema struct {
+	schema *jsonschema.Schema
+}
+
+// JSONSchemaMake compiles a JSON Schema document. References to other documents are not resolved.
+func JSONSchemaMake(document []byte) (Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(document))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	schema, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return jsonSchema{schema: schema}, nil
+}
+
+// Validate checks payload is JSON matching the schema.
+func (s jsonSchema) Validate(payload string) error {
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(payload)))
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return s.schema.Validate(v)
+}
+
+// protoSchema checks payloads are the protojson encoding of a message type.
+type protoSchema struct {
+	message proto.Message
+}
+
+// ProtoSchemaMake validates payloads as the protojson encoding, as ProtoJSONCodec sends, of the
+// type of message.
+func ProtoSchemaMake(message proto.Message) Schema {
+	return protoSchema{message: message}
+}
+
+// Validate checks payload decodes as the schema's message type.
+func (s protoSchema) Validate(payload string) error {
+	return protojson.Unmarshal([]byte(payload), s.message.ProtoReflect().New().Interface())
+}
+
+// SetSchema makes Send reject payloads of strandID that schema does not validate, before any
+// middleware sees them. A nil schema accepts every payload.
+func (c *Conduktor) SetSchema(strandID string, schema Schema) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.getStore(strandID); err != nil {
+		return err
+	}
+	if schema == nil {
+		delete(c.schemas, strandID)
+	} else {
+		c.schemas[strandID] = schema
+	}
+	c.log.Info("Strand schema set", zap.String("strand", strandID), zap.Bool("enabled", schema != nil))
+	return nil
+}
+
+// schemaCheck validates payload against strandID's schema, if it has one.
+func (c *Conduktor) schemaCheck(strandID, payload string) error {
+	c.mu.RLock()
+	schema := c.schemas[strandID]
+	c.mu.RUnlock()
+	if schema == nil {
+		return nil
+	}
+	if err := schema.Validate(payload); err != nil {
+		return fmt.Errorf("%w %s: %v", ErrSchemaMismatch, strandID, err)
+	}
+	return nil
+}
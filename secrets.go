@@ -0,0 +1,333 @@
+package condukt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// Schemes of secret references, as "vault:condukt/tls#key".
+const (
+	SecretsEnv    = "env"   // Environment variable, by name
+	SecretsFile   = "file"  // File, by path
+	SecretsVault  = "vault" // HashiCorp Vault KV secret, as path#field
+	SecretsAWSKMS = "kms"   // Base64 ciphertext decrypted by AWS KMS
+)
+
+// SecretsProvider returns secrets by name, so configs can reference secrets instead of holding them.
+type SecretsProvider interface {
+	Secret(ctx context.Context, name string) ([]byte, error)
+}
+
+// EnvSecrets reads secrets from environment variables, by variable name.
+type EnvSecrets struct{}
+
+// Secret returns the value of the environment variable name.
+func (EnvSecrets) Secret(ctx context.Context, name string) ([]byte, error) {
+	value, set := os.LookupEnv(name)
+	if !set {
+		return nil, fmt.Errorf("%s is not set", name)
+	}
+	return []byte(value), nil
+}
+
+// FileSecrets reads secrets from files, as Docker and Kubernetes mount them. Relative paths are
+// read from Dir.
+type FileSecrets struct {
+	Dir string
+}
+
+// Secret returns the contents of the file name, without a trailing newline.
+func (f FileSecrets) Secret(ctx context.Context, name string) ([]byte, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(f.Dir, name)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(data, "\r\n"), nil
+}
+
+// VaultConf configures reading secrets from HashiCorp Vault's KV version 2 engine.
+type VaultConf struct {
+	Addr      string `yaml:"addr"`      // As https://vault.internal:8200; empty disables vault: references
+	TokenEnv  string `yaml:"token_env"` // Environment variable holding the token; empty is VAULT_TOKEN
+	Mount     string `yaml:"mount"`     // Mount path of the KV engine; empty is secret
+	Namespace string `yaml:"namespace"` // Vault Enterprise namespace
+}
+
+// VaultSecrets reads secrets from a Vault KV engine. Names are path#field, as "condukt/tls#key";
+// without a field, the field is value.
+type VaultSecrets struct {
+	addr      string
+	token     string
+	mount     string
+	namespace string
+	client    *http.Client
+}
+
+// VaultSecretsMake returns a provider reading from the Vault at conf.Addr with the token from the
+// environment.
+func VaultSecretsMake(conf VaultConf) (*VaultSecrets, error) {
+	if conf.TokenEnv == "" {
+		conf.TokenEnv = "VAULT_TOKEN"
+	}
+	if conf.Mount == "" {
+		conf.Mount = "secret"
+	}
+	token, set := os.LookupEnv(conf.TokenEnv)
+	if !set {
+		return nil, fmt.Errorf("vault: %s is not set", conf.TokenEnv)
+	}
+	return &VaultSecrets{
+		addr:      strings.TrimRight(conf.Addr, "/"),
+		token:     token,
+		mount:     strings.Trim(conf.Mount, "/"),
+		namespace: conf.Namespace,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Secret reads the field of the latest version of the secret at path.
+func (v *VaultSecrets) Secret(ctx context.Context, name string) ([]byte, error) {
+	path, field, _ := strings.Cut(name, "#")
+	if field == "" {
+		field = "value"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+v.mount+"/data/"+(&url.URL{Path: strings.Trim(path, "/")}).EscapedPath(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault %s: %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("vault %s: %w", path, err)
+	}
+	value, ok := body.Data.Data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("vault %s: no string field %s", path, field)
+	}
+	return []byte(value), nil
+}
+
+// AWSKMSConf configures decrypting secrets with AWS KMS.
+type AWSKMSConf struct {
+	Enabled bool   `yaml:"enabled"` // Accept kms: references, and encryption keys with kms: aws
+	Region  string `yaml:"region"`  // Empty takes the region from the environment
+}
+
+// KMSAPI is the subset of the AWS KMS client used by AWSKMSSecrets.
+type KMSAPI interface {
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// AWSKMSSecrets decrypts secrets encrypted with AWS KMS. Names are the base64 ciphertext blobs
+// `aws kms encrypt` outputs, which name their key themselves. It is also a KMS, unwrapping the
+// encryption and signing keys configured with kms: aws.
+type AWSKMSSecrets struct {
+	client KMSAPI
+}
+
+// AWSKMSSecretsOpen returns a provider decrypting with credentials, and unless conf.Region is set
+// the region, from the environment.
+func AWSKMSSecretsOpen(ctx context.Context, conf AWSKMSConf) (*AWSKMSSecrets, error) {
+	var options []func(*awsconfig.LoadOptions) error
+	if conf.Region != "" {
+		options = append(options, awsconfig.WithRegion(conf.Region))
+	}
+	awsConf, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return AWSKMSSecretsMake(kms.NewFromConfig(awsConf)), nil
+}
+
+// AWSKMSSecretsMake returns a provider decrypting with client.
+func AWSKMSSecretsMake(client KMSAPI) *AWSKMSSecrets {
+	return &AWSKMSSecrets{client: client}
+}
+
+// Secret decrypts the base64 ciphertext name.
+func (k *AWSKMSSecrets) Secret(ctx context.Context, name string) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(name)
+	if err != nil {
+		return nil, fmt.Errorf("kms: ciphertext: %w", err)
+	}
+	return k.Decrypt(ctx, blob)
+}
+
+// Decrypt decrypts a ciphertext blob.
+func (k *AWSKMSSecrets) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	out, err := k.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// SecretsConf configures the providers secret references are resolved with. env: and file:
+// references are always available.
+type SecretsConf struct {
+	Dir    string     `yaml:"dir"` // Directory relative file: references are read from; empty is the working directory
+	Vault  VaultConf  `yaml:"vault"`
+	AWSKMS AWSKMSConf `yaml:"aws_kms"`
+}
+
+// validateRef reports a malformed reference, or one whose provider is not configured.
+func (conf SecretsConf) validateRef(ref string) error {
+	scheme, name, found := strings.Cut(ref, ":")
+	if !found || name == "" {
+		return fmt.Errorf("%q is not a scheme:name secret reference", ref)
+	}
+	switch scheme {
+	case SecretsEnv, SecretsFile:
+	case SecretsVault:
+		if conf.Vault.Addr == "" {
+			return fmt.Errorf("%q: requires secrets.vault.addr", ref)
+		}
+	case SecretsAWSKMS:
+		if !conf.AWSKMS.Enabled {
+			return fmt.Errorf("%q: requires secrets.aws_kms.enabled", ref)
+		}
+	default:
+		return fmt.Errorf("%q: unknown scheme %q (want env, file, vault, or kms)", ref, scheme)
+	}
+	return nil
+}
+
+// Secrets resolves secret references, as "file:/run/secrets/api-key", with the provider their
+// scheme names.
+type Secrets struct {
+	providers map[string]SecretsProvider
+}
+
+// SecretsMake connects to the providers conf configures. An AWS KMS provider is also registered as
+// the KMS aws.
+func SecretsMake(ctx context.Context, conf SecretsConf) (*Secrets, error) {
+	s := &Secrets{providers: map[string]SecretsProvider{
+		SecretsEnv:  EnvSecrets{},
+		SecretsFile: FileSecrets{Dir: conf.Dir},
+	}}
+	if conf.Vault.Addr != "" {
+		vault, err := VaultSecretsMake(conf.Vault)
+		if err != nil {
+			return nil, err
+		}
+		s.providers[SecretsVault] = vault
+	}
+	if conf.AWSKMS.Enabled {
+		aws, err := AWSKMSSecretsOpen(ctx, conf.AWSKMS)
+		if err != nil {
+			return nil, err
+		}
+		s.providers[SecretsAWSKMS] = aws
+		KMSRegister("aws", aws)
+	}
+	return s, nil
+}
+
+// SetProvider resolves references of scheme with provider, replacing any it had.
+func (s *Secrets) SetProvider(scheme string, provider SecretsProvider) {
+	s.providers[scheme] = provider
+}
+
+// Resolve returns the secret ref references.
+func (s *Secrets) Resolve(ctx context.Context, ref string) ([]byte, error) {
+	scheme, name, _ := strings.Cut(ref, ":")
+	provider, exists := s.providers[scheme]
+	if !exists {
+		return nil, fmt.Errorf("secret %q: no %s provider", ref, scheme)
+	}
+	secret, err := provider.Secret(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("secret %q: %w", ref, err)
+	}
+	return secret, nil
+}
+
+// secretField is a secret reference of a config, and where the secret it references goes.
+type secretField struct {
+	key string // Config key of the reference
+	ref string
+	set func(secret []byte)
+}
+
+// secretFields returns the secret references of cfg.
+func (cfg *Config) secretFields() []secretField {
+	var fields []secretField
+	if tls := &cfg.Listen.TLS; tls.KeySecret != "" {
+		fields = append(fields, secretField{"listen.tls.key_secret", tls.KeySecret, func(secret []byte) { tls.keyPEM = secret }})
+	}
+	for i := range cfg.Auth.Keys {
+		if key := &cfg.Auth.Keys[i]; key.KeySecret != "" {
+			fields = append(fields, secretField{fmt.Sprintf("auth.keys[%d].key_secret", i), key.KeySecret, func(secret []byte) { key.Key = string(secret) }})
+		}
+	}
+	for _, conf := range []struct {
+		name string
+		keys []StrandKey
+	}{{"encryption", cfg.Encryption.Keys}, {"signing", cfg.Signing.Keys}} {
+		for i := range conf.keys {
+			if key := &conf.keys[i]; key.Secret != "" {
+				fields = append(fields, secretField{fmt.Sprintf("%s.keys[%d].secret", conf.name, i), key.Secret, func(secret []byte) { key.Key, key.Secret = string(secret), "" }})
+			}
+		}
+	}
+	return fields
+}
+
+// secretsValidate reports secret references of cfg that are malformed or whose provider is not
+// configured.
+func (cfg Config) secretsValidate() error {
+	var errs []error
+	for _, field := range cfg.secretFields() {
+		if err := cfg.Secrets.validateRef(field.ref); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field.key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SecretsResolve reads the secrets cfg references with secrets, filling in the TLS private key, API
+// keys, and encryption and signing keys they stand for.
+func (cfg *Config) SecretsResolve(ctx context.Context, secrets *Secrets) error {
+	var errs []error
+	for _, field := range cfg.secretFields() {
+		secret, err := secrets.Resolve(ctx, field.ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field.key, err))
+			continue
+		}
+		field.set(secret)
+	}
+	return errors.Join(errs...)
+}
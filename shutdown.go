@@ -0,0 +1,77 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+// ErrShuttingDown is returned by Send once Shutdown has begun.
+var ErrShuttingDown = errors.New("conduktor is shutting down")
+
+// Shutdown stops the Conduktor: it rejects new sends, waits for in-flight sends, ships messages queued for
+// mirrors and remote regions, closes the wire, and closes both stores so durable messages are persisted.
+// If ctx expires before the queues drain, the remaining queued messages are abandoned and the wire and
+// stores are closed anyway.
+func (c *Conduktor) Shutdown(ctx context.Context) error {
+	// Taking the lock waits for in-flight sends to finish
+	c.mu.Lock()
+	if c.closing {
+		c.mu.Unlock()
+		return ErrShuttingDown
+	}
+	c.closing = true
+
+	var flushing []chan struct{}
+	for _, m := range c.mirrors {
+		close(m.flush)
+		flushing = append(flushing, m.stopped)
+	}
+	for _, g := range c.geo {
+		close(g.flush)
+		flushing = append(flushing, g.stopped)
+	}
+	for _, f := range c.links {
+		close(f.done)
+	}
+	cl := c.cluster
+	c.mu.Unlock()
+	c.log.Info("Shutting down", zap.Int("queues", len(flushing)))
+
+	var forced error
+flush:
+	for _, stopped := range flushing {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			forced = fmt.Errorf("shutdown forced before queues drained: %w", ctx.Err())
+			c.log.Warn("Shutdown drain timed out; abandoning queued messages")
+			break flush
+		}
+	}
+
+	if cl != nil {
+		cl.Close()
+	}
+
+	var errs []error
+	if closer, ok := c.wire.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("wire: %w", err))
+		}
+	}
+	if err := c.durable.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("durable store: %w", err))
+	}
+	if c.volatile != c.durable {
+		if err := c.volatile.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("volatile store: %w", err))
+		}
+	}
+
+	c.log.Info("Shutdown complete", zap.Bool("forced", forced != nil))
+	return errors.Join(append([]error{forced}, errs...)...)
+}
@@ -0,0 +1,211 @@
+package condukt
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HeaderSignature carries a message's HMAC signature, as keyid=<id>;headers=<a,b>;sig=<base64>.
+const HeaderSignature = "x-condukt-signature"
+
+// signingKeyMin is the shortest HMAC-SHA256 key accepted, the hash's output size.
+const signingKeyMin = sha256.Size
+
+// ErrBadSignature is returned when a message of a signed strand has a missing or invalid signature,
+// or names a signing key the SigningKeys do not hold.
+var ErrBadSignature = errors.New("bad message signature")
+
+// SigningKeys holds the HMAC-SHA256 keys of each strand, or strand pattern, by key ID. Sends are
+// signed with a strand's current key, and receives verified with the key their signature names, so
+// keys can be rotated while messages signed with older ones are still unacked.
+type SigningKeys struct {
+	keys strandKeys[[]byte]
+}
+
+// SigningKeysMake makes an empty SigningKeys.
+func SigningKeysMake() *SigningKeys {
+	return &SigningKeys{keys: strandKeysMake[[]byte]()}
+}
+
+// Add adds a key of at least 32 bytes for strandID, which may be a * pattern, and makes it the key
+// sends are signed with.
+func (s *SigningKeys) Add(strandID, keyID string, key []byte) error {
+	if keyID == "" {
+		return errors.New("key ID is required")
+	}
+	if err := signingKeyCheck(key); err != nil {
+		return err
+	}
+	if strings.ContainsAny(keyID, ";=") {
+		return fmt.Errorf("key ID %q: must not contain ; or =", keyID)
+	}
+	s.keys.add(strandID, keyID, slices.Clone(key))
+	return nil
+}
+
+// Remove drops a key of strandID once no unacked message needs it. Removing the current key leaves
+// the strand's sends unsigned until another key is added, and its receives refused.
+func (s *SigningKeys) Remove(strandID, keyID string) {
+	s.keys.remove(strandID, keyID)
+}
+
+// sign signs msg with its strand's current key, if it has one and msg is not signed already, as a
+// message imported over a bridge may be.
+func (s *SigningKeys) sign(msg *Msg) {
+	if _, signed := msg.Headers[HeaderSignature]; signed {
+		return
+	}
+	keyID, key, exists := s.keys.current(msg.Strand)
+	if !exists {
+		return
+	}
+
+	var names []string
+	for name := range msg.Headers {
+		if signingCovers(name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	sig := signingMAC(key, msg, names)
+	msg.Headers[HeaderSignature] = "keyid=" + keyID + ";headers=" + strings.Join(names, ",") + ";sig=" + base64.StdEncoding.EncodeToString(sig)
+}
+
+// verify checks the signature of msg, if its strand has keys.
+func (s *SigningKeys) verify(msg *Msg) error {
+	if !s.keys.keyed(msg.Strand) {
+		return nil
+	}
+	value, signed := msg.Headers[HeaderSignature]
+	if !signed {
+		return fmt.Errorf("%w: message %s is not signed", ErrBadSignature, msg.ID)
+	}
+
+	fields := make(map[string]string, 3)
+	for _, field := range strings.Split(value, ";") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+	key, exists := s.keys.get(msg.Strand, fields["keyid"])
+	if !exists {
+		return fmt.Errorf("%w: unknown key %q for strand %s", ErrBadSignature, fields["keyid"], msg.Strand)
+	}
+	var names []string
+	if fields["headers"] != "" {
+		names = strings.Split(fields["headers"], ",")
+	}
+	for _, name := range names {
+		if _, exists := msg.Headers[name]; !exists {
+			return fmt.Errorf("%w: message %s lacks signed header %s", ErrBadSignature, msg.ID, name)
+		}
+	}
+	sig, err := base64.StdEncoding.DecodeString(fields["sig"])
+	if err != nil || !hmac.Equal(sig, signingMAC(key, msg, names)) {
+		return fmt.Errorf("%w: message %s was altered", ErrBadSignature, msg.ID)
+	}
+	return nil
+}
+
+// signingCovers reports whether a header is signed: all but condukt's own and trace context, which
+// change as a message travels, and names the signature header cannot list.
+func signingCovers(name string) bool {
+	return !strings.HasPrefix(name, "x-condukt-") && name != HeaderTraceParent && name != HeaderTraceState && !strings.ContainsAny(name, ",;=")
+}
+
+// signingMAC returns the HMAC of msg's payload and the headers names, each length-prefixed so
+// their boundaries cannot be shifted.
+func signingMAC(key []byte, msg *Msg, names []string) []byte {
+	mac := hmac.New(sha256.New, key)
+	var buf []byte
+	for _, name := range names {
+		buf = binary.AppendUvarint(buf, uint64(len(name)))
+		buf = append(buf, name...)
+		buf = binary.AppendUvarint(buf, uint64(len(msg.Headers[name])))
+		buf = append(buf, msg.Headers[name]...)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(msg.Payload)))
+	mac.Write(buf)
+	mac.Write([]byte(msg.Payload))
+	return mac.Sum(nil)
+}
+
+// signingKeyCheck refuses keys shorter than the HMAC-SHA256 output.
+func signingKeyCheck(key []byte) error {
+	if len(key) < signingKeyMin {
+		return fmt.Errorf("%d bytes, want at least %d", len(key), signingKeyMin)
+	}
+	return nil
+}
+
+// SigningMiddleware signs messages with HMAC-SHA256 as they are sent to strands with keys in keys,
+// and verifies them as they are received, refusing those with a missing or invalid signature with
+// ErrBadSignature, so consumers can tell messages altered on their way, as over an untrusted
+// bridge, from those sent. Signatures cover the payload and the headers set at send, but not the
+// message ID or strand, which bridges may change. Messages are verified once the middleware added
+// after it has delivered them, so SigningMiddleware is added before EncryptionMiddleware, signing
+// and verifying plaintext. Refused messages stay unacked in their store.
+func SigningMiddleware(keys *SigningKeys) Middleware {
+	return Middleware{
+		Send: func(next SendHandler) SendHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				keys.sign(msg)
+				return next(ctx, msg)
+			}
+		},
+		Deliver: func(next DeliverHandler) DeliverHandler {
+			return func(ctx context.Context, msg *Msg) error {
+				if err := next(ctx, msg); err != nil {
+					return err
+				}
+				return keys.verify(msg)
+			}
+		},
+	}
+}
+
+// SigningConf configures HMAC signing of messages. Of the keys of one strand or pattern, the last
+// listed signs sends; earlier ones only verify, so a key is rotated by appending its successor and
+// removed once no unacked message needs it.
+type SigningConf struct {
+	Keys []StrandKey `yaml:"keys"`
+}
+
+// Enabled reports whether any key is configured.
+func (conf SigningConf) Enabled() bool {
+	return len(conf.Keys) > 0
+}
+
+// Validate reports keys without strands, ID, or exactly one source, and literal keys shorter than
+// 32 bytes.
+func (conf SigningConf) Validate() error {
+	var errs []error
+	for i, key := range conf.Keys {
+		if err := key.validate(signingKeyCheck); err != nil {
+			errs = append(errs, fmt.Errorf("keys[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SigningKeys returns the configured keys, reading them from the environment or unwrapping them
+// with their KMS as needed.
+func (conf SigningConf) SigningKeys(ctx context.Context) (*SigningKeys, error) {
+	keys := SigningKeysMake()
+	for i, key := range conf.Keys {
+		raw, err := key.load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("signing.keys[%d]: %w", i, err)
+		}
+		if err := keys.Add(key.Strands, key.ID, raw); err != nil {
+			return nil, fmt.Errorf("signing.keys[%d]: %w", i, err)
+		}
+	}
+	return keys, nil
+}
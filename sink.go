@@ -0,0 +1,163 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sink exports messages to another system, such as Kafka, for pipelines that read from there.
+type Sink interface {
+	// Write exports msgs, all of strandID and in the order received. Once it returns nil they are
+	// acknowledged; an error retries them, so a message may be exported more than once.
+	Write(ctx context.Context, strandID string, msgs []*Msg) error
+	// Close flushes anything buffered and releases the sink.
+	Close() error
+}
+
+// SinkRecord is a message as sinks writing JSON lines export it.
+type SinkRecord struct {
+	ID        string            `json:"id"`
+	Strand    string            `json:"strand"`
+	Timestamp int64             `json:"timestamp"` // Unix seconds the message was sent
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   string            `json:"payload"`
+}
+
+// sinkRecordMake returns msg of strandID as a SinkRecord.
+func sinkRecordMake(strandID string, msg *Msg) SinkRecord {
+	return SinkRecord{ID: msg.ID, Strand: strandID, Timestamp: msg.Timestamp, Headers: msg.Headers, Payload: msg.Payload}
+}
+
+// SinkConf configures what a sink exports and how it batches.
+type SinkConf struct {
+	Name       string        `yaml:"name"`        // Labels the sink's logs and metrics
+	Strands    []string      `yaml:"strands"`     // Strands exported
+	BatchSize  int           `yaml:"batch_size"`  // Messages written at a time at most; 0 is 100
+	BatchBytes int64         `yaml:"batch_bytes"` // Message bytes that end a batch early; 0 ends batches by size and delay only
+	BatchDelay time.Duration `yaml:"batch_delay"` // Longest a batch waits to fill once it has a message; 0 is 100ms
+	RetryDelay time.Duration `yaml:"retry_delay"` // Wait before retrying a failed write; 0 is 1s
+}
+
+// Validate reports a missing name or strands, and negative settings.
+func (conf SinkConf) Validate() error {
+	var errs []error
+	if conf.Name == "" {
+		errs = append(errs, errors.New("name: required"))
+	}
+	if len(conf.Strands) == 0 {
+		errs = append(errs, errors.New("strands: at least one is required"))
+	}
+	if conf.BatchSize < 0 || conf.BatchBytes < 0 || conf.BatchDelay < 0 || conf.RetryDelay < 0 {
+		errs = append(errs, errors.New("batch_size, batch_bytes, batch_delay, and retry_delay must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// SinkServe exports conf.Strands to sink until stop is called. Each strand is subscribed to, so the
+// sink shares its messages with the strand's other subscribers, and its messages are acknowledged
+// only once written: delivery is at least once. A batch that fails to write is retried every
+// conf.RetryDelay until it succeeds or stop is called; messages unwritten then stay unacked and are
+// resent on recovery. stop waits for writes in progress to end, then closes sink.
+func (c *Conduktor) SinkServe(sink Sink, conf SinkConf) (stop func()) {
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 100
+	}
+	if conf.BatchDelay <= 0 {
+		conf.BatchDelay = 100 * time.Millisecond
+	}
+	if conf.RetryDelay <= 0 {
+		conf.RetryDelay = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, strandID := range conf.Strands {
+		sub, err := c.Subscribe(strandID)
+		if err != nil {
+			c.log.Error("Failed to subscribe sink", zap.String("sink", conf.Name), zap.String("strand", strandID), zap.Error(err))
+			c.errs.report(ErrorSourceSink, strandID, "", err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sub.Close()
+			for ctx.Err() == nil {
+				if batch := c.sinkBatch(ctx, sub, conf); len(batch) > 0 {
+					c.sinkWrite(ctx, sink, conf, strandID, batch)
+				}
+			}
+		}()
+	}
+	c.log.Info("Sink started", zap.String("sink", conf.Name), zap.Strings("strands", conf.Strands))
+
+	return func() {
+		cancel()
+		wg.Wait()
+		if err := sink.Close(); err != nil {
+			c.log.Error("Failed to close sink", zap.String("sink", conf.Name), zap.Error(err))
+		}
+	}
+}
+
+// sinkBatch waits for a message of sub, then gathers more until the batch is full or conf.BatchDelay
+// has passed. Messages the Deliver middleware refuses are left out, unacked.
+func (c *Conduktor) sinkBatch(ctx context.Context, sub *Subscription, conf SinkConf) []*Msg {
+	var batch []*Msg
+	var bytes int64
+	fill := ctx
+	for len(batch) < conf.BatchSize && (conf.BatchBytes == 0 || bytes < conf.BatchBytes) {
+		msg, err := sub.Next(fill)
+		if msg == nil && err != nil {
+			break
+		}
+		if err == nil {
+			batch = append(batch, msg)
+			bytes += msg.Size()
+		}
+		if len(batch) == 1 && fill == ctx {
+			var cancel context.CancelFunc
+			fill, cancel = context.WithTimeout(ctx, conf.BatchDelay)
+			defer cancel()
+		}
+	}
+	return batch
+}
+
+// sinkWrite writes batch to sink until it succeeds or ctx is done, then acknowledges it.
+func (c *Conduktor) sinkWrite(ctx context.Context, sink Sink, conf SinkConf, strandID string, batch []*Msg) {
+	for {
+		err := sink.Write(ctx, strandID, batch)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		sinkFailures.WithLabelValues(conf.Name).Inc()
+		c.log.Warn("Sink write failed", zap.String("sink", conf.Name), zap.String("strand", strandID), zap.Int("messages", len(batch)), zap.Error(err))
+		c.errs.report(ErrorSourceSink, strandID, batch[0].ID, err)
+
+		timer := time.NewTimer(conf.RetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+	sinkMessages.WithLabelValues(conf.Name).Add(float64(len(batch)))
+
+	msgIDs := make([]string, len(batch))
+	for i, msg := range batch {
+		msgIDs[i] = msg.ID
+	}
+	if err := c.AcknowledgeBatch(strandID, msgIDs); err != nil {
+		c.log.Error("Failed to acknowledge sunk messages", zap.String("sink", conf.Name), zap.String("strand", strandID), zap.Error(err))
+		c.errs.report(ErrorSourceSink, strandID, "", err)
+	}
+}
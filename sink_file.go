@@ -0,0 +1,244 @@
+package condukt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSinkCurrent is the name of the file a strand's messages are appended to until it rotates.
+const fileSinkCurrent = "current.jsonl"
+
+// fileSinkTimeFormat names rotated files after when they rotated, so they sort oldest first.
+const fileSinkTimeFormat = "20060102T150405.000000000Z"
+
+// FileSinkConf configures a sink writing strands to local files.
+type FileSinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	Dir      string        `yaml:"dir"`       // Each strand's files go in a subdirectory named after it, its slash escaped
+	MaxBytes int64         `yaml:"max_bytes"` // Size that rotates a file; 0 is 64MiB
+	MaxAge   time.Duration `yaml:"max_age"`   // Age that rotates a file, checked at each write; 0 rotates by size only
+	Compress bool          `yaml:"compress"`  // Gzip rotated files
+	Keep     int           `yaml:"keep"`      // Rotated files kept per strand, deleting the oldest; 0 keeps all
+}
+
+// Validate reports a missing name, strands, or directory, and negative settings.
+func (conf FileSinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.Dir == "" {
+		errs = append(errs, errors.New("dir: required"))
+	}
+	if conf.MaxBytes < 0 || conf.MaxAge < 0 || conf.Keep < 0 {
+		errs = append(errs, errors.New("max_bytes, max_age, and keep must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// FileSink appends each strand's messages, one SinkRecord per line, to current.jsonl in the strand's
+// directory, syncing each batch before it is acknowledged. Once the file reaches MaxBytes or
+// MaxAge, it is renamed after the time it rotated, as 20060102T150405.000000000Z.jsonl, and
+// gzipped to .jsonl.gz if Compress is set.
+type FileSink struct {
+	conf FileSinkConf
+
+	mu    sync.Mutex
+	files map[string]*fileSinkFile
+}
+
+// fileSinkFile is a strand's current file.
+type fileSinkFile struct {
+	dir    string
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// FileSinkMake returns a sink writing under conf.Dir, creating it if needed.
+func FileSinkMake(conf FileSinkConf) (*FileSink, error) {
+	if conf.MaxBytes == 0 {
+		conf.MaxBytes = 64 << 20
+	}
+	if err := os.MkdirAll(conf.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileSink{conf: conf, files: make(map[string]*fileSinkFile)}, nil
+}
+
+// Write appends msgs to strandID's current file and syncs it, first rotating the file if it is due.
+// A file is thus rotated at the first write after it reaches MaxBytes or MaxAge, so a write that
+// fails to rotate fails before writing, and is retried without duplicating messages.
+func (s *FileSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	f, err := s.current(strandID)
+	if err != nil {
+		return err
+	}
+	if err := s.rotateDue(f); err != nil {
+		return err
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, msg := range msgs {
+		if err := enc.Encode(sinkRecordMake(strandID, msg)); err != nil {
+			return err
+		}
+	}
+	n, err := f.file.Write(buf.Bytes())
+	f.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+// current returns strandID's file, opening it on the first write.
+func (s *FileSink) current(strandID string) (*fileSinkFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, open := s.files[strandID]; open {
+		return f, nil
+	}
+	f := &fileSinkFile{dir: filepath.Join(s.conf.Dir, url.PathEscape(strandID))}
+	if err := os.MkdirAll(f.dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	s.files[strandID] = f
+	return f, nil
+}
+
+// open opens f's current file for appending, continuing any left by an earlier run.
+func (f *fileSinkFile) open() error {
+	file, err := os.OpenFile(filepath.Join(f.dir, fileSinkCurrent), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// rotateDue rotates f if it has reached MaxBytes or MaxAge, leaving the caller to open a new file.
+func (s *FileSink) rotateDue(f *fileSinkFile) error {
+	if f.file == nil || f.size == 0 {
+		return nil
+	}
+	if f.size < s.conf.MaxBytes && (s.conf.MaxAge == 0 || time.Since(f.opened) < s.conf.MaxAge) {
+		return nil
+	}
+
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	rotated := filepath.Join(f.dir, time.Now().UTC().Format(fileSinkTimeFormat)+".jsonl")
+	if err := os.Rename(filepath.Join(f.dir, fileSinkCurrent), rotated); err != nil {
+		return err
+	}
+	if s.conf.Compress {
+		if err := fileCompress(rotated); err != nil {
+			return err
+		}
+	}
+	return s.prune(f.dir)
+}
+
+// fileCompress replaces the file at name with a gzipped copy, name.gz.
+func fileCompress(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(name+".gz.tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name()) // Fails harmlessly once renamed
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), name+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// prune deletes all but the newest Keep rotated files in dir.
+func (s *FileSink) prune(dir string) error {
+	if s.conf.Keep == 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != fileSinkCurrent && (strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".jsonl.gz")) {
+			rotated = append(rotated, name)
+		}
+	}
+	slices.Sort(rotated)
+	for len(rotated) > s.conf.Keep {
+		if err := os.Remove(filepath.Join(dir, rotated[0])); err != nil {
+			return err
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// Close closes every strand's current file, leaving it to be continued by the next run.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, f := range s.files {
+		if f.file != nil {
+			errs = append(errs, f.file.Close())
+			f.file = nil
+		}
+	}
+	return errors.Join(errs...)
+}
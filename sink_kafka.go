@@ -0,0 +1,104 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaHeaderMsgID is the Kafka record header carrying the condukt message ID, so consumers can
+// drop the duplicates at-least-once delivery allows.
+const KafkaHeaderMsgID = "x-condukt-msg-id"
+
+// KafkaSinkConf configures a sink exporting strands to Kafka topics.
+type KafkaSinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	Brokers []string `yaml:"brokers"` // Bootstrap brokers, as host:port
+	// Topic every strand is written to; empty writes each strand to the topic named after it, with
+	// a namespace's slash as a dot.
+	Topic string `yaml:"topic"`
+	// KeyHeader names the message header whose value keys each record, so messages of one key stay
+	// in order on one partition; empty is "key". Messages without it are spread across partitions.
+	KeyHeader string `yaml:"key_header"`
+}
+
+// Validate reports a missing name, strands, or brokers, and negative settings.
+func (conf KafkaSinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(conf.Brokers) == 0 {
+		errs = append(errs, errors.New("brokers: at least one is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// KafkaSink writes messages as Kafka records: the payload as the value, the key header as the key,
+// and every header, plus KafkaHeaderMsgID, as record headers. Writes wait for every in-sync replica.
+type KafkaSink struct {
+	writer    *kafka.Writer
+	topic     string
+	keyHeader string
+}
+
+// KafkaSinkMake returns a sink writing to the brokers of conf. Brokers are not dialed until the
+// first write.
+func KafkaSinkMake(conf KafkaSinkConf) *KafkaSink {
+	if conf.KeyHeader == "" {
+		conf.KeyHeader = "key"
+	}
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(conf.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// SinkServe batches, so send each batch at once rather than wait for more
+			BatchSize:    max(conf.BatchSize, 100),
+			BatchTimeout: time.Millisecond,
+		},
+		topic:     conf.Topic,
+		keyHeader: conf.KeyHeader,
+	}
+}
+
+// Write writes msgs to strandID's topic, returning once all are acknowledged by the brokers.
+func (k *KafkaSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	topic := k.topic
+	if topic == "" {
+		topic = strings.ReplaceAll(strandID, "/", ".")
+	}
+	records := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		records[i] = kafkaRecord(msg, topic, k.keyHeader)
+	}
+	return k.writer.WriteMessages(ctx, records...)
+}
+
+// Close flushes and closes the Kafka writer.
+func (k *KafkaSink) Close() error {
+	return k.writer.Close()
+}
+
+// kafkaRecord returns msg as the record KafkaSink writes to topic, keyed by the value of its
+// keyHeader.
+func kafkaRecord(msg *Msg, topic, keyHeader string) kafka.Message {
+	record := kafka.Message{
+		Topic:   topic,
+		Value:   []byte(msg.Payload),
+		Time:    time.Unix(msg.Timestamp, 0),
+		Headers: make([]kafka.Header, 0, len(msg.Headers)+1),
+	}
+	if key, ok := msg.Headers[keyHeader]; ok {
+		record.Key = []byte(key)
+	}
+	record.Headers = append(record.Headers, kafka.Header{Key: KafkaHeaderMsgID, Value: []byte(msg.ID)})
+	for name, value := range msg.Headers {
+		record.Headers = append(record.Headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+	return record
+}
@@ -0,0 +1,178 @@
+package condukt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Compressions of the objects the S3 sink uploads.
+const (
+	SinkCompressionGzip = "gzip" // The default
+	SinkCompressionNone = "none"
+)
+
+// S3SinkConf configures a sink archiving strands to S3. Each batch becomes one object, so the batch
+// settings are its upload triggers: an object is uploaded once batch_size messages or batch_bytes
+// bytes are gathered, or batch_delay after its first message. Unset, they are 10000 messages and
+// a minute.
+type S3SinkConf struct {
+	SinkConf `yaml:",inline"`
+
+	URL         string `yaml:"url"`         // s3://bucket/prefix objects are uploaded under
+	Compression string `yaml:"compression"` // gzip (default) or none
+}
+
+// Validate reports a missing name, strands, or URL, an unknown compression, and negative settings.
+func (conf S3SinkConf) Validate() error {
+	var errs []error
+	if err := conf.SinkConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, _, err := backupS3URL(conf.URL); err != nil {
+		errs = append(errs, fmt.Errorf("url: %w", err))
+	}
+	switch conf.Compression {
+	case "", SinkCompressionGzip, SinkCompressionNone:
+	default:
+		errs = append(errs, fmt.Errorf("compression: unknown compression %q (want gzip or none)", conf.Compression))
+	}
+	return errors.Join(errs...)
+}
+
+// sinkConf returns the batching of conf, with the S3 sink's defaults.
+func (conf S3SinkConf) sinkConf() SinkConf {
+	if conf.BatchSize == 0 {
+		conf.BatchSize = 10000
+	}
+	if conf.BatchDelay == 0 {
+		conf.BatchDelay = time.Minute
+	}
+	return conf.SinkConf
+}
+
+// S3Manifest describes an object the S3 sink uploaded, for replay and offline analysis to find
+// archived messages without reading every object. It is uploaded after its object, so an object
+// with a manifest is complete.
+type S3Manifest struct {
+	Object         string `json:"object"` // Key of the object
+	Strand         string `json:"strand"`
+	Compression    string `json:"compression"`
+	Messages       int    `json:"messages"`
+	Bytes          int64  `json:"bytes"` // Size of the object
+	FirstID        string `json:"first_id"`
+	LastID         string `json:"last_id"`
+	FirstTimestamp int64  `json:"first_timestamp"` // Unix seconds
+	LastTimestamp  int64  `json:"last_timestamp"`
+}
+
+// S3Sink archives each batch as an object of JSON lines, one SinkRecord per message, and a manifest
+// beside it. Keys are made from the batch's first message:
+//
+//	prefix/strand/2006/01/02/<timestamp>-<id>.jsonl.gz
+//	prefix/strand/2006/01/02/<timestamp>-<id>.manifest.json
+//
+// so a batch retried after a partial upload overwrites its own objects.
+type S3Sink struct {
+	client      S3API
+	bucket      string
+	prefix      string
+	compression string
+}
+
+// S3SinkMake returns a sink uploading to bucket under prefix with client.
+func S3SinkMake(client S3API, bucket, prefix, compression string) *S3Sink {
+	if compression == "" {
+		compression = SinkCompressionGzip
+	}
+	return &S3Sink{client: client, bucket: bucket, prefix: prefix, compression: compression}
+}
+
+// S3SinkOpen returns a sink uploading where conf says, with the default AWS credentials.
+func S3SinkOpen(ctx context.Context, conf S3SinkConf) (*S3Sink, error) {
+	bucket, prefix, err := backupS3URL(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	awsConf, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return S3SinkMake(s3.NewFromConfig(awsConf), bucket, prefix, conf.Compression), nil
+}
+
+// Write uploads msgs as an object, then its manifest.
+func (s *S3Sink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var zw *gzip.Writer
+	if s.compression == SinkCompressionGzip {
+		zw = gzip.NewWriter(&body)
+		w = zw
+	}
+	enc := json.NewEncoder(w)
+	for _, msg := range msgs {
+		if err := enc.Encode(sinkRecordMake(strandID, msg)); err != nil {
+			return err
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+
+	first, last := msgs[0], msgs[len(msgs)-1]
+	base := path.Join(s.prefix, strandID, time.Unix(first.Timestamp, 0).UTC().Format("2006/01/02"), fmt.Sprintf("%d-%s", first.Timestamp, first.ID))
+	manifest := S3Manifest{
+		Object:         base + ".jsonl",
+		Strand:         strandID,
+		Compression:    s.compression,
+		Messages:       len(msgs),
+		Bytes:          int64(body.Len()),
+		FirstID:        first.ID,
+		LastID:         last.ID,
+		FirstTimestamp: first.Timestamp,
+		LastTimestamp:  last.Timestamp,
+	}
+	contentType := "application/x-ndjson"
+	if zw != nil {
+		manifest.Object += ".gz"
+		contentType = "application/gzip"
+	}
+	if err := s.put(ctx, manifest.Object, contentType, body.Bytes()); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, base+".manifest.json", "application/json", data)
+}
+
+// put uploads data as key.
+func (s *S3Sink) put(ctx context.Context, key, contentType string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// Close does nothing; every write is uploaded before it returns.
+func (s *S3Sink) Close() error {
+	return nil
+}
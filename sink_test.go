@@ -0,0 +1,258 @@
+package condukt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSink records what it is given, failing the first failures writes.
+type fakeSink struct {
+	mu       sync.Mutex
+	failures int
+	written  []string // Payloads of successful writes
+	closed   bool
+}
+
+func (s *fakeSink) Write(ctx context.Context, strandID string, msgs []*Msg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	for _, msg := range msgs {
+		s.written = append(s.written, msg.Payload)
+	}
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Test A Sink Gets A Strand's Messages In Batches, Retries Failed Writes, And Acks Only What It Wrote
+func TestSinkServe(t *testing.T) {
+	ctx := context.Background()
+	volatile := store.RamStoreMake()
+	mq := ConduktorMake(volatile, store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("sunk_channel", StrandConf{}))
+	for i := range 5 {
+		assert.NoError(t, mq.Send("sunk_channel", strconv.Itoa(i)))
+	}
+
+	sink := &fakeSink{failures: 2}
+	stop := mq.SinkServe(sink, SinkConf{Name: "test", Strands: []string{"sunk_channel"}, BatchSize: 10, BatchDelay: 10 * time.Millisecond, RetryDelay: time.Millisecond})
+	assert.Eventually(t, func() bool {
+		depth, err := volatile.Depth(ctx, "sunk_channel")
+		return err == nil && depth == 0
+	}, time.Second, time.Millisecond)
+	stop()
+
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, sink.written)
+	assert.True(t, sink.closed)
+
+	// Messages a failing sink never writes stay unacked
+	assert.NoError(t, mq.Send("sunk_channel", "Unwritten"))
+	sink = &fakeSink{failures: 1 << 30}
+	stop = mq.SinkServe(sink, SinkConf{Name: "test", Strands: []string{"sunk_channel"}, RetryDelay: time.Millisecond})
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	depth, err := volatile.Depth(ctx, "sunk_channel")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, depth)
+	assert.Empty(t, sink.written)
+}
+
+// Test Kafka Records Carry The Payload, The Key Header As Key, And Every Header
+func TestKafkaRecord(t *testing.T) {
+	msg := &Msg{ID: "m1", Payload: "Hello", Timestamp: 1700000000, Headers: map[string]string{"key": "customer-7", "trace": "abc"}}
+	record := kafkaRecord(msg, "orders", "key")
+	assert.Equal(t, "orders", record.Topic)
+	assert.Equal(t, "customer-7", string(record.Key))
+	assert.Equal(t, "Hello", string(record.Value))
+	assert.Equal(t, int64(1700000000), record.Time.Unix())
+	headers := make(map[string]string)
+	for _, header := range record.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	assert.Equal(t, map[string]string{KafkaHeaderMsgID: "m1", "key": "customer-7", "trace": "abc"}, headers)
+
+	assert.Nil(t, kafkaRecord(&Msg{ID: "m2"}, "orders", "key").Key)
+}
+
+// Test The S3 Sink Uploads A Batch As Gzipped JSON Lines With A Manifest Naming It
+func TestS3Sink(t *testing.T) {
+	ctx := context.Background()
+	fake := &s3Fake{objects: make(map[string][]byte)}
+	sink := S3SinkMake(fake, "bucket", "archive", "")
+	msgs := []*Msg{
+		{ID: "m1", Payload: "One", Timestamp: 1700000000, Headers: map[string]string{"key": "a"}},
+		{ID: "m2", Payload: "Two", Timestamp: 1700000001},
+	}
+	assert.NoError(t, sink.Write(ctx, "team-a/orders", msgs))
+
+	var manifest S3Manifest
+	if !assert.NoError(t, json.Unmarshal(fake.objects["archive/team-a/orders/2023/11/14/1700000000-m1.manifest.json"], &manifest)) {
+		return
+	}
+	assert.Equal(t, S3Manifest{
+		Object: "archive/team-a/orders/2023/11/14/1700000000-m1.jsonl.gz", Strand: "team-a/orders", Compression: SinkCompressionGzip,
+		Messages: 2, Bytes: int64(len(fake.objects[manifest.Object])), FirstID: "m1", LastID: "m2", FirstTimestamp: 1700000000, LastTimestamp: 1700000001,
+	}, manifest)
+
+	zr, err := gzip.NewReader(bytes.NewReader(fake.objects[manifest.Object]))
+	if !assert.NoError(t, err) {
+		return
+	}
+	dec := json.NewDecoder(zr)
+	var records []SinkRecord
+	for {
+		var record SinkRecord
+		if err := dec.Decode(&record); err != nil {
+			assert.ErrorIs(t, err, io.EOF)
+			break
+		}
+		records = append(records, record)
+	}
+	assert.Equal(t, []SinkRecord{
+		{ID: "m1", Strand: "team-a/orders", Timestamp: 1700000000, Headers: map[string]string{"key": "a"}, Payload: "One"},
+		{ID: "m2", Strand: "team-a/orders", Timestamp: 1700000001, Payload: "Two"},
+	}, records)
+}
+
+// Test The File Sink Rotates, Compresses, And Prunes A Strand's Files
+func TestFileSink(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sink, err := FileSinkMake(FileSinkConf{Dir: dir, MaxBytes: 1, Compress: true, Keep: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := range 4 {
+		assert.NoError(t, sink.Write(ctx, "team-a/orders", []*Msg{{ID: "m" + strconv.Itoa(i), Payload: strconv.Itoa(i)}}))
+	}
+	assert.NoError(t, sink.Close())
+
+	strandDir := filepath.Join(dir, "team-a%2Forders")
+	entries, err := os.ReadDir(strandDir)
+	if !assert.NoError(t, err) || !assert.Len(t, entries, 3) {
+		return
+	}
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".jsonl.gz"))
+	assert.True(t, strings.HasSuffix(entries[1].Name(), ".jsonl.gz"))
+	assert.Equal(t, fileSinkCurrent, entries[2].Name())
+
+	// The newest rotated file holds the third message, and the current file the fourth
+	zipped, err := os.ReadFile(filepath.Join(strandDir, entries[1].Name()))
+	assert.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var record SinkRecord
+	assert.NoError(t, json.NewDecoder(zr).Decode(&record))
+	assert.Equal(t, SinkRecord{ID: "m2", Strand: "team-a/orders", Payload: "2"}, record)
+	current, err := os.ReadFile(filepath.Join(strandDir, fileSinkCurrent))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(current, &record))
+	assert.Equal(t, "m3", record.ID)
+}
+
+// fakeRedis records what is published to each channel.
+type fakeRedis struct {
+	published map[string][]string
+}
+
+func (r *fakeRedis) Publish(ctx context.Context, channel string, message any) *redis.IntCmd {
+	r.published[channel] = append(r.published[channel], message.(string))
+	return redis.NewIntResult(1, nil)
+}
+
+// Test The Redis Sink Publishes To Each Strand's Channel, Skipping Messages Bridged From It
+func TestRedisSink(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeRedis{published: make(map[string][]string)}
+	sink := RedisSinkMake(client, "")
+	assert.NoError(t, sink.Write(ctx, "team-a/orders", []*Msg{
+		{ID: "m0", Payload: "0"},
+		{ID: "m1", Payload: "1", Headers: map[string]string{HeaderRedisChannel: "team-a.orders"}},
+		{ID: "m2", Payload: "2", Headers: map[string]string{HeaderRedisChannel: "orders"}},
+	}))
+	assert.Equal(t, map[string][]string{"team-a.orders": {"0", "2"}}, client.published)
+	assert.NoError(t, sink.Close())
+}
+
+// sqsFake records sent entries and deleted receipt handles, and returns its pending messages once.
+type sqsFake struct {
+	sent    []types.SendMessageBatchRequestEntry
+	deleted []string
+	pending []types.Message
+}
+
+func (q *sqsFake) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	out := &sqs.ReceiveMessageOutput{Messages: q.pending}
+	q.pending = nil
+	return out, nil
+}
+
+func (q *sqsFake) DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, entry := range params.Entries {
+		q.deleted = append(q.deleted, aws.ToString(entry.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (q *sqsFake) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	if len(params.Entries) > sqsBatchMax {
+		return nil, errors.New("too many entries")
+	}
+	q.sent = append(q.sent, params.Entries...)
+	return &sqs.SendMessageBatchOutput{}, nil
+}
+
+// Test The SQS Sink Groups FIFO Messages By Key And Deduplicates Them By ID
+func TestSQSSink(t *testing.T) {
+	fake := &sqsFake{}
+	sink := SQSSinkMake(fake, SQSSinkConf{Queue: "https://sqs.us-east-1.amazonaws.com/123456789012/jobs.fifo"})
+	msgs := make([]*Msg, 12)
+	for i := range msgs {
+		msgs[i] = &Msg{ID: "m" + strconv.Itoa(i), Payload: strconv.Itoa(i)}
+	}
+	msgs[0].Headers = map[string]string{"key": "customer-1", "trace": "t"}
+	assert.NoError(t, sink.Write(context.Background(), "jobs", msgs))
+
+	if !assert.Len(t, fake.sent, 12) {
+		return
+	}
+	assert.Equal(t, "customer-1", aws.ToString(fake.sent[0].MessageGroupId))
+	assert.Equal(t, "t", aws.ToString(fake.sent[0].MessageAttributes["trace"].StringValue))
+	assert.Equal(t, "jobs", aws.ToString(fake.sent[11].MessageGroupId))
+	assert.Equal(t, "m11", aws.ToString(fake.sent[11].MessageDeduplicationId))
+	assert.Equal(t, "11", aws.ToString(fake.sent[11].MessageBody))
+
+	standard := SQSSinkMake(fake, SQSSinkConf{Queue: "https://sqs.us-east-1.amazonaws.com/123456789012/jobs"})
+	assert.NoError(t, standard.Write(context.Background(), "jobs", msgs[:1]))
+	assert.Nil(t, fake.sent[12].MessageGroupId)
+}
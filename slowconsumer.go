@@ -0,0 +1,218 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Slow-consumer policies.
+const (
+	SlowThrottle   = "throttle"   // Hold back new deliveries until the consumer catches up
+	SlowDisconnect = "disconnect" // Close the consumer's wire connection; its messages stay stored
+	SlowDLQ        = "dlq"        // Move the consumer's overdue and excess in-flight messages to the dead-letter strand
+)
+
+// Why a consumer is slow, as reported in logs and the detection metric.
+const (
+	slowWindow  = "window"  // Too many messages in flight
+	slowLatency = "latency" // The oldest in-flight message waited too long for its ack
+)
+
+// SlowConsumerConf configures detection of slow consumers and what to do about them.
+type SlowConsumerConf struct {
+	Interval      time.Duration `yaml:"interval"`        // How often strands are checked; 0 disables detection
+	MaxInFlight   int           `yaml:"max_in_flight"`   // Delivered but unacked messages a consumer may hold; 0 is unlimited
+	MaxAckLatency time.Duration `yaml:"max_ack_latency"` // Longest a delivered message may wait for its ack; 0 is unlimited
+	Policy        string        `yaml:"policy"`          // throttle, disconnect, or dlq; empty only logs and counts
+}
+
+// Validate reports an unknown policy or negative thresholds.
+func (conf SlowConsumerConf) Validate() error {
+	var errs []error
+	if conf.Interval < 0 || conf.MaxInFlight < 0 || conf.MaxAckLatency < 0 {
+		errs = append(errs, errors.New("slow_consumers: interval, max_in_flight, and max_ack_latency must not be negative"))
+	}
+	switch conf.Policy {
+	case "", SlowThrottle, SlowDisconnect, SlowDLQ:
+	default:
+		errs = append(errs, fmt.Errorf("slow_consumers.policy: unknown policy %q (want throttle, disconnect, or dlq)", conf.Policy))
+	}
+	if conf.Interval > 0 && conf.MaxInFlight == 0 && conf.MaxAckLatency == 0 {
+		errs = append(errs, errors.New("slow_consumers: max_in_flight or max_ack_latency is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// SlowConsumerServe checks every strand's consumers each conf.Interval, applying conf.Policy to slow
+// ones, until stop is called.
+func (c *Conduktor) SlowConsumerServe(conf SlowConsumerConf) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.slowConsumerCheck(conf); err != nil {
+					c.log.Warn("Failed to check for slow consumers", zap.Error(err))
+					c.errs.report(ErrorSourceSlowConsumer, "", "", err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// slowConsumerCheck applies conf to the consumers of every strand once. Strands paused by an
+// operator are skipped, since their consumers are not expected to keep up.
+func (c *Conduktor) slowConsumerCheck(conf SlowConsumerConf) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, store := range []Store{c.durable, c.volatile} {
+		strands, err := store.ListStrands(context.Background())
+		if err != nil {
+			return err
+		}
+		for strandID := range strands {
+			if strandID == AuditStrand || strings.HasSuffix(strandID, dlqSuffix) || c.paused[strandID] != nil {
+				continue
+			}
+			if err := c.slowConsumer(conf, store, strandID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// slowConsumer checks the consumers of strandID, applying conf.Policy if they are slow and
+// releasing held deliveries once they catch up. Callers must hold c.mu.
+func (c *Conduktor) slowConsumer(conf SlowConsumerConf, store Store, strandID string) error {
+	depth, err := store.Depth(context.Background(), strandID)
+	if err != nil {
+		return err
+	}
+	inFlight := depth
+	held, throttled := c.throttled[strandID]
+	if throttled {
+		inFlight -= len(*held)
+	}
+
+	reason := ""
+	if conf.MaxInFlight > 0 && inFlight >= conf.MaxInFlight {
+		reason = slowWindow
+	}
+	if conf.MaxAckLatency > 0 && reason == "" && inFlight > 0 {
+		oldest, err := store.Peek(context.Background(), strandID, 1)
+		if err != nil {
+			return err
+		}
+		if len(oldest) > 0 && c.overdue(store, strandID, oldest[0].ID, conf.MaxAckLatency) {
+			reason = slowLatency
+		}
+	}
+
+	if reason == "" {
+		if throttled {
+			return c.throttleRelease(strandID)
+		}
+		return nil
+	}
+
+	slowConsumers.WithLabelValues(strandID, reason).Inc()
+	c.log.Warn("Slow consumer", zap.String("strand", strandID), zap.String("reason", reason),
+		zap.Int("inFlight", inFlight), zap.String("policy", conf.Policy))
+
+	switch conf.Policy {
+	case SlowThrottle:
+		if !throttled {
+			c.throttled[strandID] = &[]Msg{}
+		}
+	case SlowDisconnect:
+		disconnector, ok := c.wire.(WireDisconnector)
+		if !ok {
+			c.log.Warn("Wire cannot disconnect slow consumers", zap.String("strand", strandID))
+			return nil
+		}
+		if err := disconnector.Disconnect(strandID); err != nil {
+			c.log.Warn("Failed to disconnect slow consumer", zap.String("strand", strandID), zap.Error(err))
+			c.errs.report(ErrorSourceSlowConsumer, strandID, "", err)
+			return nil
+		}
+	case SlowDLQ:
+		if err := c.slowDivert(conf, store, strandID, inFlight); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	slowConsumerActions.WithLabelValues(strandID, conf.Policy).Inc()
+	return nil
+}
+
+// slowDivert dead-letters the oldest in-flight messages of strandID that are overdue for their ack
+// or in excess of conf.MaxInFlight. Callers must hold c.mu.
+func (c *Conduktor) slowDivert(conf SlowConsumerConf, store Store, strandID string, inFlight int) error {
+	msgs, err := store.Peek(context.Background(), strandID, inFlight)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		overdue := conf.MaxAckLatency > 0 && c.overdue(store, strandID, msg.ID, conf.MaxAckLatency)
+		excess := conf.MaxInFlight > 0 && inFlight >= conf.MaxInFlight
+		if !overdue && !excess {
+			break
+		}
+		if err := c.deadLetter(strandID, msg.ID, "slow consumer"); err != nil {
+			return err
+		}
+		inFlight--
+	}
+	return nil
+}
+
+// throttleRelease stops throttling strandID, sending the deliveries held back meanwhile.
+// Callers must hold c.mu.
+func (c *Conduktor) throttleRelease(strandID string) error {
+	held, throttled := c.throttled[strandID]
+	if !throttled {
+		return nil
+	}
+	delete(c.throttled, strandID)
+
+	store, err := c.getStore(strandID)
+	if err != nil {
+		return err
+	}
+	for _, msg := range *held {
+		if _, err := store.Get(context.Background(), strandID, msg.ID); err != nil {
+			continue // Removed while held
+		}
+		if err := c.transmit(MsgContext(context.Background(), msg), msg, nil); err != nil {
+			c.log.Error("Failed to deliver held message", zap.String("strand", strandID), zap.String("msgID", msg.ID), zap.Error(err))
+			c.errs.report(ErrorSourceRedelivery, strandID, msg.ID, err)
+			return err
+		}
+	}
+	c.log.Info("Slow consumer caught up", zap.String("strand", strandID), zap.Int("released", len(*held)))
+	return nil
+}
+
+// overdue reports whether a message has waited longer than maxLatency for its ack since it was last
+// sent on the wire, or else since it was stored. Callers must hold c.mu.
+func (c *Conduktor) overdue(store Store, strandID, msgID string, maxLatency time.Duration) bool {
+	if d, known := c.deliveries.get(msgID); known && d.Delivered != 0 {
+		return time.Since(time.Unix(0, d.Delivered)) > maxLatency
+	}
+	stored := c.storedAt(store, strandID, msgID)
+	return !stored.IsZero() && time.Since(stored) > maxLatency
+}
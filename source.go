@@ -0,0 +1,122 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Source imports messages from another system, such as a JetStream stream, into a strand.
+type Source interface {
+	// Read returns the next messages, waiting for some until ctx is done. It may return none.
+	Read(ctx context.Context) ([]SourceMsg, error)
+	// Commit advances the source's cursor past msgs, all sent to the strand, so they are not read
+	// again.
+	Commit(ctx context.Context, msgs []SourceMsg) error
+	// Close releases the source. Messages read but not committed are read again by the next run.
+	Close() error
+}
+
+// SourceMsg is a message read from a source.
+type SourceMsg struct {
+	Payload string
+	Headers map[string]string
+	Cursor  any // Where the message is in the source, for Commit
+}
+
+// SourceConf configures where a source's messages go.
+type SourceConf struct {
+	Name       string        `yaml:"name"`        // Labels the source's logs and metrics
+	Strand     string        `yaml:"strand"`      // Strand messages are sent to
+	RetryDelay time.Duration `yaml:"retry_delay"` // Wait before retrying a failed read, send, or commit; 0 is 1s
+}
+
+// Validate reports a missing name or strand, and a negative retry delay.
+func (conf SourceConf) Validate() error {
+	var errs []error
+	if conf.Name == "" {
+		errs = append(errs, errors.New("name: required"))
+	}
+	if conf.Strand == "" {
+		errs = append(errs, errors.New("strand: required"))
+	}
+	if conf.RetryDelay < 0 {
+		errs = append(errs, errors.New("retry_delay: must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// SourceServe sends the messages read from source to conf.Strand until stop is called, committing
+// them to source only once sent: delivery is at least once. A failed send is retried every
+// conf.RetryDelay until it succeeds or stop is called, and a failed read or commit is retried by
+// reading on after conf.RetryDelay, which may read uncommitted messages again. stop waits for the
+// batch in progress to end, then closes source.
+func (c *Conduktor) SourceServe(source Source, conf SourceConf) (stop func()) {
+	if conf.RetryDelay <= 0 {
+		conf.RetryDelay = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for ctx.Err() == nil {
+			msgs, err := source.Read(ctx)
+			if err == nil && len(msgs) > 0 {
+				if err = c.sourceSend(ctx, conf, msgs); err == nil {
+					err = source.Commit(ctx, msgs)
+				}
+			}
+			if err != nil && ctx.Err() == nil {
+				c.sourceFailed(ctx, conf, err)
+			}
+		}
+	}()
+	c.log.Info("Source started", zap.String("source", conf.Name), zap.String("strand", conf.Strand))
+
+	return func() {
+		cancel()
+		<-stopped
+		if err := source.Close(); err != nil {
+			c.log.Error("Failed to close source", zap.String("source", conf.Name), zap.Error(err))
+		}
+	}
+}
+
+// sourceSend sends msgs to conf.Strand in order, retrying each until it is sent or ctx is done.
+func (c *Conduktor) sourceSend(ctx context.Context, conf SourceConf, msgs []SourceMsg) error {
+	for _, msg := range msgs {
+		for {
+			err := c.Send(conf.Strand, msg.Payload, SendHeaders(msg.Headers), SendContext(ctx))
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.sourceFailed(ctx, conf, err)
+		}
+		sourceMessages.WithLabelValues(conf.Name).Inc()
+	}
+	return nil
+}
+
+// sourceFailed reports a failure of the source, then waits conf.RetryDelay or until ctx is done.
+func (c *Conduktor) sourceFailed(ctx context.Context, conf SourceConf, err error) {
+	sourceFailures.WithLabelValues(conf.Name).Inc()
+	c.log.Warn("Source failed", zap.String("source", conf.Name), zap.String("strand", conf.Strand), zap.Error(err))
+	c.errs.report(ErrorSourceImport, conf.Strand, "", err)
+	sourceWait(ctx, conf.RetryDelay)
+}
+
+// sourceWait waits d or until ctx is done.
+func sourceWait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+}
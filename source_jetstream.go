@@ -0,0 +1,126 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// HeaderNATSSubject is the header carrying the subject a message imported from JetStream was
+// published to.
+const HeaderNATSSubject = "x-nats-subject"
+
+// JetStreamSourceConf configures a source importing a JetStream stream.
+type JetStreamSourceConf struct {
+	SourceConf `yaml:",inline"`
+
+	URL    string `yaml:"url"`    // NATS server, as nats://host:4222
+	Stream string `yaml:"stream"` // Stream to import
+	// Durable names the consumer holding the import's cursor on the stream; empty is the source's
+	// name. Restarts resume from it.
+	Durable       string        `yaml:"durable"`
+	FilterSubject string        `yaml:"filter_subject"` // Import only messages of matching subjects; empty imports all
+	Batch         int           `yaml:"batch"`          // Messages fetched at a time at most; 0 is 100
+	MaxWait       time.Duration `yaml:"max_wait"`       // Longest a fetch waits for messages; 0 is 1s
+}
+
+// Validate reports a missing name, strand, URL, or stream, and negative settings.
+func (conf JetStreamSourceConf) Validate() error {
+	var errs []error
+	if err := conf.SourceConf.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.URL == "" {
+		errs = append(errs, errors.New("url: required"))
+	}
+	if conf.Stream == "" {
+		errs = append(errs, errors.New("stream: required"))
+	}
+	if conf.Batch < 0 || conf.MaxWait < 0 {
+		errs = append(errs, errors.New("batch and max_wait must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// JetStreamSource reads a JetStream stream through a durable pull consumer, acknowledging each
+// message once it is sent to the strand. Headers are imported with their first values, plus
+// HeaderNATSSubject. Messages left unacknowledged are redelivered by JetStream after the
+// consumer's ack wait, or to the next run.
+type JetStreamSource struct {
+	nc       *nats.Conn
+	consumer jetstream.Consumer
+	batch    int
+	maxWait  time.Duration
+}
+
+// JetStreamSourceOpen connects to conf.URL and creates or updates the durable consumer of
+// conf.Stream the source reads from.
+func JetStreamSourceOpen(ctx context.Context, conf JetStreamSourceConf) (*JetStreamSource, error) {
+	if conf.Durable == "" {
+		conf.Durable = conf.Name
+	}
+	if conf.Batch == 0 {
+		conf.Batch = 100
+	}
+	if conf.MaxWait == 0 {
+		conf.MaxWait = time.Second
+	}
+
+	nc, err := nats.Connect(conf.URL, nats.Name("condukt "+conf.Name))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	consumer, err := js.CreateOrUpdateConsumer(ctx, conf.Stream, jetstream.ConsumerConfig{
+		Durable:       conf.Durable,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		FilterSubject: conf.FilterSubject,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &JetStreamSource{nc: nc, consumer: consumer, batch: conf.Batch, maxWait: conf.MaxWait}, nil
+}
+
+// Read fetches the next batch, waiting up to the source's max wait for it.
+func (s *JetStreamSource) Read(ctx context.Context) ([]SourceMsg, error) {
+	batch, err := s.consumer.Fetch(s.batch, jetstream.FetchMaxWait(s.maxWait))
+	if err != nil {
+		return nil, err
+	}
+	var msgs []SourceMsg
+	for msg := range batch.Messages() {
+		headers := map[string]string{HeaderNATSSubject: msg.Subject()}
+		for name, values := range msg.Headers() {
+			if len(values) > 0 {
+				headers[name] = values[0]
+			}
+		}
+		msgs = append(msgs, SourceMsg{Payload: string(msg.Data()), Headers: headers, Cursor: msg})
+	}
+	return msgs, batch.Error()
+}
+
+// Commit acknowledges msgs, waiting for JetStream to confirm each, so the durable consumer's
+// cursor moves past them.
+func (s *JetStreamSource) Commit(ctx context.Context, msgs []SourceMsg) error {
+	for _, msg := range msgs {
+		if err := msg.Cursor.(jetstream.Msg).DoubleAck(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close drains and closes the NATS connection.
+func (s *JetStreamSource) Close() error {
+	return s.nc.Drain()
+}
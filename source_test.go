@@ -0,0 +1,246 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/jkassis/condukt/store"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeSource returns its pending messages a batch at a time, failing the first failures commits.
+type fakeSource struct {
+	mu        sync.Mutex
+	pending   [][]SourceMsg
+	failures  int
+	committed []any // Cursors of successful commits
+	closed    bool
+}
+
+func (s *fakeSource) Read(ctx context.Context) ([]SourceMsg, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil, nil
+	}
+	return s.pending[0], nil
+}
+
+func (s *fakeSource) Commit(ctx context.Context, msgs []SourceMsg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("source unavailable")
+	}
+	for _, msg := range msgs {
+		s.committed = append(s.committed, msg.Cursor)
+	}
+	s.pending = s.pending[1:]
+	return nil
+}
+
+func (s *fakeSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Test A Source's Messages Are Sent To Its Strand Before Their Cursor Is Committed
+func TestSourceServe(t *testing.T) {
+	ctx := context.Background()
+	volatile := store.RamStoreMake()
+	mq := ConduktorMake(volatile, store.RamStoreMake(), wire.GoChanWireMake())
+	assert.NoError(t, mq.StrandAdd("imported_channel", StrandConf{}))
+
+	source := &fakeSource{failures: 1, pending: [][]SourceMsg{
+		{{Payload: "0", Headers: map[string]string{HeaderNATSSubject: "orders.eu"}, Cursor: 0}, {Payload: "1", Cursor: 1}},
+		{{Payload: "2", Cursor: 2}},
+	}}
+	stop := mq.SourceServe(source, SourceConf{Name: "test", Strand: "imported_channel", RetryDelay: time.Millisecond})
+	assert.Eventually(t, func() bool {
+		source.mu.Lock()
+		defer source.mu.Unlock()
+		return len(source.pending) == 0
+	}, time.Second, time.Millisecond)
+	stop()
+	assert.Equal(t, []any{0, 1, 2}, source.committed)
+	assert.True(t, source.closed)
+
+	// The batch whose commit failed was read and sent again: at least once
+	sub, err := mq.Subscribe("imported_channel")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer sub.Close()
+	var payloads []string
+	for range 5 {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		msg, err := sub.Next(ctx)
+		cancel()
+		if !assert.NoError(t, err) {
+			return
+		}
+		payloads = append(payloads, msg.Payload)
+		if msg.Payload == "0" {
+			assert.Equal(t, "orders.eu", msg.Headers[HeaderNATSSubject])
+		}
+	}
+	assert.Equal(t, []string{"0", "1", "0", "1", "2"}, payloads)
+}
+
+// Test wal2json Transactions Decode Into One Message Per Row Change
+func TestPostgresChangesDecode(t *testing.T) {
+	msgs, err := pgChangesDecode([]pgChange{
+		{lsn: "0/16B3748", data: `{"action":"B"}`},
+		{lsn: "0/16B3748", data: `{"action":"I","schema":"public","table":"orders","columns":[{"name":"id","type":"integer","value":1}]}`},
+		{lsn: "0/16B37F0", data: `{"action":"M","transactional":true,"prefix":"audit","content":"x"}`},
+		{lsn: "0/16B3800", data: `{"action":"D","schema":"public","table":"orders","identity":[{"name":"id","type":"integer","value":1}]}`},
+		{lsn: "0/16B3860", data: `{"action":"C"}`},
+		{lsn: "0/16B3900", data: `{"action":"B"}`},
+		{lsn: "0/16B3960", data: `{"action":"C"}`},
+	})
+	if !assert.NoError(t, err) || !assert.Len(t, msgs, 2) {
+		return
+	}
+	assert.Equal(t, "public.orders", msgs[0].Headers[HeaderPGTable])
+	assert.Equal(t, "insert", msgs[0].Headers[HeaderPGAction])
+	assert.Equal(t, "delete", msgs[1].Headers[HeaderPGAction])
+	assert.Contains(t, msgs[0].Payload, `"columns"`)
+	for _, msg := range msgs {
+		assert.Equal(t, "0/16B3860", msg.Headers[HeaderPGLSN])
+		assert.Equal(t, "0/16B3860", msg.Cursor)
+	}
+
+	_, err = pgChangesDecode([]pgChange{{lsn: "0/1", data: "{"}})
+	assert.Error(t, err)
+}
+
+// Test The SQS Source Imports Attributes And Message Groups, And Deletes Committed Messages
+func TestSQSSource(t *testing.T) {
+	ctx := context.Background()
+	fake := &sqsFake{pending: []types.Message{{
+		MessageId:         aws.String("sqs-1"),
+		ReceiptHandle:     aws.String("receipt-1"),
+		Body:              aws.String("One"),
+		Attributes:        map[string]string{string(types.MessageSystemAttributeNameMessageGroupId): "customer-1"},
+		MessageAttributes: map[string]types.MessageAttributeValue{"trace": {DataType: aws.String("String"), StringValue: aws.String("t")}},
+	}}}
+	source := SQSSourceMake(fake, SQSSourceConf{Queue: "https://sqs.us-east-1.amazonaws.com/123456789012/jobs.fifo"})
+
+	msgs, err := source.Read(ctx)
+	if !assert.NoError(t, err) || !assert.Len(t, msgs, 1) {
+		return
+	}
+	assert.Equal(t, "One", msgs[0].Payload)
+	assert.Equal(t, map[string]string{HeaderSQSMessageID: "sqs-1", "trace": "t", "key": "customer-1"}, msgs[0].Headers)
+	assert.NoError(t, source.Commit(ctx, msgs))
+	assert.Equal(t, []string{"receipt-1"}, fake.deleted)
+}
+
+// Test Messages Bridged Out To Pub/Sub And Back Keep Their Headers And Ordering Key
+func TestPubSubBridge(t *testing.T) {
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	defer srv.Close()
+	client := func() *pubsub.Client { // Closing a client closes its connection, so each has its own
+		conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		assert.NoError(t, err)
+		client, err := pubsub.NewClient(ctx, "project", option.WithGRPCConn(conn))
+		assert.NoError(t, err)
+		return client
+	}
+	admin := client()
+	defer admin.Close()
+	topic, err := admin.CreateTopic(ctx, "events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = admin.CreateSubscription(ctx, "condukt-events", pubsub.SubscriptionConfig{Topic: topic, EnableMessageOrdering: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sink := PubSubSinkMake(client(), PubSubSinkConf{Topic: "events"})
+	assert.NoError(t, sink.Write(ctx, "events", []*Msg{
+		{ID: "m0", Payload: "0", Headers: map[string]string{"key": "customer-1", "trace": "t"}},
+		{ID: "m1", Payload: "1", Headers: map[string]string{"key": "customer-1"}},
+	}))
+	assert.NoError(t, sink.Close())
+
+	// Messages read but not committed when the source closes are redelivered
+	source, err := PubSubSourceMake(ctx, client(), PubSubSourceConf{Subscription: "condukt-events", MaxWait: time.Second})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, source.Ordered())
+	assert.Eventually(t, func() bool {
+		read, err := source.Read(ctx)
+		return err == nil && len(read) > 0
+	}, 5*time.Second, time.Millisecond)
+	assert.NoError(t, source.Close())
+
+	source, err = PubSubSourceMake(ctx, client(), PubSubSourceConf{Subscription: "condukt-events", MaxWait: time.Second})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer source.Close()
+	var msgs []SourceMsg
+	assert.Eventually(t, func() bool {
+		read, err := source.Read(ctx)
+		assert.NoError(t, err)
+		assert.NoError(t, source.Commit(ctx, read)) // An ordered subscription delivers a key's next message once acked
+		msgs = append(msgs, read...)
+		return len(msgs) == 2
+	}, 5*time.Second, time.Millisecond)
+	if !assert.Len(t, msgs, 2) {
+		return
+	}
+	assert.Equal(t, "0", msgs[0].Payload)
+	assert.Equal(t, "1", msgs[1].Payload)
+	assert.Equal(t, "customer-1", msgs[0].Headers["key"])
+	assert.Equal(t, "t", msgs[0].Headers["trace"])
+	assert.NotEmpty(t, msgs[0].Headers[HeaderPubSubMessageID])
+}
+
+// Test Service Bus Sessions Map To Keys And Dead Letters To The DLQ Headers
+func TestServiceBusMsgCoding(t *testing.T) {
+	sent := serviceBusMsgEncode(&Msg{ID: "m1", Payload: "order", Headers: map[string]string{"key": "cust-7", "region": "eu"}}, "orders", true, "key")
+	assert.Equal(t, "m1", *sent.MessageID)
+	assert.Equal(t, "cust-7", *sent.SessionID)
+	assert.Equal(t, "eu", sent.ApplicationProperties["region"])
+	unkeyed := serviceBusMsgEncode(&Msg{ID: "m2", Payload: "order"}, "orders", true, "key")
+	assert.Equal(t, "orders", *unkeyed.SessionID)
+	assert.Nil(t, serviceBusMsgEncode(&Msg{ID: "m3"}, "orders", false, "key").SessionID)
+
+	received := &azservicebus.ReceivedMessage{
+		MessageID:             "m1",
+		Body:                  sent.Body,
+		SessionID:             sent.SessionID,
+		ApplicationProperties: map[string]any{"region": "eu", "attempt": int64(3)},
+	}
+	msg := serviceBusMsgDecode(received, "key", false, "orders")
+	assert.Equal(t, "order", msg.Payload)
+	assert.Equal(t, map[string]string{"region": "eu", "attempt": "3", "key": "cust-7", HeaderServiceBusMessageID: "m1"}, msg.Headers)
+	assert.Same(t, received, msg.Cursor)
+
+	reason, description := "MaxDeliveryCountExceeded", "delivered 10 times"
+	received.DeadLetterReason, received.DeadLetterErrorDescription = &reason, &description
+	dead := serviceBusMsgDecode(received, "key", true, "orders")
+	assert.Equal(t, "MaxDeliveryCountExceeded: delivered 10 times", dead.Headers[HeaderDLQReason])
+	assert.Equal(t, "orders", dead.Headers[HeaderDLQSource])
+	assert.Equal(t, "cust-7", dead.Headers["key"])
+}
@@ -1,31 +1,37 @@
-package main
+package condukt
 
-// Store defines the interface for message storage and strand management.
-type Store interface {
-	// Strand Management
-	CreateStrand(StrandID string, config StrandConf) error
-	DeleteStrand(StrandID string) error
-	HasStrand(StrandID string) bool // Check if a strand exists
+import "github.com/jkassis/condukt/store"
 
-	// Message Handling
-	Save(msg Msg) error
-	Acknowledge(StrandID, msgID string) error
+// Store keeps strands and their unacked messages. Implementations are in package store.
+type Store = store.Store
 
-	// Unacked Message Iterator
-	UnackedIterator() (UnackedMessageIterator, error)
+// UnackedMessageIterator iterates over a store's unacked messages.
+type UnackedMessageIterator = store.UnackedMessageIterator
 
-	// Close the store
-	Close() error
+// EncodedSaver is a Store that can save a message already encoded with wire.MsgEncode.
+type EncodedSaver = store.EncodedSaver
 
-	// Close and reopen
-	Reload() error
+// PressureReporter is a Store that can tell when it is falling behind on writes.
+type PressureReporter = store.PressureReporter
 
-	// Close and reopen
-	Reset() error
-}
+// GroupCommitConf configures grouped commits of BadgerStore saves.
+type GroupCommitConf = store.GroupCommitConf
 
-// UnackedMessageIterator defines an interface for iterating over unacknowledged messages.
-type UnackedMessageIterator interface {
-	Next() (*Msg, bool) // Returns the next message and a bool indicating if more messages exist
-	Close() error       // Cleans up the iterator resources
-}
+// SnapshotConf configures periodic snapshots of a RamStore.
+type SnapshotConf = store.SnapshotConf
+
+// StrandConf holds per-strand settings.
+type StrandConf = store.StrandConf
+
+// Overflow actions, taken when a send would take a strand over its MaxBytes.
+const (
+	OverflowReject = store.OverflowReject
+	OverflowEvict  = store.OverflowEvict
+)
+
+// Named presets for common kinds of strand.
+var (
+	PresetWorkQueue = store.PresetWorkQueue // Durable and unordered, refusing sends when full
+	PresetEventLog  = store.PresetEventLog  // Durable and ordered
+	PresetRealtime  = store.PresetRealtime  // Volatile and ordered, evicting the oldest past 64MiB
+)
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Overflow actions, taken when a send would take a strand over its MaxBytes.
+const (
+	OverflowReject = "reject" // Refuse the send with ErrStrandFull (the default)
+	OverflowEvict  = "evict"  // Drop the oldest unacked messages to make room
+)
+
+// StrandConf holds per-queue settings.
+type StrandConf struct {
+	Durable bool
+	Ordered bool
+
+	// ReplicationFactor is the number of cluster nodes, including the owner, that persist each message.
+	// Values below 2 disable replication.
+	ReplicationFactor int
+
+	// MaxBytes caps the bytes of the strand's unacked messages, as the sum of their wire.Msg.Size
+	// whichever store holds them; 0 is unlimited.
+	// Overflow is what a send that would exceed it does: OverflowReject or OverflowEvict.
+	MaxBytes int64
+	Overflow string
+}
+
+// Named presets for common kinds of strand.
+var (
+	// PresetWorkQueue spreads durable jobs over competing consumers, refusing sends when full.
+	PresetWorkQueue = StrandConf{Durable: true, Overflow: OverflowReject}
+
+	// PresetEventLog keeps durable events in the order they were sent.
+	PresetEventLog = StrandConf{Durable: true, Ordered: true, Overflow: OverflowReject}
+
+	// PresetRealtime delivers volatile updates in order, dropping the oldest when more than 64MiB
+	// are unacked.
+	PresetRealtime = StrandConf{Ordered: true, MaxBytes: 64 << 20, Overflow: OverflowEvict}
+)
+
+// Presets maps preset names, as used in configuration files, to presets.
+var Presets = map[string]StrandConf{
+	"work_queue": PresetWorkQueue,
+	"event_log":  PresetEventLog,
+	"realtime":   PresetRealtime,
+}
+
+// Defaults returns conf with unset settings filled in.
+func (conf StrandConf) Defaults() StrandConf {
+	if conf.Overflow == "" {
+		conf.Overflow = OverflowReject
+	}
+	return conf
+}
+
+// Validate checks the settings are usable together.
+func (conf StrandConf) Validate() error {
+	var errs []error
+	if conf.MaxBytes < 0 {
+		errs = append(errs, errors.New("MaxBytes must not be negative"))
+	}
+	if conf.ReplicationFactor < 0 {
+		errs = append(errs, errors.New("ReplicationFactor must not be negative"))
+	}
+	if conf.ReplicationFactor > 1 && !conf.Durable {
+		errs = append(errs, errors.New("ReplicationFactor requires a durable strand"))
+	}
+	switch conf.Overflow {
+	case "", OverflowReject:
+	case OverflowEvict:
+		if conf.MaxBytes == 0 {
+			errs = append(errs, fmt.Errorf("Overflow %s requires MaxBytes", OverflowEvict))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown Overflow %q (want %s or %s)", conf.Overflow, OverflowReject, OverflowEvict))
+	}
+	return errors.Join(errs...)
+}
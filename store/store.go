@@ -0,0 +1,66 @@
+// Package store implements the stores a Conduktor keeps strands in: RamStore in memory and
+// BadgerStore on disk.
+package store
+
+import (
+	"context"
+
+	"github.com/jkassis/condukt/wire"
+)
+
+// Store defines the interface for message storage and strand management. Operations return
+// ctx.Err() rather than start, or carry on with, work once ctx is done.
+type Store interface {
+	// Strand Management
+	CreateStrand(ctx context.Context, StrandID string, config StrandConf) error
+	DeleteStrand(ctx context.Context, StrandID string) error
+	HasStrand(ctx context.Context, StrandID string) bool // Check if a strand exists
+
+	// Message Handling
+	Save(ctx context.Context, msg wire.Msg) error
+	Acknowledge(ctx context.Context, StrandID, msgID string) error
+	AcknowledgeBatch(ctx context.Context, StrandID string, msgIDs []string) error // Acknowledge all of msgIDs in one transaction, or none if any is missing
+
+	// Inspection
+	ListStrands(ctx context.Context) (map[string]StrandConf, error)           // All strands and their configs
+	Get(ctx context.Context, StrandID, msgID string) (*wire.Msg, error)       // A single unacked message
+	Peek(ctx context.Context, StrandID string, limit int) ([]wire.Msg, error) // Oldest unacked messages, without consuming them (limit <= 0 for all)
+	Depth(ctx context.Context, StrandID string) (int, error)                  // Number of unacked messages
+	Bytes(ctx context.Context, StrandID string) (int64, error)                // Sum of the wire.Msg.Size of unacked messages
+	Purge(ctx context.Context, StrandID string) (int, error)                  // Delete all messages but keep the strand
+	Reconcile(ctx context.Context) error                                      // Recount depths and bytes, correcting tracked values and the queue_size gauge
+
+	// Unacked Message Iterator
+	UnackedIterator(ctx context.Context) (UnackedMessageIterator, error)
+
+	// Verify the store accepts writes
+	Ping(ctx context.Context) error
+
+	// Close the store
+	Close() error
+
+	// Close and reopen
+	Reload(ctx context.Context) error
+
+	// Close and reopen
+	Reset(ctx context.Context) error
+}
+
+// EncodedSaver is a Store that can save a message already encoded with its codec, so a message
+// both stored and sent is encoded once.
+type EncodedSaver interface {
+	SaveEncoded(ctx context.Context, msg wire.Msg, data []byte) error // data is msg's encoding, not retained
+	Codec() string                                                    // Codec the store encodes with
+}
+
+// PressureReporter is a Store that can tell when it is falling behind on writes, before its saves
+// slow down.
+type PressureReporter interface {
+	UnderPressure() bool
+}
+
+// UnackedMessageIterator defines an interface for iterating over unacknowledged messages.
+type UnackedMessageIterator interface {
+	Next() (*wire.Msg, bool) // Returns the next message and a bool indicating if more messages exist
+	Close() error            // Cleans up the iterator resources
+}
@@ -0,0 +1,712 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+// BadgerStore implements a durable message store using BadgerDB.
+type BadgerStore struct {
+	db     *badger.DB
+	mu     sync.RWMutex     // Read-locked by operations badger runs concurrently, locked by the rest
+	counts sync.Mutex       // Guards depths and bytes when mu is only read-locked
+	path   string           // Store the original path
+	known  map[string]bool  // Strands with a stored config, so HasStrand needs no transaction
+	group  *groupCommitter  // Set while GroupCommitServe runs
+	depths map[string]int   // Strand -> tracked unacked message count
+	bytes  map[string]int64 // Strand -> tracked size of unacked message values
+	codec  string           // Codec messages are saved with
+	log    *zap.Logger
+}
+
+// BadgerStoreMake initializes and opens a BadgerDB-backed message store with sync writes enabled.
+func BadgerStoreMake(path string, options ...telemetry.MakeOption) (*BadgerStore, error) {
+	made := telemetry.MakeOptsApply(options)
+	if err := wire.MsgCodecCheck(made.Codec); err != nil {
+		return nil, err
+	}
+	opts := badger.DefaultOptions(path).
+		WithSyncWrites(true).          // Ensures writes are flushed to disk immediately
+		WithLoggingLevel(badger.ERROR) // Reduce log noise
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BadgerStore{db: db, path: path, known: make(map[string]bool), depths: make(map[string]int), bytes: make(map[string]int64), codec: made.Codec, log: made.Log}
+	s.RecoverStrands() // Recover strands on startup
+	if err := s.Reconcile(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateStrand registers a new strand with a given configuration.
+func (s *BadgerStore) CreateStrand(ctx context.Context, strandID string, config StrandConf) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("strand-config:%s", strandID)
+
+	err = s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), configData)
+	})
+
+	if err == nil {
+		s.known[strandID] = true
+		s.log.Debug("Strand created in BadgerDB", zap.String("strand", strandID))
+	}
+	return err
+}
+
+// DeleteStrand removes a strand and all associated messages.
+func (s *BadgerStore) DeleteStrand(ctx context.Context, strandID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Delete all messages associated with the strand
+	err := s.db.Update(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("msg:%s:", strandID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			key := item.KeyCopy(nil)
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// Delete the strand configuration
+	key := fmt.Sprintf("strand-config:%s", strandID)
+	err = s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+
+	if err == nil {
+		delete(s.known, strandID)
+	}
+	delete(s.depths, strandID)
+	delete(s.bytes, strandID)
+	telemetry.QueueSize.DeleteLabelValues(strandID)
+	telemetry.QueueBytes.DeleteLabelValues(strandID)
+	if err == nil {
+		s.log.Info("Strand deleted from BadgerDB", zap.String("strand", strandID))
+	}
+	return err
+}
+
+// HasStrand checks if a strand exists in the store.
+func (s *BadgerStore) HasStrand(ctx context.Context, strandID string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.known[strandID]
+}
+
+// RecoverStrands restores all strands on startup.
+func (s *BadgerStore) RecoverStrands() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.known)
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("strand-config:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var config StrandConf
+				if err := json.Unmarshal(val, &config); err != nil {
+					return err
+				}
+				strandID := string(item.Key()[len(prefix):])
+				s.known[strandID] = true
+				s.log.Debug("Recovered strand from BadgerDB", zap.String("strand", strandID))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return err
+}
+
+// UnderPressure reports whether compaction is falling behind: level 0 holds over halfway from the
+// tables that start compaction to those that stall writes.
+func (s *BadgerStore) UnderPressure() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	opts := s.db.Opts()
+	for _, level := range s.db.Levels() {
+		if level.Level == 0 {
+			return level.NumTables >= (opts.NumLevelZeroTables+opts.NumLevelZeroTablesStall)/2
+		}
+	}
+	return false
+}
+
+// Ping writes and deletes a probe key to verify BadgerDB accepts writes.
+func (s *BadgerStore) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := []byte("health:probe")
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(key, []byte(time.Now().Format(time.RFC3339Nano))); err != nil {
+			return err
+		}
+		return txn.Delete(key)
+	})
+}
+
+// Backup writes a full snapshot of the database to w in Badger's backup format, which DB.Load restores.
+func (s *BadgerStore) Backup(w io.Writer) error {
+	s.mu.Lock()
+	db := s.db
+	s.mu.Unlock()
+
+	_, err := db.Backup(w, 0)
+	return err
+}
+
+// Close closes the BadgerDB connection.
+func (s *BadgerStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log.Debug("Closing BadgerDB")
+	return s.db.Close()
+}
+
+// Reset clears all data in the BadgerStore by closing and reopening the database.
+func (s *BadgerStore) Reset(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Close the database
+	if err := s.db.Close(); err != nil {
+		s.log.Error("Failed to close BadgerDB during reset", zap.String("path", s.path), zap.Error(err))
+		return err
+	}
+
+	// Wait for OS to release lock
+	time.Sleep(500 * time.Millisecond)
+
+	// Ensure the directory is empty before reopening
+	os.RemoveAll(s.path)
+
+	// Reopen the database using the same path
+	opts := badger.DefaultOptions(s.path).WithSyncWrites(true).WithLoggingLevel(badger.ERROR)
+	db, err := badger.Open(opts)
+	if err != nil {
+		s.log.Error("Failed to reopen BadgerDB during reset", zap.String("path", s.path), zap.Error(err))
+		return err
+	}
+
+	s.db = db
+	clear(s.known)
+	for strandID := range s.depths {
+		telemetry.QueueSize.DeleteLabelValues(strandID)
+		telemetry.QueueBytes.DeleteLabelValues(strandID)
+	}
+	s.depths = make(map[string]int)
+	s.bytes = make(map[string]int64)
+	s.log.Debug("BadgerStore reset completed", zap.String("path", s.path))
+	return nil
+}
+
+// Reload closes and reopens the BadgerDB store without deleting data.
+func (s *BadgerStore) Reload(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Close the database
+	if err := s.db.Close(); err != nil {
+		s.log.Error("Failed to close BadgerDB during reload", zap.String("path", s.path), zap.Error(err))
+		return err
+	}
+
+	// Wait for OS to release lock
+	time.Sleep(500 * time.Millisecond)
+
+	// Reopen the database using the same path
+	opts := badger.DefaultOptions(s.path).WithSyncWrites(true).WithLoggingLevel(badger.ERROR)
+	db, err := badger.Open(opts)
+	if err != nil {
+		s.log.Error("Failed to reopen BadgerDB during reload", zap.String("path", s.path), zap.Error(err))
+		return err
+	}
+
+	s.db = db
+	s.RecoverStrands()
+	if err := s.Reconcile(ctx); err != nil {
+		return err
+	}
+	s.log.Debug("BadgerStore reloaded", zap.String("path", s.path))
+	return nil
+}
+
+// Save persists a message to BadgerDB with a "msg:" prefix.
+func (s *BadgerStore) Save(ctx context.Context, msg wire.Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return wire.MsgEncodePooledCodec(msg, s.codec, func(data []byte) error {
+		return s.SaveEncoded(ctx, msg, data)
+	})
+}
+
+// Codec returns the codec s saves messages with.
+func (s *BadgerStore) Codec() string {
+	return s.codec
+}
+
+// SaveEncoded persists a message encoded as data. Badger is done with data when it returns.
+func (s *BadgerStore) SaveEncoded(ctx context.Context, msg wire.Msg, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	save := &pendingSave{strand: msg.Strand, key: []byte("msg:" + msg.Strand + ":" + msg.ID), data: data, size: msg.Size()}
+	s.mu.RLock()
+	group := s.group
+	s.mu.RUnlock()
+	if group != nil {
+		if grouped, err := group.groupSave(ctx, save); grouped {
+			return err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	err := s.commit([]*pendingSave{save})
+	if err == nil {
+		s.log.Debug("Message saved to BadgerDB", zap.String("strand", msg.Strand), zap.String("msgID", msg.ID))
+	}
+	return err
+}
+
+// Acknowledge marks a message as processed and removes it from BadgerDB.
+func (s *BadgerStore) Acknowledge(ctx context.Context, strandID, msgID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := fmt.Sprintf("msg:%s:%s", strandID, msgID) // Updated key format
+
+	removed := false
+	var size int64
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		removed = err == nil
+		if removed {
+			if size, err = itemMsgSize(item); err != nil {
+				return err
+			}
+		}
+		return txn.Delete([]byte(key))
+	})
+
+	if err == nil && removed {
+		s.counts.Lock()
+		s.depthAdd(strandID, -1)
+		s.bytesAdd(strandID, -size)
+		s.counts.Unlock()
+	}
+	if err == nil {
+		s.log.Debug("Message acknowledged and deleted from BadgerDB", zap.String("strand", strandID), zap.String("msgID", msgID))
+	}
+	return err
+}
+
+// AcknowledgeBatch removes all of msgIDs from BadgerDB in one transaction, or none of them if any
+// is missing.
+func (s *BadgerStore) AcknowledgeBatch(ctx context.Context, strandID string, msgIDs []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	removed := 0
+	var size int64
+	err := s.db.Update(func(txn *badger.Txn) error {
+		seen := make(map[string]bool, len(msgIDs))
+		for _, msgID := range msgIDs {
+			if seen[msgID] { // Repeated IDs are removed once
+				continue
+			}
+			seen[msgID] = true
+			key := []byte(fmt.Sprintf("msg:%s:%s", strandID, msgID))
+			item, err := txn.Get(key)
+			if err == badger.ErrKeyNotFound {
+				return fmt.Errorf("message not found: %s", msgID)
+			}
+			if err != nil {
+				return err
+			}
+			itemSize, err := itemMsgSize(item)
+			if err != nil {
+				return err
+			}
+			removed++
+			size += itemSize
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		s.counts.Lock()
+		s.depthAdd(strandID, -removed)
+		s.bytesAdd(strandID, -size)
+		s.counts.Unlock()
+		s.log.Debug("Messages acknowledged and deleted from BadgerDB", zap.String("strand", strandID), zap.Int("messages", removed))
+	}
+	return err
+}
+
+// ListStrands returns all strands and their configs.
+func (s *BadgerStore) ListStrands(ctx context.Context) (map[string]StrandConf, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	strands := make(map[string]StrandConf)
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("strand-config:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var config StrandConf
+				if err := json.Unmarshal(val, &config); err != nil {
+					return err
+				}
+				strands[string(item.Key()[len(prefix):])] = config
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return strands, err
+}
+
+// Get returns a single unacked message.
+func (s *BadgerStore) Get(ctx context.Context, strandID, msgID string) (*wire.Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var msg wire.Msg
+	key := fmt.Sprintf("msg:%s:%s", strandID, msgID)
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) (err error) {
+			msg, err = wire.MsgDecode(val) // Not shared: val is only valid until the transaction ends
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Peek returns up to limit of the oldest unacked messages without consuming them.
+func (s *BadgerStore) Peek(ctx context.Context, strandID string, limit int) ([]wire.Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := []wire.Msg{}
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("msg:%s:", strandID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if limit > 0 && len(messages) >= limit {
+				break
+			}
+			err := it.Item().Value(func(val []byte) error {
+				msg, err := wire.MsgDecode(val)
+				if err != nil {
+					return err
+				}
+				messages = append(messages, msg)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return messages, err
+}
+
+// Depth returns the tracked number of unacked messages in a strand.
+func (s *BadgerStore) Depth(ctx context.Context, strandID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.counts.Lock()
+	defer s.counts.Unlock()
+	return s.depths[strandID], nil
+}
+
+// Bytes returns the tracked size of the stored unacked messages in a strand.
+func (s *BadgerStore) Bytes(ctx context.Context, strandID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.counts.Lock()
+	defer s.counts.Unlock()
+	return s.bytes[strandID], nil
+}
+
+// Reconcile counts the unacked messages and bytes of every strand, correcting tracked values that drifted.
+func (s *BadgerStore) Reconcile(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	sizes := make(map[string]int64)
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte("msg:")
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// Keys are msg:<strand>:<msgID>, and message IDs never contain ':'
+			key := string(it.Item().Key()[len("msg:"):])
+			if i := strings.LastIndexByte(key, ':'); i >= 0 {
+				size, err := itemMsgSize(it.Item())
+				if err != nil {
+					return err
+				}
+				counts[key[:i]]++
+				sizes[key[:i]] += size
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for strandID, depth := range s.depths {
+		if _, exists := counts[strandID]; !exists && depth != 0 {
+			counts[strandID] = 0
+		}
+	}
+	for strandID, count := range counts {
+		if tracked := s.depths[strandID]; tracked != count {
+			s.log.Debug("Corrected strand depth", zap.String("strand", strandID), zap.Int("tracked", tracked), zap.Int("actual", count))
+		}
+		s.depths[strandID] = count
+		s.bytes[strandID] = sizes[strandID]
+		telemetry.QueueSize.WithLabelValues(strandID).Set(float64(count))
+		telemetry.QueueBytes.WithLabelValues(strandID).Set(float64(sizes[strandID]))
+	}
+	return nil
+}
+
+// itemMsgSize returns the Msg.Size of the message item holds, the measure strand bytes are counted
+// in, so MaxBytes means the same in every store. Binary values are sized without decoding them.
+func itemMsgSize(item *badger.Item) (int64, error) {
+	var size int64
+	err := item.Value(func(val []byte) (err error) {
+		size, err = wire.MsgSize(val)
+		return err
+	})
+	return size, err
+}
+
+// depthAdd adjusts a strand's tracked depth. Callers must hold s.mu, or read-lock it and hold s.counts.
+func (s *BadgerStore) depthAdd(strandID string, delta int) {
+	s.depths[strandID] = max(s.depths[strandID]+delta, 0)
+	telemetry.QueueSize.WithLabelValues(strandID).Set(float64(s.depths[strandID]))
+}
+
+// bytesAdd adjusts a strand's tracked bytes. Callers must hold s.mu, or read-lock it and hold s.counts.
+func (s *BadgerStore) bytesAdd(strandID string, delta int64) {
+	s.bytes[strandID] = max(s.bytes[strandID]+delta, 0)
+	telemetry.QueueBytes.WithLabelValues(strandID).Set(float64(s.bytes[strandID]))
+}
+
+// Purge deletes all messages in a strand but keeps the strand.
+func (s *BadgerStore) Purge(ctx context.Context, strandID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	err := s.db.Update(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		prefix := []byte(fmt.Sprintf("msg:%s:", strandID))
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := txn.Delete(it.Item().KeyCopy(nil)); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+
+	if err == nil {
+		s.depths[strandID] = 0
+		s.bytes[strandID] = 0
+		telemetry.QueueSize.WithLabelValues(strandID).Set(0)
+		telemetry.QueueBytes.WithLabelValues(strandID).Set(0)
+		s.log.Info("Strand purged in BadgerDB", zap.String("strand", strandID), zap.Int("messages", purged))
+	}
+	return purged, err
+}
+
+// UnackedIterator returns an iterator over all unacknowledged messages across all strands, decoding
+// them into the wire.Arena ctx carries, if any.
+func (s *BadgerStore) UnackedIterator(ctx context.Context) (UnackedMessageIterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txn := s.db.NewTransaction(false)
+
+	// Set iterator options with prefix "msg:"
+	itOpts := badger.DefaultIteratorOptions
+	itOpts.Prefix = []byte("msg:") // Ensures iteration starts at "msg:"
+	it := txn.NewIterator(itOpts)
+	it.Rewind()
+
+	return &BadgerUnackedIterator{txn: txn, it: it, prefix: itOpts.Prefix, arena: wire.ArenaFrom(ctx)}, nil
+}
+
+// BadgerUnackedIterator iterates over unacknowledged messages in BadgerDB.
+type BadgerUnackedIterator struct {
+	txn    *badger.Txn
+	it     *badger.Iterator
+	prefix []byte
+	arena  *wire.Arena // Decodes messages, if set
+}
+
+// Next retrieves the next unacknowledged message across all strands.
+func (it *BadgerUnackedIterator) Next() (*wire.Msg, bool) {
+	if it.it.ValidForPrefix(it.prefix) {
+		item := it.it.Item()
+		var msg *wire.Msg
+		err := item.Value(func(val []byte) (err error) {
+			if it.arena != nil {
+				msg, err = it.arena.Decode(val)
+				return err
+			}
+			decoded, err := wire.MsgDecode(val)
+			msg = &decoded
+			return err
+		})
+		if err != nil {
+			return nil, false
+		}
+
+		it.it.Next()
+		return msg, true
+	}
+	return nil, false
+}
+
+// Close cleans up iterator resources.
+func (it *BadgerUnackedIterator) Close() error {
+	it.it.Close()
+	it.txn.Discard()
+	return nil
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"go.uber.org/zap"
+)
+
+// GroupCommitConf configures grouped commits of BadgerStore saves: concurrent saves share one
+// transaction instead of committing each. MaxBatch near the number of concurrent senders lets groups
+// commit as they fill, rather than waiting out MaxDelay.
+type GroupCommitConf struct {
+	MaxBatch int           `yaml:"max_batch"` // Saves a group commits at most; 0 is unlimited
+	MaxDelay time.Duration `yaml:"max_delay"` // Longest the first save of a group waits for others to join; the server groups saves only if set
+}
+
+// Validate reports negative settings.
+func (conf GroupCommitConf) Validate() error {
+	if conf.MaxBatch < 0 || conf.MaxDelay < 0 {
+		return errors.New("group_commit: max_batch and max_delay must not be negative")
+	}
+	return nil
+}
+
+// pendingSave is a save waiting for its group to commit.
+type pendingSave struct {
+	strand string
+	key    []byte
+	data   []byte
+	size   int64      // Msg.Size of the message, which strand bytes are counted in
+	done   chan error // Receives the result of the commit, if set
+}
+
+// groupCommitter collects saves for GroupCommitServe's loop.
+type groupCommitter struct {
+	saves   chan *pendingSave
+	stop    chan struct{} // Closed to flush the last group and end the loop
+	stopped chan struct{} // Closed when the loop has ended
+}
+
+// GroupCommitServe commits saves in groups, until stop is called: a group commits once it has
+// conf.MaxBatch saves or its first save has waited conf.MaxDelay. Each Save returns once its group
+// has committed, so it is as durable as an ungrouped one. A Save whose context ends while it waits
+// returns the context's error, but may still be committed. Call stop before closing the store.
+func (s *BadgerStore) GroupCommitServe(conf GroupCommitConf) (stop func()) {
+	g := &groupCommitter{saves: make(chan *pendingSave), stop: make(chan struct{}), stopped: make(chan struct{})}
+	s.mu.Lock()
+	s.group = g
+	s.mu.Unlock()
+
+	// One goroutine commits groups while another forms the next
+	commits := make(chan []*pendingSave)
+	go func() {
+		defer close(g.stopped)
+		for group := range commits {
+			s.mu.RLock()
+			if err := s.commit(group); err == nil {
+				s.log.Debug("Message group committed to BadgerDB", zap.Int("messages", len(group)))
+			}
+			s.mu.RUnlock()
+		}
+	}()
+	go func() {
+		defer close(commits)
+		var group []*pendingSave
+		var delay <-chan time.Time      // Set while a group waits for saves to join
+		var ready chan<- []*pendingSave // Set to commits once the group is ready to commit
+		for {
+			saves := g.saves
+			if conf.MaxBatch > 0 && len(group) >= conf.MaxBatch {
+				saves = nil // Full; wait for the previous group to commit
+			}
+			select {
+			case save := <-saves:
+				group = append(group, save)
+				if len(group) == 1 {
+					delay = time.After(conf.MaxDelay)
+				}
+				if conf.MaxBatch > 0 && len(group) >= conf.MaxBatch {
+					ready = commits
+				}
+			case <-delay:
+				delay, ready = nil, commits
+			case ready <- group:
+				group, delay, ready = nil, nil, nil
+			case <-g.stop:
+				if len(group) > 0 {
+					commits <- group
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(g.stop)
+		<-g.stopped
+		s.mu.Lock()
+		if s.group == g {
+			s.group = nil
+		}
+		s.mu.Unlock()
+	}
+}
+
+// groupSave hands save to the group commit loop and waits for its commit, reporting false if the
+// loop has stopped.
+func (g *groupCommitter) groupSave(ctx context.Context, save *pendingSave) (bool, error) {
+	save.data = bytes.Clone(save.data) // The caller may reuse data once Save returns
+	save.done = make(chan error, 1)
+	select {
+	case g.saves <- save:
+	case <-g.stopped:
+		return false, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+	select {
+	case err := <-save.done:
+		return true, err
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// commit saves group in one transaction, splitting it if it is too big for one, and reports the
+// result to each save. Callers must hold s.mu for reading.
+func (s *BadgerStore) commit(group []*pendingSave) error {
+	added := make([]bool, len(group))
+	replaced := make([]int64, len(group))
+	err := s.db.Update(func(txn *badger.Txn) error {
+		for i, save := range group {
+			item, err := txn.Get(save.key)
+			added[i] = err == badger.ErrKeyNotFound
+			if err == nil {
+				if replaced[i], err = itemMsgSize(item); err != nil {
+					return err
+				}
+			}
+			if err := txn.Set(save.key, save.data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, badger.ErrTxnTooBig) && len(group) > 1 {
+		half := len(group) / 2
+		return errors.Join(s.commit(group[:half]), s.commit(group[half:]))
+	}
+
+	if err == nil {
+		s.counts.Lock()
+		for i, save := range group {
+			if added[i] {
+				s.depthAdd(save.strand, 1)
+			}
+			s.bytesAdd(save.strand, save.size-replaced[i])
+		}
+		s.counts.Unlock()
+	}
+	for _, save := range group {
+		if save.done != nil {
+			save.done <- err
+		}
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/wire"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Reconcile Corrects Depth Drift
+func TestBadgerReconcile(t *testing.T) {
+	os.RemoveAll("/tmp/badger_test_db_reconcile")
+	s, err := BadgerStoreMake("/tmp/badger_test_db_reconcile")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	assert.NoError(t, s.CreateStrand(context.Background(), "drift_channel", StrandConf{Durable: true}))
+	for _, id := range []string{"1", "2"} {
+		assert.NoError(t, s.Save(context.Background(), wire.Msg{ID: id, Strand: "drift_channel", Payload: "x"}))
+	}
+	assert.Equal(t, float64(2), testutil.ToFloat64(telemetry.QueueSize.WithLabelValues("drift_channel")))
+
+	// A message written behind the store's back is counted on reconcile
+	assert.NoError(t, s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("msg:drift_channel:3"), []byte(`{"ID": "3", "Strand": "drift_channel"}`))
+	}))
+	assert.NoError(t, s.Reconcile(context.Background()))
+	depth, _ := s.Depth(context.Background(), "drift_channel")
+	assert.Equal(t, 3, depth)
+	assert.Equal(t, float64(3), testutil.ToFloat64(telemetry.QueueSize.WithLabelValues("drift_channel")))
+}
+
+// Test Stores In One Process Encode With Their Own Codecs
+func TestBadgerCodec(t *testing.T) {
+	_, err := BadgerStoreMake(t.TempDir(), telemetry.MakeCodec("xml"))
+	assert.Error(t, err)
+
+	ctx := context.Background()
+	for _, codec := range []string{wire.CodecJSON, wire.CodecBinary} {
+		s, err := BadgerStoreMake(t.TempDir(), telemetry.MakeCodec(codec))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer s.Close()
+		assert.Equal(t, codec, s.Codec())
+		assert.NoError(t, s.CreateStrand(ctx, "codec_channel", StrandConf{Durable: true}))
+		assert.NoError(t, s.Save(ctx, wire.Msg{ID: "1", Strand: "codec_channel", Payload: "Encoded"}))
+
+		var value []byte
+		assert.NoError(t, s.db.View(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte("msg:codec_channel:1"))
+			if err != nil {
+				return err
+			}
+			value, err = item.ValueCopy(nil)
+			return err
+		}))
+		want, _ := wire.MsgEncodeCodec(wire.Msg{ID: "1", Strand: "codec_channel", Payload: "Encoded"}, codec)
+		assert.Equal(t, want, value, codec)
+		msg, err := s.Get(ctx, "codec_channel", "1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Encoded", msg.Payload)
+		}
+	}
+}
+
+// Test Unacked Messages Decode Alike With And Without An Arena
+func TestBadgerUnackedArena(t *testing.T) {
+	s, err := BadgerStoreMake(t.TempDir())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, s.CreateStrand(ctx, "arena_channel", StrandConf{Durable: true}))
+	for i := range 100 {
+		assert.NoError(t, s.Save(ctx, wire.Msg{ID: strconv.Itoa(i), Strand: "arena_channel", Payload: "Recovered",
+			Headers: map[string]string{"n": strconv.Itoa(i)}}))
+	}
+
+	unacked := func(ctx context.Context) []wire.Msg {
+		it, err := s.UnackedIterator(ctx)
+		assert.NoError(t, err)
+		defer it.Close()
+		var msgs []wire.Msg
+		for msg, ok := it.Next(); ok; msg, ok = it.Next() {
+			msgs = append(msgs, *msg)
+		}
+		return msgs
+	}
+	alone := unacked(ctx)
+	assert.Len(t, alone, 100)
+	assert.Equal(t, alone, unacked(wire.WithArena(ctx, wire.ArenaMake(4096))))
+}
+
+// Test Concurrent Saves Commit Together, On A Full Group Or After The Delay
+func TestBadgerGroupCommit(t *testing.T) {
+	os.RemoveAll("/tmp/badger_test_db_group")
+	s, err := BadgerStoreMake("/tmp/badger_test_db_group")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+	ctx := context.Background()
+	assert.NoError(t, s.CreateStrand(ctx, "group_channel", StrandConf{Durable: true}))
+
+	// A full group commits without waiting out the delay
+	stop := s.GroupCommitServe(GroupCommitConf{MaxBatch: 8, MaxDelay: time.Hour})
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Save(ctx, wire.Msg{ID: strconv.Itoa(i), Strand: "group_channel", Payload: "Grouped"}))
+		}()
+	}
+	wg.Wait()
+	depth, _ := s.Depth(ctx, "group_channel")
+	assert.Equal(t, 8, depth)
+	stop()
+
+	// A lone save commits after the delay, and a canceled one returns without waiting
+	stop = s.GroupCommitServe(GroupCommitConf{MaxBatch: 8, MaxDelay: 20 * time.Millisecond})
+	start := time.Now()
+	assert.NoError(t, s.Save(ctx, wire.Msg{ID: "8", Strand: "group_channel", Payload: "Delayed"}))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	canceled, cancel := context.WithCancel(ctx)
+	time.AfterFunc(5*time.Millisecond, cancel)
+	assert.ErrorIs(t, s.Save(canceled, wire.Msg{ID: "9", Strand: "group_channel", Payload: "Canceled"}), context.Canceled)
+	stop()
+
+	// Once stopped, saves commit alone
+	assert.NoError(t, s.Save(ctx, wire.Msg{ID: "10", Strand: "group_channel", Payload: "Alone"}))
+	msg, err := s.Get(ctx, "group_channel", "10")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Alone", msg.Payload)
+	}
+	depth, _ = s.Depth(ctx, "group_channel")
+	assert.Equal(t, 11, depth, "the canceled save was still committed")
+}
+
+// Benchmark 16 Concurrent Durable Saves, Each Committed Alone And In Groups Of 16
+func BenchmarkBadgerSave(b *testing.B) {
+	for _, grouped := range []bool{false, true} {
+		name := "Alone"
+		if grouped {
+			name = "Grouped"
+		}
+		b.Run(name, func(b *testing.B) {
+			os.RemoveAll("/tmp/badger_bench_db_save")
+			s, err := BadgerStoreMake("/tmp/badger_bench_db_save")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+			if grouped {
+				defer s.GroupCommitServe(GroupCommitConf{MaxBatch: 16, MaxDelay: time.Millisecond})()
+			}
+			ctx := context.Background()
+			s.CreateStrand(ctx, "bench_channel", StrandConf{Durable: true})
+
+			var ids atomic.Int64
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					msg := wire.Msg{ID: strconv.FormatInt(ids.Add(1), 10), Strand: "bench_channel", Payload: "Durable"}
+					if err := s.Save(ctx, msg); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/jkassis/condukt/condukttest"
+	"github.com/jkassis/condukt/store"
+)
+
+// Test The Stores Pass The Conformance Suite
+func TestStoreConformance(t *testing.T) {
+	t.Run("RamStore", func(t *testing.T) {
+		condukttest.StoreConformance(t, func(t *testing.T) store.Store {
+			return store.RamStoreMake()
+		})
+	})
+	t.Run("BadgerStore", func(t *testing.T) {
+		condukttest.StoreConformance(t, func(t *testing.T) store.Store {
+			s, err := store.BadgerStoreMake(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { s.Close() })
+			return s
+		})
+	})
+}
@@ -0,0 +1,424 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+// ramShards is how many buckets RamStore spreads strands over. Strands in different buckets are
+// looked up, added, and removed without contending for a lock.
+const ramShards = 32
+
+// RamStore (fast but volatile)
+type RamStore struct {
+	shards [ramShards]ramShard
+	log    *zap.Logger
+}
+
+// ramShard is a bucket of strands. Each queue has its own lock.
+type ramShard struct {
+	mu      sync.RWMutex // Guards strands
+	strands map[string]*ramQueue
+}
+
+// ramQueue is a strand's unacked messages, oldest first, indexed by ID so acks take constant time.
+type ramQueue struct {
+	mu      sync.Mutex
+	config  StrandConf
+	order   *list.List               // Of wire.Msg
+	index   map[string]*list.Element // Message ID -> its element of order
+	bytes   int64                    // Total Size of the messages
+	deleted bool                     // Set once the strand is deleted or replaced, so late writers leave it and its gauges alone
+}
+
+// ramQueueMake returns an empty queue.
+func ramQueueMake(config StrandConf) *ramQueue {
+	return &ramQueue{config: config, order: list.New(), index: make(map[string]*list.Element)}
+}
+
+// remove drops a message from q, returning its size. Callers must hold q.mu.
+func (q *ramQueue) remove(e *list.Element) int64 {
+	msg := q.order.Remove(e).(wire.Msg)
+	delete(q.index, msg.ID)
+	q.bytes -= msg.Size()
+	return msg.Size()
+}
+
+// gauge publishes the depth and bytes of strandID. Callers must hold q.mu.
+func (q *ramQueue) gauge(strandID string) {
+	telemetry.QueueSize.WithLabelValues(strandID).Set(float64(q.order.Len()))
+	telemetry.QueueBytes.WithLabelValues(strandID).Set(float64(q.bytes))
+}
+
+// messages returns up to limit of q's oldest messages, or all of them if limit is 0. Callers must
+// hold q.mu.
+func (q *ramQueue) messages(limit int) []wire.Msg {
+	n := q.order.Len()
+	if limit > 0 {
+		n = min(n, limit)
+	}
+	msgs := make([]wire.Msg, 0, n)
+	for e := q.order.Front(); e != nil && len(msgs) < n; e = e.Next() {
+		msgs = append(msgs, e.Value.(wire.Msg))
+	}
+	return msgs
+}
+
+// RamStoreMake initializes an in-memory store.
+func RamStoreMake(options ...telemetry.MakeOption) *RamStore {
+	s := &RamStore{log: telemetry.MakeOptsApply(options).Log}
+	for i := range s.shards {
+		s.shards[i].strands = make(map[string]*ramQueue)
+	}
+	return s
+}
+
+// shard returns the bucket of strandID.
+func (s *RamStore) shard(strandID string) *ramShard {
+	h := fnv.New32a()
+	h.Write([]byte(strandID))
+	return &s.shards[h.Sum32()%ramShards]
+}
+
+// queue returns the queue of strandID, or nil if it does not exist.
+func (s *RamStore) queue(strandID string) *ramQueue {
+	shard := s.shard(strandID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.strands[strandID]
+}
+
+// lock returns the queue of strandID locked, or nil if it does not exist or was deleted while
+// waiting for its lock.
+func (s *RamStore) lock(strandID string) *ramQueue {
+	q := s.queue(strandID)
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	if q.deleted {
+		q.mu.Unlock()
+		return nil
+	}
+	return q
+}
+
+// queuesEach calls fn with each strand's queue, read-locking one bucket at a time.
+func (s *RamStore) queuesEach(fn func(strandID string, q *ramQueue)) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		for strandID, q := range shard.strands {
+			fn(strandID, q)
+		}
+		shard.mu.RUnlock()
+	}
+}
+
+// CreateStrand registers a new strand with a given configuration.
+func (s *RamStore) CreateStrand(ctx context.Context, strandID string, config StrandConf) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	shard := s.shard(strandID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, exists := shard.strands[strandID]; exists {
+		return errors.New("strand already exists")
+	}
+
+	shard.strands[strandID] = ramQueueMake(config)
+	return nil
+}
+
+// DeleteStrand removes a strand and all associated messages.
+func (s *RamStore) DeleteStrand(ctx context.Context, strandID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	shard := s.shard(strandID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	q, exists := shard.strands[strandID]
+	if !exists {
+		return errors.New("strand does not exist")
+	}
+
+	delete(shard.strands, strandID)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deleted = true
+	telemetry.QueueSize.DeleteLabelValues(strandID)
+	telemetry.QueueBytes.DeleteLabelValues(strandID)
+	return nil
+}
+
+// HasStrand checks if a strand exists in the store.
+func (s *RamStore) HasStrand(ctx context.Context, strandID string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return s.queue(strandID) != nil
+}
+
+// RecoverStrands restores all strands on startup.
+func (s *RamStore) RecoverStrands() error {
+	s.queuesEach(func(strandID string, _ *ramQueue) {
+		s.log.Debug("Recovered strand from RamStore", zap.String("strand", strandID))
+	})
+	return nil
+}
+
+// Save persists a message in memory, replacing any earlier message with its ID.
+func (s *RamStore) Save(ctx context.Context, msg wire.Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q := s.lock(msg.Strand)
+	if q == nil {
+		return errors.New("strand not configured")
+	}
+	defer q.mu.Unlock()
+
+	if e, exists := q.index[msg.ID]; exists {
+		q.bytes += msg.Size() - e.Value.(wire.Msg).Size()
+		e.Value = msg
+	} else {
+		q.index[msg.ID] = q.order.PushBack(msg)
+		q.bytes += msg.Size()
+	}
+	q.gauge(msg.Strand)
+	return nil
+}
+
+// Acknowledge marks a message as processed by removing it from the queue.
+func (s *RamStore) Acknowledge(ctx context.Context, strandID, msgID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+
+	e, exists := q.index[msgID]
+	if !exists {
+		return errors.New("message not found")
+	}
+	q.remove(e)
+	q.gauge(strandID)
+	return nil
+}
+
+// AcknowledgeBatch removes all of msgIDs from the queue, or none of them if any is missing.
+func (s *RamStore) AcknowledgeBatch(ctx context.Context, strandID string, msgIDs []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+
+	for _, msgID := range msgIDs {
+		if _, exists := q.index[msgID]; !exists {
+			return errors.New("message not found: " + msgID)
+		}
+	}
+	for _, msgID := range msgIDs {
+		if e, exists := q.index[msgID]; exists { // Repeated IDs are removed once
+			q.remove(e)
+		}
+	}
+	q.gauge(strandID)
+	return nil
+}
+
+// ListStrands returns all strands and their configs.
+func (s *RamStore) ListStrands(ctx context.Context) (map[string]StrandConf, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	strands := make(map[string]StrandConf)
+	s.queuesEach(func(strandID string, q *ramQueue) {
+		strands[strandID] = q.config
+	})
+	return strands, nil
+}
+
+// Get returns a single unacked message.
+func (s *RamStore) Get(ctx context.Context, strandID, msgID string) (*wire.Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return nil, errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+
+	e, exists := q.index[msgID]
+	if !exists {
+		return nil, errors.New("message not found")
+	}
+	msg := e.Value.(wire.Msg)
+	return &msg, nil
+}
+
+// Peek returns up to limit of the oldest unacked messages without consuming them.
+func (s *RamStore) Peek(ctx context.Context, strandID string, limit int) ([]wire.Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return nil, errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+	return q.messages(limit), nil
+}
+
+// Depth returns the number of unacked messages in a strand.
+func (s *RamStore) Depth(ctx context.Context, strandID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return 0, errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+	return q.order.Len(), nil
+}
+
+// Bytes returns the total size of the unacked messages in a strand.
+func (s *RamStore) Bytes(ctx context.Context, strandID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return 0, errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+	return q.bytes, nil
+}
+
+// Purge deletes all messages in a strand but keeps the strand.
+func (s *RamStore) Purge(ctx context.Context, strandID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	q := s.lock(strandID)
+	if q == nil {
+		return 0, errors.New("strand does not exist")
+	}
+	defer q.mu.Unlock()
+
+	purged := q.order.Len()
+	q.order.Init()
+	clear(q.index)
+	q.bytes = 0
+	q.gauge(strandID)
+	return purged, nil
+}
+
+// Reconcile sets the queue_size gauge of every strand. Depths and bytes are exact for the in-memory store.
+func (s *RamStore) Reconcile(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.queuesEach(func(strandID string, q *ramQueue) {
+		q.mu.Lock()
+		q.gauge(strandID)
+		q.mu.Unlock()
+	})
+	return nil
+}
+
+// UnackedIterator returns an iterator over unacknowledged messages.
+func (s *RamStore) UnackedIterator(ctx context.Context) (UnackedMessageIterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// Snapshot the queues so the iterator is unaffected by later writes
+	messages := []wire.Msg{}
+	s.queuesEach(func(_ string, q *ramQueue) {
+		q.mu.Lock()
+		messages = append(messages, q.messages(0)...)
+		q.mu.Unlock()
+	})
+
+	return &RamUnackedIterator{
+		messages: messages,
+		index:    0,
+	}, nil
+}
+
+// RamUnackedIterator implements UnackedMessageIterator for RamStore.
+type RamUnackedIterator struct {
+	messages []wire.Msg
+	index    int
+}
+
+// Next retrieves the next unacknowledged message.
+func (it *RamUnackedIterator) Next() (*wire.Msg, bool) {
+	if it.index < len(it.messages) {
+		msg := it.messages[it.index]
+		it.index++
+		return &msg, true
+	}
+	return nil, false
+}
+
+// Close cleans up iterator resources (no-op for RamStore).
+func (it *RamUnackedIterator) Close() error {
+	return nil
+}
+
+// Ping succeeds for the in-memory store unless ctx is done.
+func (s *RamStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close is a no-op for an in-memory store.
+func (s *RamStore) Close() error {
+	return nil
+}
+
+// Reset clears all data in the RamStore.
+func (s *RamStore) Reset(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Reset the internal storage
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for strandID := range shard.strands {
+			telemetry.QueueSize.DeleteLabelValues(strandID)
+			telemetry.QueueBytes.DeleteLabelValues(strandID)
+		}
+		shard.strands = make(map[string]*ramQueue)
+		shard.mu.Unlock()
+	}
+
+	s.log.Debug("RamStore reset completed")
+	return nil
+}
+
+// Reload clears all data, as an in-memory store has nothing to reopen.
+func (s *RamStore) Reload(ctx context.Context) error {
+	return s.Reset(ctx)
+}
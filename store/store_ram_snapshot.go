@@ -0,0 +1,150 @@
+package store
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jkassis/condukt/wire"
+	"go.uber.org/zap"
+)
+
+// SnapshotConf configures periodic snapshots of a RamStore, which make its strands mostly durable:
+// a crash loses only what arrived since the last snapshot.
+type SnapshotConf struct {
+	Path     string        `yaml:"path"`     // Snapshot file; snapshots are taken only if set
+	Interval time.Duration `yaml:"interval"` // Time between snapshots; 0 snapshots only when stopped
+}
+
+// Validate reports a negative interval or an interval without a path.
+func (conf SnapshotConf) Validate() error {
+	if conf.Interval < 0 {
+		return errors.New("snapshot.interval: must not be negative")
+	}
+	if conf.Interval > 0 && conf.Path == "" {
+		return errors.New("snapshot.path: required for an interval")
+	}
+	return nil
+}
+
+// ramSnapshotStrand is a strand as a snapshot holds it.
+type ramSnapshotStrand struct {
+	ID       string
+	Config   StrandConf
+	Messages []wire.Msg // Oldest first
+}
+
+// Snapshot writes the store's strands and unacked messages to path, replacing it only once the
+// snapshot is complete. Each strand is copied whole, but strands are copied one after another, so
+// the snapshot is not of a single instant.
+func (s *RamStore) Snapshot(path string) error {
+	var strands []ramSnapshotStrand
+	s.queuesEach(func(strandID string, q *ramQueue) {
+		q.mu.Lock()
+		strands = append(strands, ramSnapshotStrand{ID: strandID, Config: q.config, Messages: q.messages(0)})
+		q.mu.Unlock()
+	})
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	enc := gob.NewEncoder(tmp)
+	for i := range strands {
+		if err := enc.Encode(&strands[i]); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SnapshotRestore adds the strands and messages of the snapshot at path to the store, replacing
+// strands it already has. A missing snapshot restores nothing.
+func (s *RamStore) SnapshotRestore(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(f)
+	strands, messages := 0, 0
+	for {
+		var strand ramSnapshotStrand
+		if err := dec.Decode(&strand); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("snapshot %s: %w", path, err)
+		}
+
+		q := ramQueueMake(strand.Config)
+		for _, msg := range strand.Messages {
+			q.index[msg.ID] = q.order.PushBack(msg)
+			q.bytes += msg.Size()
+		}
+		shard := s.shard(strand.ID)
+		shard.mu.Lock()
+		if replaced := shard.strands[strand.ID]; replaced != nil {
+			replaced.mu.Lock()
+			replaced.deleted = true
+			replaced.mu.Unlock()
+		}
+		shard.strands[strand.ID] = q
+		q.gauge(strand.ID)
+		shard.mu.Unlock()
+		strands++
+		messages += len(strand.Messages)
+	}
+	s.log.Info("Restored RamStore snapshot", zap.String("path", path), zap.Int("strands", strands), zap.Int("messages", messages))
+	return nil
+}
+
+// SnapshotServe snapshots the store to conf.Path every conf.Interval, until stop is called, which
+// takes a last snapshot. Call stop once nothing more is saved, such as after shutting down.
+func (s *RamStore) SnapshotServe(conf SnapshotConf) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if conf.Interval == 0 {
+			<-done
+			return
+		}
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Snapshot(conf.Path); err != nil {
+					s.log.Error("Failed to snapshot RamStore", zap.String("path", conf.Path), zap.Error(err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if err := s.Snapshot(conf.Path); err != nil {
+			s.log.Error("Failed to snapshot RamStore", zap.String("path", conf.Path), zap.Error(err))
+		}
+	}
+}
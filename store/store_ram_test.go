@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jkassis/condukt/internal/telemetry"
+	"github.com/jkassis/condukt/wire"
+	"github.com/stretchr/testify/assert"
+)
+
+// Benchmark Acking The Newest Of 10,000 Messages, Concurrently On A Strand Per Goroutine
+func BenchmarkRamStoreSaveAck(b *testing.B) {
+	ctx := context.Background()
+	s := RamStoreMake()
+
+	var goroutines atomic.Int32
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		strandID := fmt.Sprintf("bench_%d", goroutines.Add(1))
+		s.CreateStrand(ctx, strandID, StrandConf{})
+		for i := range 10000 {
+			s.Save(ctx, wire.Msg{ID: fmt.Sprintf("backlog_%d", i), Strand: strandID, Payload: "Backlog"})
+		}
+
+		for i := 0; pb.Next(); i++ {
+			msg := wire.Msg{ID: fmt.Sprintf("msg_%d", i), Strand: strandID, Payload: "Message"}
+			s.Save(ctx, msg)
+			s.Acknowledge(ctx, strandID, msg.ID)
+		}
+	})
+}
+
+// Benchmark Sends And Acks Across 256 Strands While Other Strands Are Added And Removed
+func BenchmarkRamStoreStrands(b *testing.B) {
+	ctx := context.Background()
+	s := RamStoreMake()
+	for i := range 256 {
+		s.CreateStrand(ctx, fmt.Sprintf("bench_%d", i), StrandConf{})
+	}
+
+	var goroutines atomic.Int32
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		g := int(goroutines.Add(1))
+		scratch := fmt.Sprintf("scratch_%d", g)
+		for i := 0; pb.Next(); i++ {
+			strandID := fmt.Sprintf("bench_%d", (g*31+i)%256)
+			msg := wire.Msg{ID: fmt.Sprintf("msg_%d_%d", g, i), Strand: strandID, Payload: "Message"}
+			s.Save(ctx, msg)
+			s.Acknowledge(ctx, strandID, msg.ID)
+			if i%64 == 0 {
+				s.CreateStrand(ctx, scratch, StrandConf{})
+				s.DeleteStrand(ctx, scratch)
+			}
+		}
+	})
+}
+
+// Test A Snapshot Restores Strands And Unacked Messages In Order Into A Fresh Store
+func TestRamSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ram.snapshot")
+	s := RamStoreMake()
+	assert.NoError(t, s.SnapshotRestore(path), "a missing snapshot restores nothing")
+
+	assert.NoError(t, s.CreateStrand(ctx, "snap", StrandConf{Ordered: true, MaxBytes: 1 << 20}))
+	for i := range 3 {
+		assert.NoError(t, s.Save(ctx, wire.Msg{ID: fmt.Sprintf("msg_%d", i), Strand: "snap", Payload: "Payload",
+			Headers: map[string]string{"n": fmt.Sprint(i)}}))
+	}
+	assert.NoError(t, s.Acknowledge(ctx, "snap", "msg_1"))
+	stop := s.SnapshotServe(SnapshotConf{Path: path, Interval: time.Hour})
+	stop() // Snapshots on stop
+
+	restored := RamStoreMake()
+	assert.NoError(t, restored.SnapshotRestore(path))
+	strands, err := restored.ListStrands(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, StrandConf{Ordered: true, MaxBytes: 1 << 20}, strands["snap"])
+	msgs, err := restored.Peek(ctx, "snap", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, msgs, 2) {
+		assert.Equal(t, "msg_0", msgs[0].ID)
+		assert.Equal(t, "msg_2", msgs[1].ID)
+		assert.Equal(t, "2", msgs[1].Headers["n"])
+	}
+	bytes, _ := restored.Bytes(ctx, "snap")
+	want, _ := s.Bytes(ctx, "snap")
+	assert.Equal(t, want, bytes)
+	assert.NoError(t, restored.Acknowledge(ctx, "snap", "msg_0"))
+}
+
+// Test A Save Racing DeleteStrand Leaves No Queue Or Gauges Behind
+func TestRamDeleteRace(t *testing.T) {
+	ctx := context.Background()
+	s := RamStoreMake()
+	assert.NoError(t, s.CreateStrand(ctx, "deleted_channel", StrandConf{}))
+
+	// The save finds the queue, then waits for its lock while the strand is deleted
+	q := s.queue("deleted_channel")
+	q.mu.Lock()
+	saved := make(chan error, 1)
+	go func() { saved <- s.Save(ctx, wire.Msg{ID: "1", Strand: "deleted_channel", Payload: "Late"}) }()
+	deleted := make(chan error, 1)
+	time.Sleep(10 * time.Millisecond)
+	go func() { deleted <- s.DeleteStrand(ctx, "deleted_channel") }()
+	time.Sleep(10 * time.Millisecond)
+	q.mu.Unlock()
+	assert.NoError(t, <-deleted)
+	<-saved
+
+	assert.False(t, s.HasStrand(ctx, "deleted_channel"))
+	assert.Error(t, s.Acknowledge(ctx, "deleted_channel", "1"))
+	assert.False(t, telemetry.QueueSize.DeleteLabelValues("deleted_channel"), "the gauge stays deleted")
+	assert.False(t, telemetry.QueueBytes.DeleteLabelValues("deleted_channel"))
+}
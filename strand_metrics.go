@@ -0,0 +1,98 @@
+package condukt
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// strandSeries holds a strand's per-message metrics, resolved once so sends, receives, and acks
+// skip the label lookups.
+type strandSeries struct {
+	quiet bool // Per-message metrics are off; only the namespace count is kept
+
+	sent          prometheus.Counter
+	received      prometheus.Counter
+	acked         prometheus.Counter
+	storeSeconds  prometheus.Observer
+	wireSeconds   prometheus.Observer
+	ackSeconds    prometheus.Observer
+	namespaceSent prometheus.Counter
+}
+
+// strandSeriesMake resolves strandID's metrics.
+func strandSeriesMake(strandID string, quiet bool) *strandSeries {
+	m := &strandSeries{quiet: quiet, namespaceSent: namespaceMessagesSent.WithLabelValues(Namespace(strandID))}
+	if !quiet {
+		m.sent = messagesSent.WithLabelValues(strandID)
+		m.received = messagesReceived.WithLabelValues(strandID)
+		m.acked = messagesAcked.WithLabelValues(strandID)
+		m.storeSeconds = sendStoreSeconds.WithLabelValues(strandID)
+		m.wireSeconds = sendWireSeconds.WithLabelValues(strandID)
+		m.ackSeconds = sendAckSeconds.WithLabelValues(strandID)
+	}
+	return m
+}
+
+// strandSeriesCache holds the resolved metrics of each strand, and which strands are quiet.
+type strandSeriesCache struct {
+	mu      sync.RWMutex
+	strands map[string]*strandSeries
+	quiet   map[string]bool
+}
+
+// get returns strandID's metrics, resolving them if they are not cached yet, as for strands added
+// by an earlier run or another cluster node.
+func (sm *strandSeriesCache) get(strandID string) *strandSeries {
+	sm.mu.RLock()
+	m, cached := sm.strands[strandID]
+	sm.mu.RUnlock()
+	if cached {
+		return m
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if m, cached = sm.strands[strandID]; !cached {
+		m = strandSeriesMake(strandID, sm.quiet[strandID])
+		sm.strands[strandID] = m
+	}
+	return m
+}
+
+// forget drops strandID's metrics and its quiet setting.
+func (sm *strandSeriesCache) forget(strandID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	delete(sm.strands, strandID)
+	delete(sm.quiet, strandID)
+}
+
+// SetStrandMetrics turns strandID's per-message metrics on or off. Off, its sends, receives, and
+// acks update no per-strand counters or latency histograms, which saves their cost on very hot
+// strands; its series are removed rather than left stale. Its namespace's send count still counts.
+func (c *Conduktor) SetStrandMetrics(strandID string, enabled bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.getStore(strandID); err != nil {
+		return err
+	}
+	sm := &c.strandSeries
+	sm.mu.Lock()
+	if enabled {
+		delete(sm.quiet, strandID)
+	} else {
+		sm.quiet[strandID] = true
+		for _, vec := range []interface{ DeleteLabelValues(...string) bool }{
+			messagesSent, messagesReceived, messagesAcked, sendStoreSeconds, sendWireSeconds, sendAckSeconds,
+		} {
+			vec.DeleteLabelValues(strandID)
+		}
+	}
+	sm.strands[strandID] = strandSeriesMake(strandID, !enabled)
+	sm.mu.Unlock()
+	c.log.Info("Strand metrics set", zap.String("strand", strandID), zap.Bool("enabled", enabled))
+	return nil
+}
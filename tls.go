@@ -0,0 +1,327 @@
+package condukt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Client certificate checks of a TLS listener.
+const (
+	TLSClientNone    = "none"    // Ask for no client certificate
+	TLSClientVerify  = "verify"  // Verify a client certificate if one is presented
+	TLSClientRequire = "require" // Refuse connections without a verified client certificate
+)
+
+// Client certificate fields a certificate identity can be taken from.
+const (
+	CertIdentityCN    = "cn"    // Subject common name
+	CertIdentityDNS   = "dns"   // First DNS subject alternative name
+	CertIdentityURI   = "uri"   // First URI subject alternative name, as a SPIFFE ID
+	CertIdentityEmail = "email" // First email subject alternative name
+)
+
+// tlsReloadDefault is how often served certificate files are checked for changes by default.
+const tlsReloadDefault = time.Minute
+
+// TLSConf configures TLS, and optionally mutual TLS, on the wire, clients, admin, and gRPC
+// listeners. Without a certificate they accept plain connections.
+type TLSConf struct {
+	Cert string `yaml:"cert"` // PEM certificate chain file
+	Key  string `yaml:"key"`  // PEM private key file
+	// KeySecret references the PEM private key as a secret instead, as "vault:condukt/tls#key".
+	KeySecret string `yaml:"key_secret"`
+	ClientCA  string `yaml:"client_ca"` // PEM bundle of the CAs client certificates are verified with; empty accepts none
+	// ClientAuth is none, verify, or require; empty is require with a client CA, else none.
+	// Verified client certificates authenticate as the identities auth.certs lists.
+	ClientAuth string `yaml:"client_auth"`
+	// Reload is how often the certificate, key, and client CA files are checked for changes, which
+	// apply to new connections without dropping open ones; 0 is 1m.
+	Reload time.Duration `yaml:"reload"`
+	ACME   ACMEConf      `yaml:"acme"` // Obtaining and renewing the certificate instead
+
+	keyPEM []byte // Private key resolved from KeySecret
+}
+
+// ACMEConf configures obtaining and renewing certificates from an ACME CA, as Let's Encrypt, for
+// internet-facing listeners. Challenges are answered over TLS-ALPN on the listeners themselves,
+// which must then be reachable on port 443, or over HTTP on HTTPAddr.
+type ACMEConf struct {
+	Domains   []string `yaml:"domains"`   // Hosts certificates are obtained for; empty disables ACME
+	Email     string   `yaml:"email"`     // Contact for notices about the certificates
+	CacheDir  string   `yaml:"cache_dir"` // Where certificates and the account key are kept
+	Directory string   `yaml:"directory"` // ACME directory URL; empty is Let's Encrypt's production one
+	HTTPAddr  string   `yaml:"http_addr"` // Serves HTTP-01 challenges, as ":80"; empty answers TLS-ALPN-01 only
+}
+
+// Enabled reports whether certificates are obtained over ACME.
+func (conf ACMEConf) Enabled() bool {
+	return len(conf.Domains) > 0
+}
+
+// manager returns the ACME certificate manager of conf, or nil if ACME is disabled.
+func (conf ACMEConf) manager() *autocert.Manager {
+	if !conf.Enabled() {
+		return nil
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(conf.CacheDir),
+		HostPolicy: autocert.HostWhitelist(conf.Domains...),
+		Email:      conf.Email,
+	}
+	if conf.Directory != "" {
+		manager.Client = &acme.Client{DirectoryURL: conf.Directory}
+	}
+	return manager
+}
+
+// Enabled reports whether the listeners use TLS.
+func (conf TLSConf) Enabled() bool {
+	return conf.Cert != "" || conf.ACME.Enabled()
+}
+
+// Validate reports a certificate without a key, a client CA without TLS, ACME alongside a
+// certificate or without a cache, and unknown client checks.
+func (conf TLSConf) Validate() error {
+	var errs []error
+	if (conf.Cert == "") != (conf.Key == "" && conf.KeySecret == "") {
+		errs = append(errs, errors.New("cert and key: both or neither are required"))
+	}
+	if conf.Key != "" && conf.KeySecret != "" {
+		errs = append(errs, errors.New("key and key_secret: at most one is allowed"))
+	}
+	if conf.ClientCA != "" && !conf.Enabled() {
+		errs = append(errs, errors.New("client_ca: requires cert and key, or acme"))
+	}
+	if conf.Reload < 0 {
+		errs = append(errs, errors.New("reload: must not be negative"))
+	}
+	if conf.ACME.Enabled() {
+		if conf.Cert != "" {
+			errs = append(errs, errors.New("acme: cert and acme are exclusive"))
+		}
+		if conf.ACME.CacheDir == "" {
+			errs = append(errs, errors.New("acme.cache_dir: required"))
+		}
+	}
+	switch conf.ClientAuth {
+	case "", TLSClientNone:
+	case TLSClientVerify, TLSClientRequire:
+		if conf.ClientCA == "" {
+			errs = append(errs, fmt.Errorf("client_auth: %s requires client_ca", conf.ClientAuth))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("client_auth: unknown check %q (want none, verify, or require)", conf.ClientAuth))
+	}
+	return errors.Join(errs...)
+}
+
+// Config loads the certificate and client CAs, returning the TLS configuration of the listeners,
+// or nil without TLS. It negotiates HTTP/2 for gRPC and HTTP/1.1 for WebSocket upgrades. The files
+// are read once; ConfigServe reloads them.
+func (conf TLSConf) Config() (*tls.Config, error) {
+	if !conf.Enabled() {
+		return nil, nil
+	}
+	return conf.config(conf.ACME.manager())
+}
+
+// config returns the TLS configuration, with the certificate from manager if not nil.
+func (conf TLSConf) config(manager *autocert.Manager) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if manager != nil {
+		config.GetCertificate = manager.GetCertificate
+		config.NextProtos = append(config.NextProtos, acme.ALPNProto)
+	} else {
+		cert, err := conf.certificate()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if conf.ClientCA == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(conf.ClientCA)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no PEM certificates", conf.ClientCA)
+	}
+	switch conf.ClientAuth {
+	case "", TLSClientRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	case TLSClientVerify:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// ConfigServe returns the TLS configuration of the listeners, as Config does, and reloads it when
+// the certificate, key, or client CA files change, so new connections get the new certificate
+// while open ones are kept. A change that fails to load is logged and the previous configuration
+// kept. With ACME, certificates are obtained and renewed as handshakes need them, and HTTP-01
+// challenges served if configured. stop ends reloading and the challenge server.
+func (conf TLSConf) ConfigServe() (config *tls.Config, stop func(), err error) {
+	if !conf.Enabled() {
+		return nil, func() {}, nil
+	}
+	manager := conf.ACME.manager()
+	loaded, err := conf.config(manager)
+	if err != nil {
+		return nil, nil, err
+	}
+	var live atomic.Pointer[tls.Config]
+	live.Store(loaded)
+	config = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         loaded.NextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return live.Load(), nil },
+	}
+
+	var challenges *http.Server
+	if manager != nil && conf.ACME.HTTPAddr != "" {
+		challenges = &http.Server{Addr: conf.ACME.HTTPAddr, Handler: manager.HTTPHandler(nil), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := challenges.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("ACME challenge listener stopped", zap.String("addr", conf.ACME.HTTPAddr), zap.Error(err))
+			}
+		}()
+	}
+
+	interval := conf.Reload
+	if interval == 0 {
+		interval = tlsReloadDefault
+	}
+	modified := conf.modified()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			mtimes := conf.modified()
+			if slices.Equal(mtimes, modified) {
+				continue
+			}
+			modified = mtimes
+			reloaded, err := conf.config(manager)
+			if err != nil {
+				logger.Error("Failed to reload TLS certificates", zap.Error(err))
+				continue
+			}
+			live.Store(reloaded)
+			logger.Info("TLS certificates reloaded", zap.String("cert", conf.Cert))
+		}
+	}()
+
+	return config, func() {
+		cancel()
+		<-stopped
+		if challenges != nil {
+			challenges.Close()
+		}
+	}, nil
+}
+
+// modified returns the modification times of the certificate, key, and client CA files, zero for
+// those unset or missing.
+func (conf TLSConf) modified() []time.Time {
+	var mtimes []time.Time
+	for _, path := range []string{conf.Cert, conf.Key, conf.ClientCA} {
+		var mtime time.Time
+		if info, err := os.Stat(path); path != "" && err == nil {
+			mtime = info.ModTime()
+		}
+		mtimes = append(mtimes, mtime)
+	}
+	return mtimes
+}
+
+// certificate loads the certificate chain with its private key, from the key file or secret.
+func (conf TLSConf) certificate() (tls.Certificate, error) {
+	if conf.KeySecret == "" {
+		return tls.LoadX509KeyPair(conf.Cert, conf.Key)
+	}
+	if conf.keyPEM == nil {
+		return tls.Certificate{}, errors.New("key_secret: not resolved")
+	}
+	chain, err := os.ReadFile(conf.Cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(chain, conf.keyPEM)
+}
+
+// certIdentity returns the identity of cert from field, or "" if cert lacks the field.
+func certIdentity(cert *x509.Certificate, field string) string {
+	switch field {
+	case CertIdentityDNS:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	case CertIdentityURI:
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	case CertIdentityEmail:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	default:
+		return cert.Subject.CommonName
+	}
+	return ""
+}
+
+// certHTTP returns the verified client certificate of a request, or nil.
+func certHTTP(r *http.Request) *x509.Certificate {
+	return certVerified(r.TLS)
+}
+
+// certGRPC returns the verified client certificate of a call over a TLS listener, or nil.
+func certGRPC(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return certVerified(&info.State)
+}
+
+// certVerified returns the leaf of the connection's first verified client certificate chain, or nil.
+func certVerified(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
@@ -0,0 +1,92 @@
+package condukt
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Topology is a snapshot of cluster state for tooling and dashboards.
+type Topology struct {
+	Self    string        `json:"self"`
+	Nodes   []NodeState   `json:"nodes"`
+	Mirrors []MirrorState `json:"mirrors"`
+}
+
+// NodeState describes one node and the strands it owns.
+type NodeState struct {
+	ID      string   `json:"id"`
+	Addr    string   `json:"addr"`
+	Role    NodeRole `json:"role"`
+	Strands []string `json:"strands"`
+}
+
+// MirrorState describes replication of a local strand to a standby.
+type MirrorState struct {
+	Strand     string `json:"strand"`
+	Target     string `json:"target"`
+	LagMsgs    int    `json:"lag_messages"`
+	LagSeconds int64  `json:"lag_seconds"`
+}
+
+// Topology returns the nodes of the cluster, which strands each owns, and the replication lag of local mirrors.
+// A Conduktor that has not joined a cluster reports itself as the only node.
+func (c *Conduktor) Topology() Topology {
+	c.mu.Lock()
+	cl := c.cluster
+	topo := Topology{Self: c.id, Nodes: []NodeState{}, Mirrors: []MirrorState{}}
+	for strandID, m := range c.mirrors {
+		topo.Mirrors = append(topo.Mirrors, MirrorState{
+			Strand:     strandID,
+			Target:     m.conf.Strand,
+			LagMsgs:    len(m.queue),
+			LagSeconds: m.lag.Load(),
+		})
+	}
+	c.mu.Unlock()
+
+	if cl == nil {
+		topo.Nodes = append(topo.Nodes, NodeState{ID: c.id, Role: NodeRoleMember, Strands: []string{}})
+	} else {
+		topo.Nodes = cl.nodeStates()
+	}
+
+	sort.Slice(topo.Mirrors, func(i, j int) bool { return topo.Mirrors[i].Strand < topo.Mirrors[j].Strand })
+	return topo
+}
+
+// nodeStates lists cluster nodes with their explicitly assigned strands, sorted by ID.
+func (cl *Cluster) nodeStates() []NodeState {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+
+	owned := make(map[string][]string)
+	for strandID, owner := range cl.owners {
+		owned[owner] = append(owned[owner], strandID)
+	}
+
+	states := make([]NodeState, 0, len(cl.nodes))
+	for _, node := range cl.nodes {
+		strands := owned[node.ID]
+		if strands == nil {
+			strands = []string{}
+		}
+		sort.Strings(strands)
+		states = append(states, NodeState{ID: node.ID, Addr: node.Addr, Role: node.Role, Strands: strands})
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
+
+// TopologyHandler serves the Conduktor's topology as JSON.
+func TopologyHandler(c *Conduktor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.Topology()); err != nil {
+			c.log.Warn("Failed to encode topology", zap.Error(err))
+		}
+	})
+}
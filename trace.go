@@ -0,0 +1,240 @@
+package condukt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Delivery states of a message.
+const (
+	DeliveryPending      = "pending"       // Stored but not yet delivered, e.g. while its strand is paused
+	DeliveryInFlight     = "in_flight"     // Delivered on the wire and awaiting an ack
+	DeliveryAcked        = "acked"         // Acknowledged and removed from the store
+	DeliveryDeadLettered = "dead_lettered" // Moved to the strand's dead-letter strand
+	DeliveryEvicted      = "evicted"       // Dropped to make room under the strand's MaxBytes
+)
+
+// deliveryLogSize is how many messages the delivery log remembers.
+const deliveryLogSize = 10000
+
+// ErrMsgNotFound is returned when tracing a message that is neither stored nor remembered.
+var ErrMsgNotFound = errors.New("message not found")
+
+// Delivery records where a message is in its lifecycle. Times are Unix nanoseconds; zero if it has not happened.
+type Delivery struct {
+	ID           string `json:"id"`
+	Strand       string `json:"strand"`
+	State        string `json:"state"`
+	Store        string `json:"store,omitempty"` // durable or volatile, while the message is stored
+	Attempts     int    `json:"attempts"`        // Times it was sent on the wire
+	Stored       int64  `json:"stored,omitempty"`
+	Delivered    int64  `json:"delivered,omitempty"` // Last wire send
+	Received     int64  `json:"received,omitempty"`
+	Acked        int64  `json:"acked,omitempty"`
+	DeadLettered int64  `json:"dead_lettered,omitempty"`
+	Evicted      int64  `json:"evicted,omitempty"`
+	DLQStrand    string `json:"dlq_strand,omitempty"`
+	DLQReason    string `json:"dlq_reason,omitempty"`
+
+	span  trace.SpanContext // Span that sent the message, parenting the spans of its later steps
+	debug bool              // Marked with HeaderDebug, so each step is logged
+}
+
+// MsgTrace reports where a message is: its delivery record and, while stored, the message itself.
+type MsgTrace struct {
+	Delivery
+	Message *Msg `json:"message,omitempty"`
+}
+
+// deliveryLog remembers the delivery records of the most recent messages, forgetting the oldest,
+// and records each change in the event log, if there is one.
+type deliveryLog struct {
+	mu      sync.Mutex
+	records map[string]*Delivery // Message ID -> record
+	order   []string             // Message IDs, oldest first
+	events  EventLog
+	log     *zap.Logger
+}
+
+// deliveryLogMake returns an empty delivery log.
+func deliveryLogMake(log *zap.Logger) *deliveryLog {
+	return &deliveryLog{records: make(map[string]*Delivery), log: log}
+}
+
+// update applies fn to the record of msgID in strandID, creating it if needed.
+func (l *deliveryLog) update(msgID, strandID string, fn func(d *Delivery)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d, exists := l.records[msgID]
+	if !exists {
+		d = &Delivery{ID: msgID, Strand: strandID, State: DeliveryPending}
+		l.records[msgID] = d
+		l.order = append(l.order, msgID)
+		if len(l.order) > deliveryLogSize {
+			delete(l.records, l.order[0])
+			l.order = l.order[1:]
+		}
+	}
+	fn(d)
+}
+
+// get returns a copy of the record of msgID.
+func (l *deliveryLog) get(msgID string) (Delivery, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d, exists := l.records[msgID]
+	if !exists {
+		return Delivery{}, false
+	}
+	return *d, true
+}
+
+// stored records that msg was saved to store, sent in span.
+func (l *deliveryLog) stored(msg Msg, store string, span trace.SpanContext) {
+	l.update(msg.ID, msg.Strand, func(d *Delivery) {
+		d.State = DeliveryPending
+		d.Store = store
+		d.Stored = time.Now().UnixNano()
+		d.debug = d.debug || msg.Debug()
+		if span.IsValid() {
+			d.span = span
+		}
+	})
+	l.emit(MsgEvent{Event: EventSaved, Strand: msg.Strand, MsgID: msg.ID, Store: store})
+}
+
+// delivered records that msg was sent on the wire.
+func (l *deliveryLog) delivered(msg Msg) {
+	attempt := 0
+	l.update(msg.ID, msg.Strand, func(d *Delivery) {
+		d.State = DeliveryInFlight
+		d.Attempts++
+		d.Delivered = time.Now().UnixNano()
+		d.debug = d.debug || msg.Debug()
+		attempt = d.Attempts
+	})
+
+	event := EventSent
+	if attempt > 1 {
+		event = EventRedelivered
+	}
+	l.emit(MsgEvent{Event: event, Strand: msg.Strand, MsgID: msg.ID, Attempt: attempt})
+}
+
+// received records that msg was received from the wire.
+func (l *deliveryLog) received(msg Msg) {
+	l.update(msg.ID, msg.Strand, func(d *Delivery) {
+		d.Received = time.Now().UnixNano()
+		d.debug = d.debug || msg.Debug()
+	})
+	l.emit(MsgEvent{Event: EventReceived, Strand: msg.Strand, MsgID: msg.ID})
+}
+
+// removed records that a message left its store in state.
+func (l *deliveryLog) removed(strandID, msgID, state string) {
+	now := time.Now().UnixNano()
+	l.update(msgID, strandID, func(d *Delivery) {
+		d.State = state
+		d.Store = ""
+		switch state {
+		case DeliveryAcked:
+			d.Acked = now
+		case DeliveryEvicted:
+			d.Evicted = now
+		}
+	})
+
+	event := EventAcked
+	if state == DeliveryEvicted {
+		event = EventEvicted
+	}
+	l.emit(MsgEvent{Event: event, Strand: strandID, MsgID: msgID})
+}
+
+// deadLettered records that a message moved to dlqID.
+func (l *deliveryLog) deadLettered(strandID, msgID, dlqID, reason string) {
+	l.update(msgID, strandID, func(d *Delivery) {
+		d.State = DeliveryDeadLettered
+		d.Store = ""
+		d.DeadLettered = time.Now().UnixNano()
+		d.DLQStrand = dlqID
+		d.DLQReason = reason
+	})
+	l.emit(MsgEvent{Event: EventDeadLettered, Strand: strandID, MsgID: msgID, Reason: reason})
+}
+
+// Trace reports where a message is: pending, in flight, acked, dead-lettered, or evicted, which store
+// holds it, how often it was delivered, and when. Messages stored before this Conduktor started are
+// found in the stores; messages that left the stores are remembered for the most recent deliveryLogSize.
+func (c *Conduktor) Trace(msgID string) (MsgTrace, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, known := c.deliveries.get(msgID)
+	trace := MsgTrace{Delivery: d}
+
+	// Find the stored copy: in the dead-letter strand, the known strand, or by searching every strand
+	strands := []string{}
+	switch {
+	case d.State == DeliveryDeadLettered:
+		strands = append(strands, d.DLQStrand)
+	case known:
+		strands = append(strands, d.Strand)
+	default:
+		for _, store := range []Store{c.durable, c.volatile} {
+			all, err := store.ListStrands(context.Background())
+			if err != nil {
+				return MsgTrace{}, err
+			}
+			for strandID := range all {
+				strands = append(strands, strandID)
+			}
+		}
+	}
+	for _, strandID := range strands {
+		store, err := c.getStore(strandID)
+		if err != nil {
+			continue
+		}
+		if msg, err := store.Get(context.Background(), strandID, msgID); err == nil {
+			trace.Message = msg
+			if !known {
+				trace.Delivery = Delivery{ID: msgID, Strand: strandID, State: DeliveryPending, Stored: msg.Timestamp * int64(time.Second)}
+			}
+			trace.Store = c.storeName(store)
+			break
+		}
+	}
+
+	if !known && trace.Message == nil {
+		return MsgTrace{}, ErrMsgNotFound
+	}
+	return trace, nil
+}
+
+// storedAt returns when a message was stored: precisely from its delivery record, or to the second
+// from its timestamp, or zero if it cannot be found. Callers must hold c.mu.
+func (c *Conduktor) storedAt(store Store, strandID, msgID string) time.Time {
+	if d, known := c.deliveries.get(msgID); known && d.Stored != 0 {
+		return time.Unix(0, d.Stored)
+	}
+	if msg, err := store.Get(context.Background(), strandID, msgID); err == nil {
+		return time.Unix(msg.Timestamp, 0)
+	}
+	return time.Time{}
+}
+
+// storeName names store for traces.
+func (c *Conduktor) storeName(store Store) string {
+	if store == c.durable {
+		return "durable"
+	}
+	return "volatile"
+}
@@ -0,0 +1,175 @@
+package condukt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Tracing exporters.
+const (
+	TracingStdout = "stdout" // Pretty-printed JSON spans on stdout
+	TracingOTLP   = "otlp"   // OTLP over HTTP to Endpoint
+)
+
+// Span attributes.
+const (
+	attrStrand = attribute.Key("condukt.strand")
+	attrMsgID  = attribute.Key("condukt.msg_id")
+	attrStore  = attribute.Key("condukt.store")
+	attrWire   = attribute.Key("condukt.wire")
+
+	attrMessages = attribute.Key("condukt.messages") // Messages in a batch
+)
+
+// tracer creates the spans of a message's journey: send, store, transmit, receive, process, and ack.
+// Until TracingStart installs an exporter, it uses the global provider, which drops spans by default.
+var tracer = otel.Tracer("github.com/jkassis/condukt")
+
+// traceContext carries trace context in message headers, in the W3C traceparent and tracestate format.
+var traceContext = propagation.TraceContext{}
+
+// TracingConf selects where OpenTelemetry spans are exported.
+type TracingConf struct {
+	Exporter    string  `yaml:"exporter"`     // stdout or otlp; empty disables tracing
+	Endpoint    string  `yaml:"endpoint"`     // OTLP collector host:port (default localhost:4318)
+	Insecure    bool    `yaml:"insecure"`     // Send OTLP over plain HTTP
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces recorded; 0 records all
+	ServiceName string  `yaml:"service_name"` // Reported service name (default condukt)
+}
+
+// Validate reports an unknown exporter or sample ratio out of range.
+func (conf TracingConf) Validate() error {
+	if conf.Exporter != "" && conf.Exporter != TracingStdout && conf.Exporter != TracingOTLP {
+		return fmt.Errorf("tracing.exporter: unknown exporter %q (want stdout or otlp)", conf.Exporter)
+	}
+	if conf.SampleRatio < 0 || conf.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio: %v is not between 0 and 1", conf.SampleRatio)
+	}
+	return nil
+}
+
+// TracingStart installs a global tracer provider exporting to conf's exporter. The returned stop
+// flushes pending spans and shuts the exporter down.
+func TracingStart(ctx context.Context, conf TracingConf) (stop func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	switch conf.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case TracingStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case TracingOTLP:
+		opts := []otlptracehttp.Option{}
+		if conf.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", conf.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := conf.ServiceName
+	if name == "" {
+		name = "condukt"
+	}
+	sampler := sdktrace.AlwaysSample()
+	if conf.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(conf.SampleRatio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name))),
+	)
+	otel.SetTracerProvider(provider)
+	logger.Info("Tracing started")
+	return provider.Shutdown, nil
+}
+
+// SendContext makes Send's span a child of the span in ctx, and bounds its store and wire
+// operations by ctx.
+func SendContext(ctx context.Context) SendOption {
+	return func(o *sendOpts) {
+		o.ctx = ctx
+	}
+}
+
+// spanEnd records err on span, if any, and ends it.
+func spanEnd(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// MsgContext returns ctx carrying the span that sent msg, from its W3C trace context headers, so
+// consumers can make their processing spans part of the message's trace.
+func MsgContext(ctx context.Context, msg Msg) context.Context {
+	if msg.Headers == nil {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier(msg.Headers))
+}
+
+// msgContext returns a context carrying the span that sent msgID, as recorded when it was stored,
+// for steps that know only the message's ID.
+func (c *Conduktor) msgContext(msgID string) context.Context {
+	d, known := c.deliveries.get(msgID)
+	if !known || !d.span.IsValid() {
+		return context.Background()
+	}
+	return trace.ContextWithRemoteSpanContext(context.Background(), d.span)
+}
+
+// transmit sends msg on the wire in a span and records the delivery. data, when not nil, is msg
+// already encoded, for a wire that is an EncodedSender. Callers must hold c.mu.
+func (c *Conduktor) transmit(ctx context.Context, msg Msg, data []byte) error {
+	_, span := tracer.Start(ctx, "condukt.transmit", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrStrand.String(msg.Strand), attrMsgID.String(msg.ID), attrWire.String(c.wireType)))
+	var err error
+	for attempt := 1; ; attempt++ {
+		if sender, ok := c.wire.(EncodedSender); ok && data != nil {
+			err = sender.SendEncoded(ctx, msg, data)
+		} else {
+			err = c.wire.SendMessage(ctx, msg)
+		}
+		if err == nil || !c.retryWait(ctx, msg.Strand, attempt, err) {
+			break
+		}
+		c.log.Debug("Retrying wire send", zap.String("strand", msg.Strand), zap.String("msgID", msg.ID), zap.Int("attempt", attempt+1), zap.Error(err))
+	}
+	spanEnd(span, err)
+	if err != nil {
+		return err
+	}
+	c.deliveries.delivered(msg)
+	c.dispatch.wake(msg.Strand)
+	return nil
+}
+
+// processSpan records the time a consumer spent on a message, from its receipt to ack.
+func processSpan(ctx context.Context, strandID, msgID string, received int64) {
+	_, span := tracer.Start(ctx, "condukt.process", trace.WithTimestamp(time.Unix(0, received)),
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(attrStrand.String(strandID), attrMsgID.String(msgID)))
+	span.End()
+}
@@ -0,0 +1,155 @@
+package condukt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec converts values to and from message payloads.
+type Codec interface {
+	Marshal(v any) (string, error)
+	Unmarshal(payload string, v any) error
+}
+
+// JSONCodec encodes values as JSON.
+type JSONCodec struct{}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// Unmarshal decodes JSON into v.
+func (JSONCodec) Unmarshal(payload string, v any) error {
+	return json.Unmarshal([]byte(payload), v)
+}
+
+// ProtoJSONCodec encodes protobuf messages in their canonical JSON form. Values must implement
+// proto.Message.
+type ProtoJSONCodec struct{}
+
+// Marshal encodes v, a proto.Message, as JSON.
+func (ProtoJSONCodec) Marshal(v any) (string, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("%T is not a proto.Message", v)
+	}
+	data, err := protojson.Marshal(m)
+	return string(data), err
+}
+
+// Unmarshal decodes JSON into v, a proto.Message or a pointer to one, which is allocated if nil.
+func (ProtoJSONCodec) Unmarshal(payload string, v any) error {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Pointer {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		v = rv.Elem().Interface()
+	}
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return protojson.Unmarshal([]byte(payload), m)
+}
+
+// TypedStrand sends and receives values of type T on a strand, encoding them as payloads with
+// a Codec.
+type TypedStrand[T any] struct {
+	c        *Conduktor
+	strandID string
+	codec    Codec
+}
+
+// TypedStrandMake returns a TypedStrand for strandID on c. A nil codec is JSONCodec.
+func TypedStrandMake[T any](c *Conduktor, strandID string, codec Codec) *TypedStrand[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &TypedStrand[T]{c: c, strandID: strandID, codec: codec}
+}
+
+// Send encodes v and sends it on the strand.
+func (s *TypedStrand[T]) Send(v T, opts ...SendOption) error {
+	payload, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	return s.c.Send(s.strandID, payload, opts...)
+}
+
+// Receive waits for the next message on the strand, decodes it, and acknowledges it. Messages
+// that cannot be decoded are dead-lettered.
+func (s *TypedStrand[T]) Receive(opts ...ReceiveOption) (T, error) {
+	var v T
+	msg, err := s.c.Receive(s.strandID, opts...)
+	if err != nil {
+		return v, err
+	}
+	if v, err = s.decode(msg); err != nil {
+		return v, err
+	}
+	return v, s.c.Acknowledge(s.strandID, msg.ID)
+}
+
+// Subscribe calls handle with each message on the strand, decoded, until stop is called.
+// Messages are acknowledged when handle returns nil and dead-lettered with its error otherwise.
+func (s *TypedStrand[T]) Subscribe(handle func(T) error, opts ...ReceiveOption) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sub, err := s.c.Subscribe(s.strandID, opts...)
+		if err != nil {
+			s.c.log.Error("Failed to subscribe", zap.String("strand", s.strandID), zap.Error(err))
+			return
+		}
+		defer sub.Close()
+		for {
+			msg, err := sub.Next(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil { // Refused by the Deliver middleware
+				continue
+			}
+
+			v, err := s.decode(msg)
+			if err != nil {
+				continue
+			}
+			if err := handle(v); err != nil {
+				if err := s.c.DeadLetter(s.strandID, msg.ID, err.Error()); err != nil {
+					s.c.log.Error("Failed to dead-letter message", zap.String("strand", s.strandID), zap.String("msgID", msg.ID), zap.Error(err))
+				}
+				continue
+			}
+			if err := s.c.Acknowledge(s.strandID, msg.ID); err != nil {
+				s.c.log.Error("Failed to acknowledge message", zap.String("strand", s.strandID), zap.String("msgID", msg.ID), zap.Error(err))
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// decode decodes msg's payload, dead-lettering msg if it cannot be decoded.
+func (s *TypedStrand[T]) decode(msg *Msg) (T, error) {
+	var v T
+	err := s.codec.Unmarshal(msg.Payload, &v)
+	if err == nil {
+		return v, nil
+	}
+
+	err = fmt.Errorf("decode: %w", err)
+	s.c.log.Warn("Failed to decode message", zap.String("strand", s.strandID), zap.String("msgID", msg.ID), zap.Error(err))
+	if err := s.c.DeadLetter(s.strandID, msg.ID, err.Error()); err != nil {
+		s.c.log.Error("Failed to dead-letter message", zap.String("strand", s.strandID), zap.String("msgID", msg.ID), zap.Error(err))
+	}
+	return v, err
+}
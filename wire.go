@@ -1,7 +1,33 @@
-package main
+package condukt
 
-// Wire defines the interface for sending messages via different transports
-type Wire interface {
-	SendMessage(msg Msg) error
-	ReceiveMessage(channel string) (*Msg, error) // Receiver function restored
-}
+import "github.com/jkassis/condukt/wire"
+
+// Wire carries messages between Conduktors and their clients. Implementations are in package wire.
+type Wire = wire.Wire
+
+// WireAuthorizer authenticates wire clients and authorizes what they do.
+type WireAuthorizer = wire.WireAuthorizer
+
+// WireDisconnector closes the connection of a strand's consumer.
+type WireDisconnector = wire.WireDisconnector
+
+// EncodedSender is a Wire that can send a message already encoded with wire.MsgEncode.
+type EncodedSender = wire.EncodedSender
+
+// StrandRouter tells wire listeners which node owns a strand.
+type StrandRouter = wire.StrandRouter
+
+// CoalesceConf configures write coalescing on WebSocket connections.
+type CoalesceConf = wire.CoalesceConf
+
+// RingConf configures the buffer a GoChanWire keeps for a strand.
+type RingConf = wire.RingConf
+
+// ClientLimits caps what each wire client may use.
+type ClientLimits = wire.ClientLimits
+
+// Client limit errors.
+var (
+	ErrTooManyConnections = wire.ErrTooManyConnections
+	ErrRateLimited        = wire.ErrRateLimited
+)
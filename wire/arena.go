@@ -0,0 +1,78 @@
+package wire
+
+import "context"
+
+// ArenaDefaultChunk is the chunk size of an Arena made with a chunk size of 0.
+const ArenaDefaultChunk = 1 << 20
+
+// arenaMsgs is how many Msgs an Arena allocates at a time.
+const arenaMsgs = 1024
+
+// Arena decodes messages into memory allocated in large chunks rather than one message at a time,
+// for bulk work such as recovery at high rates: each encoding is copied into a chunk of bytes and
+// decoded in place, and each Msg comes from a chunk of Msgs. A chunk is freed whole once none of
+// its messages is reachable, so the garbage collector tracks a few large objects instead of many
+// small ones. Headers maps are still allocated one per message.
+//
+// Keeping any message, or a string taken from one, keeps its whole chunk alive; keep a Clone
+// instead to hold on to a message alone. An Arena is not safe for concurrent use.
+type Arena struct {
+	chunk int
+	bytes []byte // Unused rest of the current byte chunk
+	msgs  []Msg  // Unused rest of the current Msg chunk
+}
+
+// ArenaMake returns an Arena allocating bytes chunk at a time; 0 is ArenaDefaultChunk.
+func ArenaMake(chunk int) *Arena {
+	if chunk <= 0 {
+		chunk = ArenaDefaultChunk
+	}
+	return &Arena{chunk: chunk}
+}
+
+// alloc returns n bytes from the current chunk, starting a new one if they do not fit. Requests over
+// a quarter chunk get their own allocation rather than waste the rest of the chunk.
+func (a *Arena) alloc(n int) []byte {
+	if n > a.chunk/4 {
+		return make([]byte, n)
+	}
+	if n > len(a.bytes) {
+		a.bytes = make([]byte, a.chunk)
+	}
+	b := a.bytes[:n:n]
+	a.bytes = a.bytes[n:]
+	return b
+}
+
+// Decode decodes data as MsgDecode does, into memory from the arena. data is copied, so the caller
+// may reuse it at once.
+func (a *Arena) Decode(data []byte) (*Msg, error) {
+	buf := a.alloc(len(data))
+	copy(buf, data)
+	msg, err := msgDecode(buf, shareAll)
+	if err != nil {
+		return nil, err
+	}
+	if len(a.msgs) == 0 {
+		a.msgs = make([]Msg, arenaMsgs)
+	}
+	m := &a.msgs[0]
+	a.msgs = a.msgs[1:]
+	*m = msg
+	return m, nil
+}
+
+// arenaKey is the context key of an Arena.
+type arenaKey struct{}
+
+// WithArena returns a copy of ctx carrying arena, for stores to decode the messages of bulk reads
+// into, such as those of UnackedIterator.
+func WithArena(ctx context.Context, arena *Arena) context.Context {
+	return context.WithValue(ctx, arenaKey{}, arena)
+}
+
+// ArenaFrom returns the Arena ctx carries, or nil.
+func ArenaFrom(ctx context.Context) *Arena {
+	arena, _ := ctx.Value(arenaKey{}).(*Arena)
+	return arena
+}
@@ -0,0 +1,29 @@
+package wire
+
+import "encoding/json"
+
+// Client protocol operations, in ClientFrame.Op. Clients send requests, each with a Seq the broker
+// echoes in its reply, and the broker pushes the messages of subscribed strands.
+const (
+	ClientSend        = "send"        // Request: send Payload and Headers to Strand
+	ClientAck         = "ack"         // Request: acknowledge MsgID on Strand
+	ClientAckBatch    = "ack_batch"   // Request: acknowledge all of MsgIDs on Strand at once
+	ClientSubscribe   = "subscribe"   // Request: deliver Strand's messages to this connection
+	ClientUnsubscribe = "unsubscribe" // Request: stop delivering Strand's messages
+	ClientOK          = "ok"          // Reply: the request succeeded
+	ClientError       = "error"       // Reply: the request failed with Error
+	ClientMsg         = "msg"         // Push: Msg was delivered from Strand
+)
+
+// ClientFrame is a request, reply, or push on a client connection, sent as a WebSocket text message.
+type ClientFrame struct {
+	Op      string            `json:"op"`
+	Seq     uint64            `json:"seq,omitempty"` // Pairs a reply with its request; 0 asks for no reply
+	Strand  string            `json:"strand,omitempty"`
+	MsgID   string            `json:"msg_id,omitempty"`
+	MsgIDs  []string          `json:"msg_ids,omitempty"`
+	Payload string            `json:"payload,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Msg     json.RawMessage   `json:"msg,omitempty"` // A pushed message, encoded with MsgEncodeJSON
+	Error   string            `json:"error,omitempty"`
+}
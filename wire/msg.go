@@ -0,0 +1,218 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// MsgVersion is the schema version messages are encoded with. Decoding accepts every earlier
+// version, so peers and stores can be upgraded one at a time:
+//
+//	0: unversioned; the strand was encoded as Channel
+//	1: Strand and Version
+const MsgVersion = 1
+
+// ErrMsgVersion is returned when decoding a message encoded by a newer schema version.
+var ErrMsgVersion = errors.New("unsupported message schema version")
+
+// Msg is a message as stored and carried on a wire.
+type Msg struct {
+	Version   int // Schema version; MsgEncode and MsgDecode set it to MsgVersion
+	ID        string
+	Strand    string
+	Payload   string
+	Acked     bool
+	Timestamp int64
+	Headers   map[string]string
+}
+
+// Codecs messages can be encoded with. Each store and wire encodes with its own, chosen with
+// MakeCodec; MsgDecode detects which encoded a message.
+const (
+	CodecBinary = "binary" // Compact and fast; the default
+	CodecJSON   = "json"   // Readable, for debugging, and understood by releases before the binary codec
+)
+
+// MsgCodecCheck reports a codec other than CodecBinary or CodecJSON. An empty codec is CodecBinary.
+func MsgCodecCheck(codec string) error {
+	switch codec {
+	case "", CodecBinary, CodecJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown codec %q (want binary or json)", codec)
+}
+
+// MsgEncode encodes msg, as stored and carried on a wire, with the current schema version and the
+// default codec, CodecBinary.
+func MsgEncode(msg Msg) ([]byte, error) {
+	return MsgEncodeCodec(msg, CodecBinary)
+}
+
+// MsgEncodeCodec encodes msg as MsgEncode does, with codec. An empty codec is CodecBinary.
+func MsgEncodeCodec(msg Msg, codec string) ([]byte, error) {
+	if codec == CodecJSON {
+		return MsgEncodeJSON(msg)
+	}
+	return msgAppendBinary(make([]byte, 0, msgBinarySize(msg)), msg), nil
+}
+
+// MsgEncodeJSON encodes msg as JSON, with the current schema version, whatever the codec. Use it
+// where the encoding must be text, such as in frames sent to clients.
+func MsgEncodeJSON(msg Msg) ([]byte, error) {
+	msg.Version = MsgVersion
+	return json.Marshal(msg)
+}
+
+// maxPooledBuffer caps the size of the encoding buffers kept for reuse, so one large message does
+// not pin its buffer.
+const maxPooledBuffer = 64 << 10
+
+// encodeBuffers recycles the buffers of MsgEncodePooled.
+var encodeBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// MsgEncodePooled encodes msg as MsgEncode does, into a reused buffer, and passes the encoding to
+// use. The encoding is only valid until use returns.
+func MsgEncodePooled(msg Msg, use func(data []byte) error) error {
+	return msgEncodePooled(msg, false, use)
+}
+
+// MsgEncodePooledCodec encodes msg as MsgEncodePooled does, with codec. An empty codec is
+// CodecBinary.
+func MsgEncodePooledCodec(msg Msg, codec string, use func(data []byte) error) error {
+	return msgEncodePooled(msg, codec == CodecJSON, use)
+}
+
+// msgEncodePooled encodes msg into a reused buffer, as JSON if asJSON is set, and passes the
+// encoding to use.
+func msgEncodePooled(msg Msg, asJSON bool, use func(data []byte) error) error {
+	buf := encodeBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			encodeBuffers.Put(buf)
+		}
+	}()
+
+	if !asJSON {
+		buf.Grow(msgBinarySize(msg))
+		buf.Write(msgAppendBinary(buf.AvailableBuffer(), msg)) // Appends in place
+		return use(buf.Bytes())
+	}
+	msg.Version = MsgVersion
+	if err := json.NewEncoder(buf).Encode(msg); err != nil {
+		return err
+	}
+	return use(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// MsgDecode decodes a message encoded by any schema version up to MsgVersion, with either codec,
+// upgrading it to the current version.
+func MsgDecode(data []byte) (Msg, error) {
+	return msgDecode(data, shareNone)
+}
+
+// MsgDecodeShared decodes a message as MsgDecode does, without copying its payload where it can:
+// unless the payload is JSON with escaped characters, msg.Payload shares memory with data. data is handed
+// over; the caller must not modify or reuse it while msg, or a string taken from its payload, is
+// reachable. To keep a message without keeping all of data alive, keep its Clone.
+func MsgDecodeShared(data []byte) (Msg, error) {
+	return msgDecode(data, sharePayload)
+}
+
+// msgShare is which strings of a decoded message share memory with the bytes it was decoded from.
+type msgShare int
+
+const (
+	shareNone    msgShare = iota
+	sharePayload          // The payload only, as IDs and strands outlive messages as map keys
+	shareAll              // Every string the codec can share, for bytes freed no sooner than the message
+)
+
+// msgDecode decodes data, sharing its bytes as share says.
+func msgDecode(data []byte, share msgShare) (Msg, error) {
+	if msgBinary(data) {
+		return msgDecodeBinary(data, share)
+	}
+	var v struct {
+		Msg
+		Channel string        // Strand, in version 0
+		Payload sharedPayload // Shadows Msg.Payload
+	}
+	if share != shareNone {
+		v.Payload.in = data
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return Msg{}, err
+	}
+	v.Msg.Payload = v.Payload.out
+	if v.Version > MsgVersion {
+		return Msg{}, fmt.Errorf("%w %d (newest supported is %d)", ErrMsgVersion, v.Version, MsgVersion)
+	}
+	if v.Version == 0 && v.Strand == "" {
+		v.Strand = v.Channel
+	}
+
+	v.Msg.Version = MsgVersion
+	return v.Msg, nil
+}
+
+// sharedPayload decodes a JSON string. A string without escapes, that encoding/json hands over as
+// a slice of the message being decoded (in), is referenced rather than copied.
+type sharedPayload struct {
+	in  []byte
+	out string
+}
+
+func (p *sharedPayload) UnmarshalJSON(data []byte) error {
+	if len(data) > 2 && data[0] == '"' && within(p.in, data) {
+		text := data[1 : len(data)-1]
+		if bytes.IndexByte(text, '\\') < 0 && utf8.Valid(text) { // Otherwise decoding changes the bytes
+			p.out = unsafe.String(&text[0], len(text))
+			return nil
+		}
+	}
+	return json.Unmarshal(data, &p.out)
+}
+
+// within reports whether the bytes of part lie in those of whole.
+func within(whole, part []byte) bool {
+	if len(whole) == 0 || len(part) == 0 {
+		return false
+	}
+	start := uintptr(unsafe.Pointer(&whole[0]))
+	at := uintptr(unsafe.Pointer(&part[0]))
+	return at >= start && at+uintptr(len(part)) <= start+uintptr(len(whole))
+}
+
+// Clone returns a copy of m sharing no memory with it, such as the buffer a message decoded by
+// MsgDecodeShared references.
+func (m Msg) Clone() Msg {
+	m.Payload = strings.Clone(m.Payload)
+	m.Headers = maps.Clone(m.Headers)
+	return m
+}
+
+// HeaderDebug set to "true" logs the message at every hop, whatever the log level.
+const HeaderDebug = "x-debug"
+
+// Size approximates the bytes a message occupies: its IDs, payload, and headers. Stores count
+// strand bytes, which StrandConf.MaxBytes caps, in it.
+func (m Msg) Size() int64 {
+	size := len(m.ID) + len(m.Strand) + len(m.Payload)
+	for k, v := range m.Headers {
+		size += len(k) + len(v)
+	}
+	return int64(size)
+}
+
+// Debug reports whether msg is marked for verbose logging at every hop.
+func (m Msg) Debug() bool {
+	return strings.EqualFold(m.Headers[HeaderDebug], "true")
+}
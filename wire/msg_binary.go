@@ -0,0 +1,179 @@
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"unsafe"
+)
+
+// Binary encoding of a message: a fixed header, then length-prefixed fields.
+//
+//	0      msgMagic
+//	1      schema version
+//	2      flags; bit 0 is Acked
+//	3-10   Timestamp, big-endian
+//	11-    ID, Strand, and Payload, each a uvarint length and its bytes
+//	       the number of headers, a uvarint, then each key and value as above, sorted by key
+const (
+	msgMagic      = 0xC7 // No JSON text starts with a byte over 0x7F
+	msgHeaderSize = 11
+	msgAcked      = 1 << 0
+)
+
+// errMsgTruncated is returned when decoding a binary message that ends early.
+var errMsgTruncated = errors.New("truncated binary message")
+
+// msgBinary reports whether data is binary encoded, rather than JSON.
+func msgBinary(data []byte) bool {
+	return len(data) > 0 && data[0] == msgMagic
+}
+
+// msgAppendBinary appends the binary encoding of msg to buf.
+func msgAppendBinary(buf []byte, msg Msg) []byte {
+	var flags byte
+	if msg.Acked {
+		flags |= msgAcked
+	}
+	buf = append(buf, msgMagic, MsgVersion, flags)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(msg.Timestamp))
+	buf = appendField(buf, msg.ID)
+	buf = appendField(buf, msg.Strand)
+	buf = appendField(buf, msg.Payload)
+	buf = binary.AppendUvarint(buf, uint64(len(msg.Headers)))
+	if len(msg.Headers) == 1 { // Skip sorting the common case
+		for k, v := range msg.Headers {
+			buf = appendField(appendField(buf, k), v)
+		}
+	} else if len(msg.Headers) > 1 {
+		for _, k := range slices.Sorted(maps.Keys(msg.Headers)) {
+			buf = appendField(appendField(buf, k), msg.Headers[k])
+		}
+	}
+	return buf
+}
+
+// msgBinarySize returns at least the bytes of msg's binary encoding.
+func msgBinarySize(msg Msg) int {
+	return msgHeaderSize + int(msg.Size()) + binary.MaxVarintLen64*(4+2*len(msg.Headers))
+}
+
+// appendField appends s to buf, prefixed with its length.
+func appendField(buf []byte, s string) []byte {
+	return append(binary.AppendUvarint(buf, uint64(len(s))), s...)
+}
+
+// msgDecodeBinary decodes a binary encoded message, sharing its bytes with data as share says.
+func msgDecodeBinary(data []byte, share msgShare) (Msg, error) {
+	if len(data) < msgHeaderSize {
+		return Msg{}, errMsgTruncated
+	}
+	if version := int(data[1]); version > MsgVersion {
+		return Msg{}, fmt.Errorf("%w %d (newest supported is %d)", ErrMsgVersion, version, MsgVersion)
+	}
+	msg := Msg{
+		Version:   MsgVersion,
+		Acked:     data[2]&msgAcked != 0,
+		Timestamp: int64(binary.BigEndian.Uint64(data[3:])),
+	}
+
+	r := fieldReader{data: data[msgHeaderSize:]}
+	msg.ID = r.field(share == shareAll)
+	msg.Strand = r.field(share == shareAll)
+	msg.Payload = r.field(share != shareNone)
+	if n := r.uvarint(); n > 0 && r.err == nil {
+		if n > uint64(len(r.data)/2) { // Each header takes at least two bytes
+			return Msg{}, errMsgTruncated
+		}
+		msg.Headers = make(map[string]string, n)
+		for range n {
+			k := r.field(share == shareAll)
+			msg.Headers[k] = r.field(share == shareAll)
+		}
+	}
+	if r.err != nil {
+		return Msg{}, r.err
+	}
+	return msg, nil
+}
+
+// MsgSize returns the Size of the message data encodes. Binary messages are sized by their field
+// lengths alone, without decoding them; JSON ones are decoded. data is not retained.
+func MsgSize(data []byte) (int64, error) {
+	if !msgBinary(data) {
+		msg, err := MsgDecodeShared(data) // Only sized, so sharing data is safe
+		return msg.Size(), err
+	}
+	if len(data) < msgHeaderSize {
+		return 0, errMsgTruncated
+	}
+	if version := int(data[1]); version > MsgVersion {
+		return 0, fmt.Errorf("%w %d (newest supported is %d)", ErrMsgVersion, version, MsgVersion)
+	}
+
+	r := fieldReader{data: data[msgHeaderSize:]}
+	size := r.skip() + r.skip() + r.skip() // ID, Strand, and Payload
+	if n := r.uvarint(); n > 0 && r.err == nil {
+		if n > uint64(len(r.data)/2) { // Each header takes at least two bytes
+			return 0, errMsgTruncated
+		}
+		for range 2 * n {
+			size += r.skip()
+		}
+	}
+	return int64(size), r.err
+}
+
+// fieldReader reads the length-prefixed fields of a binary message, recording the first error.
+type fieldReader struct {
+	data []byte
+	err  error
+}
+
+// uvarint reads a uvarint.
+func (r *fieldReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(r.data)
+	if size <= 0 {
+		r.err = errMsgTruncated
+		return 0
+	}
+	r.data = r.data[size:]
+	return n
+}
+
+// skip passes over a length-prefixed string, returning its length.
+func (r *fieldReader) skip() int {
+	n := r.uvarint()
+	if r.err != nil {
+		return 0
+	}
+	if n > uint64(len(r.data)) {
+		r.err = errMsgTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return int(n)
+}
+
+// field reads a length-prefixed string, referencing the bytes it was read from if shared is set.
+func (r *fieldReader) field(shared bool) string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.data)) {
+		r.err = errMsgTruncated
+		return ""
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	if shared && n > 0 {
+		return unsafe.String(&b[0], n)
+	}
+	return string(b)
+}
@@ -0,0 +1,232 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Messages Of Every Schema Version Decode
+func TestMsgDecode(t *testing.T) {
+	data, err := MsgEncodeJSON(Msg{ID: "1", Strand: "orders", Payload: "current"})
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(data), `"Version":1`)
+		msg, err := MsgDecode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, Msg{Version: MsgVersion, ID: "1", Strand: "orders", Payload: "current"}, msg)
+	}
+
+	// Version 0 named the strand Channel
+	msg, err := MsgDecode([]byte(`{"ID": "2", "Channel": "orders", "Payload": "legacy"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Msg{Version: MsgVersion, ID: "2", Strand: "orders", Payload: "legacy"}, msg)
+
+	_, err = MsgDecode([]byte(`{"Version": 99, "ID": "3", "Strand": "orders"}`))
+	assert.ErrorIs(t, err, ErrMsgVersion)
+}
+
+// Test Binary Messages Round Trip And Reject Damage
+func TestMsgBinary(t *testing.T) {
+	msg := Msg{Version: MsgVersion, ID: "1", Strand: "orders", Payload: "caf\xe9\n\"x\"", Acked: true, Timestamp: -5,
+		Headers: map[string]string{"b": "2", "a": "1", "c": ""}}
+	data, err := MsgEncode(msg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, msgBinary(data))
+	again, _ := MsgEncode(msg)
+	assert.Equal(t, data, again, "headers are encoded in order")
+	for _, decode := range []func([]byte) (Msg, error){MsgDecode, MsgDecodeShared} {
+		decoded, err := decode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, decoded)
+	}
+
+	for n := range len(data) {
+		_, err := MsgDecode(data[:n])
+		assert.Error(t, err, "truncated to %d bytes", n)
+	}
+	data[1] = MsgVersion + 1
+	_, err = MsgDecode(data)
+	assert.ErrorIs(t, err, ErrMsgVersion)
+
+	// JSON can be chosen instead, and either decodes. JSON replaces invalid UTF-8; binary keeps it
+	msg.Payload = "café"
+	assert.Error(t, MsgCodecCheck("xml"))
+	assert.NoError(t, MsgCodecCheck(CodecJSON))
+	data, _ = MsgEncodeCodec(msg, CodecJSON)
+	assert.Contains(t, string(data), `"Payload"`)
+	decoded, err := MsgDecode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, decoded)
+}
+
+// Test MsgSize Sizes Encodings Of Either Codec As Msg.Size Does, Decoding Only JSON
+func TestMsgSize(t *testing.T) {
+	msg := Msg{ID: "1", Strand: "orders", Payload: "café", Headers: map[string]string{"b": "2", "a": "1", "c": ""}}
+	for _, codec := range []string{CodecBinary, CodecJSON} {
+		data, _ := MsgEncodeCodec(msg, codec)
+		size, err := MsgSize(data)
+		assert.NoError(t, err)
+		assert.Equal(t, msg.Size(), size, codec)
+	}
+
+	data, _ := MsgEncode(msg)
+	assert.Zero(t, testing.AllocsPerRun(10, func() { MsgSize(data) }), "binary messages are not decoded")
+	for n := range len(data) {
+		_, err := MsgSize(data[:n])
+		assert.Error(t, err, "truncated to %d bytes", n)
+	}
+	data[1] = MsgVersion + 1
+	_, err := MsgSize(data)
+	assert.ErrorIs(t, err, ErrMsgVersion)
+}
+
+// Test Pooled Encoding Matches MsgEncode
+func TestMsgEncodePooled(t *testing.T) {
+	msg := Msg{ID: "1", Strand: "orders", Payload: "<b>pooled</b>", Headers: map[string]string{"k": "v"}}
+	for _, codec := range []string{CodecBinary, CodecJSON} {
+		want, _ := MsgEncodeCodec(msg, codec)
+		for range 2 { // The second encoding reuses the first's buffer
+			assert.NoError(t, MsgEncodePooledCodec(msg, codec, func(data []byte) error {
+				assert.Equal(t, string(want), string(data), codec)
+				return nil
+			}))
+		}
+	}
+	want, _ := MsgEncode(msg)
+	assert.NoError(t, MsgEncodePooled(msg, func(data []byte) error {
+		assert.Equal(t, want, data, "binary is the default")
+		return nil
+	}))
+	assert.ErrorIs(t, MsgEncodePooled(msg, func([]byte) error { return ErrMsgVersion }), ErrMsgVersion)
+}
+
+// Test Shared Decoding References Plain Payloads And Copies Escaped Ones
+func TestMsgDecodeShared(t *testing.T) {
+	for _, payload := range []string{"plain", `"quoted"`, "line\nbreak", "", "<b>html</b>", "caf\xe9"} {
+		data, _ := MsgEncodeJSON(Msg{ID: "1", Strand: "orders", Payload: payload, Headers: map[string]string{"k": "v"}})
+		want, err := MsgDecode(data)
+		assert.NoError(t, err)
+		msg, err := MsgDecodeShared(data)
+		assert.NoError(t, err)
+		assert.Equal(t, want, msg, "%q", payload)
+	}
+
+	data := []byte(`{"Version": 1, "ID": "1", "Strand": "orders", "Payload": "shared"}`)
+	msg, err := MsgDecodeShared(data)
+	if assert.NoError(t, err) {
+		assert.True(t, within(data, unsafe.Slice(unsafe.StringData(msg.Payload), len(msg.Payload))), "payload references data")
+		clone := msg.Clone()
+		copy(data[bytes.Index(data, []byte("shared")):], "SHARED")
+		assert.Equal(t, "SHARED", msg.Payload)
+		assert.Equal(t, "shared", clone.Payload, "the clone keeps its own copy")
+	}
+	msg, err = MsgDecode(data)
+	if assert.NoError(t, err) {
+		assert.False(t, within(data, unsafe.Slice(unsafe.StringData(msg.Payload), len(msg.Payload))), "MsgDecode copies")
+	}
+
+	msg, err = MsgDecodeShared([]byte(`{"ID": "2", "Channel": "orders", "Payload": "legacy"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Msg{Version: MsgVersion, ID: "2", Strand: "orders", Payload: "legacy"}, msg)
+}
+
+// Benchmark Decoding A 1MiB Payload, Copied And Shared
+func BenchmarkMsgDecode(b *testing.B) {
+	data, _ := MsgEncodeJSON(Msg{ID: "1", Strand: "orders", Payload: strings.Repeat("x", 1<<20)})
+	for _, shared := range []bool{false, true} {
+		name, decode := "Copied", MsgDecode
+		if shared {
+			name, decode = "Shared", MsgDecodeShared
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := decode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark Encoding And Decoding A Typical Message With Each Codec
+func BenchmarkMsgCodec(b *testing.B) {
+	msg := Msg{ID: "1792214633146674900", Strand: "orders", Payload: strings.Repeat("x", 256), Timestamp: 1792214633,
+		Headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	for _, codec := range []string{CodecBinary, CodecJSON} {
+		b.Run(codec, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if err := MsgEncodePooledCodec(msg, codec, func(data []byte) error {
+					_, err := MsgDecodeShared(data)
+					return err
+				}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Test Arena Decoding Copies Messages Into Shared Chunks
+func TestArena(t *testing.T) {
+	arena := ArenaMake(1024)
+	at := func(s string) uintptr { return uintptr(unsafe.Pointer(unsafe.StringData(s))) }
+
+	want := Msg{Version: MsgVersion, ID: "1", Strand: "orders", Payload: "Arena", Timestamp: 1792214633, Headers: map[string]string{"k": "v"}}
+	data, err := MsgEncode(want)
+	assert.NoError(t, err)
+	first, err := arena.Decode(data)
+	assert.NoError(t, err)
+	clear(data) // Decode copied it
+	assert.Equal(t, want, *first)
+
+	// Large messages get their own memory, leaving the chunk for later small ones
+	data, _ = MsgEncode(Msg{ID: "2", Strand: "orders", Payload: strings.Repeat("x", 600)})
+	large, err := arena.Decode(data)
+	assert.NoError(t, err)
+	assert.Len(t, large.Payload, 600)
+	data, _ = MsgEncode(Msg{ID: "3", Strand: "orders", Payload: "Next"})
+	next, err := arena.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Next", next.Payload)
+	assert.Less(t, at(next.Payload)-at(first.Payload), uintptr(1024), "small messages share a chunk")
+	assert.Equal(t, uintptr(unsafe.Pointer(first))+2*unsafe.Sizeof(Msg{}), uintptr(unsafe.Pointer(next)), "as do Msgs")
+
+	_, err = arena.Decode([]byte{0xC7})
+	assert.Error(t, err)
+	assert.Same(t, arena, ArenaFrom(WithArena(context.Background(), arena)))
+	assert.Nil(t, ArenaFrom(context.Background()))
+}
+
+// Benchmark Decoding 1,000 Typical Messages, Each Alone And Into An Arena
+func BenchmarkArenaDecode(b *testing.B) {
+	data, _ := MsgEncode(Msg{ID: "1792214633146674900", Strand: "orders", Payload: strings.Repeat("x", 256), Timestamp: 1792214633})
+	b.Run("Alone", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			msgs := make([]*Msg, 0, 1000)
+			for range 1000 {
+				msg, _ := MsgDecode(data)
+				msgs = append(msgs, &msg)
+			}
+		}
+	})
+	b.Run("Arena", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			arena := ArenaMake(64 << 10)
+			msgs := make([]*Msg, 0, 1000)
+			for range 1000 {
+				msg, _ := arena.Decode(data)
+				msgs = append(msgs, msg)
+			}
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package wire
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jkassis/condukt/internal/rate"
+	"github.com/jkassis/condukt/internal/telemetry"
+)
+
+// Client limit errors.
+var (
+	ErrTooManyConnections = errors.New("too many connections from client")
+	ErrRateLimited        = errors.New("client rate limit exceeded")
+)
+
+// ClientLimits caps what each wire client, identified by its authenticated identity or else its IP,
+// may use. Zero values disable a limit.
+type ClientLimits struct {
+	MaxConnections int     `yaml:"max_connections"` // Open connections
+	MaxRate        float64 `yaml:"max_rate"`        // Messages per second, with bursts of up to one second's worth
+	MaxBytesRate   float64 `yaml:"max_bytes_rate"`  // Payload bytes per second, with bursts of up to one second's worth
+}
+
+// clientLimiter enforces ClientLimits across the connections of each client.
+type clientLimiter struct {
+	mu      sync.Mutex
+	limits  ClientLimits
+	clients map[string]*clientUsage // Client -> usage, while it has open connections
+}
+
+// clientUsage is what one client is using.
+type clientUsage struct {
+	connections int
+	msgs        *rate.Bucket
+	bytes       *rate.Bucket
+}
+
+// clientLimiterMake returns a limiter enforcing limits.
+func clientLimiterMake(limits ClientLimits) *clientLimiter {
+	return &clientLimiter{limits: limits, clients: make(map[string]*clientUsage)}
+}
+
+// connect counts a new connection from client, refusing it if the client has too many.
+func (l *clientLimiter) connect(client string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage, exists := l.clients[client]
+	if !exists {
+		usage = &clientUsage{}
+		if l.limits.MaxRate > 0 {
+			usage.msgs = rate.BucketMake(l.limits.MaxRate, max(l.limits.MaxRate, 1))
+		}
+		if l.limits.MaxBytesRate > 0 {
+			usage.bytes = rate.BucketMake(l.limits.MaxBytesRate, l.limits.MaxBytesRate)
+		}
+		l.clients[client] = usage
+	}
+	if l.limits.MaxConnections > 0 && usage.connections >= l.limits.MaxConnections {
+		telemetry.ClientRejections.WithLabelValues("connections").Inc()
+		return ErrTooManyConnections
+	}
+	usage.connections++
+	return nil
+}
+
+// disconnect counts a closed connection from client, forgetting clients with none left.
+func (l *clientLimiter) disconnect(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage, exists := l.clients[client]
+	if !exists {
+		return
+	}
+	if usage.connections--; usage.connections <= 0 {
+		delete(l.clients, client)
+	}
+}
+
+// allow takes a message of size payload bytes from client's rate limits, refusing it if either is exhausted.
+func (l *clientLimiter) allow(client string, size int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage, exists := l.clients[client]
+	if !exists {
+		return nil
+	}
+	now := time.Now()
+	if usage.msgs != nil && !usage.msgs.Take(1, now) {
+		telemetry.ClientRejections.WithLabelValues("rate").Inc()
+		return ErrRateLimited
+	}
+	if usage.bytes != nil && !usage.bytes.Take(float64(size), now) {
+		telemetry.ClientRejections.WithLabelValues("bytes").Inc()
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// clientKey identifies a client by its identity, or else the IP it connects from.
+func clientKey(identity, remoteAddr string) string {
+	if identity != "" {
+		return identity
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
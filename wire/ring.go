@@ -0,0 +1,185 @@
+package wire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Ring buffer overflow policies.
+const (
+	RingReject = "reject" // Fail sends to a full buffer
+	RingEvict  = "evict"  // Drop the oldest buffered message to make room
+	RingBlock  = "block"  // Wait for room, or for the send's context to be done
+)
+
+// RingDefaultCapacity is the capacity of buffers configured without one.
+const RingDefaultCapacity = 100000
+
+// ringInitial is the slots a buffer starts with. It grows as messages arrive, up to its capacity,
+// and shrinks as they leave, so idle strands hold little memory.
+const ringInitial = 16
+
+// Ring buffer errors.
+var (
+	ErrRingFull   = errors.New("channel buffer full")
+	ErrRingClosed = errors.New("channel closed")
+)
+
+// RingConf configures the buffer a GoChanWire keeps for a strand.
+type RingConf struct {
+	Capacity int    `yaml:"capacity"` // Messages buffered at most; 0 is RingDefaultCapacity
+	Overflow string `yaml:"overflow"` // What a send to a full buffer does: reject (default), evict, or block
+}
+
+// Validate reports a negative capacity or an unknown overflow policy.
+func (conf RingConf) Validate() error {
+	var errs []error
+	if conf.Capacity < 0 {
+		errs = append(errs, errors.New("buffer.capacity: must not be negative"))
+	}
+	switch conf.Overflow {
+	case "", RingReject, RingEvict, RingBlock:
+	default:
+		errs = append(errs, fmt.Errorf("buffer.overflow: unknown policy %q (want reject, evict, or block)", conf.Overflow))
+	}
+	return errors.Join(errs...)
+}
+
+// ring is a growable FIFO of messages, bounded by its conf.
+type ring struct {
+	mu     sync.Mutex
+	conf   RingConf
+	buf    []Msg
+	head   int           // Index of the oldest message
+	size   int           // Messages in buf
+	ready  chan struct{} // Signaled when a message is pushed
+	room   chan struct{} // Signaled when a message is popped
+	closed chan struct{}
+}
+
+// ringMake returns an empty ring.
+func ringMake(conf RingConf) *ring {
+	return &ring{conf: conf, ready: make(chan struct{}, 1), room: make(chan struct{}, 1), closed: make(chan struct{})}
+}
+
+// signal wakes one waiter on ch, if any.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// capacity returns the messages r holds at most. Callers must hold r.mu.
+func (r *ring) capacity() int {
+	if r.conf.Capacity == 0 {
+		return RingDefaultCapacity
+	}
+	return r.conf.Capacity
+}
+
+// resize moves r's messages, oldest first, into n slots. Callers must hold r.mu.
+func (r *ring) resize(n int) {
+	buf := make([]Msg, n)
+	for i := range r.size {
+		buf[i] = r.buf[(r.head+i)%len(r.buf)]
+	}
+	r.buf, r.head = buf, 0
+}
+
+// take removes and returns the oldest message, shrinking buf once it is mostly empty. Callers
+// must hold r.mu, and r must not be empty.
+func (r *ring) take() Msg {
+	msg := r.buf[r.head]
+	r.buf[r.head] = Msg{} // Let the payload be collected
+	r.head = (r.head + 1) % len(r.buf)
+	r.size--
+	if len(r.buf) > ringInitial && r.size <= len(r.buf)/4 {
+		r.resize(max(len(r.buf)/2, ringInitial))
+	}
+	return msg
+}
+
+// push adds msg, applying the overflow policy if r is full. It reports whether the oldest message
+// was evicted to make room.
+func (r *ring) push(ctx context.Context, msg Msg) (evicted bool, err error) {
+	r.mu.Lock()
+	select {
+	case <-r.closed: // Reset since the sender found r
+		r.mu.Unlock()
+		return false, ErrRingClosed
+	default:
+	}
+	for r.size >= r.capacity() {
+		switch r.conf.Overflow {
+		case RingEvict:
+			r.take()
+			evicted = true
+		case RingBlock:
+			r.mu.Unlock()
+			select {
+			case <-r.room:
+			case <-r.closed:
+				return false, ErrRingClosed
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+			r.mu.Lock()
+		default:
+			r.mu.Unlock()
+			return false, ErrRingFull
+		}
+	}
+
+	if r.size == len(r.buf) {
+		r.resize(min(max(2*len(r.buf), ringInitial), r.capacity()))
+	}
+	r.buf[(r.head+r.size)%len(r.buf)] = msg
+	r.size++
+	if r.size < r.capacity() {
+		signal(r.room) // Pass on a wakeup meant for blocked senders
+	}
+	r.mu.Unlock()
+	signal(r.ready)
+	return evicted, nil
+}
+
+// pop removes and returns the oldest message, waiting for one if r is empty.
+func (r *ring) pop(ctx context.Context) (Msg, error) {
+	for {
+		r.mu.Lock()
+		if r.size > 0 {
+			msg := r.take()
+			more := r.size > 0
+			r.mu.Unlock()
+			signal(r.room)
+			if more {
+				signal(r.ready) // Pass on a wakeup meant for other receivers
+			}
+			return msg, nil
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-r.ready:
+		case <-r.closed:
+			return Msg{}, ErrRingClosed
+		case <-ctx.Done():
+			return Msg{}, ctx.Err()
+		}
+	}
+}
+
+// reconfigure changes r's conf. Messages over a lowered capacity stay until received.
+func (r *ring) reconfigure(conf RingConf) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conf = conf
+}
+
+// close wakes every waiter on r with ErrRingClosed.
+func (r *ring) close() {
+	close(r.closed)
+}
@@ -0,0 +1,46 @@
+// Package wire implements the transports a Conduktor delivers messages over: Go channels, UDP,
+// and WebSockets.
+package wire
+
+import (
+	"context"
+	"net/http"
+)
+
+// Operations a WireAuthorizer is asked to authorize.
+const (
+	OpPublish   = "publish"   // Send messages to a strand
+	OpSubscribe = "subscribe" // Receive a strand's messages
+)
+
+// Wire defines the interface for sending messages via different transports. Sends and receives
+// give up with ctx.Err() once ctx is done.
+type Wire interface {
+	SendMessage(ctx context.Context, msg Msg) error
+	ReceiveMessage(ctx context.Context, channel string) (*Msg, error) // Blocks until a message arrives. Its payload may reference the receive buffer, see MsgDecodeShared
+}
+
+// EncodedSender is a Wire that can send a message already encoded with its codec, so a message
+// both stored and sent is encoded once.
+type EncodedSender interface {
+	SendEncoded(ctx context.Context, msg Msg, data []byte) error // data is msg's encoding, not retained
+	Codec() string                                               // Codec the wire encodes with
+}
+
+// WireAuthorizer authenticates wire clients and authorizes what they do. Authenticate returns a
+// context carrying what Authorize needs of the client's credentials, as its token's claims, which
+// is passed to every Authorize for the connection.
+type WireAuthorizer interface {
+	Authenticate(r *http.Request) (identity string, ctx context.Context, err error)
+	Authorize(ctx context.Context, identity, op, strandID string) error
+}
+
+// WireDisconnector closes the connection of a strand's consumer, as a slow-consumer policy.
+type WireDisconnector interface {
+	Disconnect(channel string) error
+}
+
+// StrandRouter tells wire listeners which node owns a strand, so clients can be redirected to it.
+type StrandRouter interface {
+	Route(strandID string) (addr string, local bool)
+}
@@ -0,0 +1,15 @@
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/jkassis/condukt/condukttest"
+	"github.com/jkassis/condukt/wire"
+)
+
+// Test GoChanWire Passes The Conformance Suite
+func TestWireConformance(t *testing.T) {
+	condukttest.WireConformance(t, func(t *testing.T) wire.Wire {
+		return wire.GoChanWireMake()
+	})
+}
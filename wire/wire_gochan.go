@@ -0,0 +1,127 @@
+package wire
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jkassis/condukt/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// GoChanWire is a transport that uses in-process ring buffers, one per strand, for messaging.
+type GoChanWire struct {
+	mu       sync.Mutex
+	channels map[string]*ring
+	confs    map[string]RingConf // Buffer configs of strands set with SetRing
+	conf     RingConf            // Buffer config of other strands
+	log      *zap.Logger
+}
+
+// GoChanWireMake initializes a new GoChanWire.
+func GoChanWireMake(options ...telemetry.MakeOption) *GoChanWire {
+	return &GoChanWire{
+		channels: make(map[string]*ring),
+		confs:    make(map[string]RingConf),
+		log:      telemetry.MakeOptsApply(options).Log,
+	}
+}
+
+// SetDefaultRing sets the buffer config of strands not set with SetRing. Buffers already holding
+// messages adopt it too.
+func (s *GoChanWire) SetDefaultRing(conf RingConf) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conf = conf
+	for strand, r := range s.channels {
+		if _, set := s.confs[strand]; !set {
+			r.reconfigure(conf)
+		}
+	}
+}
+
+// SetRing sets the buffer config of strandID.
+func (s *GoChanWire) SetRing(strandID string, conf RingConf) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.confs[strandID] = conf
+	if r, exists := s.channels[strandID]; exists {
+		r.reconfigure(conf)
+	}
+}
+
+// SendMessage buffers a message for its strand, applying the strand's overflow policy if the
+// buffer is full.
+func (s *GoChanWire) SendMessage(ctx context.Context, msg Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r, exists := s.channels[msg.Strand]
+	if !exists {
+		conf, set := s.confs[msg.Strand]
+		if !set {
+			conf = s.conf
+		}
+		r = ringMake(conf)
+		s.channels[msg.Strand] = r
+	}
+	s.mu.Unlock()
+
+	evicted, err := r.push(ctx, msg)
+	if err != nil {
+		s.log.Warn("Message not sent via GoChanWire", zap.String("channel", msg.Strand), zap.Error(err))
+		return err
+	}
+	if evicted {
+		s.log.Debug("Channel buffer full; evicted the oldest message", zap.String("channel", msg.Strand))
+	}
+	telemetry.MessagesSent.WithLabelValues(msg.Strand).Inc()
+	telemetry.MsgLog(s.log, msg.Debug()).Debug("Message sent via GoChanWire",
+		zap.String("channel", msg.Strand),
+		zap.String("payload", msg.Payload),
+	)
+	return nil
+}
+
+// ReceiveMessage waits for the next message buffered for a strand, or for ctx to be done.
+func (s *GoChanWire) ReceiveMessage(ctx context.Context, channel string) (*Msg, error) {
+	s.mu.Lock()
+	r, exists := s.channels[channel]
+	s.mu.Unlock()
+
+	if !exists {
+		s.log.Warn("Channel does not exist", zap.String("channel", channel))
+		return nil, errors.New("channel does not exist")
+	}
+
+	msg, err := r.pop(ctx)
+	if errors.Is(err, ErrRingClosed) {
+		s.log.Warn("Channel closed", zap.String("channel", channel))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	telemetry.MessagesReceived.WithLabelValues(channel).Inc()
+	telemetry.MsgLog(s.log, msg.Debug()).Debug("Message received via GoChanWire",
+		zap.String("channel", channel),
+		zap.String("payload", msg.Payload),
+	)
+
+	return &msg, nil
+}
+
+// Reset clears all channels, simulating a failure. Buffer configs are kept.
+func (s *GoChanWire) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Close all existing buffers, failing their waiting senders and receivers
+	for _, r := range s.channels {
+		r.close()
+	}
+	s.channels = make(map[string]*ring)
+
+	s.log.Debug("GoChanWire reset: all channels cleared")
+}
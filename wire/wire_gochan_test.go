@@ -0,0 +1,72 @@
+package wire
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test GoChanWire Buffers Grow, Shrink, And Apply Their Overflow Policy
+func TestGoChanRing(t *testing.T) {
+	ctx := context.Background()
+	w := GoChanWireMake()
+	w.SetRing("reject", RingConf{Capacity: 40})
+	w.SetRing("evict", RingConf{Capacity: 3, Overflow: RingEvict})
+	w.SetRing("block", RingConf{Capacity: 1, Overflow: RingBlock})
+
+	// Buffers grow past their initial slots, keep order, and shrink once drained
+	for i := range 40 {
+		assert.NoError(t, w.SendMessage(ctx, Msg{Strand: "reject", Payload: strconv.Itoa(i)}))
+	}
+	assert.ErrorIs(t, w.SendMessage(ctx, Msg{Strand: "reject"}), ErrRingFull)
+	r := w.channels["reject"]
+	assert.Len(t, r.buf, 40)
+	for i := range 40 {
+		msg, err := w.ReceiveMessage(ctx, "reject")
+		if assert.NoError(t, err) {
+			assert.Equal(t, strconv.Itoa(i), msg.Payload)
+		}
+	}
+	assert.Len(t, r.buf, ringInitial)
+
+	// Evicting drops the oldest messages
+	for i := range 5 {
+		assert.NoError(t, w.SendMessage(ctx, Msg{Strand: "evict", Payload: strconv.Itoa(i)}))
+	}
+	for i := 2; i < 5; i++ {
+		msg, err := w.ReceiveMessage(ctx, "evict")
+		if assert.NoError(t, err) {
+			assert.Equal(t, strconv.Itoa(i), msg.Payload)
+		}
+	}
+
+	// Blocking waits for room, or for the send's context
+	assert.NoError(t, w.SendMessage(ctx, Msg{Strand: "block", Payload: "1"}))
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.SendMessage(short, Msg{Strand: "block"}), context.DeadlineExceeded)
+	sent := make(chan error)
+	go func() { sent <- w.SendMessage(ctx, Msg{Strand: "block", Payload: "2"}) }()
+	for _, want := range []string{"1", "2"} {
+		msg, err := w.ReceiveMessage(ctx, "block")
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, msg.Payload)
+		}
+	}
+	assert.NoError(t, <-sent)
+
+	// Reset fails waiting receivers but keeps the configs
+	received := make(chan error)
+	go func() {
+		_, err := w.ReceiveMessage(ctx, "block")
+		received <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	w.Reset()
+	assert.ErrorIs(t, <-received, ErrRingClosed)
+	assert.NoError(t, w.SendMessage(ctx, Msg{Strand: "evict"}))
+	assert.Equal(t, RingEvict, w.channels["evict"].conf.Overflow)
+}
@@ -0,0 +1,178 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jkassis/condukt/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// UDP datagram sizes, in bytes.
+const (
+	UDPDatagramDefault = 4096
+	UDPDatagramMax     = 65507 // The largest UDP payload over IPv4
+)
+
+// ErrMsgTooLarge is returned when sending a message too large for the wire, which retrying cannot fix.
+var ErrMsgTooLarge = errors.New("message too large for the wire")
+
+// UDPWire handles UDP message transport (sending & receiving).
+type UDPWire struct {
+	conn         *net.UDPConn
+	addr         *net.UDPAddr
+	datagramSize atomic.Int64
+	buffers      sync.Pool                             // Receive buffers of datagramSize bytes, as *[]byte
+	filter       atomic.Pointer[func(netip.Addr) bool] // Senders whose datagrams are received; nil receives all
+	codec        string                                // Codec messages are sent with
+	log          *zap.Logger
+}
+
+// UDPWireMake initializes a new UDP connection.
+func UDPWireMake(address string, options ...telemetry.MakeOption) (*UDPWire, error) {
+	opts := telemetry.MakeOptsApply(options)
+	if err := MsgCodecCheck(opts.Codec); err != nil {
+		return nil, err
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	s := &UDPWire{conn: conn, addr: udpAddr, codec: opts.Codec, log: opts.Log}
+	s.datagramSize.Store(UDPDatagramDefault)
+	return s, nil
+}
+
+// SetDatagramSize sets the largest message, in encoded bytes, s sends and receives: 0 is
+// UDPDatagramDefault. Peers must agree on it; a peer with a smaller size truncates larger messages.
+func (s *UDPWire) SetDatagramSize(size int) error {
+	if size < 0 || size > UDPDatagramMax {
+		return fmt.Errorf("datagram size %d out of range (0 to %d)", size, UDPDatagramMax)
+	}
+	if size == 0 {
+		size = UDPDatagramDefault
+	}
+	s.datagramSize.Store(int64(size))
+	return nil
+}
+
+// SetSourceFilter drops datagrams whose sender allowed rejects, before they are decoded. Nil
+// receives datagrams from any sender.
+func (s *UDPWire) SetSourceFilter(allowed func(addr netip.Addr) bool) {
+	if allowed == nil {
+		s.filter.Store(nil)
+		return
+	}
+	s.filter.Store(&allowed)
+}
+
+// buffer returns a receive buffer from the pool, or a new one.
+func (s *UDPWire) buffer() *[]byte {
+	size := int(s.datagramSize.Load())
+	if buf, ok := s.buffers.Get().(*[]byte); ok && len(*buf) == size {
+		return buf
+	}
+	buf := make([]byte, size) // Buffers of a previous size are dropped
+	return &buf
+}
+
+// SendMessage sends a message via UDP.
+func (s *UDPWire) SendMessage(ctx context.Context, msg Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return MsgEncodePooledCodec(msg, s.codec, func(data []byte) error {
+		return s.SendEncoded(ctx, msg, data)
+	})
+}
+
+// Codec returns the codec s sends messages with.
+func (s *UDPWire) Codec() string {
+	return s.codec
+}
+
+// SendEncoded sends a message encoded as data via UDP.
+func (s *UDPWire) SendEncoded(ctx context.Context, msg Msg, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if size := s.datagramSize.Load(); int64(len(data)) > size {
+		s.log.Warn("UDP message too large", zap.String("channel", msg.Strand), zap.Int("bytes", len(data)))
+		return fmt.Errorf("%w: %d bytes exceeds the %d-byte datagram size", ErrMsgTooLarge, len(data), size)
+	}
+	deadline, _ := ctx.Deadline() // The zero time clears any earlier deadline
+	s.conn.SetWriteDeadline(deadline)
+	_, err := s.conn.WriteToUDP(data, s.addr)
+	if err != nil {
+		s.log.Error("UDP send failed", zap.Error(err))
+	}
+	return err
+}
+
+// ReceiveMessage listens for incoming messages via UDP until ctx is done.
+func (s *UDPWire) ReceiveMessage(ctx context.Context, channel string) (*Msg, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	s.conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { s.conn.SetReadDeadline(time.Now()) }) // Wake the read on cancellation
+	defer stop()
+
+	var data []byte
+	var addr netip.AddrPort
+	for {
+		buffer := s.buffer()
+		n, from, err := s.conn.ReadFromUDPAddrPort(*buffer)
+		if err == nil { // Keep only the datagram's bytes, which the message shares, so the buffer can be reused
+			data = bytes.Clone((*buffer)[:n])
+		}
+		s.buffers.Put(buffer)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// The socket deadline can pass before ctx notices its own
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() && hasDeadline && !time.Now().Before(deadline) {
+			return nil, context.DeadlineExceeded
+		}
+		if err != nil {
+			s.log.Warn("UDP receive error", zap.Error(err))
+			return nil, err
+		}
+		if allowed := s.filter.Load(); allowed == nil || (*allowed)(from.Addr()) {
+			addr = from
+			break
+		}
+	}
+
+	msg, err := MsgDecodeShared(data)
+	if err != nil {
+		s.log.Warn("Failed to unmarshal UDP message", zap.Error(err))
+		return nil, fmt.Errorf("invalid UDP message format: %w", err)
+	}
+
+	telemetry.MsgLog(s.log, msg.Debug()).Info("Message received via UDP",
+		zap.String("channel", msg.Strand),
+		zap.String("payload", msg.Payload),
+		zap.String("from", addr.String()),
+	)
+
+	return &msg, nil
+}
+
+// Close closes the UDP socket.
+func (s *UDPWire) Close() error {
+	return s.conn.Close()
+}
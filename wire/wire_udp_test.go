@@ -0,0 +1,95 @@
+package wire
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test UDP Messages Within The Datagram Size Round Trip And Larger Ones Are Refused
+func TestUDPDatagramSize(t *testing.T) {
+	w, err := UDPWireMake("127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	w.addr = w.conn.LocalAddr().(*net.UDPAddr) // Send to ourselves
+	ctx := context.Background()
+
+	assert.Error(t, w.SetDatagramSize(UDPDatagramMax+1))
+	assert.NoError(t, w.SetDatagramSize(8192))
+	large := strings.Repeat("x", 6000) // Over the default size
+	assert.NoError(t, w.SendMessage(ctx, Msg{ID: "1", Strand: "orders", Payload: large}))
+	msg, err := w.ReceiveMessage(ctx, "orders")
+	if assert.NoError(t, err) {
+		assert.Equal(t, large, msg.Payload)
+	}
+
+	// A received message keeps its payload when the buffer is reused
+	assert.NoError(t, w.SendMessage(ctx, Msg{ID: "2", Strand: "orders", Payload: "second"}))
+	_, err = w.ReceiveMessage(ctx, "orders")
+	assert.NoError(t, err)
+	if msg != nil {
+		assert.Equal(t, large, msg.Payload)
+	}
+
+	assert.NoError(t, w.SetDatagramSize(0))
+	assert.ErrorContains(t, w.SendMessage(ctx, Msg{ID: "3", Strand: "orders", Payload: large}), "datagram size")
+}
+
+// Test Datagrams From Senders The Source Filter Rejects Are Dropped
+func TestUDPSourceFilter(t *testing.T) {
+	w, err := UDPWireMake("127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	w.addr = w.conn.LocalAddr().(*net.UDPAddr)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var rejected atomic.Int64
+	w.SetSourceFilter(func(addr netip.Addr) bool {
+		rejected.Add(1)
+		return false
+	})
+	assert.NoError(t, w.SendMessage(ctx, Msg{ID: "1", Strand: "orders", Payload: "blocked"}))
+	_, err = w.ReceiveMessage(ctx, "orders")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(1), rejected.Load())
+
+	w.SetSourceFilter(func(addr netip.Addr) bool { return addr.IsLoopback() })
+	assert.NoError(t, w.SendMessage(context.Background(), Msg{ID: "2", Strand: "orders", Payload: "allowed"}))
+	msg, err := w.ReceiveMessage(context.Background(), "orders")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "allowed", msg.Payload)
+	}
+}
+
+// Benchmark Receiving Small UDP Messages
+func BenchmarkUDPReceive(b *testing.B) {
+	w, err := UDPWireMake("127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer w.Close()
+	w.addr = w.conn.LocalAddr().(*net.UDPAddr)
+	ctx := context.Background()
+	msg := Msg{ID: "1", Strand: "orders", Payload: strings.Repeat("x", 256)}
+
+	b.ReportAllocs()
+	for range b.N {
+		if err := w.SendMessage(ctx, msg); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := w.ReceiveMessage(ctx, "orders"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package wire
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jkassis/condukt/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// WSWire manages WebSocket connections for sending and receiving messages.
+type WSWire struct {
+	mu          sync.Mutex
+	connections map[string]*websocket.Conn // Channel -> WebSocket connection
+	upgrader    websocket.Upgrader
+	recvCh      map[string]chan Msg // Channel -> Message queue
+	router      StrandRouter        // Redirects clients to the owning node when set
+	authorizer  WireAuthorizer      // Authenticates clients and checks the ACL when set
+	limiter     *clientLimiter      // Enforces per-client limits when set
+	coalesce    CoalesceConf
+	pending     map[string]*wsPending // Channel -> messages buffered for its connection
+	log         *zap.Logger
+}
+
+// CoalesceConf configures write coalescing on WebSocket connections: messages sent to a channel are
+// buffered and written together, as one WebSocket message of newline-separated encodings, trading
+// latency for fewer writes. Consumers must split what they read on newlines. The zero value writes
+// each message at once.
+type CoalesceConf struct {
+	Interval time.Duration `yaml:"interval"`  // Longest a message is buffered; 0 disables coalescing
+	MaxBytes int           `yaml:"max_bytes"` // Buffered bytes that are written at once; 0 is 64KiB
+	Exempt   []string      `yaml:"exempt"`    // Latency-sensitive channels, whose messages are written at once
+}
+
+// Validate reports negative settings.
+func (conf CoalesceConf) Validate() error {
+	if conf.Interval < 0 || conf.MaxBytes < 0 {
+		return errors.New("coalesce: interval and max_bytes must not be negative")
+	}
+	return nil
+}
+
+// wsPending is the messages buffered for a connection by write coalescing.
+type wsPending struct {
+	conn  *websocket.Conn
+	buf   []byte
+	timer *time.Timer // Flushes buf once the interval is up
+}
+
+// wsError is sent to a client whose message was refused.
+type wsError struct {
+	Code  int    `json:"code"` // HTTP-style status, like 429 for rate limits
+	Error string `json:"error"`
+}
+
+// Cluster-aware routing for WebSocket clients.
+const (
+	HeaderOwner  = "X-Condukt-Owner" // Address of the node that owns the requested strand
+	wsCloseMoved = 4307              // Close code sent when a strand moves to another node; the reason is the new address
+	wsMaxHops    = 3                 // Redirects WSWireDial follows before giving up
+
+	wsCoalesceBytes = 64 << 10        // Default CoalesceConf.MaxBytes
+	wsFlushTimeout  = 5 * time.Second // Longest a coalesced write may take
+)
+
+// WSWireMake initializes a WebSocketSender.
+func WSWireMake(options ...telemetry.MakeOption) *WSWire {
+	return &WSWire{
+		log:         telemetry.MakeOptsApply(options).Log,
+		connections: make(map[string]*websocket.Conn),
+		recvCh:      make(map[string]chan Msg),
+		pending:     make(map[string]*wsPending),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true }, // Allow all origins
+		},
+	}
+}
+
+// Connections returns the number of open WebSocket connections.
+func (s *WSWire) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.connections)
+}
+
+// SendMessage sends a message via WebSocket.
+func (s *WSWire) SendMessage(ctx context.Context, msg Msg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return msgEncodePooled(msg, true, func(data []byte) error { // WebSocket messages are JSON text
+		return s.send(ctx, msg, data)
+	})
+}
+
+// Codec returns CodecJSON, as WebSocket messages are JSON text.
+func (s *WSWire) Codec() string {
+	return CodecJSON
+}
+
+// SendEncoded sends a message encoded as data via WebSocket, re-encoding it as JSON if it is binary.
+func (s *WSWire) SendEncoded(ctx context.Context, msg Msg, data []byte) error {
+	if msgBinary(data) {
+		return s.SendMessage(ctx, msg)
+	}
+	return s.send(ctx, msg, data)
+}
+
+// send sends a message JSON encoded as data via WebSocket.
+func (s *WSWire) send(ctx context.Context, msg Msg, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn, exists := s.connections[msg.Strand]
+	if !exists {
+		s.log.Warn("No WebSocket connection for channel", zap.String("channel", msg.Strand))
+		return errors.New("no active WebSocket connection for channel")
+	}
+
+	if s.coalesce.Interval > 0 && !slices.Contains(s.coalesce.Exempt, msg.Strand) {
+		if err := s.buffer(msg.Strand, conn, data); err != nil {
+			s.log.Error("Failed to send WebSocket messages", zap.Error(err))
+			return err
+		}
+	} else {
+		deadline, _ := ctx.Deadline() // The zero time clears any earlier deadline
+		conn.SetWriteDeadline(deadline)
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			s.log.Error("Failed to send WebSocket message", zap.Error(err))
+			return err
+		}
+	}
+
+	telemetry.MessagesSent.WithLabelValues(msg.Strand).Inc()
+	telemetry.MsgLog(s.log, msg.Debug()).Info("Message sent via WebSocket",
+		zap.String("channel", msg.Strand),
+		zap.String("payload", msg.Payload),
+	)
+	return nil
+}
+
+// buffer adds data to the messages buffered for channel's conn, writing them once they reach
+// MaxBytes or the interval is up. Callers must hold s.mu.
+func (s *WSWire) buffer(channel string, conn *websocket.Conn, data []byte) error {
+	p := s.pending[channel]
+	if p != nil && p.conn != conn {
+		s.flush(channel) // Written to the consumer's previous connection, most likely closed
+		p = nil
+	}
+	if p == nil {
+		p = &wsPending{conn: conn}
+		s.pending[channel] = p
+		p.timer = time.AfterFunc(s.coalesce.Interval, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.pending[channel] != p {
+				return
+			}
+			if err := s.flush(channel); err != nil {
+				s.log.Error("Failed to send WebSocket messages", zap.String("channel", channel), zap.Error(err))
+			}
+		})
+	}
+
+	if len(p.buf) > 0 {
+		p.buf = append(p.buf, '\n')
+	}
+	p.buf = append(p.buf, data...)
+	maxBytes := s.coalesce.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = wsCoalesceBytes
+	}
+	if len(p.buf) >= maxBytes {
+		return s.flush(channel)
+	}
+	return nil
+}
+
+// flush writes the messages buffered for channel, if any. Callers must hold s.mu.
+func (s *WSWire) flush(channel string) error {
+	p, exists := s.pending[channel]
+	if !exists {
+		return nil
+	}
+	delete(s.pending, channel)
+	p.timer.Stop()
+	p.conn.SetWriteDeadline(time.Now().Add(wsFlushTimeout))
+	return p.conn.WriteMessage(websocket.TextMessage, p.buf)
+}
+
+// SetCoalescing buffers the messages sent to each connection as conf says. Messages already
+// buffered are written first.
+func (s *WSWire) SetCoalescing(conf CoalesceConf) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for channel := range s.pending {
+		s.flush(channel)
+	}
+	s.coalesce = conf
+}
+
+// ReceiveMessage waits for a message from the WebSocket receive queue, or for ctx to be done.
+func (s *WSWire) ReceiveMessage(ctx context.Context, channel string) (*Msg, error) {
+	s.mu.Lock()
+	ch, exists := s.recvCh[channel]
+	s.mu.Unlock()
+
+	if !exists {
+		s.log.Warn("No WebSocket receive channel available", zap.String("channel", channel))
+		return nil, errors.New("no WebSocket receive channel available")
+	}
+
+	var msg Msg
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg = <-ch:
+	}
+	telemetry.MessagesReceived.WithLabelValues(channel).Inc()
+
+	telemetry.MsgLog(s.log, msg.Debug()).Info("Message received via WebSocket",
+		zap.String("channel", msg.Strand),
+		zap.String("payload", msg.Payload),
+	)
+
+	return &msg, nil
+}
+
+// SetRouter enables cluster-aware routing. Handshakes for strands owned by another node are
+// redirected there, and Reroute should be called whenever ownership changes.
+func (s *WSWire) SetRouter(router StrandRouter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.router = router
+}
+
+// SetAuthorizer requires clients to authenticate at the handshake, which also checks that they may
+// subscribe to the channel, and drops messages they may not publish.
+func (s *WSWire) SetAuthorizer(authorizer WireAuthorizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorizer = authorizer
+}
+
+// SetClientLimits caps the connections and message rates of each client. Connections over the cap are
+// refused with 429 Too Many Requests, and messages over a rate get a wsError with code 429.
+func (s *WSWire) SetClientLimits(limits ClientLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiter = clientLimiterMake(limits)
+}
+
+// Reroute closes connections for strands that are no longer owned locally, telling clients where the strand moved.
+func (s *WSWire) Reroute() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.router == nil {
+		return
+	}
+	for channel, conn := range s.connections {
+		addr, local := s.router.Route(channel)
+		if local {
+			continue
+		}
+
+		s.flush(channel)
+		closeMsg := websocket.FormatCloseMessage(wsCloseMoved, addr)
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		s.log.Info("WebSocket connection rerouted", zap.String("channel", channel), zap.String("owner", addr))
+	}
+}
+
+// Disconnect closes the connection of a channel's consumer with a policy-violation close code.
+func (s *WSWire) Disconnect(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn, exists := s.connections[channel]
+	if !exists {
+		return errors.New("no active WebSocket connection for channel")
+	}
+	s.flush(channel)
+	closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "slow consumer")
+	conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	delete(s.connections, channel)
+	s.log.Warn("WebSocket consumer disconnected", zap.String("channel", channel))
+	return conn.Close()
+}
+
+// Close tells every client the server is going away and closes their connections.
+func (s *WSWire) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for channel, conn := range s.connections {
+		s.flush(channel)
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		delete(s.connections, channel)
+	}
+	return nil
+}
+
+// HandleWebSocketConnection upgrades an HTTP connection to a WebSocket and handles message reception.
+// When a router is set and another node owns the channel, the client is redirected to that node instead.
+func (s *WSWire) HandleWebSocketConnection(w http.ResponseWriter, r *http.Request, channel string) {
+	s.mu.Lock()
+	router := s.router
+	authorizer := s.authorizer
+	limiter := s.limiter
+	s.mu.Unlock()
+
+	identity := ""
+	authCtx := r.Context() // Carries the client's credentials for Authorize
+	if authorizer != nil {
+		var err error
+		if identity, authCtx, err = authorizer.Authenticate(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="condukt"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err := authorizer.Authorize(authCtx, identity, OpSubscribe, channel); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	client := clientKey(identity, r.RemoteAddr)
+	if limiter != nil {
+		if err := limiter.connect(client); err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	header := http.Header{}
+	if router != nil {
+		addr, local := router.Route(channel)
+		if !local && addr != "" {
+			location := url.URL{Scheme: "ws", Host: addr, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+			w.Header().Set(HeaderOwner, addr)
+			http.Redirect(w, r, location.String(), http.StatusTemporaryRedirect)
+			s.log.Debug("WebSocket client redirected", zap.String("channel", channel), zap.String("owner", addr))
+			if limiter != nil {
+				limiter.disconnect(client)
+			}
+			return
+		}
+		header.Set(HeaderOwner, r.Host)
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		s.log.Error("WebSocket upgrade failed", zap.Error(err))
+		if limiter != nil {
+			limiter.disconnect(client)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.connections[channel] = conn
+	if _, exists := s.recvCh[channel]; !exists {
+		s.recvCh[channel] = make(chan Msg, 100) // Buffered channel for received messages
+	}
+	s.mu.Unlock()
+
+	s.log.Info("WebSocket connection established", zap.String("channel", channel))
+
+	// Handle incoming messages
+	go func() {
+		defer conn.Close()
+		if limiter != nil {
+			defer limiter.disconnect(client)
+		}
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				s.log.Warn("WebSocket read error", zap.Error(err))
+				break
+			}
+
+			for _, data := range bytes.Split(message, []byte("\n")) { // Coalesced writers send several
+				msg, err := MsgDecodeShared(data) // ReadMessage returns a new slice each time
+				if err != nil {
+					s.log.Warn("Failed to unmarshal WebSocket message", zap.Error(err))
+					continue
+				}
+				if authorizer != nil {
+					if err := authorizer.Authorize(authCtx, identity, OpPublish, msg.Strand); err != nil {
+						continue
+					}
+				}
+				if limiter != nil {
+					if err := limiter.allow(client, len(msg.Payload)); err != nil {
+						s.refuse(conn, http.StatusTooManyRequests, err)
+						continue
+					}
+				}
+
+				s.mu.Lock()
+				if ch, exists := s.recvCh[msg.Strand]; exists {
+					ch <- msg
+				}
+				s.mu.Unlock()
+			}
+		}
+
+		// Remove the connection when closed, dropping what was buffered for it
+		s.mu.Lock()
+		delete(s.connections, channel)
+		delete(s.recvCh, channel)
+		if p, exists := s.pending[channel]; exists && p.conn == conn {
+			p.timer.Stop()
+			delete(s.pending, channel)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// refuse tells a client why its message was dropped.
+func (s *WSWire) refuse(conn *websocket.Conn, code int, err error) {
+	data, _ := json.Marshal(wsError{Code: code, Error: err.Error()})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		s.log.Warn("Failed to send WebSocket error", zap.Error(err))
+	}
+}
+
+// WSWireDial connects to a WebSocket endpoint, following redirects to the node that owns the strand.
+// ctx bounds the whole dial, redirects included.
+func WSWireDial(ctx context.Context, rawURL string) (*websocket.Conn, error) {
+	for hop := 0; hop <= wsMaxHops; hop++ {
+		conn, resp, err := websocket.DefaultDialer.DialContext(ctx, rawURL, nil)
+		if err == nil {
+			return conn, nil
+		}
+		if resp == nil || resp.StatusCode != http.StatusTemporaryRedirect {
+			return nil, err
+		}
+
+		rawURL = resp.Header.Get("Location")
+		telemetry.Logger.Debug("Following WebSocket redirect", zap.String("owner", resp.Header.Get(HeaderOwner)), zap.String("url", rawURL))
+	}
+	return nil, errors.New("too many WebSocket redirects")
+}
@@ -0,0 +1,122 @@
+package wire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Per-Client Wire Limits
+func TestClientLimits(t *testing.T) {
+	ws := WSWireMake()
+	ws.SetClientLimits(ClientLimits{MaxConnections: 1, MaxRate: 2})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws.HandleWebSocketConnection(w, r, strings.TrimPrefix(r.URL.Path, "/ws/"))
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/limited_channel"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if assert.Error(t, err) && assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	// A burst of one second's worth is allowed, then messages are refused
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, conn.WriteJSON(Msg{ID: strconv.Itoa(i), Strand: "limited_channel", Payload: "x"}))
+	}
+	var refused wsError
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if assert.NoError(t, conn.ReadJSON(&refused)) {
+		assert.Equal(t, http.StatusTooManyRequests, refused.Code)
+	}
+	for i := 0; i < 2; i++ {
+		msg, err := ws.ReceiveMessage(context.Background(), "limited_channel")
+		if assert.NoError(t, err) {
+			assert.Equal(t, strconv.Itoa(i), msg.ID)
+		}
+	}
+
+	// Closing the connection frees its slot
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// Test Coalesced Messages Are Written Together, Except On Exempt Channels
+func TestCoalescing(t *testing.T) {
+	ws := WSWireMake()
+	ws.SetCoalescing(CoalesceConf{Interval: 50 * time.Millisecond, MaxBytes: 1 << 20, Exempt: []string{"urgent_channel"}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws.HandleWebSocketConnection(w, r, strings.TrimPrefix(r.URL.Path, "/ws/"))
+	}))
+	defer server.Close()
+	dial := func(channel string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/"+channel, nil)
+		assert.NoError(t, err)
+		return conn
+	}
+	batched, urgent := dial("batched_channel"), dial("urgent_channel")
+	if batched == nil || urgent == nil {
+		return
+	}
+	defer batched.Close()
+	defer urgent.Close()
+	assert.Eventually(t, func() bool { return ws.Connections() == 2 }, time.Second, 10*time.Millisecond)
+
+	ctx := context.Background()
+	for i := range 3 {
+		assert.NoError(t, ws.SendMessage(ctx, Msg{ID: strconv.Itoa(i), Strand: "batched_channel", Payload: "Batched"}))
+	}
+	assert.NoError(t, ws.SendMessage(ctx, Msg{ID: "3", Strand: "urgent_channel", Payload: "Urgent"}))
+
+	// The exempt channel's message arrives alone, before the interval is up
+	start := time.Now()
+	urgent.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := urgent.ReadMessage()
+	if assert.NoError(t, err) {
+		msg, err := MsgDecode(data)
+		assert.NoError(t, err)
+		assert.Equal(t, "3", msg.ID)
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	batched.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err = batched.ReadMessage()
+	if assert.NoError(t, err) {
+		lines := strings.Split(string(data), "\n")
+		if assert.Len(t, lines, 3) {
+			for i, line := range lines {
+				msg, err := MsgDecode([]byte(line))
+				assert.NoError(t, err)
+				assert.Equal(t, strconv.Itoa(i), msg.ID)
+			}
+		}
+	}
+
+	// Coalesced messages from clients are received one by one
+	assert.NoError(t, batched.WriteMessage(websocket.TextMessage, data))
+	for i := range 3 {
+		msg, err := ws.ReceiveMessage(ctx, "batched_channel")
+		if assert.NoError(t, err) {
+			assert.Equal(t, strconv.Itoa(i), msg.ID)
+		}
+	}
+}